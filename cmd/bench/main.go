@@ -0,0 +1,280 @@
+// Command bench load tests OnePage CMS: it serves a site seeded from a data
+// directory, or uses a running server given with -url, and measures it
+// under concurrent requests. The benchmarks of parsing, validation, form
+// generation and rendering are Go benchmarks in internal/managers.
+//
+// Results are printed in Go benchmark format, so benchstat can compare two
+// runs, followed by one line per target. With -check the exit status is 1
+// when a target is missed.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"onepagems/internal"
+	"onepagems/internal/server"
+	"onepagems/internal/types"
+)
+
+// loadTargets are the p95 latencies the load test must stay under, by path
+var loadTargets = map[string]time.Duration{
+	"/":             20 * time.Millisecond,
+	"/content.json": 20 * time.Millisecond,
+	"/health":       10 * time.Millisecond,
+}
+
+func main() {
+	seed := flag.String("data", "./data", "data directory to seed from; it is copied, never modified")
+	check := flag.Bool("check", false, "exit with status 1 when a target is missed")
+	url := flag.String("url", "", "test this running server instead of a seeded one")
+	concurrency := flag.Int("c", 16, "concurrent clients")
+	duration := flag.Duration("d", 10*time.Second, "how long to send requests to each path")
+	paths := flag.String("paths", "/,/content.json,/health", "comma-separated paths to request")
+	flag.Parse()
+
+	if run(*seed, *url, strings.Split(*paths, ","), *concurrency, *duration) {
+		fmt.Println("ok")
+		return
+	}
+	fmt.Println("FAIL")
+	if *check {
+		os.Exit(1)
+	}
+}
+
+// run load tests baseURL, or else a server seeded from seed that it starts
+// and stops, and reports whether all targets were met
+func run(seed, baseURL string, paths []string, concurrency int, duration time.Duration) bool {
+	if baseURL == "" {
+		dataDir, err := seedDataDir(seed)
+		if err != nil {
+			log.Fatalf("Failed to seed data directory: %v", err)
+		}
+		defer os.RemoveAll(dataDir)
+
+		srv, err := newSeededServer(dataDir)
+		if err != nil {
+			log.Fatalf("Failed to set up server: %v", err)
+		}
+		var stop func()
+		baseURL, stop, err = serve(srv)
+		if err != nil {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+		defer stop()
+	}
+	return runLoad(baseURL, paths, concurrency, duration)
+}
+
+// seedDataDir copies the files of seed into a new temporary directory
+func seedDataDir(seed string) (string, error) {
+	dir, err := os.MkdirTemp("", "onepagems-bench-*")
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(seed)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(seed, entry.Name()))
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(dir, entry.Name()), data, 0644); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// newSeededServer creates a quiet server over dataDir and generates the
+// site, so the public page is served as in production
+func newSeededServer(dataDir string) (*server.Server, error) {
+	config := types.DefaultConfig()
+	config.DataDir = dataDir
+	config.OutputDir = filepath.Join(dataDir, "public")
+	config.StaticDir = filepath.Join(dataDir, "static")
+	if err := internal.ValidateConfig(config); err != nil {
+		return nil, err
+	}
+
+	srv := server.NewServer(config, server.WithLogger(log.New(io.Discard, "", 0)))
+	if err := srv.Load(); err != nil {
+		return nil, err
+	}
+	if _, err := srv.Generator.Generate(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to generate site: %w", err)
+	}
+	return srv, nil
+}
+
+// verdict is the target line of one measurement
+func verdict(name string, got, target time.Duration, unit string) string {
+	status := "PASS"
+	if got > target {
+		status = "FAIL"
+	}
+	return fmt.Sprintf("--- %s: %s %s%s (target %s)", status, name, got, unit, target)
+}
+
+// loadResult is what one path measured under load
+type loadResult struct {
+	latencies []time.Duration
+	errors    int
+	elapsed   time.Duration
+}
+
+// percentile returns the latency under which p of the requests finished
+func (lr *loadResult) percentile(p float64) time.Duration {
+	if len(lr.latencies) == 0 {
+		return 0
+	}
+	index := int(float64(len(lr.latencies)-1) * p)
+	return lr.latencies[index]
+}
+
+// runLoad sends concurrent requests to each path of baseURL and reports
+// whether all met their targets
+func runLoad(baseURL string, paths []string, concurrency int, duration time.Duration) bool {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{MaxIdleConnsPerHost: concurrency},
+	}
+
+	passed := true
+	var verdicts []string
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		result := hammer(client, baseURL+path, concurrency, duration)
+		requests := len(result.latencies)
+		if requests == 0 {
+			log.Fatalf("No requests to %s completed", path)
+		}
+		var total time.Duration
+		for _, latency := range result.latencies {
+			total += latency
+		}
+		p95 := result.percentile(0.95)
+		fmt.Printf("BenchmarkLoad%s\t%8d\t%10d ns/op\t%10.1f req/s\t%8.2f p50-ms\t%8.2f p95-ms\t%8.2f p99-ms\t%d errors\n",
+			benchName(path), requests, int64(total)/int64(requests),
+			float64(requests)/result.elapsed.Seconds(),
+			ms(result.percentile(0.50)), ms(p95), ms(result.percentile(0.99)), result.errors)
+
+		target, ok := loadTargets[path]
+		if !ok {
+			target = 50 * time.Millisecond
+		}
+		verdicts = append(verdicts, verdict("Load"+benchName(path)+" p95", p95, target, ""))
+		if result.errors > 0 {
+			verdicts = append(verdicts, fmt.Sprintf("--- FAIL: Load%s %d of %d requests failed", benchName(path), result.errors, requests))
+		}
+		passed = passed && p95 <= target && result.errors == 0
+	}
+	fmt.Println()
+	for _, line := range verdicts {
+		fmt.Println(line)
+	}
+	return passed
+}
+
+// serve starts srv on a free local port and returns its URL and a function
+// that stops it
+func serve(srv *server.Server) (string, func(), error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, err
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	srv.Config.Port = fmt.Sprint(port)
+
+	go srv.Start()
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if resp, err := http.Get(baseURL + "/health"); err == nil {
+			resp.Body.Close()
+			stop := func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				srv.Stop(ctx)
+			}
+			return baseURL, stop, nil
+		}
+	}
+	return "", nil, fmt.Errorf("server did not answer on %s", baseURL)
+}
+
+// hammer requests url from concurrency clients until duration has passed
+func hammer(client *http.Client, url string, concurrency int, duration time.Duration) *loadResult {
+	result := &loadResult{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	start := time.Now()
+	deadline := start.Add(duration)
+
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var latencies []time.Duration
+			errors := 0
+			for time.Now().Before(deadline) {
+				began := time.Now()
+				resp, err := client.Get(url)
+				if err == nil {
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+					if resp.StatusCode >= 400 {
+						err = fmt.Errorf("status %d", resp.StatusCode)
+					}
+				}
+				latencies = append(latencies, time.Since(began))
+				if err != nil {
+					errors++
+				}
+			}
+			mu.Lock()
+			result.latencies = append(result.latencies, latencies...)
+			result.errors += errors
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	result.elapsed = time.Since(start)
+	sort.Slice(result.latencies, func(i, j int) bool { return result.latencies[i] < result.latencies[j] })
+	return result
+}
+
+// benchName turns a path into a benchmark name suffix; "/" is "/index"
+func benchName(path string) string {
+	if path == "/" {
+		return "/index"
+	}
+	return path
+}
+
+// ms converts a duration to fractional milliseconds
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -1,15 +1,44 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"onepagems/internal"
+	"onepagems/internal/managers"
 	"onepagems/internal/server"
+	"onepagems/internal/types"
+
+	// Embed the time zone database so event time zones validate on hosts
+	// without one
+	_ "time/tzdata"
 )
 
 func main() {
+	checkMigrations := flag.Bool("check-migrations", false, "report pending data directory migrations and exit (status 2 if any)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [doctor]\n\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "  doctor  check the data directory for problems and exit (status 1 on errors)")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
 	// Load configuration from environment variables
 	config := internal.LoadConfig()
 
+	if *checkMigrations {
+		os.Exit(runMigrationCheck(config.DataDir))
+	}
+	if flag.Arg(0) == "doctor" {
+		os.Exit(runDoctor(config))
+	}
+
 	// Validate configuration
 	if err := internal.ValidateConfig(config); err != nil {
 		log.Fatalf("Configuration validation failed: %v", err)
@@ -23,13 +52,83 @@ func main() {
 	log.Printf("  Templates directory: %s", config.TemplatesDir)
 	log.Printf("  Upload max size: %d bytes", config.UploadMaxSize)
 	log.Printf("  Session timeout: %d minutes", config.SessionTimeout)
+	log.Printf("  Request timeouts: public %ds, admin %ds, generation %ds", config.PublicTimeout, config.AdminTimeout, config.GenerateTimeout)
 	log.Printf("  Admin username: %s", config.AdminUsername)
 
 	// Create and start server
 	srv := server.NewServer(config)
 
+	// On SIGINT or SIGTERM, let requests in progress finish before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	log.Println("OnePage CMS server starting...")
-	if err := srv.Start(); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	errs := make(chan error, 1)
+	go func() { errs <- srv.Start() }()
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			log.Fatalf("Server failed to start: %v", err)
+		}
+	case <-ctx.Done():
+		stop() // a second signal exits at once
+		log.Printf("Shutting down, waiting up to %ds for requests in progress...", config.ShutdownTimeout)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(config.ShutdownTimeout)*time.Second)
+		defer cancel()
+		if err := srv.Stop(shutdownCtx); err != nil {
+			log.Printf("Shutdown incomplete: %v", err)
+			os.Exit(1)
+		}
+		log.Println("Server stopped")
+	}
+}
+
+// runMigrationCheck prints the data directory version and the migrations
+// startup would apply. It returns 0 when up to date and 2 when migrations
+// are pending.
+func runMigrationCheck(dataDir string) int {
+	runner := managers.NewMigrationRunner(managers.NewFileStorage(dataDir))
+	version, err := runner.Version()
+	if err != nil {
+		log.Fatalf("Failed to read data version: %v", err)
+	}
+	pending, err := runner.Pending()
+	if err != nil {
+		log.Fatalf("Migration check failed: %v", err)
+	}
+
+	log.Printf("Data directory %s is at version %d; this build uses version %d", dataDir, version, managers.CurrentDataVersion())
+	if len(pending) == 0 {
+		log.Printf("No migrations pending")
+		return 0
+	}
+	for _, migration := range pending {
+		log.Printf("  pending %d: %s", migration.Version, migration.Description)
+	}
+	return 2
+}
+
+// runDoctor prints the result of every doctor check with suggested fixes.
+// It returns 1 when any check failed.
+func runDoctor(config *types.Config) int {
+	storage := managers.NewFileStorage(config.DataDir)
+	doctor := managers.NewDoctor(storage, managers.NewTemplateManager(storage), config.OutputDir, config.StaticDir, config.TemplatesDir)
+	report := doctor.Run(time.Now(), time.Time{})
+
+	for _, check := range report.Checks {
+		fmt.Printf("[%s] %s: %s\n", check.Status, check.Name, check.Message)
+		if check.Path != "" && check.Status != managers.DoctorOK {
+			fmt.Printf("    path: %s\n", check.Path)
+		}
+		if check.Fix != "" {
+			fmt.Printf("    fix:  %s\n", check.Fix)
+		}
+	}
+	fmt.Printf("\nOverall: %s\n", report.Status)
+
+	if report.Status == managers.DoctorError {
+		return 1
 	}
+	return 0
 }
@@ -1,11 +1,18 @@
 package internal
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
+	"onepagems/internal/httpclient"
+	"onepagems/internal/managers"
+	"onepagems/internal/tracing"
 	"onepagems/internal/types"
 )
 
@@ -18,6 +25,38 @@ func LoadConfig() *types.Config {
 		config.Port = port
 	}
 
+	if certFile := os.Getenv("TLS_CERT_FILE"); certFile != "" {
+		config.TLSCertFile = certFile
+	}
+
+	if keyFile := os.Getenv("TLS_KEY_FILE"); keyFile != "" {
+		config.TLSKeyFile = keyFile
+	}
+
+	if hosts := os.Getenv("AUTOCERT_HOSTS"); hosts != "" {
+		for _, host := range strings.Split(hosts, ",") {
+			if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+				config.AutocertHosts = append(config.AutocertHosts, host)
+			}
+		}
+	}
+
+	if email := os.Getenv("AUTOCERT_EMAIL"); email != "" {
+		config.AutocertEmail = email
+	}
+
+	if dir := os.Getenv("AUTOCERT_DIR"); dir != "" {
+		config.AutocertDir = dir
+	}
+
+	if directory := os.Getenv("AUTOCERT_DIRECTORY"); directory != "" {
+		config.AutocertDirectory = directory
+	}
+
+	if redirectPort := os.Getenv("HTTP_REDIRECT_PORT"); redirectPort != "" {
+		config.HTTPRedirectPort = redirectPort
+	}
+
 	if username := os.Getenv("ADMIN_USERNAME"); username != "" {
 		config.AdminUsername = username
 	}
@@ -33,16 +72,86 @@ func LoadConfig() *types.Config {
 		}
 	}
 
+	if maxSizeStr := os.Getenv("ARCHIVE_MAX_SIZE"); maxSizeStr != "" {
+		if maxSize, err := strconv.ParseInt(maxSizeStr, 10, 64); err == nil {
+			config.ArchiveMaxSize = maxSize
+		}
+	}
+
 	if timeoutStr := os.Getenv("SESSION_TIMEOUT"); timeoutStr != "" {
 		if timeout, err := strconv.Atoi(timeoutStr); err == nil {
 			config.SessionTimeout = timeout
 		}
 	}
 
+	if timeoutStr := os.Getenv("IDLE_TIMEOUT"); timeoutStr != "" {
+		if timeout, err := strconv.Atoi(timeoutStr); err == nil {
+			config.IdleTimeout = timeout
+		}
+	}
+
+	if windowStr := os.Getenv("REAUTH_WINDOW"); windowStr != "" {
+		if window, err := strconv.Atoi(windowStr); err == nil {
+			config.ReauthWindow = window
+		}
+	}
+
+	if accessLogStr := os.Getenv("ACCESS_LOG"); accessLogStr != "" {
+		if accessLog, err := strconv.ParseBool(accessLogStr); err == nil {
+			config.AccessLog = accessLog
+		}
+	}
+
+	if compressionStr := os.Getenv("COMPRESSION"); compressionStr != "" {
+		if compression, err := strconv.ParseBool(compressionStr); err == nil {
+			config.Compression = compression
+		}
+	}
+
+	if persistStr := os.Getenv("PERSIST_SESSIONS"); persistStr != "" {
+		if persist, err := strconv.ParseBool(persistStr); err == nil {
+			config.PersistSessions = persist
+		}
+	}
+
+	if confirmStr := os.Getenv("CONFIRM_DESTRUCTIVE"); confirmStr != "" {
+		if confirm, err := strconv.ParseBool(confirmStr); err == nil {
+			config.ConfirmDestructive = confirm
+		}
+	}
+
+	if timeoutStr := os.Getenv("PUBLIC_TIMEOUT"); timeoutStr != "" {
+		if timeout, err := strconv.Atoi(timeoutStr); err == nil {
+			config.PublicTimeout = timeout
+		}
+	}
+
+	if timeoutStr := os.Getenv("ADMIN_TIMEOUT"); timeoutStr != "" {
+		if timeout, err := strconv.Atoi(timeoutStr); err == nil {
+			config.AdminTimeout = timeout
+		}
+	}
+
+	if timeoutStr := os.Getenv("GENERATE_TIMEOUT"); timeoutStr != "" {
+		if timeout, err := strconv.Atoi(timeoutStr); err == nil {
+			config.GenerateTimeout = timeout
+		}
+	}
+
+	if timeoutStr := os.Getenv("SHUTDOWN_TIMEOUT"); timeoutStr != "" {
+		if timeout, err := strconv.Atoi(timeoutStr); err == nil {
+			config.ShutdownTimeout = timeout
+		}
+	}
+
 	if dataDir := os.Getenv("DATA_DIR"); dataDir != "" {
 		config.DataDir = dataDir
 	}
 
+	if sitesDir := os.Getenv("SITES_DIR"); sitesDir != "" {
+		config.SitesDir = sitesDir
+	}
+
 	if staticDir := os.Getenv("STATIC_DIR"); staticDir != "" {
 		config.StaticDir = staticDir
 	}
@@ -51,6 +160,266 @@ func LoadConfig() *types.Config {
 		config.TemplatesDir = templatesDir
 	}
 
+	if outputDir := os.Getenv("OUTPUT_DIR"); outputDir != "" {
+		config.OutputDir = outputDir
+	}
+
+	if providerURL := os.Getenv("ALT_TEXT_PROVIDER_URL"); providerURL != "" {
+		config.AltTextProviderURL = providerURL
+	}
+
+	if providerKey := os.Getenv("ALT_TEXT_PROVIDER_KEY"); providerKey != "" {
+		config.AltTextProviderKey = providerKey
+	}
+
+	if cdnURL := os.Getenv("IMAGE_CDN_URL"); cdnURL != "" {
+		config.ImageCDNURL = cdnURL
+	}
+
+	if siteURL := os.Getenv("SITE_URL"); siteURL != "" {
+		config.SiteURL = siteURL
+	}
+
+	if contact := os.Getenv("SECURITY_CONTACT"); contact != "" {
+		config.SecurityContact = contact
+	}
+
+	if policy := os.Getenv("SECURITY_POLICY"); policy != "" {
+		config.SecurityPolicy = policy
+	}
+
+	if humansStr := os.Getenv("HUMANS_TXT"); humansStr != "" {
+		if humans, err := strconv.ParseBool(humansStr); err == nil {
+			config.HumansTxt = humans
+		}
+	}
+
+	if strictStr := os.Getenv("SCHEMA_STRICT"); strictStr != "" {
+		if strict, err := strconv.ParseBool(strictStr); err == nil {
+			config.SchemaStrict = strict
+		}
+	}
+
+	if lengthStr := os.Getenv("RICH_TEXT_MAX_LENGTH"); lengthStr != "" {
+		if length, err := strconv.Atoi(lengthStr); err == nil {
+			config.RichTextMaxLength = length
+		}
+	}
+
+	if itemsStr := os.Getenv("ARRAY_MAX_ITEMS"); itemsStr != "" {
+		if items, err := strconv.Atoi(itemsStr); err == nil {
+			config.ArrayMaxItems = items
+		}
+	}
+
+	if depthStr := os.Getenv("SCHEMA_MAX_DEPTH"); depthStr != "" {
+		if depth, err := strconv.Atoi(depthStr); err == nil {
+			config.SchemaMaxDepth = depth
+		}
+	}
+
+	if propertiesStr := os.Getenv("SCHEMA_MAX_PROPERTIES"); propertiesStr != "" {
+		if properties, err := strconv.Atoi(propertiesStr); err == nil {
+			config.SchemaMaxProperties = properties
+		}
+	}
+
+	if enumStr := os.Getenv("SCHEMA_MAX_ENUM_VALUES"); enumStr != "" {
+		if values, err := strconv.Atoi(enumStr); err == nil {
+			config.SchemaMaxEnumValues = values
+		}
+	}
+
+	if maxSizeStr := os.Getenv("CUSTOM_CODE_MAX_SIZE"); maxSizeStr != "" {
+		if maxSize, err := strconv.Atoi(maxSizeStr); err == nil {
+			config.CustomCodeMaxSize = maxSize
+		}
+	}
+
+	if historyStr := os.Getenv("SITE_BUILD_HISTORY"); historyStr != "" {
+		if history, err := strconv.Atoi(historyStr); err == nil {
+			config.SiteBuildHistory = history
+		}
+	}
+
+	if limitStr := os.Getenv("RATE_LIMIT_SESSION"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			config.RateLimitSession = limit
+		}
+	}
+
+	if limitStr := os.Getenv("RATE_LIMIT_TOKEN"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			config.RateLimitToken = limit
+		}
+	}
+
+	if attemptsStr := os.Getenv("LOGIN_MAX_ATTEMPTS"); attemptsStr != "" {
+		if attempts, err := strconv.Atoi(attemptsStr); err == nil {
+			config.LoginMaxAttempts = attempts
+		}
+	}
+
+	if lockoutStr := os.Getenv("LOGIN_LOCKOUT"); lockoutStr != "" {
+		if lockout, err := strconv.Atoi(lockoutStr); err == nil {
+			config.LoginLockout = lockout
+		}
+	}
+
+	if timezone := os.Getenv("TIMEZONE"); timezone != "" {
+		config.Timezone = timezone
+	}
+
+	if proxy := os.Getenv("OUTBOUND_PROXY"); proxy != "" {
+		config.OutboundProxy = proxy
+	}
+
+	if caBundle := os.Getenv("OUTBOUND_CA_BUNDLE"); caBundle != "" {
+		config.OutboundCABundle = caBundle
+	}
+
+	if signingKey := os.Getenv("EXPORT_SIGNING_KEY"); signingKey != "" {
+		config.ExportSigningKey = signingKey
+	}
+
+	if secretsKey := os.Getenv("SECRETS_KEY"); secretsKey != "" {
+		config.SecretsKey = secretsKey
+	}
+
+	if secretsKeyFile := os.Getenv("SECRETS_KEY_FILE"); secretsKeyFile != "" {
+		config.SecretsKeyFile = secretsKeyFile
+	}
+
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		config.TracingEndpoint = endpoint
+	}
+
+	if serviceName := os.Getenv("OTEL_SERVICE_NAME"); serviceName != "" {
+		config.TracingServiceName = serviceName
+	}
+
+	if headers := os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"); headers != "" {
+		config.TracingHeaders = tracing.ParseHeaders(headers)
+	}
+
+	if emails := os.Getenv("NOTIFY_EMAILS"); emails != "" {
+		config.NotifyEmails = nil
+		for _, email := range strings.Split(emails, ",") {
+			if email = strings.TrimSpace(email); email != "" {
+				config.NotifyEmails = append(config.NotifyEmails, email)
+			}
+		}
+	}
+
+	if digest := os.Getenv("NOTIFY_DIGEST"); digest != "" {
+		config.NotifyDigest = digest
+	}
+
+	if digestTime := os.Getenv("NOTIFY_DIGEST_TIME"); digestTime != "" {
+		config.NotifyDigestTime = digestTime
+	}
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		config.SMTPHost = host
+	}
+
+	if portStr := os.Getenv("SMTP_PORT"); portStr != "" {
+		if port, err := strconv.Atoi(portStr); err == nil {
+			config.SMTPPort = port
+		}
+	}
+
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		config.SMTPUsername = username
+	}
+
+	if password := os.Getenv("SMTP_PASSWORD"); password != "" {
+		config.SMTPPassword = password
+	}
+
+	if from := os.Getenv("SMTP_FROM"); from != "" {
+		config.SMTPFrom = from
+	}
+
+	for _, event := range managers.WebhookEvents {
+		if spec := os.Getenv("WEBHOOK_" + strings.ToUpper(event)); spec != "" {
+			if config.Webhooks == nil {
+				config.Webhooks = make(map[string]string)
+			}
+			config.Webhooks[event] = spec
+		}
+	}
+
+	if hooks := os.Getenv("DEPLOY_HOOK_URLS"); hooks != "" {
+		config.DeployHooks = nil
+		for _, hook := range strings.Split(hooks, ",") {
+			if hook = strings.TrimSpace(hook); hook != "" {
+				config.DeployHooks = append(config.DeployHooks, hook)
+			}
+		}
+	}
+
+	for _, event := range managers.PluginEvents {
+		if spec := os.Getenv("PLUGIN_HOOK_" + strings.ToUpper(event)); spec != "" {
+			if config.PluginHooks == nil {
+				config.PluginHooks = make(map[string]string)
+			}
+			config.PluginHooks[event] = spec
+		}
+	}
+
+	if heartbeatURL := os.Getenv("HEARTBEAT_URL"); heartbeatURL != "" {
+		config.HeartbeatURL = heartbeatURL
+	}
+
+	if intervalStr := os.Getenv("HEARTBEAT_INTERVAL"); intervalStr != "" {
+		if interval, err := strconv.Atoi(intervalStr); err == nil {
+			config.HeartbeatInterval = interval
+		}
+	}
+
+	if daysStr := os.Getenv("ACTIVITY_RETENTION_DAYS"); daysStr != "" {
+		if days, err := strconv.Atoi(daysStr); err == nil {
+			config.ActivityRetentionDays = days
+		}
+	}
+
+	if daysStr := os.Getenv("JOB_RETENTION_DAYS"); daysStr != "" {
+		if days, err := strconv.Atoi(daysStr); err == nil {
+			config.JobRetentionDays = days
+		}
+	}
+
+	if daysStr := os.Getenv("OUTBOX_RETENTION_DAYS"); daysStr != "" {
+		if days, err := strconv.Atoi(daysStr); err == nil {
+			config.OutboxRetentionDays = days
+		}
+	}
+
+	if emergency := os.Getenv("EMERGENCY_LOGIN"); emergency != "" {
+		config.EmergencyLogin = emergency
+	}
+
+	if tokenFile := os.Getenv("EMERGENCY_TOKEN_FILE"); tokenFile != "" {
+		config.EmergencyTokenFile = tokenFile
+	}
+
+	if passkeyLogin := os.Getenv("PASSKEY_LOGIN"); passkeyLogin != "" {
+		config.PasskeyLogin = passkeyLogin
+	}
+
+	if passkeyOrigin := os.Getenv("PASSKEY_ORIGIN"); passkeyOrigin != "" {
+		config.PasskeyOrigin = passkeyOrigin
+	}
+
+	if assetMode := os.Getenv("ASSET_MODE"); assetMode != "" {
+		config.AssetMode = assetMode
+	}
+
+	if renderMode := os.Getenv("RENDER_MODE"); renderMode != "" {
+		config.RenderMode = renderMode
+	}
+
 	return config
 }
 
@@ -70,11 +439,116 @@ func ValidateConfig(config *types.Config) error {
 		config.AdminPassword = hashPassword("admin123")
 	}
 
+	if config.AssetMode != "inline" && config.AssetMode != "external" {
+		return fmt.Errorf("invalid ASSET_MODE '%s': must be 'inline' or 'external'", config.AssetMode)
+	}
+
+	if config.RenderMode == "" {
+		config.RenderMode = "static"
+	}
+	if config.RenderMode != "static" && config.RenderMode != "live" {
+		return fmt.Errorf("invalid RENDER_MODE '%s': must be 'static' or 'live'", config.RenderMode)
+	}
+
+	switch config.EmergencyLogin {
+	case managers.EmergencyLoginOff, managers.EmergencyLoginToken, managers.EmergencyLoginLocal:
+	default:
+		return fmt.Errorf("invalid EMERGENCY_LOGIN %q: must be 'token', 'local' or 'off'", config.EmergencyLogin)
+	}
+	if config.EmergencyTokenFile == "" {
+		config.EmergencyTokenFile = filepath.Join(config.DataDir, "emergency_login.token")
+	}
+
+	if (config.TLSCertFile == "") != (config.TLSKeyFile == "") {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if config.TLSCertFile != "" && len(config.AutocertHosts) > 0 {
+		return fmt.Errorf("set either TLS_CERT_FILE and TLS_KEY_FILE or AUTOCERT_HOSTS, not both")
+	}
+	for _, host := range config.AutocertHosts {
+		if err := managers.ValidAutocertHost(host); err != nil {
+			return fmt.Errorf("invalid AUTOCERT_HOSTS: %w", err)
+		}
+	}
+	if len(config.AutocertHosts) > 0 {
+		if u, err := url.Parse(config.AutocertDirectory); err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("invalid AUTOCERT_DIRECTORY %q: must be an https URL", config.AutocertDirectory)
+		}
+		if config.AutocertDir == "" {
+			config.AutocertDir = filepath.Join(config.DataDir, "autocert")
+		}
+	}
+	if config.HTTPRedirectPort == "" && len(config.AutocertHosts) > 0 {
+		// The CA fetches HTTP-01 challenges from port 80
+		config.HTTPRedirectPort = "80"
+	}
+	if config.HTTPRedirectPort == "off" {
+		config.HTTPRedirectPort = ""
+	}
+	if config.HTTPRedirectPort != "" && config.HTTPRedirectPort == config.Port && config.TLSEnabled() {
+		return fmt.Errorf("HTTP_REDIRECT_PORT must differ from PORT")
+	}
+
+	if !managers.ValidPasskeyLogin(config.PasskeyLogin) {
+		return fmt.Errorf("invalid PASSKEY_LOGIN %q: must be 'passwordless', 'second_factor' or 'off'", config.PasskeyLogin)
+	}
+	if config.PasskeyOrigin != "" {
+		if _, err := managers.NewPasskeyRelyingParty(config.PasskeyOrigin, ""); err != nil {
+			return fmt.Errorf("invalid PASSKEY_ORIGIN: %w", err)
+		}
+	}
+
+	if _, err := time.LoadLocation(config.Timezone); err != nil {
+		return fmt.Errorf("invalid TIMEZONE %q: %w", config.Timezone, err)
+	}
+
+	if _, err := httpclient.NewTransport(config.OutboundProxy, config.OutboundCABundle); err != nil {
+		return fmt.Errorf("invalid outbound HTTP settings: %w", err)
+	}
+
+	if config.ExportSigningKey != "" {
+		if _, err := managers.NewExportSigner(config.ExportSigningKey); err != nil {
+			return fmt.Errorf("invalid EXPORT_SIGNING_KEY: %w", err)
+		}
+	}
+
+	if len(config.NotifyEmails) > 0 {
+		if config.SMTPHost == "" || config.SMTPFrom == "" {
+			return fmt.Errorf("NOTIFY_EMAILS needs SMTP_HOST and SMTP_FROM")
+		}
+		if _, err := managers.NewPublishNotifier(nil, nil, config.NotifyEmails, config.NotifyDigest, config.NotifyDigestTime, time.UTC); err != nil {
+			return fmt.Errorf("invalid publish notification settings: %w", err)
+		}
+	}
+
+	webhooks, err := managers.NewWebhookDispatcher(config.Webhooks, nil)
+	if err != nil {
+		return fmt.Errorf("invalid WEBHOOK_* setting: %w", err)
+	}
+	if err := webhooks.SetDeployHooks(config.DeployHooks); err != nil {
+		return fmt.Errorf("invalid DEPLOY_HOOK_URLS: %w", err)
+	}
+
+	if _, err := managers.NewExternalHooks(config.PluginHooks, nil); err != nil {
+		return fmt.Errorf("invalid PLUGIN_HOOK_* setting: %w", err)
+	}
+
+	secretsKey, err := managers.ReadSecretsKey(config.SecretsKey, config.SecretsKeyFile)
+	if err != nil {
+		return err
+	}
+	if _, err := managers.NewSecretManager(nil, secretsKey); err != nil {
+		return fmt.Errorf("invalid SECRETS_KEY: %w", err)
+	}
+
 	return nil
 }
 
-// hashPassword creates a SHA-256 hash of the password
+// hashPassword hashes the password with the default password hasher
 func hashPassword(password string) string {
-	hash := sha256.Sum256([]byte(password))
-	return hex.EncodeToString(hash[:])
+	hash, err := managers.HashPassword(password)
+	if err != nil {
+		log.Fatalf("Failed to hash admin password: %v", err)
+	}
+	return hash
 }
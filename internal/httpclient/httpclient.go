@@ -0,0 +1,80 @@
+// Package httpclient builds the HTTP clients used for outbound calls to
+// integrations, so proxy and CA settings apply to all of them.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Clients makes the clients of one server, sharing its transport. Each
+// server has its own, so servers in one process keep their own proxy and
+// CA settings. A nil *Clients makes clients with the default transport.
+type Clients struct {
+	transport http.RoundTripper
+}
+
+// NewClients returns clients with the given proxy and CA bundle, as
+// described for NewTransport
+func NewClients(proxyURL, caBundle string) (*Clients, error) {
+	transport, err := NewTransport(proxyURL, caBundle)
+	if err != nil {
+		return nil, err
+	}
+	return &Clients{transport: transport}, nil
+}
+
+// New returns a client for outbound calls with the proxy and CA bundle of
+// c. A zero timeout means none.
+func (c *Clients) New(timeout time.Duration) *http.Client {
+	if c == nil {
+		return &http.Client{Timeout: timeout, Transport: http.DefaultTransport}
+	}
+	return &http.Client{Timeout: timeout, Transport: c.transport}
+}
+
+// NewTransport builds a transport for outbound calls. proxyURL sends all
+// requests through that proxy; when empty the HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY environment variables are honored. caBundle is a PEM file of
+// extra certificate authorities trusted alongside the system ones, such as
+// a corporate TLS-inspecting proxy's.
+func NewTransport(proxyURL, caBundle string) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if proxyURL != "" {
+		proxy, err := url.Parse(proxyURL)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", proxyURL)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("proxy URL scheme must be http, https or socks5, not %q", proxy.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	} else {
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+
+	if caBundle != "" {
+		pem, err := os.ReadFile(caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %s holds no PEM certificates", caBundle)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	return transport, nil
+}
@@ -0,0 +1,68 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// proxyFor returns the proxy a client sends req through
+func proxyFor(t *testing.T, client *http.Client, req *http.Request) *url.URL {
+	t.Helper()
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("transport is %T", client.Transport)
+	}
+	if transport.Proxy == nil {
+		return nil
+	}
+	proxy, err := transport.Proxy(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proxy
+}
+
+func TestClientsKeepTheirOwnSettings(t *testing.T) {
+	first, err := NewClients("http://proxy-one.internal:3128", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewClients("socks5://proxy-two.internal:1080", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://hooks.example.com/", nil)
+	if proxy := proxyFor(t, first.New(time.Second), req); proxy == nil || proxy.Host != "proxy-one.internal:3128" {
+		t.Errorf("first clients use proxy %v", proxy)
+	}
+	if proxy := proxyFor(t, second.New(time.Second), req); proxy == nil || proxy.Host != "proxy-two.internal:1080" {
+		t.Errorf("second clients use proxy %v", proxy)
+	}
+
+	var none *Clients
+	if client := none.New(5 * time.Second); client.Transport != http.DefaultTransport || client.Timeout != 5*time.Second {
+		t.Errorf("nil Clients made %+v", client)
+	}
+}
+
+func TestNewTransportRejectsInvalidSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		proxy    string
+		caBundle string
+	}{
+		{"proxy without host", "http://", ""},
+		{"unsupported proxy scheme", "ftp://proxy.internal", ""},
+		{"missing CA bundle", "", "/nonexistent/ca.pem"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewClients(tt.proxy, tt.caBundle); err == nil {
+				t.Error("NewClients accepted the settings")
+			}
+		})
+	}
+}
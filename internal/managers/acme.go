@@ -0,0 +1,446 @@
+package managers
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// acmeMaxResponse caps ACME response bodies; certificate chains are a few KB
+const acmeMaxResponse = 1 << 20
+
+// acmeDirectory lists the ACME endpoints of a CA (RFC 8555 section 7.1.1)
+type acmeDirectory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+}
+
+// acmeProblem is an error document returned by the CA
+type acmeProblem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+	Status int    `json:"status"`
+}
+
+func (p *acmeProblem) Error() string {
+	if p.Detail != "" {
+		return fmt.Sprintf("acme: %s: %s", strings.TrimPrefix(p.Type, "urn:ietf:params:acme:error:"), p.Detail)
+	}
+	return "acme: " + p.Type
+}
+
+// acmeOrder is a certificate order
+type acmeOrder struct {
+	Status         string       `json:"status"`
+	Authorizations []string     `json:"authorizations"`
+	Finalize       string       `json:"finalize"`
+	Certificate    string       `json:"certificate"`
+	Error          *acmeProblem `json:"error"`
+}
+
+// acmeAuthorization is the CA's record of proving control of one host
+type acmeAuthorization struct {
+	Status     string          `json:"status"`
+	Challenges []acmeChallenge `json:"challenges"`
+}
+
+// acmeChallenge is one way of proving control of a host
+type acmeChallenge struct {
+	Type   string       `json:"type"`
+	URL    string       `json:"url"`
+	Token  string       `json:"token"`
+	Status string       `json:"status"`
+	Error  *acmeProblem `json:"error"`
+}
+
+// acmeClient speaks enough of ACME (RFC 8555) to register an account and
+// order certificates with HTTP-01 challenges. Requests are signed with an
+// ES256 account key.
+type acmeClient struct {
+	directoryURL string
+	key          *ecdsa.PrivateKey
+	client       *http.Client
+
+	mu     sync.Mutex
+	dir    *acmeDirectory
+	kid    string // account URL, once registered
+	nonces []string
+}
+
+// newACMEClient creates a client for the CA at directoryURL
+func newACMEClient(directoryURL string, key *ecdsa.PrivateKey, client *http.Client) *acmeClient {
+	return &acmeClient{directoryURL: directoryURL, key: key, client: client}
+}
+
+// directory fetches the CA's endpoints once
+func (c *acmeClient) directory(ctx context.Context) (*acmeDirectory, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dir != nil {
+		return c.dir, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.directoryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("acme: failed to fetch directory: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("acme: directory answered %s", resp.Status)
+	}
+	var dir acmeDirectory
+	if err := json.NewDecoder(io.LimitReader(resp.Body, acmeMaxResponse)).Decode(&dir); err != nil {
+		return nil, fmt.Errorf("acme: invalid directory: %w", err)
+	}
+	if dir.NewNonce == "" || dir.NewAccount == "" || dir.NewOrder == "" {
+		return nil, errors.New("acme: directory is missing newNonce, newAccount or newOrder")
+	}
+	c.dir = &dir
+	return c.dir, nil
+}
+
+// nonce returns an unused anti-replay nonce, fetching one when none is left
+// over from earlier responses
+func (c *acmeClient) nonce(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	if n := len(c.nonces); n > 0 {
+		nonce := c.nonces[n-1]
+		c.nonces = c.nonces[:n-1]
+		c.mu.Unlock()
+		return nonce, nil
+	}
+	c.mu.Unlock()
+
+	dir, err := c.directory(ctx)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, dir.NewNonce, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("acme: failed to fetch nonce: %w", err)
+	}
+	resp.Body.Close()
+	nonce := resp.Header.Get("Replay-Nonce")
+	if nonce == "" {
+		return "", errors.New("acme: no nonce in newNonce response")
+	}
+	return nonce, nil
+}
+
+// keepNonce saves the nonce a response carries for the next request
+func (c *acmeClient) keepNonce(resp *http.Response) {
+	if nonce := resp.Header.Get("Replay-Nonce"); nonce != "" {
+		c.mu.Lock()
+		c.nonces = append(c.nonces, nonce)
+		c.mu.Unlock()
+	}
+}
+
+// jwk is the account public key as a JSON Web Key, with its members in
+// the order RFC 7638 thumbprints need
+func (c *acmeClient) jwk() (string, error) {
+	pub, err := c.key.PublicKey.ECDH()
+	if err != nil {
+		return "", err
+	}
+	point := pub.Bytes() // 0x04 || X || Y
+	return fmt.Sprintf(`{"crv":"P-256","kty":"EC","x":"%s","y":"%s"}`,
+		base64.RawURLEncoding.EncodeToString(point[1:33]),
+		base64.RawURLEncoding.EncodeToString(point[33:65])), nil
+}
+
+// keyAuthorization is what an HTTP-01 challenge serves for token
+func (c *acmeClient) keyAuthorization(token string) (string, error) {
+	jwk, err := c.jwk()
+	if err != nil {
+		return "", err
+	}
+	thumbprint := sha256.Sum256([]byte(jwk))
+	return token + "." + base64.RawURLEncoding.EncodeToString(thumbprint[:]), nil
+}
+
+// signJWS signs payload for url as a flattened JWS. A nil payload makes a
+// POST-as-GET request. Before the account exists the key itself is sent;
+// afterwards the account URL.
+func (c *acmeClient) signJWS(url, nonce string, payload interface{}) ([]byte, error) {
+	protected := map[string]interface{}{"alg": "ES256", "nonce": nonce, "url": url}
+	c.mu.Lock()
+	kid := c.kid
+	c.mu.Unlock()
+	if kid != "" {
+		protected["kid"] = kid
+	} else {
+		jwk, err := c.jwk()
+		if err != nil {
+			return nil, err
+		}
+		protected["jwk"] = json.RawMessage(jwk)
+	}
+	header, err := json.Marshal(protected)
+	if err != nil {
+		return nil, err
+	}
+
+	encodedPayload := ""
+	if payload != nil {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		encodedPayload = base64.RawURLEncoding.EncodeToString(body)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + encodedPayload
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, c.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return json.Marshal(map[string]string{
+		"protected": base64.RawURLEncoding.EncodeToString(header),
+		"payload":   encodedPayload,
+		"signature": base64.RawURLEncoding.EncodeToString(signature),
+	})
+}
+
+// post sends a signed request and returns the response with its body read.
+// A rejected nonce is retried once with a fresh one, as RFC 8555 allows.
+func (c *acmeClient) post(ctx context.Context, url string, payload interface{}, accept string) (*http.Response, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		nonce, err := c.nonce(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		body, err := c.signJWS(url, nonce, payload)
+		if err != nil {
+			return nil, nil, fmt.Errorf("acme: failed to sign request: %w", err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, nil, err
+		}
+		req.Header.Set("Content-Type", "application/jose+json")
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("acme: request to %s failed: %w", url, err)
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, acmeMaxResponse))
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("acme: failed to read response: %w", err)
+		}
+		c.keepNonce(resp)
+
+		if resp.StatusCode < 400 {
+			return resp, data, nil
+		}
+		problem := &acmeProblem{Status: resp.StatusCode}
+		if json.Unmarshal(data, problem) != nil || problem.Type == "" {
+			problem.Type = resp.Status
+		}
+		if problem.Type == "urn:ietf:params:acme:error:badNonce" && attempt == 0 {
+			continue
+		}
+		return resp, data, problem
+	}
+}
+
+// register creates the account, or finds the existing one for the key
+func (c *acmeClient) register(ctx context.Context, email string) error {
+	c.mu.Lock()
+	registered := c.kid != ""
+	c.mu.Unlock()
+	if registered {
+		return nil
+	}
+	dir, err := c.directory(ctx)
+	if err != nil {
+		return err
+	}
+
+	account := map[string]interface{}{"termsOfServiceAgreed": true}
+	if email != "" {
+		account["contact"] = []string{"mailto:" + email}
+	}
+	resp, _, err := c.post(ctx, dir.NewAccount, account, "")
+	if err != nil {
+		return fmt.Errorf("acme: failed to register account: %w", err)
+	}
+	kid := resp.Header.Get("Location")
+	if kid == "" {
+		return errors.New("acme: no account URL in newAccount response")
+	}
+	c.mu.Lock()
+	c.kid = kid
+	c.mu.Unlock()
+	return nil
+}
+
+// obtain orders a certificate for host with the DER certificate request
+// csr and returns its chain, leaf first. serve publishes an HTTP-01 key
+// authorization under its token until the returned func is called.
+func (c *acmeClient) obtain(ctx context.Context, email, host string, csr []byte, serve func(token, keyAuth string) func()) ([][]byte, error) {
+	if err := c.register(ctx, email); err != nil {
+		return nil, err
+	}
+	dir, err := c.directory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, data, err := c.post(ctx, dir.NewOrder, map[string]interface{}{
+		"identifiers": []map[string]string{{"type": "dns", "value": host}},
+	}, "")
+	if err != nil {
+		return nil, fmt.Errorf("acme: failed to create order: %w", err)
+	}
+	orderURL := resp.Header.Get("Location")
+	var order acmeOrder
+	if err := json.Unmarshal(data, &order); err != nil || orderURL == "" {
+		return nil, errors.New("acme: invalid order response")
+	}
+
+	for _, authzURL := range order.Authorizations {
+		if err := c.authorize(ctx, authzURL, serve); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, _, err := c.post(ctx, order.Finalize, map[string]string{
+		"csr": base64.RawURLEncoding.EncodeToString(csr),
+	}, ""); err != nil {
+		return nil, fmt.Errorf("acme: failed to finalize order: %w", err)
+	}
+	err = c.poll(ctx, orderURL, &order, func() (bool, error) {
+		switch order.Status {
+		case "valid":
+			return true, nil
+		case "invalid":
+			if order.Error != nil {
+				return false, order.Error
+			}
+			return false, errors.New("acme: order is invalid")
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, data, err = c.post(ctx, order.Certificate, nil, "application/pem-certificate-chain")
+	if err != nil {
+		return nil, fmt.Errorf("acme: failed to download certificate: %w", err)
+	}
+	var chain [][]byte
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == "CERTIFICATE" {
+			chain = append(chain, block.Bytes)
+		}
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("acme: certificate download holds no certificates")
+	}
+	return chain, nil
+}
+
+// authorize proves control of one host with its HTTP-01 challenge
+func (c *acmeClient) authorize(ctx context.Context, authzURL string, serve func(token, keyAuth string) func()) error {
+	var authz acmeAuthorization
+	if _, data, err := c.post(ctx, authzURL, nil, ""); err != nil {
+		return fmt.Errorf("acme: failed to fetch authorization: %w", err)
+	} else if err := json.Unmarshal(data, &authz); err != nil {
+		return errors.New("acme: invalid authorization response")
+	}
+	if authz.Status == "valid" {
+		return nil
+	}
+
+	var challenge *acmeChallenge
+	for i := range authz.Challenges {
+		if authz.Challenges[i].Type == "http-01" {
+			challenge = &authz.Challenges[i]
+		}
+	}
+	if challenge == nil {
+		return errors.New("acme: the CA offers no http-01 challenge")
+	}
+	keyAuth, err := c.keyAuthorization(challenge.Token)
+	if err != nil {
+		return err
+	}
+	stop := serve(challenge.Token, keyAuth)
+	defer stop()
+
+	if _, _, err := c.post(ctx, challenge.URL, struct{}{}, ""); err != nil {
+		return fmt.Errorf("acme: failed to start challenge: %w", err)
+	}
+	return c.poll(ctx, authzURL, &authz, func() (bool, error) {
+		switch authz.Status {
+		case "valid":
+			return true, nil
+		case "invalid", "deactivated", "expired", "revoked":
+			for _, ch := range authz.Challenges {
+				if ch.Error != nil {
+					return false, ch.Error
+				}
+			}
+			return false, fmt.Errorf("acme: authorization is %s", authz.Status)
+		}
+		return false, nil
+	})
+}
+
+// poll fetches url into target until done reports true, waiting as long as
+// the CA's Retry-After asks, up to 10 seconds a time
+func (c *acmeClient) poll(ctx context.Context, url string, target interface{}, done func() (bool, error)) error {
+	for {
+		resp, data, err := c.post(ctx, url, nil, "")
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, target); err != nil {
+			return fmt.Errorf("acme: invalid response from %s", url)
+		}
+		if ok, err := done(); ok || err != nil {
+			return err
+		}
+
+		wait := time.Second
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			wait = min(time.Duration(seconds)*time.Second, 10*time.Second)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("acme: gave up waiting for %s: %w", url, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+}
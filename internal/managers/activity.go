@@ -0,0 +1,136 @@
+package managers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// activityFilename is the append-only activity log, one JSON entry a line
+const activityFilename = "activity.jsonl"
+
+// maxActivityLine bounds a single line of the activity log
+const maxActivityLine = 64 * 1024
+
+// maxActivityDescription keeps each entry well under maxActivityLine
+const maxActivityDescription = 4096
+
+// ActivityLogger records admin actions in activity.jsonl
+type ActivityLogger struct {
+	storage *FileStorage
+	mu      sync.Mutex
+}
+
+// NewActivityLogger creates an activity logger writing into storage
+func NewActivityLogger(storage *FileStorage) *ActivityLogger {
+	return &ActivityLogger{storage: storage}
+}
+
+// Log appends entry to the activity log
+func (al *ActivityLogger) Log(entry types.ActivityEntry) error {
+	if len(entry.Description) > maxActivityDescription {
+		entry.Description = strings.ToValidUTF8(entry.Description[:maxActivityDescription], "") + "…"
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode activity: %w", err)
+	}
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	return al.storage.AppendFile(activityFilename, append(line, '\n'))
+}
+
+// List returns every logged entry, newest first. Lines that cannot be read,
+// such as one cut short by a crash, are skipped.
+func (al *ActivityLogger) List() ([]types.ActivityEntry, error) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	file, err := os.Open(al.storage.GetFilePath(activityFilename))
+	if os.IsNotExist(err) {
+		return []types.ActivityEntry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open activity log: %w", err)
+	}
+	defer file.Close()
+
+	entries := []types.ActivityEntry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 4096), maxActivityLine)
+	for scanner.Scan() {
+		var entry types.ActivityEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read activity log: %w", err)
+	}
+	slices.Reverse(entries)
+	return entries, nil
+}
+
+// Recent returns the latest n entries, newest first
+func (al *ActivityLogger) Recent(n int) ([]types.ActivityEntry, error) {
+	entries, err := al.List()
+	if err != nil {
+		return nil, err
+	}
+	return entries[:min(n, len(entries))], nil
+}
+
+// Prune removes the entries logged before cutoff, along with unreadable
+// lines, and returns how many entries it removed. The log is rewritten
+// only when something was removed.
+func (al *ActivityLogger) Prune(cutoff time.Time) (int, error) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	file, err := os.Open(al.storage.GetFilePath(activityFilename))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open activity log: %w", err)
+	}
+	defer file.Close()
+
+	var kept bytes.Buffer
+	removed, dropped := 0, false
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 4096), maxActivityLine)
+	for scanner.Scan() {
+		var entry types.ActivityEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			dropped = true
+			continue
+		}
+		if entry.Time.Before(cutoff) {
+			removed++
+			continue
+		}
+		kept.Write(scanner.Bytes())
+		kept.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read activity log: %w", err)
+	}
+	if removed == 0 && !dropped {
+		return 0, nil
+	}
+	if err := al.storage.WriteBinaryFile(activityFilename, kept.Bytes()); err != nil {
+		return 0, fmt.Errorf("failed to prune activity log: %w", err)
+	}
+	return removed, nil
+}
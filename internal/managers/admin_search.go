@@ -0,0 +1,169 @@
+package managers
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"onepagems/internal/types"
+)
+
+// Kinds of admin search hits
+const (
+	SearchHitContent = "content"
+	SearchHitSchema  = "schema"
+	SearchHitImage   = "image"
+	SearchHitSnippet = "snippet"
+)
+
+// searchContextLength is how much text an excerpt keeps on each side of
+// the match
+const searchContextLength = 60
+
+// AdminSearchHit is one place the admin search text was found. Path is the
+// dotted content or schema path (e.g. "sections.team.members[2].bio"), the
+// image filename or the custom code slot; Match names what matched, such as
+// "value", "title" or "alt_text". Link opens the editor at the hit.
+type AdminSearchHit struct {
+	Type    string `json:"type"`
+	Path    string `json:"path"`
+	Match   string `json:"match"`
+	Excerpt string `json:"excerpt"`
+	Link    string `json:"link"`
+}
+
+// SearchAdminData finds query, ignoring case, in content values, schema
+// titles and descriptions, image filenames and alt text, and the custom code
+// snippets. HTML in content is matched as plain text. Hits are listed in
+// that order, content and schema hits by path. A nil code leaves snippets
+// out.
+func SearchAdminData(query string, schema *types.SchemaData, content *types.ContentData, images []types.ImageInfo, code *types.CustomCode) []AdminSearchHit {
+	needle := strings.ToLower(strings.TrimSpace(query))
+	hits := make([]AdminSearchHit, 0)
+	if needle == "" {
+		return hits
+	}
+
+	if content != nil {
+		contentMap := map[string]interface{}{
+			"title":       content.Title,
+			"description": content.Description,
+			"sections":    content.Sections,
+		}
+		hits = searchContent(contentMap, "", needle, hits)
+	}
+	if schema != nil {
+		hits = searchSchema(schema.Properties, "", needle, hits)
+	}
+	for _, image := range images {
+		for _, field := range []struct{ name, text string }{
+			{"filename", image.Filename},
+			{"original_name", image.OriginalName},
+			{"alt_text", image.AltText},
+		} {
+			if excerpt, ok := searchExcerpt(field.text, needle); ok {
+				hits = append(hits, AdminSearchHit{Type: SearchHitImage, Path: image.Filename, Match: field.name, Excerpt: excerpt, Link: "/admin/images"})
+			}
+		}
+	}
+	if code != nil {
+		for _, slot := range []struct{ name, html string }{
+			{"head_html", code.HeadHTML},
+			{"body_end_html", code.BodyEndHTML},
+		} {
+			if excerpt, ok := searchExcerpt(slot.html, needle); ok {
+				hits = append(hits, AdminSearchHit{Type: SearchHitSnippet, Path: slot.name, Match: "code", Excerpt: excerpt, Link: "/admin/custom-code"})
+			}
+		}
+	}
+	return hits
+}
+
+// searchContent adds a hit for every string value under value that
+// contains needle
+func searchContent(value interface{}, path, needle string, hits []AdminSearchHit) []AdminSearchHit {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			hits = searchContent(v[key], childPath, needle, hits)
+		}
+	case []interface{}:
+		for i, item := range v {
+			hits = searchContent(item, fmt.Sprintf("%s[%d]", path, i), needle, hits)
+		}
+	case string:
+		if excerpt, ok := searchExcerpt(plainText(v), needle); ok {
+			hits = append(hits, AdminSearchHit{Type: SearchHitContent, Path: path, Match: "value", Excerpt: excerpt, Link: "/admin/content#" + path})
+		}
+	}
+	return hits
+}
+
+// searchSchema adds a hit for every property title or description that
+// contains needle, descending into objects and array items
+func searchSchema(properties map[string]interface{}, path, needle string, hits []AdminSearchHit) []AdminSearchHit {
+	for _, name := range sortedKeys(properties) {
+		prop, ok := properties[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		propPath := name
+		if path != "" {
+			propPath = path + "." + name
+		}
+		hits = searchSchemaProperty(prop, propPath, needle, hits)
+	}
+	return hits
+}
+
+// searchSchemaProperty matches one property and the schemas below it
+func searchSchemaProperty(prop map[string]interface{}, path, needle string, hits []AdminSearchHit) []AdminSearchHit {
+	for _, keyword := range []string{"title", "description"} {
+		text, _ := prop[keyword].(string)
+		if excerpt, ok := searchExcerpt(text, needle); ok {
+			hits = append(hits, AdminSearchHit{Type: SearchHitSchema, Path: path, Match: keyword, Excerpt: excerpt, Link: "/admin/content#" + path})
+		}
+	}
+	if nested, ok := prop["properties"].(map[string]interface{}); ok {
+		hits = searchSchema(nested, path, needle, hits)
+	}
+	if items, ok := prop["items"].(map[string]interface{}); ok {
+		hits = searchSchemaProperty(items, path+"[]", needle, hits)
+	}
+	return hits
+}
+
+// searchExcerpt reports whether text contains needle, which is lower case,
+// and returns the text around the first match
+func searchExcerpt(text, needle string) (string, bool) {
+	index := strings.Index(strings.ToLower(text), needle)
+	if index < 0 {
+		return "", false
+	}
+	// Lower-casing can change byte lengths; fall back to the start of the
+	// text when the offsets no longer line up
+	if len(strings.ToLower(text)) != len(text) {
+		return truncateText(text, 2*searchContextLength), true
+	}
+
+	start := max(0, index-searchContextLength)
+	end := min(len(text), index+len(needle)+searchContextLength)
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+	excerpt := strings.TrimSpace(text[start:end])
+	if start > 0 {
+		excerpt = "…" + excerpt
+	}
+	if end < len(text) {
+		excerpt += "…"
+	}
+	return excerpt, true
+}
@@ -0,0 +1,96 @@
+package managers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"onepagems/internal/httpclient"
+	"onepagems/internal/tracing"
+)
+
+// AltTextSuggester proposes alt text for an image
+type AltTextSuggester interface {
+	SuggestAltText(ctx context.Context, image []byte, contentType string) (string, error)
+}
+
+// HTTPAltTextSuggester calls a vision provider over HTTP. The provider
+// receives {"image": <base64>, "content_type": ...} and must answer
+// with {"alt_text": "..."}.
+type HTTPAltTextSuggester struct {
+	endpoint string
+	apiKey   string
+	keyFunc  func() string
+	client   *http.Client
+}
+
+// NewHTTPAltTextSuggester creates a suggester for the given provider
+// endpoint, calling it through clients
+func NewHTTPAltTextSuggester(endpoint, apiKey string, clients *httpclient.Clients) *HTTPAltTextSuggester {
+	return &HTTPAltTextSuggester{
+		endpoint: endpoint,
+		apiKey:   apiKey,
+		client:   clients.New(15 * time.Second),
+	}
+}
+
+// SetAPIKeyFunc sets where the API key is looked up when none was given,
+// such as the secrets store, so a key saved later is picked up
+func (s *HTTPAltTextSuggester) SetAPIKeyFunc(keyFunc func() string) {
+	s.keyFunc = keyFunc
+}
+
+// SuggestAltText sends the image to the provider and returns its proposal
+func (s *HTTPAltTextSuggester) SuggestAltText(ctx context.Context, image []byte, contentType string) (string, error) {
+	payload, err := json.Marshal(map[string]string{
+		"image":        base64.StdEncoding.EncodeToString(image),
+		"content_type": contentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	ctx, span := tracing.StartKind(ctx, "alt_text.suggest", tracing.KindClient)
+	defer span.End()
+	span.SetAttribute("server.address", req.URL.Host)
+	tracing.Inject(ctx, req.Header)
+
+	req.Header.Set("Content-Type", "application/json")
+	apiKey := s.apiKey
+	if apiKey == "" && s.keyFunc != nil {
+		apiKey = s.keyFunc()
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		return "", fmt.Errorf("provider request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("provider returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		AltText string `json:"alt_text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse provider response: %w", err)
+	}
+
+	return result.AltText, nil
+}
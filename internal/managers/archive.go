@@ -0,0 +1,394 @@
+package managers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// archiveDataFiles are the data files a site archive carries. Secrets are
+// left out since they only decrypt with this server's key.
+var archiveDataFiles = []string{
+	"content.json",
+	contentDraftFilename,
+	"schema.json",
+	"template.html",
+	templateSettingsFilename,
+	"images.json",
+	customCodeFilename,
+	slugsFilename,
+	archivedSectionsFilename,
+	variantsFilename,
+	schedulesFilename,
+	sectionRenderersFilename,
+}
+
+// archiveImagesDir holds uploaded images and their variants
+const archiveImagesDir = "images"
+
+// ArchiveRestoreResult summarizes a restored archive
+type ArchiveRestoreResult struct {
+	Restored int      `json:"restored"`
+	Failed   int      `json:"failed"`
+	Skipped  []string `json:"skipped,omitempty"`
+
+	// Data migrations applied to an archive from an older layout, and the
+	// backup of the restored files taken before them
+	Migrations      []int  `json:"migrations,omitempty"`
+	MigrationBackup string `json:"migration_backup,omitempty"`
+}
+
+// WriteArchive writes the site's data files and images as a zip archive
+func WriteArchive(storage *FileStorage, w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	names := make([]string, 0, len(archiveDataFiles)+1)
+	for _, name := range append([]string{DataVersionFilename}, archiveDataFiles...) {
+		if storage.FileExists(name) {
+			names = append(names, name)
+		}
+	}
+
+	root := storage.GetFilePath("")
+	imagesRoot := storage.GetFilePath(archiveImagesDir)
+	err := filepath.WalkDir(imagesRoot, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if filePath == imagesRoot && os.IsNotExist(err) {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if entry.Type().IsRegular() && !strings.HasSuffix(filePath, ".tmp") {
+			rel, err := filepath.Rel(root, filePath)
+			if err != nil {
+				return err
+			}
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list images: %w", err)
+	}
+
+	for _, name := range names {
+		if err := addArchiveFile(archive, storage.GetFilePath(name), name); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", name, err)
+		}
+	}
+	return archive.Close()
+}
+
+// addArchiveFile copies one file into the archive
+func addArchiveFile(archive *zip.Writer, source, name string) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+
+	writer, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, file)
+	return err
+}
+
+// DownloadArchive fetches a remote archive into dest, reporting bytes
+// received as the current step. Archives larger than maxSize are refused.
+func DownloadArchive(ctx context.Context, client *http.Client, url, dest string, maxSize int64, progress *JobProgress) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("invalid archive URL: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed: server returned status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxSize {
+		return fmt.Errorf("archive is %d bytes, over the %d byte limit", resp.ContentLength, maxSize)
+	}
+
+	progress.Step("download", max(resp.ContentLength, 0))
+	file, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	written, err := io.Copy(file, &progressReader{reader: io.LimitReader(resp.Body, maxSize+1), progress: progress})
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+	if written > maxSize {
+		return fmt.Errorf("archive is over the %d byte limit", maxSize)
+	}
+	return file.Close()
+}
+
+// progressReader reports bytes read to a job
+type progressReader struct {
+	reader   io.Reader
+	progress *JobProgress
+}
+
+// Read passes reads through, counting them as done
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.progress.Add(int64(n))
+	}
+	return n, err
+}
+
+// RestoreArchive restores a site archive into the data directory in two
+// steps: checking every entry, then writing files. A file that fails is
+// recorded as an item error and the rest are still restored. Images are
+// written before the data files that refer to them, and only in the formats
+// uploads accept. Replaced data files keep a .bak of their previous
+// version. An archive from an older data layout is migrated afterwards.
+func RestoreArchive(storage *FileStorage, archivePath string, maxEntrySize int64, progress *JobProgress) (*ArchiveRestoreResult, error) {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("not a zip archive: %w", err)
+	}
+	defer archive.Close()
+
+	result := &ArchiveRestoreResult{}
+	progress.Step("verify", int64(len(archive.File)))
+
+	// Archives without a version marker predate versioning
+	version := 0
+
+	prefix := archivePrefix(archive.File)
+	var entries []*zip.File
+	names := make(map[*zip.File]string)
+	for _, entry := range archive.File {
+		progress.Add(1)
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+		name, ok := archiveEntryName(entry.Name, prefix)
+		if !ok {
+			result.Skipped = append(result.Skipped, entry.Name)
+			continue
+		}
+		if entry.UncompressedSize64 > uint64(maxEntrySize) {
+			progress.ItemError(name, fmt.Errorf("file is over the %d byte limit", maxEntrySize))
+			result.Failed++
+			continue
+		}
+		if name == DataVersionFilename {
+			if version, err = checkArchiveVersion(entry); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		names[entry] = name
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("archive contains no site files")
+	}
+
+	// Images first, so restored data never points at missing files
+	sort.SliceStable(entries, func(i, j int) bool {
+		return archiveRestoreOrder(names[entries[i]]) < archiveRestoreOrder(names[entries[j]])
+	})
+
+	progress.Step("restore", int64(len(entries)))
+	for _, entry := range entries {
+		name := names[entry]
+		if err := restoreArchiveEntry(storage, entry, name, maxEntrySize); err != nil {
+			progress.ItemError(name, err)
+			result.Failed++
+		} else {
+			result.Restored++
+		}
+		progress.Add(1)
+	}
+
+	if version < CurrentDataVersion() {
+		if err := migrateRestoredArchive(storage, version, result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// migrateRestoredArchive upgrades files restored from an archive of an older
+// data layout, as startup upgrades an old data directory
+func migrateRestoredArchive(storage *FileStorage, version int, result *ArchiveRestoreResult) error {
+	runner := NewMigrationRunner(storage)
+	// The marker still holds the version of the files that were replaced
+	if err := runner.writeVersion(version); err != nil {
+		return err
+	}
+	applied, backup, err := runner.Run()
+	for _, migration := range applied {
+		result.Migrations = append(result.Migrations, migration.Version)
+	}
+	result.MigrationBackup = backup
+	if err != nil {
+		return fmt.Errorf("failed to migrate the restored files from data version %d: %w", version, err)
+	}
+	return nil
+}
+
+// restoreArchiveEntry writes one archive entry into the data directory
+func restoreArchiveEntry(storage *FileStorage, entry *zip.File, name string, maxEntrySize int64) error {
+	reader, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxEntrySize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > maxEntrySize {
+		return fmt.Errorf("file is over the %d byte limit", maxEntrySize)
+	}
+
+	if strings.HasPrefix(name, archiveImagesDir+"/") {
+		// /images/ is served from the admin's origin, so only what an
+		// upload would accept is restored
+		if contentType := http.DetectContentType(data); allowedImageTypes[contentType] != imageExtension(name) {
+			return fmt.Errorf("not a %s image (detected %s)", strings.TrimPrefix(imageExtension(name), "."), contentType)
+		}
+		return storage.WriteBinaryFile(name, data)
+	}
+	if path.Ext(name) == ".json" && !json.Valid(data) {
+		return fmt.Errorf("file is not valid JSON")
+	}
+	return storage.WriteTextFile(name, string(data))
+}
+
+// archivePrefix returns the folder every entry is inside, as when a data
+// directory is zipped by name, or "" if entries are at the top level
+func archivePrefix(files []*zip.File) string {
+	prefix := ""
+	for i, file := range files {
+		first, _, found := strings.Cut(file.Name, "/")
+		if !found {
+			return ""
+		}
+		if i == 0 {
+			prefix = first + "/"
+		} else if first+"/" != prefix {
+			return ""
+		}
+	}
+	if prefix == archiveImagesDir+"/" {
+		return ""
+	}
+	for _, name := range archiveDataFiles {
+		for _, file := range files {
+			if file.Name == name {
+				return ""
+			}
+		}
+	}
+	return prefix
+}
+
+// archiveEntryName returns the data directory path of an entry, and false
+// for entries that are not site files or would escape the data directory
+func archiveEntryName(name, prefix string) (string, bool) {
+	name = strings.TrimPrefix(name, prefix)
+	if name == "" || strings.Contains(name, `\`) || strings.HasPrefix(name, "/") {
+		return "", false
+	}
+	cleaned := path.Clean(name)
+	if cleaned != name || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", false
+	}
+
+	if cleaned == DataVersionFilename {
+		return cleaned, true
+	}
+	for _, allowed := range archiveDataFiles {
+		if cleaned == allowed {
+			return cleaned, true
+		}
+	}
+	if strings.HasPrefix(cleaned, archiveImagesDir+"/") && imageExtension(cleaned) != "" {
+		return cleaned, true
+	}
+	return "", false
+}
+
+// imageExtension returns the extension of an image file name if it is one
+// uploads are stored with, or ""
+func imageExtension(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	for _, allowed := range allowedImageTypes {
+		if ext == allowed {
+			return ext
+		}
+	}
+	return ""
+}
+
+// archiveRestoreOrder sorts images before image metadata before the rest
+func archiveRestoreOrder(name string) int {
+	switch {
+	case strings.HasPrefix(name, archiveImagesDir+"/"):
+		return 0
+	case name == "images.json":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// checkArchiveVersion returns the data layout version of an archive,
+// refusing archives from a newer layout
+func checkArchiveVersion(entry *zip.File) (int, error) {
+	reader, err := entry.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, 64))
+	if err != nil {
+		return 0, err
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("archive has an invalid data version")
+	}
+	if version > CurrentDataVersion() {
+		return 0, fmt.Errorf("archive is from data version %d but this build supports up to %d; upgrade OnePage CMS first", version, CurrentDataVersion())
+	}
+	return version, nil
+}
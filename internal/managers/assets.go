@@ -0,0 +1,190 @@
+package managers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Asset modes control how inline <style> and <script> blocks are emitted
+const (
+	// AssetModeInline keeps blocks inline and allows them by hash in the CSP
+	AssetModeInline = "inline"
+	// AssetModeExternal moves blocks into files under assets/
+	AssetModeExternal = "external"
+)
+
+var (
+	styleBlockPattern  = regexp.MustCompile(`(?is)<style\b([^>]*)>(.*?)</style>`)
+	scriptBlockPattern = regexp.MustCompile(`(?is)<script\b([^>]*)>(.*?)</script>`)
+	styleAttrPattern   = regexp.MustCompile(`(?i)\sstyle\s*=\s*"([^"]*)"`)
+	srcAttrPattern     = regexp.MustCompile(`(?i)\ssrc\s*=`)
+	srcValuePattern    = regexp.MustCompile(`(?i)\ssrc\s*=\s*"([^"]*)"`)
+	typeAttrPattern    = regexp.MustCompile(`(?i)\stype\s*=\s*"([^"]*)"`)
+	embedSrcPattern    = regexp.MustCompile(`(?i)\sdata-embed-src\s*=\s*"([^"]*)"`)
+)
+
+// ProcessedAssets is the result of running generated HTML through the asset pipeline
+type ProcessedAssets struct {
+	HTML   string
+	Policy string
+	Files  map[string]string // assets/ path -> contents, external mode only
+}
+
+// ValidAssetMode reports whether mode is a supported asset mode
+func ValidAssetMode(mode string) bool {
+	return mode == AssetModeInline || mode == AssetModeExternal
+}
+
+// ProcessAssets rewrites inline styles and scripts according to mode and
+// builds a Content-Security-Policy that allows exactly what the page uses.
+// imageOrigin, when set, is added to img-src (e.g. an image CDN).
+func ProcessAssets(html, mode, imageOrigin string) (*ProcessedAssets, error) {
+	if !ValidAssetMode(mode) {
+		return nil, fmt.Errorf("unknown asset mode '%s'", mode)
+	}
+
+	result := &ProcessedAssets{Files: make(map[string]string)}
+	var styleHashes, scriptHashes, scriptOrigins []string
+
+	html = styleBlockPattern.ReplaceAllStringFunc(html, func(block string) string {
+		body := styleBlockPattern.FindStringSubmatch(block)[2]
+		if mode == AssetModeExternal {
+			name := result.addFile("style", ".css", body)
+			return `<link rel="stylesheet" href="/` + name + `">`
+		}
+		styleHashes = append(styleHashes, cspHash(body))
+		return block
+	})
+
+	html = scriptBlockPattern.ReplaceAllStringFunc(html, func(block string) string {
+		match := scriptBlockPattern.FindStringSubmatch(block)
+		attrs, body := match[1], match[2]
+		// External scripts and data blocks (e.g. JSON-LD) are not subject to
+		// hashing; scripts from other hosts are allowed by origin
+		if srcAttrPattern.MatchString(attrs) {
+			if src := srcValuePattern.FindStringSubmatch(attrs); src != nil {
+				scriptOrigins = append(scriptOrigins, originOf(unescapeAttr(src[1]))...)
+			}
+			return block
+		}
+		if !isJavaScriptType(attrs) {
+			return block
+		}
+		if mode == AssetModeExternal {
+			name := result.addFile("script", ".js", body)
+			return `<script` + attrs + ` src="/` + name + `"></script>`
+		}
+		scriptHashes = append(scriptHashes, cspHash(body))
+		return block
+	})
+
+	// style="" attributes cannot be moved to files; CSP allows them by hash
+	// only together with 'unsafe-hashes'
+	var attrHashes []string
+	for _, match := range styleAttrPattern.FindAllStringSubmatch(html, -1) {
+		attrHashes = append(attrHashes, cspHash(unescapeAttr(match[1])))
+	}
+	if len(attrHashes) > 0 {
+		styleHashes = append(append(styleHashes, "'unsafe-hashes'"), attrHashes...)
+	}
+
+	// Click-to-load embeds need their providers in frame-src
+	var frameOrigins []string
+	for _, match := range embedSrcPattern.FindAllStringSubmatch(html, -1) {
+		frameOrigins = append(frameOrigins, originOf(unescapeAttr(match[1]))...)
+	}
+
+	result.HTML = html
+	result.Policy = buildPolicy(styleHashes, scriptHashes, imageOrigin, frameOrigins, scriptOrigins)
+	return result, nil
+}
+
+// addFile records an extracted asset under a content-addressed name
+func (p *ProcessedAssets) addFile(prefix, ext, body string) string {
+	sum := sha256.Sum256([]byte(body))
+	name := path.Join("assets", prefix+"-"+hex.EncodeToString(sum[:])[:16]+ext)
+	p.Files[name] = body
+	return name
+}
+
+// buildPolicy assembles the Content-Security-Policy header value. Hosts of
+// external scripts (e.g. an analytics snippet in the custom code slots) are
+// allowed in script-src and connect-src.
+func buildPolicy(styleHashes, scriptHashes []string, imageOrigin string, frameOrigins, scriptOrigins []string) string {
+	scriptOrigins = uniqueSorted(scriptOrigins)
+	directives := []string{
+		"default-src 'self'",
+		"img-src " + strings.Join(append([]string{"'self'", "data:"}, originOf(imageOrigin)...), " "),
+		"style-src " + strings.Join(append([]string{"'self'"}, uniqueSorted(styleHashes)...), " "),
+		"script-src " + strings.Join(append(append([]string{"'self'"}, scriptOrigins...), uniqueSorted(scriptHashes)...), " "),
+		"object-src 'none'",
+		"base-uri 'self'",
+	}
+	if len(scriptOrigins) > 0 {
+		directives = append(directives, "connect-src "+strings.Join(append([]string{"'self'"}, scriptOrigins...), " "))
+	}
+	if len(frameOrigins) > 0 {
+		directives = append(directives, "frame-src "+strings.Join(uniqueSorted(frameOrigins), " "))
+	}
+	return strings.Join(directives, "; ")
+}
+
+// cspHash returns the CSP source expression for an inline block
+func cspHash(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+}
+
+// isJavaScriptType reports whether a script tag's type attribute is executable
+func isJavaScriptType(attrs string) bool {
+	match := typeAttrPattern.FindStringSubmatch(attrs)
+	if match == nil {
+		return true
+	}
+	scriptType := strings.ToLower(strings.TrimSpace(match[1]))
+	return scriptType == "" || scriptType == "module" || strings.Contains(scriptType, "javascript")
+}
+
+// originOf returns the scheme and host of rawURL, or nothing if it is empty or invalid
+func originOf(rawURL string) []string {
+	if rawURL == "" {
+		return nil
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return nil
+	}
+	return []string{parsed.Scheme + "://" + parsed.Host}
+}
+
+// unescapeAttr reverses the entity escaping html/template applies to attributes,
+// since browsers hash the decoded value
+func unescapeAttr(value string) string {
+	return strings.NewReplacer("&amp;", "&", "&#39;", "'", "&#34;", `"`, "&quot;", `"`, "&lt;", "<", "&gt;", ">").Replace(value)
+}
+
+// uniqueSorted de-duplicates values, keeping 'unsafe-hashes' first
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool)
+	var keywords, hashes []string
+	for _, v := range values {
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		if strings.HasPrefix(v, "'sha256-") {
+			hashes = append(hashes, v)
+		} else {
+			keywords = append(keywords, v)
+		}
+	}
+	sort.Strings(hashes)
+	return append(keywords, hashes...)
+}
@@ -2,18 +2,42 @@ package managers
 
 import (
 	"crypto/rand"
-	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
 	"onepagems/internal/types"
+	"sync"
 	"time"
 )
 
+// maxRecentFailedLogins is how many rejected sign-ins are kept in detail
+const maxRecentFailedLogins = 20
+
+// ErrPasswordNotSaved is returned by ChangePassword when the new password
+// could not be written to the credential store; the old one stays in effect
+var ErrPasswordNotSaved = errors.New("failed to save the new password")
+
+// ErrSessionLocked is returned for a session left unused for longer than
+// the idle timeout. The session stays valid; Reauthenticate unlocks it.
+var ErrSessionLocked = errors.New("session is locked after inactivity")
+
 // AuthManager handles authentication and session management
 type AuthManager struct {
-	sessions map[string]*types.Session
-	config   *types.Config
+	config *types.Config
+	hasher PasswordHasher
+
+	passwordMu  sync.Mutex      // guards config.AdminPassword
+	credentials CredentialStore // nil keeps password changes in memory only
+
+	mu       sync.Mutex
+	sessions map[string]*types.Session // by SessionKey of the session ID
+	store    SessionStore              // nil keeps sessions in memory only
+	dirty    bool                      // expiry extended since the last save
+
+	failedMu sync.Mutex
+	failed   types.FailedLoginReport // since the last successful login
 }
 
 // NewAuthManager creates a new authentication manager
@@ -21,53 +45,255 @@ func NewAuthManager(config *types.Config) *AuthManager {
 	return &AuthManager{
 		sessions: make(map[string]*types.Session),
 		config:   config,
+		hasher:   NewPasswordHasher(),
+	}
+}
+
+// SetPasswordHasher replaces the default PBKDF2 password hasher. hasher
+// must verify the hash already in the config; hashes it reports as needing
+// a rehash are replaced on the next successful sign-in.
+func (am *AuthManager) SetPasswordHasher(hasher PasswordHasher) {
+	am.passwordMu.Lock()
+	defer am.passwordMu.Unlock()
+	am.hasher = hasher
+}
+
+// SetCredentialStore saves password changes in store so they survive
+// restarts. LoadCredentials reads them back.
+func (am *AuthManager) SetCredentialStore(store CredentialStore) {
+	am.passwordMu.Lock()
+	defer am.passwordMu.Unlock()
+	am.credentials = store
+}
+
+// LoadCredentials replaces the configured password hash with the one in
+// the credential store, if any, and reports whether it did
+func (am *AuthManager) LoadCredentials() (bool, error) {
+	am.passwordMu.Lock()
+	defer am.passwordMu.Unlock()
+
+	if am.credentials == nil {
+		return false, nil
+	}
+	hash, err := am.credentials.LoadPasswordHash()
+	if err != nil || hash == "" {
+		return false, err
+	}
+	am.config.AdminPassword = hash
+	return true, nil
+}
+
+// savePasswordHash writes hash to the credential store; callers hold
+// passwordMu
+func (am *AuthManager) savePasswordHash(hash string) error {
+	if am.credentials == nil {
+		return nil
+	}
+	return am.credentials.SavePasswordHash(hash)
+}
+
+// SetSessionStore keeps sessions in store so they survive restarts.
+// LoadSessions reads them back.
+func (am *AuthManager) SetSessionStore(store SessionStore) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.store = store
+}
+
+// LoadSessions restores the sessions saved in the session store, keeping
+// any created since startup
+func (am *AuthManager) LoadSessions() error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if am.store == nil {
+		return nil
 	}
+	sessions, err := am.store.Load()
+	if err != nil {
+		return err
+	}
+	for key, session := range sessions {
+		if _, exists := am.sessions[key]; !exists {
+			am.sessions[key] = session
+		}
+	}
+	return nil
+}
+
+// FlushSessions drops expired sessions and saves any expiry extensions not
+// yet in the session store. It is called periodically and on shutdown.
+func (am *AuthManager) FlushSessions() error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	am.cleanupExpiredSessions()
+	if !am.dirty {
+		return nil
+	}
+	return am.saveSessions()
+}
+
+// saveSessions writes the sessions to the store; callers hold mu
+func (am *AuthManager) saveSessions() error {
+	if am.store == nil {
+		return nil
+	}
+	if err := am.store.Save(am.sessions); err != nil {
+		return err
+	}
+	am.dirty = false
+	return nil
 }
 
 // Login authenticates a user and creates a session
 func (am *AuthManager) Login(username, password string) (*types.Session, error) {
-	// Hash the provided password
-	hashedPassword := am.hashPassword(password)
-
-	// Check against configured credentials
-	if username != am.config.AdminUsername || hashedPassword != am.config.AdminPassword {
+	if !am.VerifyPassword(username, password) {
 		return nil, fmt.Errorf("invalid credentials")
 	}
 
+	return am.StartSession(username)
+}
+
+// VerifyPassword reports whether username and password are the admin's,
+// without creating a session
+func (am *AuthManager) VerifyPassword(username, password string) bool {
+	// The password is checked even for an unknown username, so both fail
+	// in the same time
+	validUser := subtle.ConstantTimeCompare([]byte(username), []byte(am.config.AdminUsername)) == 1
+	return am.checkPassword(password) && validUser
+}
+
+// checkPassword reports whether password is the admin password. A stored
+// hash the hasher no longer produces, such as a legacy SHA-256 digest, is
+// replaced with a fresh one once the password has been confirmed.
+func (am *AuthManager) checkPassword(password string) bool {
+	am.passwordMu.Lock()
+	defer am.passwordMu.Unlock()
+
+	stored := am.config.AdminPassword
+	if !am.hasher.Verify(password, stored) {
+		return false
+	}
+	if am.hasher.NeedsRehash(stored) {
+		// Keeping the old hash only delays the upgrade to the next sign-in
+		if hash, err := am.hasher.Hash(password); err == nil && am.savePasswordHash(hash) == nil {
+			am.config.AdminPassword = hash
+		}
+	}
+	return true
+}
+
+// StartSession creates a session for a user who has been authenticated some
+// other way, such as with a recovery code
+func (am *AuthManager) StartSession(username string) (*types.Session, error) {
 	// Create new session
 	sessionID, err := am.generateSessionID()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate session ID: %w", err)
 	}
 
+	now := time.Now()
 	session := &types.Session{
-		ID:        sessionID,
-		Username:  username,
-		CreatedAt: time.Now(),
-		ExpiresAt: time.Now().Add(24 * time.Hour), // 24 hour sessions
-		IsActive:  true,
+		ID:              sessionID,
+		Username:        username,
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(24 * time.Hour), // 24 hour sessions
+		IsActive:        true,
+		LastSeenAt:      now,
+		AuthenticatedAt: now,
+	}
+	if report := am.takeFailedLogins(); report.Count > 0 {
+		session.FailedLoginsSinceLast = &report
 	}
 
-	am.sessions[sessionID] = session
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.sessions[SessionKey(sessionID)] = session
+	if err := am.saveSessions(); err != nil {
+		delete(am.sessions, SessionKey(sessionID))
+		return nil, err
+	}
 	return session, nil
 }
 
+// RecordFailedLogin notes a rejected sign-in from ip
+func (am *AuthManager) RecordFailedLogin(username, ip string) {
+	am.failedMu.Lock()
+	defer am.failedMu.Unlock()
+
+	now := time.Now()
+	if am.failed.Count == 0 {
+		am.failed.FirstAttempt = now
+	}
+	am.failed.Count++
+	am.failed.LastIP = ip
+	am.failed.LastAttempt = now
+	am.failed.Recent = append(am.failed.Recent, types.FailedLogin{Username: username, IP: ip, At: now})
+	if len(am.failed.Recent) > maxRecentFailedLogins {
+		am.failed.Recent = am.failed.Recent[len(am.failed.Recent)-maxRecentFailedLogins:]
+	}
+}
+
+// FailedLogins returns the rejected sign-ins since the last successful login
+func (am *AuthManager) FailedLogins() types.FailedLoginReport {
+	am.failedMu.Lock()
+	defer am.failedMu.Unlock()
+
+	report := am.failed
+	report.Recent = append([]types.FailedLogin{}, am.failed.Recent...)
+	return report
+}
+
+// takeFailedLogins returns the rejected sign-ins and starts counting again
+func (am *AuthManager) takeFailedLogins() types.FailedLoginReport {
+	am.failedMu.Lock()
+	defer am.failedMu.Unlock()
+
+	report := am.failed
+	am.failed = types.FailedLoginReport{}
+	return report
+}
+
 // Logout invalidates a session
 func (am *AuthManager) Logout(sessionID string) error {
-	if session, exists := am.sessions[sessionID]; exists {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	key := SessionKey(sessionID)
+	if session, exists := am.sessions[key]; exists {
 		session.IsActive = false
-		delete(am.sessions, sessionID)
-		return nil
+		delete(am.sessions, key)
+		return am.saveSessions()
 	}
 	return fmt.Errorf("session not found")
 }
 
-// ValidateSession checks if a session is valid and active
+// HasSession reports whether sessionID names an active, unexpired session,
+// locked or not. Unlike ValidateSession it does not count as activity.
+func (am *AuthManager) HasSession(sessionID string) bool {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	session, exists := am.sessions[SessionKey(sessionID)]
+	return exists && session.IsActive && time.Now().Before(session.ExpiresAt)
+}
+
+// ValidateSession checks if a session is valid and active. A session left
+// unused for longer than the idle timeout is locked: it is returned along
+// with ErrSessionLocked until Reauthenticate unlocks it.
 func (am *AuthManager) ValidateSession(sessionID string) (*types.Session, error) {
-	session, exists := am.sessions[sessionID]
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	key := SessionKey(sessionID)
+	session, exists := am.sessions[key]
 	if !exists {
 		return nil, fmt.Errorf("session not found")
 	}
+	if session.ID == "" {
+		session.ID = sessionID // restored from the store, which keeps no IDs
+	}
 
 	if !session.IsActive {
 		return nil, fmt.Errorf("session is inactive")
@@ -75,16 +301,64 @@ func (am *AuthManager) ValidateSession(sessionID string) (*types.Session, error)
 
 	if time.Now().After(session.ExpiresAt) {
 		session.IsActive = false
-		delete(am.sessions, sessionID)
+		delete(am.sessions, key)
+		am.dirty = true
 		return nil, fmt.Errorf("session has expired")
 	}
 
-	// Extend session expiry on successful validation
-	session.ExpiresAt = time.Now().Add(24 * time.Hour)
+	now := time.Now()
+	idle := time.Duration(am.config.IdleTimeout) * time.Minute
+	if !session.Locked && idle > 0 && !session.LastSeenAt.IsZero() && now.Sub(session.LastSeenAt) > idle {
+		session.Locked = true
+		am.dirty = true
+	}
+	if session.Locked {
+		return session, ErrSessionLocked
+	}
+
+	// Extend session expiry on successful validation; the store catches up
+	// on the next flush rather than on every request
+	session.ExpiresAt = now.Add(24 * time.Hour)
+	session.LastSeenAt = now
+	am.dirty = true
 
 	return session, nil
 }
 
+// Reauthenticate checks the password of the session's user and, when it
+// matches, unlocks the session and records that the password was just
+// entered
+func (am *AuthManager) Reauthenticate(sessionID, password string) (*types.Session, error) {
+	am.mu.Lock()
+	session, exists := am.sessions[SessionKey(sessionID)]
+	username := ""
+	if exists {
+		username = session.Username
+	}
+	am.mu.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("session not found")
+	}
+
+	if !am.VerifyPassword(username, password) {
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	session, exists = am.sessions[SessionKey(sessionID)]
+	if !exists || !session.IsActive || time.Now().After(session.ExpiresAt) {
+		return nil, fmt.Errorf("session not found")
+	}
+	now := time.Now()
+	session.Locked = false
+	session.LastSeenAt = now
+	session.AuthenticatedAt = now
+	session.ExpiresAt = now.Add(24 * time.Hour)
+	am.dirty = true
+	return session, nil
+}
+
 // GetSessionFromRequest extracts session ID from HTTP request
 func (am *AuthManager) GetSessionFromRequest(r *http.Request) (*types.Session, error) {
 	// Try to get session ID from cookie first
@@ -111,8 +385,12 @@ func (am *AuthManager) GetSessionFromRequest(r *http.Request) (*types.Session, e
 func (am *AuthManager) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		session, err := am.GetSessionFromRequest(r)
+		if errors.Is(err, ErrSessionLocked) {
+			types.NewProblem(http.StatusUnauthorized, types.ErrCodeSessionLocked, "Session locked after inactivity; enter the password at POST /admin/auth/reauth to continue").Write(w, r)
+			return
+		}
 		if err != nil {
-			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			types.NewProblem(http.StatusUnauthorized, types.ErrCodeUnauthorized, "Authentication required").Write(w, r)
 			return
 		}
 
@@ -129,7 +407,7 @@ func (am *AuthManager) CreateSessionCookie(sessionID string) *http.Cookie {
 		Value:    sessionID,
 		Path:     "/",
 		HttpOnly: true,
-		Secure:   false, // Set to true in production with HTTPS
+		Secure:   am.config.TLSEnabled(),
 		SameSite: http.SameSiteStrictMode,
 		MaxAge:   86400, // 24 hours in seconds
 	}
@@ -146,25 +424,39 @@ func (am *AuthManager) ClearSessionCookie() *http.Cookie {
 	}
 }
 
-// CleanupExpiredSessions removes expired sessions from memory
+// CleanupExpiredSessions removes expired sessions from memory; the session
+// store drops them on the next flush
 func (am *AuthManager) CleanupExpiredSessions() {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.cleanupExpiredSessions()
+}
+
+// cleanupExpiredSessions removes expired sessions; callers hold mu
+func (am *AuthManager) cleanupExpiredSessions() {
 	now := time.Now()
-	for sessionID, session := range am.sessions {
+	for key, session := range am.sessions {
 		if now.After(session.ExpiresAt) || !session.IsActive {
-			delete(am.sessions, sessionID)
+			delete(am.sessions, key)
+			am.dirty = true
 		}
 	}
 }
 
 // GetActiveSessions returns the count of active sessions
 func (am *AuthManager) GetActiveSessions() int {
-	am.CleanupExpiredSessions()
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.cleanupExpiredSessions()
 	return len(am.sessions)
 }
 
-// ListSessions returns all active sessions (for admin purposes)
+// ListSessions returns all active sessions (for admin purposes). Sessions
+// restored after a restart have no ID until they are used again.
 func (am *AuthManager) ListSessions() []*types.Session {
-	am.CleanupExpiredSessions()
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.cleanupExpiredSessions()
 	sessions := make([]*types.Session, 0, len(am.sessions))
 	for _, session := range am.sessions {
 		sessions = append(sessions, session)
@@ -181,16 +473,9 @@ func (am *AuthManager) generateSessionID() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-// hashPassword creates a SHA-256 hash of the password
-func (am *AuthManager) hashPassword(password string) string {
-	hash := sha256.Sum256([]byte(password))
-	return hex.EncodeToString(hash[:])
-}
-
 // ChangePassword changes the admin password (requires current password)
 func (am *AuthManager) ChangePassword(currentPassword, newPassword string) error {
-	currentHashed := am.hashPassword(currentPassword)
-	if currentHashed != am.config.AdminPassword {
+	if !am.checkPassword(currentPassword) {
 		return fmt.Errorf("current password is incorrect")
 	}
 
@@ -198,7 +483,18 @@ func (am *AuthManager) ChangePassword(currentPassword, newPassword string) error
 		return fmt.Errorf("new password must be at least 8 characters long")
 	}
 
-	// Update the config with new hashed password
-	am.config.AdminPassword = am.hashPassword(newPassword)
+	am.passwordMu.Lock()
+	defer am.passwordMu.Unlock()
+	hash, err := am.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	// Saved first, so a failed save leaves the old password in effect
+	// rather than one that is lost on restart
+	if err := am.savePasswordHash(hash); err != nil {
+		return fmt.Errorf("%w: %v", ErrPasswordNotSaved, err)
+	}
+	am.config.AdminPassword = hash
 	return nil
 }
@@ -0,0 +1,319 @@
+package managers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"onepagems/internal/httpclient"
+)
+
+// acmeChallengePath is where HTTP-01 challenges are fetched
+const acmeChallengePath = "/.well-known/acme-challenge/"
+
+// autocertRenewBefore is how long before expiry a certificate is renewed
+const autocertRenewBefore = 30 * 24 * time.Hour
+
+// autocertAccountKey is the file holding the ACME account key
+const autocertAccountKey = "acme_account.key"
+
+// autocertHostPattern matches DNS names a public CA issues for
+var autocertHostPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9-]{0,61}[a-z0-9]$`)
+
+// ErrAutocertHost is returned for TLS connections to a host not in the
+// allowlist, so scanners cannot make the server order certificates
+var ErrAutocertHost = errors.New("autocert: host not allowed")
+
+// ValidAutocertHost checks that host is a DNS name a certificate can be
+// ordered for
+func ValidAutocertHost(host string) error {
+	if net.ParseIP(host) != nil {
+		return fmt.Errorf("%s: IP addresses cannot get certificates", host)
+	}
+	if strings.Contains(host, "*") {
+		return fmt.Errorf("%s: wildcards need DNS challenges, which are not supported", host)
+	}
+	if !autocertHostPattern.MatchString(host) {
+		return fmt.Errorf("%s is not a fully qualified host name", host)
+	}
+	return nil
+}
+
+// AutocertManager obtains TLS certificates from an ACME CA such as Let's
+// Encrypt for an allowlist of hosts, proving control of each with HTTP-01
+// challenges served over plain HTTP. Certificates and the account key are
+// kept in dir and renewed 30 days before they expire.
+type AutocertManager struct {
+	hosts []string
+	email string
+	dir   string
+	acme  *acmeClient
+	now   func() time.Time
+
+	mu         sync.Mutex
+	certs      map[string]*tls.Certificate
+	challenges map[string]string // key authorization by token
+
+	obtainMu sync.Mutex // one order at a time
+}
+
+// NewAutocertManager creates a manager for hosts, loading or creating the
+// account key in dir. The CA is called through clients.
+func NewAutocertManager(hosts []string, email, dir, directoryURL string, clients *httpclient.Clients) (*AutocertManager, error) {
+	if len(hosts) == 0 {
+		return nil, errors.New("autocert: no hosts")
+	}
+	for _, host := range hosts {
+		if err := ValidAutocertHost(host); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("autocert: failed to create %s: %w", dir, err)
+	}
+	key, err := loadOrCreateECKey(filepath.Join(dir, autocertAccountKey))
+	if err != nil {
+		return nil, fmt.Errorf("autocert: account key: %w", err)
+	}
+
+	sorted := append([]string(nil), hosts...)
+	sort.Strings(sorted)
+	return &AutocertManager{
+		hosts:      sorted,
+		email:      email,
+		dir:        dir,
+		acme:       newACMEClient(directoryURL, key, clients.New(30*time.Second)),
+		now:        time.Now,
+		certs:      make(map[string]*tls.Certificate),
+		challenges: make(map[string]string),
+	}, nil
+}
+
+// allowed reports whether host is in the allowlist
+func (m *AutocertManager) allowed(host string) bool {
+	i := sort.SearchStrings(m.hosts, host)
+	return i < len(m.hosts) && m.hosts[i] == host
+}
+
+// GetCertificate picks the certificate for a TLS handshake, for use as
+// tls.Config.GetCertificate. A host without a certificate yet gets one
+// ordered during the handshake.
+func (m *AutocertManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	host := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if host == "" && len(m.hosts) == 1 {
+		host = m.hosts[0] // clients connecting by IP send no server name
+	}
+	if !m.allowed(host) {
+		return nil, fmt.Errorf("%w: %q", ErrAutocertHost, host)
+	}
+	if cert := m.cached(host); cert != nil {
+		return cert, nil
+	}
+
+	ctx, cancel := context.WithTimeout(hello.Context(), 2*time.Minute)
+	defer cancel()
+	return m.obtain(ctx, host, false)
+}
+
+// cached returns the certificate for host from memory or dir while it is
+// still valid
+func (m *AutocertManager) cached(host string) *tls.Certificate {
+	m.mu.Lock()
+	cert := m.certs[host]
+	m.mu.Unlock()
+	if cert == nil {
+		loaded, err := m.load(host)
+		if err != nil {
+			return nil
+		}
+		cert = loaded
+		m.mu.Lock()
+		m.certs[host] = cert
+		m.mu.Unlock()
+	}
+	if !m.now().Before(cert.Leaf.NotAfter) {
+		return nil
+	}
+	return cert
+}
+
+// certFile is where the key and chain for host are kept
+func (m *AutocertManager) certFile(host string) string {
+	return filepath.Join(m.dir, host+".pem")
+}
+
+// load reads the key and chain for host from dir
+func (m *AutocertManager) load(host string) (*tls.Certificate, error) {
+	data, err := os.ReadFile(m.certFile(host))
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.X509KeyPair(data, data)
+	if err != nil {
+		return nil, err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, err
+		}
+	}
+	if err := cert.Leaf.VerifyHostname(host); err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// obtain orders a certificate for host unless another caller just did, or,
+// with renew, unless the one there is not due for renewal yet
+func (m *AutocertManager) obtain(ctx context.Context, host string, renew bool) (*tls.Certificate, error) {
+	m.obtainMu.Lock()
+	defer m.obtainMu.Unlock()
+
+	if cert := m.cached(host); cert != nil && (!renew || m.now().Add(autocertRenewBefore).Before(cert.Leaf.NotAfter)) {
+		return cert, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: host},
+		DNSNames: []string{host},
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("autocert: failed to create certificate request: %w", err)
+	}
+	chain, err := m.acme.obtain(ctx, m.email, host, csr, m.serveChallenge)
+	if err != nil {
+		return nil, fmt.Errorf("autocert: %s: %w", host, err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	for _, der := range chain {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	if err := writePrivateFile(m.certFile(host), data); err != nil {
+		return nil, fmt.Errorf("autocert: failed to save certificate: %w", err)
+	}
+	cert, err := m.load(host)
+	if err != nil {
+		return nil, fmt.Errorf("autocert: CA issued an unusable certificate: %w", err)
+	}
+	m.mu.Lock()
+	m.certs[host] = cert
+	m.mu.Unlock()
+	return cert, nil
+}
+
+// serveChallenge publishes a key authorization until the returned func is
+// called
+func (m *AutocertManager) serveChallenge(token, keyAuth string) func() {
+	m.mu.Lock()
+	m.challenges[token] = keyAuth
+	m.mu.Unlock()
+	return func() {
+		m.mu.Lock()
+		delete(m.challenges, token)
+		m.mu.Unlock()
+	}
+}
+
+// HTTPHandler answers HTTP-01 challenges and passes every other request to
+// fallback. It must serve port 80 of each host.
+func (m *AutocertManager) HTTPHandler(fallback http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, acmeChallengePath) {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+		m.mu.Lock()
+		keyAuth, ok := m.challenges[strings.TrimPrefix(r.URL.Path, acmeChallengePath)]
+		m.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(keyAuth))
+	})
+}
+
+// RenewDue orders certificates for hosts that have none, or whose
+// certificate expires within 30 days. Errors are joined, one per host.
+func (m *AutocertManager) RenewDue(ctx context.Context) error {
+	var errs []error
+	for _, host := range m.hosts {
+		if _, err := m.obtain(ctx, host, true); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Hosts returns the allowlisted hosts
+func (m *AutocertManager) Hosts() []string {
+	return append([]string(nil), m.hosts...)
+}
+
+// loadOrCreateECKey reads a PEM P-256 key from path, creating it first if
+// the file does not exist
+func loadOrCreateECKey(path string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil || block.Type != "EC PRIVATE KEY" {
+			return nil, fmt.Errorf("%s holds no EC private key", path)
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := writePrivateFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// writePrivateFile replaces path with data readable by the owner only
+func writePrivateFile(path string, data []byte) error {
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package managers
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// autosaveDir holds one unpublished autosave per user in the data directory
+const autosaveDir = "autosave"
+
+// Autosave is a user's unsaved editor state, kept apart from content.json
+// so it can be recovered after a crashed browser without publishing it.
+// BaseUpdated is the last_updated of the content the edits started from.
+type Autosave struct {
+	Username    string                 `json:"username"`
+	Content     map[string]interface{} `json:"content"`
+	SavedAt     time.Time              `json:"saved_at"`
+	BaseUpdated time.Time              `json:"base_updated"`
+}
+
+// AutosaveManager stores autosaved editor content per user
+type AutosaveManager struct {
+	storage *FileStorage
+}
+
+// NewAutosaveManager creates a new autosave manager
+func NewAutosaveManager(storage *FileStorage) *AutosaveManager {
+	return &AutosaveManager{
+		storage: storage,
+	}
+}
+
+// Save replaces the user's autosave with content
+func (am *AutosaveManager) Save(username string, content map[string]interface{}, baseUpdated time.Time) (*Autosave, error) {
+	if err := os.MkdirAll(am.storage.GetFilePath(autosaveDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create autosave directory: %w", err)
+	}
+	autosave := &Autosave{
+		Username:    username,
+		Content:     content,
+		SavedAt:     time.Now(),
+		BaseUpdated: baseUpdated,
+	}
+	if err := am.storage.WriteJSONFile(autosaveFilename(username), autosave); err != nil {
+		return nil, fmt.Errorf("failed to save autosave: %w", err)
+	}
+	return autosave, nil
+}
+
+// Load returns the user's autosave, or nil if there is none
+func (am *AutosaveManager) Load(username string) (*Autosave, error) {
+	filename := autosaveFilename(username)
+	if !am.storage.FileExists(filename) {
+		return nil, nil
+	}
+	var autosave Autosave
+	if err := am.storage.ReadJSONFile(filename, &autosave); err != nil {
+		return nil, fmt.Errorf("failed to load autosave: %w", err)
+	}
+	return &autosave, nil
+}
+
+// Discard removes the user's autosave, reporting whether there was one
+func (am *AutosaveManager) Discard(username string) (bool, error) {
+	filename := autosaveFilename(username)
+	if !am.storage.FileExists(filename) {
+		return false, nil
+	}
+	if err := am.storage.DeleteFile(filename); err != nil {
+		return false, fmt.Errorf("failed to discard autosave: %w", err)
+	}
+	am.storage.DeleteFile(filename + ".bak")
+	return true, nil
+}
+
+// autosaveFilename is the data file of a user's autosave; the name is hex
+// encoded so any username is a safe filename
+func autosaveFilename(username string) string {
+	return filepath.Join(autosaveDir, hex.EncodeToString([]byte(username))+".json")
+}
@@ -0,0 +1,221 @@
+package managers
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// cborHead encodes the initial byte and argument of a CBOR item
+func cborHead(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= math.MaxUint8:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= math.MaxUint16:
+		return []byte{major<<5 | 25, byte(arg >> 8), byte(arg)}
+	case arg <= math.MaxUint32:
+		return []byte{major<<5 | 26, byte(arg >> 24), byte(arg >> 16), byte(arg >> 8), byte(arg)}
+	default:
+		head := []byte{major<<5 | 27}
+		for shift := 56; shift >= 0; shift -= 8 {
+			head = append(head, byte(arg>>shift))
+		}
+		return head
+	}
+}
+
+// cborEncode encodes the values decodeCBOR returns, so tests can build
+// authenticator responses. Map entries are written in the order given.
+func cborEncode(value interface{}) []byte {
+	switch v := value.(type) {
+	case int:
+		return cborEncode(int64(v))
+	case int64:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case []interface{}:
+		out := cborHead(4, uint64(len(v)))
+		for _, item := range v {
+			out = append(out, cborEncode(item)...)
+		}
+		return out
+	case cborMap:
+		out := cborHead(5, uint64(len(v)))
+		for _, entry := range v {
+			out = append(out, cborEncode(entry.key)...)
+			out = append(out, cborEncode(entry.value)...)
+		}
+		return out
+	case bool:
+		if v {
+			return []byte{0xf5}
+		}
+		return []byte{0xf4}
+	case nil:
+		return []byte{0xf6}
+	default:
+		panic("cborEncode: unsupported type")
+	}
+}
+
+// cborMap is a CBOR map with its entries in encoding order
+type cborMap []struct{ key, value interface{} }
+
+// nestedCBORArrays returns depth arrays nested inside each other
+func nestedCBORArrays(depth int) []byte {
+	data := bytes.Repeat([]byte{0x81}, depth)
+	return append(data, 0x00)
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  interface{}
+		rest  []byte
+	}{
+		{"small integer", []byte{0x17}, int64(23), nil},
+		{"one byte integer", []byte{0x18, 0xff}, int64(255), nil},
+		{"eight byte integer", cborHead(0, math.MaxInt64), int64(math.MaxInt64), nil},
+		{"negative integer", []byte{0x38, 0x63}, int64(-100), nil},
+		{"byte string", []byte{0x43, 1, 2, 3}, []byte{1, 2, 3}, nil},
+		{"text string", append([]byte{0x65}, "hello"...), "hello", nil},
+		{"array", []byte{0x83, 0x01, 0x20, 0xf5}, []interface{}{int64(1), int64(-1), true}, nil},
+		{"map", cborEncode(cborMap{{1, 2}, {"fmt", "none"}}), map[interface{}]interface{}{int64(1): int64(2), "fmt": "none"}, nil},
+		{"half float", []byte{0xf9, 0x3c, 0x00}, float64(1), nil},
+		{"single float", []byte{0xfa, 0x3f, 0xc0, 0x00, 0x00}, float64(1.5), nil},
+		{"double float", []byte{0xfb, 0x40, 0x09, 0x21, 0xfb, 0x54, 0x44, 0x2d, 0x18}, math.Pi, nil},
+		{"null", []byte{0xf6}, nil, nil},
+		{"trailing bytes", []byte{0x01, 0x02, 0x03}, int64(1), []byte{0x02, 0x03}},
+		{"nested at the limit", nestedCBORArrays(maxCBORDepth), nestedArrays(maxCBORDepth), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := decodeCBOR(tt.input)
+			if err != nil {
+				t.Fatalf("decodeCBOR: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decoded %#v, want %#v", got, tt.want)
+			}
+			if len(rest) != len(tt.rest) || !bytes.Equal(rest, tt.rest) {
+				t.Errorf("rest is %x, want %x", rest, tt.rest)
+			}
+		})
+	}
+}
+
+// nestedArrays is what nestedCBORArrays decodes to
+func nestedArrays(depth int) interface{} {
+	var value interface{} = int64(0)
+	for i := 0; i < depth; i++ {
+		value = []interface{}{value}
+	}
+	return value
+}
+
+func TestDecodeCBORRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []byte
+		wantErr string
+	}{
+		{"empty", nil, "unexpected end"},
+		{"truncated argument", []byte{0x19, 0x01}, "unexpected end"},
+		{"truncated byte string", []byte{0x45, 1, 2}, "unexpected end"},
+		{"truncated text string", []byte{0x62, 'a'}, "unexpected end"},
+		{"truncated array", []byte{0x82, 0x01}, "unexpected end"},
+		{"truncated map", []byte{0xa1, 0x01}, "unexpected end"},
+		{"truncated float", []byte{0xfb, 0x00, 0x00}, "unexpected end"},
+		{"oversized byte string", append(cborHead(2, math.MaxUint64), 0x00), "unexpected end"},
+		{"oversized array", append(cborHead(4, 1<<40), 0x00), "unexpected end"},
+		{"oversized map", append(cborHead(5, 1<<40), 0x00, 0x00), "unexpected end"},
+		{"integer out of range", cborHead(0, math.MaxUint64), "out of range"},
+		{"negative integer out of range", cborHead(1, math.MaxUint64), "out of range"},
+		{"nested too deeply", nestedCBORArrays(maxCBORDepth + 1), "nested too deeply"},
+		{"deeply nested maps", bytes.Repeat([]byte{0xa1, 0x00}, 1000), "nested too deeply"},
+		{"indefinite array", []byte{0x9f, 0x01, 0xff}, "indefinite"},
+		{"tag", []byte{0xc0, 0x01}, "unsupported major type 6"},
+		{"array map key", []byte{0xa1, 0x80, 0x01}, "unsupported map key"},
+		{"undefined simple value", []byte{0xf0}, "unsupported simple value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := decodeCBOR(tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("decodeCBOR error %v, want one containing %q", err, tt.wantErr)
+			}
+			if strings.Contains(tt.wantErr, "unexpected end") && !errors.Is(err, errCBORTruncated) {
+				t.Errorf("error %v is not errCBORTruncated", err)
+			}
+		})
+	}
+}
+
+func FuzzDecodeCBOR(f *testing.F) {
+	f.Add([]byte{0x83, 0x01, 0x20, 0xf5})
+	f.Add(cborEncode(cborMap{{"fmt", "none"}, {"authData", []byte{1, 2, 3}}, {"attStmt", cborMap{}}}))
+	f.Add(nestedCBORArrays(maxCBORDepth + 1))
+	f.Add([]byte{0xfb, 0x40, 0x09})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		value, rest, err := decodeCBOR(data)
+		if err != nil {
+			return
+		}
+		if len(rest) > len(data) || !bytes.Equal(rest, data[len(data)-len(rest):]) {
+			t.Fatalf("rest %x is not a suffix of the input %x", rest, data)
+		}
+		// The item alone decodes to the same value, with nothing left
+		again, left, err := decodeCBOR(data[:len(data)-len(rest)])
+		if err != nil || len(left) != 0 {
+			t.Fatalf("decoding the item alone: %v, %d bytes left", err, len(left))
+		}
+		if !cborEqual(value, again) {
+			t.Fatalf("decoded %#v, then %#v", value, again)
+		}
+	})
+}
+
+// cborEqual is reflect.DeepEqual with NaN equal to itself
+func cborEqual(a, b interface{}) bool {
+	if x, ok := a.(float64); ok {
+		y, ok := b.(float64)
+		return ok && (x == y || math.IsNaN(x) && math.IsNaN(y))
+	}
+	switch x := a.(type) {
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !cborEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[interface{}]interface{}:
+		y, ok := b.(map[interface{}]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			if other, ok := y[key]; !ok || !cborEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -3,7 +3,9 @@ package managers
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
+	"sync"
 	"time"
 
 	"onepagems/internal/types"
@@ -13,6 +15,9 @@ import (
 type ContentManager struct {
 	storage *FileStorage
 	dataDir string
+	// mu orders saves so each change is recorded against the content it
+	// replaced
+	mu sync.Mutex
 }
 
 // NewContentManager creates a new content manager
@@ -58,6 +63,14 @@ func (cm *ContentManager) LoadContent() (*types.ContentData, error) {
 
 // SaveContent saves content to content.json with backup
 func (cm *ContentManager) SaveContent(content *types.ContentData) error {
+	return cm.SaveContentAt(content, AnyRevision)
+}
+
+// SaveContentAt saves content like SaveContent, but only if the content is
+// still at revision; otherwise nothing is saved and ErrRevisionConflict is
+// returned. The revision is checked under the same lock as the write, so
+// an edit made against a revision cannot overwrite one saved after it.
+func (cm *ContentManager) SaveContentAt(content *types.ContentData, revision int64) error {
 	if content == nil {
 		return fmt.Errorf("content cannot be nil")
 	}
@@ -70,12 +83,26 @@ func (cm *ContentManager) SaveContent(content *types.ContentData) error {
 		return fmt.Errorf("content validation failed: %w", err)
 	}
 
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if revision != AnyRevision {
+		journal, err := cm.loadJournal()
+		if err != nil {
+			return err
+		}
+		if journal.Revision != revision {
+			return fmt.Errorf("%w: expected revision %d, content is at %d", ErrRevisionConflict, revision, journal.Revision)
+		}
+	}
+	before := cm.storedContent()
+
 	// Save with backup
 	contentFilename := cm.contentFilePath()
 	if err := cm.storage.WriteJSONFile(contentFilename, content); err != nil {
 		return fmt.Errorf("failed to save content file: %w", err)
 	}
 
+	cm.recordChange(before, content)
 	return nil
 }
 
@@ -119,10 +146,22 @@ func (cm *ContentManager) UpdateContent(updates map[string]interface{}) error {
 
 // UpdateContentFlexible updates content with flexible nested field support for auto-save
 func (cm *ContentManager) UpdateContentFlexible(updates map[string]interface{}) error {
+	content, err := cm.MergeContentUpdates(updates)
+	if err != nil {
+		return err
+	}
+
+	// Save updated content
+	return cm.SaveContent(content)
+}
+
+// MergeContentUpdates returns the current content with updates applied,
+// keyed by dot-notation paths, without saving it
+func (cm *ContentManager) MergeContentUpdates(updates map[string]interface{}) (*types.ContentData, error) {
 	// Load current content
 	content, err := cm.LoadContent()
 	if err != nil {
-		return fmt.Errorf("failed to load current content: %w", err)
+		return nil, fmt.Errorf("failed to load current content: %w", err)
 	}
 
 	// Convert content to map for flexible updates
@@ -147,8 +186,7 @@ func (cm *ContentManager) UpdateContentFlexible(updates map[string]interface{})
 		content.Sections = sections
 	}
 
-	// Save updated content
-	return cm.SaveContent(content)
+	return content, nil
 }
 
 // setNestedValue sets a value in a nested map using dot notation
@@ -183,8 +221,28 @@ func (cm *ContentManager) BackupContent() error {
 
 // RestoreContent restores content from backup
 func (cm *ContentManager) RestoreContent() error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	before := cm.storedContent()
+
 	contentFilename := cm.contentFilePath()
-	return cm.storage.RestoreFromBackup(contentFilename)
+	if err := cm.storage.RestoreFromBackup(contentFilename); err != nil {
+		return err
+	}
+	if after := cm.storedContent(); after != nil {
+		cm.recordChange(before, after)
+	}
+	return nil
+}
+
+// LastBackup returns when the content backup was last written, or the
+// zero time if there is none
+func (cm *ContentManager) LastBackup() time.Time {
+	info, err := cm.storage.GetBackupInfo(cm.contentFilePath())
+	if err != nil {
+		return time.Time{}
+	}
+	return info.CreatedAt
 }
 
 // GetContentSummary returns a summary of the current content
@@ -276,6 +334,14 @@ func (cm *ContentManager) ExportContent() ([]byte, error) {
 	return json.MarshalIndent(content, "", "  ")
 }
 
+// EncodeExport writes v to w as indented JSON, the layout of export files.
+// Downloads use it to stream the export instead of building it in memory.
+func EncodeExport(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
 // ImportContent imports content from JSON data
 func (cm *ContentManager) ImportContent(data []byte) error {
 	var content types.ContentData
@@ -285,3 +351,86 @@ func (cm *ContentManager) ImportContent(data []byte) error {
 
 	return cm.SaveContent(&content)
 }
+
+// LoadContentWithValidation loads content and checks it against the current
+// schema. Schema violations are returned as a validation result rather than
+// an error so the content can still be edited; the result is nil when the
+// schema cannot be loaded.
+func (cm *ContentManager) LoadContentWithValidation(schemaManager *SchemaManager) (*types.ContentData, *ValidationResult, error) {
+	content, err := cm.LoadContent()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contentMap, err := contentToMap(content)
+	if err != nil {
+		return content, nil, nil
+	}
+
+	validation, err := schemaManager.ValidateContentDetailed(contentMap)
+	if err != nil {
+		return content, nil, nil
+	}
+
+	return content, validation, nil
+}
+
+// HealContent applies trivial schema fixes to the stored content and saves
+// it, unless dryRun is set. The report lists each fix and the validation
+// result after healing.
+func (cm *ContentManager) HealContent(schemaManager *SchemaManager, dryRun bool) (*HealReport, error) {
+	content, err := cm.LoadContent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load content: %w", err)
+	}
+
+	schema, err := schemaManager.LoadSchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema: %w", err)
+	}
+
+	contentMap, err := contentToMap(content)
+	if err != nil {
+		return nil, err
+	}
+
+	validator := schemaManager.newValidator(schema)
+	report := &HealReport{
+		Fixes:  validator.HealContent(contentMap),
+		DryRun: dryRun,
+	}
+	report.Validation = validator.ValidateContent(contentMap)
+
+	if len(report.Fixes) == 0 || dryRun {
+		return report, nil
+	}
+
+	var healed types.ContentData
+	if err := remarshal(contentMap, &healed); err != nil {
+		return nil, fmt.Errorf("failed to rebuild healed content: %w", err)
+	}
+	if err := cm.SaveContent(&healed); err != nil {
+		return nil, fmt.Errorf("failed to save healed content: %w", err)
+	}
+	report.Saved = true
+
+	return report, nil
+}
+
+// contentToMap converts content to the generic form used by the validator
+func contentToMap(content *types.ContentData) (map[string]interface{}, error) {
+	contentMap := make(map[string]interface{})
+	if err := remarshal(content, &contentMap); err != nil {
+		return nil, fmt.Errorf("failed to convert content: %w", err)
+	}
+	return contentMap, nil
+}
+
+// remarshal copies src into dst through JSON
+func remarshal(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
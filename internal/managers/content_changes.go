@@ -0,0 +1,296 @@
+package managers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// contentChangesFilename keeps the recent content changes for editors
+// syncing from an older revision
+const contentChangesFilename = "content_changes.json"
+
+// maxContentChanges is how many revisions of changes are kept
+const maxContentChanges = 200
+
+// Content patch operations
+const (
+	PatchSet    = "set"
+	PatchRemove = "remove"
+)
+
+// AnyRevision saves with SaveContentAt whatever revision the content is at
+const AnyRevision int64 = -1
+
+// Delta sync failures
+var (
+	ErrChangesExpired   = errors.New("changes since that revision are no longer kept; reload the content")
+	ErrUnknownRevision  = errors.New("revision is newer than the content")
+	ErrRevisionConflict = errors.New("content was changed since that revision")
+)
+
+// ContentPatch changes one content field, addressed by a dotted path such
+// as "sections.hero.title". Arrays are set whole; Value is null for a
+// remove.
+type ContentPatch struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// ContentChange is what one save changed, numbered by the revision it
+// produced
+type ContentChange struct {
+	Revision int64          `json:"revision"`
+	At       time.Time      `json:"at"`
+	Patches  []ContentPatch `json:"patches"`
+}
+
+// contentJournal is the content revision and its recent changes, oldest
+// first
+type contentJournal struct {
+	Revision int64           `json:"revision"`
+	Changes  []ContentChange `json:"changes"`
+}
+
+// ContentRevision returns the revision of the saved content; it goes up by
+// one with every save that changes something
+func (cm *ContentManager) ContentRevision() (int64, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	journal, err := cm.loadJournal()
+	if err != nil {
+		return 0, err
+	}
+	return journal.Revision, nil
+}
+
+// ChangesSince returns the current revision and the changes made after
+// revision since, oldest first. It returns ErrChangesExpired when some of
+// them are no longer kept and ErrUnknownRevision for a revision not reached
+// yet.
+func (cm *ContentManager) ChangesSince(since int64) (int64, []ContentChange, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	journal, err := cm.loadJournal()
+	if err != nil {
+		return 0, nil, err
+	}
+	if since > journal.Revision || since < 0 {
+		return journal.Revision, nil, ErrUnknownRevision
+	}
+	changes := make([]ContentChange, 0)
+	if since == journal.Revision {
+		return journal.Revision, changes, nil
+	}
+	if len(journal.Changes) == 0 || journal.Changes[0].Revision > since+1 {
+		return journal.Revision, nil, ErrChangesExpired
+	}
+	for _, change := range journal.Changes {
+		if change.Revision > since {
+			changes = append(changes, change)
+		}
+	}
+	return journal.Revision, changes, nil
+}
+
+// recordChange adds the differences between the content before and after a
+// save to the journal; callers hold mu. A failure is only logged: the
+// content is already saved.
+func (cm *ContentManager) recordChange(before, after *types.ContentData) {
+	patches, err := diffContentData(before, after)
+	if err == nil && len(patches) == 0 {
+		return
+	}
+	journal, loadErr := cm.loadJournal()
+	if err == nil {
+		err = loadErr
+	}
+	if err != nil {
+		fmt.Printf("Warning: failed to record content change: %v\n", err)
+		return
+	}
+	journal.Revision++
+	journal.Changes = append(journal.Changes, ContentChange{Revision: journal.Revision, At: after.LastUpdated, Patches: patches})
+	if len(journal.Changes) > maxContentChanges {
+		journal.Changes = journal.Changes[len(journal.Changes)-maxContentChanges:]
+	}
+
+	if err := cm.saveJournal(journal); err != nil {
+		fmt.Printf("Warning: failed to record content change: %v\n", err)
+	}
+}
+
+// ContentReplaced starts a new revision without history, for when
+// content.json was replaced outside SaveContent, such as by an archive
+// import. Editors syncing from an earlier revision are told to reload.
+func (cm *ContentManager) ContentReplaced() error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	journal, err := cm.loadJournal()
+	if err != nil {
+		journal = &contentJournal{}
+	}
+	journal.Revision++
+	journal.Changes = make([]ContentChange, 0)
+	return cm.saveJournal(journal)
+}
+
+// loadJournal reads the journal; callers hold mu
+func (cm *ContentManager) loadJournal() (*contentJournal, error) {
+	journal := &contentJournal{Changes: make([]ContentChange, 0)}
+	if !cm.storage.FileExists(contentChangesFilename) {
+		return journal, nil
+	}
+	if err := cm.storage.ReadJSONFile(contentChangesFilename, journal); err != nil {
+		return nil, fmt.Errorf("failed to load content changes: %w", err)
+	}
+	return journal, nil
+}
+
+// saveJournal writes the journal without a .bak; a lost journal only makes
+// editors reload
+func (cm *ContentManager) saveJournal(journal *contentJournal) error {
+	data, err := json.Marshal(journal)
+	if err != nil {
+		return fmt.Errorf("failed to encode content changes: %w", err)
+	}
+	if err := cm.storage.WriteBinaryFile(contentChangesFilename, data); err != nil {
+		return fmt.Errorf("failed to save content changes: %w", err)
+	}
+	return nil
+}
+
+// storedContent reads content.json as it is, or nil when there is none,
+// without creating defaults
+func (cm *ContentManager) storedContent() *types.ContentData {
+	if !cm.storage.FileExists(cm.contentFilePath()) {
+		return nil
+	}
+	var content types.ContentData
+	if err := cm.storage.ReadJSONFile(cm.contentFilePath(), &content); err != nil {
+		return nil
+	}
+	return &content
+}
+
+// diffContentData lists the patches that turn before into after. Nil
+// before counts as empty content; last_updated is not compared.
+func diffContentData(before, after *types.ContentData) ([]ContentPatch, error) {
+	if before == nil {
+		before = &types.ContentData{}
+	}
+	beforeMap, err := contentToMap(before)
+	if err != nil {
+		return nil, err
+	}
+	afterMap, err := contentToMap(after)
+	if err != nil {
+		return nil, err
+	}
+	delete(beforeMap, "last_updated")
+	delete(afterMap, "last_updated")
+	return diffContentMaps(beforeMap, afterMap, "", make([]ContentPatch, 0)), nil
+}
+
+// diffContentMaps adds the patches between two objects, descending into
+// objects present on both sides
+func diffContentMaps(before, after map[string]interface{}, path string, patches []ContentPatch) []ContentPatch {
+	keys := make(map[string]interface{}, len(before)+len(after))
+	for key := range before {
+		keys[key] = nil
+	}
+	for key := range after {
+		keys[key] = nil
+	}
+	for _, key := range sortedKeys(keys) {
+		childPath := key
+		if path != "" {
+			childPath = path + "." + key
+		}
+		oldValue, hadOld := before[key]
+		newValue, hasNew := after[key]
+		switch {
+		case !hasNew:
+			patches = append(patches, ContentPatch{Op: PatchRemove, Path: childPath})
+		case !hadOld:
+			patches = append(patches, ContentPatch{Op: PatchSet, Path: childPath, Value: newValue})
+		default:
+			oldMap, oldIsMap := oldValue.(map[string]interface{})
+			newMap, newIsMap := newValue.(map[string]interface{})
+			if oldIsMap && newIsMap {
+				patches = diffContentMaps(oldMap, newMap, childPath, patches)
+			} else if !reflect.DeepEqual(oldValue, newValue) {
+				patches = append(patches, ContentPatch{Op: PatchSet, Path: childPath, Value: newValue})
+			}
+		}
+	}
+	return patches
+}
+
+// ApplyContentPatches applies patches in order to content in the generic
+// form with title, description and sections keys
+func ApplyContentPatches(content map[string]interface{}, patches []ContentPatch) error {
+	for _, patch := range patches {
+		keys := strings.Split(patch.Path, ".")
+		if patch.Path == "" || containsString(keys, "") {
+			return fmt.Errorf("invalid patch path %q", patch.Path)
+		}
+		parent := content
+		for _, key := range keys[:len(keys)-1] {
+			child, ok := parent[key].(map[string]interface{})
+			if !ok {
+				if patch.Op == PatchRemove {
+					parent = nil
+					break
+				}
+				child = make(map[string]interface{})
+				parent[key] = child
+			}
+			parent = child
+		}
+		switch patch.Op {
+		case PatchSet:
+			parent[keys[len(keys)-1]] = patch.Value
+		case PatchRemove:
+			if parent != nil {
+				delete(parent, keys[len(keys)-1])
+			}
+		default:
+			return fmt.Errorf("unknown patch op %q for %s; expected set or remove", patch.Op, patch.Path)
+		}
+	}
+	return nil
+}
+
+// PatchesOverlap reports whether two patch paths touch the same field: they
+// are equal or one lies inside the other
+func PatchesOverlap(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+".") || strings.HasPrefix(b, a+".")
+}
+
+// PatchConflicts lists the paths of patches that touch a field changed in
+// one of changes
+func PatchConflicts(patches []ContentPatch, changes []ContentChange) []string {
+	conflicts := make([]string, 0)
+	for _, patch := range patches {
+	changed:
+		for _, change := range changes {
+			for _, serverPatch := range change.Patches {
+				if PatchesOverlap(patch.Path, serverPatch.Path) {
+					conflicts = append(conflicts, patch.Path)
+					break changed
+				}
+			}
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,349 @@
+package managers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"onepagems/internal/types"
+)
+
+func newTestContentManager(t *testing.T) *ContentManager {
+	t.Helper()
+	dir := t.TempDir()
+	return NewContentManager(NewFileStorage(dir), dir)
+}
+
+// saveTestContent saves a page with a hero title and returns its revision
+func saveTestContent(t *testing.T, cm *ContentManager, heroTitle string) int64 {
+	t.Helper()
+	content := &types.ContentData{
+		Title:    "Home",
+		Sections: map[string]interface{}{"hero": map[string]interface{}{"title": heroTitle}},
+	}
+	if err := cm.SaveContent(content); err != nil {
+		t.Fatalf("SaveContent: %v", err)
+	}
+	revision, err := cm.ContentRevision()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return revision
+}
+
+// applyToStored applies patches to the saved content the way the delta sync
+// handler does
+func applyToStored(t *testing.T, cm *ContentManager, patches []ContentPatch) *types.ContentData {
+	t.Helper()
+	content, err := cm.LoadContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	contentMap, err := contentToMap(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyContentPatches(contentMap, patches); err != nil {
+		t.Fatalf("ApplyContentPatches: %v", err)
+	}
+	patched := &types.ContentData{}
+	if err := remarshal(contentMap, patched); err != nil {
+		t.Fatal(err)
+	}
+	return patched
+}
+
+func TestPatchesOverlap(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"sections.hero.title", "sections.hero.title", true},
+		{"sections.hero", "sections.hero.title", true},
+		{"sections.hero.title", "sections.hero", true},
+		{"sections", "sections.hero.title", true},
+		{"sections.hero.title", "sections.hero.subtitle", false},
+		{"sections.hero", "sections.heroes", false},
+		{"sections.heroes.title", "sections.hero", false},
+		{"title", "description", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			if got := PatchesOverlap(tt.a, tt.b); got != tt.want {
+				t.Errorf("PatchesOverlap(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchConflicts(t *testing.T) {
+	changes := []ContentChange{
+		{Revision: 13, Patches: []ContentPatch{{Op: PatchSet, Path: "sections.hero.title", Value: "Server"}}},
+		{Revision: 14, Patches: []ContentPatch{{Op: PatchRemove, Path: "sections.faq"}}},
+	}
+	tests := []struct {
+		name    string
+		patches []ContentPatch
+		want    []string
+	}{
+		{"other fields", []ContentPatch{{Op: PatchSet, Path: "sections.hero.subtitle"}, {Op: PatchSet, Path: "title"}}, []string{}},
+		{"same field", []ContentPatch{{Op: PatchSet, Path: "sections.hero.title"}}, []string{"sections.hero.title"}},
+		{"around a changed field", []ContentPatch{{Op: PatchRemove, Path: "sections.hero"}}, []string{"sections.hero"}},
+		{"inside a removed section", []ContentPatch{{Op: PatchSet, Path: "sections.faq.items"}}, []string{"sections.faq.items"}},
+		{"both changes, listed once", []ContentPatch{{Op: PatchSet, Path: "sections"}, {Op: PatchSet, Path: "description"}}, []string{"sections"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PatchConflicts(tt.patches, changes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PatchConflicts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := PatchConflicts([]ContentPatch{{Op: PatchSet, Path: "title"}}, nil); len(got) != 0 {
+		t.Errorf("conflicts without server changes: %v", got)
+	}
+}
+
+func TestDiffThenApplyContentPatches(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after map[string]interface{}
+		wantPatches   int
+	}{
+		{
+			"unchanged",
+			map[string]interface{}{"title": "Home", "sections": map[string]interface{}{"hero": map[string]interface{}{"title": "Hi"}}},
+			map[string]interface{}{"title": "Home", "sections": map[string]interface{}{"hero": map[string]interface{}{"title": "Hi"}}},
+			0,
+		},
+		{
+			"changed field",
+			map[string]interface{}{"sections": map[string]interface{}{"hero": map[string]interface{}{"title": "Hi", "subtitle": "Welcome"}}},
+			map[string]interface{}{"sections": map[string]interface{}{"hero": map[string]interface{}{"title": "Hello", "subtitle": "Welcome"}}},
+			1,
+		},
+		{
+			"added and removed sections",
+			map[string]interface{}{"sections": map[string]interface{}{"faq": map[string]interface{}{"title": "FAQ"}}},
+			map[string]interface{}{"sections": map[string]interface{}{"about": map[string]interface{}{"title": "About"}}},
+			2,
+		},
+		{
+			"array set whole",
+			map[string]interface{}{"sections": map[string]interface{}{"faq": map[string]interface{}{"items": []interface{}{"a", "b"}}}},
+			map[string]interface{}{"sections": map[string]interface{}{"faq": map[string]interface{}{"items": []interface{}{"b"}}}},
+			1,
+		},
+		{
+			"object replaced by a value",
+			map[string]interface{}{"sections": map[string]interface{}{"hero": map[string]interface{}{"cta": map[string]interface{}{"label": "Go"}}}},
+			map[string]interface{}{"sections": map[string]interface{}{"hero": map[string]interface{}{"cta": "Go"}}},
+			1,
+		},
+		{
+			"value replaced by an object",
+			map[string]interface{}{"description": "plain"},
+			map[string]interface{}{"description": map[string]interface{}{"text": "rich"}},
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patches := diffContentMaps(tt.before, tt.after, "", make([]ContentPatch, 0))
+			if len(patches) != tt.wantPatches {
+				t.Errorf("diff has %d patches, want %d: %+v", len(patches), tt.wantPatches, patches)
+			}
+
+			// Applying the diff to a copy of before gives after
+			content := make(map[string]interface{})
+			if err := remarshal(tt.before, &content); err != nil {
+				t.Fatal(err)
+			}
+			if err := ApplyContentPatches(content, patches); err != nil {
+				t.Fatalf("ApplyContentPatches: %v", err)
+			}
+			want := make(map[string]interface{})
+			if err := remarshal(tt.after, &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(content, want) {
+				t.Errorf("applied diff gives %v, want %v", content, want)
+			}
+		})
+	}
+}
+
+func TestApplyContentPatchesRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch ContentPatch
+	}{
+		{"empty path", ContentPatch{Op: PatchSet, Path: ""}},
+		{"empty key", ContentPatch{Op: PatchSet, Path: "sections..title"}},
+		{"trailing dot", ContentPatch{Op: PatchRemove, Path: "sections."}},
+		{"unknown op", ContentPatch{Op: "merge", Path: "title"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ApplyContentPatches(map[string]interface{}{"title": "Home"}, []ContentPatch{tt.patch}); err == nil {
+				t.Errorf("ApplyContentPatches accepted %+v", tt.patch)
+			}
+		})
+	}
+
+	// Removing inside a missing object changes nothing
+	content := map[string]interface{}{"title": "Home"}
+	if err := ApplyContentPatches(content, []ContentPatch{{Op: PatchRemove, Path: "sections.hero.title"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(content, map[string]interface{}{"title": "Home"}) {
+		t.Errorf("remove created %v", content)
+	}
+}
+
+func TestContentManagerChangesSince(t *testing.T) {
+	cm := newTestContentManager(t)
+	saveTestContent(t, cm, "One")
+	saveTestContent(t, cm, "Two")
+	last := saveTestContent(t, cm, "Three")
+	if last != 3 {
+		t.Fatalf("revision after three saves is %d", last)
+	}
+	// A save that changes nothing keeps the revision
+	if again := saveTestContent(t, cm, "Three"); again != last {
+		t.Errorf("unchanged save moved the revision to %d", again)
+	}
+
+	tests := []struct {
+		name        string
+		since       int64
+		wantChanges int
+		wantErr     error
+	}{
+		{"from the start", 0, 3, nil},
+		{"one behind", 2, 1, nil},
+		{"up to date", 3, 0, nil},
+		{"ahead", 4, 0, ErrUnknownRevision},
+		{"negative", -1, 0, ErrUnknownRevision},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revision, changes, err := cm.ChangesSince(tt.since)
+			if !errors.Is(err, tt.wantErr) || revision != last || len(changes) != tt.wantChanges {
+				t.Errorf("ChangesSince(%d) = %d, %d change(s), %v", tt.since, revision, len(changes), err)
+			}
+		})
+	}
+
+	_, changes, _ := cm.ChangesSince(2)
+	want := []ContentPatch{{Op: PatchSet, Path: "sections.hero.title", Value: "Three"}}
+	if len(changes) != 1 || changes[0].Revision != 3 || !reflect.DeepEqual(changes[0].Patches, want) {
+		t.Errorf("change after revision 2 is %+v", changes)
+	}
+}
+
+func TestContentManagerChangesExpired(t *testing.T) {
+	cm := newTestContentManager(t)
+	saveTestContent(t, cm, "One")
+
+	// Only revisions 5 and 6 are still kept
+	journal := &contentJournal{Revision: 6, Changes: []ContentChange{
+		{Revision: 5, Patches: []ContentPatch{{Op: PatchSet, Path: "title", Value: "Five"}}},
+		{Revision: 6, Patches: []ContentPatch{{Op: PatchSet, Path: "title", Value: "Six"}}},
+	}}
+	if err := cm.saveJournal(journal); err != nil {
+		t.Fatal(err)
+	}
+	if revision, _, err := cm.ChangesSince(3); !errors.Is(err, ErrChangesExpired) || revision != 6 {
+		t.Errorf("ChangesSince(3) = %d, %v, want ErrChangesExpired at 6", revision, err)
+	}
+	if _, changes, err := cm.ChangesSince(4); err != nil || len(changes) != 2 {
+		t.Errorf("ChangesSince(4) = %d change(s), %v", len(changes), err)
+	}
+
+	// Replacing the content keeps nothing
+	if err := cm.ContentReplaced(); err != nil {
+		t.Fatal(err)
+	}
+	if revision, _, err := cm.ChangesSince(6); !errors.Is(err, ErrChangesExpired) || revision != 7 {
+		t.Errorf("ChangesSince(6) after a replace = %d, %v", revision, err)
+	}
+}
+
+func TestContentManagerSaveContentAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		revision func(current int64) int64
+		wantErr  error
+	}{
+		{"current revision", func(current int64) int64 { return current }, nil},
+		{"any revision", func(int64) int64 { return AnyRevision }, nil},
+		{"older revision", func(current int64) int64 { return current - 1 }, ErrRevisionConflict},
+		{"newer revision", func(current int64) int64 { return current + 1 }, ErrRevisionConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newTestContentManager(t)
+			saveTestContent(t, cm, "One")
+			current := saveTestContent(t, cm, "Two")
+
+			patched := applyToStored(t, cm, []ContentPatch{{Op: PatchSet, Path: "sections.hero.title", Value: "Mine"}})
+			err := cm.SaveContentAt(patched, tt.revision(current))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SaveContentAt: %v, want %v", err, tt.wantErr)
+			}
+
+			stored, _ := cm.LoadContent()
+			revision, _ := cm.ContentRevision()
+			title := stored.Sections["hero"].(map[string]interface{})["title"]
+			if tt.wantErr == nil && (title != "Mine" || revision != current+1) {
+				t.Errorf("after the save: title %v, revision %d", title, revision)
+			}
+			if tt.wantErr != nil && (title != "Two" || revision != current) {
+				t.Errorf("a refused save changed the content: title %v, revision %d", title, revision)
+			}
+		})
+	}
+}
+
+// TestForcedApply follows the delta sync handler: conflicting patches are
+// refused unless forced, and a forced apply over expired history still saves
+// only against the revision it read
+func TestForcedApply(t *testing.T) {
+	cm := newTestContentManager(t)
+	base := saveTestContent(t, cm, "One")
+	saveTestContent(t, cm, "Server")
+	patches := []ContentPatch{{Op: PatchSet, Path: "sections.hero.title", Value: "Offline"}}
+
+	revision, changes, err := cm.ChangesSince(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conflicts := PatchConflicts(patches, changes); !reflect.DeepEqual(conflicts, []string{"sections.hero.title"}) {
+		t.Fatalf("conflicts %v", conflicts)
+	}
+
+	// Forced, the offline edit overwrites the server's
+	if err := cm.SaveContentAt(applyToStored(t, cm, patches), revision); err != nil {
+		t.Fatalf("forced save: %v", err)
+	}
+	stored, _ := cm.LoadContent()
+	if title := stored.Sections["hero"].(map[string]interface{})["title"]; title != "Offline" {
+		t.Errorf("title after the forced apply is %v", title)
+	}
+
+	// With the history gone a forced apply uses the current revision, and
+	// loses to a save made after it was read
+	if err := cm.ContentReplaced(); err != nil {
+		t.Fatal(err)
+	}
+	revision, _, err = cm.ChangesSince(base)
+	if !errors.Is(err, ErrChangesExpired) {
+		t.Fatalf("ChangesSince after a replace: %v", err)
+	}
+	patched := applyToStored(t, cm, patches)
+	saveTestContent(t, cm, "Meanwhile")
+	if err := cm.SaveContentAt(patched, revision); !errors.Is(err, ErrRevisionConflict) {
+		t.Errorf("forced save over a newer save: %v, want ErrRevisionConflict", err)
+	}
+}
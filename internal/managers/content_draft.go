@@ -0,0 +1,130 @@
+package managers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// contentDraftFilename holds unpublished content edits
+const contentDraftFilename = "content.draft.json"
+
+// Draft failures
+var (
+	ErrNoDraft    = errors.New("there is no content draft")
+	ErrDraftStale = errors.New("the content was saved after the draft was started")
+)
+
+// ContentDraft is content edited apart from content.json, which the site
+// is generated from, until it is published or discarded. BaseUpdated is
+// the last_updated of the content the draft started from.
+type ContentDraft struct {
+	Content     *types.ContentData `json:"content"`
+	BaseUpdated time.Time          `json:"base_updated"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	UpdatedBy   string             `json:"updated_by,omitempty"`
+}
+
+// LoadDraft returns the content draft, or nil if there is none
+func (cm *ContentManager) LoadDraft() (*ContentDraft, error) {
+	if !cm.storage.FileExists(contentDraftFilename) {
+		return nil, nil
+	}
+	var draft ContentDraft
+	if err := cm.storage.ReadJSONFile(contentDraftFilename, &draft); err != nil {
+		return nil, fmt.Errorf("failed to read content draft: %w", err)
+	}
+	if draft.Content == nil {
+		return nil, fmt.Errorf("content draft has no content")
+	}
+	return &draft, nil
+}
+
+// SaveDraft replaces the draft with content. The draft keeps the base it
+// started from; a new draft starts from the current content. Drafts are
+// not checked against the schema until they are published.
+func (cm *ContentManager) SaveDraft(content *types.ContentData, username string) (*ContentDraft, error) {
+	if content == nil {
+		return nil, fmt.Errorf("content cannot be nil")
+	}
+	if err := cm.validateContent(content); err != nil {
+		return nil, fmt.Errorf("content validation failed: %w", err)
+	}
+
+	existing, err := cm.LoadDraft()
+	if err != nil {
+		return nil, err
+	}
+	var base time.Time
+	if existing != nil {
+		base = existing.BaseUpdated
+	} else {
+		current, err := cm.LoadContent()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load current content: %w", err)
+		}
+		base = current.LastUpdated
+	}
+
+	now := time.Now()
+	content.LastUpdated = now
+	draft := &ContentDraft{
+		Content:     content,
+		BaseUpdated: base,
+		UpdatedAt:   now,
+		UpdatedBy:   username,
+	}
+	if err := cm.storage.WriteJSONFile(contentDraftFilename, draft); err != nil {
+		return nil, fmt.Errorf("failed to save content draft: %w", err)
+	}
+	return draft, nil
+}
+
+// PublishDraft makes the draft the content and removes it. Unless force is
+// set it refuses with ErrDraftStale when the content was saved after the
+// draft started, as publishing would undo those changes. check, if not
+// nil, runs on the draft content before it is saved and may refuse it.
+func (cm *ContentManager) PublishDraft(force bool, check func(*types.ContentData) error) (*types.ContentData, error) {
+	draft, err := cm.LoadDraft()
+	if err != nil {
+		return nil, err
+	}
+	if draft == nil {
+		return nil, ErrNoDraft
+	}
+	if !force {
+		current, err := cm.LoadContent()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load current content: %w", err)
+		}
+		if current.LastUpdated.After(draft.BaseUpdated) {
+			return nil, ErrDraftStale
+		}
+	}
+	if check != nil {
+		if err := check(draft.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cm.SaveContent(draft.Content); err != nil {
+		return nil, err
+	}
+	if _, err := cm.DiscardDraft(); err != nil {
+		return nil, fmt.Errorf("content published, but the draft could not be removed: %w", err)
+	}
+	return draft.Content, nil
+}
+
+// DiscardDraft removes the draft, reporting whether there was one
+func (cm *ContentManager) DiscardDraft() (bool, error) {
+	if !cm.storage.FileExists(contentDraftFilename) {
+		return false, nil
+	}
+	if err := cm.storage.DeleteFile(contentDraftFilename); err != nil {
+		return false, fmt.Errorf("failed to discard content draft: %w", err)
+	}
+	return true, nil
+}
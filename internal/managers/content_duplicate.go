@@ -0,0 +1,185 @@
+package managers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultDuplicateSuffix is appended to the label of a duplicated item
+const DefaultDuplicateSuffix = " (copy)"
+
+// duplicateLabelFields are the fields, in order of preference, whose value
+// names an item and receives the suffix
+var duplicateLabelFields = []string{"title", "name", "heading", "label"}
+
+// contentPathSegment matches one dotted path segment with optional array
+// indexes, as in "items[2]"
+var contentPathSegment = regexp.MustCompile(`^([^\[\]]+)((?:\[\d+\])*)$`)
+
+// DuplicateContentItem copies a section ("sections.services") or an array
+// item ("sections.services.items[1]") of content in place. A section copy is
+// stored under a new key ending in "_copy"; an item copy is inserted right
+// after the original. The copy's label (title, name, heading or label, or
+// the item itself when it is a string) gets suffix appended. It returns the
+// path of the copy.
+func DuplicateContentItem(content map[string]interface{}, path, suffix string) (string, error) {
+	steps, err := parseContentPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	last := steps[len(steps)-1]
+	switch {
+	case last.index >= 0:
+		parent, err := contentValueAt(content, steps[:len(steps)-1])
+		if err != nil {
+			return "", err
+		}
+		items, ok := parent.([]interface{})
+		if !ok || last.index >= len(items) {
+			return "", fmt.Errorf("%s does not exist", path)
+		}
+
+		copied, err := cloneContentValue(items[last.index])
+		if err != nil {
+			return "", err
+		}
+		copied = renameDuplicate(copied, suffix)
+
+		updated := make([]interface{}, 0, len(items)+1)
+		updated = append(updated, items[:last.index+1]...)
+		updated = append(updated, copied)
+		updated = append(updated, items[last.index+1:]...)
+		if err := setContentValue(content, steps[:len(steps)-1], updated); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s[%d]", path[:strings.LastIndex(path, "[")], last.index+1), nil
+
+	case len(steps) == 2 && steps[0].key == "sections":
+		sections, ok := content["sections"].(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("content has no sections")
+		}
+		section, exists := sections[last.key]
+		if !exists {
+			return "", fmt.Errorf("%s does not exist", path)
+		}
+
+		copied, err := cloneContentValue(section)
+		if err != nil {
+			return "", err
+		}
+		key := last.key + "_copy"
+		for n := 2; sections[key] != nil; n++ {
+			key = last.key + "_copy" + strconv.Itoa(n)
+		}
+		sections[key] = renameDuplicate(copied, suffix)
+		return "sections." + key, nil
+	}
+
+	return "", fmt.Errorf("path must name a section (sections.<name>) or an array item (ending in [n])")
+}
+
+// contentPathStep is a map key, or an array index when index >= 0
+type contentPathStep struct {
+	key   string
+	index int
+}
+
+// parseContentPath splits a validator-style path such as
+// "sections.services.items[1]" into steps
+func parseContentPath(path string) ([]contentPathStep, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	var steps []contentPathStep
+	for _, segment := range strings.Split(path, ".") {
+		match := contentPathSegment.FindStringSubmatch(segment)
+		if match == nil {
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+		steps = append(steps, contentPathStep{key: match[1], index: -1})
+		for _, index := range strings.Split(strings.Trim(match[2], "[]"), "][") {
+			if index == "" {
+				continue
+			}
+			n, err := strconv.Atoi(index)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+			steps = append(steps, contentPathStep{index: n})
+		}
+	}
+	return steps, nil
+}
+
+// contentValueAt returns the value at a path
+func contentValueAt(content map[string]interface{}, steps []contentPathStep) (interface{}, error) {
+	var current interface{} = content
+	for _, step := range steps {
+		switch value := current.(type) {
+		case map[string]interface{}:
+			if step.index >= 0 {
+				return nil, fmt.Errorf("%s is not an array", step.key)
+			}
+			current = value[step.key]
+		case []interface{}:
+			if step.index < 0 || step.index >= len(value) {
+				return nil, fmt.Errorf("array index out of range")
+			}
+			current = value[step.index]
+		default:
+			return nil, fmt.Errorf("path does not exist")
+		}
+	}
+	return current, nil
+}
+
+// setContentValue replaces the value at a path
+func setContentValue(content map[string]interface{}, steps []contentPathStep, value interface{}) error {
+	parent, err := contentValueAt(content, steps[:len(steps)-1])
+	if err != nil {
+		return err
+	}
+	last := steps[len(steps)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[last.key] = value
+	case []interface{}:
+		container[last.index] = value
+	default:
+		return fmt.Errorf("path does not exist")
+	}
+	return nil
+}
+
+// cloneContentValue deep-copies a JSON value so the copy can change alone
+func cloneContentValue(value interface{}) (interface{}, error) {
+	var copied interface{}
+	if err := remarshal(value, &copied); err != nil {
+		return nil, fmt.Errorf("failed to copy item: %w", err)
+	}
+	return copied, nil
+}
+
+// renameDuplicate appends suffix to the label of a copied item
+func renameDuplicate(value interface{}, suffix string) interface{} {
+	if suffix == "" {
+		return value
+	}
+	switch item := value.(type) {
+	case string:
+		return item + suffix
+	case map[string]interface{}:
+		for _, field := range duplicateLabelFields {
+			if label, ok := item[field].(string); ok && label != "" {
+				item[field] = label + suffix
+				break
+			}
+		}
+	}
+	return value
+}
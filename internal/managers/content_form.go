@@ -0,0 +1,93 @@
+package managers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ContentFromForm builds nested content from submitted form values. Field
+// names may use brackets (sections[hero][title]) or dots
+// (sections.hero.title). A trailing [] or an array field in the schema
+// collects every value into a list; otherwise the last value wins, so a
+// hidden "false" input placed before a checkbox of the same name works.
+// Values are left as strings for CoerceContent to convert.
+func (sm *SchemaManager) ContentFromForm(values map[string][]string) (map[string]interface{}, error) {
+	schema, err := sm.LoadSchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema: %w", err)
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	content := make(map[string]interface{})
+	for _, name := range names {
+		fieldValues := values[name]
+		if len(fieldValues) == 0 {
+			continue
+		}
+
+		path, isList := ParseFormFieldName(name)
+		if len(path) == 0 {
+			continue
+		}
+
+		if !isList {
+			prop, _, ok := lookupProperty(schema.Properties, nil, strings.Join(path, "."))
+			isList = ok && primaryType(prop) == "array"
+		}
+
+		var value interface{} = fieldValues[len(fieldValues)-1]
+		if isList {
+			items := make([]interface{}, 0, len(fieldValues))
+			for _, fieldValue := range fieldValues {
+				if fieldValue != "" {
+					items = append(items, fieldValue)
+				}
+			}
+			value = items
+		}
+
+		setPathValue(content, path, value)
+	}
+
+	return content, nil
+}
+
+// ParseFormFieldName splits a form field name such as sections[hero][title]
+// or sections.hero.title into its path segments. The second result reports a
+// trailing [] marking a list field.
+func ParseFormFieldName(name string) ([]string, bool) {
+	isList := strings.HasSuffix(name, "[]")
+	name = strings.TrimSuffix(name, "[]")
+
+	name = strings.ReplaceAll(name, "][", ".")
+	name = strings.ReplaceAll(name, "[", ".")
+	name = strings.TrimSuffix(name, "]")
+
+	path := make([]string, 0)
+	for _, segment := range strings.Split(name, ".") {
+		if segment != "" {
+			path = append(path, segment)
+		}
+	}
+	return path, isList
+}
+
+// setPathValue stores value in obj at path, creating intermediate objects.
+// A non-object already in the way is replaced.
+func setPathValue(obj map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := obj[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			obj[key] = next
+		}
+		obj = next
+	}
+	obj[path[len(path)-1]] = value
+}
@@ -0,0 +1,140 @@
+package managers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ContentFix records a single change made while healing content
+type ContentFix struct {
+	Path    string      `json:"path"`
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	From    interface{} `json:"from"`
+	To      interface{} `json:"to"`
+}
+
+// HealReport describes the result of healing stored content
+type HealReport struct {
+	Fixes      []ContentFix      `json:"fixes"`
+	Saved      bool              `json:"saved"`
+	DryRun     bool              `json:"dry_run"`
+	Validation *ValidationResult `json:"validation"`
+}
+
+// HealContent fixes trivially repairable schema violations in content in
+// place: numeric and boolean strings in fields that do not accept strings are
+// converted, and over-length text is trimmed and then truncated. It returns
+// the changes made. Anything else is left for the user to fix.
+func (sv *SchemaValidator) HealContent(content map[string]interface{}) []ContentFix {
+	fixes := make([]ContentFix, 0)
+	sv.healObject(content, "", sv.schema.Properties, &fixes)
+
+	sort.Slice(fixes, func(i, j int) bool {
+		return fixes[i].Path < fixes[j].Path
+	})
+	return fixes
+}
+
+// healObject heals each declared property of an object
+func (sv *SchemaValidator) healObject(obj map[string]interface{}, path string, schemaProps map[string]interface{}, fixes *[]ContentFix) {
+	for fieldName, schemaProp := range schemaProps {
+		propMap, ok := schemaProp.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		value, exists := obj[fieldName]
+		if !exists {
+			continue
+		}
+
+		fieldPath := fieldName
+		if path != "" {
+			fieldPath = path + "." + fieldName
+		}
+		obj[fieldName] = sv.healValue(value, propMap, fieldPath, fixes)
+	}
+}
+
+// healValue returns value with any trivial fixes for its schema applied
+func (sv *SchemaValidator) healValue(value interface{}, schemaProp map[string]interface{}, fieldPath string, fixes *[]ContentFix) interface{} {
+	switch v := value.(type) {
+	case string:
+		return sv.healString(v, schemaProp, fieldPath, fixes)
+	case map[string]interface{}:
+		if properties, ok := schemaProp["properties"].(map[string]interface{}); ok {
+			sv.healObject(v, fieldPath, properties, fixes)
+		}
+	case []interface{}:
+		if items, ok := schemaProp["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				v[i] = sv.healValue(item, items, fmt.Sprintf("%s[%d]", fieldPath, i), fixes)
+			}
+		}
+	}
+	return value
+}
+
+// healString converts strings in non-string fields and shortens over-length
+// text
+func (sv *SchemaValidator) healString(str string, schemaProp map[string]interface{}, fieldPath string, fixes *[]ContentFix) interface{} {
+	typeNames, _ := parseSchemaType(schemaProp)
+	if len(typeNames) == 0 {
+		typeNames = []string{"string"}
+	}
+
+	if !containsString(typeNames, "string") {
+		if converted, code, ok := parseTypedString(strings.TrimSpace(str), typeNames); ok {
+			return sv.recordFix(fixes, fieldPath, code, "Converted string to "+strings.TrimPrefix(code, "string_to_"), str, converted)
+		}
+		return str
+	}
+
+	maxLength := maxLengthFor(schemaProp, sv.limits)
+	if maxLength == 0 || len(str) <= maxLength {
+		return str
+	}
+
+	if trimmed := strings.TrimSpace(str); len(trimmed) <= maxLength {
+		return sv.recordFix(fixes, fieldPath, "trimmed", "Trimmed surrounding whitespace to fit maxLength", str, trimmed)
+	}
+
+	truncated := truncateString(strings.TrimSpace(str), maxLength)
+	return sv.recordFix(fixes, fieldPath, "truncated", fmt.Sprintf("Truncated text to %d characters", maxLength), str, truncated)
+}
+
+// recordFix appends a fix to the report and returns the new value
+func (sv *SchemaValidator) recordFix(fixes *[]ContentFix, path, code, message string, from, to interface{}) interface{} {
+	*fixes = append(*fixes, ContentFix{
+		Path:    path,
+		Code:    code,
+		Message: message,
+		From:    from,
+		To:      to,
+	})
+	return to
+}
+
+// containsString reports whether list includes value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateString shortens str to at most maxBytes bytes without splitting a
+// UTF-8 character
+func truncateString(str string, maxBytes int) string {
+	if len(str) <= maxBytes {
+		return str
+	}
+	for maxBytes > 0 && !utf8.RuneStart(str[maxBytes]) {
+		maxBytes--
+	}
+	return str[:maxBytes]
+}
@@ -0,0 +1,56 @@
+package managers
+
+import (
+	"fmt"
+	"time"
+)
+
+// credentialsFilename stores the admin password hash once it has been
+// changed from the admin, so the change survives restarts
+const credentialsFilename = "auth.json"
+
+// CredentialStore keeps the admin password hash between restarts. A stored
+// hash takes precedence over ADMIN_PASSWORD.
+type CredentialStore interface {
+	// LoadPasswordHash returns the stored hash, or "" when none is stored
+	LoadPasswordHash() (string, error)
+	SavePasswordHash(hash string) error
+}
+
+// storedCredentials is auth.json
+type storedCredentials struct {
+	PasswordHash string    `json:"password_hash"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// FileCredentialStore keeps the password hash in auth.json in the data
+// directory
+type FileCredentialStore struct {
+	storage *FileStorage
+}
+
+// NewFileCredentialStore creates a credential store backed by auth.json
+func NewFileCredentialStore(storage *FileStorage) *FileCredentialStore {
+	return &FileCredentialStore{storage: storage}
+}
+
+// LoadPasswordHash reads the stored password hash
+func (fc *FileCredentialStore) LoadPasswordHash() (string, error) {
+	if !fc.storage.FileExists(credentialsFilename) {
+		return "", nil
+	}
+	var stored storedCredentials
+	if err := fc.storage.ReadJSONFile(credentialsFilename, &stored); err != nil {
+		return "", fmt.Errorf("failed to load credentials: %w", err)
+	}
+	return stored.PasswordHash, nil
+}
+
+// SavePasswordHash replaces the stored password hash
+func (fc *FileCredentialStore) SavePasswordHash(hash string) error {
+	stored := storedCredentials{PasswordHash: hash, UpdatedAt: time.Now()}
+	if err := fc.storage.WriteJSONFile(credentialsFilename, stored); err != nil {
+		return fmt.Errorf("failed to save credentials: %w", err)
+	}
+	return nil
+}
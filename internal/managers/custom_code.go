@@ -0,0 +1,150 @@
+package managers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// customCodeFilename stores the custom head and body-end snippets
+const customCodeFilename = "custom_code.json"
+
+// maxCustomCodeHistory is how many audit entries are kept
+const maxCustomCodeHistory = 50
+
+var (
+	// documentTagPattern matches tags that would break the page structure
+	// if pasted into a slot
+	documentTagPattern = regexp.MustCompile(`(?i)<\s*/?\s*(html|head|body)\b`)
+	scriptOpenPattern  = regexp.MustCompile(`(?i)<script\b`)
+	scriptClosePattern = regexp.MustCompile(`(?i)</script\s*>`)
+	headClosePattern   = regexp.MustCompile(`(?i)</head\s*>`)
+	bodyClosePattern   = regexp.MustCompile(`(?i)</body\s*>`)
+)
+
+// CustomCodeManager stores the custom code slots injected into the
+// generated page
+type CustomCodeManager struct {
+	storage *FileStorage
+	maxSize int
+}
+
+// NewCustomCodeManager creates a new custom code manager. Each slot may be
+// at most maxSize bytes.
+func NewCustomCodeManager(storage *FileStorage, maxSize int) *CustomCodeManager {
+	return &CustomCodeManager{
+		storage: storage,
+		maxSize: maxSize,
+	}
+}
+
+// MaxSize returns the size limit of each slot in bytes
+func (cm *CustomCodeManager) MaxSize() int {
+	return cm.maxSize
+}
+
+// Load returns the saved custom code, or empty slots if none has been saved
+func (cm *CustomCodeManager) Load() (*types.CustomCode, error) {
+	code := &types.CustomCode{}
+	if !cm.storage.FileExists(customCodeFilename) {
+		return code, nil
+	}
+
+	if err := cm.storage.ReadJSONFile(customCodeFilename, code); err != nil {
+		return nil, fmt.Errorf("failed to load custom code: %w", err)
+	}
+	return code, nil
+}
+
+// Save validates and stores both slots, recording who changed them in the
+// audit history. It returns the new audit entry.
+func (cm *CustomCodeManager) Save(headHTML, bodyEndHTML, username string) (*types.CustomCodeAudit, error) {
+	if err := cm.Validate("head_html", headHTML); err != nil {
+		return nil, err
+	}
+	if err := cm.Validate("body_end_html", bodyEndHTML); err != nil {
+		return nil, err
+	}
+
+	code, err := cm.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := types.CustomCodeAudit{
+		At:          time.Now(),
+		By:          username,
+		HeadSize:    len(headHTML),
+		HeadHash:    snippetHash(headHTML),
+		BodyEndSize: len(bodyEndHTML),
+		BodyEndHash: snippetHash(bodyEndHTML),
+	}
+
+	code.HeadHTML = headHTML
+	code.BodyEndHTML = bodyEndHTML
+	code.UpdatedAt = entry.At
+	code.UpdatedBy = username
+	code.History = append(code.History, entry)
+	if len(code.History) > maxCustomCodeHistory {
+		code.History = code.History[len(code.History)-maxCustomCodeHistory:]
+	}
+
+	if err := cm.storage.WriteJSONFile(customCodeFilename, code); err != nil {
+		return nil, fmt.Errorf("failed to save custom code: %w", err)
+	}
+	return &entry, nil
+}
+
+// Validate checks a slot against the size limit and rejects markup that
+// would break the surrounding page: document-level tags and unbalanced
+// <script> elements
+func (cm *CustomCodeManager) Validate(slot, html string) error {
+	if cm.maxSize > 0 && len(html) > cm.maxSize {
+		return fmt.Errorf("%s is %d bytes; the limit is %d bytes", slot, len(html), cm.maxSize)
+	}
+	if match := documentTagPattern.FindString(html); match != "" {
+		return fmt.Errorf("%s must not contain %s> tags", slot, strings.ToLower(strings.Join(strings.Fields(match), "")))
+	}
+	if len(scriptOpenPattern.FindAllStringIndex(html, -1)) != len(scriptClosePattern.FindAllStringIndex(html, -1)) {
+		return fmt.Errorf("%s has an unclosed <script> element", slot)
+	}
+	return nil
+}
+
+// injectCustomCode inserts the head snippet before the last </head> and the
+// body snippet before the last </body>. A slot is skipped if the template
+// has no matching tag.
+func injectCustomCode(html string, code *types.CustomCode) string {
+	if code == nil {
+		return html
+	}
+	html = insertBeforeLast(html, headClosePattern, code.HeadHTML)
+	return insertBeforeLast(html, bodyClosePattern, code.BodyEndHTML)
+}
+
+// insertBeforeLast inserts snippet before the last match of pattern
+func insertBeforeLast(html string, pattern *regexp.Regexp, snippet string) string {
+	if strings.TrimSpace(snippet) == "" {
+		return html
+	}
+	matches := pattern.FindAllStringIndex(html, -1)
+	if len(matches) == 0 {
+		return html
+	}
+	at := matches[len(matches)-1][0]
+	return html[:at] + snippet + "\n" + html[at:]
+}
+
+// snippetHash returns a short hash identifying a snippet in the audit log
+func snippetHash(html string) string {
+	if html == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(html))
+	return hex.EncodeToString(sum[:])[:16]
+}
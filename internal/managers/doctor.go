@@ -0,0 +1,334 @@
+package managers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Doctor check results, from best to worst
+const (
+	DoctorOK      = "ok"
+	DoctorWarning = "warning"
+	DoctorError   = "error"
+)
+
+// Thresholds of the doctor checks
+const (
+	staleTempAge   = 10 * time.Minute
+	maxClockSkew   = 2 * time.Minute
+	futureFileSlop = 5 * time.Minute
+)
+
+// DoctorCheck is the result of one check, with a suggested fix when it did
+// not pass
+type DoctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Path    string `json:"path,omitempty"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+// DoctorReport lists every check. Status is the worst result.
+type DoctorReport struct {
+	CheckedAt time.Time     `json:"checked_at"`
+	Status    string        `json:"status"`
+	Checks    []DoctorCheck `json:"checks"`
+}
+
+// Doctor inspects the data and output directories for problems that stop
+// the CMS from working, without changing anything
+type Doctor struct {
+	storage         *FileStorage
+	templateManager *TemplateManager
+	dirs            [][2]string
+}
+
+// NewDoctor creates a doctor for the data directory of storage and the
+// other directories the server uses
+func NewDoctor(storage *FileStorage, templateManager *TemplateManager, outputDir, staticDir, templatesDir string) *Doctor {
+	return &Doctor{
+		storage:         storage,
+		templateManager: templateManager,
+		dirs: [][2]string{
+			{"data", storage.GetFilePath("")},
+			{"images", storage.GetFilePath("images")},
+			{"output", outputDir},
+			{"static", staticDir},
+			{"templates", templatesDir},
+		},
+	}
+}
+
+// Run performs every check. reference is a trusted time to compare the
+// server clock with, such as the admin's browser clock; it is skipped when
+// zero.
+func (d *Doctor) Run(now, reference time.Time) *DoctorReport {
+	report := &DoctorReport{CheckedAt: now}
+	add := func(checks ...DoctorCheck) {
+		report.Checks = append(report.Checks, checks...)
+	}
+
+	add(d.checkDirectories()...)
+	add(d.checkDataVersion())
+	files, walkErr := d.dataFiles()
+	if walkErr != nil {
+		add(DoctorCheck{Name: "data_files", Status: DoctorError, Message: walkErr.Error(), Path: d.storage.GetFilePath(""),
+			Fix: "Make the data directory readable by the user running the server"})
+	}
+	add(d.checkTempFiles(files, now)...)
+	add(d.checkOrphanBackups(files)...)
+	add(d.checkJSON(files)...)
+	add(d.checkSecretsPermissions())
+	add(d.checkTemplate())
+	add(d.checkClock(files, now, reference)...)
+
+	report.Status = DoctorOK
+	for _, check := range report.Checks {
+		if check.Status == DoctorError || (check.Status == DoctorWarning && report.Status == DoctorOK) {
+			report.Status = check.Status
+		}
+	}
+	return report
+}
+
+// checkDirectories checks that every directory exists and is writable
+func (d *Doctor) checkDirectories() []DoctorCheck {
+	var checks []DoctorCheck
+	for _, dir := range d.dirs {
+		name, path := "directory_"+dir[0], dir[1]
+		info, err := os.Stat(path)
+		switch {
+		case os.IsNotExist(err):
+			checks = append(checks, DoctorCheck{Name: name, Status: DoctorError, Message: "Directory is missing", Path: path,
+				Fix: fmt.Sprintf("Create it with `mkdir -p %s`, or restart the server to create it", path)})
+			continue
+		case err != nil:
+			checks = append(checks, DoctorCheck{Name: name, Status: DoctorError, Message: err.Error(), Path: path,
+				Fix: "Check the permissions of the parent directory"})
+			continue
+		case !info.IsDir():
+			checks = append(checks, DoctorCheck{Name: name, Status: DoctorError, Message: "Path is a file, not a directory", Path: path,
+				Fix: "Move the file away or point the setting at a directory"})
+			continue
+		}
+
+		probe, err := os.CreateTemp(path, ".doctor-*")
+		if err != nil {
+			checks = append(checks, DoctorCheck{Name: name, Status: DoctorError, Message: "Directory is not writable", Path: path,
+				Fix: fmt.Sprintf("Give the user running the server write access, e.g. `chown -R $(id -u) %s`", path)})
+			continue
+		}
+		probe.Close()
+		os.Remove(probe.Name())
+		checks = append(checks, DoctorCheck{Name: name, Status: DoctorOK, Message: "Exists and is writable", Path: path})
+	}
+	return checks
+}
+
+// checkDataVersion reports pending or unsupported data migrations
+func (d *Doctor) checkDataVersion() DoctorCheck {
+	check := DoctorCheck{Name: "data_version", Path: d.storage.GetFilePath(DataVersionFilename)}
+	pending, err := NewMigrationRunner(d.storage).Pending()
+	switch {
+	case err != nil:
+		check.Status, check.Message = DoctorError, err.Error()
+		check.Fix = "Run a release that supports this data directory, or restore it from migration-backups"
+	case len(pending) > 0:
+		check.Status = DoctorWarning
+		check.Message = fmt.Sprintf("%d migration(s) pending", len(pending))
+		check.Fix = "Restart the server to apply them; a backup is taken first"
+	default:
+		check.Status, check.Message = DoctorOK, fmt.Sprintf("At version %d", CurrentDataVersion())
+	}
+	return check
+}
+
+// dataFiles lists the files of the data directory, relative to it, leaving
+// out migration backups
+func (d *Doctor) dataFiles() (map[string]fs.FileInfo, error) {
+	root := d.storage.GetFilePath("")
+	files := make(map[string]fs.FileInfo)
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root && os.IsNotExist(err) {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		if entry.IsDir() {
+			if rel == migrationBackupsDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		files[rel] = info
+		return nil
+	})
+	return files, err
+}
+
+// checkTempFiles finds temporary files left behind by interrupted writes
+func (d *Doctor) checkTempFiles(files map[string]fs.FileInfo, now time.Time) []DoctorCheck {
+	var checks []DoctorCheck
+	for _, rel := range sortedFileNames(files) {
+		if !strings.HasSuffix(rel, ".tmp") || now.Sub(files[rel].ModTime()) < staleTempAge {
+			continue
+		}
+		checks = append(checks, DoctorCheck{Name: "stale_temp_file", Status: DoctorWarning,
+			Message: "Temporary file left by an interrupted write", Path: d.storage.GetFilePath(rel),
+			Fix: fmt.Sprintf("Delete it; %s holds the last complete version", strings.TrimSuffix(rel, ".tmp"))})
+	}
+	if len(checks) == 0 {
+		checks = append(checks, DoctorCheck{Name: "stale_temp_file", Status: DoctorOK, Message: "No stale temporary files"})
+	}
+	return checks
+}
+
+// checkOrphanBackups finds .bak files whose original is gone
+func (d *Doctor) checkOrphanBackups(files map[string]fs.FileInfo) []DoctorCheck {
+	var checks []DoctorCheck
+	for _, rel := range sortedFileNames(files) {
+		original, ok := strings.CutSuffix(rel, ".bak")
+		if !ok {
+			continue
+		}
+		if _, exists := files[original]; exists {
+			continue
+		}
+		checks = append(checks, DoctorCheck{Name: "orphan_backup", Status: DoctorWarning,
+			Message: fmt.Sprintf("Backup of %s, which no longer exists", original), Path: d.storage.GetFilePath(rel),
+			Fix: fmt.Sprintf("Rename it to %s to restore it, or delete it", original)})
+	}
+	if len(checks) == 0 {
+		checks = append(checks, DoctorCheck{Name: "orphan_backup", Status: DoctorOK, Message: "No orphaned backups"})
+	}
+	return checks
+}
+
+// checkJSON parses every JSON file of the data directory
+func (d *Doctor) checkJSON(files map[string]fs.FileInfo) []DoctorCheck {
+	var checks []DoctorCheck
+	for _, rel := range sortedFileNames(files) {
+		if filepath.Ext(rel) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(d.storage.GetFilePath(rel))
+		if err == nil && json.Valid(data) {
+			continue
+		}
+
+		check := DoctorCheck{Name: "invalid_json", Status: DoctorError, Path: d.storage.GetFilePath(rel)}
+		if err != nil {
+			check.Message = err.Error()
+			check.Fix = "Check the file's permissions"
+		} else {
+			check.Message = "File is not valid JSON"
+			check.Fix = "Fix the syntax, or delete it to start from the default"
+			if _, hasBackup := files[rel+".bak"]; hasBackup {
+				check.Fix = fmt.Sprintf("Restore the previous version from %s.bak", rel)
+			}
+		}
+		checks = append(checks, check)
+	}
+	if len(checks) == 0 {
+		checks = append(checks, DoctorCheck{Name: "invalid_json", Status: DoctorOK, Message: "All JSON files parse"})
+	}
+	return checks
+}
+
+// checkSecretsPermissions warns when the secrets file is readable by others
+func (d *Doctor) checkSecretsPermissions() DoctorCheck {
+	path := d.storage.GetFilePath(secretsFilename)
+	check := DoctorCheck{Name: "secrets_permissions", Status: DoctorOK, Message: "No secrets stored", Path: path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return check
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		check.Status = DoctorWarning
+		check.Message = fmt.Sprintf("Secrets file has mode %v", info.Mode().Perm())
+		check.Fix = fmt.Sprintf("Run `chmod 600 %s`", path)
+		return check
+	}
+	check.Message = "Readable by its owner only"
+	return check
+}
+
+// checkTemplate parses and test-renders the saved template
+func (d *Doctor) checkTemplate() DoctorCheck {
+	const filename = "template.html"
+	check := DoctorCheck{Name: "template", Path: d.storage.GetFilePath(filename)}
+	if !d.storage.FileExists(filename) {
+		check.Status, check.Message = DoctorOK, "No template saved; the default is used"
+		return check
+	}
+
+	content, err := d.storage.ReadTextFile(filename)
+	if err == nil {
+		err = d.templateManager.ValidateTemplate(content)
+	}
+	if err != nil {
+		check.Status, check.Message = DoctorError, err.Error()
+		check.Fix = "Fix the template in the admin editor, or restore the previous version with POST /admin/template/restore"
+		return check
+	}
+	check.Status, check.Message = DoctorOK, "Parses and renders"
+	return check
+}
+
+// checkClock compares the server clock with the reference time and looks
+// for files modified in the future, a sign the clock was set back
+func (d *Doctor) checkClock(files map[string]fs.FileInfo, now, reference time.Time) []DoctorCheck {
+	var checks []DoctorCheck
+	const fix = "Synchronize the server clock with NTP (e.g. `timedatectl set-ntp true`)"
+
+	if !reference.IsZero() {
+		check := DoctorCheck{Name: "clock_skew", Status: DoctorOK}
+		skew := now.Sub(reference).Round(time.Second)
+		if skew.Abs() > maxClockSkew {
+			check.Status = DoctorWarning
+			check.Message = fmt.Sprintf("Server clock differs from yours by %s; sessions, schedules and security.txt expiry depend on it", skew)
+			check.Fix = fix
+		} else {
+			check.Message = fmt.Sprintf("Server clock is within %s of yours", maxClockSkew)
+		}
+		checks = append(checks, check)
+	}
+
+	var future []string
+	for _, rel := range sortedFileNames(files) {
+		if files[rel].ModTime().After(now.Add(futureFileSlop)) {
+			future = append(future, rel)
+		}
+	}
+	if len(future) > 0 {
+		checks = append(checks, DoctorCheck{Name: "future_files", Status: DoctorWarning,
+			Message: fmt.Sprintf("%d file(s) modified in the future, e.g. %s", len(future), future[0]),
+			Path:    d.storage.GetFilePath(future[0]), Fix: fix})
+	} else {
+		checks = append(checks, DoctorCheck{Name: "future_files", Status: DoctorOK, Message: "No files modified in the future"})
+	}
+	return checks
+}
+
+// sortedFileNames returns the keys of files in order
+func sortedFileNames(files map[string]fs.FileInfo) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,108 @@
+package managers
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// EmbedInfo describes an allowed third-party embed
+type EmbedInfo struct {
+	Provider string `json:"provider"` // youtube, vimeo, google_maps or openstreetmap
+	Label    string `json:"label"`
+	URL      string `json:"url"`       // the URL as entered
+	EmbedURL string `json:"embed_url"` // privacy-enhanced player or map URL
+}
+
+var (
+	youtubeIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	vimeoIDPattern   = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// ParseEmbedURL checks a URL against the embed allow-list (YouTube, Vimeo,
+// Google Maps embeds and OpenStreetMap embeds) and returns its
+// privacy-enhanced embed URL. YouTube uses youtube-nocookie.com and Vimeo is
+// asked not to track.
+func ParseEmbedURL(raw string) (*EmbedInfo, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("embed must be an absolute URL")
+	}
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return nil, fmt.Errorf("embed URL must use https")
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	info := &EmbedInfo{URL: raw}
+
+	switch host {
+	case "youtube.com", "m.youtube.com", "youtube-nocookie.com", "youtu.be":
+		id := ""
+		switch {
+		case host == "youtu.be":
+			id = segments[0]
+		case segments[0] == "watch":
+			id = parsed.Query().Get("v")
+		case len(segments) == 2 && (segments[0] == "embed" || segments[0] == "shorts" || segments[0] == "live"):
+			id = segments[1]
+		}
+		if !youtubeIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("not a YouTube video URL")
+		}
+		info.Provider, info.Label = "youtube", "YouTube video"
+		info.EmbedURL = "https://www.youtube-nocookie.com/embed/" + id
+
+	case "vimeo.com", "player.vimeo.com":
+		id := segments[len(segments)-1]
+		if host == "player.vimeo.com" && (len(segments) != 2 || segments[0] != "video") {
+			id = ""
+		}
+		if !vimeoIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("not a Vimeo video URL")
+		}
+		info.Provider, info.Label = "vimeo", "Vimeo video"
+		info.EmbedURL = "https://player.vimeo.com/video/" + id + "?dnt=1"
+
+	case "google.com", "maps.google.com":
+		if parsed.Path != "/maps/embed" || parsed.RawQuery == "" {
+			return nil, fmt.Errorf("Google Maps URLs must be embed links (https://www.google.com/maps/embed?...)")
+		}
+		info.Provider, info.Label = "google_maps", "Google map"
+		info.EmbedURL = "https://www.google.com/maps/embed?" + parsed.RawQuery
+
+	case "openstreetmap.org":
+		if parsed.Path != "/export/embed.html" || parsed.RawQuery == "" {
+			return nil, fmt.Errorf("OpenStreetMap URLs must be embed links (https://www.openstreetmap.org/export/embed.html?...)")
+		}
+		info.Provider, info.Label = "openstreetmap", "OpenStreetMap map"
+		info.EmbedURL = "https://www.openstreetmap.org/export/embed.html?" + parsed.RawQuery
+
+	default:
+		return nil, fmt.Errorf("embeds from %s are not allowed; use YouTube, Vimeo, Google Maps or OpenStreetMap", parsed.Hostname())
+	}
+
+	return info, nil
+}
+
+// embedHTML renders a click-to-load placeholder for an allowed embed. Nothing
+// is fetched from the provider until the visitor asks for it; without
+// JavaScript the placeholder is a plain link. Disallowed URLs render nothing.
+func embedHTML(value interface{}) template.HTML {
+	raw, ok := value.(string)
+	if !ok || raw == "" {
+		return ""
+	}
+
+	info, err := ParseEmbedURL(raw)
+	if err != nil {
+		return ""
+	}
+
+	escape := template.HTMLEscapeString
+	return template.HTML(fmt.Sprintf(
+		`<div class="embed embed-%s" data-embed-src="%s" data-embed-title="%s"><a class="embed-load" href="%s" rel="noopener">Load %s</a></div>`,
+		info.Provider, escape(info.EmbedURL), escape(info.Label), escape(info.URL), escape(info.Label)))
+}
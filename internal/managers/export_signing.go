@@ -0,0 +1,216 @@
+package managers
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ExportFormat identifies a signed content export
+const (
+	ExportFormat  = "onepagems-export"
+	ExportVersion = 1
+)
+
+// Signature algorithms of signed exports
+const (
+	ExportAlgorithmHMAC    = "hmac-sha256"
+	ExportAlgorithmEd25519 = "ed25519"
+)
+
+// ed25519KeyPrefix marks an ed25519 key in the signing key setting; any
+// other value is used as an HMAC secret
+const ed25519KeyPrefix = "ed25519:"
+
+// Import verification failures
+var (
+	ErrExportUnsigned      = errors.New("export is not signed")
+	ErrExportKeyMismatch   = errors.New("export was signed with a different key")
+	ErrExportBadSignature  = errors.New("export signature does not match its content")
+	ErrExportUnknownFormat = errors.New("unsupported export format")
+)
+
+// SignedExport is the export file written when a signing key is configured.
+// The signature covers every other field, including when it was exported
+// and whether private fields were kept.
+type SignedExport struct {
+	Format          string           `json:"format"`
+	Version         int              `json:"version"`
+	ExportedAt      time.Time        `json:"exported_at"`
+	IncludesPrivate bool             `json:"includes_private"`
+	Content         json.RawMessage  `json:"content"`
+	Signature       *ExportSignature `json:"signature,omitempty"`
+}
+
+// ExportSignature is the signature of a SignedExport. KeyID is a short
+// fingerprint of the key so a mismatch can be told apart from tampering.
+type ExportSignature struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	Value     string `json:"value"`
+}
+
+// ExportSigner signs content exports and verifies them on import
+type ExportSigner struct {
+	algorithm  string
+	secret     []byte
+	privateKey ed25519.PrivateKey
+	keyID      string
+}
+
+// NewExportSigner creates a signer from the signing key setting. A value of
+// "ed25519:" followed by a base64 seed (32 bytes) or private key (64 bytes)
+// selects ed25519; anything else is an HMAC-SHA256 secret.
+func NewExportSigner(key string) (*ExportSigner, error) {
+	if encoded, ok := strings.CutPrefix(key, ed25519KeyPrefix); ok {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ed25519 key: %w", err)
+		}
+		var privateKey ed25519.PrivateKey
+		switch len(raw) {
+		case ed25519.SeedSize:
+			privateKey = ed25519.NewKeyFromSeed(raw)
+		case ed25519.PrivateKeySize:
+			privateKey = ed25519.PrivateKey(raw)
+		default:
+			return nil, fmt.Errorf("invalid ed25519 key: expected %d or %d bytes, got %d", ed25519.SeedSize, ed25519.PrivateKeySize, len(raw))
+		}
+		publicKey := privateKey.Public().(ed25519.PublicKey)
+		return &ExportSigner{
+			algorithm:  ExportAlgorithmEd25519,
+			privateKey: privateKey,
+			keyID:      keyFingerprint(publicKey),
+		}, nil
+	}
+
+	if len(key) < 16 {
+		return nil, fmt.Errorf("HMAC signing key must be at least 16 characters")
+	}
+	return &ExportSigner{
+		algorithm: ExportAlgorithmHMAC,
+		secret:    []byte(key),
+		keyID:     hmacFingerprint([]byte(key)),
+	}, nil
+}
+
+// Algorithm returns the signature algorithm in use
+func (s *ExportSigner) Algorithm() string {
+	return s.algorithm
+}
+
+// KeyID returns the fingerprint recorded in signatures
+func (s *ExportSigner) KeyID() string {
+	return s.keyID
+}
+
+// Sign wraps exported content in a signed export
+func (s *ExportSigner) Sign(content []byte, includesPrivate bool, now time.Time) ([]byte, error) {
+	export := &SignedExport{
+		Format:          ExportFormat,
+		Version:         ExportVersion,
+		ExportedAt:      now.UTC(),
+		IncludesPrivate: includesPrivate,
+		Content:         json.RawMessage(content),
+	}
+
+	payload, err := export.signingPayload()
+	if err != nil {
+		return nil, err
+	}
+	export.Signature = &ExportSignature{
+		Algorithm: s.algorithm,
+		KeyID:     s.keyID,
+		Value:     base64.StdEncoding.EncodeToString(s.sign(payload)),
+	}
+	return json.MarshalIndent(export, "", "  ")
+}
+
+// Verify checks the signature of an export. Fields are compared in canonical
+// form, so re-indenting the file does not break the signature but changing
+// any value does.
+func (s *ExportSigner) Verify(export *SignedExport) error {
+	if export.Format != ExportFormat || export.Version != ExportVersion {
+		return ErrExportUnknownFormat
+	}
+	if export.Signature == nil || export.Signature.Value == "" {
+		return ErrExportUnsigned
+	}
+	if export.Signature.Algorithm != s.algorithm || export.Signature.KeyID != s.keyID {
+		return ErrExportKeyMismatch
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(export.Signature.Value)
+	if err != nil {
+		return ErrExportBadSignature
+	}
+	payload, err := export.signingPayload()
+	if err != nil {
+		return ErrExportBadSignature
+	}
+
+	switch s.algorithm {
+	case ExportAlgorithmEd25519:
+		publicKey := s.privateKey.Public().(ed25519.PublicKey)
+		if !ed25519.Verify(publicKey, payload, signature) {
+			return ErrExportBadSignature
+		}
+	default:
+		if !hmac.Equal(s.sign(payload), signature) {
+			return ErrExportBadSignature
+		}
+	}
+	return nil
+}
+
+// sign returns the raw signature of payload
+func (s *ExportSigner) sign(payload []byte) []byte {
+	if s.algorithm == ExportAlgorithmEd25519 {
+		return ed25519.Sign(s.privateKey, payload)
+	}
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// signingPayload is the canonical encoding of the export without its
+// signature: compact JSON with object keys sorted and numbers as written
+func (e *SignedExport) signingPayload() ([]byte, error) {
+	unsigned := *e
+	unsigned.Signature = nil
+	encoded, err := json.Marshal(&unsigned)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// keyFingerprint returns the first 8 bytes of the SHA-256 of a public key,
+// in hex
+func keyFingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// hmacFingerprint identifies an HMAC secret without exposing a plain hash
+// of it, which would allow guessing weak secrets offline
+func hmacFingerprint(secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("onepagems export key id"))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
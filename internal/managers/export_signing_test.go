@@ -0,0 +1,153 @@
+package managers
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEd25519Key returns an ed25519 signing key setting for a fixed seed
+func testEd25519Key(seed byte) string {
+	return ed25519KeyPrefix + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+}
+
+// signTestExport signs content and decodes the result as an import would
+func signTestExport(t *testing.T, signer *ExportSigner, content string) *SignedExport {
+	t.Helper()
+	data, err := signer.Sign([]byte(content), false, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	var export SignedExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		t.Fatalf("signed export is not JSON: %v", err)
+	}
+	return &export
+}
+
+func TestNewExportSigner(t *testing.T) {
+	seed := bytes.Repeat([]byte{7}, ed25519.SeedSize)
+	tests := []struct {
+		name          string
+		key           string
+		wantAlgorithm string
+	}{
+		{"HMAC secret", "a long enough secret", ExportAlgorithmHMAC},
+		{"ed25519 seed", testEd25519Key(7), ExportAlgorithmEd25519},
+		{"ed25519 private key", ed25519KeyPrefix + base64.StdEncoding.EncodeToString(ed25519.NewKeyFromSeed(seed)), ExportAlgorithmEd25519},
+		{"short HMAC secret", "too short", ""},
+		{"ed25519 key not base64", ed25519KeyPrefix + "not base64!", ""},
+		{"ed25519 key of the wrong size", ed25519KeyPrefix + base64.StdEncoding.EncodeToString(make([]byte, 16)), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewExportSigner(tt.key)
+			if tt.wantAlgorithm == "" {
+				if err == nil {
+					t.Fatal("NewExportSigner accepted the key")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewExportSigner: %v", err)
+			}
+			if signer.Algorithm() != tt.wantAlgorithm || len(signer.KeyID()) != 16 {
+				t.Errorf("algorithm %q, key ID %q", signer.Algorithm(), signer.KeyID())
+			}
+		})
+	}
+
+	// A seed and the private key made from it are the same key
+	fromSeed, _ := NewExportSigner(testEd25519Key(7))
+	fromKey, _ := NewExportSigner(ed25519KeyPrefix + base64.StdEncoding.EncodeToString(ed25519.NewKeyFromSeed(seed)))
+	if fromSeed.KeyID() != fromKey.KeyID() {
+		t.Error("a seed and its private key have different key IDs")
+	}
+}
+
+func TestExportSignerRoundTrip(t *testing.T) {
+	for _, key := range []string{"a long enough secret", testEd25519Key(7)} {
+		signer, err := NewExportSigner(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Run(signer.Algorithm(), func(t *testing.T) {
+			export := signTestExport(t, signer, `{"title":"Home","count":1.50,"tags":["a","b"]}`)
+			if export.Format != ExportFormat || export.Version != ExportVersion || export.Signature.KeyID != signer.KeyID() {
+				t.Fatalf("signed export %+v", export)
+			}
+			if err := signer.Verify(export); err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+
+			// Reformatting the content keeps the signature valid
+			var indented bytes.Buffer
+			json.Indent(&indented, export.Content, "", "    ")
+			export.Content = indented.Bytes()
+			if err := signer.Verify(export); err != nil {
+				t.Errorf("Verify after re-indenting: %v", err)
+			}
+		})
+	}
+}
+
+func TestExportSignerRejectsTampering(t *testing.T) {
+	signer, err := NewExportSigner(testEd25519Key(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, _ := NewExportSigner(testEd25519Key(8))
+	hmacSigner, _ := NewExportSigner("a long enough secret")
+
+	tests := []struct {
+		name    string
+		tamper  func(*SignedExport)
+		verify  *ExportSigner
+		wantErr error
+	}{
+		{"changed content", func(e *SignedExport) { e.Content = json.RawMessage(`{"title":"Hacked"}`) }, signer, ErrExportBadSignature},
+		{"changed number", func(e *SignedExport) { e.Content = json.RawMessage(`{"title":"Home","count":1.5}`) }, signer, ErrExportBadSignature},
+		{"private fields claimed", func(e *SignedExport) { e.IncludesPrivate = true }, signer, ErrExportBadSignature},
+		{"changed export time", func(e *SignedExport) { e.ExportedAt = e.ExportedAt.Add(time.Hour) }, signer, ErrExportBadSignature},
+		{"signature not base64", func(e *SignedExport) { e.Signature.Value = "!!" }, signer, ErrExportBadSignature},
+		{"signature removed", func(e *SignedExport) { e.Signature = nil }, signer, ErrExportUnsigned},
+		{"empty signature", func(e *SignedExport) { e.Signature.Value = "" }, signer, ErrExportUnsigned},
+		{"unknown format", func(e *SignedExport) { e.Format = "other" }, signer, ErrExportUnknownFormat},
+		{"newer version", func(e *SignedExport) { e.Version = ExportVersion + 1 }, signer, ErrExportUnknownFormat},
+		{"another ed25519 key", func(e *SignedExport) {}, otherKey, ErrExportKeyMismatch},
+		{"an HMAC key", func(e *SignedExport) {}, hmacSigner, ErrExportKeyMismatch},
+		{
+			"forged key ID", func(e *SignedExport) {
+				forged := signTestExport(t, otherKey, `{"title":"Hacked"}`)
+				forged.Signature.KeyID = signer.KeyID()
+				*e = *forged
+			}, signer, ErrExportBadSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export := signTestExport(t, signer, `{"title":"Home","count":1.50}`)
+			tt.tamper(export)
+			if err := tt.verify.Verify(export); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify: %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHMACFingerprintDoesNotExposeSecret(t *testing.T) {
+	const secret = "a long enough secret"
+	signer, _ := NewExportSigner(secret)
+	if signer.KeyID() == keyFingerprint([]byte(secret)) {
+		t.Error("the HMAC key ID is a plain hash of the secret")
+	}
+	other, _ := NewExportSigner(strings.ToUpper(secret))
+	if signer.KeyID() == other.KeyID() {
+		t.Error("two secrets share a key ID")
+	}
+}
@@ -14,6 +14,9 @@ type FormGenerator struct {
 	parser      *SchemaParser
 	validator   *SchemaValidator
 	imageFields []string // tracks fields that should be image pickers
+	limits      types.FormLimits
+
+	schemaLimits types.SchemaLimits
 }
 
 // NewFormGenerator creates a new form generator
@@ -29,37 +32,166 @@ func NewFormGenerator(schema *types.SchemaData) *FormGenerator {
 	}
 }
 
+// SetSchemaLimits sets the largest schema a form is generated from
+func (fg *FormGenerator) SetSchemaLimits(limits types.SchemaLimits) {
+	fg.schemaLimits = limits
+	fg.parser.SetSchemaLimits(limits)
+	fg.validator.SetSchemaLimits(limits)
+}
+
+// SetLimits sets the server-wide limits that field metadata includes
+func (fg *FormGenerator) SetLimits(limits types.FormLimits) {
+	fg.limits = limits
+	fg.validator.SetLimits(limits)
+}
+
 // GenerateForm generates a complete form from the JSON schema
 func (fg *FormGenerator) GenerateForm() (*types.GeneratedForm, error) {
 	if fg.schema == nil {
 		return nil, fmt.Errorf("schema is nil")
 	}
+	schema, err := resolveSchema(fg.schema, fg.schemaLimits)
+	if err != nil {
+		return nil, err
+	}
 
-	fields, err := fg.generateFormFields("", fg.schema.Properties, false)
+	fields, err := fg.generateFormFields("", schema.Properties, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate form fields: %w", err)
 	}
 
-	// Sort fields to ensure consistent ordering
-	sort.Slice(fields, func(i, j int) bool {
-		return fg.getFieldPriority(fields[i]) < fg.getFieldPriority(fields[j])
-	})
-
 	form := &types.GeneratedForm{
 		Fields: fields,
 		Action: "/admin/content",
 		Method: "POST",
 	}
+	fg.applyKeyboardMetadata(form)
+	applyAccessibilityMetadata(form.Fields)
+	if fg.limits != (types.FormLimits{}) {
+		limits := fg.limits
+		form.Limits = &limits
+	}
 
 	return form, nil
 }
 
-// generateFormFields recursively generates form fields from schema properties
+// applyKeyboardMetadata numbers the focusable fields in tab order and picks
+// the field to autofocus. Fields are already in display order; object
+// headings are not focusable.
+func (fg *FormGenerator) applyKeyboardMetadata(form *types.GeneratedForm) {
+	tabIndex := 0
+	for i := range form.Fields {
+		field := &form.Fields[i]
+		if field.Type == "object" {
+			field.Autofocus = false
+			continue
+		}
+
+		tabIndex++
+		field.TabIndex = tabIndex
+		if field.Autofocus && form.Autofocus == "" {
+			form.Autofocus = field.Name
+		} else {
+			field.Autofocus = false
+		}
+	}
+
+	// Without a schema hint, focus the first field
+	if form.Autofocus == "" {
+		for i := range form.Fields {
+			if form.Fields[i].TabIndex == 1 {
+				form.Fields[i].Autofocus = true
+				form.Autofocus = form.Fields[i].Name
+			}
+		}
+	}
+}
+
+// applyAccessibilityMetadata gives fields, and the item fields of their
+// groups, element ids for the input, help text and validation message,
+// and places each in the fieldset of its object or array item
+func applyAccessibilityMetadata(fields []types.FormField) {
+	objects := make(map[string]bool)
+	for i := range fields {
+		field := &fields[i]
+		anchor := strings.ReplaceAll(field.Name, ".", "-")
+		field.ID = "field-" + anchor
+		field.ErrorID = "error-" + anchor
+		field.DescribedBy = field.ErrorID
+		if field.Description != "" {
+			field.HelpID = "help-" + anchor
+			field.DescribedBy = field.HelpID + " " + field.ErrorID
+		}
+
+		// Nested fields follow their object, so it is already known
+		if dot := strings.LastIndex(field.Name, "."); dot >= 0 {
+			if parent := field.Name[:dot]; objects[parent] || strings.HasSuffix(parent, "[]") {
+				field.Fieldset = parent
+			}
+		}
+		if field.Type == "object" {
+			objects[field.Name] = true
+			field.Legend = strings.TrimSpace(field.Label)
+		}
+		if field.Group != nil {
+			applyAccessibilityMetadata(field.Group.Fields)
+		}
+	}
+}
+
+// orderedPropertyNames returns the names of an object's properties in display
+// order: by propertyOrder when set, then title and description first at every
+// level, then the order they are declared in the schema file, then by name
+func (fg *FormGenerator) orderedPropertyNames(prefix string, properties map[string]interface{}) []string {
+	declared := make(map[string]int)
+	for i, name := range fg.schema.PropertyOrder[prefix] {
+		declared[name] = i
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+
+	rank := func(name string) (float64, int, int) {
+		order := 1000.0
+		if prop, ok := properties[name].(map[string]interface{}); ok {
+			if value, ok := fg.validator.toFloat64(prop["propertyOrder"]); ok {
+				order = value
+			}
+		}
+
+		position, ok := declared[name]
+		if !ok {
+			position = len(declared)
+		}
+		return order, fg.getFieldPriority(types.FormField{Name: name}), position
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		orderI, priorityI, positionI := rank(names[i])
+		orderJ, priorityJ, positionJ := rank(names[j])
+		if orderI != orderJ {
+			return orderI < orderJ
+		}
+		if priorityI != priorityJ {
+			return priorityI < priorityJ
+		}
+		if positionI != positionJ {
+			return positionI < positionJ
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// generateFormFields recursively generates form fields from schema properties.
+// Nested fields follow their parent object so the form reads top to bottom.
 func (fg *FormGenerator) generateFormFields(prefix string, properties map[string]interface{}, isNested bool) ([]types.FormField, error) {
 	var fields []types.FormField
 
-	for fieldName, propData := range properties {
-		propMap, ok := propData.(map[string]interface{})
+	for _, fieldName := range fg.orderedPropertyNames(prefix, properties) {
+		propMap, ok := properties[fieldName].(map[string]interface{})
 		if !ok {
 			continue
 		}
@@ -74,6 +206,13 @@ func (fg *FormGenerator) generateFormFields(prefix string, properties map[string
 			return nil, fmt.Errorf("failed to create field %s: %w", fieldName, err)
 		}
 
+		// Arrays of objects are edited as repeatable groups of fields
+		if items, ok := propMap["items"].(map[string]interface{}); ok && field.Type == "array" && primaryType(items) == "object" {
+			if field.Group, err = fg.generateFieldGroup(field, propMap, items); err != nil {
+				return nil, fmt.Errorf("failed to generate item fields for %s: %w", fieldName, err)
+			}
+		}
+
 		fields = append(fields, field)
 
 		// Handle nested objects
@@ -91,6 +230,70 @@ func (fg *FormGenerator) generateFormFields(prefix string, properties map[string
 	return fields, nil
 }
 
+// generateFieldGroup describes the items of an array of objects: their
+// fields, named with "[]" for the index, and what the editor may do to the
+// list. The array's "x-sortable": false keeps items in their order, and an
+// array whose minItems equals its maxItems has a fixed number of items.
+func (fg *FormGenerator) generateFieldGroup(field types.FormField, prop, items map[string]interface{}) (*types.FormFieldGroup, error) {
+	itemProps, _ := items["properties"].(map[string]interface{})
+	prefix := field.Name + "[]"
+	fields, err := fg.generateFormFields(prefix, itemProps, true)
+	if err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		fields = make([]types.FormField, 0)
+	}
+
+	// Item fields listed in the item schema's required array are required
+	if required, ok := items["required"].([]interface{}); ok {
+		for i := range fields {
+			for _, name := range required {
+				if fields[i].Name == prefix+"."+fmt.Sprint(name) {
+					fields[i].Required = true
+				}
+			}
+		}
+	}
+
+	group := &types.FormFieldGroup{
+		ItemLabel:  "Item",
+		Fields:     fields,
+		NewItem:    make(map[string]interface{}),
+		CanReorder: true,
+	}
+	if title, ok := items["title"].(string); ok && title != "" {
+		group.ItemLabel = title
+	}
+	if sortable, ok := prop["x-sortable"].(bool); ok {
+		group.CanReorder = sortable
+	}
+	fixed := field.MaxItems > 0 && field.MinItems == field.MaxItems
+	group.CanAdd, group.CanRemove = !fixed, !fixed
+
+	if defaults, ok := items["default"].(map[string]interface{}); ok {
+		for name, value := range defaults {
+			group.NewItem[name] = value
+		}
+	}
+	for name, raw := range itemProps {
+		if itemProp, ok := raw.(map[string]interface{}); ok {
+			if value, ok := itemProp["default"]; ok {
+				group.NewItem[name] = value
+			}
+		}
+	}
+
+	// The first text field named like a heading sums up a collapsed item
+	for _, candidate := range []string{"title", "name", "label", "heading"} {
+		if prop, ok := itemProps[candidate].(map[string]interface{}); ok && primaryType(prop) == "string" {
+			group.SummaryField = prefix + "." + candidate
+			break
+		}
+	}
+	return group, nil
+}
+
 // createFormField creates a single form field from a schema property
 func (fg *FormGenerator) createFormField(fullName, displayName string, prop map[string]interface{}, isNested bool) (types.FormField, error) {
 	field := types.FormField{
@@ -106,6 +309,15 @@ func (fg *FormGenerator) createFormField(fullName, displayName string, prop map[
 
 	// Handle special cases
 	fg.handleSpecialFieldTypes(&field, prop)
+	if field.Type == "image" {
+		field.MaxSize = fg.limits.MaxUploadSize
+	}
+
+	// Nullable fields are optional and can be cleared back to null
+	if _, nullable := parseSchemaType(prop); nullable {
+		field.Nullable = true
+		field.Required = false
+	}
 
 	// Apply nested field styling
 	if isNested {
@@ -136,11 +348,18 @@ func (fg *FormGenerator) extractBasicProperties(field *types.FormField, displayN
 	if defaultValue, ok := prop["default"]; ok {
 		field.Value = defaultValue
 	}
+
+	// The schema can ask for a field to take focus when the form opens
+	if autofocus, ok := prop["autofocus"].(bool); ok {
+		field.Autofocus = autofocus
+	}
+
+	field.Private = isPrivate(prop)
 }
 
 // extractTypeAndFormat determines the field type based on schema type and format
 func (fg *FormGenerator) extractTypeAndFormat(field *types.FormField, prop map[string]interface{}) {
-	fieldType, _ := prop["type"].(string)
+	fieldType := primaryType(prop)
 	format, _ := prop["format"].(string)
 
 	switch fieldType {
@@ -173,7 +392,7 @@ func (fg *FormGenerator) handleStringField(field *types.FormField, format string
 		field.Type = "password"
 	case "textarea":
 		field.Type = "textarea"
-	case "url":
+	case "url", "embed":
 		field.Type = "url"
 	case "tel":
 		field.Type = "tel"
@@ -206,12 +425,13 @@ func (fg *FormGenerator) handleArrayField(field *types.FormField, prop map[strin
 
 	// Extract array item type
 	if items, ok := prop["items"].(map[string]interface{}); ok {
-		if itemType, ok := items["type"].(string); ok {
+		itemType := primaryType(items)
+		if itemType != "" {
 			field.Format = itemType
 		}
 
 		// For string arrays with enum, convert to multi-select
-		if itemType, ok := items["type"].(string); ok && itemType == "string" {
+		if itemType == "string" {
 			if enum, ok := items["enum"].([]interface{}); ok {
 				field.Type = "multiselect"
 				field.Options = fg.convertEnumToOptions(enum)
@@ -231,8 +451,20 @@ func (fg *FormGenerator) extractValidationConstraints(field *types.FormField, pr
 	if minLength, ok := prop["minLength"]; ok {
 		if minLen, ok := minLength.(float64); ok {
 			field.Required = field.Required || minLen > 0
+			field.MinLength = int(minLen)
 		}
 	}
+	if primaryType(prop) == "string" {
+		field.MaxLength = maxLengthFor(prop, fg.limits)
+	}
+
+	// Array size constraints
+	if primaryType(prop) == "array" {
+		if minItems, ok := prop["minItems"].(float64); ok {
+			field.MinItems = int(minItems)
+		}
+		field.MaxItems = maxItemsFor(prop, fg.limits)
+	}
 
 	// Number constraints
 	if field.Type == "number" {
@@ -316,24 +548,7 @@ func (fg *FormGenerator) isImageField(prop map[string]interface{}) bool {
 
 // isRichTextField determines if a field should use a rich text editor
 func (fg *FormGenerator) isRichTextField(prop map[string]interface{}) bool {
-	title, _ := prop["title"].(string)
-	description, _ := prop["description"].(string)
-	format, _ := prop["format"].(string)
-
-	if format == "html" || format == "richtext" {
-		return true
-	}
-
-	richTextKeywords := []string{"html", "rich", "formatted", "wysiwyg"}
-
-	for _, keyword := range richTextKeywords {
-		if strings.Contains(strings.ToLower(title), keyword) ||
-			strings.Contains(strings.ToLower(description), keyword) {
-			return true
-		}
-	}
-
-	return false
+	return isRichTextProperty(prop)
 }
 
 // convertEnumToOptions converts enum values to string options
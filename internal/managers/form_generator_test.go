@@ -0,0 +1,12 @@
+package managers
+
+import "testing"
+
+func BenchmarkGenerateForm(b *testing.B) {
+	schema := loadBenchSchema(b)
+	for b.Loop() {
+		if _, err := NewFormGenerator(schema).GenerateForm(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
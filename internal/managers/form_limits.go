@@ -0,0 +1,62 @@
+package managers
+
+import (
+	"strings"
+
+	"onepagems/internal/types"
+)
+
+// maxLengthFor returns the longest value a string property may have: the
+// schema's maxLength, lowered to the rich text limit for rich text fields.
+// 0 means unlimited.
+func maxLengthFor(prop map[string]interface{}, limits types.FormLimits) int {
+	maxLength := 0
+	if value, ok := prop["maxLength"].(float64); ok {
+		maxLength = int(value)
+	}
+	if limits.MaxRichTextLength > 0 && isRichTextProperty(prop) {
+		maxLength = lowerLimit(maxLength, limits.MaxRichTextLength)
+	}
+	return maxLength
+}
+
+// maxItemsFor returns the most items an array property may have: the
+// schema's maxItems, lowered to the server-wide limit. 0 means unlimited.
+func maxItemsFor(prop map[string]interface{}, limits types.FormLimits) int {
+	maxItems := 0
+	if value, ok := prop["maxItems"].(float64); ok {
+		maxItems = int(value)
+	}
+	return lowerLimit(maxItems, limits.MaxArrayItems)
+}
+
+// lowerLimit returns the stricter of two limits where 0 means unlimited
+func lowerLimit(a, b int) int {
+	if a == 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// isRichTextProperty reports whether a property is edited as rich text, by
+// its format or a hint in its title or description
+func isRichTextProperty(prop map[string]interface{}) bool {
+	title, _ := prop["title"].(string)
+	description, _ := prop["description"].(string)
+	format, _ := prop["format"].(string)
+
+	if format == "html" || format == "richtext" {
+		return true
+	}
+
+	richTextKeywords := []string{"html", "rich", "formatted", "wysiwyg"}
+
+	for _, keyword := range richTextKeywords {
+		if strings.Contains(strings.ToLower(title), keyword) ||
+			strings.Contains(strings.ToLower(description), keyword) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,713 @@
+package managers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"onepagems/internal/tracing"
+	"onepagems/internal/types"
+)
+
+// policyFilename stores the Content-Security-Policy of the generated site
+const policyFilename = "index.csp"
+
+// BuildBackupFailed starts the generation error reported when the build
+// could not be kept for rollback
+const BuildBackupFailed = "failed to keep build for rollback: "
+
+// PublicContentFilename is the published content served at /content.json
+const PublicContentFilename = "content.json"
+
+// SiteGenerator renders the public index.html from the template and content
+type SiteGenerator struct {
+	storage         *FileStorage // data directory, used to hash images
+	output          *FileStorage // output directory for generated files
+	templateManager *TemplateManager
+	contentManager  *ContentManager
+	customCode      *CustomCodeManager
+	schemaManager   *SchemaManager
+	slugManager     *SlugManager
+	variants        *VariantManager
+	schedules       *ScheduleManager
+	renderers       *SectionRendererManager
+	siteURL         string
+	securityContact string
+	securityPolicy  string
+	humansTxt       bool
+	cdnBaseURL      string
+	assetMode       string
+	location        *time.Location // site time zone
+	buildHistory    int            // builds kept for rollback
+	dataHooks       []namedTemplateDataHook
+}
+
+// NewSiteGenerator creates a new site generator writing into outputDir.
+// When cdnBaseURL is set, local /images/ references in content are rewritten
+// to that host with a content hash for cache busting. assetMode selects how
+// inline styles and scripts are emitted (see AssetModeInline/AssetModeExternal).
+func NewSiteGenerator(storage *FileStorage, templateManager *TemplateManager, contentManager *ContentManager, outputDir, cdnBaseURL, assetMode string) *SiteGenerator {
+	if !ValidAssetMode(assetMode) {
+		assetMode = AssetModeInline
+	}
+
+	return &SiteGenerator{
+		storage:         storage,
+		output:          NewFileStorage(outputDir),
+		templateManager: templateManager,
+		contentManager:  contentManager,
+		cdnBaseURL:      strings.TrimRight(cdnBaseURL, "/"),
+		assetMode:       assetMode,
+		location:        time.UTC,
+	}
+}
+
+// SetCustomCode injects the custom head and body-end code slots from cm
+// into every rendered page
+func (g *SiteGenerator) SetCustomCode(cm *CustomCodeManager) {
+	g.customCode = cm
+}
+
+// SetSchemaManager enables publishing content.json alongside the page, with
+// the fields the schema marks as private removed
+func (g *SiteGenerator) SetSchemaManager(sm *SchemaManager) {
+	g.schemaManager = sm
+}
+
+// SetSlugManager exposes stable section anchors to the template as
+// .anchors, keyed by section
+func (g *SiteGenerator) SetSlugManager(sm *SlugManager) {
+	g.slugManager = sm
+}
+
+// SetVariantManager enables A/B tests: when sections have a variant, a
+// second page with the variants in place is written as index.b.html
+func (g *SiteGenerator) SetVariantManager(vm *VariantManager) {
+	g.variants = vm
+}
+
+// SetScheduleManager applies scheduled content active at generation time
+// and records which schedules the live site was generated with
+func (g *SiteGenerator) SetScheduleManager(sm *ScheduleManager) {
+	g.schedules = sm
+}
+
+// SetSectionRenderers renders each content section with its named renderer
+// into .sections_html and .section_html for the template
+func (g *SiteGenerator) SetSectionRenderers(rm *SectionRendererManager) {
+	g.renderers = rm
+}
+
+// SetLocation sets the site time zone that dates are shown in
+func (g *SiteGenerator) SetLocation(loc *time.Location) {
+	g.location = loc
+}
+
+// SetSiteURL sets the public URL of the site, used for the site QR code and
+// the vCard URL
+func (g *SiteGenerator) SetSiteURL(siteURL string) {
+	g.siteURL = siteURL
+}
+
+// SetTextFiles configures the plain-text files written with the page. A
+// security.txt is written when securityContact is set or the content has a
+// contact email; humans.txt when humansTxt is true.
+func (g *SiteGenerator) SetTextFiles(securityContact, securityPolicy string, humansTxt bool) {
+	g.securityContact = securityContact
+	g.securityPolicy = securityPolicy
+	g.humansTxt = humansTxt
+}
+
+// OutputFile returns the full path of a generated file
+func (g *SiteGenerator) OutputFile(filename string) string {
+	return g.output.GetFilePath(filename)
+}
+
+// Generate renders the site and writes index.html, keeping the previous
+// version as index.html.bak. ctx is checked between phases so a cancelled
+// request never leaves a half-written site behind.
+func (g *SiteGenerator) Generate(ctx context.Context) (*types.GenerationResult, error) {
+	ctx, span := tracing.Start(ctx, "generate")
+	defer span.End()
+
+	result := &types.GenerationResult{
+		GeneratedAt: time.Now(),
+	}
+	fail := func(err error) (*types.GenerationResult, error) {
+		span.RecordError(err)
+		result.Errors = append(result.Errors, err.Error())
+		return result, err
+	}
+
+	site, err := g.renderSite(ctx, result.GeneratedAt)
+	if err != nil {
+		return fail(err)
+	}
+	content, scheduleIDs, assets, variant := site.content, site.scheduleIDs, site.page, site.variant
+
+	if err := os.MkdirAll(g.output.GetFilePath(""), 0755); err != nil {
+		return fail(fmt.Errorf("failed to prepare output directory: %w", err))
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fail(fmt.Errorf("generation cancelled: %w", err))
+	}
+
+	writeCtx, writeSpan := tracing.Start(ctx, "generate.write")
+	defer writeSpan.End()
+	output := g.output.WithContext(writeCtx)
+
+	// Both pages share the assets directory, so stale files are only the
+	// ones neither page references
+	assetFiles := assets.Files
+	if variant != nil {
+		assetFiles = make(map[string]string, len(assets.Files)+len(variant.Files))
+		for _, files := range []map[string]string{assets.Files, variant.Files} {
+			for name, body := range files {
+				assetFiles[name] = body
+			}
+		}
+	}
+	if err := g.writeAssets(output, assetFiles); err != nil {
+		writeSpan.RecordError(err)
+		return fail(err)
+	}
+
+	if err := output.WriteTextFile("index.html", assets.HTML); err != nil {
+		writeSpan.RecordError(err)
+		return fail(fmt.Errorf("failed to write index.html: %w", err))
+	}
+
+	// The policy is stored next to the page so the public handler serves a
+	// header that matches the generated assets
+	if err := output.WriteTextFile(policyFilename, assets.Policy); err != nil {
+		writeSpan.RecordError(err)
+		return fail(fmt.Errorf("failed to write content security policy: %w", err))
+	}
+
+	variantFiles := map[string]string{VariantPageFilename: "", variantPolicyFilename: ""}
+	if variant != nil {
+		variantFiles[VariantPageFilename] = variant.HTML
+		variantFiles[variantPolicyFilename] = variant.Policy
+	}
+	if err := writeOptionalFiles(output, variantFiles); err != nil {
+		writeSpan.RecordError(err)
+		return fail(err)
+	}
+
+	if err := g.writePublicContent(output, content, assets.HTML); err != nil {
+		writeSpan.RecordError(err)
+		return fail(err)
+	}
+
+	// The site is live at this point; failing to keep a copy for rollback
+	// is reported without failing the generation
+	if err := g.saveBuild(result.GeneratedAt); err != nil {
+		result.Errors = append(result.Errors, BuildBackupFailed+err.Error())
+	}
+	if g.schedules != nil {
+		if err := g.schedules.MarkApplied(scheduleIDs); err != nil {
+			result.Errors = append(result.Errors, "failed to record applied schedules: "+err.Error())
+		}
+	}
+
+	result.Success = true
+	result.OutputPath = g.OutputFile("index.html")
+	result.Size = int64(len(assets.HTML))
+	result.AssetMode = g.assetMode
+	result.ContentSecurityPolicy = assets.Policy
+	span.SetAttribute("output.size", result.Size)
+	return result, nil
+}
+
+// renderedSite is one render of the site, before anything is written
+type renderedSite struct {
+	content     *types.ContentData
+	scheduleIDs []string
+	page        *ProcessedAssets
+	variant     *ProcessedAssets // the B page of an A/B test, or nil
+}
+
+// renderSite renders the page, and the B page of a running A/B test, from
+// the content as of t. Generate writes the result; live mode serves it, so
+// both modes produce the same page.
+func (g *SiteGenerator) renderSite(ctx context.Context, t time.Time) (*renderedSite, error) {
+	content, scheduleIDs, err := g.loadContent(t)
+	if err != nil {
+		return nil, err
+	}
+
+	html, err := g.renderContent(ctx, content, VariantA)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("generation cancelled: %w", err)
+	}
+
+	_, assetSpan := tracing.Start(ctx, "generate.assets")
+	assetSpan.SetAttribute("asset_mode", g.assetMode)
+	assets, err := ProcessAssets(html, g.assetMode, g.cdnBaseURL)
+	assetSpan.RecordError(err)
+	assetSpan.End()
+	if err != nil {
+		return nil, fmt.Errorf("failed to process assets: %w", err)
+	}
+
+	variant, err := g.renderVariantPage(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	return &renderedSite{content: content, scheduleIDs: scheduleIDs, page: assets, variant: variant}, nil
+}
+
+// RenderPage renders index.html exactly as Generate would write it, with
+// assets processed, without writing anything
+func (g *SiteGenerator) RenderPage(ctx context.Context) (string, error) {
+	html, err := g.RenderHTML(ctx)
+	if err != nil {
+		return "", err
+	}
+	assets, err := ProcessAssets(html, g.assetMode, g.cdnBaseURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to process assets: %w", err)
+	}
+	return assets.HTML, nil
+}
+
+// RenderContentPage renders the page for content that is not saved, such as
+// a draft, with its assets inlined so it needs no other files
+func (g *SiteGenerator) RenderContentPage(ctx context.Context, content *types.ContentData) (*ProcessedAssets, error) {
+	html, err := g.renderContent(ctx, content, VariantA)
+	if err != nil {
+		return nil, err
+	}
+	assets, err := ProcessAssets(html, AssetModeInline, g.cdnBaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process assets: %w", err)
+	}
+	return assets, nil
+}
+
+// PublishedPage returns the index.html written by the last generation, or
+// an empty string if the site has not been generated yet
+func (g *SiteGenerator) PublishedPage() (string, error) {
+	if !g.output.FileExists("index.html") {
+		return "", nil
+	}
+	return g.output.ReadTextFile("index.html")
+}
+
+// PublishedContent returns the public content written by the last
+// generation, or an empty map if there is none
+func (g *SiteGenerator) PublishedContent() (map[string]interface{}, error) {
+	content := make(map[string]interface{})
+	if !g.output.FileExists(PublicContentFilename) {
+		return content, nil
+	}
+	if err := g.output.ReadJSONFile(PublicContentFilename, &content); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", PublicContentFilename, err)
+	}
+	return content, nil
+}
+
+// HasVariantPage reports whether the last generation wrote a B page
+func (g *SiteGenerator) HasVariantPage() bool {
+	return g.output.FileExists(VariantPageFilename)
+}
+
+// VariantContentSecurityPolicy returns the policy recorded for the B page
+func (g *SiteGenerator) VariantContentSecurityPolicy() string {
+	policy, err := g.output.ReadTextFile(variantPolicyFilename)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(policy)
+}
+
+// ContentSecurityPolicy returns the policy recorded for the generated site,
+// or an empty string if the site has not been generated yet
+func (g *SiteGenerator) ContentSecurityPolicy() string {
+	policy, err := g.output.ReadTextFile(policyFilename)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(policy)
+}
+
+// writePublicContent publishes the content the page was generated from,
+// without private fields, for client-side widgets and apps. With section
+// anchors enabled it also writes a search index of the sections in html.
+func (g *SiteGenerator) writePublicContent(output *FileStorage, content *types.ContentData, html string) error {
+	if g.schemaManager == nil {
+		return nil
+	}
+
+	public, err := g.schemaManager.PublicContent(content)
+	if err != nil {
+		return fmt.Errorf("failed to prepare public content: %w", err)
+	}
+
+	if err := output.WriteJSONFile(PublicContentFilename, public); err != nil {
+		return fmt.Errorf("failed to write %s: %w", PublicContentFilename, err)
+	}
+
+	if err := g.writeContactCard(output, public); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	humans := ""
+	if g.humansTxt {
+		humans = BuildHumansTxt(public, now.In(g.location))
+	}
+	files := map[string]string{
+		CalendarFilename:    BuildCalendar(public, g.siteURL, now),
+		SecurityTxtFilename: BuildSecurityTxt(public, g.securityContact, g.securityPolicy, g.siteURL, now),
+		HumansTxtFilename:   humans,
+	}
+	if err := writeOptionalFiles(output, files); err != nil {
+		return err
+	}
+
+	if g.slugManager == nil {
+		return nil
+	}
+	sections, _ := public["sections"].(map[string]interface{})
+	anchors, err := g.slugManager.Resolve(sections)
+	if err != nil {
+		return err
+	}
+	if err := output.WriteJSONFile(SearchIndexFilename, BuildSearchIndex(sections, anchors, html)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", SearchIndexFilename, err)
+	}
+	return nil
+}
+
+// writeContactCard writes the vCard and QR codes for the site URL and the
+// vCard, removing ones that no longer apply
+func (g *SiteGenerator) writeContactCard(output *FileStorage, public map[string]interface{}) error {
+	files := map[string]string{
+		VCardFilename:   BuildVCard(public, g.siteURL),
+		VCardQRFilename: "",
+		SiteQRFilename:  "",
+	}
+
+	if vcard := files[VCardFilename]; vcard != "" {
+		qr, err := EncodeQR([]byte(vcard))
+		if err != nil {
+			return fmt.Errorf("failed to encode vCard QR code: %w", err)
+		}
+		files[VCardQRFilename] = qr.SVG()
+	}
+	if g.siteURL != "" {
+		qr, err := EncodeQR([]byte(g.siteURL))
+		if err != nil {
+			return fmt.Errorf("failed to encode site QR code: %w", err)
+		}
+		files[SiteQRFilename] = qr.SVG()
+	}
+
+	return writeOptionalFiles(output, files)
+}
+
+// writeOptionalFiles writes each file, deleting ones whose body is empty so
+// files that no longer apply are not served
+func writeOptionalFiles(output *FileStorage, files map[string]string) error {
+	for name, body := range files {
+		if body == "" {
+			if output.FileExists(name) {
+				output.DeleteFile(name)
+			}
+			continue
+		}
+		if err := output.WriteBinaryFile(name, []byte(body)); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// writeAssets writes extracted asset files and removes ones no longer referenced
+func (g *SiteGenerator) writeAssets(output *FileStorage, files map[string]string) error {
+	for name, body := range files {
+		if err := output.WriteBinaryFile(name, []byte(body)); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	entries, err := os.ReadDir(g.output.GetFilePath("assets"))
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		name := path.Join("assets", entry.Name())
+		if _, current := files[name]; !current && !entry.IsDir() {
+			output.DeleteFile(name)
+		}
+	}
+	return nil
+}
+
+// RenderHTML renders the current template with the current content
+func (g *SiteGenerator) RenderHTML(ctx context.Context) (string, error) {
+	content, _, err := g.loadContent(time.Now())
+	if err != nil {
+		return "", err
+	}
+	return g.renderContent(ctx, content, VariantA)
+}
+
+// loadContent loads the content with the schedules active at t applied,
+// returning the IDs of those schedules
+func (g *SiteGenerator) loadContent(t time.Time) (*types.ContentData, []string, error) {
+	content, err := g.contentManager.LoadContent()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load content: %w", err)
+	}
+	if g.schedules == nil {
+		return content, nil, nil
+	}
+	content, ids, err := g.schedules.Apply(content, t)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to apply scheduled content: %w", err)
+	}
+	return content, ids, nil
+}
+
+// renderVariantPage renders the B page of an A/B test with its assets
+// processed, or returns nil when no section has a variant
+func (g *SiteGenerator) renderVariantPage(ctx context.Context, content *types.ContentData) (*ProcessedAssets, error) {
+	if g.variants == nil {
+		return nil, nil
+	}
+	applied, replaced, err := g.variants.Apply(content)
+	if err != nil || !replaced {
+		return nil, err
+	}
+
+	html, err := g.renderContent(ctx, applied, VariantB)
+	if err != nil {
+		return nil, fmt.Errorf("variant page: %w", err)
+	}
+	assets, err := ProcessAssets(html, g.assetMode, g.cdnBaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process variant page assets: %w", err)
+	}
+	return assets, nil
+}
+
+// renderContent renders the current template with content. The template
+// sees which A/B variant it renders as .variant.
+func (g *SiteGenerator) renderContent(ctx context.Context, content *types.ContentData, variant string) (_ string, err error) {
+	_, span := tracing.Start(ctx, "generate.render")
+	span.SetAttribute("variant", variant)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	templateContent, err := g.templateManager.LoadTemplate()
+	if err != nil {
+		return "", fmt.Errorf("failed to load template: %w", err)
+	}
+
+	data, err := g.BuildTemplateData(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to prepare template data: %w", err)
+	}
+	data["variant"] = variant
+	if err := g.runTemplateDataHooks(ctx, data); err != nil {
+		return "", err
+	}
+	if err := g.addRenderedSections(data); err != nil {
+		return "", err
+	}
+
+	page, err := g.templateManager.Engine().Execute(templateContent, data, g.templateFuncs())
+	if err != nil {
+		return "", err
+	}
+
+	// Custom code is added after rendering so it is never interpreted as
+	// template syntax, and before asset processing so inline snippets are
+	// hashed into the CSP like any other block
+	if g.customCode != nil {
+		code, err := g.customCode.Load()
+		if err != nil {
+			return "", err
+		}
+		return injectCustomCode(page, code), nil
+	}
+
+	return page, nil
+}
+
+// addRenderedSections adds the sections rendered by their section renderers
+// to template data: all of them in order as sections_html, and each by key
+// in section_html
+func (g *SiteGenerator) addRenderedSections(data map[string]interface{}) error {
+	if g.renderers == nil {
+		return nil
+	}
+	var schema *types.SchemaData
+	if g.schemaManager != nil {
+		loaded, err := g.schemaManager.LoadSchema()
+		if err != nil {
+			return fmt.Errorf("failed to load schema: %w", err)
+		}
+		schema = loaded
+	}
+	rendered, err := g.renderers.Render(schema, data, g.templateFuncs())
+	if err != nil {
+		return err
+	}
+	data["sections_html"] = rendered.HTML
+	data["section_html"] = rendered.ByKey
+	return nil
+}
+
+// BuildTemplateData converts content into the map exposed to the site
+// template. Fields the schema marks as private are left out, so a template
+// cannot publish them; if the schema cannot be read nothing is rendered.
+func (g *SiteGenerator) BuildTemplateData(content *types.ContentData) (map[string]interface{}, error) {
+	data := map[string]interface{}{
+		"title":        content.Title,
+		"description":  content.Description,
+		"sections":     content.Sections,
+		"last_updated": content.LastUpdated,
+	}
+	if g.schemaManager != nil {
+		public, err := g.schemaManager.PublicContent(content)
+		if err != nil {
+			return nil, err
+		}
+		data = public
+		data["last_updated"] = content.LastUpdated
+	}
+	if g.slugManager != nil {
+		sections, _ := data["sections"].(map[string]interface{})
+		anchors, err := g.slugManager.Resolve(sections)
+		if err != nil {
+			return nil, err
+		}
+		data["anchors"] = anchors
+	}
+
+	// Paths of the contact card and calendar files written by Generate
+	if BuildVCard(data, g.siteURL) != "" {
+		data["vcard_url"] = "/" + VCardFilename
+		data["vcard_qr"] = "/" + VCardQRFilename
+	}
+	if g.siteURL != "" {
+		data["site_qr"] = "/" + SiteQRFilename
+	}
+	if BuildCalendar(data, g.siteURL, time.Now()) != "" {
+		data["events_ics"] = "/" + CalendarFilename
+	}
+
+	data = g.addThumbnails(data).(map[string]interface{})
+	return g.rewriteImageURLs(data).(map[string]interface{}), nil
+}
+
+// addThumbnails gives every image item (an object whose src is a local
+// /images/ path) a thumbnail key pointing at its thumbnail variant, so
+// galleries can lazy-load small images and link to the full one. Items whose
+// variant does not exist are left alone. It returns copies of the content.
+func (g *SiteGenerator) addThumbnails(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			copied[key] = g.addThumbnails(nested)
+		}
+		if src, ok := v["src"].(string); ok && v["thumbnail"] == nil {
+			if thumbnail := g.thumbnailURL(src); thumbnail != "" {
+				copied["thumbnail"] = thumbnail
+			}
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, nested := range v {
+			copied[i] = g.addThumbnails(nested)
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+// thumbnailURL returns the thumbnail variant of a local image, or an empty
+// string if there is none
+func (g *SiteGenerator) thumbnailURL(src string) string {
+	if !strings.HasPrefix(src, "/images/") || strings.HasPrefix(src, "/images/variants/") {
+		return ""
+	}
+
+	variantPath := path.Join("images", "variants", "thumbnail", path.Base(src))
+	if !g.storage.FileExists(variantPath) {
+		return ""
+	}
+	return "/" + variantPath
+}
+
+// templateFuncs returns helper functions available to the site template
+func (g *SiteGenerator) templateFuncs() template.FuncMap {
+	// cdn rewrites a hard-coded /images/ path in the template itself
+	return siteTemplateFuncs(g.imageURL, g.location)
+}
+
+// rewriteImageURLs walks the content and rewrites local image paths to the CDN.
+// It returns copies so the stored content keeps its local paths.
+func (g *SiteGenerator) rewriteImageURLs(value interface{}) interface{} {
+	if g.cdnBaseURL == "" {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		rewritten := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			rewritten[key] = g.rewriteImageURLs(nested)
+		}
+		return rewritten
+	case []interface{}:
+		rewritten := make([]interface{}, len(v))
+		for i, nested := range v {
+			rewritten[i] = g.rewriteImageURLs(nested)
+		}
+		return rewritten
+	case string:
+		return g.imageURL(v)
+	default:
+		return value
+	}
+}
+
+// imageURL maps a local /images/ path to its CDN URL with a cache-busting hash.
+// Any other value is returned unchanged.
+func (g *SiteGenerator) imageURL(src string) string {
+	if g.cdnBaseURL == "" || !strings.HasPrefix(src, "/images/") {
+		return src
+	}
+
+	imagePath := path.Clean(strings.TrimPrefix(src, "/"))
+	url := g.cdnBaseURL + "/" + imagePath
+
+	data, err := g.storage.ReadBinaryFile(imagePath)
+	if err != nil {
+		// Unknown file; still point at the CDN so the page stays consistent
+		return url
+	}
+
+	hash := sha256.Sum256(data)
+	return url + "?v=" + hex.EncodeToString(hash[:])[:12]
+}
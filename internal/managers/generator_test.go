@@ -0,0 +1,23 @@
+package managers
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func BenchmarkRenderPage(b *testing.B) {
+	dir := seedBenchData(b)
+	storage := NewFileStorage(dir)
+	templates := NewTemplateManager(storage)
+	schemas := NewSchemaManager(storage, dir)
+	templates.SetSchemaManager(schemas)
+	generator := NewSiteGenerator(storage, templates, NewContentManager(storage, dir), filepath.Join(dir, "public"), "", AssetModeInline)
+	generator.SetSchemaManager(schemas)
+
+	for b.Loop() {
+		if _, err := generator.RenderPage(context.Background()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
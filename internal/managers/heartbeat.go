@@ -0,0 +1,71 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"onepagems/internal/httpclient"
+)
+
+// Heartbeat pings an external monitor, such as a healthchecks.io check,
+// while background work succeeds, so the monitor notices when it stops.
+// Failures are reported by pinging the URL with /fail appended.
+type Heartbeat struct {
+	url      string
+	interval time.Duration
+	client   *http.Client
+
+	mu   sync.Mutex
+	last time.Time // last success ping
+}
+
+// NewHeartbeat creates a heartbeat for url, pinged through clients.
+// Routine successes ping at most once per interval.
+func NewHeartbeat(url string, interval time.Duration, clients *httpclient.Clients) *Heartbeat {
+	return &Heartbeat{
+		url:      strings.TrimSuffix(url, "/"),
+		interval: interval,
+		client:   clients.New(10 * time.Second),
+	}
+}
+
+// Success pings the monitor if the last success ping is older than the
+// interval, or always when force is set, as for a finished generation
+func (h *Heartbeat) Success(ctx context.Context, now time.Time, force bool) error {
+	h.mu.Lock()
+	if !force && now.Sub(h.last) < h.interval {
+		h.mu.Unlock()
+		return nil
+	}
+	h.last = now
+	h.mu.Unlock()
+	return h.ping(ctx, h.url, "")
+}
+
+// Fail tells the monitor background work failed, with the reason as the
+// ping body
+func (h *Heartbeat) Fail(ctx context.Context, reason string) error {
+	return h.ping(ctx, h.url+"/fail", reason)
+}
+
+// ping sends one request to the monitor
+func (h *Heartbeat) ping(ctx context.Context, url, body string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create heartbeat request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("heartbeat ping failed: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("heartbeat ping returned status %d", resp.StatusCode)
+	}
+	return nil
+}
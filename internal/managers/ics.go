@@ -0,0 +1,193 @@
+package managers
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CalendarFilename is the iCalendar feed of dated content, served at /events.ics
+const CalendarFilename = "events.ics"
+
+// calendarEvent is one dated item found in the content
+type calendarEvent struct {
+	path        string
+	summary     string
+	description string
+	location    string
+	url         string
+	start, end  time.Time
+	allDay      bool
+}
+
+// BuildCalendar returns an iCalendar feed of every dated item in public
+// content, or an empty string if there are none. An item is any object with
+// a start date (RFC3339 date-time or YYYY-MM-DD), such as an event section or
+// an entry in a list of announcements. Times are written in UTC, so each
+// calendar shows them in its own time zone without VTIMEZONE definitions.
+func BuildCalendar(content map[string]interface{}, siteURL string, now time.Time) string {
+	sections, _ := content["sections"].(map[string]interface{})
+
+	var events []calendarEvent
+	keys := make([]string, 0, len(sections))
+	for key := range sections {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		events = collectEvents(key, sections[key], events)
+	}
+	if len(events) == 0 {
+		return ""
+	}
+
+	host := "onepagems"
+	if parsed, err := url.Parse(siteURL); err == nil && parsed.Hostname() != "" {
+		host = parsed.Hostname()
+	}
+
+	var b strings.Builder
+	line := func(property, value string) {
+		writeContentLine(&b, property+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//OnePage CMS//onepagems//EN")
+	line("CALSCALE", "GREGORIAN")
+	if title, ok := content["title"].(string); ok && title != "" {
+		line("X-WR-CALNAME", escapeText(title))
+	}
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, event := range events {
+		line("BEGIN", "VEVENT")
+		line("UID", event.uid(host))
+		line("DTSTAMP", stamp)
+		if event.allDay {
+			line("DTSTART;VALUE=DATE", event.start.Format("20060102"))
+			if !event.end.IsZero() {
+				// DTEND of an all-day event is exclusive
+				line("DTEND;VALUE=DATE", event.end.AddDate(0, 0, 1).Format("20060102"))
+			}
+		} else {
+			line("DTSTART", event.start.UTC().Format("20060102T150405Z"))
+			if !event.end.IsZero() {
+				line("DTEND", event.end.UTC().Format("20060102T150405Z"))
+			}
+		}
+		line("SUMMARY", escapeText(event.summary))
+		if event.description != "" {
+			line("DESCRIPTION", escapeText(event.description))
+		}
+		if event.location != "" {
+			line("LOCATION", escapeText(event.location))
+		}
+		if event.url != "" {
+			line("URL", event.url)
+		}
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+	return b.String()
+}
+
+// uid identifies the event across feed updates. It is derived from where the
+// item lives in the content and when it starts.
+func (e calendarEvent) uid(host string) string {
+	return Slugify(e.path) + "-" + e.start.UTC().Format("20060102T150405Z") + "@" + host
+}
+
+// collectEvents appends the dated items in value, at the dotted path
+func collectEvents(path string, value interface{}, events []calendarEvent) []calendarEvent {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if event, ok := parseCalendarEvent(path, v); ok {
+			return append(events, event)
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			events = collectEvents(path+"."+key, v[key], events)
+		}
+	case []interface{}:
+		for i, item := range v {
+			events = collectEvents(path+"."+strconv.Itoa(i), item, events)
+		}
+	}
+	return events
+}
+
+// parseCalendarEvent reads an object with a start date as an event. Items
+// without a title are named after their position in the content.
+func parseCalendarEvent(path string, obj map[string]interface{}) (calendarEvent, bool) {
+	text := func(key string) string {
+		value, _ := obj[key].(string)
+		return strings.TrimSpace(value)
+	}
+
+	start, allDay, ok := parseEventTime(text("start"))
+	if !ok {
+		return calendarEvent{}, false
+	}
+
+	event := calendarEvent{
+		path:    path,
+		summary: text("title"),
+		start:   start,
+		allDay:  allDay,
+	}
+	if event.summary == "" {
+		event.summary = path
+	}
+	if end, endAllDay, ok := parseEventTime(text("end")); ok && endAllDay == allDay && !end.Before(start) {
+		event.end = end
+	}
+
+	event.description = plainText(text("description"))
+	if link := text("rsvp_link"); link != "" {
+		event.url = link
+		if event.description != "" {
+			event.description += "\n\n"
+		}
+		event.description += fmt.Sprintf("%s: %s", orDefault(text("rsvp_text"), "RSVP"), link)
+	}
+
+	var location []string
+	for _, part := range []string{text("location"), text("address")} {
+		if part != "" {
+			location = append(location, part)
+		}
+	}
+	event.location = strings.Join(location, ", ")
+	return event, true
+}
+
+// parseEventTime accepts an RFC3339 date-time or a YYYY-MM-DD date
+func parseEventTime(value string) (time.Time, bool, bool) {
+	if value == "" {
+		return time.Time{}, false, false
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, true
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, true
+	}
+	return time.Time{}, false, false
+}
+
+// orDefault returns value, or fallback when value is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,261 @@
+package managers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// allowedImageTypes maps accepted image content types to their file extension
+var allowedImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// ImageManager handles image uploads and the images.json metadata index
+type ImageManager struct {
+	storage   *FileStorage
+	maxSize   int64
+	suggester AltTextSuggester
+	mu        sync.Mutex
+}
+
+// NewImageManager creates a new image manager. suggester may be nil, in
+// which case no alt text is proposed for uploads.
+func NewImageManager(storage *FileStorage, maxSize int64, suggester AltTextSuggester) *ImageManager {
+	return &ImageManager{
+		storage:   storage,
+		maxSize:   maxSize,
+		suggester: suggester,
+	}
+}
+
+// metadataFilePath returns the filename for the image metadata index
+func (im *ImageManager) metadataFilePath() string {
+	return "images.json"
+}
+
+// imagePath returns the storage-relative path for an image file
+func (im *ImageManager) imagePath(filename string) string {
+	return filepath.Join("images", filename)
+}
+
+// loadIndex reads the image metadata index, returning an empty index if none exists
+func (im *ImageManager) loadIndex() (map[string]*types.ImageInfo, error) {
+	index := make(map[string]*types.ImageInfo)
+	if !im.storage.FileExists(im.metadataFilePath()) {
+		return index, nil
+	}
+
+	if err := im.storage.ReadJSONFile(im.metadataFilePath(), &index); err != nil {
+		return nil, fmt.Errorf("failed to read image index: %w", err)
+	}
+	return index, nil
+}
+
+// saveIndex writes the image metadata index
+func (im *ImageManager) saveIndex(index map[string]*types.ImageInfo) error {
+	if err := im.storage.WriteJSONFile(im.metadataFilePath(), index); err != nil {
+		return fmt.Errorf("failed to save image index: %w", err)
+	}
+	return nil
+}
+
+// UploadImage validates and stores an uploaded image, asking the configured
+// suggester for alt text. Suggestion failures (including ctx expiring while
+// waiting for the provider) are logged but never fail the upload.
+func (im *ImageManager) UploadImage(ctx context.Context, originalName string, data []byte) (*types.ImageInfo, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("image is empty")
+	}
+
+	if im.maxSize > 0 && int64(len(data)) > im.maxSize {
+		return nil, fmt.Errorf("image exceeds maximum size of %d bytes", im.maxSize)
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := allowedImageTypes[contentType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported image type: %s", contentType)
+	}
+
+	filename, err := im.generateFilename(ext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate filename: %w", err)
+	}
+
+	if err := im.storage.WriteBinaryFile(im.imagePath(filename), data); err != nil {
+		return nil, fmt.Errorf("failed to store image: %w", err)
+	}
+
+	info := &types.ImageInfo{
+		Filename:     filename,
+		OriginalName: filepath.Base(originalName),
+		Size:         int64(len(data)),
+		ContentType:  contentType,
+		UploadedAt:   time.Now(),
+		URL:          "/images/" + filename,
+	}
+
+	im.describeUpload(ctx, info, data)
+
+	if im.suggester != nil {
+		if altText, err := im.suggester.SuggestAltText(ctx, data, contentType); err != nil {
+			log.Printf("Alt text suggestion failed for %s: %v", filename, err)
+		} else if altText = strings.TrimSpace(altText); altText != "" {
+			info.AltText = altText
+			info.AltTextSource = "suggested"
+		}
+	}
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	index, err := im.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	index[filename] = info
+	if err := im.saveIndex(index); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// ListImages returns all known images, newest first
+func (im *ImageManager) ListImages() ([]types.ImageInfo, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	index, err := im.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([]types.ImageInfo, 0, len(index))
+	for _, info := range index {
+		images = append(images, *info)
+	}
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].UploadedAt.After(images[j].UploadedAt)
+	})
+
+	return images, nil
+}
+
+// GetImage returns metadata for a single image
+func (im *ImageManager) GetImage(filename string) (*types.ImageInfo, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	index, err := im.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	info, exists := index[filename]
+	if !exists {
+		return nil, fmt.Errorf("image '%s' not found", filename)
+	}
+	return info, nil
+}
+
+// UpdateAltText stores user-edited alt text for an image
+func (im *ImageManager) UpdateAltText(filename, altText string) (*types.ImageInfo, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	index, err := im.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	info, exists := index[filename]
+	if !exists {
+		return nil, fmt.Errorf("image '%s' not found", filename)
+	}
+
+	info.AltText = strings.TrimSpace(altText)
+	info.AltTextSource = "manual"
+	if err := im.saveIndex(index); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// SuggestAltText asks the configured provider for alt text for an existing image
+// without storing it, so the user can review the proposal first.
+func (im *ImageManager) SuggestAltText(ctx context.Context, filename string) (string, error) {
+	if im.suggester == nil {
+		return "", fmt.Errorf("no alt text provider is configured")
+	}
+
+	info, err := im.GetImage(filename)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := im.storage.ReadBinaryFile(im.imagePath(info.Filename))
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+
+	altText, err := im.suggester.SuggestAltText(ctx, data, info.ContentType)
+	if err != nil {
+		return "", fmt.Errorf("alt text suggestion failed: %w", err)
+	}
+	return strings.TrimSpace(altText), nil
+}
+
+// HasSuggester reports whether alt text suggestions are available
+func (im *ImageManager) HasSuggester() bool {
+	return im.suggester != nil
+}
+
+// DeleteImage removes an image file and its metadata
+func (im *ImageManager) DeleteImage(filename string) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	index, err := im.loadIndex()
+	if err != nil {
+		return err
+	}
+
+	if _, exists := index[filename]; !exists {
+		return fmt.Errorf("image '%s' not found", filename)
+	}
+
+	if err := im.storage.DeleteFile(im.imagePath(filename)); err != nil {
+		return fmt.Errorf("failed to delete image: %w", err)
+	}
+	for name := range ImageVariants {
+		im.storage.DeleteFile(filepath.Join("images", "variants", name, filename))
+	}
+
+	delete(index, filename)
+	return im.saveIndex(index)
+}
+
+// generateFilename creates a unique, URL-safe filename for an upload
+func (im *ImageManager) generateFilename(ext string) (string, error) {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%s%s", time.Now().Unix(), hex.EncodeToString(bytes), ext), nil
+}
@@ -0,0 +1,381 @@
+package managers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"path"
+
+	"onepagems/internal/tracing"
+	"onepagems/internal/types"
+)
+
+// ImageVariant describes a named output size produced for every image
+type ImageVariant struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// ImageVariants are the differently-cropped renditions generated for each
+// image. Crops are centred on the image's focal point.
+var ImageVariants = map[string]ImageVariant{
+	"hero":      {Width: 1600, Height: 600},
+	"thumbnail": {Width: 400, Height: 400},
+}
+
+// CropImage crops an image in place to the given rectangle (in pixels)
+func (im *ImageManager) CropImage(ctx context.Context, filename string, x, y, width, height int) (*types.ImageInfo, error) {
+	return im.editImage(ctx, filename, func(src image.Image) (image.Image, error) {
+		bounds := src.Bounds()
+		rect := image.Rect(x, y, x+width, y+height).Add(bounds.Min)
+		if width <= 0 || height <= 0 || !rect.In(bounds) {
+			return nil, fmt.Errorf("crop rectangle must lie within the %dx%d image", bounds.Dx(), bounds.Dy())
+		}
+
+		dst := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(dst, dst.Bounds(), src, rect.Min, draw.Src)
+		return dst, nil
+	}, true)
+}
+
+// RotateImage rotates an image in place clockwise by 90, 180 or 270 degrees
+func (im *ImageManager) RotateImage(ctx context.Context, filename string, degrees int) (*types.ImageInfo, error) {
+	degrees = ((degrees % 360) + 360) % 360
+	if degrees%90 != 0 || degrees == 0 {
+		return nil, fmt.Errorf("rotation must be 90, 180 or 270 degrees")
+	}
+
+	return im.editImage(ctx, filename, func(src image.Image) (image.Image, error) {
+		return rotateImage(src, degrees), nil
+	}, true)
+}
+
+// SetFocalPoint stores the focal point of an image and regenerates its variants
+func (im *ImageManager) SetFocalPoint(ctx context.Context, filename string, x, y float64) (*types.ImageInfo, error) {
+	if x < 0 || x > 1 || y < 0 || y > 1 {
+		return nil, fmt.Errorf("focal point coordinates must be between 0 and 1")
+	}
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	index, err := im.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	info, exists := index[filename]
+	if !exists {
+		return nil, fmt.Errorf("image '%s' not found", filename)
+	}
+
+	info.FocalPoint = &types.FocalPoint{X: x, Y: y}
+	if err := im.renderVariants(ctx, info); err != nil {
+		return nil, err
+	}
+
+	if err := im.saveIndex(index); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// RegenerateVariants renders every variant of an image again from its
+// original, e.g. after ImageVariants changed
+func (im *ImageManager) RegenerateVariants(ctx context.Context, filename string) (*types.ImageInfo, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	index, err := im.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	info, exists := index[filename]
+	if !exists {
+		return nil, fmt.Errorf("image '%s' not found", filename)
+	}
+	if err := im.renderVariants(ctx, info); err != nil {
+		return nil, err
+	}
+
+	if err := im.saveIndex(index); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// renderVariants decodes an image's original and generates its variants
+func (im *ImageManager) renderVariants(ctx context.Context, info *types.ImageInfo) error {
+	data, err := im.storage.ReadBinaryFile(im.imagePath(info.Filename))
+	if err != nil {
+		return fmt.Errorf("failed to read image: %w", err)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %w", err)
+	}
+	return im.generateVariants(ctx, info, src)
+}
+
+// editImage decodes an image, applies op, and writes the result back in the
+// original format. The previous version is kept as a .bak file. The edit is
+// abandoned without touching the file if ctx is cancelled before it is saved.
+func (im *ImageManager) editImage(ctx context.Context, filename string, op func(image.Image) (image.Image, error), resetFocalPoint bool) (*types.ImageInfo, error) {
+	ctx, span := tracing.Start(ctx, "image.edit")
+	defer span.End()
+	span.SetAttribute("image.filename", filename)
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	index, err := im.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	info, exists := index[filename]
+	if !exists {
+		return nil, fmt.Errorf("image '%s' not found", filename)
+	}
+
+	imagePath := im.imagePath(filename)
+	data, err := im.storage.ReadBinaryFile(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("image format does not support editing: %w", err)
+	}
+
+	edited, err := op(src)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := encodeImage(edited, info.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("image edit cancelled: %w", err)
+	}
+
+	if err := im.storage.CreateBackup(imagePath); err != nil {
+		return nil, fmt.Errorf("failed to back up image: %w", err)
+	}
+	if err := im.storage.WriteBinaryFile(imagePath, encoded); err != nil {
+		return nil, fmt.Errorf("failed to save edited image: %w", err)
+	}
+
+	info.Size = int64(len(encoded))
+	info.Width = edited.Bounds().Dx()
+	info.Height = edited.Bounds().Dy()
+	if resetFocalPoint {
+		info.FocalPoint = nil
+	}
+
+	if err := im.generateVariants(ctx, info, edited); err != nil {
+		return nil, err
+	}
+
+	if err := im.saveIndex(index); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// generateVariants renders every configured variant of an image and records their URLs
+func (im *ImageManager) generateVariants(ctx context.Context, info *types.ImageInfo, src image.Image) error {
+	focal := types.FocalPoint{X: 0.5, Y: 0.5}
+	if info.FocalPoint != nil {
+		focal = *info.FocalPoint
+	}
+
+	info.Variants = make(map[string]string, len(ImageVariants))
+	for name, variant := range ImageVariants {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("variant generation cancelled: %w", err)
+		}
+
+		rendered := cropToFocalPoint(src, variant.Width, variant.Height, focal)
+
+		encoded, err := encodeImage(rendered, info.ContentType)
+		if err != nil {
+			return err
+		}
+
+		variantPath := path.Join("images", "variants", name, info.Filename)
+		if err := im.storage.WriteBinaryFile(variantPath, encoded); err != nil {
+			return fmt.Errorf("failed to save %s variant: %w", name, err)
+		}
+		info.Variants[name] = "/" + variantPath
+	}
+
+	return nil
+}
+
+// describeUpload fills in dimensions and variants for a freshly uploaded image.
+// Formats the standard library cannot decode (e.g. WebP) are left as-is.
+func (im *ImageManager) describeUpload(ctx context.Context, info *types.ImageInfo, data []byte) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+
+	info.Width = src.Bounds().Dx()
+	info.Height = src.Bounds().Dy()
+	if err := im.generateVariants(ctx, info, src); err != nil {
+		log.Printf("Failed to generate variants for %s: %v", info.Filename, err)
+	}
+}
+
+// encodeImage encodes an image in the format matching contentType
+func encodeImage(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	case "image/png":
+		err = png.Encode(&buf, img)
+	case "image/gif":
+		err = gif.Encode(&buf, img, nil)
+	default:
+		return nil, fmt.Errorf("editing %s images is not supported", contentType)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// rotateImage rotates src clockwise by 90, 180 or 270 degrees
+func rotateImage(src image.Image, degrees int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	var dst *image.RGBA
+	if degrees == 180 {
+		dst = image.NewRGBA(image.Rect(0, 0, w, h))
+	} else {
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := src.At(bounds.Min.X+x, bounds.Min.Y+y)
+			switch degrees {
+			case 90:
+				dst.Set(h-1-y, x, c)
+			case 180:
+				dst.Set(w-1-x, h-1-y, c)
+			case 270:
+				dst.Set(y, w-1-x, c)
+			}
+		}
+	}
+
+	return dst
+}
+
+// cropToFocalPoint crops src to the aspect ratio of width x height, keeping the
+// focal point as close to the centre as possible, then scales it down to fit
+func cropToFocalPoint(src image.Image, width, height int, focal types.FocalPoint) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	cropW, cropH := w, h
+	if w*height > h*width {
+		cropW = h * width / height
+	} else {
+		cropH = w * height / width
+	}
+	if cropW < 1 {
+		cropW = 1
+	}
+	if cropH < 1 {
+		cropH = 1
+	}
+
+	x0 := clampInt(int(focal.X*float64(w))-cropW/2, 0, w-cropW)
+	y0 := clampInt(int(focal.Y*float64(h))-cropH/2, 0, h-cropH)
+
+	cropped := image.NewRGBA(image.Rect(0, 0, cropW, cropH))
+	draw.Draw(cropped, cropped.Bounds(), src, bounds.Min.Add(image.Pt(x0, y0)), draw.Src)
+
+	// Never upscale; small images keep their cropped size
+	if cropW <= width {
+		return cropped
+	}
+	return scaleImage(cropped, width, height)
+}
+
+// scaleImage resizes src to width x height using bilinear sampling
+func scaleImage(src *image.RGBA, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	xRatio := float64(sw-1) / float64(maxInt(width-1, 1))
+	yRatio := float64(sh-1) / float64(maxInt(height-1, 1))
+
+	for y := 0; y < height; y++ {
+		fy := float64(y) * yRatio
+		y0 := int(fy)
+		y1 := minInt(y0+1, sh-1)
+		dy := fy - float64(y0)
+
+		for x := 0; x < width; x++ {
+			fx := float64(x) * xRatio
+			x0 := int(fx)
+			x1 := minInt(x0+1, sw-1)
+			dx := fx - float64(x0)
+
+			for c := 0; c < 4; c++ {
+				p00 := float64(src.Pix[src.PixOffset(x0, y0)+c])
+				p10 := float64(src.Pix[src.PixOffset(x1, y0)+c])
+				p01 := float64(src.Pix[src.PixOffset(x0, y1)+c])
+				p11 := float64(src.Pix[src.PixOffset(x1, y1)+c])
+				top := p00 + (p10-p00)*dx
+				bottom := p01 + (p11-p01)*dx
+				dst.Pix[dst.PixOffset(x, y)+c] = uint8(top + (bottom-top)*dy + 0.5)
+			}
+		}
+	}
+
+	return dst
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,90 @@
+package managers
+
+import (
+	"fmt"
+	"strings"
+
+	"onepagems/internal/types"
+)
+
+// ImageUsage lists where an image is used: content and draft field paths
+// holding its URL, a variant URL or its filename, and template lines
+// referring to it
+type ImageUsage struct {
+	Filename string               `json:"filename"`
+	Content  []string             `json:"content"`
+	Draft    []string             `json:"draft,omitempty"`
+	Template []ImageTemplateUsage `json:"template"`
+}
+
+// ImageTemplateUsage is one template line referring to an image, numbered
+// from 1
+type ImageTemplateUsage struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// InUse reports whether anything still refers to the image
+func (u *ImageUsage) InUse() bool {
+	return len(u.Content) > 0 || len(u.Draft) > 0 || len(u.Template) > 0
+}
+
+// FindImageUsage looks for image in the content, the draft (nil when there
+// is none) and the template. Uploaded filenames are unique, so any string
+// containing one refers to that image, whichever URL form it uses.
+func FindImageUsage(image *types.ImageInfo, content *types.ContentData, draft *types.ContentData, templateHTML string) *ImageUsage {
+	usage := &ImageUsage{
+		Filename: image.Filename,
+		Content:  imageFieldPaths(content, image.Filename),
+		Draft:    imageFieldPaths(draft, image.Filename),
+		Template: make([]ImageTemplateUsage, 0),
+	}
+	for i, line := range strings.Split(templateHTML, "\n") {
+		if strings.Contains(line, image.Filename) {
+			usage.Template = append(usage.Template, ImageTemplateUsage{
+				Line: i + 1,
+				Text: truncateText(strings.TrimSpace(line), searchExcerptLength),
+			})
+		}
+	}
+	return usage
+}
+
+// imageFieldPaths returns the paths of the content fields mentioning
+// filename, in path order
+func imageFieldPaths(content *types.ContentData, filename string) []string {
+	if content == nil {
+		return make([]string, 0)
+	}
+	contentMap := map[string]interface{}{
+		"title":       content.Title,
+		"description": content.Description,
+		"sections":    content.Sections,
+	}
+	return searchContentRaw(contentMap, "", filename, make([]string, 0))
+}
+
+// searchContentRaw returns the paths of the string values under value that
+// contain text, matched exactly and without stripping HTML, so image
+// sources inside rich text count
+func searchContentRaw(value interface{}, path, text string, paths []string) []string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			paths = searchContentRaw(v[key], childPath, text, paths)
+		}
+	case []interface{}:
+		for i, item := range v {
+			paths = searchContentRaw(item, fmt.Sprintf("%s[%d]", path, i), text, paths)
+		}
+	case string:
+		if strings.Contains(v, text) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,868 @@
+package managers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"onepagems/internal/types"
+)
+
+// importMappingDir holds uploaded data waiting to be mapped onto the schema
+const importMappingDir = "import-mappings"
+
+// importMappingTTL is how long an upload is kept without being committed
+const importMappingTTL = 24 * time.Hour
+
+// importMappingIDPattern matches the IDs Create hands out
+var importMappingIDPattern = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+// Import upload failures
+var (
+	ErrImportNotFound = errors.New("import upload not found")
+	ErrImportInvalid  = errors.New("invalid") // the upload could not be parsed
+)
+
+// Import transforms applied to a source value before it is stored
+const (
+	ImportTransformNone    = ""
+	ImportTransformTrim    = "trim"
+	ImportTransformLower   = "lower"
+	ImportTransformUpper   = "upper"
+	ImportTransformString  = "string"
+	ImportTransformNumber  = "number"
+	ImportTransformInteger = "integer"
+	ImportTransformBoolean = "boolean"
+	ImportTransformSplit   = "split"
+	ImportTransformJoin    = "join"
+	ImportTransformDate    = "date"
+)
+
+// importDateLayouts are tried in order by the date transform when the rule
+// gives no layout
+var importDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+	"01/02/2006",
+	"02.01.2006",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"2 Jan 2006",
+	"2 January 2006",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// ImportUpload is data uploaded for mapping: any JSON document, or CSV rows
+// as an array of objects keyed by the header row
+type ImportUpload struct {
+	ID        string      `json:"id"`
+	Filename  string      `json:"filename,omitempty"`
+	Format    string      `json:"format"` // json or csv
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// ImportField is a path found in uploaded data. "[]" in a path stands for
+// every element of an array, as in "items[].name"; CSV rows are "[].column".
+type ImportField struct {
+	Path   string      `json:"path"`
+	Type   string      `json:"type"`
+	Count  int         `json:"count"` // values found
+	Sample interface{} `json:"sample,omitempty"`
+}
+
+// ImportTarget is a content path values can be mapped to, with "[]" for
+// the items of array fields
+type ImportTarget struct {
+	Path     string `json:"path"`
+	Type     string `json:"type"`
+	Format   string `json:"format,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// ImportRule maps one source path onto one target path. Both must have the
+// same number of "[]": the n-th element of the source array becomes the
+// n-th element of the target array. Sources can pick one element by index,
+// as in "[0].title" for the first CSV row.
+type ImportRule struct {
+	Source    string      `json:"source"`
+	Target    string      `json:"target"`
+	Transform string      `json:"transform,omitempty"`
+	Separator string      `json:"separator,omitempty"` // split and join; "," and ", " by default
+	Layout    string      `json:"layout,omitempty"`    // date, as a Go time layout
+	Default   interface{} `json:"default,omitempty"`   // used where the source has no value
+}
+
+// ImportMapping is a set of rules. Base "current" (the default) applies
+// them to a copy of the current content; "empty" starts from nothing.
+type ImportMapping struct {
+	Rules []ImportRule `json:"rules"`
+	Base  string       `json:"base,omitempty"`
+}
+
+// ImportRuleError reports a value a rule could not map
+type ImportRuleError struct {
+	Rule    int    `json:"rule"`
+	Source  string `json:"source"`
+	Target  string `json:"target"`
+	Message string `json:"message"`
+}
+
+// ImportResult is content built from an upload by a mapping
+type ImportResult struct {
+	Content  *types.ContentData `json:"content"`
+	Applied  int                `json:"applied"`  // values written
+	Errors   []ImportRuleError  `json:"errors"`   // values left out
+	Unmapped []string           `json:"unmapped"` // source fields no rule reads
+}
+
+// ImportMappingManager keeps uploads between the steps of an import
+type ImportMappingManager struct {
+	storage *FileStorage
+}
+
+// NewImportMappingManager creates a new import mapping manager
+func NewImportMappingManager(storage *FileStorage) *ImportMappingManager {
+	return &ImportMappingManager{storage: storage}
+}
+
+// Create parses and stores uploaded data. The format is taken from the
+// filename's extension, or guessed from the data: JSON if it starts with
+// "{" or "[", CSV otherwise. Uploads older than a day are removed.
+func (im *ImportMappingManager) Create(filename string, data []byte, now time.Time) (*ImportUpload, error) {
+	im.removeExpired(now)
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	if format != "json" && format != "csv" {
+		format = "csv"
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+			format = "json"
+		}
+	}
+
+	var parsed interface{}
+	var err error
+	if format == "json" {
+		err = json.Unmarshal(data, &parsed)
+	} else {
+		parsed, err = parseImportCSV(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %v", ErrImportInvalid, strings.ToUpper(format), err)
+	}
+
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+	upload := &ImportUpload{
+		ID:        hex.EncodeToString(idBytes),
+		Filename:  filepath.Base(filename),
+		Format:    format,
+		CreatedAt: now,
+		Data:      parsed,
+	}
+	if err := os.MkdirAll(im.storage.GetFilePath(importMappingDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", importMappingDir, err)
+	}
+	if err := im.storage.WriteJSONFile(importUploadFilename(upload.ID), upload); err != nil {
+		return nil, fmt.Errorf("failed to save upload: %w", err)
+	}
+	return upload, nil
+}
+
+// Load returns an upload by ID
+func (im *ImportMappingManager) Load(id string) (*ImportUpload, error) {
+	if !importMappingIDPattern.MatchString(id) || !im.storage.FileExists(importUploadFilename(id)) {
+		return nil, ErrImportNotFound
+	}
+	var upload ImportUpload
+	if err := im.storage.ReadJSONFile(importUploadFilename(id), &upload); err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	return &upload, nil
+}
+
+// Delete removes an upload, reporting whether it existed
+func (im *ImportMappingManager) Delete(id string) (bool, error) {
+	if !importMappingIDPattern.MatchString(id) || !im.storage.FileExists(importUploadFilename(id)) {
+		return false, nil
+	}
+	if err := im.storage.DeleteFile(importUploadFilename(id)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// removeExpired deletes uploads older than importMappingTTL
+func (im *ImportMappingManager) removeExpired(now time.Time) {
+	entries, err := os.ReadDir(im.storage.GetFilePath(importMappingDir))
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err == nil && now.Sub(info.ModTime()) > importMappingTTL {
+			os.Remove(filepath.Join(im.storage.GetFilePath(importMappingDir), entry.Name()))
+		}
+	}
+}
+
+// importUploadFilename is the data file of an upload
+func importUploadFilename(id string) string {
+	return filepath.Join(importMappingDir, id+".json")
+}
+
+// parseImportCSV reads CSV with a header row into an array of objects
+func parseImportCSV(data []byte) ([]interface{}, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no header row")
+	}
+	header := records[0]
+	rows := make([]interface{}, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]interface{}, len(header))
+		for i, column := range header {
+			column = strings.TrimSpace(column)
+			if column == "" || i >= len(record) {
+				continue
+			}
+			row[column] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// DetectImportFields lists the paths in data with their type, how many
+// values were found and the first one, sorted by path
+func DetectImportFields(data interface{}) []ImportField {
+	fields := make(map[string]*ImportField)
+	var walk func(value interface{}, path string)
+	walk = func(value interface{}, path string) {
+		if path != "" {
+			field := fields[path]
+			if field == nil {
+				field = &ImportField{Path: path, Type: jsonTypeName(value)}
+				fields[path] = field
+			} else if field.Type != jsonTypeName(value) && value != nil {
+				field.Type = "mixed"
+			}
+			field.Count++
+			if field.Sample == nil && !isEmptyValue(value) {
+				if _, nested := value.(map[string]interface{}); !nested {
+					field.Sample = value
+				}
+			}
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for key, nested := range v {
+				walk(nested, joinImportPath(path, key))
+			}
+		case []interface{}:
+			for _, item := range v {
+				switch item.(type) {
+				case map[string]interface{}, []interface{}:
+					walk(item, path+"[]")
+				}
+			}
+		}
+	}
+	walk(data, "")
+
+	list := make([]ImportField, 0, len(fields))
+	for _, field := range fields {
+		if _, isArray := field.Sample.([]interface{}); isArray {
+			// Arrays of objects are described by their item fields
+			if hasPrefixField(fields, field.Path+"[]") {
+				field.Sample = nil
+			}
+		}
+		list = append(list, *field)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	return list
+}
+
+// hasPrefixField reports whether any field path starts with prefix
+func hasPrefixField(fields map[string]*ImportField, prefix string) bool {
+	for path := range fields {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinImportPath adds a key to a path
+func joinImportPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// jsonTypeName names the JSON type of a decoded value
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64, json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+// ImportTargets lists the content paths of schema, objects and arrays
+// included, in path order
+func ImportTargets(schema *types.SchemaData) []ImportTarget {
+	targets := []ImportTarget{}
+	var walk func(properties map[string]interface{}, required []string, path string)
+	walk = func(properties map[string]interface{}, required []string, path string) {
+		for key, raw := range properties {
+			prop, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			target := ImportTarget{Path: joinImportPath(path, key), Type: primaryType(prop)}
+			target.Format, _ = prop["format"].(string)
+			target.Title, _ = prop["title"].(string)
+			for _, name := range required {
+				target.Required = target.Required || name == key
+			}
+			targets = append(targets, target)
+
+			switch target.Type {
+			case "object":
+				if nested, ok := prop["properties"].(map[string]interface{}); ok {
+					walk(nested, stringList(prop["required"]), target.Path)
+				}
+			case "array":
+				items, _ := prop["items"].(map[string]interface{})
+				if nested, ok := items["properties"].(map[string]interface{}); ok {
+					targets = append(targets, ImportTarget{Path: target.Path + "[]", Type: "object"})
+					walk(nested, stringList(items["required"]), target.Path+"[]")
+				}
+			}
+		}
+	}
+	var required []string
+	if schema != nil {
+		walk(schema.Properties, required, "")
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Path < targets[j].Path })
+	return targets
+}
+
+// stringList converts a decoded JSON array of strings
+func stringList(value interface{}) []string {
+	items, _ := value.([]interface{})
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// SuggestImportRules pairs source fields with targets whose last path
+// segment has the same name, ignoring case and punctuation, and the same
+// number of "[]", preferring the shallowest target and picking a transform
+// where their types differ. A field under "[]" found only once, like the
+// columns of a one-row CSV, is also offered to targets outside arrays as
+// "[0]".
+func SuggestImportRules(fields []ImportField, targets []ImportTarget) []ImportRule {
+	rules := []ImportRule{}
+	used := make(map[string]bool)
+	for _, field := range fields {
+		if field.Type == "object" {
+			continue
+		}
+		source := field.Path
+		depth := strings.Count(source, "[]")
+		name := importFieldName(source)
+
+		var best *ImportTarget
+		for i, target := range targets {
+			if used[target.Path] || target.Type == "object" || importFieldName(target.Path) != name {
+				continue
+			}
+			targetDepth := strings.Count(target.Path, "[]")
+			if targetDepth != depth && !(field.Count == 1 && strings.HasPrefix(source, "[]") && targetDepth == depth-1) {
+				continue
+			}
+			if best == nil || strings.Count(target.Path, ".") < strings.Count(best.Path, ".") {
+				best = &targets[i]
+			}
+		}
+		if best == nil {
+			continue
+		}
+		if strings.Count(best.Path, "[]") != depth {
+			source = "[0]" + strings.TrimPrefix(source, "[]")
+		}
+
+		rule := ImportRule{Source: source, Target: best.Path}
+		switch {
+		case best.Format == "date" || best.Format == "date-time":
+			rule.Transform = ImportTransformDate
+		case field.Type == "string" && best.Type == "array":
+			rule.Transform = ImportTransformSplit
+		case field.Type == "array" && best.Type == "string":
+			rule.Transform = ImportTransformJoin
+		case field.Type == "string" && (best.Type == "number" || best.Type == "integer"):
+			rule.Transform = ImportTransformNumber
+		case field.Type == "string" && best.Type == "boolean":
+			rule.Transform = ImportTransformBoolean
+		}
+		used[best.Path] = true
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// importFieldName is the last segment of a path, lowercased with only
+// letters and digits kept
+func importFieldName(path string) string {
+	path = strings.TrimSuffix(path, "[]")
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		path = path[i+1:]
+	}
+	path = strings.TrimSuffix(path, "[]")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, path)
+}
+
+// importStep is one step of a mapping path: a key, every element of an
+// array when each is set, or one element when at is set
+type importStep struct {
+	key   string
+	each  bool
+	at    bool
+	index int
+}
+
+// importIndexPattern matches a fixed index in a path
+var importIndexPattern = regexp.MustCompile(`\[[0-9]+\]`)
+
+// importSegmentPattern splits a path segment into its key and brackets
+var importSegmentPattern = regexp.MustCompile(`^([^\[\]]*)((?:\[[0-9]*\])*)$`)
+
+// parseImportPath splits "items[].name" or "[0].title" into steps
+func parseImportPath(path string) ([]importStep, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	var steps []importStep
+	for _, segment := range strings.Split(path, ".") {
+		match := importSegmentPattern.FindStringSubmatch(segment)
+		if match == nil || (match[1] == "" && (len(steps) > 0 || match[2] == "")) {
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+		if match[1] != "" {
+			steps = append(steps, importStep{key: match[1]})
+		}
+		for _, bracket := range strings.SplitAfter(match[2], "]") {
+			if bracket == "" {
+				continue
+			}
+			if bracket == "[]" {
+				steps = append(steps, importStep{each: true})
+				continue
+			}
+			index, err := strconv.Atoi(bracket[1 : len(bracket)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+			steps = append(steps, importStep{at: true, index: index})
+		}
+	}
+	return steps, nil
+}
+
+// importValue is a source value with the array indexes it was found at
+type importValue struct {
+	indexes []int
+	value   interface{}
+	missing bool
+}
+
+// collectImportValues returns every value at steps in data. Missing keys
+// inside arrays are returned as missing, so defaults can fill them.
+func collectImportValues(data interface{}, steps []importStep) []importValue {
+	var values []importValue
+	var walk func(value interface{}, steps []importStep, indexes []int)
+	walk = func(value interface{}, steps []importStep, indexes []int) {
+		if len(steps) == 0 {
+			values = append(values, importValue{indexes: indexes, value: value, missing: value == nil})
+			return
+		}
+		step := steps[0]
+		if step.each {
+			items, _ := value.([]interface{})
+			for i, item := range items {
+				walk(item, steps[1:], append(append([]int(nil), indexes...), i))
+			}
+			return
+		}
+		if step.at {
+			items, _ := value.([]interface{})
+			if step.index >= len(items) {
+				values = append(values, importValue{indexes: indexes, missing: true})
+				return
+			}
+			walk(items[step.index], steps[1:], indexes)
+			return
+		}
+		object, _ := value.(map[string]interface{})
+		nested, ok := object[step.key]
+		if !ok {
+			values = append(values, importValue{indexes: indexes, missing: true})
+			return
+		}
+		walk(nested, steps[1:], indexes)
+	}
+	walk(data, steps, nil)
+	return values
+}
+
+// setImportValue stores value at steps in content, filling the "[]" steps
+// with indexes and creating objects and arrays on the way
+func setImportValue(content map[string]interface{}, steps []importStep, indexes []int, value interface{}) error {
+	var container interface{} = content
+	var setParent func(interface{})
+	for i, step := range steps {
+		last := i == len(steps)-1
+		newChild := func() interface{} {
+			if steps[i+1].each {
+				return []interface{}{}
+			}
+			return map[string]interface{}{}
+		}
+
+		if step.each {
+			items, ok := container.([]interface{})
+			if !ok {
+				return fmt.Errorf("target is not an array")
+			}
+			index := indexes[0]
+			indexes = indexes[1:]
+			for len(items) <= index {
+				items = append(items, nil)
+			}
+			setParent(items)
+			if last {
+				items[index] = value
+				return nil
+			}
+			if items[index] == nil {
+				items[index] = newChild()
+			}
+			parentItems, parentIndex := items, index
+			setParent = func(v interface{}) { parentItems[parentIndex] = v }
+			container = items[index]
+			continue
+		}
+
+		object, ok := container.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("target %s is not an object", step.key)
+		}
+		if last {
+			object[step.key] = value
+			return nil
+		}
+		if object[step.key] == nil {
+			object[step.key] = newChild()
+		}
+		parentObject, parentKey := object, step.key
+		setParent = func(v interface{}) { parentObject[parentKey] = v }
+		container = object[step.key]
+	}
+	return nil
+}
+
+// ApplyImportMapping builds content from data with mapping. Rules are
+// applied in order onto base (the current content, unless the mapping's
+// base is "empty"); values a rule cannot convert are skipped and reported.
+// The result is not checked against the schema.
+func ApplyImportMapping(data interface{}, mapping ImportMapping, targets []ImportTarget, base *types.ContentData) (*ImportResult, error) {
+	content := map[string]interface{}{"title": "", "description": "", "sections": map[string]interface{}{}}
+	switch mapping.Base {
+	case "", "current":
+		if base != nil {
+			current, err := contentToMap(base)
+			if err != nil {
+				return nil, err
+			}
+			delete(current, "last_updated")
+			content = current
+		}
+	case "empty":
+	default:
+		return nil, fmt.Errorf("base must be \"current\" or \"empty\"")
+	}
+
+	targetsByPath := make(map[string]ImportTarget, len(targets))
+	for _, target := range targets {
+		targetsByPath[target.Path] = target
+	}
+
+	result := &ImportResult{Errors: []ImportRuleError{}, Unmapped: []string{}}
+	mapped := make(map[string]bool)
+	replaced := make(map[string]bool)
+	for i, rule := range mapping.Rules {
+		fail := func(format string, args ...interface{}) {
+			result.Errors = append(result.Errors, ImportRuleError{Rule: i, Source: rule.Source, Target: rule.Target, Message: fmt.Sprintf(format, args...)})
+		}
+		sourceSteps, err := parseImportPath(rule.Source)
+		if err != nil {
+			fail("%v", err)
+			continue
+		}
+		targetSteps, err := parseImportPath(rule.Target)
+		if err != nil {
+			fail("%v", err)
+			continue
+		}
+		target, known := targetsByPath[rule.Target]
+		if !known {
+			fail("the schema has no field %s", rule.Target)
+			continue
+		}
+		if strings.Count(rule.Source, "[]") != strings.Count(rule.Target, "[]") {
+			fail("source and target must have the same number of []")
+			continue
+		}
+		mapped[importIndexPattern.ReplaceAllString(rule.Source, "[]")] = true
+
+		// The first rule writing into an array replaces it, so items of
+		// the base content are not mixed with imported ones
+		if i := strings.Index(rule.Target, "[]"); i >= 0 && !replaced[rule.Target[:i]] {
+			replaced[rule.Target[:i]] = true
+			arraySteps, _ := parseImportPath(rule.Target[:i])
+			if err := setImportValue(content, arraySteps, nil, []interface{}{}); err != nil {
+				fail("%s: %v", rule.Target[:i], err)
+				continue
+			}
+		}
+
+		for _, found := range collectImportValues(data, sourceSteps) {
+			value := found.value
+			if found.missing {
+				if rule.Default == nil {
+					continue
+				}
+				value = rule.Default
+			} else if value, err = transformImportValue(value, rule, target); err != nil {
+				fail("%s: %v", describeImportIndexes(rule.Source, found.indexes), err)
+				continue
+			}
+			if err := setImportValue(content, targetSteps, found.indexes, value); err != nil {
+				fail("%s: %v", describeImportIndexes(rule.Target, found.indexes), err)
+				continue
+			}
+			result.Applied++
+		}
+	}
+
+	for _, field := range DetectImportFields(data) {
+		if !mapped[field.Path] && field.Type != "object" && field.Type != "array" {
+			covered := false
+			for source := range mapped {
+				covered = covered || strings.HasPrefix(field.Path, source+".") || strings.HasPrefix(field.Path, source+"[]")
+			}
+			if !covered {
+				result.Unmapped = append(result.Unmapped, field.Path)
+			}
+		}
+	}
+
+	var contentData types.ContentData
+	if err := remarshal(content, &contentData); err != nil {
+		return nil, fmt.Errorf("mapped content does not fit the content structure: %w", err)
+	}
+	if contentData.Sections == nil {
+		contentData.Sections = map[string]interface{}{}
+	}
+	result.Content = &contentData
+	return result, nil
+}
+
+// describeImportIndexes fills the "[]" of path with indexes for messages
+func describeImportIndexes(path string, indexes []int) string {
+	for _, index := range indexes {
+		path = strings.Replace(path, "[]", "["+strconv.Itoa(index)+"]", 1)
+	}
+	return path
+}
+
+// transformImportValue applies the rule's transform to one value
+func transformImportValue(value interface{}, rule ImportRule, target ImportTarget) (interface{}, error) {
+	switch rule.Transform {
+	case ImportTransformNone:
+		return value, nil
+	case ImportTransformTrim, ImportTransformLower, ImportTransformUpper:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s needs a string", rule.Transform)
+		}
+		switch rule.Transform {
+		case ImportTransformLower:
+			return strings.ToLower(s), nil
+		case ImportTransformUpper:
+			return strings.ToUpper(s), nil
+		}
+		return strings.TrimSpace(s), nil
+	case ImportTransformString:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case nil:
+			return "", nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+		return nil, fmt.Errorf("cannot turn %s into a string", jsonTypeName(value))
+	case ImportTransformNumber, ImportTransformInteger:
+		var n float64
+		switch v := value.(type) {
+		case float64:
+			n = v
+		case string:
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a number", v)
+			}
+			n = parsed
+		default:
+			return nil, fmt.Errorf("cannot turn %s into a number", jsonTypeName(value))
+		}
+		if rule.Transform == ImportTransformInteger && n != float64(int64(n)) {
+			return nil, fmt.Errorf("%v is not a whole number", n)
+		}
+		return n, nil
+	case ImportTransformBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case float64:
+			return v != 0, nil
+		case string:
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "true", "yes", "y", "1", "on":
+				return true, nil
+			case "false", "no", "n", "0", "off", "":
+				return false, nil
+			}
+			return nil, fmt.Errorf("%q is not yes or no", v)
+		}
+		return nil, fmt.Errorf("cannot turn %s into a boolean", jsonTypeName(value))
+	case ImportTransformSplit:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("split needs a string")
+		}
+		separator := rule.Separator
+		if separator == "" {
+			separator = ","
+		}
+		items := []interface{}{}
+		for _, part := range strings.Split(s, separator) {
+			if part = strings.TrimSpace(part); part != "" {
+				items = append(items, part)
+			}
+		}
+		return items, nil
+	case ImportTransformJoin:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("join needs an array")
+		}
+		separator := rule.Separator
+		if separator == "" {
+			separator = ", "
+		}
+		parts := make([]string, 0, len(items))
+		for _, item := range items {
+			part, err := transformImportValue(item, ImportRule{Transform: ImportTransformString}, target)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, part.(string))
+		}
+		return strings.Join(parts, separator), nil
+	case ImportTransformDate:
+		t, err := parseImportDate(value, rule.Layout)
+		if err != nil {
+			return nil, err
+		}
+		if target.Format == "date" {
+			return t.Format("2006-01-02"), nil
+		}
+		return t.Format(time.RFC3339), nil
+	}
+	return nil, fmt.Errorf("unknown transform %q", rule.Transform)
+}
+
+// parseImportDate reads a date string with layout, or any of the common
+// layouts; numbers are Unix seconds
+func parseImportDate(value interface{}, layout string) (time.Time, error) {
+	switch v := value.(type) {
+	case float64:
+		return time.Unix(int64(v), 0).UTC(), nil
+	case string:
+		v = strings.TrimSpace(v)
+		layouts := importDateLayouts
+		if layout != "" {
+			layouts = []string{layout}
+		}
+		for _, l := range layouts {
+			if t, err := time.Parse(l, v); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%q is not a date this import understands; set the rule's layout", v)
+	}
+	return time.Time{}, fmt.Errorf("cannot turn %s into a date", jsonTypeName(value))
+}
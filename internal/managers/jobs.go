@@ -0,0 +1,348 @@
+package managers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// jobsFilename keeps recent jobs across restarts
+const jobsFilename = "jobs.json"
+
+// Limits keep the job history and per-item error lists small
+const (
+	maxJobHistory   = 50
+	maxJobItemError = 200
+)
+
+// JobFunc does the work of a job, reporting through progress. Its result is
+// stored on the job when it succeeds.
+type JobFunc func(ctx context.Context, progress *JobProgress) (interface{}, error)
+
+// JobManager runs jobs in the background and publishes their progress to
+// subscribers
+type JobManager struct {
+	storage     *FileStorage
+	mu          sync.Mutex
+	saveMu      sync.Mutex
+	jobs        map[string]*types.Job
+	subscribers map[string][]chan types.Job
+	loadOnce    sync.Once
+}
+
+// NewJobManager creates a job manager. Jobs that were running when the
+// server last stopped are marked interrupted when the history is first
+// read.
+func NewJobManager(storage *FileStorage) *JobManager {
+	return &JobManager{
+		storage:     storage,
+		jobs:        make(map[string]*types.Job),
+		subscribers: make(map[string][]chan types.Job),
+	}
+}
+
+// load reads the saved job history, once, on first use
+func (jm *JobManager) load() {
+	jm.loadOnce.Do(func() {
+		var saved []*types.Job
+		if !jm.storage.FileExists(jobsFilename) || jm.storage.ReadJSONFile(jobsFilename, &saved) != nil {
+			return
+		}
+		jm.mu.Lock()
+		defer jm.mu.Unlock()
+		for _, job := range saved {
+			if !job.Finished() {
+				job.Status = types.JobInterrupted
+				job.Error = "The server stopped before the job finished"
+			}
+			jm.jobs[job.ID] = job
+		}
+	})
+}
+
+// Start runs fn as a new job and returns it
+func (jm *JobManager) Start(jobType, username string, steps int, fn JobFunc) types.Job {
+	jm.load()
+	id := make([]byte, 8)
+	rand.Read(id)
+	job := &types.Job{
+		ID:        hex.EncodeToString(id),
+		Type:      jobType,
+		Status:    types.JobQueued,
+		Steps:     steps,
+		CreatedBy: username,
+		CreatedAt: time.Now(),
+	}
+
+	jm.mu.Lock()
+	jm.jobs[job.ID] = job
+	jm.pruneLocked()
+	snapshot := jm.snapshotLocked(job)
+	jm.mu.Unlock()
+
+	go jm.run(job.ID, fn)
+	return snapshot
+}
+
+// run executes a job, recording its outcome. A panic fails the job rather
+// than the server.
+func (jm *JobManager) run(id string, fn JobFunc) {
+	progress := &JobProgress{jm: jm, id: id}
+	jm.update(id, func(job *types.Job) {
+		now := time.Now()
+		job.Status = types.JobRunning
+		job.StartedAt = &now
+	})
+	jm.save()
+
+	var result interface{}
+	var err error
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = fmt.Errorf("job panicked: %v", recovered)
+			}
+		}()
+		result, err = fn(context.Background(), progress)
+	}()
+
+	jm.update(id, func(job *types.Job) {
+		now := time.Now()
+		job.FinishedAt = &now
+		if err != nil {
+			job.Status = types.JobFailed
+			job.Error = err.Error()
+			return
+		}
+		job.Status = types.JobSucceeded
+		job.Result = result
+		job.Progress = 100
+	})
+	jm.save()
+
+	jm.mu.Lock()
+	for _, ch := range jm.subscribers[id] {
+		close(ch)
+	}
+	delete(jm.subscribers, id)
+	jm.mu.Unlock()
+}
+
+// Get returns a job by ID
+func (jm *JobManager) Get(id string) (types.Job, bool) {
+	jm.load()
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	job, ok := jm.jobs[id]
+	if !ok {
+		return types.Job{}, false
+	}
+	return jm.snapshotLocked(job), true
+}
+
+// List returns recent jobs, newest first
+func (jm *JobManager) List() []types.Job {
+	jm.load()
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	jobs := make([]types.Job, 0, len(jm.jobs))
+	for _, job := range jm.jobs {
+		jobs = append(jobs, jm.snapshotLocked(job))
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// Subscribe returns a channel receiving the job after each change. The
+// channel is closed when the job finishes; it is nil if the job is unknown
+// or already finished. Call cancel to stop receiving.
+func (jm *JobManager) Subscribe(id string) (updates <-chan types.Job, cancel func()) {
+	jm.load()
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	job, ok := jm.jobs[id]
+	if !ok || job.Finished() {
+		return nil, func() {}
+	}
+
+	ch := make(chan types.Job, 16)
+	jm.subscribers[id] = append(jm.subscribers[id], ch)
+	return ch, func() {
+		jm.mu.Lock()
+		defer jm.mu.Unlock()
+		subscribers := jm.subscribers[id]
+		for i, sub := range subscribers {
+			if sub == ch {
+				jm.subscribers[id] = append(subscribers[:i], subscribers[i+1:]...)
+				close(ch)
+				break
+			}
+		}
+	}
+}
+
+// update changes a job and notifies subscribers. Slow subscribers miss
+// intermediate updates rather than blocking the job.
+func (jm *JobManager) update(id string, change func(job *types.Job)) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	job, ok := jm.jobs[id]
+	if !ok {
+		return
+	}
+	change(job)
+
+	snapshot := jm.snapshotLocked(job)
+	for _, ch := range jm.subscribers[id] {
+		select {
+		case ch <- snapshot:
+		default:
+		}
+	}
+}
+
+// save writes the job history
+func (jm *JobManager) save() {
+	jm.mu.Lock()
+	jobs := make([]types.Job, 0, len(jm.jobs))
+	for _, job := range jm.jobs {
+		jobs = append(jobs, jm.snapshotLocked(job))
+	}
+	jm.mu.Unlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	// Written without a .bak; the history is informational
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err == nil {
+		jm.saveMu.Lock()
+		err = jm.storage.WriteBinaryFile(jobsFilename, data)
+		jm.saveMu.Unlock()
+	}
+	if err != nil {
+		fmt.Printf("Warning: failed to save jobs: %v\n", err)
+	}
+}
+
+// pruneLocked drops the oldest finished jobs beyond the history limit
+func (jm *JobManager) pruneLocked() {
+	if len(jm.jobs) <= maxJobHistory {
+		return
+	}
+	finished := make([]*types.Job, 0, len(jm.jobs))
+	for _, job := range jm.jobs {
+		if job.Finished() {
+			finished = append(finished, job)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].CreatedAt.Before(finished[j].CreatedAt)
+	})
+	for _, job := range finished {
+		if len(jm.jobs) <= maxJobHistory {
+			break
+		}
+		delete(jm.jobs, job.ID)
+	}
+}
+
+// Prune removes the finished jobs that ended before cutoff and returns how
+// many it removed. Running and queued jobs are always kept.
+func (jm *JobManager) Prune(cutoff time.Time) int {
+	jm.load()
+
+	jm.mu.Lock()
+	removed := 0
+	for id, job := range jm.jobs {
+		if !job.Finished() {
+			continue
+		}
+		ended := job.CreatedAt
+		if job.FinishedAt != nil {
+			ended = *job.FinishedAt
+		}
+		if ended.Before(cutoff) {
+			delete(jm.jobs, id)
+			removed++
+		}
+	}
+	jm.mu.Unlock()
+
+	if removed > 0 {
+		jm.save()
+	}
+	return removed
+}
+
+// snapshotLocked copies a job so callers can read it without the lock
+func (jm *JobManager) snapshotLocked(job *types.Job) types.Job {
+	snapshot := *job
+	snapshot.Errors = append([]types.JobItemError(nil), job.Errors...)
+	return snapshot
+}
+
+// JobProgress reports the progress of a running job
+type JobProgress struct {
+	jm *JobManager
+	id string
+}
+
+// Step starts the next step, with total units of work (0 if unknown)
+func (p *JobProgress) Step(name string, total int64) {
+	p.jm.update(p.id, func(job *types.Job) {
+		job.StepIndex++
+		if job.StepIndex > job.Steps {
+			job.Steps = job.StepIndex
+		}
+		job.Step = name
+		job.Done, job.Total = 0, total
+		job.Progress = jobPercent(job)
+	})
+}
+
+// Add records n more units of the current step as done
+func (p *JobProgress) Add(n int64) {
+	p.jm.update(p.id, func(job *types.Job) {
+		job.Done += n
+		job.Progress = jobPercent(job)
+	})
+}
+
+// ItemError records the failure of one item without stopping the job
+func (p *JobProgress) ItemError(item string, err error) {
+	p.jm.update(p.id, func(job *types.Job) {
+		job.ErrorCount++
+		if len(job.Errors) < maxJobItemError {
+			job.Errors = append(job.Errors, types.JobItemError{Item: item, Error: err.Error()})
+		}
+	})
+}
+
+// jobPercent is the share of finished steps plus the current step's share,
+// rounded to a tenth of a percent
+func jobPercent(job *types.Job) float64 {
+	if job.Steps == 0 {
+		return 0
+	}
+	current := 0.0
+	if job.Total > 0 {
+		current = float64(min(job.Done, job.Total)) / float64(job.Total)
+	}
+	percent := (float64(job.StepIndex-1) + current) / float64(job.Steps) * 100
+	return float64(int(percent*10)) / 10
+}
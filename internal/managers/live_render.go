@@ -0,0 +1,138 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Render modes control how the public page is served
+const (
+	// RenderModeStatic serves the index.html written by Generate
+	RenderModeStatic = "static"
+	// RenderModeLive renders the page from the current content per request
+	RenderModeLive = "live"
+)
+
+// liveRenderMaxAge bounds how long a live render is reused while no data
+// file changes, so scheduled content still goes live on time
+const liveRenderMaxAge = time.Minute
+
+// ValidRenderMode reports whether mode is a supported render mode
+func ValidRenderMode(mode string) bool {
+	return mode == RenderModeStatic || mode == RenderModeLive
+}
+
+// LiveRenderer serves the site in live mode. It renders through the same
+// code path as Generate and reuses the last render until a file in the data
+// directory changes, so edits show up on the next request without a
+// publish step.
+type LiveRenderer struct {
+	generator *SiteGenerator
+
+	mu         sync.Mutex
+	site       *renderedSite
+	stamp      string
+	renderedAt time.Time
+}
+
+// NewLiveRenderer creates a live renderer over generator
+func NewLiveRenderer(generator *SiteGenerator) *LiveRenderer {
+	return &LiveRenderer{generator: generator}
+}
+
+// Pages returns the rendered page and, while an A/B test runs, its B page
+func (lr *LiveRenderer) Pages(ctx context.Context) (page, variant *ProcessedAssets, err error) {
+	site, _, err := lr.current(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return site.page, site.variant, nil
+}
+
+// PublicContent returns the public content of the current render and when
+// it was rendered
+func (lr *LiveRenderer) PublicContent(ctx context.Context) (map[string]interface{}, time.Time, error) {
+	site, renderedAt, err := lr.current(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if lr.generator.schemaManager == nil {
+		return nil, time.Time{}, fmt.Errorf("no schema manager configured")
+	}
+	public, err := lr.generator.schemaManager.PublicContent(site.content)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to prepare public content: %w", err)
+	}
+	return public, renderedAt, nil
+}
+
+// Asset returns an external asset of the last render by its name under
+// assets/, for AssetModeExternal. Only the last render is searched; older
+// asset names fall through to the files Generate wrote.
+func (lr *LiveRenderer) Asset(name string) (string, bool) {
+	name = path.Join("assets", name)
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	if lr.site == nil {
+		return "", false
+	}
+	for _, assets := range []*ProcessedAssets{lr.site.page, lr.site.variant} {
+		if assets == nil {
+			continue
+		}
+		if body, ok := assets.Files[name]; ok {
+			return body, true
+		}
+	}
+	return "", false
+}
+
+// current returns the cached render, rendering again when the data
+// directory changed or the render is older than liveRenderMaxAge. Renders
+// are serialised, so a burst of requests after an edit renders once.
+func (lr *LiveRenderer) current(ctx context.Context) (*renderedSite, time.Time, error) {
+	stamp, err := lr.dataStamp()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	if lr.site != nil && lr.stamp == stamp && time.Since(lr.renderedAt) < liveRenderMaxAge {
+		return lr.site, lr.renderedAt, nil
+	}
+
+	now := time.Now()
+	site, err := lr.generator.renderSite(ctx, now)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	lr.site, lr.stamp, lr.renderedAt = site, stamp, now
+	return site, now, nil
+}
+
+// dataStamp summarises the name, size and modification time of every file
+// in the data directory; any edit through the admin changes it
+func (lr *LiveRenderer) dataStamp() (string, error) {
+	entries, err := os.ReadDir(lr.generator.storage.GetFilePath(""))
+	if err != nil {
+		return "", fmt.Errorf("failed to read data directory: %w", err)
+	}
+	var stamp strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // removed while listing
+		}
+		fmt.Fprintf(&stamp, "%s:%d:%d;", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return stamp.String(), nil
+}
@@ -0,0 +1,265 @@
+package managers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DataVersionFilename marks the layout version of the data directory
+const DataVersionFilename = "version"
+
+// migrationBackupsDir holds a copy of the data directory taken before each
+// upgrade, relative to the data directory
+const migrationBackupsDir = "migration-backups"
+
+// Migration upgrades the data directory from Version-1 to Version
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(storage *FileStorage) error
+}
+
+// dataMigrations lists every layout change in order. Append new migrations
+// here; never edit or reorder released ones.
+var dataMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "Record the data directory version",
+		Up: func(storage *FileStorage) error {
+			return storage.EnsureDirectories()
+		},
+	},
+}
+
+// CurrentDataVersion is the layout version this build reads and writes
+func CurrentDataVersion() int {
+	return dataMigrations[len(dataMigrations)-1].Version
+}
+
+// ErrMigrationBackup reports that the data directory could not be backed
+// up, so no migration was applied
+var ErrMigrationBackup = errors.New("pre-migration backup failed")
+
+// MigrationRunner upgrades the data directory on startup
+type MigrationRunner struct {
+	storage    *FileStorage
+	migrations []Migration
+}
+
+// NewMigrationRunner creates a runner for the data directory of storage
+func NewMigrationRunner(storage *FileStorage) *MigrationRunner {
+	return &MigrationRunner{
+		storage:    storage,
+		migrations: dataMigrations,
+	}
+}
+
+// Version returns the layout version on disk. A data directory without a
+// marker is version 0, or current when it holds no files yet.
+func (mr *MigrationRunner) Version() (int, error) {
+	if mr.storage.FileExists(DataVersionFilename) {
+		text, err := mr.storage.ReadTextFile(DataVersionFilename)
+		if err != nil {
+			return 0, err
+		}
+		version, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil || version < 0 {
+			return 0, fmt.Errorf("invalid data version %q in %s", strings.TrimSpace(text), DataVersionFilename)
+		}
+		return version, nil
+	}
+
+	entries, err := os.ReadDir(mr.storage.GetFilePath(""))
+	if os.IsNotExist(err) || (err == nil && len(entries) == 0) {
+		return CurrentDataVersion(), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read data directory: %w", err)
+	}
+	return 0, nil
+}
+
+// Pending returns the migrations not yet applied. A data directory written
+// by a newer build is an error, since this build could damage it.
+func (mr *MigrationRunner) Pending() ([]Migration, error) {
+	version, err := mr.Version()
+	if err != nil {
+		return nil, err
+	}
+	if version > CurrentDataVersion() {
+		return nil, fmt.Errorf("data directory is at version %d but this build supports up to %d; upgrade OnePage CMS", version, CurrentDataVersion())
+	}
+
+	var pending []Migration
+	for _, migration := range mr.migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Run backs up the data directory and applies pending migrations in order,
+// recording the version after each one so a failure resumes where it
+// stopped. It returns the migrations applied and the backup path, which is
+// empty when nothing was pending or the directory was new.
+func (mr *MigrationRunner) Run() ([]Migration, string, error) {
+	version, err := mr.Version()
+	if err != nil {
+		return nil, "", err
+	}
+	pending, err := mr.Pending()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !mr.storage.FileExists(DataVersionFilename) && version == CurrentDataVersion() {
+		// New install: nothing to upgrade
+		if err := mr.storage.EnsureDirectories(); err != nil {
+			return nil, "", err
+		}
+		return nil, "", mr.writeVersion(version)
+	}
+	if len(pending) == 0 {
+		return nil, "", nil
+	}
+
+	backup, err := mr.backup(version)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrMigrationBackup, err)
+	}
+
+	var applied []Migration
+	for _, migration := range pending {
+		if err := migration.Up(mr.storage); err != nil {
+			return applied, backup, fmt.Errorf("migration %d (%s) failed: %w; a backup is at %s", migration.Version, migration.Description, err, backup)
+		}
+		if err := mr.writeVersion(migration.Version); err != nil {
+			return applied, backup, err
+		}
+		applied = append(applied, migration)
+	}
+	return applied, backup, nil
+}
+
+// writeVersion records the layout version
+func (mr *MigrationRunner) writeVersion(version int) error {
+	if err := mr.storage.WriteBinaryFile(DataVersionFilename, []byte(strconv.Itoa(version)+"\n")); err != nil {
+		return fmt.Errorf("failed to write data version: %w", err)
+	}
+	return nil
+}
+
+// backup copies the data directory, except earlier migration backups, to
+// migration-backups/v<version>-<time>
+func (mr *MigrationRunner) backup(version int) (string, error) {
+	root := mr.storage.GetFilePath("")
+	name := fmt.Sprintf("v%d-%s", version, time.Now().UTC().Format("20060102T150405Z"))
+	target := filepath.Join(root, migrationBackupsDir, name)
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel == migrationBackupsDir {
+			return filepath.SkipDir
+		}
+
+		dest := filepath.Join(target, rel)
+		if entry.IsDir() {
+			return os.MkdirAll(dest, 0755)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		return copyFile(path, dest)
+	})
+	if err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// MigrationBackup is a copy of the data directory taken before an upgrade
+type MigrationBackup struct {
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Backups lists the pre-migration backups, oldest first. Directories not
+// named by backup are left out.
+func (mr *MigrationRunner) Backups() ([]MigrationBackup, error) {
+	entries, err := os.ReadDir(mr.storage.GetFilePath(migrationBackupsDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", migrationBackupsDir, err)
+	}
+
+	var backups []MigrationBackup
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		versionText, stamp, found := strings.Cut(strings.TrimPrefix(entry.Name(), "v"), "-")
+		version, versionErr := strconv.Atoi(versionText)
+		createdAt, timeErr := time.Parse("20060102T150405Z", stamp)
+		if !found || versionErr != nil || timeErr != nil {
+			continue
+		}
+		backups = append(backups, MigrationBackup{Name: entry.Name(), Version: version, CreatedAt: createdAt})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.Before(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// DeleteBackup removes a pre-migration backup by name
+func (mr *MigrationRunner) DeleteBackup(name string) error {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid backup name %q", name)
+	}
+	path := filepath.Join(mr.storage.GetFilePath(migrationBackupsDir), name)
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("backup %q not found", name)
+	}
+	return os.RemoveAll(path)
+}
+
+// copyFile copies a regular file, keeping its permissions
+func copyFile(source, dest string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,275 @@
+package managers
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// mustacheEngine renders logic-less Mustache templates: {{name}} (escaped),
+// {{{name}}} and {{& name}} (raw), {{#name}}...{{/name}} sections that
+// repeat for lists and enter objects, {{^name}}...{{/name}} inverted
+// sections and {{! comments}}. Names may be dotted and {{.}} is the
+// current item. Empty strings count as false, as in JavaScript. Partials
+// and delimiter changes are not supported.
+type mustacheEngine struct{}
+
+// Mustache node kinds
+const (
+	mustacheText = iota
+	mustacheVariable
+	mustacheSection
+	mustacheInverted
+)
+
+// mustacheNode is a parsed piece of a Mustache template
+type mustacheNode struct {
+	kind     int
+	text     string // literal text, or the tag name
+	raw      bool   // variable is not HTML-escaped
+	children []mustacheNode
+}
+
+func (mustacheEngine) Name() string {
+	return EngineMustache
+}
+
+func (mustacheEngine) Check(source string) error {
+	_, err := parseMustache(source)
+	return err
+}
+
+func (mustacheEngine) Execute(source string, data map[string]interface{}, _ template.FuncMap) (string, error) {
+	nodes, err := parseMustache(source)
+	if err != nil {
+		return "", fmt.Errorf("template parsing failed: %w", err)
+	}
+	var b strings.Builder
+	renderMustache(&b, nodes, []interface{}{data})
+	return b.String(), nil
+}
+
+func (mustacheEngine) Fields(source string) ([]TemplateField, error) {
+	nodes, err := parseMustache(source)
+	if err != nil {
+		return nil, fmt.Errorf("template parsing failed: %w", err)
+	}
+	walker := &fieldWalker{fields: make(map[string]*TemplateField)}
+	mustacheFields(walker, nodes, [][]string{{}})
+
+	fields := make([]TemplateField, 0, len(walker.fields))
+	for _, field := range walker.fields {
+		fields = append(fields, *field)
+	}
+	sortTemplateFields(fields)
+	return fields, nil
+}
+
+// parseMustache parses source into a tree of nodes
+func parseMustache(source string) ([]mustacheNode, error) {
+	type frame struct {
+		node  mustacheNode
+		nodes []mustacheNode
+	}
+	stack := []frame{{}}
+	line := 1
+
+	for source != "" {
+		start := strings.Index(source, "{{")
+		if start < 0 {
+			stack[len(stack)-1].nodes = append(stack[len(stack)-1].nodes, mustacheNode{kind: mustacheText, text: source})
+			break
+		}
+		if start > 0 {
+			stack[len(stack)-1].nodes = append(stack[len(stack)-1].nodes, mustacheNode{kind: mustacheText, text: source[:start]})
+		}
+		line += strings.Count(source[:start], "\n")
+		source = source[start:]
+
+		closing := "}}"
+		if strings.HasPrefix(source, "{{{") {
+			closing = "}}}"
+		}
+		end := strings.Index(source, closing)
+		if end < 0 {
+			return nil, fmt.Errorf("line %d: unclosed tag", line)
+		}
+		tag := source[2:end]
+		if closing == "}}}" {
+			tag = "&" + tag[1:]
+		}
+		line += strings.Count(source[:end], "\n")
+		source = source[end+len(closing):]
+
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			return nil, fmt.Errorf("line %d: empty tag", line)
+		}
+		sigil, name := tag[0], strings.TrimSpace(tag[1:])
+		top := &stack[len(stack)-1]
+		switch sigil {
+		case '!':
+			continue
+		case '#', '^':
+			kind := mustacheSection
+			if sigil == '^' {
+				kind = mustacheInverted
+			}
+			stack = append(stack, frame{node: mustacheNode{kind: kind, text: name}})
+		case '/':
+			if len(stack) == 1 || stack[len(stack)-1].node.text != name {
+				return nil, fmt.Errorf("line %d: unexpected {{/%s}}", line, name)
+			}
+			closed := stack[len(stack)-1]
+			closed.node.children = closed.nodes
+			stack = stack[:len(stack)-1]
+			stack[len(stack)-1].nodes = append(stack[len(stack)-1].nodes, closed.node)
+		case '&':
+			top.nodes = append(top.nodes, mustacheNode{kind: mustacheVariable, text: name, raw: true})
+		case '>', '=', '<', '$':
+			return nil, fmt.Errorf("line %d: {{%c}} tags are not supported", line, sigil)
+		default:
+			top.nodes = append(top.nodes, mustacheNode{kind: mustacheVariable, text: tag})
+		}
+	}
+
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("unclosed section {{#%s}}", stack[len(stack)-1].node.text)
+	}
+	return stack[0].nodes, nil
+}
+
+// renderMustache writes nodes with the context stack, innermost last
+func renderMustache(b *strings.Builder, nodes []mustacheNode, stack []interface{}) {
+	for _, node := range nodes {
+		switch node.kind {
+		case mustacheText:
+			b.WriteString(node.text)
+		case mustacheVariable:
+			text := mustacheString(lookupMustache(stack, node.text))
+			if !node.raw {
+				text = html.EscapeString(text)
+			}
+			b.WriteString(text)
+		case mustacheSection:
+			value := lookupMustache(stack, node.text)
+			if !mustacheTruthy(value) {
+				continue
+			}
+			if list := reflect.ValueOf(value); list.Kind() == reflect.Slice {
+				for i := 0; i < list.Len(); i++ {
+					renderMustache(b, node.children, append(stack, list.Index(i).Interface()))
+				}
+				continue
+			}
+			renderMustache(b, node.children, append(stack, value))
+		case mustacheInverted:
+			if !mustacheTruthy(lookupMustache(stack, node.text)) {
+				renderMustache(b, node.children, stack)
+			}
+		}
+	}
+}
+
+// lookupMustache resolves a dotted name: its first part in the innermost
+// context that has it, the rest inside that value
+func lookupMustache(stack []interface{}, name string) interface{} {
+	if name == "." {
+		return stack[len(stack)-1]
+	}
+	parts := strings.Split(name, ".")
+	var value interface{}
+	found := false
+	for i := len(stack) - 1; i >= 0 && !found; i-- {
+		value, found = mustacheKey(stack[i], parts[0])
+	}
+	for _, part := range parts[1:] {
+		if !found {
+			return nil
+		}
+		value, found = mustacheKey(value, part)
+	}
+	return value
+}
+
+// mustacheKey looks up a key in a map value
+func mustacheKey(value interface{}, key string) (interface{}, bool) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	found, ok := object[key]
+	return found, ok
+}
+
+// mustacheTruthy reports whether a section is shown for a value
+func mustacheTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+	if list := reflect.ValueOf(value); list.Kind() == reflect.Slice || list.Kind() == reflect.Map {
+		return list.Len() > 0
+	}
+	return true
+}
+
+// mustacheString formats a value for output
+func mustacheString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// mustacheFields records the paths nodes read. Names are taken to start in
+// the innermost section, unless they repeat the section's own name, as in
+// {{#image}}<img src="{{image}}">{{/image}}, or start with sections or a
+// key the generator adds. Whether a section is a list cannot be seen, so
+// items are not marked with "[]".
+func mustacheFields(walker *fieldWalker, nodes []mustacheNode, scopes [][]string) {
+	for _, node := range nodes {
+		if node.kind == mustacheText {
+			continue
+		}
+		path := mustachePath(scopes, node.text)
+		switch node.kind {
+		case mustacheVariable:
+			walker.record(path, true)
+		case mustacheSection:
+			walker.record(path, false)
+			mustacheFields(walker, node.children, append(scopes, path))
+		case mustacheInverted:
+			walker.record(path, false)
+			mustacheFields(walker, node.children, scopes)
+		}
+	}
+}
+
+// mustachePath resolves a tag name to a content path
+func mustachePath(scopes [][]string, name string) []string {
+	scope := scopes[len(scopes)-1]
+	if name == "." {
+		return scope
+	}
+	parts := strings.Split(name, ".")
+	if parts[0] == "sections" || templateSystemFields[parts[0]] {
+		return parts
+	}
+	if len(scope) > 0 && scope[len(scope)-1] == parts[0] {
+		scope = scope[:len(scope)-1]
+	}
+	return appendStep(scope, parts...)
+}
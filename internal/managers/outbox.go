@@ -0,0 +1,321 @@
+package managers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// outboxFilename holds outbound messages until they are delivered
+const outboxFilename = "outbox.json"
+
+// Kinds of outbound message
+const (
+	OutboxWebhook = "webhook"
+	OutboxEmail   = "email"
+	OutboxDeploy  = "deploy"
+)
+
+// Outbox retry policy: the first retry comes after outboxRetryBase, each
+// later one after twice as long up to outboxRetryMax, and a message that
+// failed outboxMaxAttempts times is dead-lettered
+const (
+	outboxRetryBase   = 30 * time.Second
+	outboxRetryMax    = time.Hour
+	outboxMaxAttempts = 10
+)
+
+// Outbox message states
+const (
+	OutboxPending = "pending"
+	OutboxDead    = "dead"
+)
+
+// ErrOutboxNotFound is returned for unknown message IDs
+var ErrOutboxNotFound = errors.New("outbox message not found")
+
+// OutboxMessage is one notification waiting to be delivered. Payload is
+// what the handler for its kind needs, as JSON.
+type OutboxMessage struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	Summary     string          `json:"summary"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	CreatedAt   time.Time       `json:"created_at"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	LastError   string          `json:"last_error,omitempty"`
+	LastAttempt *time.Time      `json:"last_attempt,omitempty"`
+}
+
+// OutboxHandler delivers the payload of one message
+type OutboxHandler func(ctx context.Context, payload json.RawMessage) error
+
+// Outbox keeps webhooks, emails and deploy triggers on disk until they are
+// delivered, so they survive restarts and are retried with backoff when the
+// receiving end fails. Messages that keep failing are kept as dead letters
+// for an admin to retry or discard.
+type Outbox struct {
+	storage  *FileStorage
+	handlers map[string]OutboxHandler
+	now      func() time.Time
+
+	mu        sync.Mutex // guards the file
+	processMu sync.Mutex // one delivery pass at a time
+}
+
+// NewOutbox creates an outbox. Register a handler for each kind before
+// messages are processed.
+func NewOutbox(storage *FileStorage) *Outbox {
+	return &Outbox{storage: storage, handlers: make(map[string]OutboxHandler), now: time.Now}
+}
+
+// Handle registers the handler delivering messages of kind
+func (o *Outbox) Handle(kind string, handler OutboxHandler) {
+	o.handlers[kind] = handler
+}
+
+// Enqueue stores a message for delivery on the next pass. summary says
+// what it is in listings; payload is encoded as JSON.
+func (o *Outbox) Enqueue(kind, summary string, payload interface{}) (*OutboxMessage, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s message: %w", kind, err)
+	}
+	id := make([]byte, 8)
+	rand.Read(id)
+	now := o.now()
+	message := &OutboxMessage{
+		ID:          hex.EncodeToString(id),
+		Kind:        kind,
+		Summary:     summary,
+		Payload:     data,
+		Status:      OutboxPending,
+		CreatedAt:   now,
+		NextAttempt: now,
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	messages, err := o.load()
+	if err != nil {
+		return nil, err
+	}
+	if err := o.save(append(messages, message)); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// List returns the messages waiting or dead-lettered, oldest first
+func (o *Outbox) List() ([]OutboxMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	messages, err := o.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]OutboxMessage, 0, len(messages))
+	for _, message := range messages {
+		list = append(list, *message)
+	}
+	return list, nil
+}
+
+// Process delivers the pending messages that are due, oldest first. Failed
+// messages are rescheduled, or dead-lettered after too many attempts. It
+// returns how many were delivered and how many failed.
+func (o *Outbox) Process(ctx context.Context) (delivered, failed int, err error) {
+	o.processMu.Lock()
+	defer o.processMu.Unlock()
+
+	o.mu.Lock()
+	messages, err := o.load()
+	o.mu.Unlock()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	now := o.now()
+	var due []*OutboxMessage
+	for _, message := range messages {
+		if message.Status == OutboxPending && !message.NextAttempt.After(now) {
+			due = append(due, message)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].CreatedAt.Before(due[j].CreatedAt) })
+
+	for _, message := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		deliverErr := o.deliver(ctx, message)
+		if deliverErr == nil {
+			delivered++
+		} else {
+			failed++
+		}
+		if err := o.record(message.ID, deliverErr); err != nil {
+			return delivered, failed, err
+		}
+	}
+	return delivered, failed, nil
+}
+
+// deliver runs the handler for one message, turning a panic into an error
+func (o *Outbox) deliver(ctx context.Context, message *OutboxMessage) (err error) {
+	handler := o.handlers[message.Kind]
+	if handler == nil {
+		return fmt.Errorf("no handler for %s messages", message.Kind)
+	}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("handler panicked: %v", recovered)
+		}
+	}()
+	return handler(ctx, message.Payload)
+}
+
+// record removes a delivered message, or notes the failure and schedules
+// the next attempt
+func (o *Outbox) record(id string, deliverErr error) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	messages, err := o.load()
+	if err != nil {
+		return err
+	}
+	for i, message := range messages {
+		if message.ID != id {
+			continue
+		}
+		if deliverErr == nil {
+			messages = append(messages[:i], messages[i+1:]...)
+			break
+		}
+		now := o.now()
+		message.Attempts++
+		message.LastAttempt = &now
+		message.LastError = deliverErr.Error()
+		if message.Attempts >= outboxMaxAttempts {
+			message.Status = OutboxDead
+			break
+		}
+		message.NextAttempt = now.Add(outboxBackoff(message.Attempts))
+		break
+	}
+	return o.save(messages)
+}
+
+// outboxBackoff is the wait after the given number of failed attempts
+func outboxBackoff(attempts int) time.Duration {
+	delay := outboxRetryBase
+	for i := 1; i < attempts && delay < outboxRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxRetryMax)
+}
+
+// Retry makes a message due now, reviving it if it was dead-lettered
+func (o *Outbox) Retry(id string) (*OutboxMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	messages, err := o.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, message := range messages {
+		if message.ID == id {
+			if message.Status == OutboxDead {
+				message.Attempts = 0
+			}
+			message.Status = OutboxPending
+			message.NextAttempt = o.now()
+			if err := o.save(messages); err != nil {
+				return nil, err
+			}
+			return message, nil
+		}
+	}
+	return nil, ErrOutboxNotFound
+}
+
+// Discard removes a message without delivering it
+func (o *Outbox) Discard(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	messages, err := o.load()
+	if err != nil {
+		return err
+	}
+	for i, message := range messages {
+		if message.ID == id {
+			return o.save(append(messages[:i], messages[i+1:]...))
+		}
+	}
+	return ErrOutboxNotFound
+}
+
+// PruneDead removes the dead letters whose last attempt was before cutoff
+// and returns how many it removed. Pending messages are never pruned.
+func (o *Outbox) PruneDead(cutoff time.Time) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	messages, err := o.load()
+	if err != nil {
+		return 0, err
+	}
+	kept := messages[:0]
+	for _, message := range messages {
+		last := message.CreatedAt
+		if message.LastAttempt != nil {
+			last = *message.LastAttempt
+		}
+		if message.Status == OutboxDead && last.Before(cutoff) {
+			continue
+		}
+		kept = append(kept, message)
+	}
+	removed := len(messages) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	if err := o.save(kept); err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// load reads the messages; callers hold mu
+func (o *Outbox) load() ([]*OutboxMessage, error) {
+	var messages []*OutboxMessage
+	if !o.storage.FileExists(outboxFilename) {
+		return messages, nil
+	}
+	if err := o.storage.ReadJSONFile(outboxFilename, &messages); err != nil {
+		return nil, fmt.Errorf("failed to load outbox: %w", err)
+	}
+	return messages, nil
+}
+
+// save writes the messages; callers hold mu
+func (o *Outbox) save(messages []*OutboxMessage) error {
+	if messages == nil {
+		messages = []*OutboxMessage{}
+	}
+	if err := o.storage.WriteJSONFile(outboxFilename, messages); err != nil {
+		return fmt.Errorf("failed to save outbox: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package managers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Line diff operations
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// maxDiffCells bounds the line comparison table; larger changes are shown
+// as the whole changed middle of the page being replaced
+const maxDiffCells = 4_000_000
+
+// DiffLine is one line of a diff. OldLine and NewLine are 1-based line
+// numbers, zero on the side the line does not exist.
+type DiffLine struct {
+	Op      string `json:"op"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+	Text    string `json:"text"`
+}
+
+// DiffHunk is a run of changes with the unchanged lines around them
+type DiffHunk struct {
+	OldStart int        `json:"old_start"`
+	OldLines int        `json:"old_lines"`
+	NewStart int        `json:"new_start"`
+	NewLines int        `json:"new_lines"`
+	Lines    []DiffLine `json:"lines"`
+}
+
+// PageDiff compares the published page with the one a generation would
+// write
+type PageDiff struct {
+	Changed bool       `json:"changed"`
+	Added   int        `json:"added"`
+	Removed int        `json:"removed"`
+	Hunks   []DiffHunk `json:"hunks"`
+}
+
+// DiffText compares two texts line by line, keeping context unchanged lines
+// around each change
+func DiffText(oldText, newText string, context int) *PageDiff {
+	lines := diffLines(splitLines(oldText), splitLines(newText))
+
+	diff := &PageDiff{Hunks: make([]DiffHunk, 0)}
+	for _, line := range lines {
+		switch line.Op {
+		case DiffInsert:
+			diff.Added++
+		case DiffDelete:
+			diff.Removed++
+		}
+	}
+	diff.Changed = diff.Added > 0 || diff.Removed > 0
+	if !diff.Changed {
+		return diff
+	}
+
+	// Group changes whose context overlaps into one hunk
+	var hunk *DiffHunk
+	lastChange := -1
+	for i, line := range lines {
+		if line.Op == DiffEqual {
+			continue
+		}
+		start := max(i-context, 0)
+		if hunk != nil && start <= lastChange+context+1 {
+			hunk.Lines = append(hunk.Lines, lines[lastChange+1:i+1]...)
+		} else {
+			if hunk != nil {
+				hunk.Lines = append(hunk.Lines, lines[lastChange+1:min(lastChange+1+context, len(lines))]...)
+				diff.Hunks = append(diff.Hunks, *hunk)
+			}
+			hunk = &DiffHunk{Lines: append([]DiffLine(nil), lines[start:i+1]...)}
+		}
+		lastChange = i
+	}
+	hunk.Lines = append(hunk.Lines, lines[lastChange+1:min(lastChange+1+context, len(lines))]...)
+	diff.Hunks = append(diff.Hunks, *hunk)
+
+	for i := range diff.Hunks {
+		countHunk(&diff.Hunks[i])
+	}
+	return diff
+}
+
+// Unified formats the diff like diff -u, with the given file labels
+func (d *PageDiff) Unified(oldName, newName string) string {
+	if !d.Changed {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for _, hunk := range d.Hunks {
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+		for _, line := range hunk.Lines {
+			switch line.Op {
+			case DiffInsert:
+				b.WriteString("+")
+			case DiffDelete:
+				b.WriteString("-")
+			default:
+				b.WriteString(" ")
+			}
+			b.WriteString(line.Text)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// countHunk fills in the line ranges of a hunk from its lines
+func countHunk(hunk *DiffHunk) {
+	for _, line := range hunk.Lines {
+		if line.OldLine > 0 {
+			if hunk.OldStart == 0 {
+				hunk.OldStart = line.OldLine
+			}
+			hunk.OldLines++
+		}
+		if line.NewLine > 0 {
+			if hunk.NewStart == 0 {
+				hunk.NewStart = line.NewLine
+			}
+			hunk.NewLines++
+		}
+	}
+}
+
+// splitLines splits text into lines without their line endings
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines returns the edit script turning a into b, using a longest
+// common subsequence of the lines between their common prefix and suffix
+func diffLines(a, b []string) []DiffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, DiffLine{Op: DiffEqual, OldLine: i + 1, NewLine: i + 1, Text: a[i]})
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(midA), len(midB)
+	if n*m > maxDiffCells {
+		for i, text := range midA {
+			lines = append(lines, DiffLine{Op: DiffDelete, OldLine: prefix + i + 1, Text: text})
+		}
+		for j, text := range midB {
+			lines = append(lines, DiffLine{Op: DiffInsert, NewLine: prefix + j + 1, Text: text})
+		}
+	} else {
+		// lcs[i][j] is the common subsequence length of midA[i:] and midB[j:]
+		lcs := make([][]int32, n+1)
+		for i := range lcs {
+			lcs[i] = make([]int32, m+1)
+		}
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < n || j < m {
+			switch {
+			case i < n && j < m && midA[i] == midB[j]:
+				lines = append(lines, DiffLine{Op: DiffEqual, OldLine: prefix + i + 1, NewLine: prefix + j + 1, Text: midA[i]})
+				i++
+				j++
+			case j < m && (i == n || lcs[i][j+1] >= lcs[i+1][j]):
+				lines = append(lines, DiffLine{Op: DiffInsert, NewLine: prefix + j + 1, Text: midB[j]})
+				j++
+			default:
+				lines = append(lines, DiffLine{Op: DiffDelete, OldLine: prefix + i + 1, Text: midA[i]})
+				i++
+			}
+		}
+	}
+
+	for k := 0; k < suffix; k++ {
+		i, j := len(a)-suffix+k, len(b)-suffix+k
+		lines = append(lines, DiffLine{Op: DiffEqual, OldLine: i + 1, NewLine: j + 1, Text: a[i]})
+	}
+	return lines
+}
@@ -0,0 +1,446 @@
+package managers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// passkeysFilename stores the registered passkeys and their public keys
+const passkeysFilename = "passkeys.json"
+
+// passkeyTimeout is how long a registration or login ceremony may take
+const passkeyTimeout = 5 * time.Minute
+
+// maxPasskeyChallenges bounds the pending ceremonies, as login challenges
+// are handed out before sign-in
+const maxPasskeyChallenges = 1000
+
+// Passkey login modes
+const (
+	PasskeyLoginOff          = "off"
+	PasskeyLoginPasswordless = "passwordless"  // a passkey alone signs in
+	PasskeyLoginSecondFactor = "second_factor" // a passkey is needed after the password
+)
+
+// ValidPasskeyLogin reports whether mode is a known passkey login mode
+func ValidPasskeyLogin(mode string) bool {
+	switch mode {
+	case PasskeyLoginOff, PasskeyLoginPasswordless, PasskeyLoginSecondFactor:
+		return true
+	}
+	return false
+}
+
+// Passkey failures other than ErrPasskeyInvalid
+var (
+	ErrPasskeyNotFound  = errors.New("passkey not found")
+	ErrPasskeyChallenge = errors.New("unknown or expired passkey challenge")
+)
+
+// PasskeyRelyingParty is the site passkeys are registered with: the origin
+// the admin is served from, whose host is the relying party ID
+type PasskeyRelyingParty struct {
+	Origin string
+	Name   string
+}
+
+// NewPasskeyRelyingParty checks origin and returns the relying party for it
+func NewPasskeyRelyingParty(origin, name string) (PasskeyRelyingParty, error) {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return PasskeyRelyingParty{}, fmt.Errorf("passkey origin %q must be an http(s) URL", origin)
+	}
+	return PasskeyRelyingParty{Origin: u.Scheme + "://" + u.Host, Name: name}, nil
+}
+
+// ID is the relying party ID: the host name of the origin
+func (rp PasskeyRelyingParty) ID() string {
+	u, _ := url.Parse(rp.Origin)
+	return u.Hostname()
+}
+
+// storedPasskey is a passkey as written to passkeys.json
+type storedPasskey struct {
+	types.Passkey
+	PublicKey []byte `json:"public_key"` // COSE key
+}
+
+// passkeyChallenge is a pending registration or login
+type passkeyChallenge struct {
+	login    bool
+	username string
+	// secondFactor marks a login challenge issued after the password was
+	// checked, so the passkey completes a two-factor sign-in
+	secondFactor bool
+	expires      time.Time
+}
+
+// PasskeyLogin is a completed passkey sign-in
+type PasskeyLogin struct {
+	Passkey      types.Passkey
+	SecondFactor bool // the password was checked before the passkey
+}
+
+// PasskeyManager registers WebAuthn passkeys for the admin and checks
+// passkey sign-ins
+type PasskeyManager struct {
+	storage    *FileStorage
+	mu         sync.Mutex // guards passkeys.json and challenges
+	challenges map[string]passkeyChallenge
+}
+
+// NewPasskeyManager creates a passkey manager backed by passkeys.json
+func NewPasskeyManager(storage *FileStorage) *PasskeyManager {
+	return &PasskeyManager{storage: storage, challenges: make(map[string]passkeyChallenge)}
+}
+
+// load reads the stored passkeys; callers hold mu
+func (pm *PasskeyManager) load() ([]storedPasskey, error) {
+	if !pm.storage.FileExists(passkeysFilename) {
+		return nil, nil
+	}
+	var passkeys []storedPasskey
+	if err := pm.storage.ReadJSONFile(passkeysFilename, &passkeys); err != nil {
+		return nil, fmt.Errorf("failed to load passkeys: %w", err)
+	}
+	return passkeys, nil
+}
+
+// save replaces the stored passkeys; callers hold mu
+func (pm *PasskeyManager) save(passkeys []storedPasskey) error {
+	if passkeys == nil {
+		passkeys = []storedPasskey{}
+	}
+	if err := pm.storage.WriteJSONFile(passkeysFilename, passkeys); err != nil {
+		return fmt.Errorf("failed to save passkeys: %w", err)
+	}
+	return nil
+}
+
+// List returns the passkeys of username, or of every user when username is
+// empty, newest first
+func (pm *PasskeyManager) List(username string) ([]types.Passkey, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	stored, err := pm.load()
+	if err != nil {
+		return nil, err
+	}
+	passkeys := []types.Passkey{}
+	for _, p := range stored {
+		if username == "" || p.Username == username {
+			passkeys = append(passkeys, p.Passkey)
+		}
+	}
+	sort.Slice(passkeys, func(i, j int) bool {
+		return passkeys[i].CreatedAt.After(passkeys[j].CreatedAt)
+	})
+	return passkeys, nil
+}
+
+// Count returns how many passkeys username has
+func (pm *PasskeyManager) Count(username string) (int, error) {
+	passkeys, err := pm.List(username)
+	return len(passkeys), err
+}
+
+// Delete removes the passkey with id
+func (pm *PasskeyManager) Delete(id string) (*types.Passkey, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	stored, err := pm.load()
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range stored {
+		if p.ID == id {
+			if err := pm.save(append(stored[:i], stored[i+1:]...)); err != nil {
+				return nil, err
+			}
+			return &p.Passkey, nil
+		}
+	}
+	return nil, ErrPasskeyNotFound
+}
+
+// newChallenge records a pending ceremony and returns its challenge;
+// callers hold mu
+func (pm *PasskeyManager) newChallenge(challenge passkeyChallenge, now time.Time) (string, error) {
+	for key, c := range pm.challenges {
+		if now.After(c.expires) {
+			delete(pm.challenges, key)
+		}
+	}
+	if len(pm.challenges) >= maxPasskeyChallenges {
+		return "", errors.New("too many pending passkey requests; try again later")
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	key := encodeBase64URL(raw)
+	challenge.expires = now.Add(passkeyTimeout)
+	pm.challenges[key] = challenge
+	return key, nil
+}
+
+// takeChallenge removes and returns a pending ceremony; each challenge is
+// used at most once. Callers hold mu.
+func (pm *PasskeyManager) takeChallenge(key string, login bool, now time.Time) (passkeyChallenge, error) {
+	challenge, ok := pm.challenges[trimBase64Padding(key)]
+	if !ok {
+		return passkeyChallenge{}, ErrPasskeyChallenge
+	}
+	delete(pm.challenges, trimBase64Padding(key))
+	if now.After(challenge.expires) || challenge.login != login {
+		return passkeyChallenge{}, ErrPasskeyChallenge
+	}
+	return challenge, nil
+}
+
+// passkeyUserID is the WebAuthn user handle of username: stable, and not
+// the username itself
+func passkeyUserID(username string) string {
+	hash := sha256.Sum256([]byte("onepagems passkey user:" + username))
+	return encodeBase64URL(hash[:16])
+}
+
+// descriptors lists passkeys as credential descriptors
+func descriptors(passkeys []storedPasskey, username string) []types.PasskeyDescriptor {
+	list := []types.PasskeyDescriptor{}
+	for _, p := range passkeys {
+		if username == "" || p.Username == username {
+			list = append(list, types.PasskeyDescriptor{Type: "public-key", ID: p.ID, Transports: p.Transports})
+		}
+	}
+	return list
+}
+
+// BeginRegistration starts registering a passkey for username and returns
+// the options for navigator.credentials.create
+func (pm *PasskeyManager) BeginRegistration(rp PasskeyRelyingParty, username string, now time.Time) (*types.PasskeyCreationOptions, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	stored, err := pm.load()
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := pm.newChallenge(passkeyChallenge{username: username}, now)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make([]types.PasskeyCredentialParam, 0, len(passkeyAlgorithms))
+	for _, alg := range passkeyAlgorithms {
+		params = append(params, types.PasskeyCredentialParam{Type: "public-key", Alg: alg})
+	}
+	return &types.PasskeyCreationOptions{
+		Challenge:          challenge,
+		RP:                 types.PasskeyRelyingParty{ID: rp.ID(), Name: rp.Name},
+		User:               types.PasskeyUser{ID: passkeyUserID(username), Name: username, DisplayName: username},
+		PubKeyCredParams:   params,
+		Timeout:            int(passkeyTimeout.Milliseconds()),
+		ExcludeCredentials: descriptors(stored, username),
+		AuthenticatorSelection: types.PasskeyAuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "preferred",
+		},
+		Attestation: "none",
+	}, nil
+}
+
+// FinishRegistration checks the credential created for a registration
+// started by username and stores it under name
+func (pm *PasskeyManager) FinishRegistration(rp PasskeyRelyingParty, username, name string, credential types.PasskeyCredential, now time.Time) (*types.Passkey, error) {
+	clientDataJSON, err := decodeBase64URL(credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, passkeyError("client data is not base64url")
+	}
+	cd, err := parseClientData(clientDataJSON, "webauthn.create", rp.Origin)
+	if err != nil {
+		return nil, err
+	}
+	attestation, err := decodeBase64URL(credential.Response.AttestationObject)
+	if err != nil {
+		return nil, passkeyError("attestation object is not base64url")
+	}
+	rawAuthData, err := parseAttestationObject(attestation)
+	if err != nil {
+		return nil, err
+	}
+	authData, err := parseAuthenticatorData(rawAuthData, rp.ID())
+	if err != nil {
+		return nil, err
+	}
+	if authData.credentialID == nil {
+		return nil, passkeyError("no credential was created")
+	}
+	_, alg, err := parseCOSEKey(authData.publicKey)
+	if err != nil {
+		return nil, passkeyError("%v", err)
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	challenge, err := pm.takeChallenge(cd.Challenge, false, now)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.username != username {
+		return nil, ErrPasskeyChallenge
+	}
+
+	stored, err := pm.load()
+	if err != nil {
+		return nil, err
+	}
+	id := encodeBase64URL(authData.credentialID)
+	for _, p := range stored {
+		if p.ID == id {
+			return nil, passkeyError("passkey is already registered")
+		}
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = fmt.Sprintf("Passkey %d", len(stored)+1)
+	}
+	passkey := storedPasskey{
+		Passkey: types.Passkey{
+			ID:         id,
+			Name:       shortenMessage(name, 100),
+			Username:   username,
+			Algorithm:  alg,
+			SignCount:  authData.signCount,
+			Transports: credential.Response.Transports,
+			CreatedAt:  now,
+		},
+		PublicKey: authData.publicKey,
+	}
+	if err := pm.save(append(stored, passkey)); err != nil {
+		return nil, err
+	}
+	return &passkey.Passkey, nil
+}
+
+// BeginLogin starts a passkey sign-in and returns the options for
+// navigator.credentials.get. A passwordless login offers every passkey; a
+// second factor login, for username whose password was just checked,
+// offers that user's passkeys.
+func (pm *PasskeyManager) BeginLogin(rp PasskeyRelyingParty, username string, secondFactor bool, now time.Time) (*types.PasskeyRequestOptions, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	stored, err := pm.load()
+	if err != nil {
+		return nil, err
+	}
+	allowed := descriptors(stored, username)
+	if len(allowed) == 0 {
+		return nil, ErrPasskeyNotFound
+	}
+	challenge, err := pm.newChallenge(passkeyChallenge{login: true, username: username, secondFactor: secondFactor}, now)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := "required"
+	if secondFactor {
+		verification = "preferred"
+	}
+	return &types.PasskeyRequestOptions{
+		Challenge:        challenge,
+		RPID:             rp.ID(),
+		Timeout:          int(passkeyTimeout.Milliseconds()),
+		AllowCredentials: allowed,
+		UserVerification: verification,
+	}, nil
+}
+
+// FinishLogin checks a passkey assertion for a pending login and records
+// its use. A passwordless login needs the authenticator to have verified
+// the user; a second factor login only needs their presence.
+func (pm *PasskeyManager) FinishLogin(rp PasskeyRelyingParty, credential types.PasskeyCredential, now time.Time) (*PasskeyLogin, error) {
+	clientDataJSON, err := decodeBase64URL(credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, passkeyError("client data is not base64url")
+	}
+	cd, err := parseClientData(clientDataJSON, "webauthn.get", rp.Origin)
+	if err != nil {
+		return nil, err
+	}
+	rawAuthData, err := decodeBase64URL(credential.Response.AuthenticatorData)
+	if err != nil {
+		return nil, passkeyError("authenticator data is not base64url")
+	}
+	signature, err := decodeBase64URL(credential.Response.Signature)
+	if err != nil {
+		return nil, passkeyError("signature is not base64url")
+	}
+	authData, err := parseAuthenticatorData(rawAuthData, rp.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	challenge, err := pm.takeChallenge(cd.Challenge, true, now)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := pm.load()
+	if err != nil {
+		return nil, err
+	}
+	id := trimBase64Padding(credential.RawID)
+	if id == "" {
+		id = trimBase64Padding(credential.ID)
+	}
+	index := -1
+	for i, p := range stored {
+		if p.ID == id && (challenge.username == "" || p.Username == challenge.username) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, ErrPasskeyNotFound
+	}
+	passkey := &stored[index]
+
+	if err := verifyPasskeySignature(passkey.PublicKey, rawAuthData, clientDataJSON, signature); err != nil {
+		return nil, err
+	}
+	if !challenge.secondFactor && !authData.userVerified() {
+		return nil, passkeyError("the authenticator did not verify the user; a PIN or biometrics is needed to sign in without a password")
+	}
+	// A counter that does not move forward suggests a cloned authenticator;
+	// authenticators that keep no counter always report 0
+	if authData.signCount != 0 || passkey.SignCount != 0 {
+		if authData.signCount <= passkey.SignCount {
+			return nil, passkeyError("signature counter went backwards; the passkey may have been cloned")
+		}
+	}
+
+	passkey.SignCount = authData.signCount
+	passkey.LastUsedAt = now
+	if err := pm.save(stored); err != nil {
+		return nil, err
+	}
+	return &PasskeyLogin{Passkey: passkey.Passkey, SecondFactor: challenge.secondFactor}, nil
+}
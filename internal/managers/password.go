@@ -0,0 +1,139 @@
+package managers
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PasswordHasher hashes admin passwords and checks them against stored
+// hashes. A hasher must verify every hash it may find in the config,
+// including ones it would no longer produce.
+type PasswordHasher interface {
+	// Hash returns an encoded hash of password with a fresh salt
+	Hash(password string) (string, error)
+	// Verify reports whether password matches the encoded hash
+	Verify(password, hash string) bool
+	// NeedsRehash reports whether hash should be replaced with a new one
+	// on the next successful sign-in
+	NeedsRehash(hash string) bool
+}
+
+// DefaultPBKDF2Iterations is the PBKDF2-HMAC-SHA256 work factor recommended
+// by OWASP
+const DefaultPBKDF2Iterations = 600000
+
+// MaxPBKDF2Iterations is the highest work factor accepted from a stored
+// hash, so a tampered or imported auth.json cannot make each sign-in burn
+// CPU for minutes
+const MaxPBKDF2Iterations = 10 * DefaultPBKDF2Iterations
+
+// pbkdf2Prefix starts every hash made by PBKDF2Hasher
+const pbkdf2Prefix = "$pbkdf2-sha256$"
+
+// PBKDF2Hasher hashes passwords with salted PBKDF2-HMAC-SHA256, encoded as
+// $pbkdf2-sha256$<iterations>$<salt>$<key>. It also verifies the unsalted
+// SHA-256 hex digests of earlier versions, and asks for them to be rehashed.
+// PBKDF2 is used rather than bcrypt or argon2id because it is the only one
+// in the standard library and the module has no dependencies; those can be
+// plugged in as a PasswordHasher.
+type PBKDF2Hasher struct {
+	Iterations int
+}
+
+// NewPasswordHasher returns the default password hasher
+func NewPasswordHasher() *PBKDF2Hasher {
+	return &PBKDF2Hasher{Iterations: DefaultPBKDF2Iterations}
+}
+
+// HashPassword hashes password with the default hasher
+func HashPassword(password string) (string, error) {
+	return NewPasswordHasher().Hash(password)
+}
+
+// Hash returns the encoded PBKDF2 hash of password with a random salt
+func (h *PBKDF2Hasher) Hash(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, h.iterations(), sha256.Size)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return pbkdf2Prefix + strconv.Itoa(h.iterations()) + "$" +
+		base64.RawStdEncoding.EncodeToString(salt) + "$" +
+		base64.RawStdEncoding.EncodeToString(key), nil
+}
+
+// Verify reports whether password matches hash, in constant time
+func (h *PBKDF2Hasher) Verify(password, hash string) bool {
+	if IsLegacyPasswordHash(hash) {
+		sum := sha256.Sum256([]byte(password))
+		return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(strings.ToLower(hash))) == 1
+	}
+	iterations, salt, want, ok := parsePBKDF2Hash(hash)
+	if !ok {
+		return false
+	}
+	got, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// NeedsRehash reports whether hash is a legacy digest or uses fewer
+// iterations than the hasher is configured for
+func (h *PBKDF2Hasher) NeedsRehash(hash string) bool {
+	iterations, _, _, ok := parsePBKDF2Hash(hash)
+	return !ok || iterations < h.iterations()
+}
+
+// iterations returns the configured work factor, within what Verify accepts
+func (h *PBKDF2Hasher) iterations() int {
+	if h.Iterations <= 0 {
+		return DefaultPBKDF2Iterations
+	}
+	return min(h.Iterations, MaxPBKDF2Iterations)
+}
+
+// IsLegacyPasswordHash reports whether hash is an unsalted SHA-256 hex
+// digest, as stored before password hashing used PBKDF2
+func IsLegacyPasswordHash(hash string) bool {
+	if len(hash) != hex.EncodedLen(sha256.Size) {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// parsePBKDF2Hash splits an encoded PBKDF2 hash into its parts, rejecting
+// work factors outside 1 to MaxPBKDF2Iterations
+func parsePBKDF2Hash(hash string) (iterations int, salt, key []byte, ok bool) {
+	rest, found := strings.CutPrefix(hash, pbkdf2Prefix)
+	if !found {
+		return 0, nil, nil, false
+	}
+	parts := strings.Split(rest, "$")
+	if len(parts) != 3 {
+		return 0, nil, nil, false
+	}
+	iterations, err := strconv.Atoi(parts[0])
+	if err != nil || iterations <= 0 || iterations > MaxPBKDF2Iterations {
+		return 0, nil, nil, false
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[1]); err != nil {
+		return 0, nil, nil, false
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[2]); err != nil || len(key) == 0 {
+		return 0, nil, nil, false
+	}
+	return iterations, salt, key, true
+}
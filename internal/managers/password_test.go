@@ -0,0 +1,153 @@
+package managers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPBKDF2HasherRoundTrip(t *testing.T) {
+	hasher := &PBKDF2Hasher{Iterations: 1000}
+	for _, password := range []string{"correct horse battery staple", "", "pässwörd ✓", strings.Repeat("x", 1024)} {
+		hash, err := hasher.Hash(password)
+		if err != nil {
+			t.Fatalf("Hash: %v", err)
+		}
+		if !strings.HasPrefix(hash, "$pbkdf2-sha256$1000$") {
+			t.Errorf("hash %q is not in PHC form", hash)
+		}
+		if !hasher.Verify(password, hash) {
+			t.Errorf("Verify(%q) rejected its own hash", password)
+		}
+		if hasher.Verify(password+"!", hash) {
+			t.Errorf("Verify accepted a wrong password for %q", password)
+		}
+		if hasher.NeedsRehash(hash) {
+			t.Errorf("NeedsRehash(%q) for a current hash", hash)
+		}
+		if other, _ := hasher.Hash(password); other == hash {
+			t.Error("two hashes of the same password share a salt")
+		}
+	}
+}
+
+func TestHashPasswordUsesDefaultWorkFactor(t *testing.T) {
+	hash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := pbkdf2Prefix + strconv.Itoa(DefaultPBKDF2Iterations) + "$"; !strings.HasPrefix(hash, want) {
+		t.Errorf("hash %q does not start with %q", hash, want)
+	}
+	if !NewPasswordHasher().Verify("secret", hash) {
+		t.Error("the default hasher rejected its own hash")
+	}
+}
+
+func TestPBKDF2HasherLegacyHash(t *testing.T) {
+	sum := sha256.Sum256([]byte("secret"))
+	legacy := hex.EncodeToString(sum[:])
+	hasher := &PBKDF2Hasher{Iterations: 1000}
+
+	for _, hash := range []string{legacy, strings.ToUpper(legacy)} {
+		if !IsLegacyPasswordHash(hash) {
+			t.Fatalf("IsLegacyPasswordHash(%q) is false", hash)
+		}
+		if !hasher.Verify("secret", hash) {
+			t.Errorf("legacy hash %q rejected the right password", hash)
+		}
+		if hasher.Verify("Secret", hash) {
+			t.Errorf("legacy hash %q accepted a wrong password", hash)
+		}
+		if !hasher.NeedsRehash(hash) {
+			t.Errorf("legacy hash %q does not need a rehash", hash)
+		}
+	}
+
+	// Upgrading replaces the digest with a salted hash of the same password
+	upgraded, err := hasher.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsLegacyPasswordHash(upgraded) || !hasher.Verify("secret", upgraded) || hasher.NeedsRehash(upgraded) {
+		t.Errorf("upgraded hash %q", upgraded)
+	}
+}
+
+func TestPBKDF2HasherNeedsRehash(t *testing.T) {
+	weak, err := (&PBKDF2Hasher{Iterations: 1000}).Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		iterations int
+		want       bool
+	}{
+		{"same work factor", 1000, false},
+		{"lower work factor", 500, false},
+		{"higher work factor", 2000, true},
+		{"default work factor", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (&PBKDF2Hasher{Iterations: tt.iterations}).NeedsRehash(weak); got != tt.want {
+				t.Errorf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPBKDF2HasherRejectsMalformedHashes(t *testing.T) {
+	hasher := &PBKDF2Hasher{Iterations: 1000}
+	valid, err := hasher.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(valid, "$") // "", "pbkdf2-sha256", iterations, salt, key
+	salt, key := parts[3], parts[4]
+	join := func(fields ...string) string { return "$" + strings.Join(fields, "$") }
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"empty", ""},
+		{"plain text", "secret"},
+		{"other algorithm", join("pbkdf2-sha512", "1000", salt, key)},
+		{"missing key", join("pbkdf2-sha256", "1000", salt)},
+		{"extra field", join("pbkdf2-sha256", "1000", salt, key, "x")},
+		{"empty key", join("pbkdf2-sha256", "1000", salt, "")},
+		{"zero iterations", join("pbkdf2-sha256", "0", salt, key)},
+		{"negative iterations", join("pbkdf2-sha256", "-1000", salt, key)},
+		{"iterations above the ceiling", join("pbkdf2-sha256", strconv.Itoa(MaxPBKDF2Iterations+1), salt, key)},
+		{"iterations overflow", join("pbkdf2-sha256", "99999999999999999999", salt, key)},
+		{"non-numeric iterations", join("pbkdf2-sha256", "lots", salt, key)},
+		{"padded salt", join("pbkdf2-sha256", "1000", salt+"==", key)},
+		{"invalid base64 key", join("pbkdf2-sha256", "1000", salt, key[:len(key)-1]+"!")},
+		{"short hex digest", strings.Repeat("a", 63)},
+		{"non-hex digest", strings.Repeat("g", 64)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if hasher.Verify("secret", tt.hash) {
+				t.Errorf("Verify accepted %q", tt.hash)
+			}
+			if !hasher.NeedsRehash(tt.hash) {
+				t.Errorf("NeedsRehash(%q) is false", tt.hash)
+			}
+		})
+	}
+}
+
+func TestPBKDF2HasherCapsWorkFactor(t *testing.T) {
+	hasher := &PBKDF2Hasher{Iterations: MaxPBKDF2Iterations * 2}
+	if got := hasher.iterations(); got != MaxPBKDF2Iterations {
+		t.Errorf("iterations() = %d, want the ceiling %d", got, MaxPBKDF2Iterations)
+	}
+	if _, _, _, ok := parsePBKDF2Hash(pbkdf2Prefix + strconv.Itoa(MaxPBKDF2Iterations) + "$c2FsdA$a2V5"); !ok {
+		t.Error("a hash at the ceiling was rejected")
+	}
+}
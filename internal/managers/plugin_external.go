@@ -0,0 +1,191 @@
+package managers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"onepagems/internal/httpclient"
+	"onepagems/internal/types"
+)
+
+// externalHookTimeout bounds one run of an external hook
+const externalHookTimeout = 30 * time.Second
+
+// ExternalHookTarget is a command run, or a URL posted to, on an event
+type ExternalHookTarget struct {
+	Command []string `json:"command,omitempty"`
+	URL     string   `json:"-"` // may carry a token, so never returned
+}
+
+// ExternalHookPayload is what an external hook receives: on stdin for a
+// command, as the request body for a URL
+type ExternalHookPayload struct {
+	Event string      `json:"event"`
+	At    time.Time   `json:"at"`
+	Data  interface{} `json:"data"`
+}
+
+// ParseExternalHookTargets reads a comma-separated list of hooks, each a
+// command prefixed with "exec:" (split on spaces into program and
+// arguments) or an http(s) URL
+func ParseExternalHookTargets(spec string) ([]ExternalHookTarget, error) {
+	var targets []ExternalHookTarget
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+			continue
+		case strings.HasPrefix(entry, "exec:"):
+			command := strings.Fields(strings.TrimPrefix(entry, "exec:"))
+			if len(command) == 0 {
+				return nil, fmt.Errorf("hook %q has no command", entry)
+			}
+			targets = append(targets, ExternalHookTarget{Command: command})
+		case strings.HasPrefix(entry, "https://") || strings.HasPrefix(entry, "http://"):
+			targets = append(targets, ExternalHookTarget{URL: entry})
+		default:
+			return nil, fmt.Errorf("hook %q must be exec:<command> or an http(s) URL", entry)
+		}
+	}
+	return targets, nil
+}
+
+// ExternalHooks is the plugin binary users configure: it runs commands
+// and posts to URLs set per event in PLUGIN_HOOK_<EVENT>. A command that
+// exits non-zero, or a URL answering other than 2xx, fails the hook; for
+// content_save that rejects the save with the command's stderr or the
+// response body as the reason.
+type ExternalHooks struct {
+	targets map[string][]ExternalHookTarget
+	client  *http.Client
+}
+
+// NewExternalHooks creates the external hooks from hook lists by event, in
+// the format of ParseExternalHookTargets, posting through clients
+func NewExternalHooks(specs map[string]string, clients *httpclient.Clients) (*ExternalHooks, error) {
+	eh := &ExternalHooks{
+		targets: make(map[string][]ExternalHookTarget),
+		client:  clients.New(externalHookTimeout),
+	}
+	for event, spec := range specs {
+		if !isPluginEvent(event) {
+			return nil, fmt.Errorf("unknown plugin event %q", event)
+		}
+		targets, err := ParseExternalHookTargets(spec)
+		if err != nil {
+			return nil, fmt.Errorf("hooks for %s: %w", event, err)
+		}
+		if len(targets) > 0 {
+			eh.targets[event] = targets
+		}
+	}
+	return eh, nil
+}
+
+// isPluginEvent reports whether name is a known plugin event
+func isPluginEvent(name string) bool {
+	for _, event := range PluginEvents {
+		if event == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Name identifies the external hooks among the plugins
+func (eh *ExternalHooks) Name() string {
+	return "external"
+}
+
+// Enabled reports whether any hook is configured
+func (eh *ExternalHooks) Enabled() bool {
+	return len(eh.targets) > 0
+}
+
+// OnContentSave runs the content_save hooks, stopping at the first failure
+func (eh *ExternalHooks) OnContentSave(ctx context.Context, content *types.ContentData) error {
+	return eh.run(ctx, PluginEventContentSave, content)
+}
+
+// OnGenerate runs the generate hooks
+func (eh *ExternalHooks) OnGenerate(ctx context.Context, result *types.GenerationResult) error {
+	return eh.run(ctx, PluginEventGenerate, result)
+}
+
+// OnUpload runs the upload hooks
+func (eh *ExternalHooks) OnUpload(ctx context.Context, image *types.ImageInfo) error {
+	return eh.run(ctx, PluginEventUpload, image)
+}
+
+// run delivers the event to each of its hooks in order
+func (eh *ExternalHooks) run(ctx context.Context, event string, data interface{}) error {
+	targets := eh.targets[event]
+	if len(targets) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(ExternalHookPayload{Event: event, At: time.Now(), Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to encode hook payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, externalHookTimeout)
+	defer cancel()
+	for _, target := range targets {
+		if target.URL != "" {
+			err = eh.post(ctx, target.URL, payload)
+		} else {
+			err = runHookCommand(ctx, target.Command, event, payload)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runHookCommand runs command with the payload on stdin and the event in
+// ONEPAGEMS_EVENT
+func runHookCommand(ctx context.Context, command []string, event string, payload []byte) error {
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(), "ONEPAGEMS_EVENT="+event)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if reason := strings.TrimSpace(stderr.String()); reason != "" {
+			return fmt.Errorf("%s", shortenMessage(reason, 500))
+		}
+		return fmt.Errorf("%s: %w", command[0], err)
+	}
+	return nil
+}
+
+// post sends the payload to url as JSON
+func (eh *ExternalHooks) post(ctx context.Context, url string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create hook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := eh.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("hook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if reason := strings.TrimSpace(string(body)); reason != "" {
+		return fmt.Errorf("%s", shortenMessage(reason, 500))
+	}
+	return fmt.Errorf("hook answered %s", resp.Status)
+}
@@ -0,0 +1,163 @@
+package managers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"onepagems/internal/types"
+)
+
+// Plugin events, named as in PLUGIN_HOOK_<EVENT>
+const (
+	PluginEventContentSave = "content_save"
+	PluginEventGenerate    = "generate"
+	PluginEventUpload      = "upload"
+)
+
+// PluginEvents lists every plugin event, in the order they are documented
+var PluginEvents = []string{PluginEventContentSave, PluginEventGenerate, PluginEventUpload}
+
+// Plugin extends the CMS without forking it. A plugin implements any of
+// ContentSaveHook, GenerateHook and UploadHook; the server also mounts the
+// admin routes of plugins implementing server.RouteProvider.
+type Plugin interface {
+	// Name identifies the plugin in errors, logs and its route prefix
+	Name() string
+}
+
+// ContentSaveHook is called before content from the editor or an import is
+// saved. Returning an error rejects the save and shows the error to the
+// editor.
+type ContentSaveHook interface {
+	OnContentSave(ctx context.Context, content *types.ContentData) error
+}
+
+// GenerateHook is called after the site has been generated. Errors are
+// logged; the site is already live.
+type GenerateHook interface {
+	OnGenerate(ctx context.Context, result *types.GenerationResult) error
+}
+
+// UploadHook is called after an image has been uploaded. Errors are logged.
+type UploadHook interface {
+	OnUpload(ctx context.Context, image *types.ImageInfo) error
+}
+
+// ErrPluginRejected is wrapped by every PluginRejection
+var ErrPluginRejected = errors.New("rejected by plugin")
+
+// PluginRejection is a ContentSaveHook refusing a save
+type PluginRejection struct {
+	Plugin string
+	Err    error
+}
+
+func (e *PluginRejection) Error() string {
+	return fmt.Sprintf("plugin '%s': %v", e.Plugin, e.Err)
+}
+
+// Unwrap lets callers test for ErrPluginRejected
+func (e *PluginRejection) Unwrap() []error {
+	return []error{ErrPluginRejected, e.Err}
+}
+
+// PluginManager holds the registered plugins and runs their hooks in the
+// order they were registered
+type PluginManager struct {
+	plugins []Plugin
+}
+
+// NewPluginManager creates a plugin manager with no plugins
+func NewPluginManager() *PluginManager {
+	return &PluginManager{}
+}
+
+// Register adds a plugin. Register plugins before the server starts.
+func (pm *PluginManager) Register(plugin Plugin) {
+	pm.plugins = append(pm.plugins, plugin)
+}
+
+// Plugins returns the registered plugins
+func (pm *PluginManager) Plugins() []Plugin {
+	return pm.plugins
+}
+
+// PluginHooks returns the events plugin handles
+func PluginHooks(plugin Plugin) []string {
+	var events []string
+	if _, ok := plugin.(ContentSaveHook); ok {
+		events = append(events, PluginEventContentSave)
+	}
+	if _, ok := plugin.(GenerateHook); ok {
+		events = append(events, PluginEventGenerate)
+	}
+	if _, ok := plugin.(UploadHook); ok {
+		events = append(events, PluginEventUpload)
+	}
+	return events
+}
+
+// ContentSave runs the content save hooks, stopping at the first that
+// rejects the content with a *PluginRejection
+func (pm *PluginManager) ContentSave(ctx context.Context, content *types.ContentData) error {
+	for _, plugin := range pm.plugins {
+		hook, ok := plugin.(ContentSaveHook)
+		if !ok {
+			continue
+		}
+		if err := callPluginHook(func() error { return hook.OnContentSave(ctx, content) }); err != nil {
+			return &PluginRejection{Plugin: plugin.Name(), Err: err}
+		}
+	}
+	return nil
+}
+
+// Generated runs every generate hook and returns their errors joined
+func (pm *PluginManager) Generated(ctx context.Context, result *types.GenerationResult) error {
+	var errs []error
+	for _, plugin := range pm.plugins {
+		if hook, ok := plugin.(GenerateHook); ok {
+			if err := callPluginHook(func() error { return hook.OnGenerate(ctx, result) }); err != nil {
+				errs = append(errs, fmt.Errorf("plugin '%s': %w", plugin.Name(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Uploaded runs every upload hook and returns their errors joined
+func (pm *PluginManager) Uploaded(ctx context.Context, image *types.ImageInfo) error {
+	var errs []error
+	for _, plugin := range pm.plugins {
+		if hook, ok := plugin.(UploadHook); ok {
+			if err := callPluginHook(func() error { return hook.OnUpload(ctx, image) }); err != nil {
+				errs = append(errs, fmt.Errorf("plugin '%s': %w", plugin.Name(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HasHook reports whether any plugin handles event
+func (pm *PluginManager) HasHook(event string) bool {
+	for _, plugin := range pm.plugins {
+		for _, handled := range PluginHooks(plugin) {
+			if handled == event {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// callPluginHook runs a hook, turning a panic into an error so a faulty
+// plugin cannot take the server down
+func callPluginHook(hook func() error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return hook()
+}
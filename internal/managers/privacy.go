@@ -0,0 +1,123 @@
+package managers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"onepagems/internal/types"
+)
+
+// PublicContent returns content as a map without the fields marked
+// "x-private": true in the schema. Private fields stay editable in the admin
+// but never leave it.
+func (sm *SchemaManager) PublicContent(content *types.ContentData) (map[string]interface{}, error) {
+	schema, err := sm.LoadSchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema: %w", err)
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode content: %w", err)
+	}
+	values := make(map[string]interface{})
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to decode content: %w", err)
+	}
+
+	// Private fields may be reached through a $ref, so walk the resolved
+	// schema; one whose refs do not resolve cannot be trusted to hide them
+	resolved, err := ResolveSchemaRefs(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve schema: %w", err)
+	}
+	root := map[string]interface{}{"properties": resolved.Properties}
+	return stripPrivate(values, root).(map[string]interface{}), nil
+}
+
+// privacyCombinators are the keywords whose subschemas also describe a
+// value. Every branch is taken to apply, so a field private in any of them
+// is left out whichever branch the value matches.
+var privacyCombinators = []string{"allOf", "anyOf", "oneOf"}
+
+// schemaBranches returns schema and the subschemas of its combinators, at
+// any depth
+func schemaBranches(schema map[string]interface{}) []map[string]interface{} {
+	if schema == nil {
+		return nil
+	}
+	branches := []map[string]interface{}{schema}
+	for _, keyword := range privacyCombinators {
+		list, _ := schema[keyword].([]interface{})
+		for _, item := range list {
+			if branch, ok := item.(map[string]interface{}); ok {
+				branches = append(branches, schemaBranches(branch)...)
+			}
+		}
+	}
+	return branches
+}
+
+// stripPrivate returns a copy of value without the properties its schema
+// marks as private, following nested objects, array items and combinator
+// branches. A schema with a type union such as ["object", "array"] is
+// followed as whichever the value is.
+func stripPrivate(value interface{}, schema map[string]interface{}) interface{} {
+	branches := schemaBranches(schema)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			props := subschemas(branches, func(branch map[string]interface{}) interface{} {
+				properties, _ := branch["properties"].(map[string]interface{})
+				return properties[key]
+			})
+			if isPrivate(props) {
+				continue
+			}
+			copied[key] = stripPrivate(nested, props)
+		}
+		return copied
+	case []interface{}:
+		items := subschemas(branches, func(branch map[string]interface{}) interface{} {
+			return branch["items"]
+		})
+		copied := make([]interface{}, len(v))
+		for i, nested := range v {
+			copied[i] = stripPrivate(nested, items)
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+// subschemas collects the subschema pick returns from each branch into one
+// schema whose allOf holds them all, or nil when no branch has one
+func subschemas(branches []map[string]interface{}, pick func(map[string]interface{}) interface{}) map[string]interface{} {
+	var found []interface{}
+	for _, branch := range branches {
+		if sub, ok := pick(branch).(map[string]interface{}); ok {
+			found = append(found, sub)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil
+	case 1:
+		return found[0].(map[string]interface{})
+	default:
+		return map[string]interface{}{"allOf": found}
+	}
+}
+
+// isPrivate reports whether a property schema, or any of its combinator
+// branches, is marked "x-private": true
+func isPrivate(prop map[string]interface{}) bool {
+	for _, branch := range schemaBranches(prop) {
+		if private, _ := branch["x-private"].(bool); private {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,362 @@
+package managers
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// publishNotificationsFilename holds publish events waiting for the next
+// digest
+const publishNotificationsFilename = "publish_notifications.json"
+
+// When publish notifications are sent
+const (
+	NotifyImmediate = "immediate"
+	NotifyDaily     = "daily"
+)
+
+// PublishSummary describes what a publish changed
+type PublishSummary struct {
+	TitleChanged       bool     `json:"title_changed,omitempty"`
+	DescriptionChanged bool     `json:"description_changed,omitempty"`
+	SectionsAdded      []string `json:"sections_added,omitempty"`
+	SectionsRemoved    []string `json:"sections_removed,omitempty"`
+	SectionsChanged    []string `json:"sections_changed,omitempty"`
+	LinesAdded         int      `json:"lines_added"`
+	LinesRemoved       int      `json:"lines_removed"`
+}
+
+// PublishEvent is one site generation to tell stakeholders about
+type PublishEvent struct {
+	Site    string         `json:"site"` // site title when published
+	At      time.Time      `json:"at"`
+	By      string         `json:"by"`
+	Reason  string         `json:"reason,omitempty"`
+	Summary PublishSummary `json:"summary"`
+}
+
+// SummarizePublish compares the public content and page of the previous
+// publish with the new ones
+func SummarizePublish(oldContent, newContent map[string]interface{}, oldPage, newPage string) PublishSummary {
+	summary := PublishSummary{
+		TitleChanged:       !reflect.DeepEqual(oldContent["title"], newContent["title"]),
+		DescriptionChanged: !reflect.DeepEqual(oldContent["description"], newContent["description"]),
+	}
+
+	oldSections, _ := oldContent["sections"].(map[string]interface{})
+	newSections, _ := newContent["sections"].(map[string]interface{})
+	for key, section := range newSections {
+		previous, ok := oldSections[key]
+		switch {
+		case !ok:
+			summary.SectionsAdded = append(summary.SectionsAdded, key)
+		case !reflect.DeepEqual(previous, section):
+			summary.SectionsChanged = append(summary.SectionsChanged, key)
+		}
+	}
+	for key := range oldSections {
+		if _, ok := newSections[key]; !ok {
+			summary.SectionsRemoved = append(summary.SectionsRemoved, key)
+		}
+	}
+	sort.Strings(summary.SectionsAdded)
+	sort.Strings(summary.SectionsChanged)
+	sort.Strings(summary.SectionsRemoved)
+
+	diff := DiffText(oldPage, newPage, 0)
+	summary.LinesAdded = diff.Added
+	summary.LinesRemoved = diff.Removed
+	return summary
+}
+
+// Mailer sends a plain-text email
+type Mailer interface {
+	Send(to []string, subject, body string) error
+}
+
+// SMTPMailer sends email through an SMTP server, with STARTTLS when the
+// server offers it
+type SMTPMailer struct {
+	addr     string
+	host     string
+	username string
+	password func() string
+	from     string
+}
+
+// NewSMTPMailer creates a mailer for host:port. password is looked up on
+// each send, so a password saved later is picked up; without a username no
+// authentication is attempted.
+func NewSMTPMailer(host string, port int, username string, password func() string, from string) *SMTPMailer {
+	return &SMTPMailer{
+		addr:     net.JoinHostPort(host, fmt.Sprint(port)),
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+// Send delivers one message to all recipients
+func (m *SMTPMailer) Send(to []string, subject, body string) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password(), m.host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	if err := smtp.SendMail(m.addr, auth, m.from, to, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// publishNotifications is the stored queue of unsent events
+type publishNotifications struct {
+	Pending  []PublishEvent `json:"pending"`
+	LastSent time.Time      `json:"last_sent,omitempty"`
+}
+
+// PublishNotifier emails stakeholders when the site is published, either
+// right away or as one digest a day
+type PublishNotifier struct {
+	storage    *FileStorage
+	mailer     Mailer
+	recipients []string
+	mode       string
+	digestAt   time.Duration // time of day of the daily digest
+	location   *time.Location
+	siteURL    string
+	outbox     *Outbox
+
+	mu sync.Mutex
+}
+
+// MailMessage is an email waiting in the outbox
+type MailMessage struct {
+	To      []string `json:"to"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+}
+
+// NewPublishNotifier creates a notifier sending to recipients. mode is
+// NotifyImmediate or NotifyDaily; a daily digest goes out at digestAt
+// ("15:04") in location.
+func NewPublishNotifier(storage *FileStorage, mailer Mailer, recipients []string, mode, digestAt string, location *time.Location) (*PublishNotifier, error) {
+	if mode != NotifyImmediate && mode != NotifyDaily {
+		return nil, fmt.Errorf("invalid digest mode %q: must be %q or %q", mode, NotifyImmediate, NotifyDaily)
+	}
+	at, err := time.Parse("15:04", digestAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest time %q: use HH:MM", digestAt)
+	}
+	return &PublishNotifier{
+		storage:    storage,
+		mailer:     mailer,
+		recipients: recipients,
+		mode:       mode,
+		digestAt:   time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute,
+		location:   location,
+	}, nil
+}
+
+// SetSiteURL sets the live page linked from each message
+func (n *PublishNotifier) SetSiteURL(url string) {
+	n.siteURL = url
+}
+
+// SetOutbox hands digests to the outbox, which retries them, instead of
+// sending them directly
+func (n *PublishNotifier) SetOutbox(outbox *Outbox) {
+	n.outbox = outbox
+}
+
+// Mode returns NotifyImmediate or NotifyDaily
+func (n *PublishNotifier) Mode() string {
+	return n.mode
+}
+
+// Recipients returns who is notified
+func (n *PublishNotifier) Recipients() []string {
+	return n.recipients
+}
+
+// Pending returns the events not yet sent
+func (n *PublishNotifier) Pending() ([]PublishEvent, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	queue, err := n.load()
+	if err != nil {
+		return nil, err
+	}
+	return queue.Pending, nil
+}
+
+// Published queues an event. In immediate mode everything queued is sent
+// right away; events whose email fails stay queued for the next attempt.
+func (n *PublishNotifier) Published(event PublishEvent) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	queue, err := n.load()
+	if err != nil {
+		return err
+	}
+	queue.Pending = append(queue.Pending, event)
+	if err := n.save(queue); err != nil {
+		return err
+	}
+	if n.mode == NotifyImmediate {
+		return n.flush(queue, event.At)
+	}
+	return nil
+}
+
+// SendDue sends what is waiting: in daily mode once the digest time of
+// day has passed since the first event queued, in immediate mode any events
+// whose email failed before
+func (n *PublishNotifier) SendDue(now time.Time) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	queue, err := n.load()
+	if err != nil || len(queue.Pending) == 0 {
+		return err
+	}
+	if n.mode == NotifyDaily {
+		local := now.In(n.location)
+		due := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, n.location).Add(n.digestAt)
+		if local.Before(due) {
+			due = due.AddDate(0, 0, -1)
+		}
+		// Events since the last digest time wait for the next one
+		if !queue.Pending[0].At.Before(due) {
+			return nil
+		}
+	}
+	return n.flush(queue, now)
+}
+
+// Flush sends everything queued now, whatever the mode
+func (n *PublishNotifier) Flush(now time.Time) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	queue, err := n.load()
+	if err != nil {
+		return err
+	}
+	return n.flush(queue, now)
+}
+
+// flush sends the queued events as one message, or queues it in the
+// outbox, and empties the queue
+func (n *PublishNotifier) flush(queue *publishNotifications, now time.Time) error {
+	if len(queue.Pending) == 0 {
+		return nil
+	}
+	subject, body := n.FormatDigest(queue.Pending)
+	if n.outbox != nil {
+		message := MailMessage{To: n.recipients, Subject: subject, Body: body}
+		if _, err := n.outbox.Enqueue(OutboxEmail, subject, message); err != nil {
+			return err
+		}
+	} else if err := n.mailer.Send(n.recipients, subject, body); err != nil {
+		return err
+	}
+	queue.Pending = nil
+	queue.LastSent = now
+	return n.save(queue)
+}
+
+// FormatDigest builds the subject and plain-text body for events
+func (n *PublishNotifier) FormatDigest(events []PublishEvent) (string, string) {
+	site := events[len(events)-1].Site
+	if site == "" {
+		site = "Your site"
+	}
+	subject := fmt.Sprintf("%s was published", site)
+	if len(events) > 1 {
+		subject = fmt.Sprintf("%s was published %d times", site, len(events))
+	}
+
+	var b strings.Builder
+	for i, event := range events {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		by := event.By
+		if by == "" {
+			by = "someone"
+		}
+		fmt.Fprintf(&b, "Published %s by %s", event.At.In(n.location).Format("2 Jan 2006 15:04 MST"), by)
+		if event.Reason != "" {
+			fmt.Fprintf(&b, " (%s)", event.Reason)
+		}
+		b.WriteString("\n")
+		b.WriteString(FormatPublishSummary(event.Summary))
+	}
+	if n.siteURL != "" {
+		fmt.Fprintf(&b, "\nSee the live page: %s\n", n.siteURL)
+	}
+	return subject, b.String()
+}
+
+// FormatPublishSummary lists the changes of one publish, a line each
+func FormatPublishSummary(summary PublishSummary) string {
+	var b strings.Builder
+	if summary.TitleChanged {
+		b.WriteString("- Site title changed\n")
+	}
+	if summary.DescriptionChanged {
+		b.WriteString("- Site description changed\n")
+	}
+	for _, group := range []struct {
+		label string
+		keys  []string
+	}{
+		{"Sections added", summary.SectionsAdded},
+		{"Sections changed", summary.SectionsChanged},
+		{"Sections removed", summary.SectionsRemoved},
+	} {
+		if len(group.keys) > 0 {
+			fmt.Fprintf(&b, "- %s: %s\n", group.label, strings.Join(group.keys, ", "))
+		}
+	}
+	fmt.Fprintf(&b, "- Page: %d line(s) added, %d removed\n", summary.LinesAdded, summary.LinesRemoved)
+	return b.String()
+}
+
+// load reads the queue; callers hold mu
+func (n *PublishNotifier) load() (*publishNotifications, error) {
+	queue := &publishNotifications{}
+	if !n.storage.FileExists(publishNotificationsFilename) {
+		return queue, nil
+	}
+	if err := n.storage.ReadJSONFile(publishNotificationsFilename, queue); err != nil {
+		return nil, fmt.Errorf("failed to load publish notifications: %w", err)
+	}
+	return queue, nil
+}
+
+// save writes the queue; callers hold mu
+func (n *PublishNotifier) save(queue *publishNotifications) error {
+	if err := n.storage.WriteJSONFile(publishNotificationsFilename, queue); err != nil {
+		return fmt.Errorf("failed to save publish notifications: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package managers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"onepagems/internal/types"
+)
+
+// minPhoneDigits is the fewest digits a usable phone number has
+const minPhoneDigits = 7
+
+// qualityEmail matches the addresses the schema validator accepts
+var qualityEmail = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
+// QualityItem is one thing to fix, with a link to where it is fixed
+type QualityItem struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+	Link    string `json:"link"`
+}
+
+// QualityCheck counts how many of the points a check looks at pass
+type QualityCheck struct {
+	Name   string        `json:"name"`
+	Passed int           `json:"passed"`
+	Total  int           `json:"total"`
+	Items  []QualityItem `json:"items,omitempty"`
+}
+
+// QualityReport scores how complete the content is from 0 to 100, the
+// average of the checks that apply, and lists what to do to improve it
+type QualityReport struct {
+	Score  int            `json:"score"`
+	Checks []QualityCheck `json:"checks"`
+	Todo   []QualityItem  `json:"todo"`
+}
+
+// ScoreContent checks that required fields are filled, images have alt
+// text, the title and description search engines show are set, and the
+// contact section holds a valid email or phone number
+func ScoreContent(schema *types.SchemaData, content *types.ContentData, images []types.ImageInfo) *QualityReport {
+	contentMap := map[string]interface{}{
+		"title":       content.Title,
+		"description": content.Description,
+		"sections":    content.Sections,
+	}
+
+	checks := []QualityCheck{
+		checkRequiredFilled(schema, contentMap),
+		checkImageAltText(images),
+		checkSEO(content),
+		checkContactInfo(content.Sections),
+	}
+
+	report := &QualityReport{Checks: checks, Todo: make([]QualityItem, 0)}
+	sum, counted := 0.0, 0
+	for _, check := range checks {
+		report.Todo = append(report.Todo, check.Items...)
+		if check.Total == 0 {
+			continue
+		}
+		sum += float64(check.Passed) / float64(check.Total)
+		counted++
+	}
+	report.Score = 100
+	if counted > 0 {
+		report.Score = int(sum / float64(counted) * 100)
+	}
+	return report
+}
+
+// checkRequiredFilled counts required fields that have a non-empty value
+func checkRequiredFilled(schema *types.SchemaData, content map[string]interface{}) QualityCheck {
+	check := QualityCheck{Name: "Required fields filled"}
+	if schema == nil {
+		return check
+	}
+	walkRequired(schema.Properties, nil, content, "", &check)
+	return check
+}
+
+// walkRequired checks the required properties of one schema object and
+// descends into nested objects that have content
+func walkRequired(properties map[string]interface{}, required []interface{}, values map[string]interface{}, path string, check *QualityCheck) {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop, ok := properties[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+
+		isRequired, _ := prop["required"].(bool)
+		for _, requiredName := range required {
+			if requiredName == name {
+				isRequired = true
+			}
+		}
+		value, exists := values[name]
+		if isRequired {
+			check.Total++
+			if exists && !emptyContentValue(value) {
+				check.Passed++
+			} else {
+				check.Items = append(check.Items, QualityItem{
+					Field:   fieldPath,
+					Message: fmt.Sprintf("Fill in %s", qualityLabel(prop, fieldPath)),
+					Link:    "/admin/content",
+				})
+			}
+		}
+
+		nested, hasNested := prop["properties"].(map[string]interface{})
+		child, isObject := value.(map[string]interface{})
+		if hasNested && isObject {
+			childRequired, _ := prop["required"].([]interface{})
+			walkRequired(nested, childRequired, child, fieldPath, check)
+		}
+	}
+}
+
+// emptyContentValue reports whether a value leaves a field effectively blank
+func emptyContentValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// qualityLabel names a field by its schema title, or its path
+func qualityLabel(prop map[string]interface{}, path string) string {
+	if title, ok := prop["title"].(string); ok && title != "" {
+		return fmt.Sprintf("%q (%s)", title, path)
+	}
+	return path
+}
+
+// checkImageAltText counts uploaded images that have alt text
+func checkImageAltText(images []types.ImageInfo) QualityCheck {
+	check := QualityCheck{Name: "Images with alt text", Total: len(images)}
+	for _, image := range images {
+		if strings.TrimSpace(image.AltText) != "" {
+			check.Passed++
+			continue
+		}
+		check.Items = append(check.Items, QualityItem{
+			Field:   image.Filename,
+			Message: fmt.Sprintf("Add alt text to %s", image.Filename),
+			Link:    "/admin/images",
+		})
+	}
+	return check
+}
+
+// checkSEO checks the site title and description, which become the page
+// title and meta description search results show
+func checkSEO(content *types.ContentData) QualityCheck {
+	check := QualityCheck{Name: "Search engine basics", Total: 2}
+	if strings.TrimSpace(content.Title) != "" {
+		check.Passed++
+	} else {
+		check.Items = append(check.Items, QualityItem{Field: "title", Message: "Add a site title", Link: "/admin/content"})
+	}
+	if strings.TrimSpace(content.Description) != "" {
+		check.Passed++
+	} else {
+		check.Items = append(check.Items, QualityItem{Field: "description", Message: "Add a site description for search results", Link: "/admin/content"})
+	}
+	return check
+}
+
+// checkContactInfo checks the email and phone of the contact section, when
+// the site has one. At least one of them must be given.
+func checkContactInfo(sections map[string]interface{}) QualityCheck {
+	check := QualityCheck{Name: "Contact details valid"}
+	contact, ok := sections[vcardContactSection].(map[string]interface{})
+	if !ok {
+		return check
+	}
+
+	email, _ := contact["email"].(string)
+	phone, _ := contact["phone"].(string)
+	email, phone = strings.TrimSpace(email), strings.TrimSpace(phone)
+
+	check.Total = 1
+	if email == "" && phone == "" {
+		check.Items = append(check.Items, QualityItem{Field: "sections.contact", Message: "Add a contact email or phone number", Link: "/admin/content"})
+		return check
+	}
+	valid := true
+	if email != "" && !qualityEmail.MatchString(email) {
+		valid = false
+		check.Items = append(check.Items, QualityItem{Field: "sections.contact.email", Message: fmt.Sprintf("Fix the contact email %q", email), Link: "/admin/content"})
+	}
+	if phone != "" && countDigits(phone) < minPhoneDigits {
+		valid = false
+		check.Items = append(check.Items, QualityItem{Field: "sections.contact.phone", Message: fmt.Sprintf("Fix the contact phone number %q", phone), Link: "/admin/content"})
+	}
+	if valid {
+		check.Passed = 1
+	}
+	return check
+}
+
+// countDigits counts the decimal digits in s
+func countDigits(s string) int {
+	count := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,217 @@
+package managers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recoveryCodesFilename stores the hashes of the admin recovery codes
+const recoveryCodesFilename = "recovery_codes.json"
+
+// RecoveryCodeCount is how many recovery codes are generated at a time
+const RecoveryCodeCount = 10
+
+// Emergency login modes
+const (
+	EmergencyLoginOff   = "off"
+	EmergencyLoginToken = "token" // with the token in the emergency token file
+	EmergencyLoginLocal = "local" // with the token, and only from a loopback address
+)
+
+// Recovery failures
+var (
+	ErrRecoveryCodeInvalid    = errors.New("invalid or already used recovery code")
+	ErrEmergencyTokenInvalid  = errors.New("invalid emergency token")
+	ErrEmergencyTokenDisabled = errors.New("no emergency token file")
+)
+
+// recoveryCode is one stored code. Only its hash is kept.
+type recoveryCode struct {
+	Hash   string     `json:"hash"`
+	UsedAt *time.Time `json:"used_at,omitempty"`
+}
+
+// recoveryCodes is the stored set of codes
+type recoveryCodes struct {
+	CreatedAt time.Time      `json:"created_at"`
+	Codes     []recoveryCode `json:"codes"`
+}
+
+// RecoveryStatus describes the recovery codes without revealing them
+type RecoveryStatus struct {
+	Total     int       `json:"total"`
+	Remaining int       `json:"remaining"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+}
+
+// RecoveryManager keeps one-time recovery codes that sign the admin in when
+// the password is lost
+type RecoveryManager struct {
+	storage *FileStorage
+	mu      sync.Mutex
+}
+
+// NewRecoveryManager creates a new recovery manager
+func NewRecoveryManager(storage *FileStorage) *RecoveryManager {
+	return &RecoveryManager{storage: storage}
+}
+
+// Generate replaces all codes with new ones and returns them. They are not
+// stored in a readable form, so this is the only time they can be shown.
+// There are no codes until a signed-in admin asks for them.
+func (rm *RecoveryManager) Generate() ([]string, error) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	return rm.generate()
+}
+
+// generate creates and stores new codes; callers hold mu
+func (rm *RecoveryManager) generate() ([]string, error) {
+	stored := recoveryCodes{CreatedAt: time.Now()}
+	codes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		stored.Codes = append(stored.Codes, recoveryCode{Hash: hashRecoveryCode(code)})
+	}
+	if err := rm.storage.WriteJSONFile(recoveryCodesFilename, stored); err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// Use signs off a code, returning how many codes are left
+func (rm *RecoveryManager) Use(code string) (int, error) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	stored, err := rm.load()
+	if err != nil {
+		return 0, err
+	}
+	hash := hashRecoveryCode(code)
+	matched := -1
+	remaining := 0
+	for i, c := range stored.Codes {
+		if c.UsedAt != nil {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(c.Hash), []byte(hash)) == 1 {
+			matched = i
+			continue
+		}
+		remaining++
+	}
+	if matched < 0 {
+		return 0, ErrRecoveryCodeInvalid
+	}
+	now := time.Now()
+	stored.Codes[matched].UsedAt = &now
+	if err := rm.storage.WriteJSONFile(recoveryCodesFilename, stored); err != nil {
+		return 0, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+	return remaining, nil
+}
+
+// Status returns how many codes exist and how many are unused
+func (rm *RecoveryManager) Status() (RecoveryStatus, error) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	stored, err := rm.load()
+	if err != nil {
+		return RecoveryStatus{}, err
+	}
+	status := RecoveryStatus{Total: len(stored.Codes), CreatedAt: stored.CreatedAt}
+	for _, c := range stored.Codes {
+		if c.UsedAt == nil {
+			status.Remaining++
+		}
+	}
+	return status, nil
+}
+
+// load reads the stored codes; callers hold mu
+func (rm *RecoveryManager) load() (*recoveryCodes, error) {
+	stored := &recoveryCodes{}
+	if !rm.storage.FileExists(recoveryCodesFilename) {
+		return stored, nil
+	}
+	if err := rm.storage.ReadJSONFile(recoveryCodesFilename, stored); err != nil {
+		return nil, fmt.Errorf("failed to load recovery codes: %w", err)
+	}
+	return stored, nil
+}
+
+// recoveryAlphabet leaves out look-alike characters
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// newRecoveryCode returns a random code such as "k3f9q-7hx2m". Bytes from
+// 248 up are discarded, so every character is equally likely.
+func newRecoveryCode() (string, error) {
+	const limit = 256 - 256%len(recoveryAlphabet)
+	code := make([]byte, 0, 11)
+	raw := make([]byte, 16)
+	for len(code) < 11 {
+		if _, err := rand.Read(raw); err != nil {
+			return "", fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		for _, b := range raw {
+			if int(b) >= limit || len(code) == 11 {
+				continue
+			}
+			if len(code) == 5 {
+				code = append(code, '-')
+			}
+			code = append(code, recoveryAlphabet[int(b)%len(recoveryAlphabet)])
+		}
+	}
+	return string(code), nil
+}
+
+// hashRecoveryCode hashes a code, ignoring case, spaces and dashes
+func hashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+	hash := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(hash[:])
+}
+
+// ConsumeEmergencyToken checks token against the token in the file at path
+// and deletes the file when it matches, so each token works once. Creating
+// the file needs access to the server, which is what makes it safe.
+func ConsumeEmergencyToken(path, token string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrEmergencyTokenDisabled
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read emergency token file: %w", err)
+	}
+	expected := strings.TrimSpace(string(data))
+	if expected == "" {
+		return ErrEmergencyTokenDisabled
+	}
+	if token == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(strings.TrimSpace(token))) != 1 {
+		return ErrEmergencyTokenInvalid
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove used emergency token file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package managers
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNewRecoveryCode(t *testing.T) {
+	pattern := regexp.MustCompile(`^[` + recoveryAlphabet + `]{5}-[` + recoveryAlphabet + `]{5}$`)
+	counts := make(map[rune]int)
+	const codes = 20000
+	for i := 0; i < codes; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pattern.MatchString(code) {
+			t.Fatalf("code %q is malformed", code)
+		}
+		for _, c := range strings.ReplaceAll(code, "-", "") {
+			counts[c]++
+		}
+	}
+
+	// Each character should be drawn 20000*10/31 ≈ 6452 times; modulo bias
+	// would make the first 8 about 12% more common
+	expected := float64(codes*10) / float64(len(recoveryAlphabet))
+	for _, c := range recoveryAlphabet {
+		if ratio := float64(counts[c]) / expected; ratio < 0.94 || ratio > 1.06 {
+			t.Errorf("%q drawn %d times, expected about %.0f", c, counts[c], expected)
+		}
+	}
+}
+
+func TestRecoveryManager(t *testing.T) {
+	rm := NewRecoveryManager(NewFileStorage(t.TempDir()))
+	if status, err := rm.Status(); err != nil || status.Total != 0 {
+		t.Fatalf("Status before any codes = %+v, %v", status, err)
+	}
+	if _, err := rm.Use("abcde-fghjk"); !errors.Is(err, ErrRecoveryCodeInvalid) {
+		t.Errorf("Use without codes: %v", err)
+	}
+
+	codes, err := rm.Generate()
+	if err != nil || len(codes) != RecoveryCodeCount {
+		t.Fatalf("Generate = %v, %v", codes, err)
+	}
+	// Case, spaces and the dash are ignored
+	remaining, err := rm.Use(" " + strings.ToUpper(strings.ReplaceAll(codes[3], "-", "")) + " ")
+	if err != nil || remaining != RecoveryCodeCount-1 {
+		t.Fatalf("Use = %d, %v", remaining, err)
+	}
+	if _, err := rm.Use(codes[3]); !errors.Is(err, ErrRecoveryCodeInvalid) {
+		t.Errorf("reused code: %v", err)
+	}
+
+	// Regenerating revokes the earlier codes
+	if _, err := rm.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rm.Use(codes[0]); !errors.Is(err, ErrRecoveryCodeInvalid) {
+		t.Errorf("revoked code: %v", err)
+	}
+	if status, _ := rm.Status(); status.Total != RecoveryCodeCount || status.Remaining != RecoveryCodeCount {
+		t.Errorf("Status = %+v", status)
+	}
+}
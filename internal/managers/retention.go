@@ -0,0 +1,134 @@
+package managers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// retentionFilename keeps the report of the last retention run
+const retentionFilename = "retention.json"
+
+// RetentionInterval is how long the scheduler waits between retention runs
+const RetentionInterval = 24 * time.Hour
+
+// RetentionPolicy is how many days each kind of record is kept; 0 keeps
+// it forever
+type RetentionPolicy struct {
+	ActivityDays int `json:"activity_days"`
+	JobDays      int `json:"job_days"`
+	OutboxDays   int `json:"outbox_days"`
+}
+
+// Enabled reports whether anything is ever pruned
+func (p RetentionPolicy) Enabled() bool {
+	return p.ActivityDays > 0 || p.JobDays > 0 || p.OutboxDays > 0
+}
+
+// RetentionReport says what one retention run purged: activity log
+// entries, finished jobs and dead-lettered outbox messages. Errors lists
+// the kinds that could not be pruned; the others still were.
+type RetentionReport struct {
+	RanAt    time.Time `json:"ran_at"`
+	Activity int       `json:"activity"`
+	Jobs     int       `json:"jobs"`
+	Outbox   int       `json:"outbox"`
+	Errors   []string  `json:"errors,omitempty"`
+}
+
+// Total is the number of records purged
+func (r *RetentionReport) Total() int {
+	return r.Activity + r.Jobs + r.Outbox
+}
+
+// RetentionManager prunes old records according to a policy and keeps the
+// report of its last run
+type RetentionManager struct {
+	storage  *FileStorage
+	policy   RetentionPolicy
+	activity *ActivityLogger
+	jobs     *JobManager
+	outbox   *Outbox
+	mu       sync.Mutex
+}
+
+// NewRetentionManager creates a retention manager pruning the given stores
+func NewRetentionManager(storage *FileStorage, policy RetentionPolicy, activity *ActivityLogger, jobs *JobManager, outbox *Outbox) *RetentionManager {
+	return &RetentionManager{
+		storage:  storage,
+		policy:   policy,
+		activity: activity,
+		jobs:     jobs,
+		outbox:   outbox,
+	}
+}
+
+// Policy returns the configured policy
+func (rm *RetentionManager) Policy() RetentionPolicy {
+	return rm.policy
+}
+
+// LastReport returns the report of the last run, or nil before the first
+func (rm *RetentionManager) LastReport() (*RetentionReport, error) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if !rm.storage.FileExists(retentionFilename) {
+		return nil, nil
+	}
+	var report RetentionReport
+	if err := rm.storage.ReadJSONFile(retentionFilename, &report); err != nil {
+		return nil, fmt.Errorf("failed to load retention report: %w", err)
+	}
+	return &report, nil
+}
+
+// Due reports whether the policy prunes anything and the last run is at
+// least RetentionInterval before now
+func (rm *RetentionManager) Due(now time.Time) (bool, error) {
+	if !rm.policy.Enabled() {
+		return false, nil
+	}
+	last, err := rm.LastReport()
+	if err != nil {
+		return false, err
+	}
+	return last == nil || now.Sub(last.RanAt) >= RetentionInterval, nil
+}
+
+// Run prunes every kind of record older than its retention period at now
+// and saves the report
+func (rm *RetentionManager) Run(now time.Time) (*RetentionReport, error) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	report := &RetentionReport{RanAt: now}
+	if days := rm.policy.ActivityDays; days > 0 {
+		removed, err := rm.activity.Prune(retentionCutoff(now, days))
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		}
+		report.Activity = removed
+	}
+	if days := rm.policy.JobDays; days > 0 {
+		report.Jobs = rm.jobs.Prune(retentionCutoff(now, days))
+	}
+	if days := rm.policy.OutboxDays; days > 0 {
+		removed, err := rm.outbox.PruneDead(retentionCutoff(now, days))
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		}
+		report.Outbox = removed
+	}
+
+	if err := rm.storage.WriteJSONFile(retentionFilename, report); err != nil {
+		return report, fmt.Errorf("failed to save retention report: %w", err)
+	}
+	return report, nil
+}
+
+// retentionCutoff is the time before which records kept for days are
+// purged
+func retentionCutoff(now time.Time, days int) time.Time {
+	return now.AddDate(0, 0, -days)
+}
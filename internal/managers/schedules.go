@@ -0,0 +1,241 @@
+package managers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// schedulesFilename stores scheduled content and the schedules the live
+// site was last generated with
+const schedulesFilename = "schedules.json"
+
+// ContentSchedule replaces the value at a content path, such as a whole
+// section ("sections.banner") or one field ("sections.hero.title"), while
+// the time is within [StartsAt, EndsAt). A zero bound leaves that side
+// open. A section path the content does not have adds the section only
+// for the window.
+type ContentSchedule struct {
+	ID        string      `json:"id"`
+	Path      string      `json:"path"`
+	Value     interface{} `json:"value"`
+	StartsAt  time.Time   `json:"starts_at,omitempty"`
+	EndsAt    time.Time   `json:"ends_at,omitempty"`
+	Note      string      `json:"note,omitempty"`
+	CreatedBy string      `json:"created_by,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// ActiveAt reports whether the schedule applies at t
+func (cs ContentSchedule) ActiveAt(t time.Time) bool {
+	return (cs.StartsAt.IsZero() || !t.Before(cs.StartsAt)) && (cs.EndsAt.IsZero() || t.Before(cs.EndsAt))
+}
+
+// scheduleFile is the stored form of the schedules
+type scheduleFile struct {
+	Schedules []ContentSchedule `json:"schedules"`
+	Applied   []string          `json:"applied"`
+}
+
+// ScheduleManager stores scheduled content and applies what is active
+type ScheduleManager struct {
+	storage *FileStorage
+	mu      sync.Mutex
+}
+
+// NewScheduleManager creates a new schedule manager
+func NewScheduleManager(storage *FileStorage) *ScheduleManager {
+	return &ScheduleManager{
+		storage: storage,
+	}
+}
+
+// List returns every schedule, ordered by start
+func (sm *ScheduleManager) List() ([]ContentSchedule, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	file, err := sm.load()
+	if err != nil {
+		return nil, err
+	}
+	return file.Schedules, nil
+}
+
+// Add stores a new schedule, assigning its ID and creation time
+func (sm *ScheduleManager) Add(schedule ContentSchedule) (*ContentSchedule, error) {
+	if _, err := parseContentPath(schedule.Path); err != nil {
+		return nil, err
+	}
+	if !schedule.StartsAt.IsZero() && !schedule.EndsAt.IsZero() && !schedule.EndsAt.After(schedule.StartsAt) {
+		return nil, fmt.Errorf("ends_at must be after starts_at")
+	}
+
+	id := make([]byte, 8)
+	rand.Read(id)
+	schedule.ID = hex.EncodeToString(id)
+	schedule.CreatedAt = time.Now()
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	file, err := sm.load()
+	if err != nil {
+		return nil, err
+	}
+	file.Schedules = append(file.Schedules, schedule)
+	sortSchedules(file.Schedules)
+	if err := sm.storage.WriteJSONFile(schedulesFilename, file); err != nil {
+		return nil, fmt.Errorf("failed to save schedules: %w", err)
+	}
+	return &schedule, nil
+}
+
+// Delete removes a schedule, reporting whether it existed
+func (sm *ScheduleManager) Delete(id string) (bool, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	file, err := sm.load()
+	if err != nil {
+		return false, err
+	}
+	index := slices.IndexFunc(file.Schedules, func(cs ContentSchedule) bool { return cs.ID == id })
+	if index < 0 {
+		return false, nil
+	}
+	file.Schedules = slices.Delete(file.Schedules, index, index+1)
+	if err := sm.storage.WriteJSONFile(schedulesFilename, file); err != nil {
+		return false, fmt.Errorf("failed to save schedules: %w", err)
+	}
+	return true, nil
+}
+
+// Apply returns a copy of content with the schedules active at t applied,
+// in order of start so later ones win, and the IDs of those schedules.
+// Schedules whose path no longer fits the content are skipped.
+func (sm *ScheduleManager) Apply(content *types.ContentData, t time.Time) (*types.ContentData, []string, error) {
+	active, err := sm.activeAt(t)
+	if err != nil || len(active) == 0 {
+		return content, nil, err
+	}
+
+	copied, err := cloneContentValue(content.Sections)
+	if err != nil {
+		return nil, nil, err
+	}
+	contentMap := map[string]interface{}{
+		"title":       content.Title,
+		"description": content.Description,
+		"sections":    copied,
+	}
+
+	ids := make([]string, 0, len(active))
+	for _, schedule := range active {
+		if err := ApplyContentValue(contentMap, schedule.Path, schedule.Value); err != nil {
+			continue
+		}
+		ids = append(ids, schedule.ID)
+	}
+
+	applied := *content
+	applied.Title, _ = contentMap["title"].(string)
+	applied.Description, _ = contentMap["description"].(string)
+	applied.Sections, _ = contentMap["sections"].(map[string]interface{})
+	return &applied, ids, nil
+}
+
+// MarkApplied records the schedules the live site was generated with
+func (sm *ScheduleManager) MarkApplied(ids []string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	file, err := sm.load()
+	if err != nil {
+		return err
+	}
+	if ids == nil {
+		ids = []string{}
+	}
+	if slices.Equal(file.Applied, ids) {
+		return nil
+	}
+	file.Applied = ids
+	return sm.storage.WriteJSONFile(schedulesFilename, file)
+}
+
+// NeedsRegeneration reports whether the schedules active at t differ from
+// the ones the live site was generated with
+func (sm *ScheduleManager) NeedsRegeneration(t time.Time) (bool, error) {
+	active, err := sm.activeAt(t)
+	if err != nil {
+		return false, err
+	}
+	sm.mu.Lock()
+	file, err := sm.load()
+	sm.mu.Unlock()
+	if err != nil {
+		return false, err
+	}
+
+	ids := make([]string, 0, len(active))
+	for _, schedule := range active {
+		ids = append(ids, schedule.ID)
+	}
+	applied := slices.Clone(file.Applied)
+	slices.Sort(ids)
+	slices.Sort(applied)
+	return !slices.Equal(ids, applied), nil
+}
+
+// activeAt returns the schedules active at t, in order of start
+func (sm *ScheduleManager) activeAt(t time.Time) ([]ContentSchedule, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	file, err := sm.load()
+	if err != nil {
+		return nil, err
+	}
+	var active []ContentSchedule
+	for _, schedule := range file.Schedules {
+		if schedule.ActiveAt(t) {
+			active = append(active, schedule)
+		}
+	}
+	return active, nil
+}
+
+// load reads the schedule file; callers hold mu
+func (sm *ScheduleManager) load() (*scheduleFile, error) {
+	file := &scheduleFile{Schedules: make([]ContentSchedule, 0), Applied: make([]string, 0)}
+	if !sm.storage.FileExists(schedulesFilename) {
+		return file, nil
+	}
+	if err := sm.storage.ReadJSONFile(schedulesFilename, file); err != nil {
+		return nil, fmt.Errorf("failed to load schedules: %w", err)
+	}
+	return file, nil
+}
+
+// sortSchedules orders schedules by start, open starts first
+func sortSchedules(schedules []ContentSchedule) {
+	sort.SliceStable(schedules, func(i, j int) bool {
+		return schedules[i].StartsAt.Before(schedules[j].StartsAt)
+	})
+}
+
+// ApplyContentValue sets the value at a validator-style path of content,
+// adding the last key if it is missing
+func ApplyContentValue(content map[string]interface{}, path string, value interface{}) error {
+	steps, err := parseContentPath(path)
+	if err != nil {
+		return err
+	}
+	copied, err := cloneContentValue(value)
+	if err != nil {
+		return err
+	}
+	return setContentValue(content, steps, copied)
+}
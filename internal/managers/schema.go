@@ -3,13 +3,36 @@ package managers
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"onepagems/internal/types"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // SchemaManager handles schema.json operations
 type SchemaManager struct {
 	storage *FileStorage
 	dataDir string
+	strict  bool
+	limits  types.FormLimits
+
+	schemaLimits types.SchemaLimits
+
+	// The analysis of schema.json, kept until the file changes
+	analysisMu    sync.Mutex
+	analysis      *SchemaAnalysis
+	analysisStamp schemaStamp
+	saves         atomic.Int64 // schema saves through this manager
+}
+
+// schemaStamp identifies one version of schema.json
+type schemaStamp struct {
+	modTime time.Time
+	size    int64
+	saves   int64
 }
 
 // NewSchemaManager creates a new schema manager
@@ -20,6 +43,50 @@ func NewSchemaManager(storage *FileStorage, dataDir string) *SchemaManager {
 	}
 }
 
+// SetStrict makes content validation reject fields the schema does not
+// declare, regardless of each object's additionalProperties setting
+func (sm *SchemaManager) SetStrict(strict bool) {
+	sm.strict = strict
+}
+
+// SetLimits sets the server-wide limits content validation enforces and
+// generated forms report to the editor
+func (sm *SchemaManager) SetLimits(limits types.FormLimits) {
+	sm.limits = limits
+}
+
+// SetSchemaLimits sets the largest schema the manager loads, saves or
+// imports; unset limits use the defaults
+func (sm *SchemaManager) SetSchemaLimits(limits types.SchemaLimits) {
+	sm.schemaLimits = limits
+}
+
+// newValidator creates a validator for schema using the manager's strictness
+// and limits
+func (sm *SchemaManager) newValidator(schema *types.SchemaData) *SchemaValidator {
+	validator := NewSchemaValidator(schema)
+	validator.SetStrict(sm.strict)
+	validator.SetLimits(sm.limits)
+	validator.SetSchemaLimits(sm.schemaLimits)
+	return validator
+}
+
+// newParser creates a parser for schema using the manager's schema limits
+func (sm *SchemaManager) newParser(schema *types.SchemaData) *SchemaParser {
+	parser := NewSchemaParser(schema)
+	parser.SetSchemaLimits(sm.schemaLimits)
+	return parser
+}
+
+// newFormGenerator creates a form generator for schema using the manager's
+// limits
+func (sm *SchemaManager) newFormGenerator(schema *types.SchemaData) *FormGenerator {
+	formGenerator := NewFormGenerator(schema)
+	formGenerator.SetLimits(sm.limits)
+	formGenerator.SetSchemaLimits(sm.schemaLimits)
+	return formGenerator
+}
+
 // schemaFilePath returns the filename for schema.json
 func (sm *SchemaManager) schemaFilePath() string {
 	return "schema.json"
@@ -31,12 +98,12 @@ func (sm *SchemaManager) LoadSchema() (*types.SchemaData, error) {
 
 	// Check if schema.json exists
 	if !sm.storage.FileExists(schemaFilename) {
-		// Create default schema
+		// Create default schema, then read it back like a stored one so
+		// its numbers are float64 as the validator expects
 		defaultSchema := sm.createDefaultSchema()
 		if err := sm.SaveSchema(defaultSchema); err != nil {
 			return nil, fmt.Errorf("failed to create default schema: %w", err)
 		}
-		return defaultSchema, nil
 	}
 
 	// Load existing schema
@@ -69,6 +136,7 @@ func (sm *SchemaManager) SaveSchema(schema *types.SchemaData) error {
 	if err := sm.storage.WriteJSONFile(schemaFilename, schema); err != nil {
 		return fmt.Errorf("failed to save schema file: %w", err)
 	}
+	sm.saves.Add(1) // the cached analysis is stale even if the stamp matches
 
 	return nil
 }
@@ -96,6 +164,24 @@ func (sm *SchemaManager) UpdateSchema(updates map[string]interface{}) error {
 			} else {
 				return fmt.Errorf("type must be a string")
 			}
+		case "additionalProperties":
+			if allowed, ok := value.(bool); ok {
+				schema.AdditionalProperties = &allowed
+			} else {
+				return fmt.Errorf("additionalProperties must be a boolean")
+			}
+		case "$defs":
+			if defs, ok := value.(map[string]interface{}); ok {
+				schema.Defs = defs
+			} else {
+				return fmt.Errorf("$defs must be a map")
+			}
+		case "definitions":
+			if definitions, ok := value.(map[string]interface{}); ok {
+				schema.Definitions = definitions
+			} else {
+				return fmt.Errorf("definitions must be a map")
+			}
 		case "$schema":
 			if schemaVersion, ok := value.(string); ok {
 				schema.Schema = schemaVersion
@@ -147,7 +233,7 @@ func (sm *SchemaManager) GetSchemaInfo() (map[string]interface{}, error) {
 	propertyTypes := make(map[string]string)
 	for name, prop := range schema.Properties {
 		if propMap, ok := prop.(map[string]interface{}); ok {
-			if propType, ok := propMap["type"].(string); ok {
+			if propType := primaryType(propMap); propType != "" {
 				propertyTypes[name] = propType
 			}
 		}
@@ -212,9 +298,10 @@ func (sm *SchemaManager) createDefaultSchema() *types.SchemaData {
 				"description": "Various content sections of your website",
 				"properties": map[string]interface{}{
 					"hero": map[string]interface{}{
-						"type":        "object",
-						"title":       "Hero Section",
-						"description": "The main hero/banner section",
+						"type":          "object",
+						"title":         "Hero Section",
+						"propertyOrder": 1,
+						"description":   "The main hero/banner section",
 						"properties": map[string]interface{}{
 							"title": map[string]interface{}{
 								"type":        "string",
@@ -235,9 +322,10 @@ func (sm *SchemaManager) createDefaultSchema() *types.SchemaData {
 						},
 					},
 					"about": map[string]interface{}{
-						"type":        "object",
-						"title":       "About Section",
-						"description": "Information about you or your organization",
+						"type":          "object",
+						"title":         "About Section",
+						"propertyOrder": 2,
+						"description":   "Information about you or your organization",
 						"properties": map[string]interface{}{
 							"title": map[string]interface{}{
 								"type":        "string",
@@ -250,12 +338,19 @@ func (sm *SchemaManager) createDefaultSchema() *types.SchemaData {
 								"description": "Main content for the about section",
 								"format":      "textarea",
 							},
+							"video": map[string]interface{}{
+								"type":        "string",
+								"title":       "About Video",
+								"description": "YouTube, Vimeo or map embed link shown below the text",
+								"format":      "embed",
+							},
 						},
 					},
 					"contact": map[string]interface{}{
-						"type":        "object",
-						"title":       "Contact Section",
-						"description": "Contact information and details",
+						"type":          "object",
+						"title":         "Contact Section",
+						"propertyOrder": 3,
+						"description":   "Contact information and details",
 						"properties": map[string]interface{}{
 							"title": map[string]interface{}{
 								"type":        "string",
@@ -305,7 +400,8 @@ func (sm *SchemaManager) validateSchema(schema *types.SchemaData) error {
 		schema.Properties = make(map[string]interface{})
 	}
 
-	return nil
+	_, err := resolveSchema(schema, sm.schemaLimits)
+	return err
 }
 
 // ExportSchema exports schema as JSON for external use
@@ -336,7 +432,7 @@ func (sm *SchemaManager) GenerateFormFromSchema() ([]types.FormField, error) {
 	}
 
 	// Use the comprehensive form generator
-	formGenerator := NewFormGenerator(schema)
+	formGenerator := sm.newFormGenerator(schema)
 	form, err := formGenerator.GenerateForm()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate form: %w", err)
@@ -353,7 +449,7 @@ func (sm *SchemaManager) GenerateCompleteForm() (*types.GeneratedForm, error) {
 	}
 
 	// Use the comprehensive form generator
-	formGenerator := NewFormGenerator(schema)
+	formGenerator := sm.newFormGenerator(schema)
 	return formGenerator.GenerateForm()
 }
 
@@ -365,7 +461,7 @@ func (sm *SchemaManager) createFormFieldFromProperty(name string, prop map[strin
 	}
 
 	// Extract type
-	if fieldType, ok := prop["type"].(string); ok {
+	if fieldType := primaryType(prop); fieldType != "" {
 		switch fieldType {
 		case "string":
 			field.Type = "text"
@@ -407,15 +503,46 @@ func (sm *SchemaManager) createFormFieldFromProperty(name string, prop map[strin
 	return field
 }
 
-// ParseSchemaDetailed returns comprehensive schema analysis using the schema parser
+// ParseSchemaDetailed returns comprehensive schema analysis using the schema
+// parser. The analysis is made on first use and reused until schema.json
+// changes. It is shared by every caller and must be treated as read-only;
+// the Get* accessors below return copies of its parts for callers that
+// need to change them.
 func (sm *SchemaManager) ParseSchemaDetailed() (*SchemaAnalysis, error) {
+	sm.analysisMu.Lock()
+	defer sm.analysisMu.Unlock()
+
+	stamp, stampErr := sm.schemaStamp()
+	if sm.analysis != nil && stampErr == nil && stamp == sm.analysisStamp {
+		return sm.analysis, nil
+	}
+
 	schema, err := sm.LoadSchema()
 	if err != nil {
 		return nil, err
 	}
 
-	parser := NewSchemaParser(schema)
-	return parser.ParseSchema()
+	parser := sm.newParser(schema)
+	analysis, err := parser.ParseSchema()
+	if err != nil {
+		return nil, err
+	}
+	// The stamp was taken before reading, so a save in between makes the
+	// next call parse again. Without a file yet there is nothing to stamp.
+	if stampErr == nil {
+		sm.analysis, sm.analysisStamp = analysis, stamp
+	}
+	return analysis, nil
+}
+
+// schemaStamp returns the modification time and size of schema.json, and
+// how often it was saved
+func (sm *SchemaManager) schemaStamp() (schemaStamp, error) {
+	info, err := os.Stat(sm.storage.GetFilePath(sm.schemaFilePath()))
+	if err != nil {
+		return schemaStamp{}, err
+	}
+	return schemaStamp{modTime: info.ModTime(), size: info.Size(), saves: sm.saves.Load()}, nil
 }
 
 // GetFieldMetadata returns detailed metadata for a specific field
@@ -425,7 +552,7 @@ func (sm *SchemaManager) GetFieldMetadata(fieldName string) (*ParsedProperty, er
 		return nil, err
 	}
 
-	parser := NewSchemaParser(schema)
+	parser := sm.newParser(schema)
 	return parser.GetFieldMetadata(fieldName)
 }
 
@@ -436,7 +563,7 @@ func (sm *SchemaManager) GetValidationRules() ([]ValidationRule, error) {
 		return nil, err
 	}
 
-	return analysis.ValidationRules, nil
+	return slices.Clone(analysis.ValidationRules), nil
 }
 
 // ValidateFieldValue validates a single field value against the schema
@@ -446,7 +573,7 @@ func (sm *SchemaManager) ValidateFieldValue(fieldName string, value interface{})
 		return nil, err
 	}
 
-	parser := NewSchemaParser(schema)
+	parser := sm.newParser(schema)
 	failures := parser.ValidateFieldValue(fieldName, value)
 	return failures, nil
 }
@@ -458,7 +585,7 @@ func (sm *SchemaManager) GetSchemaFieldTypes() (map[string]string, error) {
 		return nil, err
 	}
 
-	return analysis.PropertyTypes, nil
+	return maps.Clone(analysis.PropertyTypes), nil
 }
 
 // GetRequiredFields returns list of required field names
@@ -468,7 +595,7 @@ func (sm *SchemaManager) GetRequiredFields() ([]string, error) {
 		return nil, err
 	}
 
-	return analysis.RequiredFields, nil
+	return slices.Clone(analysis.RequiredFields), nil
 }
 
 // GetOptionalFields returns list of optional field names
@@ -478,7 +605,7 @@ func (sm *SchemaManager) GetOptionalFields() ([]string, error) {
 		return nil, err
 	}
 
-	return analysis.OptionalFields, nil
+	return slices.Clone(analysis.OptionalFields), nil
 }
 
 // GetNestedObjects returns list of fields that are nested objects
@@ -488,7 +615,7 @@ func (sm *SchemaManager) GetNestedObjects() ([]string, error) {
 		return nil, err
 	}
 
-	return analysis.NestedObjects, nil
+	return slices.Clone(analysis.NestedObjects), nil
 }
 
 // GetArrayFields returns list of fields that are arrays
@@ -498,7 +625,7 @@ func (sm *SchemaManager) GetArrayFields() ([]string, error) {
 		return nil, err
 	}
 
-	return analysis.Arrays, nil
+	return slices.Clone(analysis.Arrays), nil
 }
 
 // GetEnumFields returns map of field names to their enum values
@@ -508,7 +635,11 @@ func (sm *SchemaManager) GetEnumFields() (map[string][]interface{}, error) {
 		return nil, err
 	}
 
-	return analysis.EnumFields, nil
+	enums := make(map[string][]interface{}, len(analysis.EnumFields))
+	for name, values := range analysis.EnumFields {
+		enums[name] = slices.Clone(values)
+	}
+	return enums, nil
 }
 
 // ValidateContentDetailed validates content using the comprehensive schema validator
@@ -518,11 +649,23 @@ func (sm *SchemaManager) ValidateContentDetailed(content interface{}) (*Validati
 		return nil, fmt.Errorf("failed to load schema: %w", err)
 	}
 
-	validator := NewSchemaValidator(schema)
+	validator := sm.newValidator(schema)
 	result := validator.ValidateContent(content)
 	return result, nil
 }
 
+// CoerceContent converts submitted string values in content to the types
+// declared by the current schema, in place, and returns the changes made
+func (sm *SchemaManager) CoerceContent(content map[string]interface{}) ([]ContentFix, error) {
+	schema, err := sm.LoadSchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema: %w", err)
+	}
+
+	validator := sm.newValidator(schema)
+	return validator.CoerceContent(content), nil
+}
+
 // ValidateFieldValueDetailed validates a single field value using the comprehensive validator
 func (sm *SchemaManager) ValidateFieldValueDetailed(fieldName string, value interface{}) (*ValidationResult, error) {
 	schema, err := sm.LoadSchema()
@@ -530,7 +673,7 @@ func (sm *SchemaManager) ValidateFieldValueDetailed(fieldName string, value inte
 		return nil, fmt.Errorf("failed to load schema: %w", err)
 	}
 
-	validator := NewSchemaValidator(schema)
+	validator := sm.newValidator(schema)
 	result := validator.ValidateFieldValue(fieldName, value)
 	return result, nil
 }
@@ -542,7 +685,7 @@ func (sm *SchemaManager) GenerateValidationReport(content interface{}) (map[stri
 		return nil, fmt.Errorf("failed to load schema: %w", err)
 	}
 
-	validator := NewSchemaValidator(schema)
+	validator := sm.newValidator(schema)
 	report := validator.GenerateValidationReport(content)
 	return report, nil
 }
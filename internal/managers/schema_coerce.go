@@ -0,0 +1,149 @@
+package managers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CoerceContent converts submitted values in content to the types the schema
+// declares, in place, before validation. Browser forms submit every value as
+// a string, so numeric and boolean strings are converted for fields that do
+// not accept strings, text is trimmed, and empty strings become null for
+// optional fields. Keys may be dotted paths such as "sections.hero.title", as
+// sent by auto-save. It returns the changes made.
+func (sv *SchemaValidator) CoerceContent(content map[string]interface{}) []ContentFix {
+	fixes := make([]ContentFix, 0)
+	sv.coerceObject(content, "", sv.schema.Properties, nil, &fixes)
+
+	sort.Slice(fixes, func(i, j int) bool {
+		return fixes[i].Path < fixes[j].Path
+	})
+	return fixes
+}
+
+// coerceObject coerces each field of an object that the schema declares
+func (sv *SchemaValidator) coerceObject(obj map[string]interface{}, path string, schemaProps map[string]interface{}, required []string, fixes *[]ContentFix) {
+	for key, value := range obj {
+		prop, isRequired, ok := lookupProperty(schemaProps, required, key)
+		if !ok {
+			continue
+		}
+
+		fieldPath := key
+		if path != "" {
+			fieldPath = path + "." + key
+		}
+		obj[key] = sv.coerceValue(value, prop, isRequired, fieldPath, fixes)
+	}
+}
+
+// coerceValue returns value converted to the type its schema expects
+func (sv *SchemaValidator) coerceValue(value interface{}, schemaProp map[string]interface{}, required bool, fieldPath string, fixes *[]ContentFix) interface{} {
+	switch v := value.(type) {
+	case string:
+		return sv.coerceString(v, schemaProp, required, fieldPath, fixes)
+	case map[string]interface{}:
+		if properties, ok := schemaProp["properties"].(map[string]interface{}); ok {
+			sv.coerceObject(v, fieldPath, properties, requiredFields(schemaProp), fixes)
+		}
+	case []interface{}:
+		if items, ok := schemaProp["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				v[i] = sv.coerceValue(item, items, true, fmt.Sprintf("%s[%d]", fieldPath, i), fixes)
+			}
+		}
+	}
+	return value
+}
+
+// coerceString converts a submitted string according to the field's types
+func (sv *SchemaValidator) coerceString(str string, schemaProp map[string]interface{}, required bool, fieldPath string, fixes *[]ContentFix) interface{} {
+	// A minimum length makes a field required in the form as well
+	if minLength, ok := schemaProp["minLength"].(float64); ok && minLength > 0 {
+		required = true
+	}
+
+	trimmed := strings.TrimSpace(str)
+	if trimmed == "" && !required {
+		return sv.recordFix(fixes, fieldPath, "empty_to_null", "Stored empty optional field as null", str, nil)
+	}
+
+	typeNames, _ := parseSchemaType(schemaProp)
+	if len(typeNames) == 0 {
+		typeNames = []string{"string"}
+	}
+
+	if !containsString(typeNames, "string") {
+		// Checkboxes submit "on" when ticked and no value otherwise
+		if trimmed == "on" && containsString(typeNames, "boolean") {
+			return sv.recordFix(fixes, fieldPath, "string_to_boolean", "Converted checkbox value to boolean", str, true)
+		}
+		if converted, code, ok := parseTypedString(trimmed, typeNames); ok {
+			return sv.recordFix(fixes, fieldPath, code, "Converted string to "+strings.TrimPrefix(code, "string_to_"), str, converted)
+		}
+		return str
+	}
+
+	if trimmed != str {
+		return sv.recordFix(fixes, fieldPath, "trimmed", "Trimmed surrounding whitespace", str, trimmed)
+	}
+	return str
+}
+
+// parseTypedString converts str to the first of typeNames it can represent.
+// It returns the converted value and a fix code, or false if none apply.
+func parseTypedString(str string, typeNames []string) (interface{}, string, bool) {
+	for _, typeName := range typeNames {
+		switch typeName {
+		case "integer":
+			if num, err := strconv.ParseInt(str, 10, 64); err == nil {
+				return float64(num), "string_to_integer", true
+			}
+		case "number":
+			if num, err := strconv.ParseFloat(str, 64); err == nil {
+				return num, "string_to_number", true
+			}
+		case "boolean":
+			if str == "true" || str == "false" {
+				return str == "true", "string_to_boolean", true
+			}
+		}
+	}
+	return nil, "", false
+}
+
+// lookupProperty resolves a field name, which may be a dotted path, against
+// schema properties. It returns the field's schema and whether it is
+// required, using required lists on each parent object.
+func lookupProperty(schemaProps map[string]interface{}, required []string, key string) (map[string]interface{}, bool, bool) {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		prop, ok := schemaProps[part].(map[string]interface{})
+		if !ok {
+			return nil, false, false
+		}
+
+		if i == len(parts)-1 {
+			isRequired, _ := prop["required"].(bool)
+			return prop, isRequired || containsString(required, part), true
+		}
+
+		schemaProps, _ = prop["properties"].(map[string]interface{})
+		required = requiredFields(prop)
+	}
+	return nil, false, false
+}
+
+// requiredFields returns the names in an object schema's required list
+func requiredFields(schemaProp map[string]interface{}) []string {
+	list, _ := schemaProp["required"].([]interface{})
+	names := make([]string, 0, len(list))
+	for _, item := range list {
+		if name, ok := item.(string); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
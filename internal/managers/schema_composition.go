@@ -0,0 +1,191 @@
+package managers
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// compositionKeywords combine subschemas
+var compositionKeywords = []string{"allOf", "anyOf", "oneOf", "not"}
+
+// hasComposition reports whether schema uses a composition keyword
+func hasComposition(schema map[string]interface{}) bool {
+	for _, keyword := range compositionKeywords {
+		if _, ok := schema[keyword]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeOf names the JSON type of a decoded value
+func (sv *SchemaValidator) jsonTypeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	}
+	if reflect.TypeOf(value).Kind() == reflect.Slice {
+		return "array"
+	}
+	if sv.isNumber(value) {
+		return "number"
+	}
+	return ""
+}
+
+// validateComposition checks value against the allOf, anyOf, oneOf and not
+// keywords of schemaProp. Each error names the branch that failed, e.g.
+// "anyOf[1]", and carries that branch's own errors as causes.
+func (sv *SchemaValidator) validateComposition(fieldName string, value interface{}, schemaProp map[string]interface{}, fieldPath string, result *ValidationResult) {
+	if branches, ok := schemaProp["allOf"].([]interface{}); ok {
+		for i, branch := range sv.checkBranches(fieldName, value, "allOf", branches, fieldPath) {
+			if branch.Valid {
+				continue
+			}
+			result.Valid = false
+			result.Errors = append(result.Errors, ValidationDetailError{
+				Field:        fieldName,
+				Code:         "all_of",
+				Message:      fmt.Sprintf("Field '%s' does not match %s: %s", fieldName, branchLabel("allOf", i, branches[i]), branch.Errors[0].Message),
+				Value:        value,
+				PropertyPath: fieldPath,
+				Causes:       branch.Errors,
+			})
+		}
+	}
+
+	if branches, ok := schemaProp["anyOf"].([]interface{}); ok && len(branches) > 0 {
+		checked := sv.checkBranches(fieldName, value, "anyOf", branches, fieldPath)
+		if len(matchingBranches(checked)) == 0 {
+			result.Valid = false
+			result.Errors = append(result.Errors, ValidationDetailError{
+				Field:        fieldName,
+				Code:         "any_of",
+				Message:      fmt.Sprintf("Field '%s' matches none of the %d anyOf schemas", fieldName, len(branches)),
+				Value:        value,
+				PropertyPath: fieldPath,
+				Causes:       branchCauses(checked),
+			})
+		}
+	}
+
+	if branches, ok := schemaProp["oneOf"].([]interface{}); ok && len(branches) > 0 {
+		checked := sv.checkBranches(fieldName, value, "oneOf", branches, fieldPath)
+		switch matched := matchingBranches(checked); len(matched) {
+		case 1:
+		case 0:
+			result.Valid = false
+			result.Errors = append(result.Errors, ValidationDetailError{
+				Field:        fieldName,
+				Code:         "one_of",
+				Message:      fmt.Sprintf("Field '%s' matches none of the %d oneOf schemas", fieldName, len(branches)),
+				Value:        value,
+				PropertyPath: fieldPath,
+				Causes:       branchCauses(checked),
+			})
+		default:
+			labels := make([]string, len(matched))
+			for i, index := range matched {
+				labels[i] = branchLabel("oneOf", index, branches[index])
+			}
+			result.Valid = false
+			result.Errors = append(result.Errors, ValidationDetailError{
+				Field:        fieldName,
+				Code:         "one_of",
+				Message:      fmt.Sprintf("Field '%s' must match exactly one oneOf schema but matches %s", fieldName, strings.Join(labels, " and ")),
+				Value:        value,
+				Expected:     "exactly one match",
+				PropertyPath: fieldPath,
+			})
+		}
+	}
+
+	if not, ok := schemaProp["not"].(map[string]interface{}); ok {
+		if sv.validateBranch(fieldName, value, not, fieldPath).Valid {
+			result.Valid = false
+			result.Errors = append(result.Errors, ValidationDetailError{
+				Field:        fieldName,
+				Code:         "not",
+				Message:      fmt.Sprintf("Field '%s' must not match the schema in 'not'", fieldName),
+				Value:        value,
+				PropertyPath: fieldPath,
+			})
+		}
+	}
+}
+
+// checkBranches validates value against each branch of a composition
+// keyword, labelling the errors of each with the branch they came from.
+// Branches that are not schemas always match.
+func (sv *SchemaValidator) checkBranches(fieldName string, value interface{}, keyword string, branches []interface{}, fieldPath string) []*ValidationResult {
+	checked := make([]*ValidationResult, len(branches))
+	for i, raw := range branches {
+		branch, ok := raw.(map[string]interface{})
+		if !ok {
+			checked[i] = &ValidationResult{Valid: true}
+			continue
+		}
+		checked[i] = sv.validateBranch(fieldName, value, branch, fieldPath)
+		label := branchLabel(keyword, i, branch)
+		for j := range checked[i].Errors {
+			checked[i].Errors[j].Branch = label
+		}
+	}
+	return checked
+}
+
+// validateBranch validates value against one composition branch. Branches
+// often leave out "type" and only add constraints, so an untyped branch
+// takes the value's own type rather than the string default of properties.
+func (sv *SchemaValidator) validateBranch(fieldName string, value interface{}, branch map[string]interface{}, fieldPath string) *ValidationResult {
+	if _, typed := branch["type"]; !typed {
+		if valueType := sv.jsonTypeOf(value); valueType != "" && valueType != "null" {
+			withType := make(map[string]interface{}, len(branch)+1)
+			for key, v := range branch {
+				withType[key] = v
+			}
+			withType["type"] = valueType
+			branch = withType
+		}
+	}
+	return sv.validateAgainst(fieldName, value, branch, fieldPath)
+}
+
+// matchingBranches returns the indexes of the branches value matched
+func matchingBranches(checked []*ValidationResult) []int {
+	matched := make([]int, 0)
+	for i, branch := range checked {
+		if branch.Valid {
+			matched = append(matched, i)
+		}
+	}
+	return matched
+}
+
+// branchCauses gathers the errors of every branch
+func branchCauses(checked []*ValidationResult) []ValidationDetailError {
+	causes := make([]ValidationDetailError, 0)
+	for _, branch := range checked {
+		causes = append(causes, branch.Errors...)
+	}
+	return causes
+}
+
+// branchLabel names a branch in messages, with its title when it has one,
+// e.g. `oneOf[1] ("Video")`
+func branchLabel(keyword string, index int, branch interface{}) string {
+	label := fmt.Sprintf("%s[%d]", keyword, index)
+	if schema, ok := branch.(map[string]interface{}); ok {
+		if title, ok := schema["title"].(string); ok && title != "" {
+			label += fmt.Sprintf(" (%q)", title)
+		}
+	}
+	return label
+}
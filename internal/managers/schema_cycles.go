@@ -0,0 +1,198 @@
+package managers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"onepagems/internal/types"
+)
+
+// ErrSchemaCycle is wrapped by every SchemaCycleError
+var ErrSchemaCycle = errors.New("schema contains a cycle")
+
+// SchemaCycleError reports a schema that refers back to itself, through a
+// local $ref or a nested object that is one of its own ancestors. Expanding
+// it would never end.
+type SchemaCycleError struct {
+	Path   string // dotted path of the schema closing the cycle
+	Target string // dotted path of the schema it leads back to, "" for the root
+	Ref    string // the $ref followed, empty for a self-referential object
+}
+
+func (e *SchemaCycleError) Error() string {
+	target := "the root schema"
+	if e.Target != "" {
+		target = fmt.Sprintf("'%s'", e.Target)
+	}
+	if e.Ref != "" {
+		return fmt.Sprintf("schema at '%s' refers back to %s through $ref %q", e.Path, target, e.Ref)
+	}
+	return fmt.Sprintf("schema at '%s' contains %s, one of its own ancestors", e.Path, target)
+}
+
+// Unwrap lets callers test for ErrSchemaCycle
+func (e *SchemaCycleError) Unwrap() error {
+	return ErrSchemaCycle
+}
+
+// checkSchema refuses schemas with cycles or over limits, before anything
+// recurses through them
+func checkSchema(schema *types.SchemaData, limits types.SchemaLimits) error {
+	if err := FindSchemaCycle(schema); err != nil {
+		return err
+	}
+	return CheckSchemaLimits(schema, limits)
+}
+
+// cycleFrame is one step of the cycle search: entering a schema, or leaving
+// it once everything below it has been searched
+type cycleFrame struct {
+	schema map[string]interface{}
+	path   string
+	from   string // path of the schema that led here
+	ref    string // the $ref that led here, if any
+	leave  bool
+}
+
+// FindSchemaCycle returns a *SchemaCycleError for the first cycle in schema,
+// or nil. It follows subschemas and local $refs ("#" and "#/..."); other
+// references are left for the caller to resolve. The search keeps its own
+// stack, so it is safe on schemas of any depth.
+func FindSchemaCycle(schema *types.SchemaData) error {
+	if schema == nil {
+		return nil
+	}
+	root := schemaRoot(schema)
+
+	const (
+		unvisited = iota
+		searching // on the current path
+		done
+	)
+	state := make(map[uintptr]int)
+	paths := make(map[uintptr]string)
+	stack := []cycleFrame{{schema: root}}
+
+	for len(stack) > 0 {
+		frame := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		id := reflect.ValueOf(frame.schema).Pointer()
+
+		if frame.leave {
+			state[id] = done
+			continue
+		}
+		switch state[id] {
+		case searching:
+			return &SchemaCycleError{Path: frame.from, Target: paths[id], Ref: frame.ref}
+		case done:
+			continue
+		}
+		state[id] = searching
+		paths[id] = frame.path
+		stack = append(stack, cycleFrame{schema: frame.schema, leave: true})
+
+		if ref, ok := frame.schema["$ref"].(string); ok {
+			if target, ok := resolveLocalRef(root, ref); ok {
+				stack = append(stack, cycleFrame{schema: target, path: refPath(ref), from: frame.path, ref: ref})
+			}
+		}
+		for _, child := range subschemasOf(frame.schema, frame.path) {
+			stack = append(stack, cycleFrame{schema: child.schema, path: child.path, from: frame.path})
+		}
+	}
+	return nil
+}
+
+// subschemasOf lists the subschemas directly under schema, with their paths
+func subschemasOf(schema map[string]interface{}, path string) []schemaNode {
+	var children []schemaNode
+	join := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+	for _, keyword := range subschemaMapKeywords {
+		named, ok := schema[keyword].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, name := range sortedKeys(named) {
+			if child, ok := named[name].(map[string]interface{}); ok {
+				childPath := join(keyword + "." + name)
+				if keyword == "properties" {
+					childPath = join(name)
+				}
+				children = append(children, schemaNode{schema: child, path: childPath})
+			}
+		}
+	}
+	for _, keyword := range subschemaKeywords {
+		if child, ok := schema[keyword].(map[string]interface{}); ok {
+			children = append(children, schemaNode{schema: child, path: join(keyword)})
+		}
+	}
+	for _, keyword := range subschemaListKeywords {
+		list, ok := schema[keyword].([]interface{})
+		if !ok {
+			continue
+		}
+		for i, item := range list {
+			if child, ok := item.(map[string]interface{}); ok {
+				children = append(children, schemaNode{schema: child, path: join(keyword + "[" + strconv.Itoa(i) + "]")})
+			}
+		}
+	}
+	return children
+}
+
+// refPath turns a local JSON pointer into the dotted path used in
+// diagnostics, e.g. "#/properties/team/items" into "team.items"
+func refPath(ref string) string {
+	if !strings.HasPrefix(ref, "#/") {
+		return ""
+	}
+	tokens := strings.Split(ref[2:], "/")
+	parts := make([]string, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		token := strings.ReplaceAll(strings.ReplaceAll(tokens[i], "~1", "/"), "~0", "~")
+		if token == "properties" && i+1 < len(tokens) {
+			continue // the property name that follows is the path segment
+		}
+		parts = append(parts, token)
+	}
+	return strings.Join(parts, ".")
+}
+
+// resolveLocalRef finds the schema a local JSON pointer such as
+// "#/properties/hero" points to within root
+func resolveLocalRef(root map[string]interface{}, ref string) (map[string]interface{}, bool) {
+	if ref == "#" {
+		return root, true
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, false
+	}
+	var current interface{} = root
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := current.(type) {
+		case map[string]interface{}:
+			current = node[token]
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			current = node[i]
+		default:
+			return nil, false
+		}
+	}
+	schema, ok := current.(map[string]interface{})
+	return schema, ok
+}
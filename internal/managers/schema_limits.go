@@ -0,0 +1,132 @@
+package managers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"onepagems/internal/types"
+)
+
+// Default schema limits, far above what a one-page site needs
+const (
+	DefaultSchemaMaxDepth      = 32
+	DefaultSchemaMaxProperties = 2000
+	DefaultSchemaMaxEnumValues = 1000
+)
+
+// ErrSchemaTooLarge is wrapped by every SchemaLimitError
+var ErrSchemaTooLarge = errors.New("schema exceeds size limits")
+
+// SchemaLimitError reports the first place a schema exceeds a limit
+type SchemaLimitError struct {
+	Path    string // dotted path of the offending schema, "" for the root
+	Message string
+}
+
+func (e *SchemaLimitError) Error() string {
+	if e.Path == "" {
+		return "schema " + e.Message
+	}
+	return fmt.Sprintf("schema at '%s' %s", e.Path, e.Message)
+}
+
+// Unwrap lets callers test for ErrSchemaTooLarge
+func (e *SchemaLimitError) Unwrap() error {
+	return ErrSchemaTooLarge
+}
+
+// schemaLimitsOrDefault fills unset limits with the defaults
+func schemaLimitsOrDefault(limits types.SchemaLimits) types.SchemaLimits {
+	if limits.MaxDepth <= 0 {
+		limits.MaxDepth = DefaultSchemaMaxDepth
+	}
+	if limits.MaxProperties <= 0 {
+		limits.MaxProperties = DefaultSchemaMaxProperties
+	}
+	if limits.MaxEnumValues <= 0 {
+		limits.MaxEnumValues = DefaultSchemaMaxEnumValues
+	}
+	return limits
+}
+
+// schemaNode is one subschema waiting to be checked
+type schemaNode struct {
+	schema map[string]interface{}
+	path   string
+	depth  int
+}
+
+// subschemaMapKeywords hold objects of named subschemas
+var subschemaMapKeywords = []string{"properties", "patternProperties", "$defs", "definitions", "dependentSchemas"}
+
+// subschemaKeywords hold a single subschema
+var subschemaKeywords = []string{"items", "additionalProperties", "additionalItems", "unevaluatedProperties", "unevaluatedItems", "contains", "propertyNames", "not", "if", "then", "else"}
+
+// subschemaListKeywords hold arrays of subschemas
+var subschemaListKeywords = []string{"items", "prefixItems", "allOf", "anyOf", "oneOf"}
+
+// CheckSchemaLimits reports whether schema stays within limits. It walks the
+// schema with an explicit stack, so it is safe on schemas too deep for the
+// recursive parser, and stops at the first limit exceeded.
+func CheckSchemaLimits(schema *types.SchemaData, limits types.SchemaLimits) error {
+	if schema == nil {
+		return nil
+	}
+	limits = schemaLimitsOrDefault(limits)
+
+	if len(schema.Properties) > limits.MaxProperties {
+		return &SchemaLimitError{Message: fmt.Sprintf("has more than %d properties", limits.MaxProperties)}
+	}
+	stack := make([]schemaNode, 0, len(schema.Properties))
+	for _, name := range sortedKeys(schema.Properties) {
+		if prop, ok := schema.Properties[name].(map[string]interface{}); ok {
+			stack = append(stack, schemaNode{schema: prop, path: name, depth: 1})
+		}
+	}
+	for _, defs := range []struct {
+		keyword string
+		schemas map[string]interface{}
+	}{{"$defs", schema.Defs}, {"definitions", schema.Definitions}} {
+		for _, name := range sortedKeys(defs.schemas) {
+			if def, ok := defs.schemas[name].(map[string]interface{}); ok {
+				stack = append(stack, schemaNode{schema: def, path: defs.keyword + "." + name, depth: 1})
+			}
+		}
+	}
+
+	count := len(stack)
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if node.depth > limits.MaxDepth {
+			return &SchemaLimitError{Path: node.path, Message: fmt.Sprintf("nests deeper than %d levels", limits.MaxDepth)}
+		}
+		if enum, ok := node.schema["enum"].([]interface{}); ok && len(enum) > limits.MaxEnumValues {
+			return &SchemaLimitError{Path: node.path, Message: fmt.Sprintf("has an enum of more than %d values", limits.MaxEnumValues)}
+		}
+
+		children := subschemasOf(node.schema, node.path)
+		for i := range children {
+			children[i].depth = node.depth + 1
+		}
+
+		count += len(children)
+		if count > limits.MaxProperties {
+			return &SchemaLimitError{Message: fmt.Sprintf("has more than %d properties and subschemas", limits.MaxProperties)}
+		}
+		stack = append(stack, children...)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in order, for deterministic errors
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
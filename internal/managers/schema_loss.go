@@ -0,0 +1,77 @@
+package managers
+
+import (
+	"encoding/json"
+	"sort"
+
+	"onepagems/internal/types"
+)
+
+// SchemaDroppedFields returns the dotted paths of content values that
+// schema does not declare. The editor no longer shows them, so replacing
+// the schema with it loses them. Objects declared without properties, or
+// that allow additional properties, are not looked into.
+func SchemaDroppedFields(schema *types.SchemaData, content *types.ContentData) []string {
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return nil
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil
+	}
+	delete(values, "last_updated")
+
+	dropped := []string{}
+	collectDroppedFields(values, schema.Properties, "", &dropped)
+	sort.Strings(dropped)
+	return dropped
+}
+
+// collectDroppedFields adds the keys of values missing from properties,
+// and those of nested objects, to dropped
+func collectDroppedFields(values map[string]interface{}, properties map[string]interface{}, path string, dropped *[]string) {
+	for key, value := range values {
+		fieldPath := key
+		if path != "" {
+			fieldPath = path + "." + key
+		}
+		if isEmptyValue(value) {
+			continue
+		}
+		prop, declared := properties[key].(map[string]interface{})
+		if !declared {
+			*dropped = append(*dropped, fieldPath)
+			continue
+		}
+		nested, isObject := value.(map[string]interface{})
+		nestedProps, hasProps := prop["properties"].(map[string]interface{})
+		if !isObject || !hasProps {
+			continue
+		}
+		if additional, ok := prop["additionalProperties"]; ok && additional != false {
+			continue
+		}
+		collectDroppedFields(nested, nestedProps, fieldPath, dropped)
+	}
+}
+
+// isEmptyValue reports whether value holds no content worth keeping
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		for _, nested := range v {
+			if !isEmptyValue(nested) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
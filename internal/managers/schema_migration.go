@@ -0,0 +1,412 @@
+package managers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"onepagems/internal/types"
+)
+
+// Kinds of schema change
+const (
+	SchemaFieldAdded       = "added"
+	SchemaFieldRemoved     = "removed"
+	SchemaFieldTypeChanged = "type_changed"
+)
+
+// Kinds of migration action
+const (
+	MigrationRename  = "rename"
+	MigrationDefault = "default"
+	MigrationDrop    = "drop"
+)
+
+// SchemaChange is one field that differs between two schemas. Paths use
+// "[]" for the items of arrays, as in "sections.team.members[].name".
+// Values counts the content values at the path, which the change affects.
+type SchemaChange struct {
+	Path     string `json:"path"`
+	Kind     string `json:"kind"`
+	OldType  string `json:"old_type,omitempty"`
+	NewType  string `json:"new_type,omitempty"`
+	Required bool   `json:"required,omitempty"`
+	Values   int    `json:"values"`
+}
+
+// SchemaMigrationOptions chooses the transformations applied to content.
+// Renames moves values from old paths to new ones and is applied first;
+// both paths need the same number of "[]".
+type SchemaMigrationOptions struct {
+	Renames     map[string]string `json:"renames,omitempty"`
+	AddDefaults bool              `json:"add_defaults,omitempty"`
+	DropOrphans bool              `json:"drop_orphans,omitempty"`
+}
+
+// MigrationAction is one change made to content
+type MigrationAction struct {
+	Action string      `json:"action"`
+	Path   string      `json:"path"`
+	To     string      `json:"to,omitempty"` // renames
+	Value  interface{} `json:"value,omitempty"`
+}
+
+// SchemaMigrationReport describes a content migration. Impacted lists the
+// content paths holding values the new schema does not declare after the
+// actions ran; SuggestedRenames pairs removed and added fields of the same
+// type under the same parent.
+type SchemaMigrationReport struct {
+	Changes          []SchemaChange    `json:"changes"`
+	Actions          []MigrationAction `json:"actions"`
+	Impacted         []string          `json:"impacted"`
+	SuggestedRenames map[string]string `json:"suggested_renames"`
+	Validation       *ValidationResult `json:"validation"`
+	DryRun           bool              `json:"dry_run"`
+	Saved            bool              `json:"saved"`
+}
+
+// schemaField is a field of a schema with its declaration
+type schemaField struct {
+	prop     map[string]interface{}
+	required bool
+}
+
+// schemaFields flattens the properties of schema by path
+func schemaFields(schema *types.SchemaData) map[string]schemaField {
+	fields := make(map[string]schemaField)
+	var walk func(properties map[string]interface{}, required []string, path string)
+	walk = func(properties map[string]interface{}, required []string, path string) {
+		for key, raw := range properties {
+			prop, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			fieldPath := joinImportPath(path, key)
+			fields[fieldPath] = schemaField{prop: prop, required: containsString(required, key)}
+			if nested, ok := prop["properties"].(map[string]interface{}); ok {
+				walk(nested, stringList(prop["required"]), fieldPath)
+			}
+			if items, ok := prop["items"].(map[string]interface{}); ok {
+				if nested, ok := items["properties"].(map[string]interface{}); ok {
+					walk(nested, stringList(items["required"]), fieldPath+"[]")
+				}
+			}
+		}
+	}
+	if schema != nil {
+		walk(schema.Properties, nil, "")
+	}
+	return fields
+}
+
+// DiffSchemas lists the fields added, removed or retyped from oldSchema to
+// newSchema, counting the values content holds at each, in path order
+func DiffSchemas(oldSchema, newSchema *types.SchemaData, content map[string]interface{}) []SchemaChange {
+	oldFields, newFields := schemaFields(oldSchema), schemaFields(newSchema)
+	changes := []SchemaChange{}
+	for path, field := range newFields {
+		old, existed := oldFields[path]
+		switch {
+		case !existed:
+			changes = append(changes, SchemaChange{Path: path, Kind: SchemaFieldAdded, NewType: primaryType(field.prop), Required: field.required})
+		case primaryType(old.prop) != primaryType(field.prop):
+			changes = append(changes, SchemaChange{Path: path, Kind: SchemaFieldTypeChanged, OldType: primaryType(old.prop), NewType: primaryType(field.prop), Required: field.required})
+		}
+	}
+	for path, field := range oldFields {
+		if _, kept := newFields[path]; !kept {
+			changes = append(changes, SchemaChange{Path: path, Kind: SchemaFieldRemoved, OldType: primaryType(field.prop)})
+		}
+	}
+	for i := range changes {
+		changes[i].Values = countContentValues(content, changes[i].Path)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// countContentValues counts the non-empty values at path in content
+func countContentValues(content map[string]interface{}, path string) int {
+	steps, err := parseImportPath(path)
+	if err != nil {
+		return 0
+	}
+	count := 0
+	for _, found := range collectImportValues(content, steps) {
+		if !found.missing && !isEmptyValue(found.value) {
+			count++
+		}
+	}
+	return count
+}
+
+// suggestRenames pairs each removed field with an added field of the same
+// type under the same parent, when there is exactly one
+func suggestRenames(changes []SchemaChange) map[string]string {
+	renames := make(map[string]string)
+	parent := func(path string) string {
+		if i := strings.LastIndex(path, "."); i >= 0 {
+			return path[:i]
+		}
+		return ""
+	}
+	for _, removed := range changes {
+		if removed.Kind != SchemaFieldRemoved || removed.Values == 0 {
+			continue
+		}
+		var match string
+		candidates := 0
+		for _, added := range changes {
+			if added.Kind == SchemaFieldAdded && added.NewType == removed.OldType && parent(added.Path) == parent(removed.Path) {
+				match = added.Path
+				candidates++
+			}
+		}
+		if candidates == 1 {
+			renames[removed.Path] = match
+		}
+	}
+	return renames
+}
+
+// MigrateContent brings content, in the form of content.json, in line with
+// newSchema: values are moved by options.Renames, missing required fields
+// and fields with a default are filled in with options.AddDefaults, and
+// values newSchema does not declare are removed with options.DropOrphans.
+// content is changed in place. oldSchema, the schema content was written
+// for, is diffed with newSchema for the report; the report's validation is
+// against newSchema.
+func (sm *SchemaManager) MigrateContent(oldSchema, newSchema *types.SchemaData, content map[string]interface{}, options SchemaMigrationOptions) (*SchemaMigrationReport, error) {
+	if err := sm.validateSchema(newSchema); err != nil {
+		return nil, fmt.Errorf("schema validation failed: %w", err)
+	}
+	report := &SchemaMigrationReport{
+		Changes: DiffSchemas(oldSchema, newSchema, content),
+		Actions: []MigrationAction{},
+	}
+	report.SuggestedRenames = suggestRenames(report.Changes)
+
+	newFields := schemaFields(newSchema)
+	renames := make([]string, 0, len(options.Renames))
+	for from := range options.Renames {
+		renames = append(renames, from)
+	}
+	sort.Strings(renames)
+	for _, from := range renames {
+		to := options.Renames[from]
+		if _, declared := newFields[to]; !declared {
+			return nil, fmt.Errorf("cannot rename %s to %s: the new schema has no field %s", from, to, to)
+		}
+		moved, err := renameContentValue(content, from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range moved {
+			report.Actions = append(report.Actions, MigrationAction{Action: MigrationRename, Path: path.from, To: path.to})
+		}
+	}
+
+	if options.AddDefaults {
+		addMigrationDefaults(content, newSchema.Properties, nil, "", &report.Actions)
+	}
+
+	if options.DropOrphans {
+		dropOrphans(content, newSchema.Properties, "", &report.Actions)
+	}
+
+	report.Impacted = []string{}
+	collectDroppedFields(withoutKey(content, "last_updated"), newSchema.Properties, "", &report.Impacted)
+	sort.Strings(report.Impacted)
+	report.Validation = sm.newValidator(newSchema).ValidateContent(content)
+	return report, nil
+}
+
+// movedValue is a value moved by a rename, with its concrete paths
+type movedValue struct {
+	from, to string
+}
+
+// renameContentValue moves every value at from to the same place at to,
+// leaving values already at to in place
+func renameContentValue(content map[string]interface{}, from, to string) ([]movedValue, error) {
+	if strings.Count(from, "[]") != strings.Count(to, "[]") {
+		return nil, fmt.Errorf("cannot rename %s to %s: both need the same number of []", from, to)
+	}
+	fromSteps, err := parseImportPath(from)
+	if err != nil {
+		return nil, err
+	}
+	toSteps, err := parseImportPath(to)
+	if err != nil {
+		return nil, err
+	}
+
+	var moved []movedValue
+	for _, found := range collectImportValues(content, fromSteps) {
+		if found.missing {
+			continue
+		}
+		target := describeImportIndexes(to, found.indexes)
+		if existing := collectImportValues(content, indexedSteps(toSteps, found.indexes)); len(existing) == 1 && !existing[0].missing && !isEmptyValue(existing[0].value) {
+			continue
+		}
+		if err := setImportValue(content, toSteps, found.indexes, found.value); err != nil {
+			return nil, fmt.Errorf("cannot rename %s to %s: %w", from, target, err)
+		}
+		deleteContentValue(content, indexedSteps(fromSteps, found.indexes))
+		moved = append(moved, movedValue{from: describeImportIndexes(from, found.indexes), to: target})
+	}
+	return moved, nil
+}
+
+// indexedSteps replaces the "[]" steps of steps with fixed indexes
+func indexedSteps(steps []importStep, indexes []int) []importStep {
+	fixed := make([]importStep, len(steps))
+	copy(fixed, steps)
+	for i := range fixed {
+		if fixed[i].each && len(indexes) > 0 {
+			fixed[i] = importStep{at: true, index: indexes[0]}
+			indexes = indexes[1:]
+		}
+	}
+	return fixed
+}
+
+// deleteContentValue removes the key at the end of steps, which hold only
+// keys and fixed indexes
+func deleteContentValue(content interface{}, steps []importStep) {
+	for i, step := range steps {
+		last := i == len(steps)-1
+		switch {
+		case step.at:
+			items, _ := content.([]interface{})
+			if step.index >= len(items) || last {
+				return
+			}
+			content = items[step.index]
+		default:
+			object, ok := content.(map[string]interface{})
+			if !ok {
+				return
+			}
+			if last {
+				delete(object, step.key)
+				return
+			}
+			content = object[step.key]
+		}
+	}
+}
+
+// addMigrationDefaults fills in fields missing from object: those with a
+// default get it, and required ones without get an empty value of their
+// type. Objects are looked into, as are the items of arrays.
+func addMigrationDefaults(object map[string]interface{}, properties map[string]interface{}, required []string, path string, actions *[]MigrationAction) {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		prop, ok := properties[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		fieldPath := joinImportPath(path, key)
+		if _, exists := object[key]; !exists {
+			value, hasDefault := prop["default"]
+			if !hasDefault && containsString(required, key) {
+				value, hasDefault = emptyValueOf(prop), true
+			}
+			if hasDefault {
+				cloned, err := cloneContentValue(value)
+				if err != nil {
+					continue
+				}
+				object[key] = cloned
+				*actions = append(*actions, MigrationAction{Action: MigrationDefault, Path: fieldPath, Value: value})
+			}
+		}
+
+		switch value := object[key].(type) {
+		case map[string]interface{}:
+			if nested, ok := prop["properties"].(map[string]interface{}); ok {
+				addMigrationDefaults(value, nested, stringList(prop["required"]), fieldPath, actions)
+			}
+		case []interface{}:
+			items, _ := prop["items"].(map[string]interface{})
+			if nested, ok := items["properties"].(map[string]interface{}); ok {
+				for i, item := range value {
+					if itemObject, ok := item.(map[string]interface{}); ok {
+						addMigrationDefaults(itemObject, nested, stringList(items["required"]), fmt.Sprintf("%s[%d]", fieldPath, i), actions)
+					}
+				}
+			}
+		}
+	}
+}
+
+// emptyValueOf is the empty value of a property's type
+func emptyValueOf(prop map[string]interface{}) interface{} {
+	switch primaryType(prop) {
+	case "object":
+		return map[string]interface{}{}
+	case "array":
+		return []interface{}{}
+	case "number", "integer":
+		return float64(0)
+	case "boolean":
+		return false
+	case "null":
+		return nil
+	}
+	return ""
+}
+
+// dropOrphans removes the values of object that properties does not
+// declare, looking into declared objects and the items of arrays. Objects
+// declared without properties, or allowing additional ones, are kept whole.
+func dropOrphans(object map[string]interface{}, properties map[string]interface{}, path string, actions *[]MigrationAction) {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if path == "" && key == "last_updated" {
+			continue
+		}
+		fieldPath := joinImportPath(path, key)
+		prop, declared := properties[key].(map[string]interface{})
+		if !declared {
+			*actions = append(*actions, MigrationAction{Action: MigrationDrop, Path: fieldPath, Value: object[key]})
+			delete(object, key)
+			continue
+		}
+		if additional, ok := prop["additionalProperties"]; ok && additional != false {
+			continue
+		}
+		switch value := object[key].(type) {
+		case map[string]interface{}:
+			if nested, ok := prop["properties"].(map[string]interface{}); ok {
+				dropOrphans(value, nested, fieldPath, actions)
+			}
+		case []interface{}:
+			items, _ := prop["items"].(map[string]interface{})
+			nested, ok := items["properties"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if additional, ok := items["additionalProperties"]; ok && additional != false {
+				continue
+			}
+			for i, item := range value {
+				if itemObject, ok := item.(map[string]interface{}); ok {
+					dropOrphans(itemObject, nested, fmt.Sprintf("%s[%d]", fieldPath, i), actions)
+				}
+			}
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 // SchemaParser handles parsing and analysis of JSON Schema definitions
 type SchemaParser struct {
 	schema *types.SchemaData
+	limits types.SchemaLimits
 }
 
 // NewSchemaParser creates a new schema parser
@@ -21,16 +22,25 @@ func NewSchemaParser(schema *types.SchemaData) *SchemaParser {
 	}
 }
 
+// SetSchemaLimits sets the largest schema ParseSchema accepts; unset limits
+// use the defaults
+func (sp *SchemaParser) SetSchemaLimits(limits types.SchemaLimits) {
+	sp.limits = limits
+}
+
 // ParsedProperty represents a parsed schema property with all metadata
 type ParsedProperty struct {
 	Name                 string                     `json:"name"`
 	Type                 string                     `json:"type"`
+	Types                []string                   `json:"types,omitempty"` // For type unions
+	Nullable             bool                       `json:"nullable,omitempty"`
 	Format               string                     `json:"format,omitempty"`
 	Title                string                     `json:"title,omitempty"`
 	Description          string                     `json:"description,omitempty"`
 	Required             bool                       `json:"required"`
 	Default              interface{}                `json:"default,omitempty"`
 	Enum                 []interface{}              `json:"enum,omitempty"`
+	Const                interface{}                `json:"const,omitempty"`
 	Pattern              string                     `json:"pattern,omitempty"`
 	MinLength            *int                       `json:"minLength,omitempty"`
 	MaxLength            *int                       `json:"maxLength,omitempty"`
@@ -70,6 +80,10 @@ func (sp *SchemaParser) ParseSchema() (*SchemaAnalysis, error) {
 	if sp.schema == nil {
 		return nil, fmt.Errorf("schema is nil")
 	}
+	schema, err := resolveSchema(sp.schema, sp.limits)
+	if err != nil {
+		return nil, err
+	}
 
 	analysis := &SchemaAnalysis{
 		PropertyTypes:   make(map[string]string),
@@ -86,8 +100,8 @@ func (sp *SchemaParser) ParseSchema() (*SchemaAnalysis, error) {
 	// Get required fields from root level
 	requiredFields := sp.extractRequiredFields(sp.schema.Properties)
 
-	// Parse each property
-	for propName, propData := range sp.schema.Properties {
+	// Parse each property, with $refs already expanded
+	for propName, propData := range schema.Properties {
 		propMap, ok := propData.(map[string]interface{})
 		if !ok {
 			continue
@@ -150,8 +164,18 @@ func (sp *SchemaParser) parseProperty(name string, prop map[string]interface{},
 	parsed.Required = sp.isRequired(name, requiredFields)
 
 	// Extract basic properties
-	if propType, ok := prop["type"].(string); ok {
-		parsed.Type = propType
+	typeNames, nullable := parseSchemaType(prop)
+	parsed.Nullable = nullable
+	if len(typeNames) > 0 {
+		parsed.Type = typeNames[0]
+	} else if nullable {
+		parsed.Type = "null"
+	}
+	if len(typeNames) > 1 || (nullable && len(typeNames) > 0) {
+		parsed.Types = typeNames
+		if nullable {
+			parsed.Types = append(parsed.Types, "null")
+		}
 	}
 
 	if format, ok := prop["format"].(string); ok {
@@ -198,6 +222,10 @@ func (sp *SchemaParser) parseProperty(name string, prop map[string]interface{},
 		parsed.Enum = enumData
 	}
 
+	if constValue, ok := prop["const"]; ok {
+		parsed.Const = constValue
+	}
+
 	// Extract examples
 	if examples, ok := prop["examples"].([]interface{}); ok {
 		parsed.Examples = examples
@@ -241,6 +269,48 @@ func (sp *SchemaParser) parseProperty(name string, prop map[string]interface{},
 	return parsed, nil
 }
 
+// parseSchemaType reads a property's type keyword, which may be a single type
+// name or an array of them. It returns the declared types other than "null",
+// in order, and whether null is allowed either by the union or by the
+// OpenAPI-style "nullable": true.
+func parseSchemaType(prop map[string]interface{}) ([]string, bool) {
+	nullable, _ := prop["nullable"].(bool)
+	typeNames := make([]string, 0, 1)
+
+	switch propType := prop["type"].(type) {
+	case string:
+		if propType == "null" {
+			nullable = true
+		} else {
+			typeNames = append(typeNames, propType)
+		}
+	case []interface{}:
+		for _, item := range propType {
+			name, ok := item.(string)
+			if !ok {
+				continue
+			}
+			if name == "null" {
+				nullable = true
+			} else {
+				typeNames = append(typeNames, name)
+			}
+		}
+	}
+
+	return typeNames, nullable
+}
+
+// primaryType returns the first non-null type of a property, or "" if none
+// is declared
+func primaryType(prop map[string]interface{}) string {
+	typeNames, _ := parseSchemaType(prop)
+	if len(typeNames) == 0 {
+		return ""
+	}
+	return typeNames[0]
+}
+
 // extractRequiredFields extracts required field names from schema
 func (sp *SchemaParser) extractRequiredFields(schemaProps map[string]interface{}) []string {
 	// First check if required is defined at the schema root level
@@ -372,13 +442,32 @@ func (sp *SchemaParser) extractValidationRules(propertyName string, prop *Parsed
 		})
 	}
 
+	// Const validation
+	if prop.Const != nil {
+		rules = append(rules, ValidationRule{
+			Type:         "const",
+			Value:        prop.Const,
+			Message:      fmt.Sprintf("Field '%s' must be %v", propertyName, prop.Const),
+			PropertyPath: fullPath,
+		})
+	}
+
 	// Type validation
-	rules = append(rules, ValidationRule{
-		Type:         "type",
-		Value:        prop.Type,
-		Message:      fmt.Sprintf("Field '%s' must be of type %s", propertyName, prop.Type),
-		PropertyPath: fullPath,
-	})
+	if len(prop.Types) > 0 {
+		rules = append(rules, ValidationRule{
+			Type:         "type",
+			Value:        prop.Types,
+			Message:      fmt.Sprintf("Field '%s' must be of type %s", propertyName, strings.Join(prop.Types, " or ")),
+			PropertyPath: fullPath,
+		})
+	} else {
+		rules = append(rules, ValidationRule{
+			Type:         "type",
+			Value:        prop.Type,
+			Message:      fmt.Sprintf("Field '%s' must be of type %s", propertyName, prop.Type),
+			PropertyPath: fullPath,
+		})
+	}
 
 	// Recursively extract rules from nested objects
 	if prop.Type == "object" && prop.Properties != nil {
@@ -496,6 +585,14 @@ func (sp *SchemaParser) validateSingleRule(rule ValidationRule, value interface{
 		return value != nil && value != ""
 
 	case "type":
+		if expectedTypes, ok := rule.Value.([]string); ok {
+			for _, expectedType := range expectedTypes {
+				if sp.checkType(value, expectedType) {
+					return true
+				}
+			}
+			return false
+		}
 		expectedType := rule.Value.(string)
 		return sp.checkType(value, expectedType)
 
@@ -504,28 +601,28 @@ func (sp *SchemaParser) validateSingleRule(rule ValidationRule, value interface{
 			minLen := rule.Value.(int)
 			return len(str) >= minLen
 		}
-		return false
+		return true // non-strings are caught by the type rule
 
 	case "maxLength":
 		if str, ok := value.(string); ok {
 			maxLen := rule.Value.(int)
 			return len(str) <= maxLen
 		}
-		return false
+		return true
 
 	case "minimum":
 		if num, ok := sp.toFloat64(value); ok {
 			min := rule.Value.(float64)
 			return num >= min
 		}
-		return false
+		return true // non-numbers are caught by the type rule
 
 	case "maximum":
 		if num, ok := sp.toFloat64(value); ok {
 			max := rule.Value.(float64)
 			return num <= max
 		}
-		return false
+		return true
 
 	case "enum":
 		enumValues := rule.Value.([]interface{})
@@ -536,6 +633,9 @@ func (sp *SchemaParser) validateSingleRule(rule ValidationRule, value interface{
 		}
 		return false
 
+	case "const":
+		return reflect.DeepEqual(value, rule.Value)
+
 	case "pattern":
 		// Pattern validation would require regex - simplified for now
 		return true
@@ -574,6 +674,8 @@ func (sp *SchemaParser) checkType(value interface{}, expectedType string) bool {
 		return reflect.TypeOf(value).Kind() == reflect.Slice
 	case "object":
 		return reflect.TypeOf(value).Kind() == reflect.Map
+	case "null":
+		return false // nil is handled above
 	default:
 		return true
 	}
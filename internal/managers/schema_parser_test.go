@@ -0,0 +1,12 @@
+package managers
+
+import "testing"
+
+func BenchmarkParseSchema(b *testing.B) {
+	schema := loadBenchSchema(b)
+	for b.Loop() {
+		if _, err := NewSchemaParser(schema).ParseSchema(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
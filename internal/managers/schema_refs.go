@@ -0,0 +1,225 @@
+package managers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"onepagems/internal/types"
+)
+
+// ErrSchemaRef is wrapped by every SchemaRefError
+var ErrSchemaRef = errors.New("schema has an unresolvable $ref")
+
+// SchemaRefError reports a $ref that does not point to a schema in the same
+// document
+type SchemaRefError struct {
+	Path string // dotted path of the schema holding the $ref
+	Ref  string
+}
+
+func (e *SchemaRefError) Error() string {
+	if !strings.HasPrefix(e.Ref, "#") {
+		return fmt.Sprintf("schema at '%s' refers to %q; only local references (\"#/...\") are supported", e.Path, e.Ref)
+	}
+	return fmt.Sprintf("schema at '%s' refers to %q, which does not exist", e.Path, e.Ref)
+}
+
+// Unwrap lets callers test for ErrSchemaRef
+func (e *SchemaRefError) Unwrap() error {
+	return ErrSchemaRef
+}
+
+// schemaRoot returns the root schema as a map that local JSON pointers
+// resolve against, e.g. "#/$defs/address" or "#/properties/hero"
+func schemaRoot(schema *types.SchemaData) map[string]interface{} {
+	root := map[string]interface{}{"properties": schema.Properties}
+	if schema.Defs != nil {
+		root["$defs"] = schema.Defs
+	}
+	if schema.Definitions != nil {
+		root["definitions"] = schema.Definitions
+	}
+	return root
+}
+
+// resolveSchema checks schema and returns a copy with every local $ref
+// replaced by the schema it points to, ready for code that walks properties
+// without knowing about references. The expanded schema is checked against
+// the limits again, since references can multiply its size.
+func resolveSchema(schema *types.SchemaData, limits types.SchemaLimits) (*types.SchemaData, error) {
+	if err := checkSchema(schema, limits); err != nil {
+		return nil, err
+	}
+	resolved, err := ResolveSchemaRefs(schema)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckSchemaLimits(resolved, limits); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// ResolveSchemaRefs returns a copy of schema in which every local $ref,
+// including refs inside $defs and refs to other refs, is replaced by its
+// target. Keywords next to a $ref override the target's, so a property can
+// reuse a definition with its own title. Cyclic schemas fail with a
+// *SchemaCycleError and dangling or remote refs with a *SchemaRefError. The
+// input is not modified; schemas reached by several refs are shared.
+func ResolveSchemaRefs(schema *types.SchemaData) (*types.SchemaData, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	if err := FindSchemaCycle(schema); err != nil {
+		return nil, err
+	}
+
+	r := &refResolver{
+		root:     schemaRoot(schema),
+		resolved: make(map[uintptr]map[string]interface{}),
+	}
+	resolved := *schema
+	var err error
+	if resolved.Properties, err = r.resolveMap(schema.Properties, ""); err != nil {
+		return nil, err
+	}
+	if resolved.Defs, err = r.resolveMap(schema.Defs, "$defs"); err != nil {
+		return nil, err
+	}
+	if resolved.Definitions, err = r.resolveMap(schema.Definitions, "definitions"); err != nil {
+		return nil, err
+	}
+	return &resolved, nil
+}
+
+// refResolver expands $refs, remembering each schema it has already expanded
+type refResolver struct {
+	root     map[string]interface{}
+	resolved map[uintptr]map[string]interface{}
+}
+
+// resolveMap expands every subschema of a map of named subschemas such as
+// properties; path is the dotted path of the map's owner
+func (r *refResolver) resolveMap(named map[string]interface{}, path string) (map[string]interface{}, error) {
+	if named == nil {
+		return nil, nil
+	}
+	out := make(map[string]interface{}, len(named))
+	for _, name := range sortedKeys(named) {
+		child, ok := named[name].(map[string]interface{})
+		if !ok {
+			out[name] = named[name]
+			continue
+		}
+		childPath := name
+		if path != "" {
+			childPath = path + "." + name
+		}
+		expanded, err := r.resolve(child, childPath)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = expanded
+	}
+	return out, nil
+}
+
+// resolve expands the $ref of schema, if any, and those of its subschemas.
+// The caller has ruled out cycles, so this always ends.
+func (r *refResolver) resolve(schema map[string]interface{}, path string) (map[string]interface{}, error) {
+	id := reflect.ValueOf(schema).Pointer()
+	if done, ok := r.resolved[id]; ok {
+		return done, nil
+	}
+
+	out := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		out[key] = value
+	}
+	if err := r.resolveChildren(out, path); err != nil {
+		return nil, err
+	}
+
+	if ref, ok := out["$ref"].(string); ok {
+		target, found := resolveLocalRef(r.root, ref)
+		if !found {
+			return nil, &SchemaRefError{Path: path, Ref: ref}
+		}
+		expanded, err := r.resolve(target, refPath(ref))
+		if err != nil {
+			return nil, err
+		}
+		merged := make(map[string]interface{}, len(expanded)+len(out))
+		for key, value := range expanded {
+			merged[key] = value
+		}
+		for key, value := range out {
+			if key != "$ref" {
+				merged[key] = value
+			}
+		}
+		out = merged
+	}
+
+	r.resolved[id] = out
+	return out, nil
+}
+
+// resolveChildren replaces the subschemas held by schema's keywords with
+// their expanded copies
+func (r *refResolver) resolveChildren(schema map[string]interface{}, path string) error {
+	join := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+
+	for _, keyword := range subschemaMapKeywords {
+		named, ok := schema[keyword].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		owner := join(keyword)
+		if keyword == "properties" {
+			owner = path
+		}
+		expanded, err := r.resolveMap(named, owner)
+		if err != nil {
+			return err
+		}
+		schema[keyword] = expanded
+	}
+	for _, keyword := range subschemaKeywords {
+		if child, ok := schema[keyword].(map[string]interface{}); ok {
+			expanded, err := r.resolve(child, join(keyword))
+			if err != nil {
+				return err
+			}
+			schema[keyword] = expanded
+		}
+	}
+	for _, keyword := range subschemaListKeywords {
+		list, ok := schema[keyword].([]interface{})
+		if !ok {
+			continue
+		}
+		out := make([]interface{}, len(list))
+		for i, item := range list {
+			child, ok := item.(map[string]interface{})
+			if !ok {
+				out[i] = item
+				continue
+			}
+			expanded, err := r.resolve(child, fmt.Sprintf("%s[%d]", join(keyword), i))
+			if err != nil {
+				return err
+			}
+			out[i] = expanded
+		}
+		schema[keyword] = out
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package managers
+
+import (
+	"html/template"
+	"sort"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// maxSampleDepth stops sample generation in deeply nested schemas
+const maxSampleDepth = 16
+
+// SampleContent builds content that fills every public property of the
+// schema, so a template rendered with it touches every field a real page
+// could have. Each value is the property's default, first example, const or
+// first enum value, or else a placeholder matching its type and format.
+// Arrays hold one item.
+func SampleContent(schema *types.SchemaData) map[string]interface{} {
+	sample := make(map[string]interface{})
+	if schema == nil {
+		return sample
+	}
+	for name, value := range schema.Properties {
+		if prop, ok := value.(map[string]interface{}); ok && !isPrivate(prop) {
+			sample[name] = sampleValue(name, prop, 0)
+		}
+	}
+	return sample
+}
+
+// ExampleContent builds content like SampleContent, but with private fields
+// too, as a full request to save content would send
+func ExampleContent(schema *types.SchemaData) map[string]interface{} {
+	example := make(map[string]interface{})
+	if schema == nil {
+		return example
+	}
+	for name, value := range schema.Properties {
+		if prop, ok := value.(map[string]interface{}); ok {
+			example[name] = sampleValueOf(name, prop, 0, true)
+		}
+	}
+	return example
+}
+
+// sampleValue returns a sample value for one public property
+func sampleValue(name string, prop map[string]interface{}, depth int) interface{} {
+	return sampleValueOf(name, prop, depth, false)
+}
+
+// sampleValueOf returns a sample value for one property, leaving out private
+// nested fields unless withPrivate is set
+func sampleValueOf(name string, prop map[string]interface{}, depth int, withPrivate bool) interface{} {
+	if value, ok := prop["default"]; ok {
+		return value
+	}
+	if examples, ok := prop["examples"].([]interface{}); ok && len(examples) > 0 {
+		return examples[0]
+	}
+	if value, ok := prop["const"]; ok {
+		return value
+	}
+	if enum, ok := prop["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+
+	switch primaryType(prop) {
+	case "integer", "number":
+		if minimum, ok := prop["minimum"].(float64); ok {
+			return minimum
+		}
+		return float64(1)
+	case "boolean":
+		return true
+	case "array":
+		items, ok := prop["items"].(map[string]interface{})
+		if !ok || depth >= maxSampleDepth {
+			return []interface{}{}
+		}
+		return []interface{}{sampleValueOf(name, items, depth+1, withPrivate)}
+	case "object":
+		object := make(map[string]interface{})
+		properties, _ := prop["properties"].(map[string]interface{})
+		if depth >= maxSampleDepth {
+			return object
+		}
+		names := make([]string, 0, len(properties))
+		for key := range properties {
+			names = append(names, key)
+		}
+		sort.Strings(names)
+		for _, key := range names {
+			if child, ok := properties[key].(map[string]interface{}); ok && (withPrivate || !isPrivate(child)) {
+				object[key] = sampleValueOf(key, child, depth+1, withPrivate)
+			}
+		}
+		// Image items get a thumbnail when the site is generated
+		if src, ok := object["src"].(string); ok {
+			object["thumbnail"] = src
+		}
+		return object
+	default:
+		return sampleString(name, prop)
+	}
+}
+
+// sampleString returns a placeholder string in the property's format
+func sampleString(name string, prop map[string]interface{}) string {
+	format, _ := prop["format"].(string)
+	switch format {
+	case "email":
+		return "name@example.com"
+	case "uri", "url":
+		return "https://example.com/"
+	case "embed":
+		return "https://www.youtube.com/watch?v=aqz-KE-bpKQ"
+	case "image":
+		return "/images/sample.jpg"
+	case "date":
+		return "2026-01-01"
+	case "date-time", "datetime-local":
+		return "2026-01-01T09:00:00Z"
+	case "time":
+		return "09:00"
+	case "color":
+		return "#336699"
+	case "tel":
+		return "+1 555 0100"
+	}
+	if title, ok := prop["title"].(string); ok && title != "" {
+		return "Sample " + title
+	}
+	return "Sample " + name
+}
+
+// sampleTemplateData wraps sample content in the keys the generator adds to
+// template data, so templates that use them validate
+func sampleTemplateData(content map[string]interface{}) map[string]interface{} {
+	data := make(map[string]interface{}, len(content)+8)
+	for key, value := range content {
+		data[key] = value
+	}
+	anchors := make(map[string]interface{})
+	sectionHTML := make(map[string]template.HTML)
+	if sections, ok := content["sections"].(map[string]interface{}); ok {
+		for key := range sections {
+			anchors[key] = key
+			sectionHTML[key] = template.HTML("<section id=\"" + key + "\"></section>")
+		}
+	}
+	data["anchors"] = anchors
+	data["last_updated"] = time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	data["variant"] = VariantA
+	data["vcard_url"] = "/" + VCardFilename
+	data["vcard_qr"] = "/" + VCardQRFilename
+	data["site_qr"] = "/" + SiteQRFilename
+	data["events_ics"] = "/" + CalendarFilename
+	data["section_html"] = sectionHTML
+	data["sections_html"] = template.HTML("")
+	return data
+}
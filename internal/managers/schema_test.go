@@ -0,0 +1,68 @@
+package managers
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"onepagems/internal/types"
+)
+
+// benchDataDir is the sample site the benchmarks measure against
+const benchDataDir = "../../data"
+
+// seedBenchData copies the sample site into a temporary directory, so a
+// benchmark never changes it, and returns the directory
+func seedBenchData(b *testing.B) string {
+	b.Helper()
+	dir := b.TempDir()
+	entries, err := os.ReadDir(benchDataDir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(benchDataDir, entry.Name()))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, entry.Name()), data, 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// loadBenchSchema returns the schema of the sample site
+func loadBenchSchema(b *testing.B) *types.SchemaData {
+	b.Helper()
+	dir := seedBenchData(b)
+	schema, err := NewSchemaManager(NewFileStorage(dir), dir).LoadSchema()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return schema
+}
+
+// loadBenchContent returns the content of the sample site as the decoded
+// request body validation runs on, a plain map
+func loadBenchContent(b *testing.B) map[string]interface{} {
+	b.Helper()
+	dir := seedBenchData(b)
+	content, err := NewContentManager(NewFileStorage(dir), dir).LoadContent()
+	if err != nil {
+		b.Fatal(err)
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		b.Fatal(err)
+	}
+	contentMap := make(map[string]interface{})
+	if err := json.Unmarshal(data, &contentMap); err != nil {
+		b.Fatal(err)
+	}
+	return contentMap
+}
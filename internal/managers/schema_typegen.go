@@ -0,0 +1,303 @@
+package managers
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Client type export formats
+const (
+	TypeExportTypeScript = "typescript"
+	TypeExportZod        = "zod"
+)
+
+// typeExportHeader opens every generated file
+const typeExportHeader = `// Generated by OnePage CMS from the content schema. Do not edit.
+// Matches the content served at /content.json; private fields are left out.
+`
+
+var tsIdentifierPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// ExportClientTypes generates type definitions for the public content, as
+// TypeScript interfaces (TypeExportTypeScript) or a zod schema
+// (TypeExportZod), so frontends reading /content.json are type checked
+func (sm *SchemaManager) ExportClientTypes(format string) (string, error) {
+	if format != TypeExportTypeScript && format != TypeExportZod {
+		return "", fmt.Errorf("unknown type export format %q: use %q or %q", format, TypeExportTypeScript, TypeExportZod)
+	}
+	schema, err := sm.LoadSchema()
+	if err != nil {
+		return "", err
+	}
+	analysis, err := sm.newParser(schema).ParseSchema()
+	if err != nil {
+		return "", err
+	}
+	gen := &typeGenerator{order: schema.PropertyOrder}
+	root := &ParsedProperty{Type: "object", Properties: analysis.Properties}
+
+	var b strings.Builder
+	b.WriteString(typeExportHeader)
+	b.WriteString("\n")
+	if format == TypeExportZod {
+		b.WriteString("import { z } from \"zod\";\n\n")
+		fmt.Fprintf(&b, "export const SiteContentSchema = %s;\n\n", gen.zod(root, "", 0))
+		b.WriteString("export type SiteContent = z.infer<typeof SiteContentSchema>;\n")
+	} else {
+		fmt.Fprintf(&b, "export interface SiteContent %s\n", gen.typescript(root, "", 0))
+	}
+	return b.String(), nil
+}
+
+// typeGenerator writes type expressions for parsed properties
+type typeGenerator struct {
+	order map[string][]string // schema property order by parent path
+}
+
+// publicKeys returns the non-private property names of an object, in schema
+// file order where known and then by name
+func (g *typeGenerator) publicKeys(prop *ParsedProperty, path string) []string {
+	keys := make([]string, 0, len(prop.Properties))
+	seen := make(map[string]bool, len(prop.Properties))
+	for _, key := range g.order[path] {
+		if nested, ok := prop.Properties[key]; ok && !seen[key] && !isPrivate(nested.Raw) {
+			keys = append(keys, key)
+			seen[key] = true
+		}
+	}
+	rest := make([]string, 0)
+	for key, nested := range prop.Properties {
+		if !seen[key] && !isPrivate(nested.Raw) {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
+// childPath is the property order key of a nested object
+func childPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// tsKey quotes property names that are not identifiers
+func tsKey(key string) string {
+	if tsIdentifierPattern.MatchString(key) {
+		return key
+	}
+	return jsLiteral(key)
+}
+
+// jsLiteral writes a JSON value, which is also a valid JS literal
+func jsLiteral(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "undefined"
+	}
+	return string(data)
+}
+
+// typeNames returns the non-null types of a property
+func typeNames(prop *ParsedProperty) []string {
+	if len(prop.Types) == 0 {
+		if prop.Type == "null" {
+			return nil
+		}
+		return []string{prop.Type}
+	}
+	names := make([]string, 0, len(prop.Types))
+	for _, name := range prop.Types {
+		if name != "null" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// typescript returns the TypeScript type of prop
+func (g *typeGenerator) typescript(prop *ParsedProperty, path string, depth int) string {
+	var parts []string
+	switch {
+	case prop.Const != nil:
+		parts = []string{jsLiteral(prop.Const)}
+	case len(prop.Enum) > 0:
+		for _, value := range prop.Enum {
+			parts = append(parts, jsLiteral(value))
+		}
+	default:
+		for _, name := range typeNames(prop) {
+			parts = append(parts, g.typescriptOf(name, prop, path, depth))
+		}
+	}
+	if len(parts) == 0 {
+		parts = []string{"unknown"}
+	}
+	if prop.Nullable {
+		parts = append(parts, "null")
+	}
+	return strings.Join(parts, " | ")
+}
+
+// typescriptOf returns the TypeScript type for one JSON Schema type name
+func (g *typeGenerator) typescriptOf(name string, prop *ParsedProperty, path string, depth int) string {
+	switch name {
+	case "string":
+		return "string"
+	case "number", "integer":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		if prop.Items == nil {
+			return "unknown[]"
+		}
+		item := g.typescript(prop.Items, path+".items", depth)
+		if strings.Contains(item, " | ") {
+			item = "(" + item + ")"
+		}
+		return item + "[]"
+	case "object":
+		keys := g.publicKeys(prop, path)
+		if len(keys) == 0 {
+			return "Record<string, unknown>"
+		}
+		indent := strings.Repeat("  ", depth+1)
+		var b strings.Builder
+		b.WriteString("{\n")
+		for _, key := range keys {
+			nested := prop.Properties[key]
+			doc := nested.Description
+			if doc == "" {
+				doc = nested.Title
+			}
+			if doc != "" {
+				fmt.Fprintf(&b, "%s/** %s */\n", indent, strings.ReplaceAll(doc, "*/", "* /"))
+			}
+			optional := "?"
+			if nested.Required {
+				optional = ""
+			}
+			fmt.Fprintf(&b, "%s%s%s: %s;\n", indent, tsKey(key), optional, g.typescript(nested, childPath(path, key), depth+1))
+		}
+		b.WriteString(strings.Repeat("  ", depth))
+		b.WriteString("}")
+		return b.String()
+	default:
+		return "unknown"
+	}
+}
+
+// zod returns the zod schema expression of prop
+func (g *typeGenerator) zod(prop *ParsedProperty, path string, depth int) string {
+	var parts []string
+	switch {
+	case prop.Const != nil:
+		parts = []string{"z.literal(" + jsLiteral(prop.Const) + ")"}
+	case len(prop.Enum) > 0:
+		allStrings := true
+		for _, value := range prop.Enum {
+			if _, ok := value.(string); !ok {
+				allStrings = false
+			}
+		}
+		if allStrings {
+			literals := make([]string, len(prop.Enum))
+			for i, value := range prop.Enum {
+				literals[i] = jsLiteral(value)
+			}
+			parts = []string{"z.enum([" + strings.Join(literals, ", ") + "])"}
+		} else {
+			for _, value := range prop.Enum {
+				parts = append(parts, "z.literal("+jsLiteral(value)+")")
+			}
+		}
+	default:
+		for _, name := range typeNames(prop) {
+			parts = append(parts, g.zodOf(name, prop, path, depth))
+		}
+	}
+
+	expr := "z.unknown()"
+	switch len(parts) {
+	case 0:
+	case 1:
+		expr = parts[0]
+	default:
+		expr = "z.union([" + strings.Join(parts, ", ") + "])"
+	}
+	if prop.Nullable {
+		expr += ".nullable()"
+	}
+	return expr
+}
+
+// zodOf returns the zod schema for one JSON Schema type name, with the
+// property's constraints
+func (g *typeGenerator) zodOf(name string, prop *ParsedProperty, path string, depth int) string {
+	switch name {
+	case "string":
+		expr := "z.string()"
+		switch prop.Format {
+		case "email":
+			expr += ".email()"
+		case "uri", "url":
+			expr += ".url()"
+		}
+		if prop.MinLength != nil {
+			expr += fmt.Sprintf(".min(%d)", *prop.MinLength)
+		}
+		if prop.MaxLength != nil {
+			expr += fmt.Sprintf(".max(%d)", *prop.MaxLength)
+		}
+		if prop.Pattern != "" {
+			expr += ".regex(new RegExp(" + jsLiteral(prop.Pattern) + "))"
+		}
+		return expr
+	case "number", "integer":
+		expr := "z.number()"
+		if name == "integer" {
+			expr += ".int()"
+		}
+		if prop.Minimum != nil {
+			expr += ".min(" + jsLiteral(*prop.Minimum) + ")"
+		}
+		if prop.Maximum != nil {
+			expr += ".max(" + jsLiteral(*prop.Maximum) + ")"
+		}
+		return expr
+	case "boolean":
+		return "z.boolean()"
+	case "array":
+		if prop.Items == nil {
+			return "z.array(z.unknown())"
+		}
+		return "z.array(" + g.zod(prop.Items, path+".items", depth) + ")"
+	case "object":
+		keys := g.publicKeys(prop, path)
+		if len(keys) == 0 {
+			return "z.record(z.string(), z.unknown())"
+		}
+		indent := strings.Repeat("  ", depth+1)
+		var b strings.Builder
+		b.WriteString("z.object({\n")
+		for _, key := range keys {
+			nested := prop.Properties[key]
+			expr := g.zod(nested, childPath(path, key), depth+1)
+			if !nested.Required {
+				expr += ".optional()"
+			}
+			fmt.Fprintf(&b, "%s%s: %s,\n", indent, tsKey(key), expr)
+		}
+		b.WriteString(strings.Repeat("  ", depth))
+		b.WriteString("})")
+		return b.String()
+	default:
+		return "z.unknown()"
+	}
+}
@@ -2,7 +2,9 @@ package managers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"onepagems/internal/types"
 	"reflect"
 	"regexp"
@@ -15,6 +17,10 @@ import (
 type SchemaValidator struct {
 	schema *types.SchemaData
 	parser *SchemaParser
+	strict bool
+	limits types.FormLimits
+
+	schemaLimits types.SchemaLimits
 }
 
 // NewSchemaValidator creates a new schema validator
@@ -25,6 +31,32 @@ func NewSchemaValidator(schema *types.SchemaData) *SchemaValidator {
 	}
 }
 
+// SetStrict rejects undeclared fields in every object, even where the schema
+// does not set additionalProperties to false
+func (sv *SchemaValidator) SetStrict(strict bool) {
+	sv.strict = strict
+}
+
+// SetLimits applies the server-wide rich text length and array size limits
+// on top of the schema's own
+func (sv *SchemaValidator) SetLimits(limits types.FormLimits) {
+	sv.limits = limits
+}
+
+// SetSchemaLimits sets the largest schema content is validated against;
+// content checked against a larger schema fails with a schema_too_large error
+// (a cyclic schema fails with schema_cycle)
+func (sv *SchemaValidator) SetSchemaLimits(limits types.SchemaLimits) {
+	sv.schemaLimits = limits
+	sv.parser.SetSchemaLimits(limits)
+}
+
+// rootSystemFields are top-level content fields maintained by the CMS itself
+// rather than declared in the schema
+var rootSystemFields = map[string]bool{
+	"last_updated": true,
+}
+
 // ValidationResult represents the result of content validation
 type ValidationResult struct {
 	Valid      bool                      `json:"valid"`
@@ -42,6 +74,11 @@ type ValidationDetailError struct {
 	Value        interface{} `json:"value,omitempty"`
 	Expected     interface{} `json:"expected,omitempty"`
 	PropertyPath string      `json:"property_path"`
+
+	// Branch names the allOf, anyOf or oneOf branch an error came from, and
+	// Causes holds the branch errors behind a composition error
+	Branch string                  `json:"branch,omitempty"`
+	Causes []ValidationDetailError `json:"causes,omitempty"`
 }
 
 // ValidateContent validates an entire content object against the schema
@@ -52,8 +89,16 @@ func (sv *SchemaValidator) ValidateContent(content interface{}) *ValidationResul
 		Warnings: make([]types.ValidationWarning, 0),
 	}
 
+	// The validator recurses through the schema, so refuse one that is too
+	// large or cyclic before following it, and expand its $refs
+	schema, err := resolveSchema(sv.schema, sv.schemaLimits)
+	if err != nil {
+		sv.addSchemaError(err, result)
+		return result
+	}
+
 	// Validate that content is an object if schema type is object
-	if sv.schema.Type == "object" {
+	if schema.Type == "object" {
 		contentMap, ok := content.(map[string]interface{})
 		if !ok {
 			result.Valid = false
@@ -69,10 +114,14 @@ func (sv *SchemaValidator) ValidateContent(content interface{}) *ValidationResul
 		}
 
 		// Validate each property
-		sv.validateObject(contentMap, "", sv.schema.Properties, result)
+		additional := interface{}(true)
+		if schema.AdditionalProperties != nil {
+			additional = *schema.AdditionalProperties
+		}
+		sv.validateObject(contentMap, "", schema.Properties, additional, result)
 
 		// Check for required fields
-		sv.validateRequiredFields(contentMap, result)
+		sv.validateRequiredFields(contentMap, schema.Properties, result)
 	}
 
 	result.FieldCount = len(result.Errors) + len(result.Warnings)
@@ -86,8 +135,27 @@ func (sv *SchemaValidator) ValidateContent(content interface{}) *ValidationResul
 	return result
 }
 
-// validateObject validates an object and its properties
-func (sv *SchemaValidator) validateObject(obj map[string]interface{}, path string, schemaProps map[string]interface{}, result *ValidationResult) {
+// addSchemaError fails result because the schema itself cannot be used
+func (sv *SchemaValidator) addSchemaError(err error, result *ValidationResult) {
+	code, summary := "schema_too_large", "Schema exceeds size limits"
+	if errors.Is(err, ErrSchemaCycle) {
+		code, summary = "schema_cycle", "Schema contains a cycle"
+	} else if errors.Is(err, ErrSchemaRef) {
+		code, summary = "schema_ref", "Schema has an unresolvable $ref"
+	}
+	result.Valid = false
+	result.Errors = append(result.Errors, ValidationDetailError{
+		Field:   "_schema",
+		Code:    code,
+		Message: err.Error(),
+	})
+	result.Summary = summary
+}
+
+// validateObject validates an object and its properties. additional is the
+// object's additionalProperties keyword: a boolean, or a schema that
+// undeclared fields must match.
+func (sv *SchemaValidator) validateObject(obj map[string]interface{}, path string, schemaProps map[string]interface{}, additional interface{}, result *ValidationResult) {
 	// Validate each field in the object
 	for fieldName, value := range obj {
 		fieldPath := fieldName
@@ -100,40 +168,95 @@ func (sv *SchemaValidator) validateObject(obj map[string]interface{}, path strin
 			if propMap, ok := schemaProp.(map[string]interface{}); ok {
 				sv.validateField(fieldName, value, propMap, fieldPath, result)
 			}
-		} else {
-			// Check if additional properties are allowed
-			// For now, we'll allow additional properties but add a warning
-			result.Warnings = append(result.Warnings, types.ValidationWarning{
-				Field:   fieldPath,
-				Code:    "additional_property",
-				Message: fmt.Sprintf("Field '%s' is not defined in schema but is allowed", fieldName),
-			})
+			continue
+		}
+
+		if path == "" && rootSystemFields[fieldName] {
+			continue
 		}
+
+		sv.validateAdditionalProperty(fieldName, value, path, fieldPath, additional, result)
+	}
+}
+
+// validateAdditionalProperty handles a field the object's schema does not
+// declare. Fields are checked against an additionalProperties schema when one
+// is given; otherwise they are an error when the object disallows additional
+// properties or the validator is strict, and a warning when allowed.
+func (sv *SchemaValidator) validateAdditionalProperty(fieldName string, value interface{}, objectPath, fieldPath string, additional interface{}, result *ValidationResult) {
+	allowed, isBool := additional.(bool)
+	if additionalSchema, ok := additional.(map[string]interface{}); ok {
+		sv.validateField(fieldName, value, additionalSchema, fieldPath, result)
+		return
 	}
+
+	if sv.strict || (isBool && !allowed) {
+		object := objectPath
+		if object == "" {
+			object = "content"
+		}
+		result.Valid = false
+		result.Errors = append(result.Errors, ValidationDetailError{
+			Field:        fieldName,
+			Code:         "additional_property",
+			Message:      fmt.Sprintf("Field '%s' is not defined in schema and is not allowed in '%s'", fieldName, object),
+			Value:        value,
+			PropertyPath: fieldPath,
+		})
+		return
+	}
+
+	result.Warnings = append(result.Warnings, types.ValidationWarning{
+		Field:   fieldPath,
+		Code:    "additional_property",
+		Message: fmt.Sprintf("Field '%s' is not defined in schema but is allowed", fieldName),
+	})
 }
 
 // validateField validates a single field against its schema definition
 func (sv *SchemaValidator) validateField(fieldName string, value interface{}, schemaProp map[string]interface{}, fieldPath string, result *ValidationResult) {
-	// Get field type
-	fieldType := "string" // default
-	if propType, ok := schemaProp["type"].(string); ok {
-		fieldType = propType
+	// Get field type. A type union matches if any member matches, and the
+	// matching type decides which constraints apply.
+	typeNames, nullable := parseSchemaType(schemaProp)
+	if len(typeNames) == 0 && !nullable {
+		typeNames = []string{"string"} // default
+		// A schema made of allOf/anyOf/oneOf/not leaves the type to them
+		if hasComposition(schemaProp) && value != nil {
+			typeNames = []string{sv.jsonTypeOf(value)}
+		}
+	}
+
+	fieldType := ""
+	for _, typeName := range typeNames {
+		if sv.validateType(value, typeName) {
+			fieldType = typeName
+			break
+		}
 	}
 
 	// Type validation
-	if !sv.validateType(value, fieldType) {
+	if value != nil && fieldType == "" {
+		expected := strings.Join(typeNames, " or ")
+		if nullable {
+			expected = strings.Join(append(typeNames, "null"), " or ")
+		}
 		result.Valid = false
 		result.Errors = append(result.Errors, ValidationDetailError{
 			Field:        fieldName,
 			Code:         "invalid_type",
-			Message:      fmt.Sprintf("Field '%s' must be of type %s", fieldName, fieldType),
+			Message:      fmt.Sprintf("Field '%s' must be of type %s", fieldName, expected),
 			Value:        value,
-			Expected:     fieldType,
+			Expected:     expected,
 			PropertyPath: fieldPath,
 		})
 		return // Skip further validation if type is wrong
 	}
 
+	// An explicit null satisfies a nullable field without further checks
+	if value == nil && nullable {
+		return
+	}
+
 	// String validations
 	if fieldType == "string" && value != nil {
 		sv.validateStringField(fieldName, value, schemaProp, fieldPath, result)
@@ -154,11 +277,21 @@ func (sv *SchemaValidator) validateField(fieldName string, value interface{}, sc
 		sv.validateNestedObject(fieldName, value, schemaProp, fieldPath, result)
 	}
 
+	// Composition validation
+	if hasComposition(schemaProp) {
+		sv.validateComposition(fieldName, value, schemaProp, fieldPath, result)
+	}
+
 	// Enum validation
 	if enumValues, ok := schemaProp["enum"].([]interface{}); ok && len(enumValues) > 0 {
 		sv.validateEnum(fieldName, value, enumValues, fieldPath, result)
 	}
 
+	// Const validation
+	if constValue, ok := schemaProp["const"]; ok {
+		sv.validateConst(fieldName, value, constValue, fieldPath, result)
+	}
+
 	// Format validation
 	if format, ok := schemaProp["format"].(string); ok && format != "" {
 		sv.validateFormat(fieldName, value, format, fieldPath, result)
@@ -220,15 +353,15 @@ func (sv *SchemaValidator) validateStringField(fieldName string, value interface
 	}
 
 	// MaxLength validation
-	if maxLength, ok := schemaProp["maxLength"].(float64); ok {
-		if len(str) > int(maxLength) {
+	if maxLength := maxLengthFor(schemaProp, sv.limits); maxLength > 0 {
+		if len(str) > maxLength {
 			result.Valid = false
 			result.Errors = append(result.Errors, ValidationDetailError{
 				Field:        fieldName,
 				Code:         "max_length",
-				Message:      fmt.Sprintf("Field '%s' must be at most %d characters", fieldName, int(maxLength)),
+				Message:      fmt.Sprintf("Field '%s' must be at most %d characters", fieldName, maxLength),
 				Value:        len(str),
-				Expected:     int(maxLength),
+				Expected:     maxLength,
 				PropertyPath: fieldPath,
 			})
 		}
@@ -304,7 +437,8 @@ func (sv *SchemaValidator) validateNumberField(fieldName string, value interface
 
 	// MultipleOf validation
 	if multipleOf, ok := schemaProp["multipleOf"].(float64); ok && multipleOf > 0 {
-		if remainder := num / multipleOf; remainder != float64(int64(remainder)) {
+		// Allow for binary rounding, so 19.99 is a multiple of 0.01
+		if quotient := num / multipleOf; math.Abs(quotient-math.Round(quotient)) > 1e-9 {
 			result.Valid = false
 			result.Errors = append(result.Errors, ValidationDetailError{
 				Field:        fieldName,
@@ -343,15 +477,15 @@ func (sv *SchemaValidator) validateArrayField(fieldName string, value interface{
 	}
 
 	// MaxItems validation
-	if maxItems, ok := schemaProp["maxItems"].(float64); ok {
-		if arrayLen > int(maxItems) {
+	if maxItems := maxItemsFor(schemaProp, sv.limits); maxItems > 0 {
+		if arrayLen > maxItems {
 			result.Valid = false
 			result.Errors = append(result.Errors, ValidationDetailError{
 				Field:        fieldName,
 				Code:         "max_items",
-				Message:      fmt.Sprintf("Field '%s' must have at most %d items", fieldName, int(maxItems)),
+				Message:      fmt.Sprintf("Field '%s' must have at most %d items", fieldName, maxItems),
 				Value:        arrayLen,
-				Expected:     int(maxItems),
+				Expected:     maxItems,
 				PropertyPath: fieldPath,
 			})
 		}
@@ -378,6 +512,11 @@ func (sv *SchemaValidator) validateArrayField(fieldName string, value interface{
 		}
 	}
 
+	// Contains validation
+	if contains, ok := schemaProp["contains"].(map[string]interface{}); ok {
+		sv.validateContains(fieldName, arr, contains, schemaProp, fieldPath, result)
+	}
+
 	// Validate array items against items schema
 	if items, ok := schemaProp["items"].(map[string]interface{}); ok {
 		for i := 0; i < arrayLen; i++ {
@@ -395,9 +534,49 @@ func (sv *SchemaValidator) validateNestedObject(fieldName string, value interfac
 		return
 	}
 
-	// Get nested properties
-	if properties, ok := schemaProp["properties"].(map[string]interface{}); ok {
-		sv.validateObject(objMap, fieldPath, properties, result)
+	// Property count validation
+	if minProperties, ok := schemaProp["minProperties"].(float64); ok {
+		if len(objMap) < int(minProperties) {
+			result.Valid = false
+			result.Errors = append(result.Errors, ValidationDetailError{
+				Field:        fieldName,
+				Code:         "min_properties",
+				Message:      fmt.Sprintf("Field '%s' must have at least %d properties", fieldName, int(minProperties)),
+				Value:        len(objMap),
+				Expected:     int(minProperties),
+				PropertyPath: fieldPath,
+			})
+		}
+	}
+
+	if maxProperties, ok := schemaProp["maxProperties"].(float64); ok {
+		if len(objMap) > int(maxProperties) {
+			result.Valid = false
+			result.Errors = append(result.Errors, ValidationDetailError{
+				Field:        fieldName,
+				Code:         "max_properties",
+				Message:      fmt.Sprintf("Field '%s' must have at most %d properties", fieldName, int(maxProperties)),
+				Value:        len(objMap),
+				Expected:     int(maxProperties),
+				PropertyPath: fieldPath,
+			})
+		}
+	}
+
+	// Property name validation
+	if propertyNames, ok := schemaProp["propertyNames"].(map[string]interface{}); ok {
+		sv.validatePropertyNames(fieldName, objMap, propertyNames, fieldPath, result)
+	}
+
+	// Get nested properties. Objects that declare neither properties nor
+	// additionalProperties are free-form and only checked in strict mode.
+	properties, hasProperties := schemaProp["properties"].(map[string]interface{})
+	additional, hasAdditional := schemaProp["additionalProperties"]
+	if !hasAdditional {
+		additional = true
+	}
+	if hasProperties || hasAdditional || sv.strict {
+		sv.validateObject(objMap, fieldPath, properties, additional, result)
 	}
 
 	// Validate required fields for this nested object
@@ -418,6 +597,118 @@ func (sv *SchemaValidator) validateNestedObject(fieldName string, value interfac
 	}
 }
 
+// validatePropertyNames validates every key of an object against the
+// propertyNames schema
+func (sv *SchemaValidator) validatePropertyNames(fieldName string, obj map[string]interface{}, namesSchema map[string]interface{}, fieldPath string, result *ValidationResult) {
+	for key := range obj {
+		keyResult := sv.validateAgainst(key, key, namesSchema, fieldPath+"."+key)
+		if keyResult.Valid {
+			continue
+		}
+
+		result.Valid = false
+		result.Errors = append(result.Errors, ValidationDetailError{
+			Field:        fieldName,
+			Code:         "property_names",
+			Message:      fmt.Sprintf("Field '%s' has an invalid property name '%s': %s", fieldName, key, keyResult.Errors[0].Message),
+			Value:        key,
+			PropertyPath: fieldPath + "." + key,
+		})
+	}
+}
+
+// validateContains checks how many array items match the contains schema.
+// At least minContains (default 1) and at most maxContains items must match.
+func (sv *SchemaValidator) validateContains(fieldName string, arr reflect.Value, contains map[string]interface{}, schemaProp map[string]interface{}, fieldPath string, result *ValidationResult) {
+	matches := 0
+	for i := 0; i < arr.Len(); i++ {
+		itemPath := fmt.Sprintf("%s[%d]", fieldPath, i)
+		if sv.validateAgainst(fieldName, arr.Index(i).Interface(), contains, itemPath).Valid {
+			matches++
+		}
+	}
+
+	minContains := 1
+	if value, ok := schemaProp["minContains"].(float64); ok {
+		minContains = int(value)
+	}
+
+	if matches < minContains {
+		code := "contains"
+		message := fmt.Sprintf("Field '%s' must contain at least one matching item", fieldName)
+		if _, ok := schemaProp["minContains"]; ok {
+			code = "min_contains"
+			message = fmt.Sprintf("Field '%s' must contain at least %d matching items", fieldName, minContains)
+		}
+		result.Valid = false
+		result.Errors = append(result.Errors, ValidationDetailError{
+			Field:        fieldName,
+			Code:         code,
+			Message:      message,
+			Value:        matches,
+			Expected:     minContains,
+			PropertyPath: fieldPath,
+		})
+	}
+
+	if maxContains, ok := schemaProp["maxContains"].(float64); ok && matches > int(maxContains) {
+		result.Valid = false
+		result.Errors = append(result.Errors, ValidationDetailError{
+			Field:        fieldName,
+			Code:         "max_contains",
+			Message:      fmt.Sprintf("Field '%s' must contain at most %d matching items", fieldName, int(maxContains)),
+			Value:        matches,
+			Expected:     int(maxContains),
+			PropertyPath: fieldPath,
+		})
+	}
+}
+
+// validateAgainst validates value against a subschema without touching the
+// caller's result, for keywords that only need to know whether it matches
+func (sv *SchemaValidator) validateAgainst(fieldName string, value interface{}, subschema map[string]interface{}, fieldPath string) *ValidationResult {
+	result := &ValidationResult{
+		Valid:    true,
+		Errors:   make([]ValidationDetailError, 0),
+		Warnings: make([]types.ValidationWarning, 0),
+	}
+	sv.validateField(fieldName, value, subschema, fieldPath, result)
+	return result
+}
+
+// validateConst validates that value equals the schema's const value
+func (sv *SchemaValidator) validateConst(fieldName string, value interface{}, constValue interface{}, fieldPath string, result *ValidationResult) {
+	if sv.valuesEqual(value, constValue) {
+		return
+	}
+
+	result.Valid = false
+	result.Errors = append(result.Errors, ValidationDetailError{
+		Field:        fieldName,
+		Code:         "const",
+		Message:      fmt.Sprintf("Field '%s' must be %v", fieldName, constValue),
+		Value:        value,
+		Expected:     constValue,
+		PropertyPath: fieldPath,
+	})
+}
+
+// valuesEqual compares JSON values, treating numbers of different Go types
+// as equal when they have the same value
+func (sv *SchemaValidator) valuesEqual(a, b interface{}) bool {
+	if _, isString := a.(string); !isString {
+		if numA, ok := sv.toFloat64(a); ok {
+			if _, isString := b.(string); !isString {
+				if numB, ok := sv.toFloat64(b); ok {
+					return numA == numB
+				}
+			}
+			return false
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
 // validateEnum validates that value is one of the allowed enum values
 func (sv *SchemaValidator) validateEnum(fieldName string, value interface{}, enumValues []interface{}, fieldPath string, result *ValidationResult) {
 	for _, enumValue := range enumValues {
@@ -481,6 +772,30 @@ func (sv *SchemaValidator) validateFormat(fieldName string, value interface{}, f
 				PropertyPath: fieldPath,
 			})
 		}
+	case "timezone":
+		if !sv.isValidTimezone(str) {
+			result.Valid = false
+			result.Errors = append(result.Errors, ValidationDetailError{
+				Field:        fieldName,
+				Code:         "format_timezone",
+				Message:      fmt.Sprintf("Field '%s' must be an IANA time zone such as Europe/London", fieldName),
+				Value:        str,
+				Expected:     "IANA time zone name",
+				PropertyPath: fieldPath,
+			})
+		}
+	case "embed":
+		if _, err := ParseEmbedURL(str); err != nil {
+			result.Valid = false
+			result.Errors = append(result.Errors, ValidationDetailError{
+				Field:        fieldName,
+				Code:         "format_embed",
+				Message:      fmt.Sprintf("Field '%s': %s", fieldName, err.Error()),
+				Value:        str,
+				Expected:     "YouTube, Vimeo, Google Maps or OpenStreetMap URL",
+				PropertyPath: fieldPath,
+			})
+		}
 	case "uri":
 		if !sv.isValidURI(str) {
 			result.Valid = false
@@ -551,9 +866,9 @@ func (sv *SchemaValidator) validatePattern(fieldName string, value interface{},
 }
 
 // validateRequiredFields checks that all required fields are present
-func (sv *SchemaValidator) validateRequiredFields(content map[string]interface{}, result *ValidationResult) {
+func (sv *SchemaValidator) validateRequiredFields(content map[string]interface{}, schemaProps map[string]interface{}, result *ValidationResult) {
 	// Check required fields defined at property level
-	for propName, propData := range sv.schema.Properties {
+	for propName, propData := range schemaProps {
 		if propMap, ok := propData.(map[string]interface{}); ok {
 			if required, ok := propMap["required"].(bool); ok && required {
 				if _, exists := content[propName]; !exists {
@@ -578,11 +893,26 @@ func (sv *SchemaValidator) ValidateFieldValue(fieldName string, value interface{
 		Warnings: make([]types.ValidationWarning, 0),
 	}
 
+	schema, err := resolveSchema(sv.schema, sv.schemaLimits)
+	if err != nil {
+		sv.addSchemaError(err, result)
+		return result
+	}
+
 	// Find the field schema
-	if schemaProp, exists := sv.schema.Properties[fieldName]; exists {
+	if schemaProp, exists := schema.Properties[fieldName]; exists {
 		if propMap, ok := schemaProp.(map[string]interface{}); ok {
 			sv.validateField(fieldName, value, propMap, fieldName, result)
 		}
+	} else if sv.strict || (schema.AdditionalProperties != nil && !*schema.AdditionalProperties) {
+		result.Valid = false
+		result.Errors = append(result.Errors, ValidationDetailError{
+			Field:        fieldName,
+			Code:         "unknown_field",
+			Message:      fmt.Sprintf("Field '%s' is not defined in schema", fieldName),
+			Value:        value,
+			PropertyPath: fieldName,
+		})
 	} else {
 		result.Warnings = append(result.Warnings, types.ValidationWarning{
 			Field:   fieldName,
@@ -684,6 +1014,15 @@ func (sv *SchemaValidator) isValidDateTime(datetime string) bool {
 	return err == nil
 }
 
+func (sv *SchemaValidator) isValidTimezone(zone string) bool {
+	// "Local" would resolve to the server's zone rather than a fixed one
+	if zone == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(zone)
+	return err == nil
+}
+
 func (sv *SchemaValidator) isValidURI(uri string) bool {
 	// Basic URI validation - contains scheme and host
 	uriRegex := `^[a-zA-Z][a-zA-Z0-9+.-]*://[^\s]+$`
@@ -0,0 +1,18 @@
+package managers
+
+import "testing"
+
+func BenchmarkValidateContent(b *testing.B) {
+	schema := loadBenchSchema(b)
+	content := loadBenchContent(b)
+	for b.Loop() {
+		NewSchemaValidator(schema).ValidateContent(content)
+	}
+}
+
+func BenchmarkValidateField(b *testing.B) {
+	schema := loadBenchSchema(b)
+	for b.Loop() {
+		NewSchemaValidator(schema).ValidateFieldValue("title", "A new title")
+	}
+}
@@ -0,0 +1,157 @@
+package managers
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SearchIndexFilename is the client-side search index served at /search.json
+const SearchIndexFilename = "search.json"
+
+// Limits keep the index small enough to fetch on first keystroke
+const (
+	searchExcerptLength = 160
+	searchTextLength    = 2000
+)
+
+var (
+	htmlTagPattern    = regexp.MustCompile(`<[^>]*>`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// SearchIndex lists the sections of the published page with their text
+type SearchIndex struct {
+	GeneratedAt time.Time     `json:"generated_at"`
+	Sections    []SearchEntry `json:"sections"`
+}
+
+// SearchEntry is one section of the page. Position is its order on the
+// page, from 0; Anchor is the id to jump to.
+type SearchEntry struct {
+	Key      string `json:"key"`
+	Anchor   string `json:"anchor"`
+	Title    string `json:"title,omitempty"`
+	Excerpt  string `json:"excerpt"`
+	Text     string `json:"text"`
+	Position int    `json:"position"`
+}
+
+// BuildSearchIndex indexes the sections that appear in the rendered page,
+// in page order. A section is found by its anchor id; sections the template
+// does not render, or renders without an anchor, are left out.
+func BuildSearchIndex(sections map[string]interface{}, anchors map[string]string, html string) *SearchIndex {
+	type located struct {
+		entry  SearchEntry
+		offset int
+	}
+
+	found := make([]located, 0, len(sections))
+	for key, section := range sections {
+		anchor := anchors[key]
+		if anchor == "" {
+			continue
+		}
+		offset := strings.Index(html, `id="`+anchor+`"`)
+		if offset < 0 {
+			continue
+		}
+
+		entry := SearchEntry{Key: key, Anchor: anchor}
+		body := section
+		if fields, ok := section.(map[string]interface{}); ok {
+			if title, ok := fields["title"].(string); ok {
+				entry.Title = plainText(title)
+			}
+			body = withoutKey(fields, "title")
+		}
+		text := strings.Join(collectText(body, nil), " ")
+		entry.Text = truncateText(text, searchTextLength)
+		entry.Excerpt = truncateText(text, searchExcerptLength)
+		found = append(found, located{entry: entry, offset: offset})
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].offset < found[j].offset
+	})
+
+	index := &SearchIndex{GeneratedAt: time.Now(), Sections: make([]SearchEntry, len(found))}
+	for i, item := range found {
+		item.entry.Position = i
+		index.Sections[i] = item.entry
+	}
+	return index
+}
+
+// collectText gathers the readable strings of a section in key order,
+// skipping links, image paths and other values that are not prose
+func collectText(value interface{}, texts []string) []string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			texts = collectText(v[key], texts)
+		}
+	case []interface{}:
+		for _, item := range v {
+			texts = collectText(item, texts)
+		}
+	case string:
+		if isProse(v) {
+			if text := plainText(v); text != "" {
+				texts = append(texts, text)
+			}
+		}
+	}
+	return texts
+}
+
+// withoutKey returns a shallow copy of fields without key
+func withoutKey(fields map[string]interface{}, key string) map[string]interface{} {
+	copied := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if k != key {
+			copied[k] = v
+		}
+	}
+	return copied
+}
+
+// isProse reports whether a string is text rather than a URL, path or date
+func isProse(value string) bool {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" || strings.HasPrefix(trimmed, "/") || strings.HasPrefix(trimmed, "#") {
+		return false
+	}
+	if strings.Contains(trimmed, "://") || strings.HasPrefix(trimmed, "mailto:") || strings.HasPrefix(trimmed, "tel:") {
+		return false
+	}
+	if _, err := time.Parse(time.RFC3339, trimmed); err == nil {
+		return false
+	}
+	return true
+}
+
+// plainText strips HTML tags and collapses whitespace
+func plainText(value string) string {
+	text := htmlTagPattern.ReplaceAllString(value, " ")
+	text = unescapeAttr(text)
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(text, " "))
+}
+
+// truncateText shortens text to at most limit bytes at a word boundary
+func truncateText(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	cut := truncateRunes(text, limit)
+	if space := strings.LastIndex(cut, " "); space > limit/2 {
+		cut = cut[:space]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
@@ -0,0 +1,294 @@
+package managers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secretsFilename stores the encrypted integration credentials
+const secretsFilename = "secrets.json"
+
+// Well-known secret names read by built-in integrations
+const (
+	SecretAltTextProviderKey = "alt_text_provider_key"
+	SecretSMTPPassword       = "smtp_password"
+	SecretMatrixAccessToken  = "matrix_access_token"
+)
+
+// maxSecretSize bounds a single secret value
+const maxSecretSize = 8 * 1024
+
+var secretNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// Secret store failures
+var (
+	ErrSecretsDisabled = errors.New("secrets storage is not configured; set SECRETS_KEY or SECRETS_KEY_FILE")
+	ErrSecretNotFound  = errors.New("secret not found")
+	ErrSecretsWrongKey = errors.New("secrets file was encrypted with a different key")
+	ErrSecretInvalid   = errors.New("invalid secret")
+)
+
+// SecretInfo describes a stored secret without its value
+type SecretInfo struct {
+	Name      string    `json:"name"`
+	Size      int       `json:"size"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+}
+
+// secretsFile is the on-disk form. Each value is sealed on its own with
+// AES-256-GCM, using its name as additional data so values cannot be
+// swapped between names.
+type secretsFile struct {
+	KeyID   string                  `json:"key_id"`
+	Secrets map[string]sealedSecret `json:"secrets"`
+}
+
+type sealedSecret struct {
+	Nonce      string    `json:"nonce"`
+	Ciphertext string    `json:"ciphertext"`
+	Size       int       `json:"size"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	UpdatedBy  string    `json:"updated_by,omitempty"`
+}
+
+// SecretManager stores third-party credentials encrypted at rest. Values
+// can be read by integrations inside the process but are never returned by
+// the API.
+type SecretManager struct {
+	storage *FileStorage
+	aead    cipher.AEAD
+	keyID   string
+	master  []byte // the key itself, for DeriveKey
+	mu      sync.Mutex
+}
+
+// NewSecretManager creates a secret manager. key is a base64 encoded
+// 32-byte key; when it is empty the manager is disabled and every
+// operation returns ErrSecretsDisabled.
+func NewSecretManager(storage *FileStorage, key string) (*SecretManager, error) {
+	sm := &SecretManager{storage: storage}
+	if err := sm.SetKey(key); err != nil {
+		return nil, err
+	}
+	return sm, nil
+}
+
+// SetKey replaces the key, as NewSecretManager takes it, for a manager
+// created before the key was read
+func (sm *SecretManager) SetKey(key string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if key == "" {
+		sm.aead, sm.keyID, sm.master = nil, "", nil
+		return nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return fmt.Errorf("invalid secrets key: %w", err)
+	}
+	if len(raw) != 32 {
+		return fmt.Errorf("invalid secrets key: expected 32 bytes, got %d", len(raw))
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	sm.aead, sm.keyID, sm.master = aead, hmacFingerprint(raw), raw
+	return nil
+}
+
+// DeriveKey returns a 32-byte key for purpose derived from the secrets key,
+// so other keys survive restarts without being stored, and false when no
+// secrets key is configured. Each purpose gets an unrelated key.
+func (sm *SecretManager) DeriveKey(purpose string) ([]byte, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.master == nil {
+		return nil, false
+	}
+	mac := hmac.New(sha256.New, sm.master)
+	mac.Write([]byte("onepagems derived key: " + purpose))
+	return mac.Sum(nil), true
+}
+
+// ReadSecretsKey returns the secrets key from SECRETS_KEY, or from the file
+// named by SECRETS_KEY_FILE, as written by Docker secrets, systemd
+// credentials or a keyring helper
+func ReadSecretsKey(key, keyFile string) (string, error) {
+	if key != "" || keyFile == "" {
+		return key, nil
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secrets key file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Enabled reports whether a key is configured
+func (sm *SecretManager) Enabled() bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.aead != nil
+}
+
+// List returns the stored secrets by name, without values
+func (sm *SecretManager) List() ([]SecretInfo, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	file, err := sm.load()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]SecretInfo, 0, len(file.Secrets))
+	for name, sealed := range file.Secrets {
+		infos = append(infos, SecretInfo{Name: name, Size: sealed.Size, UpdatedAt: sealed.UpdatedAt, UpdatedBy: sealed.UpdatedBy})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos, nil
+}
+
+// Get decrypts a secret for use by an integration
+func (sm *SecretManager) Get(name string) (string, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	file, err := sm.load()
+	if err != nil {
+		return "", err
+	}
+	sealed, ok := file.Secrets[name]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(sealed.Nonce)
+	if err != nil {
+		return "", fmt.Errorf("secret %s is corrupt: %w", name, err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(sealed.Ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("secret %s is corrupt: %w", name, err)
+	}
+	plaintext, err := sm.aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("secret %s could not be decrypted", name)
+	}
+	return string(plaintext), nil
+}
+
+// Set encrypts and stores a secret, replacing any previous value
+func (sm *SecretManager) Set(name, value, username string) (*SecretInfo, error) {
+	if !secretNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("%w: name must be 1-64 lowercase letters, digits, '_', '.' or '-'", ErrSecretInvalid)
+	}
+	if value == "" {
+		return nil, fmt.Errorf("%w: value is required", ErrSecretInvalid)
+	}
+	if len(value) > maxSecretSize {
+		return nil, fmt.Errorf("%w: value exceeds %d bytes", ErrSecretInvalid, maxSecretSize)
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	file, err := sm.load()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, sm.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := sealedSecret{
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(sm.aead.Seal(nil, nonce, []byte(value), []byte(name))),
+		Size:       len(value),
+		UpdatedAt:  time.Now(),
+		UpdatedBy:  username,
+	}
+	file.Secrets[name] = sealed
+
+	if err := sm.save(file); err != nil {
+		return nil, err
+	}
+	return &SecretInfo{Name: name, Size: sealed.Size, UpdatedAt: sealed.UpdatedAt, UpdatedBy: sealed.UpdatedBy}, nil
+}
+
+// Delete removes a secret
+func (sm *SecretManager) Delete(name string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	file, err := sm.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := file.Secrets[name]; !ok {
+		return ErrSecretNotFound
+	}
+	delete(file.Secrets, name)
+
+	return sm.save(file)
+}
+
+// save writes the secrets file readable by the owner only
+func (sm *SecretManager) save(file *secretsFile) error {
+	if err := sm.storage.WriteJSONFile(secretsFilename, file); err != nil {
+		return fmt.Errorf("failed to save secrets: %w", err)
+	}
+	for _, path := range []string{secretsFilename, secretsFilename + ".bak"} {
+		if sm.storage.FileExists(path) {
+			os.Chmod(sm.storage.GetFilePath(path), 0600)
+		}
+	}
+	return nil
+}
+
+// load reads the secrets file, checking it belongs to the configured key;
+// callers hold mu
+func (sm *SecretManager) load() (*secretsFile, error) {
+	if sm.aead == nil {
+		return nil, ErrSecretsDisabled
+	}
+
+	file := &secretsFile{KeyID: sm.keyID, Secrets: map[string]sealedSecret{}}
+	if !sm.storage.FileExists(secretsFilename) {
+		return file, nil
+	}
+	if err := sm.storage.ReadJSONFile(secretsFilename, file); err != nil {
+		return nil, fmt.Errorf("failed to load secrets: %w", err)
+	}
+	if file.KeyID != sm.keyID {
+		return nil, ErrSecretsWrongKey
+	}
+	if file.Secrets == nil {
+		file.Secrets = map[string]sealedSecret{}
+	}
+	return file, nil
+}
@@ -0,0 +1,251 @@
+package managers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testSecretsKey returns a base64 secrets key made of one repeated byte
+func testSecretsKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func newTestSecretManager(t *testing.T, dir, key string) *SecretManager {
+	t.Helper()
+	sm, err := NewSecretManager(NewFileStorage(dir), key)
+	if err != nil {
+		t.Fatalf("NewSecretManager: %v", err)
+	}
+	return sm
+}
+
+func TestNewSecretManagerRejectsInvalidKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"not base64", "not a key!"},
+		{"too short", base64.StdEncoding.EncodeToString(make([]byte, 16))},
+		{"too long", base64.StdEncoding.EncodeToString(make([]byte, 33))},
+		{"unpadded", strings.TrimRight(testSecretsKey(1), "=")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSecretManager(NewFileStorage(t.TempDir()), tt.key); err == nil || !strings.Contains(err.Error(), "invalid secrets key") {
+				t.Errorf("NewSecretManager error %v, want an invalid key", err)
+			}
+		})
+	}
+	// Surrounding whitespace, as left by a key file, is ignored
+	newTestSecretManager(t, t.TempDir(), " "+testSecretsKey(1)+"\n")
+}
+
+func TestSecretManagerDisabled(t *testing.T) {
+	sm := newTestSecretManager(t, t.TempDir(), "")
+	if sm.Enabled() {
+		t.Fatal("a manager without a key is enabled")
+	}
+	if _, err := sm.List(); !errors.Is(err, ErrSecretsDisabled) {
+		t.Errorf("List: %v", err)
+	}
+	if _, err := sm.Get("smtp_password"); !errors.Is(err, ErrSecretsDisabled) {
+		t.Errorf("Get: %v", err)
+	}
+	if _, err := sm.Set("smtp_password", "hunter2", "admin"); !errors.Is(err, ErrSecretsDisabled) {
+		t.Errorf("Set: %v", err)
+	}
+	if err := sm.Delete("smtp_password"); !errors.Is(err, ErrSecretsDisabled) {
+		t.Errorf("Delete: %v", err)
+	}
+	if _, ok := sm.DeriveKey("csrf"); ok {
+		t.Error("DeriveKey returned a key without a secrets key")
+	}
+
+	// Setting the key later enables it
+	if err := sm.SetKey(testSecretsKey(1)); err != nil || !sm.Enabled() {
+		t.Fatalf("SetKey: %v, enabled %v", err, sm.Enabled())
+	}
+}
+
+func TestSecretManagerRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	sm := newTestSecretManager(t, dir, testSecretsKey(1))
+
+	info, err := sm.Set(SecretSMTPPassword, "hunter2", "admin")
+	if err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if info.Name != SecretSMTPPassword || info.Size != len("hunter2") || info.UpdatedBy != "admin" {
+		t.Errorf("Set returned %+v", info)
+	}
+	if _, err := sm.Set(SecretMatrixAccessToken, "token", "admin"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if _, err := sm.Set(SecretSMTPPassword, "correct horse", "editor"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// A new manager with the same key reads what the first one wrote
+	reopened := newTestSecretManager(t, dir, testSecretsKey(1))
+	value, err := reopened.Get(SecretSMTPPassword)
+	if err != nil || value != "correct horse" {
+		t.Fatalf("Get = %q, %v", value, err)
+	}
+	infos, err := reopened.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Name != SecretMatrixAccessToken || infos[1].Name != SecretSMTPPassword || infos[1].UpdatedBy != "editor" {
+		t.Errorf("List = %+v", infos)
+	}
+
+	if err := reopened.Delete(SecretMatrixAccessToken); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := reopened.Get(SecretMatrixAccessToken); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Get after Delete: %v", err)
+	}
+	if err := reopened.Delete(SecretMatrixAccessToken); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("second Delete: %v", err)
+	}
+
+	// Values are encrypted at rest, in files only the owner can read
+	for _, name := range []string{secretsFilename, secretsFilename + ".bak"} {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte("hunter2")) || bytes.Contains(data, []byte("correct horse")) {
+			t.Errorf("%s holds a secret in plain text", name)
+		}
+		if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+			t.Errorf("%s mode is %v, want 0600", name, fi.Mode().Perm())
+		}
+	}
+}
+
+func TestSecretManagerWrongKey(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := newTestSecretManager(t, dir, testSecretsKey(1)).Set(SecretSMTPPassword, "hunter2", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	other := newTestSecretManager(t, dir, testSecretsKey(2))
+	if _, err := other.Get(SecretSMTPPassword); !errors.Is(err, ErrSecretsWrongKey) {
+		t.Errorf("Get: %v, want ErrSecretsWrongKey", err)
+	}
+	// Nothing is overwritten with the wrong key either
+	if _, err := other.Set(SecretSMTPPassword, "other", "admin"); !errors.Is(err, ErrSecretsWrongKey) {
+		t.Errorf("Set: %v, want ErrSecretsWrongKey", err)
+	}
+}
+
+func TestSecretManagerBindsValuesToNames(t *testing.T) {
+	dir := t.TempDir()
+	sm := newTestSecretManager(t, dir, testSecretsKey(1))
+	sm.Set("first", "one", "admin")
+	sm.Set("second", "two", "admin")
+
+	// Swap the sealed values on disk
+	storage := NewFileStorage(dir)
+	var file secretsFile
+	if err := storage.ReadJSONFile(secretsFilename, &file); err != nil {
+		t.Fatal(err)
+	}
+	file.Secrets["first"], file.Secrets["second"] = file.Secrets["second"], file.Secrets["first"]
+	if err := storage.WriteJSONFile(secretsFilename, file); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err := sm.Get("first"); err == nil {
+		t.Errorf("Get decrypted a swapped value: %q", value)
+	}
+}
+
+func TestSecretManagerSetValidation(t *testing.T) {
+	sm := newTestSecretManager(t, t.TempDir(), testSecretsKey(1))
+	tests := []struct {
+		name      string
+		secret    string
+		value     string
+		wantValid bool
+	}{
+		{"well-known name", SecretAltTextProviderKey, "key", true},
+		{"dots and dashes", "smtp.backup-password", "key", true},
+		{"largest value", "large", strings.Repeat("x", maxSecretSize), true},
+		{"empty name", "", "key", false},
+		{"uppercase name", "SMTP_PASSWORD", "key", false},
+		{"leading dot", ".hidden", "key", false},
+		{"path in name", "../auth", "key", false},
+		{"name too long", strings.Repeat("a", 65), "key", false},
+		{"empty value", "empty", "", false},
+		{"value too large", "huge", strings.Repeat("x", maxSecretSize+1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := sm.Set(tt.secret, tt.value, "admin")
+			if tt.wantValid && err != nil {
+				t.Errorf("Set: %v", err)
+			}
+			if !tt.wantValid && !errors.Is(err, ErrSecretInvalid) {
+				t.Errorf("Set error %v, want ErrSecretInvalid", err)
+			}
+		})
+	}
+}
+
+func TestReadSecretsKey(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "secrets.key")
+	if err := os.WriteFile(keyFile, []byte(testSecretsKey(2)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		key     string
+		keyFile string
+		want    string
+		wantErr bool
+	}{
+		{"neither", "", "", "", false},
+		{"key", testSecretsKey(1), "", testSecretsKey(1), false},
+		{"key file, trimmed", "", keyFile, testSecretsKey(2), false},
+		{"key wins over the file", testSecretsKey(1), keyFile, testSecretsKey(1), false},
+		{"missing key file", "", keyFile + ".missing", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadSecretsKey(tt.key, tt.keyFile)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ReadSecretsKey = %q, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestSecretManagerDeriveKey(t *testing.T) {
+	first := newTestSecretManager(t, t.TempDir(), testSecretsKey(1))
+	again := newTestSecretManager(t, t.TempDir(), testSecretsKey(1))
+	other := newTestSecretManager(t, t.TempDir(), testSecretsKey(2))
+
+	csrf, ok := first.DeriveKey("csrf")
+	if !ok || len(csrf) != 32 {
+		t.Fatalf("DeriveKey = %x, %v", csrf, ok)
+	}
+	if key, _ := again.DeriveKey("csrf"); !bytes.Equal(key, csrf) {
+		t.Error("the same secrets key derived different keys")
+	}
+	if key, _ := first.DeriveKey("sessions"); bytes.Equal(key, csrf) {
+		t.Error("two purposes derived the same key")
+	}
+	if key, _ := other.DeriveKey("csrf"); bytes.Equal(key, csrf) {
+		t.Error("two secrets keys derived the same key")
+	}
+	if raw, _ := base64.StdEncoding.DecodeString(testSecretsKey(1)); bytes.Equal(csrf, raw) {
+		t.Error("DeriveKey returned the secrets key itself")
+	}
+}
@@ -0,0 +1,173 @@
+package managers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// archivedSectionsFilename keeps sections taken off the page
+const archivedSectionsFilename = "archived_sections.json"
+
+// Section archive errors, mapped to HTTP statuses by the server
+var (
+	ErrSectionNotFound = errors.New("section not found")
+	ErrSectionExists   = errors.New("a section with this key already exists")
+	ErrSectionRequired = errors.New("the schema requires this section")
+)
+
+// ArchivedSection is a section removed from the page but kept for later
+type ArchivedSection struct {
+	Key        string      `json:"key"`
+	Section    interface{} `json:"section"`
+	ArchivedAt time.Time   `json:"archived_at"`
+	ArchivedBy string      `json:"archived_by,omitempty"`
+}
+
+// ArchivedSections lists archived sections, most recently archived first
+func (cm *ContentManager) ArchivedSections() ([]ArchivedSection, error) {
+	archive, err := cm.loadSectionArchive()
+	if err != nil {
+		return nil, err
+	}
+
+	sections := make([]ArchivedSection, 0, len(archive))
+	for _, section := range archive {
+		sections = append(sections, section)
+	}
+	sort.Slice(sections, func(i, j int) bool {
+		return sections[i].ArchivedAt.After(sections[j].ArchivedAt)
+	})
+	return sections, nil
+}
+
+// ArchiveSection moves a section out of the content into the archive, so it
+// is no longer generated or edited. An archived section with the same key
+// is replaced.
+func (cm *ContentManager) ArchiveSection(key, username string) (*ArchivedSection, error) {
+	content, err := cm.LoadContent()
+	if err != nil {
+		return nil, err
+	}
+	section, exists := content.Sections[key]
+	if !exists {
+		return nil, ErrSectionNotFound
+	}
+
+	archive, err := cm.loadSectionArchive()
+	if err != nil {
+		return nil, err
+	}
+	archived := ArchivedSection{Key: key, Section: section, ArchivedAt: time.Now(), ArchivedBy: username}
+	archive[key] = archived
+	if err := cm.storage.WriteJSONFile(archivedSectionsFilename, archive); err != nil {
+		return nil, fmt.Errorf("failed to save archived sections: %w", err)
+	}
+
+	delete(content.Sections, key)
+	if err := cm.SaveContent(content); err != nil {
+		// Keep the content authoritative: the section is still live
+		delete(archive, key)
+		cm.storage.WriteJSONFile(archivedSectionsFilename, archive)
+		return nil, err
+	}
+	return &archived, nil
+}
+
+// RestoreSection puts an archived section back into the content. It fails
+// with ErrSectionExists if a live section has taken its key meanwhile.
+func (cm *ContentManager) RestoreSection(key string) (*ArchivedSection, error) {
+	archive, err := cm.loadSectionArchive()
+	if err != nil {
+		return nil, err
+	}
+	archived, exists := archive[key]
+	if !exists {
+		return nil, ErrSectionNotFound
+	}
+
+	content, err := cm.LoadContent()
+	if err != nil {
+		return nil, err
+	}
+	if _, taken := content.Sections[key]; taken {
+		return nil, ErrSectionExists
+	}
+	if content.Sections == nil {
+		content.Sections = make(map[string]interface{})
+	}
+	content.Sections[key] = archived.Section
+	if err := cm.SaveContent(content); err != nil {
+		return nil, err
+	}
+
+	delete(archive, key)
+	if err := cm.storage.WriteJSONFile(archivedSectionsFilename, archive); err != nil {
+		return nil, fmt.Errorf("section restored but the archive was not updated: %w", err)
+	}
+	return &archived, nil
+}
+
+// DeleteArchivedSection permanently removes an archived section
+func (cm *ContentManager) DeleteArchivedSection(key string) error {
+	archive, err := cm.loadSectionArchive()
+	if err != nil {
+		return err
+	}
+	if _, exists := archive[key]; !exists {
+		return ErrSectionNotFound
+	}
+	delete(archive, key)
+	return cm.storage.WriteJSONFile(archivedSectionsFilename, archive)
+}
+
+// IsSectionArchived reports whether a section key is in the archive
+func (cm *ContentManager) IsSectionArchived(key string) bool {
+	archive, err := cm.loadSectionArchive()
+	if err != nil {
+		return false
+	}
+	_, exists := archive[key]
+	return exists
+}
+
+// loadSectionArchive reads the archive, keyed by section key
+func (cm *ContentManager) loadSectionArchive() (map[string]ArchivedSection, error) {
+	archive := make(map[string]ArchivedSection)
+	if !cm.storage.FileExists(archivedSectionsFilename) {
+		return archive, nil
+	}
+	if err := cm.storage.ReadJSONFile(archivedSectionsFilename, &archive); err != nil {
+		return nil, fmt.Errorf("failed to read archived sections: %w", err)
+	}
+	return archive, nil
+}
+
+// SectionRequired reports whether the schema requires a section, which then
+// cannot be archived without making the content invalid
+func (sm *SchemaManager) SectionRequired(key string) bool {
+	schema, err := sm.LoadSchema()
+	if err != nil {
+		return false
+	}
+	sections, ok := schema.Properties["sections"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	if required, ok := sections["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name == key {
+				return true
+			}
+		}
+	}
+	if properties, ok := sections["properties"].(map[string]interface{}); ok {
+		if prop, ok := properties[key].(map[string]interface{}); ok {
+			if required, _ := prop["required"].(bool); required {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,303 @@
+package managers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// SectionPreset is a ready-made section schema that can be added to the
+// content schema under any key
+type SectionPreset struct {
+	Name        string                 `json:"name"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Schema      map[string]interface{} `json:"schema"`
+}
+
+// sectionPresets builds each built-in preset. Functions are used so every
+// caller gets its own copy of the schema to modify.
+var sectionPresets = map[string]func() SectionPreset{
+	"event":   eventPreset,
+	"gallery": galleryPreset,
+	"pricing": pricingPreset,
+}
+
+// PricingCurrencies are the ISO 4217 codes a pricing plan may use
+var PricingCurrencies = []interface{}{"USD", "EUR", "GBP", "JPY", "CAD", "AUD", "CHF", "CNY", "HKD", "SGD", "INR"}
+
+// sectionKeyPattern restricts section keys to names usable in templates
+var sectionKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// SectionPresets returns the built-in section presets sorted by name
+func SectionPresets() []SectionPreset {
+	presets := make([]SectionPreset, 0, len(sectionPresets))
+	for _, build := range sectionPresets {
+		presets = append(presets, build())
+	}
+	sort.Slice(presets, func(i, j int) bool {
+		return presets[i].Name < presets[j].Name
+	})
+	return presets
+}
+
+// GetSectionPreset returns the named section preset
+func GetSectionPreset(name string) (SectionPreset, bool) {
+	build, ok := sectionPresets[name]
+	if !ok {
+		return SectionPreset{}, false
+	}
+	return build(), true
+}
+
+// AddSectionPreset adds the named preset to the schema as sections.<key>.
+// The key defaults to the preset name and must not already be in use.
+func (sm *SchemaManager) AddSectionPreset(presetName, key string) error {
+	preset, ok := GetSectionPreset(presetName)
+	if !ok {
+		return fmt.Errorf("unknown section preset: %s", presetName)
+	}
+
+	if key == "" {
+		key = preset.Name
+	}
+	if !sectionKeyPattern.MatchString(key) {
+		return fmt.Errorf("section key must start with a letter and contain only lowercase letters, digits and underscores")
+	}
+
+	schema, err := sm.LoadSchema()
+	if err != nil {
+		return fmt.Errorf("failed to load schema: %w", err)
+	}
+
+	sections, ok := schema.Properties["sections"].(map[string]interface{})
+	if !ok {
+		sections = map[string]interface{}{
+			"type":  "object",
+			"title": "Content Sections",
+		}
+		schema.Properties["sections"] = sections
+	}
+	properties, ok := sections["properties"].(map[string]interface{})
+	if !ok {
+		properties = make(map[string]interface{})
+		sections["properties"] = properties
+	}
+
+	if _, exists := properties[key]; exists {
+		return fmt.Errorf("section %s already exists", key)
+	}
+
+	sectionSchema := preset.Schema
+	sectionSchema["x-preset"] = preset.Name
+	properties[key] = sectionSchema
+
+	return sm.SaveSchema(schema)
+}
+
+// eventPreset is a dated event with a location, an RSVP link and an optional
+// countdown. Dates must carry a UTC offset; the time zone controls how they
+// are displayed.
+func eventPreset() SectionPreset {
+	return SectionPreset{
+		Name:        "event",
+		Title:       "Event",
+		Description: "An event with time zone aware start and end times, location, RSVP link and countdown",
+		Schema: map[string]interface{}{
+			"type":        "object",
+			"title":       "Event Section",
+			"description": "Upcoming event details",
+			"properties": map[string]interface{}{
+				"title": map[string]interface{}{
+					"type":      "string",
+					"title":     "Event Name",
+					"minLength": 1,
+					"maxLength": 120,
+					"required":  true,
+				},
+				"description": map[string]interface{}{
+					"type":   "string",
+					"title":  "Event Description",
+					"format": "textarea",
+				},
+				"start": map[string]interface{}{
+					"type":        "string",
+					"title":       "Starts",
+					"description": "RFC3339 date-time with offset, e.g. 2026-05-01T18:00:00+02:00",
+					"format":      "date-time",
+					"required":    true,
+				},
+				"end": map[string]interface{}{
+					"type":        "string",
+					"title":       "Ends",
+					"description": "RFC3339 date-time with offset, e.g. 2026-05-01T22:00:00+02:00",
+					"format":      "date-time",
+				},
+				"timezone": map[string]interface{}{
+					"type":        "string",
+					"title":       "Time Zone",
+					"description": "IANA time zone the times are shown in, e.g. Europe/Berlin",
+					"format":      "timezone",
+				},
+				"location": map[string]interface{}{
+					"type":  "string",
+					"title": "Location",
+				},
+				"address": map[string]interface{}{
+					"type":  "string",
+					"title": "Address",
+				},
+				"rsvp_link": map[string]interface{}{
+					"type":   "string",
+					"title":  "RSVP Link",
+					"format": "uri",
+				},
+				"rsvp_text": map[string]interface{}{
+					"type":    "string",
+					"title":   "RSVP Button Text",
+					"default": "RSVP",
+				},
+				"countdown": map[string]interface{}{
+					"type":        "boolean",
+					"title":       "Show Countdown",
+					"description": "Count down to the start time in the visitor's browser",
+				},
+			},
+		},
+	}
+}
+
+// galleryPreset is an image gallery with captions, shown as a grid or a
+// carousel
+func galleryPreset() SectionPreset {
+	return SectionPreset{
+		Name:        "gallery",
+		Title:       "Gallery",
+		Description: "Images with captions, shown as a grid or carousel with lightbox support",
+		Schema: map[string]interface{}{
+			"type":        "object",
+			"title":       "Gallery Section",
+			"description": "Image gallery with captions",
+			"properties": map[string]interface{}{
+				"title": map[string]interface{}{
+					"type":      "string",
+					"title":     "Gallery Title",
+					"maxLength": 100,
+				},
+				"layout": map[string]interface{}{
+					"type":    "string",
+					"title":   "Layout",
+					"enum":    []interface{}{"grid", "carousel"},
+					"default": "grid",
+				},
+				"images": map[string]interface{}{
+					"type":  "array",
+					"title": "Images",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"src": map[string]interface{}{
+								"type":     "string",
+								"title":    "Image",
+								"format":   "image",
+								"required": true,
+							},
+							"caption": map[string]interface{}{
+								"type":      "string",
+								"title":     "Caption",
+								"maxLength": 200,
+							},
+							"alt": map[string]interface{}{
+								"type":        "string",
+								"title":       "Alt Text",
+								"description": "Describes the image for screen readers; defaults to the caption",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// pricingPreset is a table of plans with prices, features and an optional
+// highlighted plan
+func pricingPreset() SectionPreset {
+	return SectionPreset{
+		Name:        "pricing",
+		Title:       "Pricing",
+		Description: "Plans with a price, currency, billing period and feature list",
+		Schema: map[string]interface{}{
+			"type":        "object",
+			"title":       "Pricing Section",
+			"description": "Pricing plans",
+			"properties": map[string]interface{}{
+				"title": map[string]interface{}{
+					"type":      "string",
+					"title":     "Pricing Title",
+					"maxLength": 100,
+				},
+				"plans": map[string]interface{}{
+					"type":     "array",
+					"title":    "Plans",
+					"minItems": 1,
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"name": map[string]interface{}{
+								"type":      "string",
+								"title":     "Plan Name",
+								"minLength": 1,
+								"maxLength": 60,
+								"required":  true,
+							},
+							"price": map[string]interface{}{
+								"type":        "number",
+								"title":       "Price",
+								"description": "Amount in the plan's currency, up to two decimal places",
+								"minimum":     0,
+								"multipleOf":  0.01,
+								"required":    true,
+							},
+							"currency": map[string]interface{}{
+								"type":     "string",
+								"title":    "Currency",
+								"enum":     PricingCurrencies,
+								"default":  "USD",
+								"required": true,
+							},
+							"period": map[string]interface{}{
+								"type":    "string",
+								"title":   "Billing Period",
+								"enum":    []interface{}{"month", "year", "one-time"},
+								"default": "month",
+							},
+							"features": map[string]interface{}{
+								"type":  "array",
+								"title": "Features",
+								"items": map[string]interface{}{
+									"type":      "string",
+									"maxLength": 120,
+								},
+							},
+							"highlight": map[string]interface{}{
+								"type":        "boolean",
+								"title":       "Highlight",
+								"description": "Feature this plan as the recommended one",
+							},
+							"button_text": map[string]interface{}{
+								"type":  "string",
+								"title": "Button Text",
+							},
+							"button_link": map[string]interface{}{
+								"type":   "string",
+								"title":  "Button Link",
+								"format": "url",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,287 @@
+package managers
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// sectionRenderersFilename stores the section renderers registered on top
+// of the built-in ones
+const sectionRenderersFilename = "section_renderers.json"
+
+// SectionRenderer renders one content section as an HTML fragment. Source
+// is a Go template whose dot holds .key (the section key), .anchor (its
+// anchor id), .section (the section content) and .site (the whole template
+// data), so sections written for different themes can sit on one page.
+type SectionRenderer struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Source      string    `json:"source"`
+	BuiltIn     bool      `json:"built_in,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+// builtInSectionRenderers are the renderers every site has, matching the
+// sections of the default template and the section presets
+var builtInSectionRenderers = map[string]SectionRenderer{
+	"hero": {
+		Name:        "hero",
+		Description: "Full-width banner with a title, subtitle and button",
+		Source: `{{with .section}}<section class="hero" id="{{$.anchor}}">
+    <div class="container">
+        <h1>{{.title}}</h1>
+        {{if .subtitle}}<p>{{.subtitle}}</p>{{end}}
+        {{if .button_text}}<a href="{{.button_link}}" class="btn">{{.button_text}}</a>{{end}}
+    </div>
+</section>{{end}}`,
+	},
+	"about": {
+		Name:        "about",
+		Description: "Heading, text and an optional image and video",
+		Source: `{{with .section}}<section class="section" id="{{$.anchor}}">
+    <div class="container">
+        {{if .title}}<h2>{{.title}}</h2>{{end}}
+        <div class="about-content">
+            <div class="about-text"><p>{{.content}}</p></div>
+            {{if .image}}<div class="about-image"><img src="{{.image}}" alt="{{.title}}"></div>{{end}}
+        </div>
+        {{if .video}}{{embed .video}}{{end}}
+    </div>
+</section>{{end}}`,
+	},
+	"gallery": {
+		Name:        "gallery",
+		Description: "Image grid that opens full images in a lightbox",
+		Source: `{{with .section}}<section class="section" id="{{$.anchor}}">
+    <div class="container">
+        {{if .title}}<h2>{{.title}}</h2>{{end}}
+        <div class="gallery gallery-{{or .layout "grid"}}" data-lightbox-group="{{$.key}}">
+            {{range .images}}
+            <figure class="gallery-item">
+                <a href="{{.src}}" data-lightbox="{{$.key}}" data-caption="{{.caption}}">
+                    <img src="{{or .thumbnail .src}}" alt="{{or .alt .caption}}" loading="lazy" decoding="async">
+                </a>
+                {{if .caption}}<figcaption>{{.caption}}</figcaption>{{end}}
+            </figure>
+            {{end}}
+        </div>
+    </div>
+</section>{{end}}`,
+	},
+	"pricing": {
+		Name:        "pricing",
+		Description: "Pricing plans as cards",
+		Source: `{{with .section}}<section class="section" id="{{$.anchor}}">
+    <div class="container">
+        {{if .title}}<h2>{{.title}}</h2>{{end}}
+        <div class="pricing-grid">
+            {{range .plans}}
+            <div class="pricing-card{{if .highlight}} highlight{{end}}">
+                <h3>{{.name}}</h3>
+                <p class="price">{{.price}} {{.currency}}{{if .period}}{{if ne .period "one-time"}} <small>/ {{.period}}</small>{{end}}{{end}}</p>
+                {{if .features}}<ul>{{range .features}}<li>{{.}}</li>{{end}}</ul>{{end}}
+                {{if .button_text}}<a href="{{or .button_link "#contact"}}" class="btn">{{.button_text}}</a>{{end}}
+            </div>
+            {{end}}
+        </div>
+    </div>
+</section>{{end}}`,
+	},
+}
+
+// SectionRendererManager keeps the named section renderers and renders
+// content sections with them
+type SectionRendererManager struct {
+	storage *FileStorage
+}
+
+// NewSectionRendererManager creates a new section renderer manager
+func NewSectionRendererManager(storage *FileStorage) *SectionRendererManager {
+	return &SectionRendererManager{storage: storage}
+}
+
+// loadCustom returns the registered renderers by name
+func (rm *SectionRendererManager) loadCustom() (map[string]SectionRenderer, error) {
+	renderers := make(map[string]SectionRenderer)
+	if !rm.storage.FileExists(sectionRenderersFilename) {
+		return renderers, nil
+	}
+	if err := rm.storage.ReadJSONFile(sectionRenderersFilename, &renderers); err != nil {
+		return nil, fmt.Errorf("failed to load section renderers: %w", err)
+	}
+	return renderers, nil
+}
+
+// List returns every renderer sorted by name. A registered renderer
+// replaces the built-in one of the same name.
+func (rm *SectionRendererManager) List() ([]SectionRenderer, error) {
+	renderers, err := rm.all()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]SectionRenderer, 0, len(renderers))
+	for _, renderer := range renderers {
+		list = append(list, renderer)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// all returns the built-in renderers overlaid with the registered ones
+func (rm *SectionRendererManager) all() (map[string]SectionRenderer, error) {
+	custom, err := rm.loadCustom()
+	if err != nil {
+		return nil, err
+	}
+	renderers := make(map[string]SectionRenderer, len(builtInSectionRenderers)+len(custom))
+	for name, renderer := range builtInSectionRenderers {
+		renderer.BuiltIn = true
+		renderers[name] = renderer
+	}
+	for name, renderer := range custom {
+		renderers[name] = renderer
+	}
+	return renderers, nil
+}
+
+// Register stores a renderer under name, replacing any renderer of that
+// name until it is removed again
+func (rm *SectionRendererManager) Register(name, description, source string) (*SectionRenderer, error) {
+	if !sectionKeyPattern.MatchString(name) {
+		return nil, fmt.Errorf("renderer name must start with a letter and contain only lowercase letters, digits and underscores")
+	}
+	if strings.TrimSpace(source) == "" {
+		return nil, fmt.Errorf("renderer source cannot be empty")
+	}
+	if _, err := template.New(name).Funcs(siteTemplateFuncs(func(src string) string { return src }, time.UTC)).Parse(source); err != nil {
+		return nil, fmt.Errorf("template parsing failed: %w", err)
+	}
+
+	custom, err := rm.loadCustom()
+	if err != nil {
+		return nil, err
+	}
+	renderer := SectionRenderer{Name: name, Description: description, Source: source, UpdatedAt: time.Now()}
+	custom[name] = renderer
+	if err := rm.storage.WriteJSONFile(sectionRenderersFilename, custom); err != nil {
+		return nil, fmt.Errorf("failed to save section renderers: %w", err)
+	}
+	return &renderer, nil
+}
+
+// Remove deletes a registered renderer. A built-in renderer it replaced is
+// used again.
+func (rm *SectionRendererManager) Remove(name string) error {
+	custom, err := rm.loadCustom()
+	if err != nil {
+		return err
+	}
+	if _, ok := custom[name]; !ok {
+		return fmt.Errorf("section renderer %s is not registered", name)
+	}
+	delete(custom, name)
+	if err := rm.storage.WriteJSONFile(sectionRenderersFilename, custom); err != nil {
+		return fmt.Errorf("failed to save section renderers: %w", err)
+	}
+	return nil
+}
+
+// RenderedSections are the sections of a page rendered by their renderers
+type RenderedSections struct {
+	// HTML is every rendered section in schema order
+	HTML template.HTML
+	// ByKey is each rendered section by section key
+	ByKey map[string]template.HTML
+}
+
+// Render renders the sections in data["sections"], in the order the schema
+// declares them and then by key. Each section uses the renderer named by
+// its schema's x-renderer, else its x-preset, else its key; sections
+// without a renderer are left out.
+func (rm *SectionRendererManager) Render(schema *types.SchemaData, data map[string]interface{}, funcs template.FuncMap) (*RenderedSections, error) {
+	renderers, err := rm.all()
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := &RenderedSections{ByKey: make(map[string]template.HTML)}
+	sections, _ := data["sections"].(map[string]interface{})
+	anchors, _ := data["anchors"].(map[string]string)
+
+	var html strings.Builder
+	for _, key := range sectionRenderOrder(schema, sections) {
+		name := sectionRendererName(schema, key)
+		renderer, ok := renderers[name]
+		if !ok {
+			continue
+		}
+		tmpl, err := template.New(name).Funcs(funcs).Parse(renderer.Source)
+		if err != nil {
+			return nil, fmt.Errorf("section renderer %s: %w", name, err)
+		}
+
+		anchor := key
+		if value, ok := anchors[key]; ok {
+			anchor = value
+		}
+		var buf strings.Builder
+		err = tmpl.Execute(&buf, map[string]interface{}{
+			"key":     key,
+			"anchor":  anchor,
+			"section": sections[key],
+			"site":    data,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("section renderer %s failed for section %s: %w", name, key, err)
+		}
+		rendered.ByKey[key] = template.HTML(buf.String())
+		html.WriteString(buf.String())
+		html.WriteString("\n")
+	}
+	rendered.HTML = template.HTML(html.String())
+	return rendered, nil
+}
+
+// sectionRenderOrder lists the keys of sections with content, those the
+// schema declares first in their declared order
+func sectionRenderOrder(schema *types.SchemaData, sections map[string]interface{}) []string {
+	keys := make([]string, 0, len(sections))
+	seen := make(map[string]bool, len(sections))
+	if schema != nil {
+		for _, key := range schema.PropertyOrder["sections"] {
+			if _, ok := sections[key]; ok && !seen[key] {
+				keys = append(keys, key)
+				seen[key] = true
+			}
+		}
+	}
+	rest := make([]string, 0, len(sections))
+	for key := range sections {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
+// sectionRendererName returns the renderer a section uses
+func sectionRendererName(schema *types.SchemaData, key string) string {
+	if schema == nil {
+		return key
+	}
+	sections, _ := schema.Properties["sections"].(map[string]interface{})
+	properties, _ := sections["properties"].(map[string]interface{})
+	prop, _ := properties[key].(map[string]interface{})
+	for _, keyword := range []string{"x-renderer", "x-preset"} {
+		if name, ok := prop[keyword].(string); ok && name != "" {
+			return name
+		}
+	}
+	return key
+}
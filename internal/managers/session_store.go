@@ -0,0 +1,98 @@
+package managers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// sessionsFilename stores the admin sessions between restarts
+const sessionsFilename = "sessions.json"
+
+// SessionStore keeps sessions between restarts. Sessions are keyed by
+// SessionKey of their ID, so a store never holds an ID that could be used
+// to sign in; restored sessions have an empty ID until it is presented.
+type SessionStore interface {
+	Load() (map[string]*types.Session, error)
+	Save(sessions map[string]*types.Session) error
+}
+
+// SessionKey is the key a session is stored under: a hash of its ID
+func SessionKey(sessionID string) string {
+	hash := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(hash[:])
+}
+
+// storedSession is a session as written to sessions.json
+type storedSession struct {
+	Username        string    `json:"username"`
+	CreatedAt       time.Time `json:"created_at"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	LastSeenAt      time.Time `json:"last_seen_at,omitzero"`
+	AuthenticatedAt time.Time `json:"authenticated_at,omitzero"`
+	Locked          bool      `json:"locked,omitempty"`
+}
+
+// FileSessionStore keeps sessions in sessions.json in the data directory
+type FileSessionStore struct {
+	storage *FileStorage
+}
+
+// NewFileSessionStore creates a session store backed by sessions.json
+func NewFileSessionStore(storage *FileStorage) *FileSessionStore {
+	return &FileSessionStore{storage: storage}
+}
+
+// Load reads the stored sessions, skipping expired ones
+func (fs *FileSessionStore) Load() (map[string]*types.Session, error) {
+	sessions := make(map[string]*types.Session)
+	if !fs.storage.FileExists(sessionsFilename) {
+		return sessions, nil
+	}
+
+	var stored map[string]storedSession
+	if err := fs.storage.ReadJSONFile(sessionsFilename, &stored); err != nil {
+		return nil, fmt.Errorf("failed to load sessions: %w", err)
+	}
+	now := time.Now()
+	for key, s := range stored {
+		if now.After(s.ExpiresAt) {
+			continue
+		}
+		sessions[key] = &types.Session{
+			Username:        s.Username,
+			CreatedAt:       s.CreatedAt,
+			ExpiresAt:       s.ExpiresAt,
+			IsActive:        true,
+			LastSeenAt:      s.LastSeenAt,
+			AuthenticatedAt: s.AuthenticatedAt,
+			Locked:          s.Locked,
+		}
+	}
+	return sessions, nil
+}
+
+// Save replaces the stored sessions with the active ones in sessions
+func (fs *FileSessionStore) Save(sessions map[string]*types.Session) error {
+	stored := make(map[string]storedSession, len(sessions))
+	for key, s := range sessions {
+		if !s.IsActive {
+			continue
+		}
+		stored[key] = storedSession{
+			Username:        s.Username,
+			CreatedAt:       s.CreatedAt,
+			ExpiresAt:       s.ExpiresAt,
+			LastSeenAt:      s.LastSeenAt,
+			AuthenticatedAt: s.AuthenticatedAt,
+			Locked:          s.Locked,
+		}
+	}
+	if err := fs.storage.WriteJSONFile(sessionsFilename, stored); err != nil {
+		return fmt.Errorf("failed to save sessions: %w", err)
+	}
+	return nil
+}
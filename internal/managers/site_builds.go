@@ -0,0 +1,238 @@
+package managers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Site builds are kept in the data directory, one file per build, with the
+// ID of the build the output directory currently holds
+const (
+	siteBuildsDir      = "builds"
+	siteBuildsLiveFile = "builds/live.json"
+	siteBuildIDLayout  = "20060102-150405.000"
+)
+
+// siteBuildPages are the generated files a build keeps besides assets
+var siteBuildPages = []string{"index.html", policyFilename, PublicContentFilename, VariantPageFilename, variantPolicyFilename}
+
+// Site build errors
+var (
+	ErrBuildNotFound   = errors.New("build not found")
+	ErrNoPreviousBuild = errors.New("no earlier build to roll back to")
+)
+
+// SiteBuild is a generated page kept for rollback: index.html with its
+// content security policy, extracted assets and published content
+type SiteBuild struct {
+	ID          string            `json:"id"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Size        int64             `json:"size"`
+	Files       map[string]string `json:"files,omitempty"`
+}
+
+// SiteBuildInfo describes a kept build without its files
+type SiteBuildInfo struct {
+	ID          string    `json:"id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Size        int64     `json:"size"`
+	Live        bool      `json:"live"`
+}
+
+// siteBuildsLive records which build is live and when it was rolled back to
+type siteBuildsLive struct {
+	ID           string     `json:"id"`
+	RolledBackAt *time.Time `json:"rolled_back_at,omitempty"`
+}
+
+// SetBuildHistory sets how many generated builds are kept for rollback;
+// 0 keeps none
+func (g *SiteGenerator) SetBuildHistory(n int) {
+	g.buildHistory = max(n, 0)
+}
+
+// Builds lists kept builds, newest first
+func (g *SiteGenerator) Builds() ([]SiteBuildInfo, error) {
+	ids, err := g.buildIDs()
+	if err != nil {
+		return nil, err
+	}
+	live := g.liveBuild()
+
+	builds := make([]SiteBuildInfo, 0, len(ids))
+	for _, id := range ids {
+		build, err := g.loadBuild(id)
+		if err != nil {
+			return nil, err
+		}
+		builds = append(builds, SiteBuildInfo{ID: build.ID, GeneratedAt: build.GeneratedAt, Size: build.Size, Live: build.ID == live.ID})
+	}
+	return builds, nil
+}
+
+// Rollback writes a kept build back to the output directory, making it the
+// live site without touching content. An empty id picks the build before
+// the live one.
+func (g *SiteGenerator) Rollback(id string) (*SiteBuildInfo, error) {
+	if id == "" {
+		ids, err := g.buildIDs()
+		if err != nil {
+			return nil, err
+		}
+		live := g.liveBuild().ID
+		for i, candidate := range ids {
+			if candidate == live && i+1 < len(ids) {
+				id = ids[i+1]
+				break
+			}
+		}
+		if id == "" {
+			return nil, ErrNoPreviousBuild
+		}
+	}
+
+	build, err := g.loadBuild(id)
+	if err != nil {
+		return nil, err
+	}
+
+	assets := make(map[string]string)
+	for name, body := range build.Files {
+		switch {
+		case strings.HasPrefix(name, "assets/"):
+			assets[name] = body
+		case name == "index.html":
+			if err := g.output.WriteTextFile(name, body); err != nil {
+				return nil, fmt.Errorf("failed to write index.html: %w", err)
+			}
+		default:
+			if err := g.output.WriteBinaryFile(name, []byte(body)); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", name, err)
+			}
+		}
+	}
+	if err := g.writeAssets(g.output, assets); err != nil {
+		return nil, err
+	}
+	// A build without an A/B test must not leave the current B page live
+	for _, name := range siteBuildPages {
+		if _, kept := build.Files[name]; !kept && g.output.FileExists(name) {
+			g.output.DeleteFile(name)
+		}
+	}
+
+	now := time.Now()
+	if err := g.storage.WriteJSONFile(siteBuildsLiveFile, siteBuildsLive{ID: build.ID, RolledBackAt: &now}); err != nil {
+		return nil, fmt.Errorf("failed to record live build: %w", err)
+	}
+	return &SiteBuildInfo{ID: build.ID, GeneratedAt: build.GeneratedAt, Size: build.Size, Live: true}, nil
+}
+
+// saveBuild keeps the files Generate just wrote as a build and drops the
+// oldest builds beyond the history size
+func (g *SiteGenerator) saveBuild(generatedAt time.Time) error {
+	if g.buildHistory == 0 {
+		return nil
+	}
+
+	build := SiteBuild{ID: generatedAt.UTC().Format(siteBuildIDLayout), GeneratedAt: generatedAt, Files: make(map[string]string)}
+	for _, name := range siteBuildPages {
+		if !g.output.FileExists(name) {
+			continue
+		}
+		body, err := g.output.ReadBinaryFile(name)
+		if err != nil {
+			return err
+		}
+		build.Files[name] = string(body)
+	}
+	build.Size = int64(len(build.Files["index.html"]))
+	if entries, err := os.ReadDir(g.output.GetFilePath("assets")); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			name := path.Join("assets", entry.Name())
+			body, err := g.output.ReadBinaryFile(name)
+			if err != nil {
+				return err
+			}
+			build.Files[name] = string(body)
+		}
+	}
+
+	if err := os.MkdirAll(g.storage.GetFilePath(siteBuildsDir), 0755); err != nil {
+		return fmt.Errorf("failed to create builds directory: %w", err)
+	}
+	if err := g.storage.WriteJSONFile(buildFilename(build.ID), build); err != nil {
+		return fmt.Errorf("failed to save build: %w", err)
+	}
+	if err := g.storage.WriteJSONFile(siteBuildsLiveFile, siteBuildsLive{ID: build.ID}); err != nil {
+		return fmt.Errorf("failed to record live build: %w", err)
+	}
+
+	ids, err := g.buildIDs()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids[min(g.buildHistory, len(ids)):] {
+		g.storage.DeleteFile(buildFilename(id))
+	}
+	return nil
+}
+
+// buildIDs lists the IDs of kept builds, newest first
+func (g *SiteGenerator) buildIDs() ([]string, error) {
+	entries, err := os.ReadDir(g.storage.GetFilePath(siteBuildsDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list builds: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(siteBuildIDLayout, id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids, nil
+}
+
+// loadBuild reads a kept build
+func (g *SiteGenerator) loadBuild(id string) (*SiteBuild, error) {
+	if _, err := time.Parse(siteBuildIDLayout, id); err != nil || !g.storage.FileExists(buildFilename(id)) {
+		return nil, ErrBuildNotFound
+	}
+	var build SiteBuild
+	if err := g.storage.ReadJSONFile(buildFilename(id), &build); err != nil {
+		return nil, fmt.Errorf("failed to read build %s: %w", id, err)
+	}
+	return &build, nil
+}
+
+// liveBuild returns the build the output directory holds, if known
+func (g *SiteGenerator) liveBuild() siteBuildsLive {
+	var live siteBuildsLive
+	if g.storage.FileExists(siteBuildsLiveFile) {
+		g.storage.ReadJSONFile(siteBuildsLiveFile, &live)
+	}
+	return live
+}
+
+// buildFilename is the data file of a build
+func buildFilename(id string) string {
+	return filepath.Join(siteBuildsDir, id+".json")
+}
@@ -0,0 +1,270 @@
+package managers
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// siteBackupsDir holds the archives taken before a factory reset
+const siteBackupsDir = "site-backups"
+
+// SitePreset is a starting point a site can be reset to: the default
+// schema, content and template, plus section presets filled with example
+// content
+type SitePreset struct {
+	Name        string   `json:"name"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Sections    []string `json:"sections,omitempty"` // section preset names
+}
+
+// sitePresets are the built-in site presets, in display order
+var sitePresets = []SitePreset{
+	{Name: "blank", Title: "Blank", Description: "The default page with hero, about and contact sections"},
+	{Name: "event", Title: "Event", Description: "The default page plus an event with a date, location and RSVP link", Sections: []string{"event"}},
+	{Name: "portfolio", Title: "Portfolio", Description: "The default page plus an image gallery", Sections: []string{"gallery"}},
+	{Name: "product", Title: "Product", Description: "The default page plus pricing plans", Sections: []string{"pricing"}},
+}
+
+// SitePresets returns the built-in site presets
+func SitePresets() []SitePreset {
+	return append([]SitePreset(nil), sitePresets...)
+}
+
+// GetSitePreset returns the named site preset
+func GetSitePreset(name string) (SitePreset, bool) {
+	for _, preset := range sitePresets {
+		if preset.Name == name {
+			return preset, true
+		}
+	}
+	return SitePreset{}, false
+}
+
+// SiteResetResult describes a factory reset
+type SiteResetResult struct {
+	Preset        string   `json:"preset"`
+	Backup        string   `json:"backup"` // archive of the site before the reset, in the data directory
+	Removed       []string `json:"removed"`
+	ImagesRemoved bool     `json:"images_removed"`
+}
+
+// BackupSite writes an archive of the site into site-backups and returns
+// its path relative to the data directory
+func BackupSite(storage *FileStorage, now time.Time) (string, error) {
+	dir := storage.GetFilePath(siteBackupsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", siteBackupsDir, err)
+	}
+	name := filepath.Join(siteBackupsDir, "site-"+now.UTC().Format("20060102-150405.000")+".zip")
+	target := storage.GetFilePath(name)
+
+	file, err := os.Create(target + ".tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create site backup: %w", err)
+	}
+	if err := WriteArchive(storage, file); err != nil {
+		file.Close()
+		os.Remove(target + ".tmp")
+		return "", fmt.Errorf("failed to write site backup: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(target + ".tmp")
+		return "", fmt.Errorf("failed to write site backup: %w", err)
+	}
+	if err := os.Rename(target+".tmp", target); err != nil {
+		os.Remove(target + ".tmp")
+		return "", fmt.Errorf("failed to save site backup: %w", err)
+	}
+	return filepath.ToSlash(name), nil
+}
+
+// ResetSite returns the site to preset. It first archives the site into
+// site-backups, which POST /admin/import/archive restores. Every data file
+// a site archive carries is removed, and images too unless keepImages;
+// content, schema and template are then recreated from the defaults and
+// the preset's sections. Credentials, sessions and secrets are kept.
+func ResetSite(storage *FileStorage, preset SitePreset, keepImages bool, cm *ContentManager, sm *SchemaManager, tm *TemplateManager, now time.Time) (*SiteResetResult, error) {
+	backup, err := BackupSite(storage, now)
+	if err != nil {
+		return nil, err
+	}
+	result := &SiteResetResult{Preset: preset.Name, Backup: backup, Removed: []string{}}
+
+	for _, name := range archiveDataFiles {
+		if keepImages && name == "images.json" {
+			continue
+		}
+		switch name {
+		case "content.json", "schema.json", "template.html":
+			// Replaced below, keeping a .bak of the old version
+			continue
+		}
+		err := os.Remove(storage.GetFilePath(name))
+		if err == nil {
+			result.Removed = append(result.Removed, name)
+		} else if !os.IsNotExist(err) {
+			return result, fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+	if !keepImages {
+		if err := os.RemoveAll(storage.GetFilePath(archiveImagesDir)); err != nil {
+			return result, fmt.Errorf("failed to remove images: %w", err)
+		}
+		result.ImagesRemoved = true
+	}
+
+	if err := sm.SaveSchema(sm.createDefaultSchema()); err != nil {
+		return result, fmt.Errorf("failed to reset schema: %w", err)
+	}
+	content := cm.createDefaultContent()
+	content.LastUpdated = now
+	for _, section := range preset.Sections {
+		if err := sm.AddSectionPreset(section, ""); err != nil {
+			return result, fmt.Errorf("failed to add section %s: %w", section, err)
+		}
+		if sectionPreset, ok := GetSectionPreset(section); ok {
+			content.Sections[section] = sampleValueOf(section, sectionPreset.Schema, 1, true)
+		}
+	}
+	if err := cm.SaveContent(content); err != nil {
+		return result, fmt.Errorf("failed to reset content: %w", err)
+	}
+	if err := tm.SaveTemplateWithEngine(tm.GetDefaultTemplate(), EngineGo); err != nil {
+		return result, fmt.Errorf("failed to reset template: %w", err)
+	}
+	return result, nil
+}
+
+// siteSlotPattern restricts site slot names to safe directory names
+var siteSlotPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Site slot failures
+var (
+	ErrSiteSlotInvalid = errors.New("site slot must be lowercase letters, digits and dashes, starting with a letter or digit")
+	ErrSiteSlotExists  = errors.New("site slot already exists")
+)
+
+// SiteSlot is a copy of a site in the sites directory. Each slot is the
+// data directory of another instance: start one with DATA_DIR set to it.
+type SiteSlot struct {
+	Name      string    `json:"name"`
+	DataDir   string    `json:"data_dir"`
+	Title     string    `json:"title,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Files     int       `json:"files"`
+}
+
+// DuplicateSite copies the site's data files and images into a new slot in
+// sitesDir. Credentials, sessions, secrets and backups stay behind, so the
+// copy starts with the admin password from its own configuration.
+func DuplicateSite(storage *FileStorage, sitesDir, name string, now time.Time) (*SiteSlot, error) {
+	if !siteSlotPattern.MatchString(name) {
+		return nil, ErrSiteSlotInvalid
+	}
+	if err := os.MkdirAll(sitesDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create sites directory: %w", err)
+	}
+	target := filepath.Join(sitesDir, name)
+	// Mkdir, not MkdirAll, so two requests cannot fill the same slot
+	if err := os.Mkdir(target, 0755); err != nil {
+		if os.IsExist(err) {
+			return nil, ErrSiteSlotExists
+		}
+		return nil, fmt.Errorf("failed to create site slot: %w", err)
+	}
+
+	files := 0
+	for _, file := range append([]string{DataVersionFilename}, archiveDataFiles...) {
+		if !storage.FileExists(file) {
+			continue
+		}
+		if err := copyFile(storage.GetFilePath(file), filepath.Join(target, file)); err != nil {
+			os.RemoveAll(target)
+			return nil, fmt.Errorf("failed to copy %s: %w", file, err)
+		}
+		files++
+	}
+
+	imagesRoot := storage.GetFilePath(archiveImagesDir)
+	err := filepath.WalkDir(imagesRoot, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == imagesRoot && os.IsNotExist(err) {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(storage.GetFilePath(""), path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(target, rel)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		files++
+		return copyFile(path, dest)
+	})
+	if err != nil {
+		os.RemoveAll(target)
+		return nil, fmt.Errorf("failed to copy images: %w", err)
+	}
+
+	slot := readSiteSlot(sitesDir, name)
+	slot.CreatedAt = now
+	slot.Files = files
+	return &slot, nil
+}
+
+// ListSiteSlots returns the slots in sitesDir, newest first
+func ListSiteSlots(sitesDir string) ([]SiteSlot, error) {
+	entries, err := os.ReadDir(sitesDir)
+	if os.IsNotExist(err) {
+		return []SiteSlot{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sites directory: %w", err)
+	}
+	slots := []SiteSlot{}
+	for _, entry := range entries {
+		if entry.IsDir() && siteSlotPattern.MatchString(entry.Name()) {
+			slots = append(slots, readSiteSlot(sitesDir, entry.Name()))
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].CreatedAt.After(slots[j].CreatedAt)
+	})
+	return slots, nil
+}
+
+// readSiteSlot describes the slot called name from its files
+func readSiteSlot(sitesDir, name string) SiteSlot {
+	dir := filepath.Join(sitesDir, name)
+	slot := SiteSlot{Name: name, DataDir: dir}
+	if info, err := os.Stat(dir); err == nil {
+		slot.CreatedAt = info.ModTime()
+	}
+	var content types.ContentData
+	if err := NewFileStorage(dir).ReadJSONFile("content.json", &content); err == nil {
+		slot.Title = content.Title
+	}
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.Type().IsRegular() {
+			slot.Files++
+		}
+		return nil
+	})
+	return slot
+}
@@ -0,0 +1,246 @@
+package managers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// slugsFilename stores the anchor slug of every section
+const slugsFilename = "slugs.json"
+
+// maxSlugLength keeps anchors and URLs readable
+const maxSlugLength = 80
+
+// transliterations maps Latin, Greek and Cyrillic letters to ASCII. The
+// standard library has no Unicode normalization, so accented letters are
+// listed one by one.
+var transliterations = map[rune]string{
+	// Latin
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'ç': "c", 'ć': "c", 'č': "c", 'ĉ': "c", 'ċ': "c",
+	'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ĝ': "g", 'ģ': "g",
+	'ĥ': "h", 'ħ': "h",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ĵ': "j", 'ķ': "k",
+	'ĺ': "l", 'ļ': "l", 'ľ': "l", 'ł': "l",
+	'ñ': "n", 'ń': "n", 'ņ': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o",
+	'ŕ': "r", 'ř': "r",
+	'ś': "s", 'ş': "s", 'š': "s", 'ŝ': "s", 'ș': "s",
+	'ţ': "t", 'ť': "t", 'ț': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y",
+	'ź': "z", 'ż': "z", 'ž': "z",
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'þ': "th",
+	// Greek
+	'α': "a", 'ά': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'έ': "e", 'ζ': "z",
+	'η': "i", 'ή': "i", 'θ': "th", 'ι': "i", 'ί': "i", 'ϊ': "i", 'κ': "k", 'λ': "l",
+	'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'ό': "o", 'π': "p", 'ρ': "r", 'σ': "s",
+	'ς': "s", 'τ': "t", 'υ': "y", 'ύ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o", 'ώ': "o",
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'є': "ye", 'ж': "zh", 'з': "z", 'и': "i", 'і': "i", 'ї': "yi", 'й': "y", 'к': "k",
+	'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// Slugify turns text into a lowercase, hyphen-separated slug for anchors
+// and URLs. Latin, Greek and Cyrillic letters are transliterated to ASCII;
+// letters and digits of other scripts (e.g. CJK) are kept as they are, and
+// emoji, symbols and punctuation become separators.
+func Slugify(text string) string {
+	var b strings.Builder
+	pendingDash := false
+
+	write := func(s string) {
+		if s == "" {
+			return
+		}
+		if pendingDash && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		pendingDash = false
+		b.WriteString(s)
+	}
+
+	for _, r := range strings.ToLower(text) {
+		if ascii, ok := transliterations[r]; ok {
+			write(ascii)
+			continue
+		}
+		switch {
+		case unicode.IsMark(r):
+			// combining accents of decomposed input
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			write(string(r))
+		default:
+			pendingDash = true
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(truncateRunes(slug, maxSlugLength), "-")
+	}
+	return slug
+}
+
+// truncateRunes cuts s to at most n bytes without splitting a character
+func truncateRunes(s string, n int) string {
+	cut := 0
+	for i := range s {
+		if i > n {
+			break
+		}
+		cut = i
+	}
+	return s[:cut]
+}
+
+// UniqueSlug returns slug, or slug with the lowest numeric suffix (-2, -3,
+// ...) that is not in taken
+func UniqueSlug(slug string, taken map[string]bool) string {
+	if !taken[slug] {
+		return slug
+	}
+	for i := 2; ; i++ {
+		candidate := slug + "-" + strconv.Itoa(i)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// SlugManager keeps a stored slug for every section so anchors stay the same
+// when a section title is edited. Slugs change only when set explicitly or
+// regenerated.
+type SlugManager struct {
+	storage *FileStorage
+}
+
+// NewSlugManager creates a new slug manager
+func NewSlugManager(storage *FileStorage) *SlugManager {
+	return &SlugManager{
+		storage: storage,
+	}
+}
+
+// Load returns the stored slugs by section key
+func (sm *SlugManager) Load() (map[string]string, error) {
+	slugs := make(map[string]string)
+	if !sm.storage.FileExists(slugsFilename) {
+		return slugs, nil
+	}
+
+	if err := sm.storage.ReadJSONFile(slugsFilename, &slugs); err != nil {
+		return nil, fmt.Errorf("failed to load slugs: %w", err)
+	}
+	return slugs, nil
+}
+
+// Resolve returns the slug of every section, assigning and storing one for
+// sections that have none yet. New slugs come from the section title, or
+// the key when the title is empty or has no usable characters.
+func (sm *SlugManager) Resolve(sections map[string]interface{}) (map[string]string, error) {
+	slugs, err := sm.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	taken := make(map[string]bool)
+	for key, slug := range slugs {
+		if _, exists := sections[key]; exists {
+			taken[slug] = true
+		}
+	}
+
+	// Assign in key order so the same content always gets the same slugs
+	keys := make([]string, 0, len(sections))
+	for key := range sections {
+		if _, exists := slugs[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		slug := ""
+		if section, ok := sections[key].(map[string]interface{}); ok {
+			if title, ok := section["title"].(string); ok {
+				slug = Slugify(title)
+			}
+		}
+		if slug == "" {
+			slug = Slugify(key)
+		}
+		if slug == "" {
+			slug = "section"
+		}
+		slug = UniqueSlug(slug, taken)
+		taken[slug] = true
+		slugs[key] = slug
+	}
+
+	if len(keys) > 0 {
+		if err := sm.storage.WriteJSONFile(slugsFilename, slugs); err != nil {
+			return nil, fmt.Errorf("failed to save slugs: %w", err)
+		}
+	}
+
+	resolved := make(map[string]string, len(sections))
+	for key := range sections {
+		resolved[key] = slugs[key]
+	}
+	return resolved, nil
+}
+
+// Set stores an explicit slug for a section. The slug is normalized with
+// Slugify and must not be used by another section.
+func (sm *SlugManager) Set(key, slug string) (string, error) {
+	normalized := Slugify(slug)
+	if normalized == "" {
+		return "", fmt.Errorf("slug must contain at least one letter or digit")
+	}
+
+	slugs, err := sm.Load()
+	if err != nil {
+		return "", err
+	}
+	for other, existing := range slugs {
+		if other != key && existing == normalized {
+			return "", fmt.Errorf("slug %s is already used by section %s", normalized, other)
+		}
+	}
+
+	slugs[key] = normalized
+	if err := sm.storage.WriteJSONFile(slugsFilename, slugs); err != nil {
+		return "", fmt.Errorf("failed to save slugs: %w", err)
+	}
+	return normalized, nil
+}
+
+// Regenerate forgets the stored slug of a section, or of every section when
+// key is empty, so the next Resolve derives it from the current title
+func (sm *SlugManager) Regenerate(key string) error {
+	slugs, err := sm.Load()
+	if err != nil {
+		return err
+	}
+
+	if key == "" {
+		slugs = make(map[string]string)
+	} else {
+		delete(slugs, key)
+	}
+
+	if err := sm.storage.WriteJSONFile(slugsFilename, slugs); err != nil {
+		return fmt.Errorf("failed to save slugs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,661 @@
+package managers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// StarterTemplate is a complete starting design for a kind of site: a
+// template with the schema it was written for and sample content to
+// replace
+type StarterTemplate struct {
+	Name        string   `json:"name"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Sections    []string `json:"sections"` // section keys, in page order
+
+	Schema   *types.SchemaData  `json:"-"`
+	Content  *types.ContentData `json:"-"`
+	Template string             `json:"-"`
+}
+
+// starterTemplates builds each built-in starter, in gallery order. Functions
+// are used so every caller gets its own copy to modify.
+var starterTemplates = []func() StarterTemplate{
+	portfolioStarter,
+	businessStarter,
+	restaurantStarter,
+	resumeStarter,
+}
+
+// starterFiles are the data files applying a starter replaces
+var starterFiles = []string{"schema.json", "template.html", templateSettingsFilename, "content.json"}
+
+// StarterTemplates returns the built-in starters
+func StarterTemplates() []StarterTemplate {
+	starters := make([]StarterTemplate, 0, len(starterTemplates))
+	for _, build := range starterTemplates {
+		starters = append(starters, build())
+	}
+	return starters
+}
+
+// GetStarterTemplate returns the named starter
+func GetStarterTemplate(name string) (StarterTemplate, bool) {
+	for _, build := range starterTemplates {
+		if starter := build(); starter.Name == name {
+			return starter, true
+		}
+	}
+	return StarterTemplate{}, false
+}
+
+// StarterApplyResult describes an applied starter
+type StarterApplyResult struct {
+	Starter string   `json:"starter"`
+	Backup  string   `json:"backup"` // archive of the site before, in the data directory
+	Files   []string `json:"files"`
+}
+
+// ApplyStarterTemplate replaces the schema, template and content with the
+// starter's. The site is archived into site-backups first, and everything
+// is checked before anything is written: the content against the starter's
+// schema and the template with both. If a write still fails, the files
+// already replaced are put back, so the site never mixes a starter's
+// template with the old schema.
+func ApplyStarterTemplate(storage *FileStorage, starter StarterTemplate, cm *ContentManager, sm *SchemaManager, tm *TemplateManager, now time.Time) (*StarterApplyResult, error) {
+	// Numbers as the validator reads them from schema.json
+	var schema types.SchemaData
+	if err := remarshal(starter.Schema, &schema); err != nil {
+		return nil, fmt.Errorf("failed to read starter schema: %w", err)
+	}
+	if err := sm.validateSchema(&schema); err != nil {
+		return nil, fmt.Errorf("starter schema is invalid: %w", err)
+	}
+	contentMap, err := contentToMap(starter.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read starter content: %w", err)
+	}
+	delete(contentMap, "last_updated")
+	if result := sm.newValidator(&schema).ValidateContent(contentMap); !result.Valid {
+		return nil, fmt.Errorf("starter content does not match its schema: %s", result.Errors[0].Message)
+	}
+	if err := tm.checkTemplateWith(starter.Template, EngineGo, sampleTemplateData(SampleContent(&schema))); err != nil {
+		return nil, fmt.Errorf("starter template does not render its schema's sample content: %w", err)
+	}
+	if err := tm.checkTemplateWith(starter.Template, EngineGo, sampleTemplateData(contentMap)); err != nil {
+		return nil, fmt.Errorf("starter template does not render its content: %w", err)
+	}
+
+	backup, err := BackupSite(storage, now)
+	if err != nil {
+		return nil, err
+	}
+	result := &StarterApplyResult{Starter: starter.Name, Backup: backup, Files: starterFiles}
+
+	previous := make(map[string][]byte, len(starterFiles))
+	for _, name := range starterFiles {
+		if data, err := os.ReadFile(storage.GetFilePath(name)); err == nil {
+			previous[name] = data
+		}
+	}
+	restore := func(cause error) (*StarterApplyResult, error) {
+		for _, name := range starterFiles {
+			if data, ok := previous[name]; ok {
+				storage.WriteBinaryFile(name, data)
+			} else {
+				os.Remove(storage.GetFilePath(name))
+			}
+		}
+		return result, cause
+	}
+
+	if err := sm.SaveSchema(&schema); err != nil {
+		return restore(fmt.Errorf("failed to save schema: %w", err))
+	}
+	if err := tm.SaveTemplateWithEngine(starter.Template, EngineGo); err != nil {
+		return restore(err)
+	}
+	content := *starter.Content
+	if err := cm.SaveContent(&content); err != nil {
+		return restore(fmt.Errorf("failed to save content: %w", err))
+	}
+	return result, nil
+}
+
+// starterString is a string property for starter schemas; format may be
+// empty
+func starterString(title, format string) map[string]interface{} {
+	prop := map[string]interface{}{"type": "string", "title": title}
+	if format != "" {
+		prop["format"] = format
+	}
+	return prop
+}
+
+// starterSection is a section object with a title and the given fields,
+// shown at position order
+func starterSection(title string, order int, properties map[string]interface{}) map[string]interface{} {
+	properties["title"] = starterString("Section Title", "")
+	return map[string]interface{}{
+		"type":          "object",
+		"title":         title,
+		"propertyOrder": order,
+		"properties":    properties,
+	}
+}
+
+// starterList is an array of objects with the given fields
+func starterList(title string, properties map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":  "array",
+		"title": title,
+		"items": map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+		},
+	}
+}
+
+// starterHero is the hero section every starter opens with
+func starterHero() map[string]interface{} {
+	return starterSection("Hero Section", 1, map[string]interface{}{
+		"subtitle":    starterString("Subtitle", ""),
+		"button_text": starterString("Button Text", ""),
+		"button_link": starterString("Button Link", ""),
+	})
+}
+
+// starterContact is the contact section most starters end with
+func starterContact(order int) map[string]interface{} {
+	return starterSection("Contact Section", order, map[string]interface{}{
+		"email":   starterString("Email Address", "email"),
+		"phone":   starterString("Phone Number", ""),
+		"address": starterString("Address", "textarea"),
+	})
+}
+
+// starterSchema wraps section schemas in the page schema
+func starterSchema(sections map[string]interface{}) *types.SchemaData {
+	return &types.SchemaData{
+		Schema: "https://json-schema.org/draft/2020-12/schema",
+		Type:   "object",
+		Properties: map[string]interface{}{
+			"title": map[string]interface{}{
+				"type":      "string",
+				"title":     "Page Title",
+				"minLength": 1,
+				"maxLength": 100,
+			},
+			"description": map[string]interface{}{
+				"type":      "string",
+				"title":     "Page Description",
+				"maxLength": 500,
+			},
+			"sections": map[string]interface{}{
+				"type":       "object",
+				"title":      "Content Sections",
+				"properties": sections,
+			},
+		},
+	}
+}
+
+// portfolioStarter showcases projects for a designer, photographer or
+// developer
+func portfolioStarter() StarterTemplate {
+	return StarterTemplate{
+		Name:        "portfolio",
+		Title:       "Portfolio",
+		Description: "Selected projects with images and links, an about section and contact details",
+		Sections:    []string{"hero", "projects", "about", "contact"},
+		Schema: starterSchema(map[string]interface{}{
+			"hero": starterHero(),
+			"projects": starterSection("Projects Section", 2, map[string]interface{}{
+				"items": starterList("Projects", map[string]interface{}{
+					"title":       starterString("Project Name", ""),
+					"description": starterString("Description", "textarea"),
+					"image":       starterString("Image", "image"),
+					"link":        starterString("Link", "uri"),
+				}),
+			}),
+			"about": starterSection("About Section", 3, map[string]interface{}{
+				"content": starterString("About Text", "textarea"),
+			}),
+			"contact": starterContact(4),
+		}),
+		Content: &types.ContentData{
+			Title:       "Alex Morgan",
+			Description: "Product designer crafting calm, useful interfaces",
+			Sections: map[string]interface{}{
+				"hero": map[string]interface{}{
+					"title":       "Hi, I'm Alex",
+					"subtitle":    "I design digital products people enjoy using",
+					"button_text": "See my work",
+					"button_link": "#projects",
+				},
+				"projects": map[string]interface{}{
+					"title": "Selected Work",
+					"items": []interface{}{
+						map[string]interface{}{"title": "Banking App Redesign", "description": "A simpler way to move money, tested with 40 customers."},
+						map[string]interface{}{"title": "Museum Wayfinding", "description": "Signs and a companion app for a city museum."},
+						map[string]interface{}{"title": "Design System", "description": "Components and guidelines shared by five product teams."},
+					},
+				},
+				"about": map[string]interface{}{
+					"title":   "About Me",
+					"content": "I have spent ten years turning complicated problems into clear products, working closely with engineers and the people who use what we build.",
+				},
+				"contact": map[string]interface{}{
+					"title": "Let's Work Together",
+					"email": "hello@example.com",
+				},
+			},
+		},
+		Template: starterPage("#6c5ce7", `
+    {{with .sections.projects}}
+    <section class="section" id="{{$.anchors.projects}}">
+        <div class="container">
+            <h2>{{.title}}</h2>
+            <div class="grid">
+                {{range .items}}
+                <article class="card">
+                    {{if .image}}<img src="{{.image}}" alt="{{.title}}" loading="lazy">{{end}}
+                    <h3>{{.title}}</h3>
+                    <p>{{.description}}</p>
+                    {{if .link}}<a href="{{.link}}">View project</a>{{end}}
+                </article>
+                {{end}}
+            </div>
+        </div>
+    </section>
+    {{end}}
+
+    {{with .sections.about}}
+    <section class="section alt" id="{{$.anchors.about}}">
+        <div class="container narrow">
+            <h2>{{.title}}</h2>
+            <p>{{.content}}</p>
+        </div>
+    </section>
+    {{end}}
+`+starterContactMarkup),
+	}
+}
+
+// businessStarter presents a small business's services and what customers
+// say about it
+func businessStarter() StarterTemplate {
+	return StarterTemplate{
+		Name:        "business",
+		Title:       "Business",
+		Description: "Services, customer testimonials and contact details for a small business",
+		Sections:    []string{"hero", "services", "testimonials", "contact"},
+		Schema: starterSchema(map[string]interface{}{
+			"hero": starterHero(),
+			"services": starterSection("Services Section", 2, map[string]interface{}{
+				"items": starterList("Services", map[string]interface{}{
+					"title":       starterString("Service", ""),
+					"description": starterString("Description", "textarea"),
+				}),
+			}),
+			"testimonials": starterSection("Testimonials Section", 3, map[string]interface{}{
+				"items": starterList("Testimonials", map[string]interface{}{
+					"quote": starterString("Quote", "textarea"),
+					"name":  starterString("Name", ""),
+					"role":  starterString("Role or Company", ""),
+				}),
+			}),
+			"contact": starterContact(4),
+		}),
+		Content: &types.ContentData{
+			Title:       "Brightside Plumbing",
+			Description: "Reliable plumbing and heating for homes and businesses",
+			Sections: map[string]interface{}{
+				"hero": map[string]interface{}{
+					"title":       "Plumbing you can count on",
+					"subtitle":    "Same-day repairs, fair prices and a two-year guarantee",
+					"button_text": "Get a quote",
+					"button_link": "#contact",
+				},
+				"services": map[string]interface{}{
+					"title": "What We Do",
+					"items": []interface{}{
+						map[string]interface{}{"title": "Repairs", "description": "Leaks, blockages and broken fittings fixed fast."},
+						map[string]interface{}{"title": "Installations", "description": "Bathrooms, kitchens and water heaters fitted to last."},
+						map[string]interface{}{"title": "Maintenance", "description": "Yearly boiler checks that prevent winter surprises."},
+					},
+				},
+				"testimonials": map[string]interface{}{
+					"title": "What Customers Say",
+					"items": []interface{}{
+						map[string]interface{}{"quote": "They came within the hour and fixed it for less than quoted.", "name": "Priya S.", "role": "Homeowner"},
+						map[string]interface{}{"quote": "Our go-to for every property we manage.", "name": "Tom W.", "role": "Lettings agent"},
+					},
+				},
+				"contact": map[string]interface{}{
+					"title":   "Contact Us",
+					"email":   "office@example.com",
+					"phone":   "+1 555 0100",
+					"address": "12 Harbour Street\nSpringfield",
+				},
+			},
+		},
+		Template: starterPage("#0984e3", `
+    {{with .sections.services}}
+    <section class="section" id="{{$.anchors.services}}">
+        <div class="container">
+            <h2>{{.title}}</h2>
+            <div class="grid">
+                {{range .items}}
+                <article class="card">
+                    <h3>{{.title}}</h3>
+                    <p>{{.description}}</p>
+                </article>
+                {{end}}
+            </div>
+        </div>
+    </section>
+    {{end}}
+
+    {{with .sections.testimonials}}
+    <section class="section alt" id="{{$.anchors.testimonials}}">
+        <div class="container">
+            <h2>{{.title}}</h2>
+            <div class="grid">
+                {{range .items}}
+                <blockquote class="card">
+                    <p>&ldquo;{{.quote}}&rdquo;</p>
+                    <footer>{{.name}}{{if .role}}, {{.role}}{{end}}</footer>
+                </blockquote>
+                {{end}}
+            </div>
+        </div>
+    </section>
+    {{end}}
+`+starterContactMarkup),
+	}
+}
+
+// restaurantStarter lists a restaurant's menu and opening hours
+func restaurantStarter() StarterTemplate {
+	return StarterTemplate{
+		Name:        "restaurant",
+		Title:       "Restaurant",
+		Description: "A menu in courses with prices, opening hours and a reservation link",
+		Sections:    []string{"hero", "menu", "hours", "contact"},
+		Schema: starterSchema(map[string]interface{}{
+			"hero": starterHero(),
+			"menu": starterSection("Menu Section", 2, map[string]interface{}{
+				"courses": starterList("Courses", map[string]interface{}{
+					"name": starterString("Course", ""),
+					"dishes": starterList("Dishes", map[string]interface{}{
+						"name":        starterString("Dish", ""),
+						"description": starterString("Description", ""),
+						"price":       starterString("Price", ""),
+					}),
+				}),
+			}),
+			"hours": starterSection("Opening Hours Section", 3, map[string]interface{}{
+				"items": starterList("Opening Hours", map[string]interface{}{
+					"days":  starterString("Days", ""),
+					"times": starterString("Times", ""),
+				}),
+				"reservation_link": starterString("Reservation Link", "uri"),
+			}),
+			"contact": starterContact(4),
+		}),
+		Content: &types.ContentData{
+			Title:       "Olive & Thyme",
+			Description: "Seasonal Mediterranean cooking in the old town",
+			Sections: map[string]interface{}{
+				"hero": map[string]interface{}{
+					"title":       "Olive & Thyme",
+					"subtitle":    "Seasonal Mediterranean cooking, made from scratch every day",
+					"button_text": "See the menu",
+					"button_link": "#menu",
+				},
+				"menu": map[string]interface{}{
+					"title": "Menu",
+					"courses": []interface{}{
+						map[string]interface{}{"name": "Starters", "dishes": []interface{}{
+							map[string]interface{}{"name": "Grilled halloumi", "description": "Honey, thyme and lemon", "price": "9"},
+							map[string]interface{}{"name": "Burrata", "description": "Heritage tomatoes and basil oil", "price": "11"},
+						}},
+						map[string]interface{}{"name": "Mains", "dishes": []interface{}{
+							map[string]interface{}{"name": "Slow-roasted lamb", "description": "Lemon potatoes and salsa verde", "price": "24"},
+							map[string]interface{}{"name": "Wild mushroom orzo", "description": "Parmesan and crispy sage", "price": "18"},
+						}},
+					},
+				},
+				"hours": map[string]interface{}{
+					"title": "Opening Hours",
+					"items": []interface{}{
+						map[string]interface{}{"days": "Tuesday – Friday", "times": "17:00 – 22:00"},
+						map[string]interface{}{"days": "Saturday – Sunday", "times": "12:00 – 22:30"},
+					},
+				},
+				"contact": map[string]interface{}{
+					"title":   "Find Us",
+					"phone":   "+1 555 0123",
+					"address": "4 Market Square\nOld Town",
+				},
+			},
+		},
+		Template: starterPage("#d35400", `
+    {{with .sections.menu}}
+    <section class="section" id="{{$.anchors.menu}}">
+        <div class="container narrow">
+            <h2>{{.title}}</h2>
+            {{range .courses}}
+            <h3 class="course">{{.name}}</h3>
+            <ul class="menu">
+                {{range .dishes}}
+                <li><span><strong>{{.name}}</strong>{{if .description}} &middot; {{.description}}{{end}}</span><span>{{.price}}</span></li>
+                {{end}}
+            </ul>
+            {{end}}
+        </div>
+    </section>
+    {{end}}
+
+    {{with .sections.hours}}
+    <section class="section alt" id="{{$.anchors.hours}}">
+        <div class="container narrow">
+            <h2>{{.title}}</h2>
+            <ul class="menu">
+                {{range .items}}<li><span>{{.days}}</span><span>{{.times}}</span></li>{{end}}
+            </ul>
+            {{if .reservation_link}}<p><a href="{{.reservation_link}}" class="btn">Book a table</a></p>{{end}}
+        </div>
+    </section>
+    {{end}}
+`+starterContactMarkup),
+	}
+}
+
+// resumeStarter is a one-page CV: profile, experience, education and skills
+func resumeStarter() StarterTemplate {
+	return StarterTemplate{
+		Name:        "resume",
+		Title:       "Resume",
+		Description: "A one-page CV with experience, education and skills",
+		Sections:    []string{"hero", "experience", "education", "skills", "contact"},
+		Schema: starterSchema(map[string]interface{}{
+			"hero": starterHero(),
+			"experience": starterSection("Experience Section", 2, map[string]interface{}{
+				"items": starterList("Positions", map[string]interface{}{
+					"role":    starterString("Role", ""),
+					"company": starterString("Company", ""),
+					"period":  starterString("Period", ""),
+					"summary": starterString("Summary", "textarea"),
+				}),
+			}),
+			"education": starterSection("Education Section", 3, map[string]interface{}{
+				"items": starterList("Qualifications", map[string]interface{}{
+					"degree": starterString("Degree", ""),
+					"school": starterString("School", ""),
+					"year":   starterString("Year", ""),
+				}),
+			}),
+			"skills": starterSection("Skills Section", 4, map[string]interface{}{
+				"items": map[string]interface{}{
+					"type":  "array",
+					"title": "Skills",
+					"items": map[string]interface{}{"type": "string"},
+				},
+			}),
+			"contact": starterContact(5),
+		}),
+		Content: &types.ContentData{
+			Title:       "Sam Rivera",
+			Description: "Backend engineer with eight years of experience building reliable services",
+			Sections: map[string]interface{}{
+				"hero": map[string]interface{}{
+					"title":       "Sam Rivera",
+					"subtitle":    "Backend engineer · Go, PostgreSQL, distributed systems",
+					"button_text": "Get in touch",
+					"button_link": "#contact",
+				},
+				"experience": map[string]interface{}{
+					"title": "Experience",
+					"items": []interface{}{
+						map[string]interface{}{"role": "Senior Engineer", "company": "Northwind", "period": "2022 – present", "summary": "Lead the payments platform team; cut checkout latency by 60%."},
+						map[string]interface{}{"role": "Software Engineer", "company": "Contoso", "period": "2017 – 2022", "summary": "Built the order pipeline handling two million orders a day."},
+					},
+				},
+				"education": map[string]interface{}{
+					"title": "Education",
+					"items": []interface{}{
+						map[string]interface{}{"degree": "BSc Computer Science", "school": "State University", "year": "2017"},
+					},
+				},
+				"skills": map[string]interface{}{
+					"title": "Skills",
+					"items": []interface{}{"Go", "PostgreSQL", "Kubernetes", "System design", "Mentoring"},
+				},
+				"contact": map[string]interface{}{
+					"title": "Contact",
+					"email": "sam@example.com",
+				},
+			},
+		},
+		Template: starterPage("#2d3436", `
+    {{with .sections.experience}}
+    <section class="section" id="{{$.anchors.experience}}">
+        <div class="container narrow">
+            <h2>{{.title}}</h2>
+            {{range .items}}
+            <article class="entry">
+                <h3>{{.role}}{{if .company}} &middot; {{.company}}{{end}}</h3>
+                {{if .period}}<p class="muted">{{.period}}</p>{{end}}
+                <p>{{.summary}}</p>
+            </article>
+            {{end}}
+        </div>
+    </section>
+    {{end}}
+
+    {{with .sections.education}}
+    <section class="section alt" id="{{$.anchors.education}}">
+        <div class="container narrow">
+            <h2>{{.title}}</h2>
+            {{range .items}}
+            <article class="entry">
+                <h3>{{.degree}}</h3>
+                <p class="muted">{{.school}}{{if .year}}, {{.year}}{{end}}</p>
+            </article>
+            {{end}}
+        </div>
+    </section>
+    {{end}}
+
+    {{with .sections.skills}}
+    <section class="section" id="{{$.anchors.skills}}">
+        <div class="container narrow">
+            <h2>{{.title}}</h2>
+            <ul class="tags">{{range .items}}<li>{{.}}</li>{{end}}</ul>
+        </div>
+    </section>
+    {{end}}
+`+starterContactMarkup),
+	}
+}
+
+// starterContactMarkup renders the contact section of starterContact
+const starterContactMarkup = `
+    {{with .sections.contact}}
+    <section class="section" id="{{$.anchors.contact}}">
+        <div class="container narrow">
+            <h2>{{.title}}</h2>
+            {{if .email}}<p><a href="mailto:{{.email}}">{{.email}}</a></p>{{end}}
+            {{if .phone}}<p><a href="tel:{{.phone}}">{{.phone}}</a></p>{{end}}
+            {{if .address}}<p class="address">{{.address}}</p>{{end}}
+        </div>
+    </section>
+    {{end}}
+`
+
+// starterPage wraps the sections of a starter in the page, hero and footer
+// all starters share, with accent as the brand colour
+func starterPage(accent, sections string) string {
+	return strings.Replace(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.title}}</title>
+    <meta name="description" content="{{.description}}">
+    <style>
+        * { margin: 0; padding: 0; box-sizing: border-box; }
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; line-height: 1.6; color: #2d3436; }
+        a { color: ACCENT; }
+        .container { max-width: 1100px; margin: 0 auto; padding: 0 20px; }
+        .narrow { max-width: 760px; }
+        .hero { background: ACCENT; color: #fff; padding: 6rem 0; text-align: center; }
+        .hero h1 { font-size: 2.8rem; margin-bottom: 0.5rem; }
+        .hero p { font-size: 1.25rem; opacity: 0.9; margin-bottom: 2rem; }
+        .btn { display: inline-block; padding: 0.8rem 1.8rem; border-radius: 4px; background: ACCENT; color: #fff; text-decoration: none; }
+        .hero .btn { background: #fff; color: ACCENT; }
+        .section { padding: 4rem 0; }
+        .section.alt { background: #f5f6fa; }
+        .section h2 { font-size: 2rem; margin-bottom: 1.5rem; }
+        .grid { display: grid; grid-template-columns: repeat(auto-fit, minmax(260px, 1fr)); gap: 1.5rem; }
+        .card { background: #fff; border-radius: 8px; padding: 1.5rem; box-shadow: 0 2px 12px rgba(0,0,0,0.08); }
+        .card img { width: 100%; border-radius: 4px; margin-bottom: 1rem; }
+        .card h3 { margin-bottom: 0.5rem; }
+        .card footer { margin-top: 1rem; font-weight: 600; }
+        .course { margin: 1.5rem 0 0.5rem; color: ACCENT; }
+        .menu { list-style: none; }
+        .menu li { display: flex; justify-content: space-between; gap: 1rem; padding: 0.6rem 0; border-bottom: 1px dashed #dfe6e9; }
+        .entry { margin-bottom: 1.5rem; }
+        .muted { color: #636e72; }
+        .tags { list-style: none; display: flex; flex-wrap: wrap; gap: 0.5rem; }
+        .tags li { background: #f5f6fa; border-radius: 999px; padding: 0.3rem 0.9rem; }
+        .address { white-space: pre-line; }
+        footer.site { padding: 2rem 0; text-align: center; color: #636e72; }
+    </style>
+</head>
+<body>
+    {{with .sections.hero}}
+    <section class="hero" id="{{$.anchors.hero}}">
+        <div class="container">
+            <h1>{{.title}}</h1>
+            {{if .subtitle}}<p>{{.subtitle}}</p>{{end}}
+            {{if .button_text}}<a href="{{or .button_link "#"}}" class="btn">{{.button_text}}</a>{{end}}
+        </div>
+    </section>
+    {{end}}
+`+sections+`
+    <footer class="site">
+        <div class="container">
+            <p>&copy; {{.last_updated.Year}} {{.title}}</p>
+        </div>
+    </footer>
+</body>
+</html>`, "ACCENT", accent, -1)
+}
@@ -1,6 +1,7 @@
 package managers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,12 +9,14 @@ import (
 	"path/filepath"
 	"time"
 
+	"onepagems/internal/tracing"
 	"onepagems/internal/types"
 )
 
 // FileStorage handles all file operations for the CMS
 type FileStorage struct {
 	dataDir string
+	ctx     context.Context // parent for storage spans, see WithContext
 }
 
 // NewFileStorage creates a new file storage instance
@@ -23,6 +26,30 @@ func NewFileStorage(dataDir string) *FileStorage {
 	}
 }
 
+// WithContext returns a copy of the storage whose operations are traced as
+// children of the span in ctx
+func (fs *FileStorage) WithContext(ctx context.Context) *FileStorage {
+	scoped := *fs
+	scoped.ctx = ctx
+	return &scoped
+}
+
+// trace starts a span for a storage operation. The returned function ends
+// the span and records the operation's error, if any.
+func (fs *FileStorage) trace(op, filename string) func(*error) {
+	ctx := fs.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	_, span := tracing.Start(ctx, "storage."+op)
+	span.SetAttribute("file.name", filename)
+	return func(err *error) {
+		span.RecordError(*err)
+		span.End()
+	}
+}
+
 // EnsureDirectories creates all necessary directories if they don't exist
 func (fs *FileStorage) EnsureDirectories() error {
 	dirs := []string{
@@ -52,7 +79,9 @@ func (fs *FileStorage) GetFilePath(filename string) string {
 }
 
 // ReadJSONFile reads and unmarshals a JSON file
-func (fs *FileStorage) ReadJSONFile(filename string, target interface{}) error {
+func (fs *FileStorage) ReadJSONFile(filename string, target interface{}) (err error) {
+	defer fs.trace("read", filename)(&err)
+
 	fullPath := fs.GetFilePath(filename)
 
 	data, err := os.ReadFile(fullPath)
@@ -71,7 +100,9 @@ func (fs *FileStorage) ReadJSONFile(filename string, target interface{}) error {
 }
 
 // WriteJSONFile marshals and writes data to a JSON file
-func (fs *FileStorage) WriteJSONFile(filename string, data interface{}) error {
+func (fs *FileStorage) WriteJSONFile(filename string, data interface{}) (err error) {
+	defer fs.trace("write", filename)(&err)
+
 	// Create backup before writing
 	if err := fs.CreateBackup(filename); err != nil {
 		// Log the error but don't fail the write operation
@@ -103,7 +134,9 @@ func (fs *FileStorage) WriteJSONFile(filename string, data interface{}) error {
 }
 
 // ReadTextFile reads a text file and returns its contents
-func (fs *FileStorage) ReadTextFile(filename string) (string, error) {
+func (fs *FileStorage) ReadTextFile(filename string) (_ string, err error) {
+	defer fs.trace("read", filename)(&err)
+
 	fullPath := fs.GetFilePath(filename)
 
 	data, err := os.ReadFile(fullPath)
@@ -118,7 +151,9 @@ func (fs *FileStorage) ReadTextFile(filename string) (string, error) {
 }
 
 // WriteTextFile writes text content to a file
-func (fs *FileStorage) WriteTextFile(filename string, content string) error {
+func (fs *FileStorage) WriteTextFile(filename string, content string) (err error) {
+	defer fs.trace("write", filename)(&err)
+
 	// Create backup before writing
 	if err := fs.CreateBackup(filename); err != nil {
 		// Log the error but don't fail the write operation
@@ -143,6 +178,60 @@ func (fs *FileStorage) WriteTextFile(filename string, content string) error {
 	return nil
 }
 
+// WriteBinaryFile writes raw bytes to a file without creating a backup
+func (fs *FileStorage) WriteBinaryFile(filename string, data []byte) (err error) {
+	defer fs.trace("write", filename)(&err)
+
+	fullPath := fs.GetFilePath(filename)
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", filename, err)
+	}
+
+	// Write to temporary file first, then rename (atomic operation)
+	tempPath := fullPath + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write temporary file %s: %w", tempPath, err)
+	}
+
+	if err := os.Rename(tempPath, fullPath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename temporary file %s to %s: %w", tempPath, fullPath, err)
+	}
+
+	return nil
+}
+
+// AppendFile appends data to a file, creating it when missing. There is no
+// backup; append-only logs are only rewritten to prune old entries.
+func (fs *FileStorage) AppendFile(filename string, data []byte) (err error) {
+	defer fs.trace("append", filename)(&err)
+
+	file, err := os.OpenFile(fs.GetFilePath(filename), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to append to %s: %w", filename, err)
+	}
+	return file.Close()
+}
+
+// ReadBinaryFile reads raw bytes from a file
+func (fs *FileStorage) ReadBinaryFile(filename string) (_ []byte, err error) {
+	defer fs.trace("read", filename)(&err)
+
+	data, err := os.ReadFile(fs.GetFilePath(filename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("file %s does not exist", filename)
+		}
+		return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
+	}
+	return data, nil
+}
+
 // CreateBackup creates a backup of a file with .bak extension
 func (fs *FileStorage) CreateBackup(filename string) error {
 	sourcePath := fs.GetFilePath(filename)
@@ -292,7 +381,9 @@ func (fs *FileStorage) ListFiles() ([]types.FileInfo, error) {
 }
 
 // DeleteFile deletes a file and its backup if it exists
-func (fs *FileStorage) DeleteFile(filename string) error {
+func (fs *FileStorage) DeleteFile(filename string) (err error) {
+	defer fs.trace("delete", filename)(&err)
+
 	sourcePath := fs.GetFilePath(filename)
 	backupPath := sourcePath + ".bak"
 
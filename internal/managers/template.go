@@ -2,7 +2,6 @@ package managers
 
 import (
 	"fmt"
-	"html/template"
 	"strings"
 	"time"
 
@@ -11,7 +10,8 @@ import (
 
 // TemplateManager handles template operations
 type TemplateManager struct {
-	storage *FileStorage
+	storage       *FileStorage
+	schemaManager *SchemaManager
 }
 
 // NewTemplateManager creates a new template manager
@@ -21,6 +21,39 @@ func NewTemplateManager(storage *FileStorage) *TemplateManager {
 	}
 }
 
+// SetSchemaManager makes template validation render sample content built
+// from the site's schema instead of fixed test data
+func (tm *TemplateManager) SetSchemaManager(sm *SchemaManager) {
+	tm.schemaManager = sm
+}
+
+// templateSettingsFilename records which engine renders template.html. It
+// is written with every save so its backup matches the template's.
+const templateSettingsFilename = "template_settings.json"
+
+// templateSettings are stored next to the template
+type templateSettings struct {
+	Engine string `json:"engine"`
+}
+
+// EngineName returns the name of the engine that renders the saved template
+func (tm *TemplateManager) EngineName() string {
+	var settings templateSettings
+	if tm.storage.FileExists(templateSettingsFilename) {
+		tm.storage.ReadJSONFile(templateSettingsFilename, &settings)
+	}
+	if _, err := TemplateEngineByName(settings.Engine); err != nil || settings.Engine == "" {
+		return EngineGo
+	}
+	return settings.Engine
+}
+
+// Engine returns the engine that renders the saved template
+func (tm *TemplateManager) Engine() TemplateEngine {
+	engine, _ := TemplateEngineByName(tm.EngineName())
+	return engine
+}
+
 // LoadTemplate loads the HTML template from file
 func (tm *TemplateManager) LoadTemplate() (string, error) {
 	const filename = "template.html"
@@ -28,7 +61,7 @@ func (tm *TemplateManager) LoadTemplate() (string, error) {
 	if !tm.storage.FileExists(filename) {
 		// Generate default template if none exists
 		defaultTemplate := tm.GetDefaultTemplate()
-		if err := tm.SaveTemplate(defaultTemplate); err != nil {
+		if err := tm.SaveTemplateWithEngine(defaultTemplate, EngineGo); err != nil {
 			return "", fmt.Errorf("failed to create default template: %w", err)
 		}
 		return defaultTemplate, nil
@@ -42,37 +75,89 @@ func (tm *TemplateManager) LoadTemplate() (string, error) {
 	return content, nil
 }
 
-// SaveTemplate saves the HTML template to file
+// SaveTemplate saves the HTML template to file, keeping its engine
 func (tm *TemplateManager) SaveTemplate(content string) error {
+	return tm.SaveTemplateWithEngine(content, tm.EngineName())
+}
+
+// SaveTemplateWithEngine saves the HTML template rendered by the named
+// engine
+func (tm *TemplateManager) SaveTemplateWithEngine(content, engine string) error {
 	const filename = "template.html"
 
 	// Validate template before saving
-	if err := tm.ValidateTemplate(content); err != nil {
+	if err := tm.ValidateTemplateWithEngine(content, engine); err != nil {
 		return fmt.Errorf("template validation failed: %w", err)
 	}
 
 	if err := tm.storage.WriteTextFile(filename, content); err != nil {
 		return fmt.Errorf("failed to save template: %w", err)
 	}
+	if engine == "" {
+		engine = EngineGo
+	}
+	if err := tm.storage.WriteJSONFile(templateSettingsFilename, templateSettings{Engine: engine}); err != nil {
+		return fmt.Errorf("failed to save template settings: %w", err)
+	}
 
 	return nil
 }
 
-// ValidateTemplate validates the HTML template syntax
+// ValidateTemplate validates the HTML template with the saved template's
+// engine
 func (tm *TemplateManager) ValidateTemplate(content string) error {
+	return tm.ValidateTemplateWithEngine(content, tm.EngineName())
+}
+
+// ValidateTemplateWithEngine validates the HTML template syntax for the
+// named engine
+func (tm *TemplateManager) ValidateTemplateWithEngine(content, engineName string) error {
+	// Execute template with sample content from the site's schema, so a
+	// template reading into a section or field the schema lacks fails here
+	// rather than when the site is generated
+	return tm.checkTemplateWith(content, engineName, tm.testData())
+}
+
+// checkTemplateWith validates the HTML template syntax for the named engine
+// and that it renders a page from data
+func (tm *TemplateManager) checkTemplateWith(content, engineName string, data map[string]interface{}) error {
 	// Check if template is not empty
 	if strings.TrimSpace(content) == "" {
 		return fmt.Errorf("template cannot be empty")
 	}
 
-	// Try to parse as Go template
-	tmpl, err := template.New("test").Parse(content)
+	engine, err := TemplateEngineByName(engineName)
 	if err != nil {
+		return err
+	}
+
+	// Try to parse the template
+	if err := engine.Check(content); err != nil {
 		return fmt.Errorf("template parsing failed: %w", err)
 	}
 
-	// Test execution with dummy data to catch runtime errors
-	testData := map[string]interface{}{
+	output, err := engine.Execute(content, data, siteTemplateFuncs(func(src string) string { return src }, time.UTC))
+	if err != nil {
+		return err
+	}
+
+	// Check for basic HTML structure
+	if !strings.Contains(strings.ToLower(output), "<html") {
+		return fmt.Errorf("template must contain valid HTML structure")
+	}
+
+	return nil
+}
+
+// testData returns the data templates are validated with: sample content
+// from the schema when there is one, or fixed hero and about sections
+func (tm *TemplateManager) testData() map[string]interface{} {
+	if tm.schemaManager != nil {
+		if schema, err := tm.schemaManager.LoadSchema(); err == nil {
+			return sampleTemplateData(SampleContent(schema))
+		}
+	}
+	return map[string]interface{}{
 		"title":       "Test Title",
 		"description": "Test Description",
 		"sections": map[string]interface{}{
@@ -90,20 +175,6 @@ func (tm *TemplateManager) ValidateTemplate(content string) error {
 			},
 		},
 	}
-
-	// Execute template with test data
-	var buf strings.Builder
-	if err := tmpl.Execute(&buf, testData); err != nil {
-		return fmt.Errorf("template execution failed: %w", err)
-	}
-
-	// Check for basic HTML structure
-	output := buf.String()
-	if !strings.Contains(strings.ToLower(output), "<html") {
-		return fmt.Errorf("template must contain valid HTML structure")
-	}
-
-	return nil
 }
 
 // GetDefaultTemplate returns the default HTML template
@@ -254,6 +325,123 @@ func (tm *TemplateManager) GetDefaultTemplate() string {
             margin-bottom: 1rem;
         }
         
+        /* Gallery */
+        .gallery-grid {
+            display: grid;
+            grid-template-columns: repeat(auto-fill, minmax(220px, 1fr));
+            gap: 1rem;
+            margin-top: 2rem;
+        }
+        
+        .gallery-carousel {
+            display: flex;
+            gap: 1rem;
+            margin-top: 2rem;
+            overflow-x: auto;
+            scroll-snap-type: x mandatory;
+        }
+        
+        .gallery-carousel .gallery-item {
+            flex: 0 0 80%;
+            max-width: 480px;
+            scroll-snap-align: center;
+        }
+        
+        .gallery-item img {
+            width: 100%;
+            border-radius: 10px;
+            display: block;
+        }
+        
+        .gallery-item figcaption {
+            margin-top: 0.5rem;
+            color: #666;
+            font-size: 0.9rem;
+        }
+        
+        /* Pricing */
+        .pricing-grid {
+            display: grid;
+            grid-template-columns: repeat(auto-fit, minmax(250px, 1fr));
+            gap: 2rem;
+            margin-top: 2rem;
+        }
+        
+        .pricing-card {
+            background: white;
+            padding: 2rem;
+            border-radius: 10px;
+            box-shadow: 0 5px 15px rgba(0,0,0,0.1);
+            text-align: center;
+        }
+        
+        .pricing-card.highlight {
+            border: 2px solid #007cba;
+        }
+        
+        .pricing-card .price {
+            font-size: 2rem;
+            font-weight: bold;
+            color: #007cba;
+            margin: 1rem 0;
+        }
+        
+        .pricing-card .price small {
+            font-size: 1rem;
+            color: #666;
+        }
+        
+        .pricing-card ul {
+            list-style: none;
+            margin-bottom: 1.5rem;
+        }
+        
+        /* Event */
+        .event-details {
+            text-align: center;
+        }
+        
+        .event-details .event-time {
+            font-size: 1.25rem;
+            color: #007cba;
+            margin: 1rem 0;
+        }
+        
+        .event-countdown {
+            font-size: 2rem;
+            font-weight: bold;
+            margin: 1rem 0;
+        }
+        
+        /* Embeds */
+        .embed {
+            position: relative;
+            aspect-ratio: 16 / 9;
+            margin-top: 2rem;
+            background: #222;
+            border-radius: 10px;
+            overflow: hidden;
+            display: flex;
+            align-items: center;
+            justify-content: center;
+        }
+        
+        .embed iframe {
+            position: absolute;
+            inset: 0;
+            width: 100%;
+            height: 100%;
+            border: 0;
+        }
+        
+        .embed-load {
+            color: white;
+            padding: 1rem 2rem;
+            border: 2px solid white;
+            border-radius: 5px;
+            text-decoration: none;
+        }
+        
         /* Contact */
         .contact-info {
             display: grid;
@@ -270,6 +458,12 @@ func (tm *TemplateManager) GetDefaultTemplate() string {
             color: #007cba;
             margin-bottom: 1rem;
         }
+
+        .contact-card img {
+            width: 160px;
+            height: 160px;
+            margin-top: 1rem;
+        }
         
         /* Footer */
         footer {
@@ -306,7 +500,7 @@ func (tm *TemplateManager) GetDefaultTemplate() string {
 
     <!-- Hero Section -->
     {{with .sections.hero}}
-    <section class="hero">
+    <section class="hero" id="{{$.anchors.hero}}">
         <div class="container">
             <h1>{{.title}}</h1>
             <p>{{.subtitle}}</p>
@@ -319,7 +513,7 @@ func (tm *TemplateManager) GetDefaultTemplate() string {
 
     <!-- About Section -->
     {{with .sections.about}}
-    <section class="section">
+    <section class="section" id="{{$.anchors.about}}">
         <div class="container">
             <h2>{{.title}}</h2>
             <div class="about-content">
@@ -332,13 +526,89 @@ func (tm *TemplateManager) GetDefaultTemplate() string {
                 </div>
                 {{end}}
             </div>
+            {{if .video}}{{embed .video}}{{end}}
         </div>
     </section>
     {{end}}
 
+    <!-- Gallery Section -->
+    {{with .sections.gallery}}
+    <section class="section" id="{{$.anchors.gallery}}">
+        <div class="container">
+            {{if .title}}<h2>{{.title}}</h2>{{end}}
+            <div class="gallery gallery-{{or .layout "grid"}}" data-lightbox-group="gallery">
+                {{range .images}}
+                <figure class="gallery-item">
+                    <a href="{{.src}}" data-lightbox="gallery" data-caption="{{.caption}}">
+                        <img src="{{or .thumbnail .src}}" alt="{{or .alt .caption}}" loading="lazy" decoding="async">
+                    </a>
+                    {{if .caption}}<figcaption>{{.caption}}</figcaption>{{end}}
+                </figure>
+                {{end}}
+            </div>
+        </div>
+    </section>
+    {{end}}
+
+    <!-- Pricing Section -->
+    {{with .sections.pricing}}
+    <section class="section" id="{{$.anchors.pricing}}">
+        <div class="container">
+            {{if .title}}<h2>{{.title}}</h2>{{end}}
+            <div class="pricing-grid">
+                {{range .plans}}
+                <div class="pricing-card{{if .highlight}} highlight{{end}}">
+                    <h3>{{.name}}</h3>
+                    <p class="price">{{.price}} {{.currency}}{{if .period}}{{if ne .period "one-time"}} <small>/ {{.period}}</small>{{end}}{{end}}</p>
+                    {{if .features}}
+                    <ul>
+                        {{range .features}}<li>{{.}}</li>{{end}}
+                    </ul>
+                    {{end}}
+                    {{if .button_text}}<a href="{{or .button_link "#contact"}}" class="btn">{{.button_text}}</a>{{end}}
+                </div>
+                {{end}}
+            </div>
+        </div>
+    </section>
+    {{end}}
+
+    <!-- Event Section -->
+    {{with .sections.event}}
+    <section class="section" id="{{$.anchors.event}}">
+        <div class="container event-details">
+            <h2>{{.title}}</h2>
+            <p class="event-time">
+                <time datetime="{{.start}}">{{localTime .start .timezone}}</time>{{if .end}} – <time datetime="{{.end}}">{{localTime .end .timezone}}</time>{{end}}
+            </p>
+            {{if .location}}<p>{{.location}}{{if .address}}, {{.address}}{{end}}</p>{{end}}
+            {{if .description}}<p>{{.description}}</p>{{end}}
+            {{if .countdown}}<p class="event-countdown" data-countdown="{{.start}}" aria-live="off"></p>{{end}}
+            {{if .rsvp_link}}<a href="{{.rsvp_link}}" class="btn">{{or .rsvp_text "RSVP"}}</a>{{end}}
+            {{if $.events_ics}}<p><a href="{{$.events_ics}}" download>Add to calendar</a></p>{{end}}
+        </div>
+        <script type="application/ld+json">
+        {"@context": "https://schema.org", "@type": "Event", "name": {{.title}}, "startDate": {{.start}}{{if .end}}, "endDate": {{.end}}{{end}}{{if .description}}, "description": {{.description}}{{end}}{{if .location}}, "location": {"@type": "Place", "name": {{.location}}{{if .address}}, "address": {{.address}}{{end}}}{{end}}{{if .rsvp_link}}, "url": {{.rsvp_link}}{{end}}}
+        </script>
+        {{if .countdown}}
+        <script>
+        document.querySelectorAll('[data-countdown]').forEach(function (el) {
+            var target = Date.parse(el.getAttribute('data-countdown'));
+            function tick() {
+                var s = Math.max(0, Math.floor((target - Date.now()) / 1000));
+                el.textContent = s ? Math.floor(s / 86400) + 'd ' + Math.floor(s % 86400 / 3600) + 'h ' + Math.floor(s % 3600 / 60) + 'm ' + (s % 60) + 's' : 'Happening now';
+                if (s) setTimeout(tick, 1000);
+            }
+            tick();
+        });
+        </script>
+        {{end}}
+    </section>
+    {{end}}
+
     <!-- Services Section -->
     {{with .sections.services}}
-    <section class="section">
+    <section class="section" id="{{$.anchors.services}}">
         <div class="container">
             <h2>{{.title}}</h2>
             {{if .items}}
@@ -360,7 +630,7 @@ func (tm *TemplateManager) GetDefaultTemplate() string {
 
     <!-- Contact Section -->
     {{with .sections.contact}}
-    <section class="section">
+    <section class="section" id="{{$.anchors.contact}}">
         <div class="container">
             <h2>{{.title}}</h2>
             <div class="contact-info">
@@ -382,6 +652,13 @@ func (tm *TemplateManager) GetDefaultTemplate() string {
                     <p>{{.address}}</p>
                 </div>
                 {{end}}
+                {{if $.vcard_url}}
+                <div class="contact-item contact-card">
+                    <h3>Save Contact</h3>
+                    <p><a href="{{$.vcard_url}}" download>Download contact card</a></p>
+                    <img src="{{$.vcard_qr}}" alt="QR code with our contact details" loading="lazy">
+                </div>
+                {{end}}
             </div>
         </div>
     </section>
@@ -393,6 +670,21 @@ func (tm *TemplateManager) GetDefaultTemplate() string {
             <p>&copy; 2025 {{.title}}. All rights reserved.</p>
         </div>
     </footer>
+
+    <script>
+    // Embeds load only when clicked, so visitors are not tracked by default
+    document.querySelectorAll('.embed[data-embed-src]').forEach(function (embed) {
+        embed.querySelector('.embed-load').addEventListener('click', function (event) {
+            event.preventDefault();
+            var frame = document.createElement('iframe');
+            frame.src = embed.getAttribute('data-embed-src');
+            frame.title = embed.getAttribute('data-embed-title');
+            frame.allow = 'autoplay; encrypted-media; fullscreen; picture-in-picture';
+            frame.referrerPolicy = 'strict-origin-when-cross-origin';
+            embed.replaceChildren(frame);
+        });
+    });
+    </script>
 </body>
 </html>`
 }
@@ -443,6 +735,10 @@ func (tm *TemplateManager) RestoreTemplate() error {
 	if err := tm.storage.RestoreFromBackup(filename); err != nil {
 		return fmt.Errorf("failed to restore template from backup: %w", err)
 	}
+	// Templates saved before engines were recorded are Go templates
+	if err := tm.storage.RestoreFromBackup(templateSettingsFilename); err != nil {
+		tm.storage.DeleteFile(templateSettingsFilename)
+	}
 
 	// Validate restored template
 	content, err := tm.LoadTemplate()
@@ -468,63 +764,39 @@ func (tm *TemplateManager) DeleteTemplate() error {
 	return nil
 }
 
-// GetTemplateVariables extracts variables used in the template
-func (tm *TemplateManager) GetTemplateVariables(content string) ([]string, error) {
-	tmpl, err := template.New("analysis").Parse(content)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse template for analysis: %w", err)
-	}
+// TemplateVariables are the content paths a template reads, in the
+// notation of TemplateField, and those of them the schema does not declare
+type TemplateVariables struct {
+	Variables []TemplateField `json:"variables"`
+	Undefined []string        `json:"undefined"`
+}
 
-	// This is a simple extraction - in a real implementation you might want
-	// to use a more sophisticated method to extract all template variables
-	variables := []string{
-		"title",
-		"description",
-		"sections.hero.title",
-		"sections.hero.subtitle",
-		"sections.hero.image",
-		"sections.hero.button_text",
-		"sections.hero.button_link",
-		"sections.about.title",
-		"sections.about.content",
-		"sections.about.image",
-		"sections.services.title",
-		"sections.services.items",
-		"sections.contact.title",
-		"sections.contact.email",
-		"sections.contact.phone",
-		"sections.contact.address",
+// Paths returns the paths of the variables
+func (tv *TemplateVariables) Paths() []string {
+	paths := make([]string, len(tv.Variables))
+	for i, field := range tv.Variables {
+		paths[i] = field.Path
 	}
+	return paths
+}
 
-	// Test that the template can execute (basic validation)
-	testData := map[string]interface{}{
-		"title":       "Test",
-		"description": "Test",
-		"sections": map[string]interface{}{
-			"hero": map[string]interface{}{
-				"title": "Test", "subtitle": "Test", "image": "test.jpg",
-				"button_text": "Test", "button_link": "#test",
-			},
-			"about": map[string]interface{}{
-				"title": "Test", "content": "Test", "image": "test.jpg",
-			},
-			"services": map[string]interface{}{
-				"title": "Test",
-				"items": []map[string]interface{}{
-					{"title": "Test", "description": "Test", "image": "test.jpg"},
-				},
-			},
-			"contact": map[string]interface{}{
-				"title": "Test", "email": "test@test.com",
-				"phone": "123", "address": "Test",
-			},
-		},
+// GetTemplateVariables walks the parsed template, with the engine of the
+// saved template, for the content paths it reads, following with and
+// range scopes, and checks them against the site's schema. Undefined is
+// empty when there is no schema manager.
+func (tm *TemplateManager) GetTemplateVariables(content string) (*TemplateVariables, error) {
+	fields, err := tm.Engine().Fields(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template for analysis: %w", err)
 	}
 
-	var buf strings.Builder
-	if err := tmpl.Execute(&buf, testData); err != nil {
-		return nil, fmt.Errorf("template execution test failed: %w", err)
+	variables := &TemplateVariables{Variables: fields, Undefined: make([]string, 0)}
+	if tm.schemaManager != nil {
+		schema, err := tm.schemaManager.LoadSchema()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load schema for analysis: %w", err)
+		}
+		variables.Undefined = UndefinedTemplateFields(fields, schema)
 	}
-
 	return variables, nil
 }
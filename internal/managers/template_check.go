@@ -0,0 +1,110 @@
+package managers
+
+import (
+	"sort"
+
+	"onepagems/internal/types"
+)
+
+// maxTypoDistance is how many edits apart a template variable and a
+// declared field may be for the field to be suggested as what was meant
+const maxTypoDistance = 2
+
+// TemplateFieldIssue is a template variable with nothing behind it, and
+// the declared field it is most likely a typo of, if any
+type TemplateFieldIssue struct {
+	Path       string `json:"path"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// TemplateCheck cross-references a template with the schema and content.
+// Paths are in the notation of TemplateField.
+type TemplateCheck struct {
+	// OK is true when every variable the template reads has a field behind it
+	OK bool `json:"ok"`
+	// Unbacked variables have neither a schema property nor content, so
+	// they always render blank
+	Unbacked []TemplateFieldIssue `json:"unbacked"`
+	// ContentOnly variables have content but no schema property, so the
+	// editor cannot change them
+	ContentOnly []string `json:"content_only"`
+	// Unrendered fields are declared in the schema but never shown
+	Unrendered []string `json:"unrendered"`
+	// Empty fields are declared and shown but have no content yet
+	Empty []string `json:"empty"`
+}
+
+// CheckTemplate reports the variables of a template that no schema
+// property or content value backs, suggesting the declared field each was
+// probably meant to be, and the schema fields the template never renders.
+// engine reads the template's fields.
+func CheckTemplate(engine TemplateEngine, templateContent string, schema *types.SchemaData, content *types.ContentData) (*TemplateCheck, error) {
+	if resolved, err := ResolveSchemaRefs(schema); err == nil {
+		schema = resolved
+	}
+	compat, err := CheckTemplateCompatibility(engine, templateContent, schema, content)
+	if err != nil {
+		return nil, err
+	}
+
+	contentMap := map[string]interface{}{
+		"title":       content.Title,
+		"description": content.Description,
+		"sections":    content.Sections,
+	}
+	declared := make([]string, 0)
+	for _, path := range schemaLeafPaths(schema.Properties, nil) {
+		declared = append(declared, joinFieldPath(path))
+	}
+	sort.Strings(declared)
+
+	check := &TemplateCheck{
+		Unbacked:    make([]TemplateFieldIssue, 0),
+		ContentOnly: make([]string, 0),
+		Unrendered:  compat.Unused,
+		Empty:       compat.Empty,
+	}
+	for _, path := range compat.Missing {
+		if contentFilledAt(contentMap, splitFieldPath(path)) {
+			check.ContentOnly = append(check.ContentOnly, path)
+			continue
+		}
+		check.Unbacked = append(check.Unbacked, TemplateFieldIssue{Path: path, Suggestion: closestFieldPath(path, declared)})
+	}
+	check.OK = len(check.Unbacked) == 0
+	return check, nil
+}
+
+// closestFieldPath returns the declared path fewest edits from path, if it
+// is close enough to be a typo
+func closestFieldPath(path string, declared []string) string {
+	best, bestDistance := "", maxTypoDistance+1
+	for _, candidate := range declared {
+		if distance := editDistance(path, candidate); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b, in runes
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	previous := make([]int, len(br)+1)
+	current := make([]int, len(br)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		current[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(br)]
+}
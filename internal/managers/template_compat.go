@@ -0,0 +1,311 @@
+package managers
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"onepagems/internal/types"
+)
+
+// TemplateCompatibility compares the fields a template reads with the
+// schema and content, before the template replaces the current one.
+// Paths are in the notation of TemplateField.
+type TemplateCompatibility struct {
+	// Compatible is true when the schema declares every field the template reads
+	Compatible bool `json:"compatible"`
+	// Missing fields are read by the template but not declared in the schema
+	Missing []string `json:"missing"`
+	// Empty fields are declared but have no content, so render blank
+	Empty []string `json:"empty"`
+	// Unused fields are declared in the schema but never shown by the template
+	Unused []string `json:"unused"`
+	// Added fields were declared in the schema to make the template fit
+	Added []string `json:"added,omitempty"`
+}
+
+// CheckTemplateCompatibility reports which fields a template reads that the
+// schema lacks or the content leaves empty, and which schema fields it
+// does not show. engine reads the template's fields.
+func CheckTemplateCompatibility(engine TemplateEngine, templateContent string, schema *types.SchemaData, content *types.ContentData) (*TemplateCompatibility, error) {
+	fields, err := engine.Fields(templateContent)
+	if err != nil {
+		return nil, err
+	}
+
+	root := map[string]interface{}{"type": "object", "properties": schema.Properties}
+	contentMap := map[string]interface{}{
+		"title":       content.Title,
+		"description": content.Description,
+		"sections":    content.Sections,
+	}
+
+	report := &TemplateCompatibility{Missing: make([]string, 0), Empty: make([]string, 0), Unused: make([]string, 0)}
+	for _, field := range leafTemplateFields(fields) {
+		if generatedThumbnail(fields, field) {
+			continue
+		}
+		if !schemaDeclaresPath(root, field.Steps) {
+			report.Missing = append(report.Missing, field.Path)
+		} else if !contentFilledAt(contentMap, field.Steps) {
+			report.Empty = append(report.Empty, field.Path)
+		}
+	}
+
+	for _, path := range schemaLeafPaths(schema.Properties, nil) {
+		if !templateShows(fields, path) {
+			report.Unused = append(report.Unused, joinFieldPath(path))
+		}
+	}
+	sort.Strings(report.Unused)
+
+	report.Compatible = len(report.Missing) == 0
+	return report, nil
+}
+
+// UndefinedTemplateFields returns the paths of the fields a template reads
+// that schema does not declare, after resolving its $refs. Fields that only
+// lead to deeper ones and generated thumbnails are not reported.
+func UndefinedTemplateFields(fields []TemplateField, schema *types.SchemaData) []string {
+	if resolved, err := ResolveSchemaRefs(schema); err == nil {
+		schema = resolved
+	}
+	root := map[string]interface{}{"type": "object", "properties": schema.Properties}
+
+	undefined := make([]string, 0)
+	for _, field := range leafTemplateFields(fields) {
+		if !generatedThumbnail(fields, field) && !schemaDeclaresPath(root, field.Steps) {
+			undefined = append(undefined, field.Path)
+		}
+	}
+	return undefined
+}
+
+// AddTemplateFields declares the given missing fields in the schema as
+// optional properties: strings at the end of each path, objects and arrays
+// of objects on the way. Paths that run into a declared non-object field
+// are skipped. It returns the paths added.
+func AddTemplateFields(schema *types.SchemaData, paths []string) []string {
+	added := make([]string, 0, len(paths))
+	for _, path := range paths {
+		steps := splitFieldPath(path)
+		if len(steps) == 0 {
+			continue
+		}
+		if addSchemaPath(schema.Properties, steps) {
+			added = append(added, path)
+		}
+	}
+	return added
+}
+
+// addSchemaPath declares the steps under properties, reporting whether the
+// whole path could be declared
+func addSchemaPath(properties map[string]interface{}, steps []string) bool {
+	name, rest := steps[0], steps[1:]
+	prop, exists := properties[name].(map[string]interface{})
+	if !exists {
+		switch {
+		case len(rest) == 0:
+			prop = map[string]interface{}{"type": "string"}
+		case rest[0] == "[]":
+			prop = map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+		default:
+			prop = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		properties[name] = prop
+	}
+	if len(rest) == 0 {
+		return true
+	}
+
+	if rest[0] == "[]" {
+		if primaryType(prop) != "array" {
+			return false
+		}
+		rest = rest[1:]
+		if len(rest) == 0 {
+			return true
+		}
+		items, _ := prop["items"].(map[string]interface{})
+		if items == nil || (primaryType(items) == "string" && items["properties"] == nil) {
+			items = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+			prop["items"] = items
+		}
+		prop = items
+	}
+
+	if primaryType(prop) != "object" {
+		return false
+	}
+	children, ok := prop["properties"].(map[string]interface{})
+	if !ok {
+		children = make(map[string]interface{})
+		prop["properties"] = children
+	}
+	return addSchemaPath(children, rest)
+}
+
+// generatedThumbnail reports whether a field is the thumbnail the generator
+// adds to image items, next to a src the template also reads
+func generatedThumbnail(fields []TemplateField, field TemplateField) bool {
+	parent, ok := strings.CutSuffix(field.Path, ".thumbnail")
+	return ok && slices.ContainsFunc(fields, func(other TemplateField) bool { return other.Path == parent+".src" })
+}
+
+// leafTemplateFields drops fields that are only the way to deeper ones
+func leafTemplateFields(fields []TemplateField) []TemplateField {
+	leaves := make([]TemplateField, 0, len(fields))
+	for _, field := range fields {
+		if !slices.ContainsFunc(fields, func(other TemplateField) bool { return isDeeperFieldPath(other.Path, field.Path) }) {
+			leaves = append(leaves, field)
+		}
+	}
+	return leaves
+}
+
+// isDeeperFieldPath reports whether path lies under parent
+func isDeeperFieldPath(path, parent string) bool {
+	rest, ok := strings.CutPrefix(path, parent)
+	return ok && (strings.HasPrefix(rest, ".") || strings.HasPrefix(rest, "[]"))
+}
+
+// schemaDeclaresPath reports whether a property schema declares the steps
+// below it. Objects without declared properties, or that allow additional
+// ones, accept any field; arrays accept any item if their items are not
+// described.
+func schemaDeclaresPath(prop map[string]interface{}, steps []string) bool {
+	for i, step := range steps {
+		if step == "[]" {
+			if primaryType(prop) != "array" {
+				// Ranging over an object visits its values, which cannot be told apart
+				return primaryType(prop) == "object"
+			}
+			items, ok := prop["items"].(map[string]interface{})
+			if !ok {
+				return true
+			}
+			prop = items
+			continue
+		}
+
+		// Engines that cannot tell lists from objects name item fields directly
+		if items, ok := prop["items"].(map[string]interface{}); ok && primaryType(prop) == "array" {
+			prop = items
+		}
+		if primaryType(prop) != "object" {
+			return false
+		}
+		properties, _ := prop["properties"].(map[string]interface{})
+		if child, ok := properties[step].(map[string]interface{}); ok {
+			prop = child
+			continue
+		}
+		// The generator adds a thumbnail to every image item
+		if step == "thumbnail" && i == len(steps)-1 && properties["src"] != nil {
+			return true
+		}
+		switch additional := prop["additionalProperties"].(type) {
+		case map[string]interface{}:
+			prop = additional
+			continue
+		case bool:
+			if additional {
+				return true
+			}
+		}
+		return len(properties) == 0 && prop["additionalProperties"] == nil
+	}
+	return true
+}
+
+// contentFilledAt reports whether content has a non-empty value at the
+// steps; for "[]" any item will do
+func contentFilledAt(value interface{}, steps []string) bool {
+	if len(steps) == 0 {
+		return !emptyContentValue(value)
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		rest := steps
+		if steps[0] == "[]" {
+			rest = steps[1:]
+		}
+		for _, item := range v {
+			if contentFilledAt(item, rest) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		if steps[0] == "[]" {
+			for _, item := range v {
+				if contentFilledAt(item, steps[1:]) {
+					return true
+				}
+			}
+			return false
+		}
+		return contentFilledAt(v[steps[0]], steps[1:])
+	}
+	return false
+}
+
+// schemaLeafPaths lists the public fields a page can show: properties
+// without nested properties, with array items of objects descended into
+func schemaLeafPaths(properties map[string]interface{}, prefix []string) [][]string {
+	var paths [][]string
+	for name, value := range properties {
+		prop, ok := value.(map[string]interface{})
+		if !ok || isPrivate(prop) {
+			continue
+		}
+		path := appendStep(prefix, name)
+		if nested, ok := prop["properties"].(map[string]interface{}); ok && len(nested) > 0 {
+			paths = append(paths, schemaLeafPaths(nested, path)...)
+			continue
+		}
+		if items, ok := prop["items"].(map[string]interface{}); ok {
+			if nested, ok := items["properties"].(map[string]interface{}); ok && len(nested) > 0 {
+				paths = append(paths, schemaLeafPaths(nested, appendStep(path, "[]"))...)
+				continue
+			}
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// templateShows reports whether a template reads a schema field: the field
+// itself, something inside it, or a containing object it outputs whole
+func templateShows(fields []TemplateField, path []string) bool {
+	target := joinFieldPath(path)
+	for _, field := range fields {
+		if field.Path == target || isDeeperFieldPath(field.Path, target) {
+			return true
+		}
+		if field.Whole && isDeeperFieldPath(target, field.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitFieldPath parses "a.b[].c" into steps
+func splitFieldPath(path string) []string {
+	var steps []string
+	for _, part := range strings.Split(path, ".") {
+		name, items := part, 0
+		for strings.HasSuffix(name, "[]") {
+			name = strings.TrimSuffix(name, "[]")
+			items++
+		}
+		if name == "" {
+			return nil
+		}
+		steps = append(steps, name)
+		for ; items > 0; items-- {
+			steps = append(steps, "[]")
+		}
+	}
+	return steps
+}
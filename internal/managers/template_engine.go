@@ -0,0 +1,86 @@
+package managers
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Template engines
+const (
+	EngineGo       = "go"
+	EngineMustache = "mustache"
+)
+
+// TemplateEngine renders the site template. The engine is chosen per
+// template, so themes written for other ecosystems can be used as they are.
+type TemplateEngine interface {
+	// Name identifies the engine where the template's settings are stored
+	Name() string
+	// Check parses source and reports syntax errors
+	Check(source string) error
+	// Execute renders source with data. funcs are the site template helpers,
+	// for engines that can call them.
+	Execute(source string, data map[string]interface{}, funcs template.FuncMap) (string, error)
+	// Fields lists the content paths source reads
+	Fields(source string) ([]TemplateField, error)
+}
+
+// templateEngines are the available engines by name
+var templateEngines = map[string]TemplateEngine{
+	EngineGo:       goTemplateEngine{},
+	EngineMustache: mustacheEngine{},
+}
+
+// TemplateEngineByName returns the named engine; an empty name is Go
+// templates
+func TemplateEngineByName(name string) (TemplateEngine, error) {
+	if name == "" {
+		name = EngineGo
+	}
+	engine, ok := templateEngines[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown template engine %q (available: %s)", name, strings.Join(TemplateEngineNames(), ", "))
+	}
+	return engine, nil
+}
+
+// TemplateEngineNames lists the available engines, sorted
+func TemplateEngineNames() []string {
+	names := make([]string, 0, len(templateEngines))
+	for name := range templateEngines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// goTemplateEngine renders Go html/template syntax with the site helpers
+type goTemplateEngine struct{}
+
+func (goTemplateEngine) Name() string {
+	return EngineGo
+}
+
+func (goTemplateEngine) Check(source string) error {
+	_, err := template.New("site").Funcs(siteTemplateFuncs(func(src string) string { return src }, time.UTC)).Parse(source)
+	return err
+}
+
+func (goTemplateEngine) Execute(source string, data map[string]interface{}, funcs template.FuncMap) (string, error) {
+	tmpl, err := template.New("site").Funcs(funcs).Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("template parsing failed: %w", err)
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("template execution failed: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func (goTemplateEngine) Fields(source string) ([]TemplateField, error) {
+	return TemplateFields(source)
+}
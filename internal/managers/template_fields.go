@@ -0,0 +1,238 @@
+package managers
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"text/template/parse"
+	"time"
+)
+
+// templateSystemFields are keys the generator adds to template data besides
+// the content itself
+var templateSystemFields = map[string]bool{
+	"anchors":       true,
+	"last_updated":  true,
+	"variant":       true,
+	"vcard_url":     true,
+	"vcard_qr":      true,
+	"site_qr":       true,
+	"events_ics":    true,
+	"sections_html": true,
+	"section_html":  true,
+}
+
+// TemplateField is a content path a template reads, such as
+// "sections.hero.title". "[]" stands for each item of the array before it,
+// as in "sections.services.items[].title". Whole is true when the value
+// itself is output or passed to a function, rather than only entered with
+// with or range.
+type TemplateField struct {
+	Path  string   `json:"path"`
+	Steps []string `json:"-"`
+	Whole bool     `json:"whole,omitempty"`
+}
+
+// TemplateFields lists the content paths a template reads, sorted. Dot is
+// followed through with, range, variables and {{template}} calls; fields
+// read from function results cannot be known and are left out, as are the
+// keys the generator adds such as anchors.
+func TemplateFields(content string) ([]TemplateField, error) {
+	tmpl, err := template.New("fields").Funcs(siteTemplateFuncs(func(src string) string { return src }, time.UTC)).Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("template parsing failed: %w", err)
+	}
+
+	walker := &fieldWalker{
+		trees:  make(map[string]*parse.Tree),
+		fields: make(map[string]*TemplateField),
+		walked: make(map[string]bool),
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			walker.trees[t.Name()] = t.Tree
+		}
+	}
+	if tmpl.Tree != nil {
+		root := []string{}
+		walker.walk(tmpl.Tree.Root, &fieldScope{dot: root, vars: map[string][]string{"$": root}})
+	}
+
+	fields := make([]TemplateField, 0, len(walker.fields))
+	for _, field := range walker.fields {
+		fields = append(fields, *field)
+	}
+	sortTemplateFields(fields)
+	return fields, nil
+}
+
+// sortTemplateFields orders fields by path
+func sortTemplateFields(fields []TemplateField) {
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Path < fields[j].Path })
+}
+
+// fieldWalker collects the content paths read while walking template trees
+type fieldWalker struct {
+	trees  map[string]*parse.Tree
+	fields map[string]*TemplateField
+	walked map[string]bool // template calls already followed, by name and dot
+}
+
+// fieldScope is what dot and each variable refer to. A nil path is unknown,
+// such as the result of a function; an empty one is the template data.
+type fieldScope struct {
+	dot  []string
+	vars map[string][]string
+}
+
+// with returns a scope for a nested block, whose variables do not leak out
+func (s *fieldScope) with(dot []string) *fieldScope {
+	vars := make(map[string][]string, len(s.vars))
+	for name, path := range s.vars {
+		vars[name] = path
+	}
+	return &fieldScope{dot: dot, vars: vars}
+}
+
+// walk visits a node and everything under it
+func (fw *fieldWalker) walk(node parse.Node, scope *fieldScope) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			fw.walk(child, scope)
+		}
+	case *parse.ActionNode:
+		fw.declare(n.Pipe, fw.pipe(n.Pipe, scope, true), scope)
+	case *parse.IfNode:
+		inner := scope.with(scope.dot)
+		fw.declare(n.Pipe, fw.pipe(n.Pipe, inner, true), inner)
+		fw.walk(n.List, inner)
+		fw.walk(n.ElseList, scope.with(scope.dot))
+	case *parse.WithNode:
+		path := fw.pipe(n.Pipe, scope, false)
+		inner := scope.with(path)
+		fw.declare(n.Pipe, path, inner)
+		fw.walk(n.List, inner)
+		fw.walk(n.ElseList, scope.with(scope.dot))
+	case *parse.RangeNode:
+		path := fw.pipe(n.Pipe, scope, false)
+		var item []string
+		if path != nil {
+			item = appendStep(path, "[]")
+		}
+		inner := scope.with(item)
+		switch len(n.Pipe.Decl) {
+		case 1:
+			inner.vars[n.Pipe.Decl[0].Ident[0]] = item
+		case 2:
+			inner.vars[n.Pipe.Decl[0].Ident[0]] = nil
+			inner.vars[n.Pipe.Decl[1].Ident[0]] = item
+		}
+		fw.walk(n.List, inner)
+		fw.walk(n.ElseList, scope.with(scope.dot))
+	case *parse.TemplateNode:
+		var dot []string
+		if n.Pipe != nil {
+			dot = fw.pipe(n.Pipe, scope, false)
+		}
+		key := n.Name + "\x00" + joinFieldPath(dot)
+		tree, ok := fw.trees[n.Name]
+		if !ok || dot == nil || fw.walked[key] {
+			return
+		}
+		fw.walked[key] = true
+		fw.walk(tree.Root, &fieldScope{dot: dot, vars: map[string][]string{"$": dot}})
+	}
+}
+
+// declare binds the variables a pipeline declares to its value
+func (fw *fieldWalker) declare(pipe *parse.PipeNode, path []string, scope *fieldScope) {
+	if pipe == nil {
+		return
+	}
+	for _, variable := range pipe.Decl {
+		scope.vars[variable.Ident[0]] = path
+	}
+}
+
+// pipe records the fields a pipeline reads and returns the path of its
+// value, or nil if the value comes from a function. A lone field is
+// recorded as whole when use is true; with and range only enter it.
+func (fw *fieldWalker) pipe(pipe *parse.PipeNode, scope *fieldScope, use bool) []string {
+	if pipe == nil {
+		return nil
+	}
+	if len(pipe.Cmds) == 1 && len(pipe.Cmds[0].Args) == 1 {
+		path := fw.arg(pipe.Cmds[0].Args[0], scope)
+		fw.record(path, use)
+		return path
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			fw.record(fw.arg(arg, scope), true)
+		}
+	}
+	return nil
+}
+
+// arg returns the path an argument refers to, or nil if unknown
+func (fw *fieldWalker) arg(node parse.Node, scope *fieldScope) []string {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return scope.dot
+	case *parse.FieldNode:
+		if scope.dot == nil {
+			return nil
+		}
+		return appendStep(scope.dot, n.Ident...)
+	case *parse.VariableNode:
+		base, ok := scope.vars[n.Ident[0]]
+		if !ok || base == nil {
+			return nil
+		}
+		return appendStep(base, n.Ident[1:]...)
+	case *parse.ChainNode:
+		base := fw.arg(n.Node, scope)
+		if base == nil {
+			return nil
+		}
+		return appendStep(base, n.Field...)
+	case *parse.PipeNode:
+		return fw.pipe(n, scope, true)
+	}
+	return nil
+}
+
+// record notes that a content path is read
+func (fw *fieldWalker) record(path []string, whole bool) {
+	if len(path) == 0 || templateSystemFields[path[0]] {
+		return
+	}
+	key := joinFieldPath(path)
+	if field, ok := fw.fields[key]; ok {
+		field.Whole = field.Whole || whole
+		return
+	}
+	fw.fields[key] = &TemplateField{Path: key, Steps: path, Whole: whole}
+}
+
+// appendStep returns a copy of path with steps added
+func appendStep(path []string, steps ...string) []string {
+	return append(append(make([]string, 0, len(path)+len(steps)), path...), steps...)
+}
+
+// joinFieldPath formats path steps as "a.b[].c"
+func joinFieldPath(path []string) string {
+	var b strings.Builder
+	for i, step := range path {
+		if i > 0 && step != "[]" {
+			b.WriteString(".")
+		}
+		b.WriteString(step)
+	}
+	return b.String()
+}
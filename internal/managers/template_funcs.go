@@ -0,0 +1,100 @@
+package managers
+
+import (
+	"fmt"
+	"html/template"
+	"time"
+)
+
+// Default layouts of the date helpers
+const (
+	dateLayout     = "2 Jan 2006"
+	dateTimeLayout = "2 Jan 2006 15:04 MST"
+)
+
+// siteTemplateFuncs returns the helpers available to site templates. cdn
+// rewrites image paths; the generator supplies its CDN mapping and template
+// validation passes paths through unchanged. loc is the site time zone that
+// dates are shown in.
+func siteTemplateFuncs(cdn func(string) string, loc *time.Location) template.FuncMap {
+	return template.FuncMap{
+		"cdn":   cdn,
+		"embed": embedHTML,
+		"formatDate": func(value interface{}, layout ...string) string {
+			return formatTime(value, loc, dateLayout, layout)
+		},
+		"formatDateTime": func(value interface{}, layout ...string) string {
+			return formatTime(value, loc, dateTimeLayout, layout)
+		},
+		"localTime": func(value interface{}, zone interface{}) string {
+			return localTime(value, zone, loc)
+		},
+		"slugify": slugify,
+	}
+}
+
+// slugify is Slugify for template values of any type. Sections should use
+// the stored anchors instead, which survive title edits.
+func slugify(value interface{}) string {
+	return Slugify(fmt.Sprint(value))
+}
+
+// localTime formats an RFC3339 date-time for display in the named IANA time
+// zone, or the site time zone when none is named. Values that are not
+// date-times are returned unchanged.
+func localTime(value interface{}, zone interface{}, site *time.Location) string {
+	str, ok := value.(string)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+
+	t, err := time.Parse(time.RFC3339, str)
+	if err != nil {
+		return str
+	}
+
+	loc := site
+	if name, ok := zone.(string); ok && name != "" {
+		if named, err := time.LoadLocation(name); err == nil {
+			loc = named
+		}
+	}
+	return t.In(loc).Format("Mon, 2 Jan 2006 15:04 MST")
+}
+
+// formatTime formats a time, or an RFC3339 or YYYY-MM-DD string, in loc
+// using the first of layouts or else fallback. Other values are returned
+// unchanged.
+func formatTime(value interface{}, loc *time.Location, fallback string, layouts []string) string {
+	var t time.Time
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		t = *v
+	case string:
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			// A plain date has no time to shift between zones
+			if date, dateErr := time.Parse(time.DateOnly, v); dateErr == nil {
+				parsed, err = date, nil
+				loc = time.UTC
+			}
+		}
+		if err != nil {
+			return v
+		}
+		t = parsed
+	default:
+		return fmt.Sprint(value)
+	}
+
+	layout := fallback
+	if len(layouts) > 0 && layouts[0] != "" {
+		layout = layouts[0]
+	}
+	return t.In(loc).Format(layout)
+}
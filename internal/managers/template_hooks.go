@@ -0,0 +1,75 @@
+package managers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"onepagems/internal/tracing"
+)
+
+// TemplateDataHook adds computed values to the template data of a page
+// before it is rendered, e.g. the current year or a value fetched from an
+// external API. Hooks run on every render (generation, preview and live
+// mode) after the content has been converted, so they may read it and may
+// overwrite any key. An error fails the render.
+type TemplateDataHook func(ctx context.Context, data map[string]interface{}) error
+
+// namedTemplateDataHook is a registered hook; the name labels its errors
+// and trace spans
+type namedTemplateDataHook struct {
+	name string
+	hook TemplateDataHook
+}
+
+// AddTemplateDataHook registers hook to run before every render, after the
+// hooks already registered. Register hooks before the server starts.
+func (g *SiteGenerator) AddTemplateDataHook(name string, hook TemplateDataHook) {
+	g.dataHooks = append(g.dataHooks, namedTemplateDataHook{name: name, hook: hook})
+}
+
+// runTemplateDataHooks applies the registered hooks to data in order
+func (g *SiteGenerator) runTemplateDataHooks(ctx context.Context, data map[string]interface{}) error {
+	for _, h := range g.dataHooks {
+		hookCtx, span := tracing.Start(ctx, "generate.hook")
+		span.SetAttribute("hook", h.name)
+		err := h.hook(hookCtx, data)
+		span.RecordError(err)
+		span.End()
+		if err != nil {
+			return fmt.Errorf("template data hook '%s': %w", h.name, err)
+		}
+	}
+	return nil
+}
+
+// CachedTemplateValue returns a hook that sets data[key] to the value of
+// fetch, calling fetch at most once per ttl. When fetch fails, the last
+// value is kept until a later call succeeds; with no value yet the error
+// fails the render.
+func CachedTemplateValue(key string, ttl time.Duration, fetch func(ctx context.Context) (interface{}, error)) TemplateDataHook {
+	var (
+		mu        sync.Mutex
+		value     interface{}
+		fetched   bool
+		fetchedAt time.Time
+	)
+	return func(ctx context.Context, data map[string]interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !fetched || time.Since(fetchedAt) >= ttl {
+			fresh, err := fetch(ctx)
+			switch {
+			case err == nil:
+				value, fetched = fresh, true
+				fetchedAt = time.Now()
+			case !fetched:
+				return err
+			}
+		}
+		data[key] = value
+		return nil
+	}
+}
@@ -0,0 +1,135 @@
+package managers
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Plain-text site files, relative to the output directory
+const (
+	SecurityTxtFilename = ".well-known/security.txt"
+	HumansTxtFilename   = "humans.txt"
+)
+
+// securityTxtLifetime is how long a generated security.txt stays valid.
+// RFC 9116 recommends less than a year; every generation renews it.
+const securityTxtLifetime = 180 * 24 * time.Hour
+
+// BuildSecurityTxt returns an RFC 9116 security.txt, or an empty string
+// when there is no contact. contact is a mailto:, tel: or https: URI; when
+// it is empty the email of sections.contact is used. policy is an optional
+// URL of the vulnerability disclosure policy.
+func BuildSecurityTxt(content map[string]interface{}, contact, policy, siteURL string, now time.Time) string {
+	if contact == "" {
+		if email := contactField(content, "email"); email != "" {
+			contact = "mailto:" + email
+		}
+	}
+	contact = oneLine(contact)
+	if contact == "" {
+		return ""
+	}
+	if !strings.Contains(contact, ":") {
+		// A bare address is the common mistake
+		contact = "mailto:" + contact
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Contact: %s\n", contact)
+	fmt.Fprintf(&b, "Expires: %s\n", now.Add(securityTxtLifetime).UTC().Format(time.RFC3339))
+	if policy = oneLine(policy); policy != "" {
+		fmt.Fprintf(&b, "Policy: %s\n", policy)
+	}
+	if canonical := siteFileURL(siteURL, SecurityTxtFilename); canonical != "" {
+		fmt.Fprintf(&b, "Canonical: %s\n", canonical)
+	}
+	return b.String()
+}
+
+// BuildHumansTxt returns a humans.txt (humanstxt.org) crediting the site
+// owner from the content. Members of a team section, given as items with a
+// name and optional role, are listed too.
+func BuildHumansTxt(content map[string]interface{}, now time.Time) string {
+	var b strings.Builder
+
+	b.WriteString("/* TEAM */\n")
+	if title, ok := content["title"].(string); ok && title != "" {
+		fmt.Fprintf(&b, "Site: %s\n", oneLine(title))
+	}
+	if email := contactField(content, "email"); email != "" {
+		fmt.Fprintf(&b, "Contact: %s\n", oneLine(email))
+	}
+	if address := contactField(content, "address"); address != "" {
+		fmt.Fprintf(&b, "Location: %s\n", oneLine(address))
+	}
+	for _, member := range teamMembers(content) {
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "Name: %s\n", member[0])
+		if member[1] != "" {
+			fmt.Fprintf(&b, "Role: %s\n", member[1])
+		}
+	}
+
+	b.WriteString("\n/* SITE */\n")
+	fmt.Fprintf(&b, "Last update: %s\n", now.Format("2006/01/02"))
+	b.WriteString("Standards: HTML5, CSS3\n")
+	b.WriteString("Software: OnePage CMS\n")
+	return b.String()
+}
+
+// contactField returns a field of sections.contact
+func contactField(content map[string]interface{}, key string) string {
+	sections, _ := content["sections"].(map[string]interface{})
+	contact, _ := sections[vcardContactSection].(map[string]interface{})
+	value, _ := contact[key].(string)
+	return strings.TrimSpace(value)
+}
+
+// teamMembers returns name and role pairs from the item lists of
+// sections.team, in content order
+func teamMembers(content map[string]interface{}) [][2]string {
+	sections, _ := content["sections"].(map[string]interface{})
+	team, _ := sections["team"].(map[string]interface{})
+
+	keys := make([]string, 0, len(team))
+	for key := range team {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var members [][2]string
+	for _, key := range keys {
+		items, ok := team[key].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, item := range items {
+			fields, _ := item.(map[string]interface{})
+			name, _ := fields["name"].(string)
+			if strings.TrimSpace(name) == "" {
+				continue
+			}
+			role, _ := fields["role"].(string)
+			members = append(members, [2]string{oneLine(name), oneLine(role)})
+		}
+	}
+	return members
+}
+
+// siteFileURL returns the public URL of a generated file, or an empty
+// string without a site URL
+func siteFileURL(siteURL, filename string) string {
+	base, err := url.Parse(siteURL)
+	if err != nil || base.Host == "" {
+		return ""
+	}
+	return base.ResolveReference(&url.URL{Path: "/" + filename}).String()
+}
+
+// oneLine collapses whitespace so a value cannot start a new field
+func oneLine(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
@@ -0,0 +1,140 @@
+package managers
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// variantsFilename stores the B variant of sections under experiment
+const variantsFilename = "variants.json"
+
+// Page variants. A is the page built from the content as it is; B replaces
+// each section that has a variant.
+const (
+	VariantA = "a"
+	VariantB = "b"
+)
+
+// Generated files of the B page, next to index.html and its policy
+const (
+	VariantPageFilename   = "index.b.html"
+	variantPolicyFilename = "index.b.csp"
+)
+
+// VariantManager stores alternative versions of sections for A/B tests and
+// counts how often each version of the page is shown
+type VariantManager struct {
+	storage *FileStorage
+
+	mu        sync.Mutex
+	exposures map[string]int64
+	since     time.Time
+}
+
+// VariantExposures counts page views by variant since the server started
+type VariantExposures struct {
+	Counts map[string]int64 `json:"counts"`
+	Since  time.Time        `json:"since"`
+}
+
+// NewVariantManager creates a new variant manager
+func NewVariantManager(storage *FileStorage) *VariantManager {
+	return &VariantManager{
+		storage:   storage,
+		exposures: map[string]int64{VariantA: 0, VariantB: 0},
+		since:     time.Now(),
+	}
+}
+
+// Load returns the B variant of each section under experiment, by key
+func (vm *VariantManager) Load() (map[string]interface{}, error) {
+	variants := make(map[string]interface{})
+	if !vm.storage.FileExists(variantsFilename) {
+		return variants, nil
+	}
+	if err := vm.storage.ReadJSONFile(variantsFilename, &variants); err != nil {
+		return nil, fmt.Errorf("failed to load variants: %w", err)
+	}
+	return variants, nil
+}
+
+// Keys lists the sections that have a variant, sorted
+func (vm *VariantManager) Keys() ([]string, error) {
+	variants, err := vm.Load()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(variants))
+	for key := range variants {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Set stores the B variant of a section
+func (vm *VariantManager) Set(key string, section interface{}) error {
+	variants, err := vm.Load()
+	if err != nil {
+		return err
+	}
+	variants[key] = section
+	return vm.storage.WriteJSONFile(variantsFilename, variants)
+}
+
+// Delete ends the experiment on a section, reporting whether it had one
+func (vm *VariantManager) Delete(key string) (bool, error) {
+	variants, err := vm.Load()
+	if err != nil {
+		return false, err
+	}
+	if _, exists := variants[key]; !exists {
+		return false, nil
+	}
+	delete(variants, key)
+	return true, vm.storage.WriteJSONFile(variantsFilename, variants)
+}
+
+// Apply returns a copy of content with every section that has a variant
+// replaced by it. Variants of sections the content no longer has are
+// skipped. It reports whether any section was replaced.
+func (vm *VariantManager) Apply(content *types.ContentData) (*types.ContentData, bool, error) {
+	variants, err := vm.Load()
+	if err != nil {
+		return nil, false, err
+	}
+
+	applied := *content
+	applied.Sections = make(map[string]interface{}, len(content.Sections))
+	replaced := false
+	for key, section := range content.Sections {
+		if variant, exists := variants[key]; exists {
+			section = variant
+			replaced = true
+		}
+		applied.Sections[key] = section
+	}
+	return &applied, replaced, nil
+}
+
+// RecordExposure counts one view of a page variant
+func (vm *VariantManager) RecordExposure(variant string) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.exposures[variant]++
+}
+
+// Exposures returns the view counts of each variant
+func (vm *VariantManager) Exposures() VariantExposures {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	counts := make(map[string]int64, len(vm.exposures))
+	for variant, count := range vm.exposures {
+		counts[variant] = count
+	}
+	return VariantExposures{Counts: counts, Since: vm.since}
+}
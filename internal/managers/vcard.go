@@ -0,0 +1,91 @@
+package managers
+
+import (
+	"strings"
+)
+
+// Published contact card files, relative to the output directory
+const (
+	VCardFilename       = "contact.vcf"
+	VCardQRFilename     = "qr/contact.svg"
+	SiteQRFilename      = "qr/site.svg"
+	vcardContactSection = "contact"
+)
+
+// BuildVCard returns a vCard 3.0 built from sections.contact of public
+// content, or an empty string if the section has no email, phone or
+// address. The card is named after contact.name, falling back to the site
+// title.
+func BuildVCard(content map[string]interface{}, siteURL string) string {
+	sections, _ := content["sections"].(map[string]interface{})
+	contact, _ := sections[vcardContactSection].(map[string]interface{})
+
+	field := func(obj map[string]interface{}, key string) string {
+		value, _ := obj[key].(string)
+		return strings.TrimSpace(value)
+	}
+
+	email, phone, address := field(contact, "email"), field(contact, "phone"), field(contact, "address")
+	if email == "" && phone == "" && address == "" {
+		return ""
+	}
+
+	org := field(content, "title")
+	name := field(contact, "name")
+	if name == "" {
+		name = org
+	}
+
+	var b strings.Builder
+	line := func(property, value string) {
+		writeContentLine(&b, property+":"+value)
+	}
+
+	line("BEGIN", "VCARD")
+	line("VERSION", "3.0")
+	line("FN", escapeText(name))
+	if org != "" {
+		line("ORG", escapeText(org))
+	}
+	if email != "" {
+		line("EMAIL;TYPE=INTERNET", escapeText(email))
+	}
+	if phone != "" {
+		line("TEL;TYPE=WORK,VOICE", escapeText(phone))
+	}
+	if address != "" {
+		// Free-form addresses go in the street component
+		line("ADR;TYPE=WORK", ";;"+escapeText(address)+";;;;")
+	}
+	if siteURL != "" {
+		line("URL", escapeText(siteURL))
+	}
+	line("END", "VCARD")
+	return b.String()
+}
+
+// maxContentLineOctets is the folding limit of vCard and iCalendar lines
+const maxContentLineOctets = 75
+
+// escapeText escapes vCard and iCalendar text values (RFC 2426 section 4,
+// RFC 5545 section 3.3.11)
+func escapeText(value string) string {
+	value = strings.ReplaceAll(value, "\r\n", "\n")
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(value)
+}
+
+// writeContentLine writes a content line folded at 75 octets, ending in
+// CRLF. Continuation lines start with a space, which counts toward the limit.
+func writeContentLine(b *strings.Builder, line string) {
+	limit := maxContentLineOctets
+	for len(line) > limit {
+		cut := truncateRunes(line, limit)
+		if cut == "" {
+			break
+		}
+		b.WriteString(cut + "\r\n ")
+		line = line[len(cut):]
+		limit = maxContentLineOctets - 1
+	}
+	b.WriteString(line + "\r\n")
+}
@@ -0,0 +1,240 @@
+package managers
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithms accepted for passkeys, in order of preference
+const (
+	coseAlgES256 = -7
+	coseAlgEdDSA = -8
+	coseAlgRS256 = -257
+)
+
+// passkeyAlgorithms is offered to authenticators when registering
+var passkeyAlgorithms = []int{coseAlgES256, coseAlgEdDSA, coseAlgRS256}
+
+// Authenticator data flags
+const (
+	authFlagUserPresent  = 0x01
+	authFlagUserVerified = 0x04
+	authFlagAttested     = 0x40
+)
+
+// ErrPasskeyInvalid is wrapped by every failed passkey check
+var ErrPasskeyInvalid = errors.New("invalid passkey response")
+
+// passkeyError reports why a passkey response was refused
+func passkeyError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrPasskeyInvalid, fmt.Sprintf(format, args...))
+}
+
+// decodeBase64URL decodes base64url with or without padding
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(trimBase64Padding(s))
+}
+
+// trimBase64Padding drops trailing '=' padding
+func trimBase64Padding(s string) string {
+	for len(s) > 0 && s[len(s)-1] == '=' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// encodeBase64URL encodes b as base64url without padding
+func encodeBase64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// clientData is the part of clientDataJSON the server checks
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// parseClientData decodes clientDataJSON and checks its type and origin.
+// The challenge is returned for the caller to match.
+func parseClientData(raw []byte, wantType, origin string) (*clientData, error) {
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return nil, passkeyError("unreadable client data")
+	}
+	if cd.Type != wantType {
+		return nil, passkeyError("client data type is %q, not %q", cd.Type, wantType)
+	}
+	if cd.Origin != origin {
+		return nil, passkeyError("origin %q does not match %q", cd.Origin, origin)
+	}
+	if cd.CrossOrigin {
+		return nil, passkeyError("cross-origin requests are not accepted")
+	}
+	return &cd, nil
+}
+
+// authenticatorData is the parsed authenticator data of a response
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte // only with authFlagAttested
+	publicKey    []byte // COSE key, only with authFlagAttested
+}
+
+// parseAuthenticatorData decodes authenticator data and checks that it is
+// for rpID and that the user was present
+func parseAuthenticatorData(data []byte, rpID string) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, passkeyError("authenticator data too short")
+	}
+	ad := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	expected := sha256.Sum256([]byte(rpID))
+	if subtle.ConstantTimeCompare(ad.rpIDHash, expected[:]) != 1 {
+		return nil, passkeyError("passkey is for another site than %q", rpID)
+	}
+	if ad.flags&authFlagUserPresent == 0 {
+		return nil, passkeyError("user was not present")
+	}
+
+	if ad.flags&authFlagAttested != 0 {
+		rest := data[37:]
+		if len(rest) < 18 {
+			return nil, passkeyError("attested credential data too short")
+		}
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLen == 0 || idLen > 1023 || len(rest) < idLen {
+			return nil, passkeyError("invalid credential ID")
+		}
+		ad.credentialID = rest[:idLen]
+		rest = rest[idLen:]
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, passkeyError("invalid credential public key: %v", err)
+		}
+		ad.publicKey = rest[:len(rest)-len(after)]
+	}
+	return ad, nil
+}
+
+// userVerified reports whether the authenticator verified the user, with a
+// PIN or biometrics
+func (ad *authenticatorData) userVerified() bool {
+	return ad.flags&authFlagUserVerified != 0
+}
+
+// parseAttestationObject returns the authenticator data of a registration.
+// The attestation statement is not verified: the admin registers their own
+// authenticator while signed in, and "none" attestation is requested.
+func parseAttestationObject(raw []byte) ([]byte, error) {
+	decoded, _, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, passkeyError("unreadable attestation object: %v", err)
+	}
+	object, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, passkeyError("attestation object is not a map")
+	}
+	authData, ok := object["authData"].([]byte)
+	if !ok {
+		return nil, passkeyError("attestation object has no authenticator data")
+	}
+	return authData, nil
+}
+
+// parseCOSEKey decodes a COSE public key and returns it with its algorithm
+func parseCOSEKey(raw []byte) (crypto.PublicKey, int, error) {
+	decoded, _, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, 0, err
+	}
+	key, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, errors.New("COSE key is not a map")
+	}
+	kty, _ := key[int64(1)].(int64)
+	alg, _ := key[int64(3)].(int64)
+
+	switch {
+	case kty == 2 && alg == coseAlgES256:
+		crv, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		y, _ := key[int64(-3)].([]byte)
+		if crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, errors.New("unsupported EC2 key")
+		}
+		// Parsing the uncompressed point checks that it is on the curve
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, 0, fmt.Errorf("invalid EC2 key: %w", err)
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		return pub, coseAlgES256, nil
+	case kty == 1 && alg == coseAlgEdDSA:
+		crv, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		if crv != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, errors.New("unsupported OKP key")
+		}
+		return ed25519.PublicKey(x), coseAlgEdDSA, nil
+	case kty == 3 && alg == coseAlgRS256:
+		n, _ := key[int64(-1)].([]byte)
+		e, _ := key[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, errors.New("unsupported RSA key")
+		}
+		exponent := 0
+		for _, b := range e {
+			exponent = exponent<<8 | int(b)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, coseAlgRS256, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported key type %d with algorithm %d", kty, alg)
+	}
+}
+
+// verifyPasskeySignature checks an assertion signature over the
+// authenticator data followed by the hash of the client data
+func verifyPasskeySignature(publicKey []byte, authData, clientDataJSON, signature []byte) error {
+	pub, alg, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return passkeyError("stored public key: %v", err)
+	}
+	clientHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), authData...), clientHash[:]...)
+
+	valid := false
+	switch alg {
+	case coseAlgES256:
+		digest := sha256.Sum256(signed)
+		valid = ecdsa.VerifyASN1(pub.(*ecdsa.PublicKey), digest[:], signature)
+	case coseAlgEdDSA:
+		valid = ed25519.Verify(pub.(ed25519.PublicKey), signed, signature)
+	case coseAlgRS256:
+		digest := sha256.Sum256(signed)
+		valid = rsa.VerifyPKCS1v15(pub.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	}
+	if !valid {
+		return passkeyError("signature does not verify")
+	}
+	return nil
+}
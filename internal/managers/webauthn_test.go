@@ -0,0 +1,367 @@
+package managers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// testAuthenticator signs WebAuthn responses with an ES256 key, as a
+// security key or platform authenticator would
+type testAuthenticator struct {
+	t            *testing.T
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+}
+
+func newTestAuthenticator(t *testing.T) *testAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testAuthenticator{t: t, key: key, credentialID: []byte("test-credential-1")}
+}
+
+// coseKey returns the public key as an ES256 COSE key
+func (a *testAuthenticator) coseKey() []byte {
+	point, err := a.key.PublicKey.ECDH()
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	raw := point.Bytes() // 0x04 || x || y
+	return cborEncode(cborMap{
+		{1, 2}, {3, coseAlgES256}, {-1, 1}, {-2, raw[1:33]}, {-3, raw[33:]},
+	})
+}
+
+// authData builds authenticator data for rpID, with the attested credential
+// data when attested is set
+func (a *testAuthenticator) authData(rpID string, flags byte, signCount uint32, attested bool) []byte {
+	hash := sha256.Sum256([]byte(rpID))
+	data := append(hash[:], flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[33:], signCount)
+	if attested {
+		data[32] |= authFlagAttested
+		data = append(data, make([]byte, 16)...) // AAGUID
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.coseKey()...)
+	}
+	return data
+}
+
+// clientDataJSON encodes the client data a browser passes to the
+// authenticator
+func clientDataJSON(typ, challenge, origin string) []byte {
+	data, _ := json.Marshal(map[string]interface{}{"type": typ, "challenge": challenge, "origin": origin})
+	return data
+}
+
+// register answers a registration challenge with "none" attestation
+func (a *testAuthenticator) register(rp PasskeyRelyingParty, challenge string, signCount uint32) types.PasskeyCredential {
+	authData := a.authData(rp.ID(), authFlagUserPresent|authFlagUserVerified, signCount, true)
+	attestation := cborEncode(cborMap{{"fmt", "none"}, {"attStmt", cborMap{}}, {"authData", authData}})
+	return types.PasskeyCredential{
+		ID:   encodeBase64URL(a.credentialID),
+		Type: "public-key",
+		Response: types.PasskeyCredentialResponse{
+			ClientDataJSON:    encodeBase64URL(clientDataJSON("webauthn.create", challenge, rp.Origin)),
+			AttestationObject: encodeBase64URL(attestation),
+		},
+	}
+}
+
+// assertion is a login response before it is signed and encoded, for tests
+// to tamper with
+type assertion struct {
+	rpID       string
+	flags      byte
+	signCount  uint32
+	clientData []byte
+	signer     *ecdsa.PrivateKey
+}
+
+// assert answers a login challenge
+func (a *testAuthenticator) assert(rp PasskeyRelyingParty, challenge string, signCount uint32, tamper func(*assertion)) types.PasskeyCredential {
+	as := &assertion{
+		rpID:       rp.ID(),
+		flags:      authFlagUserPresent | authFlagUserVerified,
+		signCount:  signCount,
+		clientData: clientDataJSON("webauthn.get", challenge, rp.Origin),
+		signer:     a.key,
+	}
+	if tamper != nil {
+		tamper(as)
+	}
+	authData := a.authData(as.rpID, as.flags, as.signCount, false)
+	clientHash := sha256.Sum256(as.clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, as.signer, digest[:])
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return types.PasskeyCredential{
+		ID:    encodeBase64URL(a.credentialID),
+		RawID: encodeBase64URL(a.credentialID),
+		Type:  "public-key",
+		Response: types.PasskeyCredentialResponse{
+			ClientDataJSON:    encodeBase64URL(as.clientData),
+			AuthenticatorData: encodeBase64URL(authData),
+			Signature:         encodeBase64URL(signature),
+		},
+	}
+}
+
+// newTestPasskey registers an authenticator for admin, with the given
+// signature counter
+func newTestPasskey(t *testing.T, signCount uint32) (*PasskeyManager, PasskeyRelyingParty, *testAuthenticator) {
+	t.Helper()
+	rp, err := NewPasskeyRelyingParty("https://cms.example.com", "OnePage CMS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm := NewPasskeyManager(NewFileStorage(t.TempDir()))
+	auth := newTestAuthenticator(t)
+	now := time.Now()
+
+	options, err := pm.BeginRegistration(rp, "admin", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	passkey, err := pm.FinishRegistration(rp, "admin", "Security key", auth.register(rp, options.Challenge, signCount), now)
+	if err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	if passkey.Algorithm != coseAlgES256 || passkey.ID != encodeBase64URL(auth.credentialID) || passkey.SignCount != signCount {
+		t.Fatalf("registered %+v", passkey)
+	}
+	return pm, rp, auth
+}
+
+func TestPasskeyRegistrationChallengeIsSingleUse(t *testing.T) {
+	pm, rp, auth := newTestPasskey(t, 0)
+	now := time.Now()
+
+	options, err := pm.BeginRegistration(rp, "admin", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := newTestAuthenticator(t)
+	other.credentialID = []byte("test-credential-2")
+	credential := other.register(rp, options.Challenge, 0)
+	if _, err := pm.FinishRegistration(rp, "admin", "", credential, now); err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	if _, err := pm.FinishRegistration(rp, "admin", "", credential, now); !errors.Is(err, ErrPasskeyChallenge) {
+		t.Errorf("replayed registration: %v, want ErrPasskeyChallenge", err)
+	}
+
+	// The same credential cannot be registered twice
+	options, _ = pm.BeginRegistration(rp, "admin", now)
+	if _, err := pm.FinishRegistration(rp, "admin", "", auth.register(rp, options.Challenge, 0), now); !errors.Is(err, ErrPasskeyInvalid) {
+		t.Errorf("duplicate registration: %v, want ErrPasskeyInvalid", err)
+	}
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		secondFactor bool
+		signCount    uint32
+		tamper       func(*assertion)
+		wantErr      error
+		wantMessage  string
+	}{
+		{name: "valid ES256 assertion", signCount: 6},
+		{name: "authenticator without a counter", signCount: 0, wantErr: ErrPasskeyInvalid, wantMessage: "counter went backwards"},
+		{name: "lowered signature counter", signCount: 4, wantErr: ErrPasskeyInvalid, wantMessage: "counter went backwards"},
+		{name: "repeated signature counter", signCount: 5, wantErr: ErrPasskeyInvalid, wantMessage: "counter went backwards"},
+		{
+			name: "wrong rpIdHash", signCount: 6,
+			tamper:  func(as *assertion) { as.rpID = "evil.example.com" },
+			wantErr: ErrPasskeyInvalid, wantMessage: "another site",
+		},
+		{
+			name: "wrong origin", signCount: 6,
+			tamper: func(as *assertion) {
+				as.clientData = []byte(strings.Replace(string(as.clientData), "cms.example.com", "evil.example.com", 1))
+			},
+			wantErr: ErrPasskeyInvalid, wantMessage: "origin",
+		},
+		{
+			name: "registration client data", signCount: 6,
+			tamper: func(as *assertion) {
+				as.clientData = []byte(strings.Replace(string(as.clientData), "webauthn.get", "webauthn.create", 1))
+			},
+			wantErr: ErrPasskeyInvalid, wantMessage: "client data type",
+		},
+		{
+			name: "signed by another key", signCount: 6,
+			tamper:  func(as *assertion) { as.signer = otherKey },
+			wantErr: ErrPasskeyInvalid, wantMessage: "signature does not verify",
+		},
+		{
+			name: "user not present", signCount: 6,
+			tamper:  func(as *assertion) { as.flags = authFlagUserVerified },
+			wantErr: ErrPasskeyInvalid, wantMessage: "not present",
+		},
+		{
+			name: "passwordless without user verification", signCount: 6,
+			tamper:  func(as *assertion) { as.flags = authFlagUserPresent },
+			wantErr: ErrPasskeyInvalid, wantMessage: "did not verify the user",
+		},
+		{
+			name: "second factor without user verification", secondFactor: true, signCount: 6,
+			tamper: func(as *assertion) { as.flags = authFlagUserPresent },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm, rp, auth := newTestPasskey(t, 5)
+			now := time.Now()
+			username := ""
+			if tt.secondFactor {
+				username = "admin"
+			}
+			options, err := pm.BeginLogin(rp, username, tt.secondFactor, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			login, err := pm.FinishLogin(rp, auth.assert(rp, options.Challenge, tt.signCount, tt.tamper), now)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || !strings.Contains(err.Error(), tt.wantMessage) {
+					t.Fatalf("FinishLogin error %v, want %v containing %q", err, tt.wantErr, tt.wantMessage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FinishLogin: %v", err)
+			}
+			if login.SecondFactor != tt.secondFactor || login.Passkey.SignCount != tt.signCount || !login.Passkey.LastUsedAt.Equal(now) {
+				t.Errorf("login %+v", login)
+			}
+
+			// The challenge is spent, and the counter has moved on
+			replay := auth.assert(rp, options.Challenge, tt.signCount+1, tt.tamper)
+			if _, err := pm.FinishLogin(rp, replay, now); !errors.Is(err, ErrPasskeyChallenge) {
+				t.Errorf("replayed challenge: %v, want ErrPasskeyChallenge", err)
+			}
+			options, _ = pm.BeginLogin(rp, username, tt.secondFactor, now)
+			if _, err := pm.FinishLogin(rp, auth.assert(rp, options.Challenge, tt.signCount, tt.tamper), now); !errors.Is(err, ErrPasskeyInvalid) {
+				t.Errorf("reused counter: %v, want ErrPasskeyInvalid", err)
+			}
+		})
+	}
+}
+
+func TestPasskeyLoginExpiredChallenge(t *testing.T) {
+	pm, rp, auth := newTestPasskey(t, 0)
+	now := time.Now()
+	options, err := pm.BeginLogin(rp, "", false, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credential := auth.assert(rp, options.Challenge, 1, nil)
+	if _, err := pm.FinishLogin(rp, credential, now.Add(passkeyTimeout+time.Second)); !errors.Is(err, ErrPasskeyChallenge) {
+		t.Errorf("expired challenge: %v, want ErrPasskeyChallenge", err)
+	}
+}
+
+func TestParseAuthenticatorData(t *testing.T) {
+	auth := newTestAuthenticator(t)
+	const rpID = "cms.example.com"
+	valid := auth.authData(rpID, authFlagUserPresent, 7, true)
+
+	tests := []struct {
+		name        string
+		data        []byte
+		wantMessage string
+	}{
+		{"attested credential", valid, ""},
+		{"assertion", auth.authData(rpID, authFlagUserPresent, 7, false), ""},
+		{"too short", valid[:36], "too short"},
+		{"wrong rpIdHash", auth.authData("example.org", authFlagUserPresent, 7, true), "another site"},
+		{"user not present", auth.authData(rpID, 0, 7, false), "not present"},
+		{"attested data cut short", valid[:37+17], "attested credential data too short"},
+		{"credential ID past the end", valid[:37+18+4], "invalid credential ID"},
+		{"truncated public key", valid[:len(valid)-1], "invalid credential public key"},
+		{"public key nested too deeply", append(append([]byte(nil), valid[:37+18+len(auth.credentialID)]...), nestedCBORArrays(maxCBORDepth+1)...), "nested too deeply"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ad, err := parseAuthenticatorData(tt.data, rpID)
+			if tt.wantMessage != "" {
+				if !errors.Is(err, ErrPasskeyInvalid) || !strings.Contains(err.Error(), tt.wantMessage) {
+					t.Fatalf("error %v, want one containing %q", err, tt.wantMessage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAuthenticatorData: %v", err)
+			}
+			if ad.signCount != 7 {
+				t.Errorf("signCount %d, want 7", ad.signCount)
+			}
+			if ad.flags&authFlagAttested != 0 {
+				if string(ad.credentialID) != string(auth.credentialID) {
+					t.Errorf("credential ID %q", ad.credentialID)
+				}
+				if _, alg, err := parseCOSEKey(ad.publicKey); err != nil || alg != coseAlgES256 {
+					t.Errorf("public key: %v, algorithm %d", err, alg)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCOSEKey(t *testing.T) {
+	auth := newTestAuthenticator(t)
+	point, _ := auth.key.PublicKey.ECDH()
+	x, y := point.Bytes()[1:33], point.Bytes()[33:]
+	offCurve := append([]byte(nil), y...)
+	offCurve[31] ^= 1
+
+	tests := []struct {
+		name    string
+		key     []byte
+		wantAlg int
+	}{
+		{"ES256", auth.coseKey(), coseAlgES256},
+		{"EdDSA", cborEncode(cborMap{{1, 1}, {3, coseAlgEdDSA}, {-1, 6}, {-2, make([]byte, 32)}}), coseAlgEdDSA},
+		{"point off the curve", cborEncode(cborMap{{1, 2}, {3, coseAlgES256}, {-1, 1}, {-2, x}, {-3, offCurve}}), 0},
+		{"P-384 curve", cborEncode(cborMap{{1, 2}, {3, coseAlgES256}, {-1, 2}, {-2, x}, {-3, y}}), 0},
+		{"short RSA modulus", cborEncode(cborMap{{1, 3}, {3, coseAlgRS256}, {-1, make([]byte, 128)}, {-2, []byte{1, 0, 1}}}), 0},
+		{"unknown algorithm", cborEncode(cborMap{{1, 2}, {3, -35}}), 0},
+		{"not a map", cborEncode([]interface{}{1, 2}), 0},
+		{"truncated", auth.coseKey()[:20], 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, alg, err := parseCOSEKey(tt.key)
+			if tt.wantAlg == 0 {
+				if err == nil {
+					t.Fatal("parseCOSEKey accepted the key")
+				}
+				return
+			}
+			if err != nil || alg != tt.wantAlg {
+				t.Fatalf("parseCOSEKey: %v, algorithm %d, want %d", err, alg, tt.wantAlg)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package managers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"onepagems/internal/httpclient"
+)
+
+// Events that can be posted to webhooks
+const (
+	EventPublish          = "publish"
+	EventGenerationFailed = "generation_failed"
+	EventBackupFailed     = "backup_failed"
+	EventLoginAlert       = "login_alert"
+)
+
+// WebhookEvents lists every event type, in the order they are documented
+var WebhookEvents = []string{EventPublish, EventGenerationFailed, EventBackupFailed, EventLoginAlert}
+
+// WebhookEvent is something that happened on the site, worded for people
+type WebhookEvent struct {
+	Type  string    `json:"type"`
+	Title string    `json:"title"`
+	Text  string    `json:"text,omitempty"`
+	URL   string    `json:"url,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+// message formats the event as one chat message
+func (e WebhookEvent) message() string {
+	var b strings.Builder
+	b.WriteString(e.Title)
+	if e.Text != "" {
+		b.WriteString("\n")
+		b.WriteString(e.Text)
+	}
+	if e.URL != "" {
+		b.WriteString("\n")
+		b.WriteString(e.URL)
+	}
+	return b.String()
+}
+
+// WebhookAdapter builds the request that delivers an event to one kind of
+// endpoint
+type WebhookAdapter interface {
+	NewRequest(ctx context.Context, url string, event WebhookEvent) (*http.Request, error)
+}
+
+// webhookAdapters are the supported endpoint kinds by name
+var webhookAdapters = map[string]WebhookAdapter{
+	"webhook": jsonWebhook{},
+	"slack":   slackWebhook{},
+	"discord": discordWebhook{},
+	"matrix":  &matrixWebhook{},
+}
+
+// jsonWebhook posts the event itself as JSON
+type jsonWebhook struct{}
+
+func (jsonWebhook) NewRequest(ctx context.Context, url string, event WebhookEvent) (*http.Request, error) {
+	return newJSONRequest(ctx, "POST", url, event)
+}
+
+// slackWebhook posts to a Slack incoming webhook
+type slackWebhook struct{}
+
+func (slackWebhook) NewRequest(ctx context.Context, url string, event WebhookEvent) (*http.Request, error) {
+	return newJSONRequest(ctx, "POST", url, map[string]string{"text": event.message()})
+}
+
+// discordWebhook posts to a Discord channel webhook
+type discordWebhook struct{}
+
+func (discordWebhook) NewRequest(ctx context.Context, url string, event WebhookEvent) (*http.Request, error) {
+	// Discord rejects messages over 2000 characters
+	return newJSONRequest(ctx, "POST", url, map[string]string{"content": shortenMessage(event.message(), 2000)})
+}
+
+// matrixWebhook sends a room message through the Matrix client API. The
+// URL is the room's send endpoint, such as
+// https://matrix.example.org/_matrix/client/v3/rooms/!room:example.org/send/m.room.message;
+// the access token comes from token.
+type matrixWebhook struct {
+	token func() string
+}
+
+func (m *matrixWebhook) NewRequest(ctx context.Context, url string, event WebhookEvent) (*http.Request, error) {
+	txn := make([]byte, 8)
+	rand.Read(txn)
+	req, err := newJSONRequest(ctx, "PUT", strings.TrimSuffix(url, "/")+"/"+hex.EncodeToString(txn), map[string]string{
+		"msgtype": "m.notice",
+		"body":    event.message(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if m.token != nil {
+		if token := m.token(); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	return req, nil
+}
+
+// newJSONRequest creates a request with a JSON body
+func newJSONRequest(ctx context.Context, method, url string, body interface{}) (*http.Request, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// shortenMessage cuts s to at most n characters, ending it with an ellipsis
+func shortenMessage(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// WebhookTarget is one endpoint an event is posted to
+type WebhookTarget struct {
+	Adapter string `json:"adapter"`
+	URL     string `json:"-"` // may carry a token, so never returned
+}
+
+// ParseWebhookTargets reads a comma-separated list of endpoints, each a URL
+// prefixed with its kind, as in "slack:https://hooks.slack.com/...". A bare
+// URL receives the event as JSON.
+func ParseWebhookTargets(spec string) ([]WebhookTarget, error) {
+	var targets []WebhookTarget
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		target := WebhookTarget{Adapter: "webhook", URL: entry}
+		if kind, url, ok := strings.Cut(entry, ":"); ok && kind != "http" && kind != "https" {
+			target = WebhookTarget{Adapter: kind, URL: url}
+		}
+		if _, ok := webhookAdapters[target.Adapter]; !ok {
+			return nil, fmt.Errorf("unknown webhook kind %q", target.Adapter)
+		}
+		if !strings.HasPrefix(target.URL, "https://") && !strings.HasPrefix(target.URL, "http://") {
+			return nil, fmt.Errorf("webhook URL %q must start with http:// or https://", target.URL)
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// WebhookDispatcher posts events to the endpoints configured for their type
+type WebhookDispatcher struct {
+	targets map[string][]WebhookTarget
+	deploys []string
+	client  *http.Client
+	matrix  *matrixWebhook
+}
+
+// NewWebhookDispatcher creates a dispatcher from endpoint lists by event
+// type, in the format of ParseWebhookTargets, posting through clients
+func NewWebhookDispatcher(specs map[string]string, clients *httpclient.Clients) (*WebhookDispatcher, error) {
+	d := &WebhookDispatcher{
+		targets: make(map[string][]WebhookTarget),
+		client:  clients.New(10 * time.Second),
+		matrix:  &matrixWebhook{},
+	}
+	for event, spec := range specs {
+		if !isWebhookEvent(event) {
+			return nil, fmt.Errorf("unknown webhook event %q", event)
+		}
+		targets, err := ParseWebhookTargets(spec)
+		if err != nil {
+			return nil, fmt.Errorf("webhooks for %s: %w", event, err)
+		}
+		if len(targets) > 0 {
+			d.targets[event] = targets
+		}
+	}
+	return d, nil
+}
+
+// isWebhookEvent reports whether name is a known event type
+func isWebhookEvent(name string) bool {
+	for _, event := range WebhookEvents {
+		if event == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SetMatrixToken sets where the Matrix access token is looked up, such as
+// the secrets store
+func (d *WebhookDispatcher) SetMatrixToken(token func() string) {
+	d.matrix.token = token
+}
+
+// Enabled reports whether any endpoint is configured
+func (d *WebhookDispatcher) Enabled() bool {
+	return len(d.targets) > 0
+}
+
+// Targets returns the configured endpoint kinds by event type
+func (d *WebhookDispatcher) Targets() map[string][]WebhookTarget {
+	return d.targets
+}
+
+// WebhookDelivery is an event waiting in the outbox for one endpoint,
+// named by its place in the event type's list
+type WebhookDelivery struct {
+	Event   WebhookEvent `json:"event"`
+	Target  int          `json:"target"`
+	Adapter string       `json:"adapter"`
+}
+
+// Deliveries splits the event into one delivery per endpoint for its type
+func (d *WebhookDispatcher) Deliveries(event WebhookEvent) []WebhookDelivery {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	var deliveries []WebhookDelivery
+	for i, target := range d.targets[event.Type] {
+		deliveries = append(deliveries, WebhookDelivery{Event: event, Target: i, Adapter: target.Adapter})
+	}
+	return deliveries
+}
+
+// Deliver posts one delivery. It fails if the endpoints were changed so
+// that the one it was meant for is gone.
+func (d *WebhookDispatcher) Deliver(ctx context.Context, delivery WebhookDelivery) error {
+	targets := d.targets[delivery.Event.Type]
+	if delivery.Target < 0 || delivery.Target >= len(targets) || targets[delivery.Target].Adapter != delivery.Adapter {
+		return fmt.Errorf("the %s webhook for %s is no longer configured", delivery.Adapter, delivery.Event.Type)
+	}
+	if err := d.post(ctx, targets[delivery.Target], delivery.Event); err != nil {
+		return fmt.Errorf("%s webhook for %s: %w", delivery.Adapter, delivery.Event.Type, err)
+	}
+	return nil
+}
+
+// SetDeployHooks sets the build hook URLs of hosting providers, such as
+// Netlify or Cloudflare Pages, triggered after each publish
+func (d *WebhookDispatcher) SetDeployHooks(urls []string) error {
+	for _, url := range urls {
+		if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+			return fmt.Errorf("deploy hook URL %q must start with http:// or https://", url)
+		}
+	}
+	d.deploys = urls
+	return nil
+}
+
+// DeployHooks returns how many deploy hooks are configured. Their URLs are
+// not returned since they act as credentials.
+func (d *WebhookDispatcher) DeployHooks() int {
+	return len(d.deploys)
+}
+
+// TriggerDeploy posts the event to the deploy hook at index
+func (d *WebhookDispatcher) TriggerDeploy(ctx context.Context, index int, event WebhookEvent) error {
+	if index < 0 || index >= len(d.deploys) {
+		return fmt.Errorf("deploy hook %d is no longer configured", index+1)
+	}
+	req, err := newJSONRequest(ctx, "POST", d.deploys[index], event)
+	if err != nil {
+		return err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("deploy hook %d: request failed: %w", index+1, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("deploy hook %d returned status %d", index+1, resp.StatusCode)
+	}
+	return nil
+}
+
+// Dispatch posts the event to every endpoint for its type, returning the
+// failures together
+func (d *WebhookDispatcher) Dispatch(ctx context.Context, event WebhookEvent) error {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	var errs []error
+	for _, target := range d.targets[event.Type] {
+		if err := d.post(ctx, target, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s webhook for %s: %w", target.Adapter, event.Type, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// post delivers the event to one endpoint
+func (d *WebhookDispatcher) post(ctx context.Context, target WebhookTarget, event WebhookEvent) error {
+	adapter := webhookAdapters[target.Adapter]
+	if target.Adapter == "matrix" {
+		adapter = d.matrix
+	}
+	req, err := adapter.NewRequest(ctx, target.URL, event)
+	if err != nil {
+		return err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// activityListSpec sorts and filters GET /admin/activity
+var activityListSpec = listSpec[types.ActivityEntry]{
+	Sorts: map[string]func(a, b types.ActivityEntry) int{
+		"time":     func(a, b types.ActivityEntry) int { return a.Time.Compare(b.Time) },
+		"action":   compareBy(func(e types.ActivityEntry) string { return e.Action }),
+		"username": compareBy(func(e types.ActivityEntry) string { return e.Username }),
+	},
+	DefaultSort: "-time",
+	Text: func(e types.ActivityEntry) []string {
+		return []string{e.Action, e.Description, e.Username}
+	},
+}
+
+// handleActivityList lists the activity log. Besides the list parameters,
+// action and username keep exact matches (ignoring case) and since and
+// until (RFC 3339) bound the time.
+func (s *Server) handleActivityList(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	query := r.URL.Query()
+	var bounds [2]time.Time
+	for i, name := range []string{"since", "until"} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, fmt.Sprintf("%s must be an RFC 3339 time", name))
+			return
+		}
+		bounds[i] = t
+	}
+	since, until := bounds[0], bounds[1]
+	action, username := query.Get("action"), query.Get("username")
+
+	entries, err := s.Activity.List()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+	matched := entries[:0]
+	for _, entry := range entries {
+		switch {
+		case action != "" && !strings.EqualFold(entry.Action, action),
+			username != "" && !strings.EqualFold(entry.Username, username),
+			!since.IsZero() && entry.Time.Before(since),
+			!until.IsZero() && entry.Time.After(until):
+			continue
+		}
+		matched = append(matched, entry)
+	}
+
+	entries, page, ok := paginateList(s, w, r, matched, activityListSpec)
+	if !ok {
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Activity retrieved")
+	response.SetData(map[string]interface{}{
+		"activity": entries,
+	})
+	response.Meta["pagination"] = page
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
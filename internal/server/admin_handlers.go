@@ -1,14 +1,17 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"html/template"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"onepagems/internal/managers"
 	"onepagems/internal/types"
 )
 
@@ -48,39 +51,43 @@ type ActivityItem struct {
 
 // handleAdminPanel serves the main admin dashboard
 func (s *Server) handleAdminPanel(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	session, ok := types.SessionFromContext(r.Context())
 	if !ok {
-		http.Error(w, "Session not found", http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Session not found")
 		return
 	}
 
 	// Gather dashboard data
 	stats, err := s.getAdminStats()
 	if err != nil {
-		http.Error(w, "Failed to load dashboard stats", http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load dashboard stats")
 		return
 	}
 
 	status, err := s.getSystemStatus()
 	if err != nil {
-		http.Error(w, "Failed to load system status", http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load system status")
 		return
 	}
 
 	recentActivity := s.getRecentActivity()
 
+	quality, err := s.getQualityReport()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to score content")
+		return
+	}
+
 	dashboardContent, err := s.renderTemplate("admin_dashboard.html", map[string]interface{}{
 		"Stats":          stats,
 		"Status":         status,
 		"RecentActivity": recentActivity,
+		"Quality":        quality,
+		"FailedLogins":   session.FailedLoginsSinceLast,
+		"Location":       s.location,
 	})
 	if err != nil {
-		http.Error(w, "Failed to render dashboard", http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to render dashboard")
 		return
 	}
 
@@ -94,42 +101,39 @@ func (s *Server) handleAdminPanel(w http.ResponseWriter, r *http.Request) {
 		RecentActivity: recentActivity,
 	}
 
-	s.renderAdminPage(w, pageData)
+	s.renderAdminPage(w, r, pageData)
 }
 
-// handleAdminContent serves the content editor interface
+// handleAdminContent serves the content editor interface, or the content
+// itself to API clients that accept JSON. Updates are POSTed to handleContentUpdate.
 func (s *Server) handleAdminContent(w http.ResponseWriter, r *http.Request) {
+	// Both forms share the URL, so caches must key them by Accept
+	w.Header().Set("Vary", "Accept")
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		s.handleContentGet(w, r)
+		return
+	}
+
 	session, ok := types.SessionFromContext(r.Context())
 	if !ok {
-		http.Error(w, "Session not found", http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Session not found")
 		return
 	}
 
-	switch r.Method {
-	case "GET":
-		// Serve content editor interface
-		contentEditorHTML, err := s.renderTemplate("admin_content.html", nil)
-		if err != nil {
-			http.Error(w, "Failed to render content editor", http.StatusInternalServerError)
-			return
-		}
-
-		pageData := AdminPageData{
-			Title:    "Content Editor",
-			Username: session.Username,
-			Page:     "content",
-			Content:  contentEditorHTML,
-		}
-
-		s.renderAdminPage(w, pageData)
-
-	case "POST":
-		// Handle content updates
-		s.handleContentUpdate(w, r)
+	contentEditorHTML, err := s.renderTemplate("admin_content.html", nil)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to render content editor")
+		return
+	}
 
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	pageData := AdminPageData{
+		Title:    "Content Editor",
+		Username: session.Username,
+		Page:     "content",
+		Content:  contentEditorHTML,
 	}
+
+	s.renderAdminPage(w, r, pageData)
 }
 
 // handleContentUpdate processes content form submissions
@@ -137,79 +141,122 @@ func (s *Server) handleContentUpdate(w http.ResponseWriter, r *http.Request) {
 	var content map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&content); err != nil {
 		response := types.NewAPIResponse(false, "Invalid JSON data: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
 		return
 	}
 
-	// Validate content against schema
-	validationResult, err := s.SchemaManager.ValidateContentDetailed(content)
+	validationResult, err := s.saveSubmittedContent(r.Context(), content)
 	if err != nil {
-		response := types.NewAPIResponse(false, "Validation failed: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		response := types.NewAPIResponse(false, err.Error())
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
 		return
 	}
 
 	if !validationResult.Valid {
-		response := types.NewAPIResponse(false, "Content validation failed")
-		response.SetData(map[string]interface{}{
-			"errors":      validationResult.Errors,
-			"valid":       false,
-			"error_count": len(validationResult.Errors),
-		})
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
+		s.writeContentInvalid(w, r, validationResult)
 		return
 	}
 
+	s.writeContentSaved(w, r, validationResult)
+}
+
+// saveSubmittedContent coerces, validates and saves content submitted from
+// the editor. Content that fails validation is not saved; the returned result
+// reports why.
+func (s *Server) saveSubmittedContent(ctx context.Context, content map[string]interface{}) (*managers.ValidationResult, error) {
+	return s.saveSubmittedContentAt(ctx, content, managers.AnyRevision)
+}
+
+// saveSubmittedContentAt is saveSubmittedContent for content made from
+// revision; it fails with managers.ErrRevisionConflict if the content was
+// saved since
+func (s *Server) saveSubmittedContentAt(ctx context.Context, content map[string]interface{}, revision int64) (*managers.ValidationResult, error) {
+	// Convert form strings to schema types before validating
+	if _, err := s.SchemaManager.CoerceContent(content); err != nil {
+		return nil, fmt.Errorf("Failed to process content: %w", err)
+	}
+
+	// Validate content against schema
+	validationResult, err := s.SchemaManager.ValidateContentDetailed(content)
+	if err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+
+	if !validationResult.Valid {
+		return validationResult, nil
+	}
+
 	// Save content
 	contentData := &types.ContentData{}
 	if err := s.mapToContentData(content, contentData); err != nil {
-		response := types.NewAPIResponse(false, "Failed to process content: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
-		return
+		return nil, fmt.Errorf("Failed to process content: %w", err)
 	}
 
-	if err := s.ContentManager.SaveContent(contentData); err != nil {
-		response := types.NewAPIResponse(false, "Failed to save content: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
-		return
+	if err := s.Plugins.ContentSave(ctx, contentData); err != nil {
+		validationResult.Valid = false
+		validationResult.Errors = append(validationResult.Errors, managers.ValidationDetailError{
+			Code:    "plugin_rejected",
+			Message: err.Error(),
+		})
+		return validationResult, nil
+	}
+
+	if err := s.ContentManager.SaveContentAt(contentData, revision); err != nil {
+		return nil, fmt.Errorf("Failed to save content: %w", err)
+	}
+
+	// Saved edits need no recovery
+	if session, ok := types.SessionFromContext(ctx); ok {
+		s.Autosaves.Discard(session.Username)
 	}
 
 	// Log activity
-	s.logActivity("Content Updated", "Content has been successfully updated through the admin panel")
+	s.logActivity(ctx, "Content Updated", "Content has been successfully updated through the admin panel")
 
-	response := types.NewAPIResponse(true, "Content saved successfully")
+	return validationResult, nil
+}
+
+// writeContentInvalid sends the validation errors for rejected content
+func (s *Server) writeContentInvalid(w http.ResponseWriter, r *http.Request, validationResult *managers.ValidationResult) {
+	response := types.NewAPIResponse(false, "Content validation failed")
 	response.SetData(map[string]interface{}{
-		"validation": validationResult,
-		"timestamp":  time.Now().Format(time.RFC3339),
+		"errors":      validationResult.Errors,
+		"valid":       false,
+		"error_count": len(validationResult.Errors),
 	})
+	s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+}
+
+// writeContentSaved sends the success response for a content save
+func (s *Server) writeContentSaved(w http.ResponseWriter, r *http.Request, validationResult *managers.ValidationResult) {
+	response := types.NewAPIResponse(true, "Content saved successfully")
+	data := map[string]interface{}{
+		"validation": validationResult,
+		"timestamp":  s.Clock().Format(time.RFC3339),
+	}
+	// The revision to sync from with GET /admin/content/changes
+	if revision, err := s.ContentManager.ContentRevision(); err == nil {
+		data["revision"] = revision
+	}
+	response.SetData(data)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
 
 // Helper methods
 
 // renderAdminPage renders the main admin template with provided data
-func (s *Server) renderAdminPage(w http.ResponseWriter, data AdminPageData) {
+func (s *Server) renderAdminPage(w http.ResponseWriter, r *http.Request, data AdminPageData) {
 	tmplPath := filepath.Join("templates", "admin.html")
 	tmpl, err := template.ParseFiles(tmplPath)
 	if err != nil {
-		http.Error(w, "Failed to load admin template", http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load admin template")
 		return
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if err := tmpl.Execute(w, data); err != nil {
-		http.Error(w, "Failed to render admin page", http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to render admin page")
 		return
 	}
 }
@@ -245,19 +292,43 @@ func (s *Server) getAdminStats() (*AdminStats, error) {
 		fieldCount = s.countSchemaFields(schema.Properties)
 	}
 
-	// TODO: Count images in images directory
 	imageCount := 0
+	if images, err := s.ImageManager.ListImages(); err == nil {
+		imageCount = len(images)
+	}
+
+	lastUpdated := s.Clock()
+	if content, err := s.ContentManager.LoadContent(); err == nil && !content.LastUpdated.IsZero() {
+		lastUpdated = content.LastUpdated
+	}
 
 	stats := &AdminStats{
 		ContentFields: fieldCount,
 		Images:        imageCount,
-		LastUpdated:   time.Now().Format("2006-01-02"),
+		LastUpdated:   lastUpdated.In(s.location).Format("2006-01-02"),
 		SchemaVersion: "1.0", // TODO: Get from schema
 	}
 
 	return stats, nil
 }
 
+// getQualityReport scores the completeness of the current content
+func (s *Server) getQualityReport() (*managers.QualityReport, error) {
+	schema, err := s.SchemaManager.LoadSchema()
+	if err != nil {
+		return nil, err
+	}
+	content, err := s.ContentManager.LoadContent()
+	if err != nil {
+		return nil, err
+	}
+	images, err := s.ImageManager.ListImages()
+	if err != nil {
+		return nil, err
+	}
+	return managers.ScoreContent(schema, content, images), nil
+}
+
 // getSystemStatus collects system component status
 func (s *Server) getSystemStatus() (*SystemStatus, error) {
 	status := &SystemStatus{
@@ -267,31 +338,65 @@ func (s *Server) getSystemStatus() (*SystemStatus, error) {
 		SiteGenerated:    "Pending",
 	}
 
+	if info, err := os.Stat(s.Generator.OutputFile("index.html")); err == nil {
+		status.SiteGenerated = info.ModTime().In(s.location).Format("2006-01-02 15:04 MST")
+	}
+
 	// TODO: Get actual file modification times
 	return status, nil
 }
 
-// getRecentActivity returns recent activity items
+// recentActivityCount is how many activity entries the dashboard shows
+const recentActivityCount = 10
+
+// getRecentActivity returns the latest entries of the activity log
 func (s *Server) getRecentActivity() []ActivityItem {
-	// TODO: Implement actual activity logging
-	return []ActivityItem{
-		{
-			Action:      "Content Updated",
-			Description: "Website content was updated through the admin panel",
-			Timestamp:   time.Now().Add(-1 * time.Hour),
-		},
-		{
-			Action:      "Schema Modified",
-			Description: "JSON schema was updated to add new fields",
-			Timestamp:   time.Now().Add(-2 * time.Hour),
-		},
+	entries, err := s.Activity.Recent(recentActivityCount)
+	if err != nil {
+		s.Logger.Printf("Failed to read activity log: %v", err)
+		return nil
+	}
+	items := make([]ActivityItem, 0, len(entries))
+	for _, entry := range entries {
+		items = append(items, ActivityItem{
+			Action:      entry.Action,
+			Description: entry.Description,
+			Timestamp:   entry.Time.In(s.location),
+		})
 	}
+	return items
 }
 
-// logActivity logs an activity item
-func (s *Server) logActivity(action, description string) {
-	// TODO: Implement proper activity logging to file or database
-	fmt.Printf("[ACTIVITY] %s: %s\n", action, description)
+// logActivity records an admin action in the activity log, with the
+// signed-in user and request ID from ctx
+func (s *Server) logActivity(ctx context.Context, action, description string) {
+	username := ""
+	if session, ok := types.SessionFromContext(ctx); ok {
+		username = session.Username
+	}
+	s.logActivityAs(ctx, username, action, description)
+}
+
+// logActivityAs records an action taken by username, for requests made
+// before or without a session such as sign-in
+func (s *Server) logActivityAs(ctx context.Context, username, action, description string) {
+	requestID := types.RequestIDFromContext(ctx)
+	if requestID != "" {
+		s.Logger.Printf("[ACTIVITY] [%s] %s: %s", requestID, action, description)
+	} else {
+		s.Logger.Printf("[ACTIVITY] %s: %s", action, description)
+	}
+
+	entry := types.ActivityEntry{
+		Time:        s.Clock(),
+		Action:      action,
+		Description: description,
+		Username:    username,
+		RequestID:   requestID,
+	}
+	if err := s.Activity.Log(entry); err != nil {
+		s.Logger.Printf("Failed to record activity: %v", err)
+	}
 }
 
 // countSchemaFields recursively counts fields in schema
@@ -318,8 +423,18 @@ func (s *Server) mapToContentData(content map[string]interface{}, target *types.
 		target.Description = description
 	}
 
-	// Store all content as custom data
-	target.Sections = content
+	// Sections hold the page's content blocks. Submissions without a
+	// sections object keep their top-level objects as sections instead.
+	if sections, ok := content["sections"].(map[string]interface{}); ok {
+		target.Sections = sections
+	} else {
+		target.Sections = make(map[string]interface{})
+		for key, value := range content {
+			if section, ok := value.(map[string]interface{}); ok {
+				target.Sections[key] = section
+			}
+		}
+	}
 
 	return nil
 }
@@ -328,110 +443,145 @@ func (s *Server) mapToContentData(content map[string]interface{}, target *types.
 
 // handleAPIStats returns dashboard statistics as JSON
 func (s *Server) handleAPIStats(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	stats, err := s.getAdminStats()
 	if err != nil {
 		response := types.NewAPIResponse(false, "Failed to get stats: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
 		return
 	}
 
 	response := types.NewAPIResponse(true, "Stats retrieved successfully")
 	response.SetData(stats)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
+}
+
+// handleAPIQuality returns the content quality score and its to-do list
+func (s *Server) handleAPIQuality(w http.ResponseWriter, r *http.Request) {
+	quality, err := s.getQualityReport()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to score content: "+err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Content quality scored")
+	response.SetData(quality)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
 }
 
 // handleAPIGenerate handles site generation requests
 func (s *Server) handleAPIGenerate(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	result, err := s.publishSite(r.Context(), "")
+	if err != nil {
+		response := types.NewAPIResponse(false, "Site generation failed: "+err.Error())
+		response.SetData(result)
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
 		return
 	}
 
-	// For now, return success. In Phase 8, this will actually generate the site
+	s.logActivity(r.Context(), "Site Generated", "Site was generated to "+result.OutputPath)
+
 	response := types.NewAPIResponse(true, "Site generation completed successfully")
+	response.SetData(result)
+	response.Meta["image_cdn"] = s.Config.ImageCDNURL != ""
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
 
-// handleContentAutoSave handles auto-save functionality for content editor
-func (s *Server) handleContentAutoSave(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// diffContextLines is how many unchanged lines surround each change
+const diffContextLines = 3
+
+// handleAPIGenerateDiff compares the published index.html with the page a
+// generation would write now, so the change can be reviewed before it
+// goes live. ?format=text returns a unified diff and ?format=html a
+// colored view; the default is JSON hunks.
+func (s *Server) handleAPIGenerateDiff(w http.ResponseWriter, r *http.Request) {
+	published, err := s.Generator.PublishedPage()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to read published page: "+err.Error())
 		return
 	}
-
-	// Parse JSON body
-	var updates map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		// Try parsing as form data for backward compatibility
-		if err := r.ParseForm(); err != nil {
-			response := types.NewAPIResponse(false, "Invalid request data")
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(response)
-			return
-		}
-
-		// Convert form data to map
-		updates = make(map[string]interface{})
-		for key, values := range r.Form {
-			if len(values) > 0 {
-				updates[key] = values[0]
-			}
-		}
+	candidate, err := s.Generator.RenderPage(r.Context())
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to render page: "+err.Error())
+		return
 	}
 
-	// Update content
-	if err := s.ContentManager.UpdateContentFlexible(updates); err != nil {
-		response := types.NewAPIResponse(false, "Auto-save failed: "+err.Error())
+	diff := managers.DiffText(published, candidate, diffContextLines)
+	switch format := r.URL.Query().Get("format"); format {
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(diff.Unified("published/index.html", "candidate/index.html")))
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(renderDiffHTML(diff, published == "")))
+	case "", "json":
+		response := types.NewAPIResponse(true, "Page diff generated")
+		response.SetData(diff)
+		response.Meta["published"] = published != ""
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
-		return
+		s.encodeResponse(w, r, response)
+	default:
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, fmt.Sprintf("Unknown format %q; use json, text or html", format))
 	}
+}
 
-	response := types.NewAPIResponse(true, "Content auto-saved successfully")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+// renderDiffHTML renders a standalone page showing the hunks of diff with
+// added lines in green and removed lines in red
+func renderDiffHTML(diff *managers.PageDiff, firstPublish bool) string {
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE html><html><head><meta charset="utf-8"><title>Page changes</title><style>
+body{font-family:sans-serif;margin:1rem}
+pre{font-family:monospace;margin:0;white-space:pre-wrap;word-break:break-all}
+.hunk{border:1px solid #ddd;margin-bottom:1rem}
+.head{background:#f0f4ff;color:#555;padding:2px 6px}
+.insert{background:#e6ffed}.delete{background:#ffeef0}
+.num{color:#999;display:inline-block;width:4em}
+</style></head><body>`)
+	switch {
+	case firstPublish:
+		b.WriteString("<p>The site has not been published yet; every line is new.</p>")
+	case !diff.Changed:
+		b.WriteString("<p>No changes: publishing now would not change the live page.</p>")
+	default:
+		fmt.Fprintf(&b, "<p>%d lines added, %d removed.</p>", diff.Added, diff.Removed)
+	}
+	for _, hunk := range diff.Hunks {
+		fmt.Fprintf(&b, `<div class="hunk"><div class="head">@@ -%d,%d +%d,%d @@</div>`, hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+		for _, line := range hunk.Lines {
+			marker, number := " ", line.NewLine
+			switch line.Op {
+			case managers.DiffInsert:
+				marker = "+"
+			case managers.DiffDelete:
+				marker, number = "-", line.OldLine
+			}
+			fmt.Fprintf(&b, `<pre class="%s"><span class="num">%d</span>%s %s</pre>`, line.Op, number, marker, template.HTMLEscapeString(line.Text))
+		}
+		b.WriteString("</div>")
+	}
+	b.WriteString("</body></html>")
+	return b.String()
 }
 
 // handlePreviewContent provides preview functionality
 func (s *Server) handlePreviewContent(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	// For now, redirect to the main site. In Phase 8, this will provide live preview
 	http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 }
 
 // handleAPIStatus returns system status as JSON
 func (s *Server) handleAPIStatus(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	status, err := s.getSystemStatus()
 	if err != nil {
 		response := types.NewAPIResponse(false, "Failed to get status: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
 		return
 	}
 
 	response := types.NewAPIResponse(true, "Status retrieved successfully")
 	response.SetData(status)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// archiveDownloadTimeout bounds fetching a remote archive
+const archiveDownloadTimeout = 30 * time.Minute
+
+// handleArchiveExport downloads the site's data files and images as a zip
+// archive that POST /admin/import/archive can restore
+func (s *Server) handleArchiveExport(w http.ResponseWriter, r *http.Request) {
+	filename := fmt.Sprintf("onepagems-%s.zip", s.Clock().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+
+	if err := managers.WriteArchive(s.Storage, w); err != nil {
+		// Headers are already sent; the truncated zip will not open
+		s.Logger.Printf("Archive export failed: %v", err)
+	}
+}
+
+// handleArchiveImport restores a site archive as a background job. The body
+// is either the zip itself (raw, or the "archive" field of a multipart
+// form) or {"url": "..."} to fetch it. It answers 202 with the job; follow
+// its progress at /admin/jobs/{id} or /admin/jobs/{id}/events.
+func (s *Server) handleArchiveImport(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	if !s.requireRecentAuth(w, r) {
+		return
+	}
+	if !s.requireConfirmation(w, r, "archive.import", "", nil) {
+		return
+	}
+
+	archive, err := os.CreateTemp("", "onepagems-import-*.zip")
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to create temporary file")
+		return
+	}
+	archivePath := archive.Name()
+	archive.Close()
+
+	remoteURL, err := s.receiveArchive(w, r, archivePath)
+	if err != nil {
+		os.Remove(archivePath)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			s.writeError(w, r, http.StatusRequestEntityTooLarge, types.ErrCodePayloadTooLarge,
+				fmt.Sprintf("Archive exceeds the %d byte limit", s.Config.ArchiveMaxSize))
+			return
+		}
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	steps := 2
+	if remoteURL != "" {
+		steps = 3
+	}
+	session, _ := types.SessionFromContext(r.Context())
+	maxSize, maxEntrySize := s.Config.ArchiveMaxSize, max(s.Config.UploadMaxSize, 16*1024*1024)
+	job := s.Jobs.Start("archive_import", session.Username, steps, func(ctx context.Context, progress *managers.JobProgress) (interface{}, error) {
+		defer os.Remove(archivePath)
+		if remoteURL != "" {
+			client := s.HTTPClients.New(archiveDownloadTimeout)
+			if err := managers.DownloadArchive(ctx, client, remoteURL, archivePath, maxSize, progress); err != nil {
+				return nil, err
+			}
+		}
+		result, err := managers.RestoreArchive(s.Storage, archivePath, maxEntrySize, progress)
+		if result != nil {
+			if err := s.ContentManager.ContentReplaced(); err != nil {
+				s.Logger.Printf("Failed to reset content changes: %v", err)
+			}
+		}
+		return result, err
+	})
+
+	source := "upload"
+	if remoteURL != "" {
+		source = remoteURL
+	}
+	s.logActivity(r.Context(), "Archive Import Started", fmt.Sprintf("%s started job %s from %s", session.Username, job.ID, source))
+
+	s.writeJobAccepted(w, r, job, "Archive import started")
+}
+
+// receiveArchive saves an uploaded archive to path, or returns the URL to
+// fetch it from
+func (s *Server) receiveArchive(w http.ResponseWriter, r *http.Request, path string) (string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.Config.ArchiveMaxSize)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var body io.Reader = r.Body
+	switch mediaType {
+	case "application/json":
+		var requestData struct {
+			URL string `json:"url"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+			return "", fmt.Errorf("invalid JSON in request body: %w", err)
+		}
+		parsed, err := url.Parse(requestData.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return "", fmt.Errorf("url must be an http or https URL")
+		}
+		return requestData.URL, nil
+	case "multipart/form-data":
+		file, _, err := r.FormFile("archive")
+		if err != nil {
+			return "", fmt.Errorf("missing archive file: %w", err)
+		}
+		defer file.Close()
+		body = file
+	}
+
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+	written, err := io.Copy(out, body)
+	if err != nil {
+		return "", err
+	}
+	if written == 0 {
+		return "", fmt.Errorf("request body is empty; send a zip archive or {\"url\": ...}")
+	}
+	return "", out.Close()
+}
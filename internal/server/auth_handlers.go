@@ -2,76 +2,103 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"time"
 
+	"onepagems/internal/managers"
 	"onepagems/internal/types"
 )
 
 // handleAdminLogin handles admin login requests
 func (s *Server) handleAdminLogin(w http.ResponseWriter, r *http.Request) {
-	if r.Method == "GET" {
-		// Serve login form
-		s.serveLoginForm(w, r)
+	// Parse login credentials
+	if err := r.ParseForm(); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid form data")
 		return
 	}
 
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	username := r.FormValue("username")
+	password := r.FormValue("password")
+	recoveryCode := r.FormValue("recovery_code")
+
+	if !s.allowLogin(w, r, username) {
 		return
 	}
 
-	// Parse login credentials
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
+	// A recovery code stands in for a lost password
+	if username != "" && password == "" && recoveryCode != "" {
+		s.loginWithRecoveryCode(w, r, username, recoveryCode)
 		return
 	}
 
-	username := r.FormValue("username")
-	password := r.FormValue("password")
-
 	if username == "" || password == "" {
-		http.Error(w, "Username and password are required", http.StatusBadRequest)
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Username and password are required")
+		return
+	}
+
+	// With passkeys as a second factor the password alone does not sign in
+	if s.passkeySecondFactor(username) {
+		s.loginWithSecondFactor(w, r, username, password)
 		return
 	}
 
 	// Attempt login
 	session, err := s.AuthManager.Login(username, password)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "Invalid credentials",
-		})
+		s.rejectLogin(w, r, username)
 		return
 	}
+	s.recordLoginSuccess(r, username)
+	s.logActivityAs(r.Context(), username, "Login", "Signed in from "+clientIP(r))
 
 	// Set session cookie
 	cookie := s.AuthManager.CreateSessionCookie(session.ID)
 	http.SetCookie(w, cookie)
 
 	// Return success response
+	failedAttempts := 0
+	if session.FailedLoginsSinceLast != nil {
+		failedAttempts = session.FailedLoginsSinceLast.Count
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success":    true,
-		"message":    "Login successful",
-		"session_id": session.ID,
-		"expires_at": session.ExpiresAt,
+		"success":                          true,
+		"message":                          "Login successful",
+		"session_id":                       session.ID,
+		"expires_at":                       session.ExpiresAt,
+		"failed_attempts_since_last_login": failedAttempts,
 	})
 }
 
-// handleAdminLogout handles admin logout requests
-func (s *Server) handleAdminLogout(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// rejectLogin records and reports a failed sign-in and answers it with 401
+func (s *Server) rejectLogin(w http.ResponseWriter, r *http.Request, username string) {
+	s.recordLoginFailure(r, username)
+	if tracker, ok := s.AuthManager.(LoginAttemptTracker); ok {
+		tracker.RecordFailedLogin(username, clientIP(r))
+	}
+	s.alertFailedLogin(r, username)
+	s.writeError(w, r, http.StatusUnauthorized, types.ErrCodeInvalidCredentials, "Invalid credentials")
+}
+
+// clientIP returns the address a request came from, without its port
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
 	}
+	return r.RemoteAddr
+}
 
-	// Get session from request
+// handleAdminLogout handles admin logout requests
+func (s *Server) handleAdminLogout(w http.ResponseWriter, r *http.Request) {
+	// Get session from request; a locked session can still sign out
 	session, err := s.AuthManager.GetSessionFromRequest(r)
-	if err == nil {
+	if err == nil || errors.Is(err, managers.ErrSessionLocked) {
 		// Logout the session
 		s.AuthManager.Logout(session.ID)
+		s.logActivityAs(r.Context(), session.Username, "Logout", "Signed out")
 	}
 
 	// Clear session cookie
@@ -231,7 +258,7 @@ func (s *Server) serveLoginForm(w http.ResponseWriter, r *http.Request) {
                         window.location.href = '/admin';
                     }, 1000);
                 } else {
-                    errorDiv.textContent = data.error || 'Login failed';
+                    errorDiv.textContent = data.detail || 'Login failed';
                     errorDiv.style.display = 'block';
                 }
             } catch (error) {
@@ -244,55 +271,95 @@ func (s *Server) serveLoginForm(w http.ResponseWriter, r *http.Request) {
 </html>`)
 }
 
-// handleAuthStatus returns current authentication status
-func (s *Server) handleAuthStatus(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// sessionFlushInterval is how often expired sessions are dropped and
+// extended expiry times saved
+const sessionFlushInterval = 5 * time.Minute
+
+// runSessionFlush flushes the sessions of persister every
+// sessionFlushInterval, and a last time on Stop
+func (s *Server) runSessionFlush(persister SessionPersister) {
+	ticker := time.NewTicker(sessionFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := persister.FlushSessions(); err != nil {
+				s.Logger.Printf("Failed to save sessions: %v", err)
+			}
+		case <-s.stopping:
+			if err := persister.FlushSessions(); err != nil {
+				s.Logger.Printf("Failed to save sessions: %v", err)
+			}
+			return
+		}
 	}
+}
 
+// handleAuthStatus returns current authentication status
+func (s *Server) handleAuthStatus(w http.ResponseWriter, r *http.Request) {
 	session, ok := types.SessionFromContext(r.Context())
 	if !ok {
-		http.Error(w, "No session found", http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "No session found")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	status := map[string]interface{}{
 		"authenticated":   true,
 		"username":        session.Username,
 		"session_id":      session.ID,
 		"created_at":      session.CreatedAt,
 		"expires_at":      session.ExpiresAt,
 		"active_sessions": s.AuthManager.GetActiveSessions(),
-	})
+		"csrf_token":      s.csrfToken(session),
+		"idle_timeout":    s.Config.IdleTimeout,
+		"reauth_window":   s.Config.ReauthWindow,
+	}
+	if !session.AuthenticatedAt.IsZero() {
+		status["authenticated_at"] = session.AuthenticatedAt
+	}
+	// Rejected sign-ins before this session started, and since
+	if session.FailedLoginsSinceLast != nil {
+		status["failed_logins_since_last_login"] = session.FailedLoginsSinceLast
+	}
+	if tracker, ok := s.AuthManager.(LoginAttemptTracker); ok {
+		status["failed_logins"] = tracker.FailedLogins()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// sessionListSpec sorts and filters GET /admin/auth/sessions
+var sessionListSpec = listSpec[*types.Session]{
+	Sorts: map[string]func(a, b *types.Session) int{
+		"username":   compareBy(func(s *types.Session) string { return s.Username }),
+		"created_at": func(a, b *types.Session) int { return a.CreatedAt.Compare(b.CreatedAt) },
+		"expires_at": func(a, b *types.Session) int { return a.ExpiresAt.Compare(b.ExpiresAt) },
+	},
+	DefaultSort: "-created_at",
+	Text:        func(s *types.Session) []string { return []string{s.Username} },
 }
 
 // handleAuthSessions lists all active sessions
 func (s *Server) handleAuthSessions(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	sessions, page, ok := paginateList(s, w, r, s.AuthManager.ListSessions(), sessionListSpec)
+	if !ok {
 		return
 	}
 
-	sessions := s.AuthManager.ListSessions()
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"sessions": sessions,
-		"count":    len(sessions),
+		"sessions":   sessions,
+		"count":      page.Total,
+		"pagination": page,
 	})
 }
 
 // handleChangePassword changes the admin password
 func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid form data")
 		return
 	}
 
@@ -301,23 +368,25 @@ func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
 	confirmPassword := r.FormValue("confirm_password")
 
 	if currentPassword == "" || newPassword == "" || confirmPassword == "" {
-		http.Error(w, "All password fields are required", http.StatusBadRequest)
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "All password fields are required")
 		return
 	}
 
 	if newPassword != confirmPassword {
-		http.Error(w, "New passwords do not match", http.StatusBadRequest)
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "New passwords do not match")
 		return
 	}
 
 	if err := s.AuthManager.ChangePassword(currentPassword, newPassword); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
-		})
+		if errors.Is(err, managers.ErrPasswordNotSaved) {
+			s.Logger.Printf("Password change failed: %v", err)
+			s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to save the new password")
+			return
+		}
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, err.Error())
 		return
 	}
+	s.logActivity(r.Context(), "Password Changed", "Admin password changed")
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"onepagems/internal/types"
+)
+
+// handleContentAutoSave keeps the editor's unsaved edits for the current
+// user. The edits are stored apart from content.json, so nothing is
+// published until the user saves; saving the content discards them. Form
+// posts with dot-notation field names update the current content, JSON
+// bodies may be the whole document or dot-notation updates.
+func (s *Server) handleContentAutoSave(w http.ResponseWriter, r *http.Request) {
+	username, ok := s.autosaveUser(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse form data for backward compatibility, JSON otherwise
+	var updates map[string]interface{}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if err := r.ParseForm(); err != nil {
+			response := types.NewAPIResponse(false, "Invalid request data")
+			s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+			return
+		}
+
+		// Convert form data to map
+		updates = make(map[string]interface{})
+		for key, values := range r.PostForm {
+			if len(values) > 0 {
+				updates[key] = values[0]
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		response := types.NewAPIResponse(false, "Invalid request data")
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	// Convert form strings to schema types
+	if _, err := s.SchemaManager.CoerceContent(updates); err != nil {
+		response := types.NewAPIResponse(false, "Auto-save failed: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
+		return
+	}
+
+	content, err := s.ContentManager.MergeContentUpdates(updates)
+	if err != nil {
+		response := types.NewAPIResponse(false, "Auto-save failed: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
+		return
+	}
+
+	autosave, err := s.Autosaves.Save(username, map[string]interface{}{
+		"title":       content.Title,
+		"description": content.Description,
+		"sections":    content.Sections,
+	}, content.LastUpdated)
+	if err != nil {
+		response := types.NewAPIResponse(false, "Auto-save failed: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Content auto-saved successfully")
+	response.Meta["saved_at"] = autosave.SavedAt
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleContentAutosaveGet returns the current user's auto-saved edits so the
+// editor can offer to recover them. meta.recoverable is true when they are
+// newer than the saved content; meta.content_changed warns that the content
+// has been saved since the edits started, so restoring them would overwrite
+// those changes.
+func (s *Server) handleContentAutosaveGet(w http.ResponseWriter, r *http.Request) {
+	username, ok := s.autosaveUser(w, r)
+	if !ok {
+		return
+	}
+
+	autosave, err := s.Autosaves.Load(username)
+	if err != nil {
+		response := types.NewAPIResponse(false, err.Error())
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
+		return
+	}
+
+	if autosave == nil {
+		response := types.NewAPIResponse(true, "No auto-saved edits")
+		response.Meta["recoverable"] = false
+		w.Header().Set("Content-Type", "application/json")
+		s.encodeResponse(w, r, response)
+		return
+	}
+
+	content, err := s.ContentManager.LoadContent()
+	if err != nil {
+		response := types.NewAPIResponse(false, "Failed to load content: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Auto-saved edits found")
+	response.SetData(autosave)
+	response.Meta["recoverable"] = autosave.SavedAt.After(content.LastUpdated)
+	response.Meta["content_changed"] = content.LastUpdated.After(autosave.BaseUpdated)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleContentAutosaveDiscard drops the current user's auto-saved edits
+func (s *Server) handleContentAutosaveDiscard(w http.ResponseWriter, r *http.Request) {
+	username, ok := s.autosaveUser(w, r)
+	if !ok {
+		return
+	}
+
+	discarded, err := s.Autosaves.Discard(username)
+	if err != nil {
+		response := types.NewAPIResponse(false, err.Error())
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Auto-saved edits discarded")
+	response.Meta["discarded"] = discarded
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// autosaveUser returns the user whose autosave a request works on
+func (s *Server) autosaveUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	session, ok := types.SessionFromContext(r.Context())
+	if !ok || session.Username == "" {
+		s.writeError(w, r, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Auto-save needs a signed-in user")
+		return "", false
+	}
+	return session.Username, true
+}
@@ -0,0 +1,325 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// BasicPageData represents the data passed to the server-rendered admin
+// pages that work without JavaScript
+type BasicPageData struct {
+	Page      string
+	CSRFToken string
+	Message   string
+	Errors    []string
+	Fields    []BasicField
+	Template  string
+	// CloseFieldsets is how many fieldsets are still open after the last
+	// field
+	CloseFieldsets int
+	Engine         string
+	Engines        []string
+	Images         []types.ImageInfo
+}
+
+// BasicField is a generated form field with its current value and error
+type BasicField struct {
+	types.FormField
+	Current  string
+	Selected map[string]bool
+	Items    []string
+	Checked  bool
+	Error    string
+	// CloseBefore is how many fieldsets end before this field
+	CloseBefore int
+}
+
+// handleBasicContent serves the server-rendered content form. It is built
+// from the same generated form as the editor and posts to /admin/content/form.
+func (s *Server) handleBasicContent(w http.ResponseWriter, r *http.Request) {
+	content, err := s.ContentManager.LoadContent()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to load content: %v", err))
+		return
+	}
+
+	values := make(map[string]interface{})
+	if err := remarshalJSON(content, &values); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to process content: %v", err))
+		return
+	}
+
+	message := ""
+	if r.URL.Query().Get("saved") == "1" {
+		message = "Content saved successfully"
+	}
+
+	s.renderBasicContent(w, r, http.StatusOK, values, nil, message)
+}
+
+// renderBasicContent renders the content form with the given values and
+// validation errors, so a rejected submission can be corrected in place
+func (s *Server) renderBasicContent(w http.ResponseWriter, r *http.Request, status int, values map[string]interface{}, validationErrors []managers.ValidationDetailError, message string) {
+	form, err := s.SchemaManager.GenerateCompleteForm()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to generate form: %v", err))
+		return
+	}
+	form.Fields = s.withoutArchivedSections(form.Fields)
+
+	fieldErrors := make(map[string]string)
+	otherErrors := make([]string, 0)
+	for _, validationError := range validationErrors {
+		if _, exists := fieldErrors[validationError.Field]; !exists && hasFormField(form.Fields, validationError.Field) {
+			fieldErrors[validationError.Field] = validationError.Message
+			continue
+		}
+		otherErrors = append(otherErrors, validationError.Field+": "+validationError.Message)
+	}
+
+	fields := make([]BasicField, 0, len(form.Fields))
+	for _, field := range form.Fields {
+		value, exists := lookupPath(values, field.Name)
+		if !exists {
+			value = field.Value
+		}
+		fields = append(fields, newBasicField(field, value, fieldErrors[field.Name]))
+	}
+
+	s.renderBasicPage(w, r, status, "Content Editor", BasicPageData{
+		Page:           "content",
+		Message:        message,
+		Errors:         otherErrors,
+		Fields:         fields,
+		CloseFieldsets: nestFieldsets(fields),
+	})
+}
+
+// handleBasicTemplate serves the server-rendered template editor
+func (s *Server) handleBasicTemplate(w http.ResponseWriter, r *http.Request) {
+	content, err := s.TemplateManager.LoadTemplate()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to load template: %v", err))
+		return
+	}
+
+	message := ""
+	if r.URL.Query().Get("saved") == "1" {
+		message = "Template saved successfully"
+	}
+
+	s.renderBasicPage(w, r, http.StatusOK, "Template Editor", BasicPageData{
+		Page:     "template",
+		Message:  message,
+		Template: content,
+		Engine:   s.TemplateManager.EngineName(),
+		Engines:  managers.TemplateEngineNames(),
+	})
+}
+
+// handleBasicTemplatePost saves the template from the server-rendered editor.
+// An invalid template is shown again with the error instead of being saved.
+func (s *Server) handleBasicTemplatePost(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid form data")
+		return
+	}
+	if !s.checkCSRF(w, r) {
+		return
+	}
+
+	content := r.PostFormValue("content")
+	engine := r.PostFormValue("engine")
+	if engine == "" {
+		engine = s.TemplateManager.EngineName()
+	}
+	if content == "" {
+		s.renderBasicPage(w, r, http.StatusBadRequest, "Template Editor", BasicPageData{
+			Page:    "template",
+			Errors:  []string{"Template content is required"},
+			Engine:  engine,
+			Engines: managers.TemplateEngineNames(),
+		})
+		return
+	}
+
+	if err := s.TemplateManager.SaveTemplateWithEngine(content, engine); err != nil {
+		s.renderBasicPage(w, r, http.StatusBadRequest, "Template Editor", BasicPageData{
+			Page:     "template",
+			Errors:   []string{fmt.Sprintf("Failed to save template: %v", err)},
+			Template: content,
+			Engine:   engine,
+			Engines:  managers.TemplateEngineNames(),
+		})
+		return
+	}
+
+	s.logActivity(r.Context(), "Template Updated", "Template has been updated through the basic editor")
+	http.Redirect(w, r, "/admin/basic/template?saved=1", http.StatusSeeOther)
+}
+
+// handleBasicImages serves the server-rendered image list and upload form
+func (s *Server) handleBasicImages(w http.ResponseWriter, r *http.Request) {
+	message := ""
+	if r.URL.Query().Get("uploaded") == "1" {
+		message = "Image uploaded successfully"
+	}
+	s.renderBasicImages(w, r, http.StatusOK, message, nil)
+}
+
+// handleBasicImagesPost uploads an image from the server-rendered form
+func (s *Server) handleBasicImagesPost(w http.ResponseWriter, r *http.Request) {
+	// Allow a little headroom for the multipart envelope
+	r.Body = http.MaxBytesReader(w, r.Body, s.Config.UploadMaxSize+1024*1024)
+	if err := r.ParseMultipartForm(s.Config.UploadMaxSize); err != nil {
+		s.renderBasicImages(w, r, http.StatusBadRequest, "", []string{"Invalid upload: " + err.Error()})
+		return
+	}
+	if !s.checkCSRF(w, r) {
+		return
+	}
+
+	headers := r.MultipartForm.File["image"]
+	if len(headers) == 0 {
+		s.renderBasicImages(w, r, http.StatusBadRequest, "", []string{"Image file is required"})
+		return
+	}
+
+	if _, err := s.uploadFormImages(r, headers); err != nil {
+		s.renderBasicImages(w, r, http.StatusBadRequest, "", []string{"Failed to upload image: " + err.Error()})
+		return
+	}
+
+	http.Redirect(w, r, "/admin/basic/images?uploaded=1", http.StatusSeeOther)
+}
+
+// renderBasicImages renders the image page with a message or errors
+func (s *Server) renderBasicImages(w http.ResponseWriter, r *http.Request, status int, message string, errs []string) {
+	images, err := s.ImageManager.ListImages()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to list images: %v", err))
+		return
+	}
+
+	s.renderBasicPage(w, r, status, "Images", BasicPageData{
+		Page:    "images",
+		Message: message,
+		Errors:  errs,
+		Images:  images,
+	})
+}
+
+// renderBasicPage renders admin_basic.html inside the admin layout. The CSRF
+// token for the session is added to every page.
+func (s *Server) renderBasicPage(w http.ResponseWriter, r *http.Request, status int, title string, data BasicPageData) {
+	session, ok := types.SessionFromContext(r.Context())
+	if !ok {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Session not found")
+		return
+	}
+	data.CSRFToken = s.csrfToken(session)
+
+	pageHTML, err := s.renderTemplate("admin_basic.html", data)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to render page")
+		return
+	}
+
+	if status != http.StatusOK {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+	}
+	s.renderAdminPage(w, r, AdminPageData{
+		Title:    title,
+		Username: session.Username,
+		Page:     data.Page,
+		Content:  template.HTML(pageHTML),
+	})
+}
+
+// newBasicField prepares a form field for server-side rendering
+func newBasicField(field types.FormField, value interface{}, fieldError string) BasicField {
+	basic := BasicField{
+		FormField: field,
+		Selected:  make(map[string]bool),
+		Error:     fieldError,
+	}
+
+	switch v := value.(type) {
+	case nil:
+	case bool:
+		basic.Checked = v
+		basic.Current = fmt.Sprint(v)
+	case []interface{}:
+		for _, item := range v {
+			text := fmt.Sprint(item)
+			basic.Items = append(basic.Items, text)
+			basic.Selected[text] = true
+		}
+	case map[string]interface{}:
+	default:
+		basic.Current = fmt.Sprint(v)
+		basic.Selected[basic.Current] = true
+	}
+	return basic
+}
+
+// nestFieldsets works out where the fieldsets of object fields end in the
+// flat field list, setting CloseBefore, and returns how many are still open
+// after the last field
+func nestFieldsets(fields []BasicField) int {
+	open := make([]string, 0)
+	for i := range fields {
+		for len(open) > 0 && open[len(open)-1] != fields[i].Fieldset {
+			open = open[:len(open)-1]
+			fields[i].CloseBefore++
+		}
+		if fields[i].Type == "object" {
+			open = append(open, fields[i].Name)
+		}
+	}
+	return len(open)
+}
+
+// hasFormField reports whether fields include one with the given name
+func hasFormField(fields []types.FormField, name string) bool {
+	for _, field := range fields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupPath returns the value at a dotted path in nested content
+func lookupPath(obj map[string]interface{}, path string) (interface{}, bool) {
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		value, exists := obj[part]
+		if !exists {
+			return nil, false
+		}
+		if i == len(parts)-1 {
+			return value, true
+		}
+		if obj, exists = value.(map[string]interface{}); !exists {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// remarshalJSON copies src into dst through JSON
+func remarshalJSON(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// bulkResult is the outcome of a bulk job. Items that failed are listed in
+// the job's errors.
+type bulkResult struct {
+	Succeeded []string `json:"succeeded"`
+	Failed    int      `json:"failed"`
+}
+
+// handleImagesBulkDelete deletes the listed images as a background job
+func (s *Server) handleImagesBulkDelete(w http.ResponseWriter, r *http.Request) {
+	filenames, ok := s.decodeBulkFilenames(w, r)
+	if !ok {
+		return
+	}
+	if len(filenames) == 0 {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "filenames is required")
+		return
+	}
+	if r.URL.Query().Get("force") != "true" {
+		inUse := make([]*managers.ImageUsage, 0)
+		for _, filename := range filenames {
+			// Missing images fail in the job like any other error
+			if usage, err := s.imageUsage(filename); err == nil && usage.InUse() {
+				inUse = append(inUse, usage)
+			}
+		}
+		if len(inUse) > 0 {
+			response := types.NewAPIResponse(false, fmt.Sprintf("%d of the images are still used; delete them with ?force=true to leave those references broken", len(inUse)))
+			response.SetData(map[string]interface{}{"in_use": inUse})
+			s.writeErrorResponse(w, r, http.StatusConflict, response)
+			return
+		}
+	}
+	if !s.requireConfirmation(w, r, "images.bulk_delete", "", map[string]interface{}{"count": len(filenames)}) {
+		return
+	}
+
+	s.logActivity(r.Context(), "Images Bulk Delete", fmt.Sprintf("Deleting %d image(s)", len(filenames)))
+	s.startBulkJob(w, r, "images_delete", "delete", filenames, func(ctx context.Context, filename string) error {
+		return s.ImageManager.DeleteImage(filename)
+	})
+}
+
+// handleImagesRegenerateVariants renders the variants of the listed images
+// again, or of every image when none are listed, as a background job
+func (s *Server) handleImagesRegenerateVariants(w http.ResponseWriter, r *http.Request) {
+	filenames, ok := s.decodeBulkFilenames(w, r)
+	if !ok {
+		return
+	}
+	if len(filenames) == 0 {
+		images, err := s.ImageManager.ListImages()
+		if err != nil {
+			s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list images: "+err.Error())
+			return
+		}
+		for _, image := range images {
+			filenames = append(filenames, image.Filename)
+		}
+	}
+
+	s.logActivity(r.Context(), "Image Variants Regenerated", fmt.Sprintf("Regenerating variants of %d image(s)", len(filenames)))
+	s.startBulkJob(w, r, "images_regenerate_variants", "regenerate", filenames, func(ctx context.Context, filename string) error {
+		_, err := s.ImageManager.RegenerateVariants(ctx, filename)
+		return err
+	})
+}
+
+// handleMigrationBackupsPrune deletes pre-migration backups taken before a
+// date, given as {"before": "2006-01-02"} or an RFC 3339 time
+func (s *Server) handleMigrationBackupsPrune(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	var requestData struct {
+		Before string `json:"before"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON in request body")
+		return
+	}
+	before, err := time.Parse(time.RFC3339, requestData.Before)
+	if err != nil {
+		before, err = time.ParseInLocation(time.DateOnly, requestData.Before, time.UTC)
+	}
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "before must be a date (2006-01-02) or an RFC 3339 time")
+		return
+	}
+
+	runner := managers.NewMigrationRunner(s.Storage)
+	backups, err := runner.Backups()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+	var names []string
+	for _, backup := range backups {
+		if backup.CreatedAt.Before(before) {
+			names = append(names, backup.Name)
+		}
+	}
+
+	s.logActivity(r.Context(), "Migration Backups Pruned", fmt.Sprintf("Deleting %d backup(s) taken before %s", len(names), before.Format(time.RFC3339)))
+	s.startBulkJob(w, r, "migration_backups_prune", "delete", names, func(ctx context.Context, name string) error {
+		return runner.DeleteBackup(name)
+	})
+}
+
+// decodeBulkFilenames reads {"filenames": [...]} from the request body,
+// dropping blanks and duplicates
+func (s *Server) decodeBulkFilenames(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	var requestData struct {
+		Filenames []string `json:"filenames"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON in request body")
+		return nil, false
+	}
+
+	filenames := make([]string, 0, len(requestData.Filenames))
+	for _, filename := range requestData.Filenames {
+		filename = strings.TrimSpace(filename)
+		if filename != "" && !slices.Contains(filenames, filename) {
+			filenames = append(filenames, filename)
+		}
+	}
+	return filenames, true
+}
+
+// startBulkJob runs fn for each item in a background job with a single
+// step, recording a failure per item without stopping, and answers 202
+func (s *Server) startBulkJob(w http.ResponseWriter, r *http.Request, jobType, step string, items []string, fn func(ctx context.Context, item string) error) {
+	session, _ := types.SessionFromContext(r.Context())
+	job := s.Jobs.Start(jobType, session.Username, 1, func(ctx context.Context, progress *managers.JobProgress) (interface{}, error) {
+		result := &bulkResult{Succeeded: []string{}}
+		progress.Step(step, int64(len(items)))
+		for _, item := range items {
+			if err := fn(ctx, item); err != nil {
+				progress.ItemError(item, err)
+				result.Failed++
+			} else {
+				result.Succeeded = append(result.Succeeded, item)
+			}
+			progress.Add(1)
+		}
+		return result, nil
+	})
+
+	s.writeJobAccepted(w, r, job, fmt.Sprintf("Started %s of %d item(s)", jobType, len(items)))
+}
+
+// writeJobAccepted answers 202 with a started job and its location
+func (s *Server) writeJobAccepted(w http.ResponseWriter, r *http.Request, job types.Job, message string) {
+	response := types.NewAPIResponse(true, message)
+	response.SetData(job)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/admin/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	s.encodeResponse(w, r, response)
+}
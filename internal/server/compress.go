@@ -0,0 +1,174 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// gzipWriters reuses gzip writers across responses
+var gzipWriters = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(io.Discard) },
+}
+
+// compressibleTypes are the content types worth compressing besides text/*
+var compressibleTypes = map[string]bool{
+	"application/json":          true,
+	"application/problem+json":  true,
+	"application/manifest+json": true,
+	"application/javascript":    true,
+	"application/xml":           true,
+	"application/rss+xml":       true,
+	"application/atom+xml":      true,
+	"image/svg+xml":             true,
+}
+
+// isCompressible reports whether a response of contentType shrinks under gzip
+func isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") || compressibleTypes[mediaType]
+}
+
+// acceptsGzip reports whether the client accepts gzip responses
+func acceptsGzip(r *http.Request) bool {
+	for _, coding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			continue
+		}
+		// gzip;q=0 refuses it
+		if quality, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			q, err := strconv.ParseFloat(quality, 64)
+			return err != nil || q > 0
+		}
+		return true
+	}
+	return false
+}
+
+// withCompression gzips text, JSON and SVG responses for clients that
+// accept it. Event streams, range requests and responses a handler already
+// encoded are sent as they are.
+func (s *Server) withCompression(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.Config.Compression || isEventStream(r.URL.Path) || r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		writer := &gzipResponseWriter{ResponseWriter: w, status: http.StatusOK, accepted: acceptsGzip(r)}
+		next.ServeHTTP(writer, r)
+		// Not deferred: after a panic, a held back status must not go out
+		// before the recovery middleware answers 500
+		writer.close()
+	})
+}
+
+// gzipResponseWriter holds back the status until the first write, when the
+// content type is known, and then either compresses the body or passes it
+// through
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer // set when compressing
+	accepted    bool         // the client accepts gzip
+	status      int
+	wroteHeader bool // WriteHeader was called
+	started     bool // the header went out
+}
+
+// WriteHeader records the status; it is sent with the first write
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.started || w.wroteHeader {
+		return
+	}
+	if status < 200 {
+		// Informational responses go out at once
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+	w.wroteHeader = true
+}
+
+// Write compresses b if the response is compressible
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.started {
+		if len(b) == 0 {
+			return 0, nil
+		}
+		w.start(b)
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// start decides whether to compress from the status and headers, sniffing
+// the content type from the first bytes of the body when it is not set.
+// Compressible responses vary by Accept-Encoding even when not compressed.
+func (w *gzipResponseWriter) start(body []byte) {
+	w.started = true
+	header := w.Header()
+	contentType := header.Get("Content-Type")
+	if contentType == "" && len(body) > 0 {
+		contentType = http.DetectContentType(body)
+		header.Set("Content-Type", contentType)
+	}
+	if len(body) > 0 && w.status != http.StatusNoContent && w.status != http.StatusNotModified &&
+		w.status != http.StatusPartialContent && header.Get("Content-Encoding") == "" &&
+		header.Get("Content-Range") == "" && isCompressible(contentType) {
+		// Set here rather than up front, as a timeout handler replaces
+		// the header keys its handler set, Vary included
+		header.Add("Vary", "Accept-Encoding")
+	} else {
+		w.accepted = false
+	}
+	if w.accepted {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", "gzip")
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// Flush sends what has been written so far
+func (w *gzipResponseWriter) Flush() {
+	if !w.started {
+		w.start(nil)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// set write deadlines
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close finishes the gzip stream, or sends a status held back for a
+// response without a body
+func (w *gzipResponseWriter) close() {
+	if !w.started {
+		if !w.wroteHeader {
+			return
+		}
+		w.start(nil)
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+	}
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// ConfirmHeader and ConfirmParam carry the confirmation token of a
+// destructive request. The query parameter works for bodies the handler
+// streams, such as archive uploads.
+const (
+	ConfirmHeader = "X-Confirm-Token"
+	ConfirmParam  = "confirm_token"
+)
+
+// confirmTokenTTL is how long a confirmation token may be used
+const confirmTokenTTL = 5 * time.Minute
+
+// confirmActions describes the destructive actions that need a
+// confirmation token, by the name the token is issued for
+var confirmActions = map[string]string{
+	"content.restore":    "Replace the content with its backup",
+	"content.import":     "Replace the content with an import",
+	"schema.restore":     "Replace the schema with its backup",
+	"schema.import":      "Replace the schema with one that no longer has some content fields",
+	"schema.migrate":     "Migrate the content to a schema, removing values it does not declare",
+	"template.restore":   "Replace the template with its backup",
+	"archive.import":     "Replace the site data with an archive",
+	"images.delete":      "Delete an image",
+	"images.bulk_delete": "Delete several images",
+	"site.rollback":      "Replace the live site with an earlier build",
+	"site.reset":         "Replace the content, schema and template with a preset",
+	"templates.apply":    "Replace the content, schema and template with a starter template",
+	"retention.run":      "Purge activity, jobs and dead letters past their retention period",
+}
+
+// usedConfirmations remembers spent tokens until they expire, so each
+// token confirms one request
+type usedConfirmations struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+}
+
+// newUsedConfirmations creates an empty set of spent tokens
+func newUsedConfirmations() *usedConfirmations {
+	return &usedConfirmations{tokens: make(map[string]time.Time)}
+}
+
+// spend marks token used and reports whether it was unused
+func (uc *usedConfirmations) spend(token string, expires, now time.Time) bool {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	for t, exp := range uc.tokens {
+		if now.After(exp) {
+			delete(uc.tokens, t)
+		}
+	}
+	if _, used := uc.tokens[token]; used {
+		return false
+	}
+	uc.tokens[token] = expires
+	return true
+}
+
+// confirmMAC signs a confirmation for one session, action and target
+func (s *Server) confirmMAC(session *types.Session, action, target string, expires int64) string {
+	mac := hmac.New(sha256.New, s.csrfKey)
+	fmt.Fprintf(mac, "confirm\x00%s\x00%s\x00%s\x00%d", session.ID, action, target, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// confirmToken issues a token confirming action on target for the session.
+// Like the CSRF token it is an HMAC, so it needs no stored state until
+// it is spent.
+func (s *Server) confirmToken(session *types.Session, action, target string, expires time.Time) string {
+	return strconv.FormatInt(expires.Unix(), 10) + "." + s.confirmMAC(session, action, target, expires.Unix())
+}
+
+// confirmURL is where a token for action on target is issued
+func confirmURL(action, target string) string {
+	query := url.Values{"action": {action}}
+	if target != "" {
+		query.Set("target", target)
+	}
+	return "/admin/confirm?" + query.Encode()
+}
+
+// requireConfirmation checks the confirmation token of a destructive
+// request for action on target and spends it. Without a valid token it
+// answers 428 with where to get one, plus details about what would be
+// lost, and returns false. It passes every request when
+// CONFIRM_DESTRUCTIVE is off.
+func (s *Server) requireConfirmation(w http.ResponseWriter, r *http.Request, action, target string, details map[string]interface{}) bool {
+	if !s.Config.ConfirmDestructive {
+		return true
+	}
+	session, ok := types.SessionFromContext(r.Context())
+	if !ok {
+		s.writeError(w, r, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Authentication required")
+		return false
+	}
+
+	token := r.Header.Get(ConfirmHeader)
+	if token == "" {
+		token = r.URL.Query().Get(ConfirmParam)
+	}
+	now := s.Clock()
+	detail := "This cannot be undone; confirm it with a token from GET " + confirmURL(action, target) + " in the " + ConfirmHeader + " header"
+	if token != "" {
+		expiresStr, mac, _ := strings.Cut(token, ".")
+		expiresUnix, err := strconv.ParseInt(expiresStr, 10, 64)
+		expires := time.Unix(expiresUnix, 0)
+		switch {
+		case err != nil || !hmac.Equal([]byte(mac), []byte(s.confirmMAC(session, action, target, expiresUnix))):
+			detail = "Invalid confirmation token for " + action + "; get a new one from GET " + confirmURL(action, target)
+		case now.After(expires):
+			detail = "Confirmation token expired; get a new one from GET " + confirmURL(action, target)
+		case !s.confirmations.spend(token, expires, now):
+			detail = "Confirmation token already used; get a new one from GET " + confirmURL(action, target)
+		default:
+			return true
+		}
+	}
+
+	problem := types.NewProblem(http.StatusPreconditionRequired, types.ErrCodeConfirmationRequired, detail)
+	data := map[string]interface{}{
+		"action":      action,
+		"description": confirmActions[action],
+		"confirm_url": confirmURL(action, target),
+	}
+	if target != "" {
+		data["target"] = target
+	}
+	for key, value := range details {
+		data[key] = value
+	}
+	problem.Data = data
+	problem.Write(w, r)
+	return false
+}
+
+// handleConfirm issues a confirmation token for one destructive action,
+// valid for five minutes and one request
+func (s *Server) handleConfirm(w http.ResponseWriter, r *http.Request) {
+	action := r.URL.Query().Get("action")
+	target := r.URL.Query().Get("target")
+	description, ok := confirmActions[action]
+	if !ok {
+		actions := make([]string, 0, len(confirmActions))
+		for name := range confirmActions {
+			actions = append(actions, name)
+		}
+		sort.Strings(actions)
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest,
+			fmt.Sprintf("Unknown action %q; expected one of %s", action, strings.Join(actions, ", ")))
+		return
+	}
+	session, _ := types.SessionFromContext(r.Context())
+
+	expires := s.Clock().Add(confirmTokenTTL)
+	response := types.NewAPIResponse(true, "Confirmation token issued: "+description)
+	response.SetData(map[string]interface{}{
+		"token":       s.confirmToken(session, action, target, expires),
+		"action":      action,
+		"target":      target,
+		"description": description,
+		"expires_at":  expires,
+		"header":      ConfirmHeader,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	s.encodeResponse(w, r, response)
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// handleContentChanges returns the content patches saved after revision
+// ?since=, so an editor that went offline can catch up without reloading
+// everything. A revision whose changes are no longer kept gets a 409; the
+// editor then reloads GET /admin/content.
+func (s *Server) handleContentChanges(w http.ResponseWriter, r *http.Request) {
+	since, err := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "since must be a content revision number")
+		return
+	}
+
+	revision, changes, err := s.ContentManager.ChangesSince(since)
+	if s.writeChangesError(w, r, since, revision, err) {
+		return
+	}
+
+	response := types.NewAPIResponse(true, fmt.Sprintf("%d change(s) since revision %d", len(changes), since))
+	response.SetData(map[string]interface{}{
+		"revision": revision,
+		"changes":  changes,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleContentChangesApply saves patches an editor made offline against
+// base_revision. Patches touching a field that was changed on the server
+// since then are conflicts, and nothing is saved unless force is set; the
+// response lists them with the server's changes so the editor can merge.
+// The patched content is validated and saved like any other edit, only if
+// no other save came in meanwhile. It needs the X-CSRF-Token header.
+func (s *Server) handleContentChangesApply(w http.ResponseWriter, r *http.Request) {
+	if !s.checkCSRF(w, r) {
+		return
+	}
+
+	var requestData struct {
+		BaseRevision *int64                  `json:"base_revision"`
+		Patches      []managers.ContentPatch `json:"patches"`
+		Force        bool                    `json:"force"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON in request body")
+		return
+	}
+	if requestData.BaseRevision == nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "base_revision is required")
+		return
+	}
+	if len(requestData.Patches) == 0 {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "patches is required")
+		return
+	}
+
+	base := *requestData.BaseRevision
+	revision, changes, err := s.ContentManager.ChangesSince(base)
+	// Forcing overwrites whatever the server has, known or not
+	forced := requestData.Force && (errors.Is(err, managers.ErrChangesExpired) || errors.Is(err, managers.ErrUnknownRevision))
+	if !forced && s.writeChangesError(w, r, base, revision, err) {
+		return
+	}
+	if !requestData.Force {
+		if conflicts := managers.PatchConflicts(requestData.Patches, changes); len(conflicts) > 0 {
+			response := types.NewAPIResponse(false, fmt.Sprintf("%d field(s) were changed on the server since revision %d; merge them, or send force to overwrite", len(conflicts), base))
+			response.SetData(map[string]interface{}{
+				"revision":  revision,
+				"conflicts": conflicts,
+				"changes":   changes,
+			})
+			s.writeErrorResponse(w, r, http.StatusConflict, response)
+			return
+		}
+	}
+
+	content, err := s.ContentManager.LoadContent()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load content: "+err.Error())
+		return
+	}
+	contentMap := map[string]interface{}{
+		"title":       content.Title,
+		"description": content.Description,
+		"sections":    content.Sections,
+	}
+	if err := managers.ApplyContentPatches(contentMap, requestData.Patches); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// The content was read at revision; a save since then means it has to
+	// be checked again
+	validationResult, err := s.saveSubmittedContentAt(r.Context(), contentMap, revision)
+	if errors.Is(err, managers.ErrRevisionConflict) {
+		current, _ := s.ContentManager.ContentRevision()
+		response := types.NewAPIResponse(false, fmt.Sprintf("The content was saved again while the patches were applied; sync from revision %d and try again", base))
+		response.SetData(map[string]interface{}{"revision": current})
+		s.writeErrorResponse(w, r, http.StatusConflict, response)
+		return
+	}
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+	if !validationResult.Valid {
+		s.writeContentInvalid(w, r, validationResult)
+		return
+	}
+	revision, err = s.ContentManager.ContentRevision()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, fmt.Sprintf("Applied %d patch(es)", len(requestData.Patches)))
+	response.SetData(map[string]interface{}{
+		"revision":   revision,
+		"validation": validationResult,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// writeChangesError writes the response for a ChangesSince failure and
+// reports whether there was one
+func (s *Server) writeChangesError(w http.ResponseWriter, r *http.Request, since, revision int64, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, managers.ErrUnknownRevision):
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest,
+			fmt.Sprintf("Revision %d is newer than the content, which is at revision %d", since, revision))
+	case errors.Is(err, managers.ErrChangesExpired):
+		response := types.NewAPIResponse(false, fmt.Sprintf("Changes since revision %d are no longer kept; reload the content", since))
+		response.SetData(map[string]interface{}{"revision": revision})
+		s.writeErrorResponse(w, r, http.StatusConflict, response)
+	default:
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+	}
+	return true
+}
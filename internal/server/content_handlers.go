@@ -2,162 +2,385 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"strings"
 	"time"
 
+	"onepagems/internal/managers"
 	"onepagems/internal/types"
 )
 
-// handleContent handles content management requests
-func (s *Server) handleContent(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case "GET":
-		// Load and return current content
-		content, err := s.ContentManager.LoadContent()
-		if err != nil {
-			response := types.NewAPIResponse(false, "Failed to load content: "+err.Error())
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(response)
-			return
+// handleContentGet loads and returns the current content as JSON. Content
+// that violates the current schema is still returned, with the validation
+// result in meta.validation.
+func (s *Server) handleContentGet(w http.ResponseWriter, r *http.Request) {
+	content, validation, err := s.ContentManager.LoadContentWithValidation(s.SchemaManager)
+	if err != nil {
+		response := types.NewAPIResponse(false, "Failed to load content: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Content loaded successfully")
+	response.SetData(content)
+	if validation != nil && !validation.Valid {
+		response.Meta["validation"] = validation
+	}
+	// The revision to sync from with GET /admin/content/changes
+	if revision, err := s.ContentManager.ContentRevision(); err == nil {
+		response.Meta["revision"] = revision
+	}
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeCachedResponse(w, r, response)
+}
+
+// handleContentForm saves content from a classic form-encoded or multipart
+// submission, so the editor works without JavaScript. Bracketed field names
+// such as sections[hero][title] map onto nested content, and files uploaded
+// under a field name are stored as images and replace its value with the
+// image URL. The form must carry the session's CSRF token. Browsers are
+// redirected back to the basic editor, or shown it again with the errors if
+// validation fails; clients that accept JSON get the usual response.
+func (s *Server) handleContentForm(w http.ResponseWriter, r *http.Request) {
+	// Allow a little headroom for the multipart envelope
+	r.Body = http.MaxBytesReader(w, r.Body, s.Config.UploadMaxSize+1024*1024)
+	if err := r.ParseMultipartForm(s.Config.UploadMaxSize); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		response := types.NewAPIResponse(false, "Invalid form submission: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+	if !s.checkCSRF(w, r) {
+		return
+	}
+
+	values := make(map[string][]string, len(r.PostForm))
+	for name, fieldValues := range r.PostForm {
+		if name == CSRFField {
+			continue
 		}
+		values[name] = fieldValues
+	}
 
-		response := types.NewAPIResponse(true, "Content loaded successfully")
-		response.SetData(content)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
-
-	case "POST":
-		// Update content
-		var updates map[string]interface{}
-		if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-			response := types.NewAPIResponse(false, "Invalid JSON in request body: "+err.Error())
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(response)
-			return
+	if r.MultipartForm != nil {
+		for name, headers := range r.MultipartForm.File {
+			urls, err := s.uploadFormImages(r, headers)
+			if err != nil {
+				response := types.NewAPIResponse(false, "Failed to upload image for "+name+": "+err.Error())
+				s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+				return
+			}
+			if len(urls) > 0 {
+				values[name] = urls
+			}
 		}
+	}
+
+	content, err := s.SchemaManager.ContentFromForm(values)
+	if err != nil {
+		response := types.NewAPIResponse(false, "Failed to process form: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
+		return
+	}
 
-		if err := s.ContentManager.UpdateContent(updates); err != nil {
-			response := types.NewAPIResponse(false, "Failed to update content: "+err.Error())
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(response)
+	validationResult, err := s.saveSubmittedContent(r.Context(), content)
+	if err != nil {
+		response := types.NewAPIResponse(false, err.Error())
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
+		return
+	}
+
+	wantsJSON := strings.Contains(r.Header.Get("Accept"), "application/json")
+	if !validationResult.Valid {
+		if wantsJSON {
+			s.writeContentInvalid(w, r, validationResult)
 			return
 		}
+		s.renderBasicContent(w, r, http.StatusBadRequest, content, validationResult.Errors, "")
+		return
+	}
 
-		response := types.NewAPIResponse(true, "Content updated successfully")
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+	if wantsJSON {
+		s.writeContentSaved(w, r, validationResult)
+		return
+	}
+	http.Redirect(w, r, "/admin/basic/content?saved=1", http.StatusSeeOther)
+}
+
+// uploadFormImages stores the files submitted under one form field and
+// returns their URLs. Empty file inputs are skipped.
+func (s *Server) uploadFormImages(r *http.Request, headers []*multipart.FileHeader) ([]string, error) {
+	urls := make([]string, 0, len(headers))
+	for _, header := range headers {
+		if header.Size == 0 {
+			continue
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
 
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		info, err := s.ImageManager.UploadImage(r.Context(), header.Filename, data)
+		if err != nil {
+			return nil, err
+		}
+		s.logActivity(r.Context(), "Image Uploaded", "Image "+info.OriginalName+" was uploaded as "+info.Filename)
+		s.afterUpload(r.Context(), info)
+		urls = append(urls, info.URL)
 	}
+	return urls, nil
 }
 
-// handleContentInfo returns information about the current content
-func (s *Server) handleContentInfo(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// handleContentHeal fixes trivially repairable schema violations in the stored
+// content and reports what changed. With ?dry_run=true nothing is saved.
+func (s *Server) handleContentHeal(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	report, err := s.ContentManager.HealContent(s.SchemaManager, dryRun)
+	if err != nil {
+		response := types.NewAPIResponse(false, "Failed to heal content: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
+		return
+	}
+
+	if report.Saved {
+		s.logActivity(r.Context(), "Content Healed", fmt.Sprintf("Applied %d automatic fixes to content", len(report.Fixes)))
+	}
+
+	message := fmt.Sprintf("%d fixes applied", len(report.Fixes))
+	if dryRun {
+		message = fmt.Sprintf("%d fixes available", len(report.Fixes))
+	}
+
+	response := types.NewAPIResponse(true, message)
+	response.SetData(report)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleContentDuplicate copies a section or array item, e.g. to add another
+// service card like an existing one. The body is {"path", "suffix"}, with
+// path in validation-error form ("sections.services.items[1]"). The copy is
+// validated and saved like any other edit.
+func (s *Server) handleContentDuplicate(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		Path   string  `json:"path"`
+		Suffix *string `json:"suffix"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON in request body")
+		return
+	}
+	suffix := managers.DefaultDuplicateSuffix
+	if requestData.Suffix != nil {
+		suffix = *requestData.Suffix
+	}
+
+	content, err := s.ContentManager.LoadContent()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load content: "+err.Error())
+		return
+	}
+	contentMap := map[string]interface{}{
+		"title":       content.Title,
+		"description": content.Description,
+		"sections":    content.Sections,
+	}
+
+	newPath, err := managers.DuplicateContentItem(contentMap, requestData.Path, suffix)
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, err.Error())
 		return
 	}
 
+	validationResult, err := s.saveSubmittedContent(r.Context(), contentMap)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+	if !validationResult.Valid {
+		s.writeContentInvalid(w, r, validationResult)
+		return
+	}
+
+	s.logActivity(r.Context(), "Content Duplicated", fmt.Sprintf("Duplicated %s as %s", requestData.Path, newPath))
+
+	response := types.NewAPIResponse(true, "Content duplicated")
+	response.SetData(map[string]interface{}{
+		"path":       requestData.Path,
+		"new_path":   newPath,
+		"validation": validationResult,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleContentInfo returns information about the current content
+func (s *Server) handleContentInfo(w http.ResponseWriter, r *http.Request) {
 	summary, err := s.ContentManager.GetContentSummary()
 	if err != nil {
 		response := types.NewAPIResponse(false, "Failed to get content information: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
 		return
 	}
 
 	response := types.NewAPIResponse(true, "Content information retrieved")
 	response.SetData(summary)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
 
 // handleContentRestore restores content from backup
 func (s *Server) handleContentRestore(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !s.requireRecentAuth(w, r) {
+		return
+	}
+	if !s.requireConfirmation(w, r, "content.restore", "", nil) {
 		return
 	}
-
 	if err := s.ContentManager.RestoreContent(); err != nil {
 		response := types.NewAPIResponse(false, "Failed to restore content: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
 		return
 	}
 
 	response := types.NewAPIResponse(true, "Content restored from backup successfully")
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
 
-// handleContentExport exports content as JSON
+// handleContentExport exports content as JSON. Fields marked x-private in
+// the schema are left out unless include_private=true is given, e.g. for a
+// full backup.
 func (s *Server) handleContentExport(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+	includePrivate := r.URL.Query().Get("include_private") == "true"
+	var content interface{}
+	var err error
+	if includePrivate {
+		content, err = s.ContentManager.LoadContent()
+	} else {
+		content, err = s.publicContent()
 	}
-
-	data, err := s.ContentManager.ExportContent()
 	if err != nil {
 		response := types.NewAPIResponse(false, "Failed to export content: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
 		return
 	}
 
+	// A signature covers the whole document, so signed exports are built
+	// in memory; unsigned ones are encoded straight to the response
+	var signed []byte
+	if s.ExportSigner != nil {
+		data, err := json.MarshalIndent(content, "", "  ")
+		if err == nil {
+			signed, err = s.ExportSigner.Sign(data, includePrivate, s.Clock())
+		}
+		if err != nil {
+			response := types.NewAPIResponse(false, "Failed to export content: "+err.Error())
+			s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Content-Disposition", "attachment; filename=content-export.json")
-	w.Write(data)
+	if signed != nil {
+		w.Write(signed)
+		return
+	}
+	if err := managers.EncodeExport(w, content); err != nil {
+		// Headers are already sent; the client sees a truncated file
+		s.Logger.Printf("Content export failed: %v", err)
+	}
 }
 
-// handleContentImport imports content from JSON
+// publicContent returns the current content without private fields
+func (s *Server) publicContent() (map[string]interface{}, error) {
+	content, err := s.ContentManager.LoadContent()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.SchemaManager.PublicContent(content)
+}
+
+// handleContentImport imports content from JSON. The body is either
+// {"content": ...} or a signed export file. With a signing key configured,
+// unsigned or tampered files are refused unless ?force=true is given.
 func (s *Server) handleContentImport(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !s.requireConfirmation(w, r, "content.import", "", nil) {
 		return
 	}
 
-	// Read the request body
-	var requestData struct {
-		Content json.RawMessage `json:"content"`
-	}
+	// Read the request body; a signed export has content plus signature fields
+	var requestData managers.SignedExport
 
 	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
 		response := types.NewAPIResponse(false, "Invalid JSON in request body: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	if err := s.verifyImport(&requestData); err != nil {
+		if r.URL.Query().Get("force") != "true" {
+			s.writeError(w, r, http.StatusUnprocessableEntity, types.ErrCodeInvalidSignature,
+				err.Error()+"; import with ?force=true to accept it anyway")
+			return
+		}
+		s.logActivity(r.Context(), "content_import_forced", err.Error())
+	}
+
+	var imported types.ContentData
+	if err := json.Unmarshal(requestData.Content, &imported); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid content: "+err.Error())
+		return
+	}
+	if err := s.Plugins.ContentSave(r.Context(), &imported); err != nil {
+		s.writeError(w, r, http.StatusUnprocessableEntity, types.ErrCodeValidationFailed, err.Error())
 		return
 	}
 
 	if err := s.ContentManager.ImportContent(requestData.Content); err != nil {
 		response := types.NewAPIResponse(false, "Failed to import content: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
 		return
 	}
 
+	s.logActivity(r.Context(), "Content Imported", "Content replaced by an import")
+
 	response := types.NewAPIResponse(true, "Content imported successfully")
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
 
-// handleTestContent tests content management operations
-func (s *Server) handleTestContent(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// verifyImport checks the signature of an import. Without a signing key
+// only files that claim to be signed are rejected, since they cannot be
+// checked.
+func (s *Server) verifyImport(data *managers.SignedExport) error {
+	signed := data.Format != "" || data.Signature != nil
+	if s.ExportSigner == nil {
+		if signed {
+			return fmt.Errorf("export is signed but no signing key is configured")
+		}
+		return nil
+	}
+	if !signed {
+		return managers.ErrExportUnsigned
 	}
+	return s.ExportSigner.Verify(data)
+}
 
+// handleTestContent tests content management operations
+func (s *Server) handleTestContent(w http.ResponseWriter, r *http.Request) {
 	results := make(map[string]interface{})
 
 	// Test 1: Load current content
@@ -180,7 +403,7 @@ func (s *Server) handleTestContent(w http.ResponseWriter, r *http.Request) {
 
 	// Test 3: Update content (test update)
 	testUpdates := map[string]interface{}{
-		"description": "Test description updated at " + time.Now().Format(time.RFC3339),
+		"description": "Test description updated at " + s.Clock().Format(time.RFC3339),
 	}
 	if err := s.ContentManager.UpdateContent(testUpdates); err != nil {
 		results["update_content"] = "Failed: " + err.Error()
@@ -208,5 +431,5 @@ func (s *Server) handleTestContent(w http.ResponseWriter, r *http.Request) {
 	response.SetData(results)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
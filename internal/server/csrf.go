@@ -0,0 +1,94 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"onepagems/internal/types"
+)
+
+// CSRFHeader and CSRFField carry the CSRF token on form posts
+const (
+	CSRFHeader = "X-CSRF-Token"
+	CSRFField  = "csrf_token"
+)
+
+// csrfKeyFilename keeps the CSRF key when there is no secrets key to
+// derive it from
+const csrfKeyFilename = "csrf.key"
+
+// newCSRFKey returns a random key for signing CSRF tokens
+func newCSRFKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+// loadCSRFKey returns the key CSRF and confirmation tokens are signed with.
+// It must outlive restarts, since persisted sessions keep their tokens: it
+// is derived from the secrets key, or else kept in DATA_DIR/csrf.key,
+// readable by the owner only.
+func (s *Server) loadCSRFKey() ([]byte, error) {
+	if key, ok := s.Secrets.DeriveKey("csrf"); ok {
+		return key, nil
+	}
+	if s.Storage.FileExists(csrfKeyFilename) {
+		text, err := s.Storage.ReadTextFile(csrfKeyFilename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSRF key: %w", err)
+		}
+		key, err := hex.DecodeString(strings.TrimSpace(text))
+		if err == nil && len(key) == 32 {
+			return key, nil
+		}
+		// A damaged key is replaced; signed-in admins get new tokens
+		s.Logger.Printf("Replacing the invalid CSRF key in %s", csrfKeyFilename)
+	}
+
+	key := newCSRFKey()
+	path := s.Storage.GetFilePath(csrfKeyFilename)
+	if err := os.WriteFile(path+".tmp", []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to save CSRF key: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		os.Remove(path + ".tmp")
+		return nil, fmt.Errorf("failed to save CSRF key: %w", err)
+	}
+	return key, nil
+}
+
+// csrfToken returns the CSRF token for a session. It is an HMAC of the
+// session ID, so it works with any AuthProvider and needs no stored state.
+func (s *Server) csrfToken(session *types.Session) string {
+	mac := hmac.New(sha256.New, s.csrfKey)
+	mac.Write([]byte(session.ID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkCSRF verifies the token sent in the X-CSRF-Token header or the
+// csrf_token form field of a parsed form post. On failure it writes a 403
+// and returns false.
+func (s *Server) checkCSRF(w http.ResponseWriter, r *http.Request) bool {
+	session, ok := types.SessionFromContext(r.Context())
+	if !ok {
+		s.writeError(w, r, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Authentication required")
+		return false
+	}
+
+	token := r.Header.Get(CSRFHeader)
+	if token == "" {
+		token = r.PostFormValue(CSRFField)
+	}
+
+	if !hmac.Equal([]byte(token), []byte(s.csrfToken(session))) {
+		s.writeError(w, r, http.StatusForbidden, types.ErrCodeForbidden, "Missing or invalid CSRF token")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"onepagems/internal/types"
+)
+
+// requireAdmin writes a 403 and returns false unless the request comes from
+// the configured admin user. Other providers may let editors sign in; only
+// the admin may change code that runs on the public site or manage secrets.
+func (s *Server) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	session, ok := types.SessionFromContext(r.Context())
+	if !ok {
+		s.writeError(w, r, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Authentication required")
+		return false
+	}
+	if session.Username != s.Config.AdminUsername {
+		s.writeError(w, r, http.StatusForbidden, types.ErrCodeForbidden, "Only the admin user can do this")
+		return false
+	}
+	return true
+}
+
+// handleCustomCodeGet returns the custom head and body-end code slots with
+// their audit history
+func (s *Server) handleCustomCodeGet(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	code, err := s.CustomCode.Load()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to load custom code: %v", err))
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Custom code retrieved")
+	response.SetData(map[string]interface{}{
+		"custom_code": code,
+		"max_size":    s.CustomCode.MaxSize(),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleCustomCodePost saves the custom code slots. They are injected into
+// the page the next time the site is generated.
+func (s *Server) handleCustomCodePost(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	var requestData struct {
+		HeadHTML    string `json:"head_html"`
+		BodyEndHTML string `json:"body_end_html"`
+	}
+
+	// Both slots plus JSON escaping fit comfortably in four times the limit
+	r.Body = http.MaxBytesReader(w, r.Body, int64(4*s.CustomCode.MaxSize()+4096))
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		response := types.NewAPIResponse(false, "Invalid JSON in request body: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	session, _ := types.SessionFromContext(r.Context())
+	entry, err := s.CustomCode.Save(requestData.HeadHTML, requestData.BodyEndHTML, session.Username)
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	s.logActivity(r.Context(), "Custom Code Updated", fmt.Sprintf("%s set head code (%d bytes, %s) and body-end code (%d bytes, %s)",
+		entry.By, entry.HeadSize, entry.HeadHash, entry.BodyEndSize, entry.BodyEndHash))
+
+	response := types.NewAPIResponse(true, "Custom code saved; regenerate the site to publish it")
+	response.SetData(map[string]interface{}{
+		"audit": entry,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// handleDoctor checks the data directory layout and reports problems with
+// suggested fixes. Pass ?client_time=<RFC3339> to also compare the server
+// clock with the caller's.
+func (s *Server) handleDoctor(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	var reference time.Time
+	if clientTime := r.URL.Query().Get("client_time"); clientTime != "" {
+		parsed, err := time.Parse(time.RFC3339, clientTime)
+		if err != nil {
+			s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "client_time must be an RFC3339 timestamp")
+			return
+		}
+		reference = parsed
+	}
+
+	doctor := managers.NewDoctor(s.Storage, s.TemplateManager, s.Config.OutputDir, s.Config.StaticDir, s.Config.TemplatesDir)
+	report := doctor.Run(s.Clock(), reference)
+
+	response := types.NewAPIResponse(true, "Doctor finished with status "+report.Status)
+	response.SetData(report)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// errDraftRejected stops a publish whose draft failed validation or a
+// plugin check; the handler reports the collected errors
+var errDraftRejected = errors.New("draft rejected")
+
+// draftContentMap is the content of a draft as validation sees it
+func draftContentMap(content *types.ContentData) map[string]interface{} {
+	return map[string]interface{}{
+		"title":       content.Title,
+		"description": content.Description,
+		"sections":    content.Sections,
+	}
+}
+
+// handleContentDraftGet returns the content draft. meta.has_draft is false
+// when there is none; meta.content_changed warns that the content was saved
+// after the draft started, so publishing it would undo those changes, and
+// meta.validation lists what must be fixed before it can be published.
+func (s *Server) handleContentDraftGet(w http.ResponseWriter, r *http.Request) {
+	draft, err := s.ContentManager.LoadDraft()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+	if draft == nil {
+		response := types.NewAPIResponse(true, "No content draft")
+		response.Meta["has_draft"] = false
+		w.Header().Set("Content-Type", "application/json")
+		s.encodeResponse(w, r, response)
+		return
+	}
+
+	content, err := s.ContentManager.LoadContent()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load content: "+err.Error())
+		return
+	}
+	validation, err := s.SchemaManager.ValidateContentDetailed(draftContentMap(draft.Content))
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Validation failed: "+err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Content draft loaded")
+	response.SetData(draft)
+	response.Meta["has_draft"] = true
+	response.Meta["content_changed"] = content.LastUpdated.After(draft.BaseUpdated)
+	response.Meta["validation"] = validation
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleContentDraftSave replaces the draft with the posted content,
+// {"title", "description", "sections"}, leaving the published content as
+// it is. The draft is saved even if it does not validate yet;
+// meta.validation says what publishing it would be refused for.
+func (s *Server) handleContentDraftSave(w http.ResponseWriter, r *http.Request) {
+	var content map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&content); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON in request body")
+		return
+	}
+	if _, err := s.SchemaManager.CoerceContent(content); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to process content: "+err.Error())
+		return
+	}
+	validation, err := s.SchemaManager.ValidateContentDetailed(content)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Validation failed: "+err.Error())
+		return
+	}
+
+	contentData := &types.ContentData{}
+	if err := s.mapToContentData(content, contentData); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Failed to process content: "+err.Error())
+		return
+	}
+	username := ""
+	if session, ok := types.SessionFromContext(r.Context()); ok {
+		username = session.Username
+	}
+	draft, err := s.ContentManager.SaveDraft(contentData, username)
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Content draft saved")
+	response.SetData(draft)
+	response.Meta["validation"] = validation
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleContentDraftDiscard drops the draft
+func (s *Server) handleContentDraftDiscard(w http.ResponseWriter, r *http.Request) {
+	discarded, err := s.ContentManager.DiscardDraft()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+	if discarded {
+		s.logActivity(r.Context(), "Draft Discarded", "The content draft was discarded")
+	}
+
+	response := types.NewAPIResponse(true, "Content draft discarded")
+	response.Meta["discarded"] = discarded
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleContentDraftPublish makes the draft the content, checked like any
+// other save. It answers 409 when the content was saved after the draft
+// started, unless ?force=true. Generate the site to put it live.
+func (s *Server) handleContentDraftPublish(w http.ResponseWriter, r *http.Request) {
+	force := r.URL.Query().Get("force") == "true"
+
+	var validation *managers.ValidationResult
+	content, err := s.ContentManager.PublishDraft(force, func(content *types.ContentData) error {
+		result, err := s.SchemaManager.ValidateContentDetailed(draftContentMap(content))
+		if err != nil {
+			return err
+		}
+		validation = result
+		if !result.Valid {
+			return errDraftRejected
+		}
+		if err := s.Plugins.ContentSave(r.Context(), content); err != nil {
+			validation.Valid = false
+			validation.Errors = append(validation.Errors, managers.ValidationDetailError{
+				Code:    "plugin_rejected",
+				Message: err.Error(),
+			})
+			return errDraftRejected
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errDraftRejected):
+		s.writeContentInvalid(w, r, validation)
+		return
+	case errors.Is(err, managers.ErrNoDraft):
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "There is no content draft to publish")
+		return
+	case errors.Is(err, managers.ErrDraftStale):
+		s.writeError(w, r, http.StatusConflict, types.ErrCodeConflict,
+			"The content was saved after this draft was started; publishing it would undo those changes. Publish with ?force=true to replace them anyway")
+		return
+	case err != nil:
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to publish draft: "+err.Error())
+		return
+	}
+
+	// The published draft supersedes any unsaved editor state
+	if session, ok := types.SessionFromContext(r.Context()); ok {
+		s.Autosaves.Discard(session.Username)
+	}
+	s.logActivity(r.Context(), "Draft Published", "The content draft was published")
+
+	response := types.NewAPIResponse(true, "Content draft published")
+	response.SetData(content)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleContentDraftPreview renders the page as it would look with the
+// draft published
+func (s *Server) handleContentDraftPreview(w http.ResponseWriter, r *http.Request) {
+	draft, err := s.ContentManager.LoadDraft()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+	if draft == nil {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "There is no content draft to preview")
+		return
+	}
+
+	page, err := s.Generator.RenderContentPage(r.Context(), draft.Content)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to render draft: "+err.Error())
+		return
+	}
+	if page.Policy != "" {
+		w.Header().Set("Content-Security-Policy", page.Policy)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(page.HTML))
+}
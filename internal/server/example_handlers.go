@@ -0,0 +1,186 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// apiExample is an example request and the response it gets, built from the
+// current schema
+type apiExample struct {
+	Endpoint    string      `json:"endpoint"`
+	Method      string      `json:"method"`
+	Path        string      `json:"path"`
+	Description string      `json:"description"`
+	Request     interface{} `json:"request"`
+	Response    interface{} `json:"response"`
+	// Valid reports whether the example request passes validation as it is.
+	// Schemas with patterns the placeholders cannot match may need edits.
+	Valid  bool                             `json:"valid"`
+	Errors []managers.ValidationDetailError `json:"errors,omitempty"`
+}
+
+// apiExampleBuilders build the example of each endpoint by name
+var apiExampleBuilders = map[string]func(s *Server, schema *types.SchemaData) (*apiExample, error){
+	"content":        (*Server).contentSaveExample,
+	"validate":       (*Server).contentValidateExample,
+	"validate-field": (*Server).fieldValidateExample,
+}
+
+// handleAPIExamplesList lists the endpoints that have examples
+func (s *Server) handleAPIExamplesList(w http.ResponseWriter, r *http.Request) {
+	endpoints := make([]string, 0, len(apiExampleBuilders))
+	for name := range apiExampleBuilders {
+		endpoints = append(endpoints, name)
+	}
+	sort.Strings(endpoints)
+
+	response := types.NewAPIResponse(true, "Example endpoints retrieved")
+	response.SetData(map[string]interface{}{"endpoints": endpoints})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleAPIExample returns an example request and response for an endpoint,
+// filled from the current schema
+func (s *Server) handleAPIExample(w http.ResponseWriter, r *http.Request) {
+	endpoint := r.PathValue("endpoint")
+	build, ok := apiExampleBuilders[endpoint]
+	if !ok {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, fmt.Sprintf("No example for %q; see /admin/api/examples", endpoint))
+		return
+	}
+
+	schema, err := s.SchemaManager.LoadSchema()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load schema: "+err.Error())
+		return
+	}
+	example, err := build(s, schema)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to build example: "+err.Error())
+		return
+	}
+	example.Endpoint = endpoint
+
+	response := types.NewAPIResponse(true, "Example generated from the current schema")
+	response.SetData(example)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// validateExample validates a copy of content, as a save would after
+// coercing form values
+func (s *Server) validateExample(content map[string]interface{}) (*managers.ValidationResult, error) {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	copied := make(map[string]interface{})
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, err
+	}
+	if _, err := s.SchemaManager.CoerceContent(copied); err != nil {
+		return nil, err
+	}
+	return s.SchemaManager.ValidateContentDetailed(copied)
+}
+
+// contentSaveExample is POST /admin/content with full content
+func (s *Server) contentSaveExample(schema *types.SchemaData) (*apiExample, error) {
+	content := managers.ExampleContent(schema)
+	result, err := s.validateExample(content)
+	if err != nil {
+		return nil, err
+	}
+
+	// The same bodies writeContentSaved and writeContentInvalid send
+	var response *types.APIResponse
+	if result.Valid {
+		response = types.NewAPIResponse(true, "Content saved successfully")
+		response.SetData(map[string]interface{}{
+			"validation": result,
+			"timestamp":  s.Clock().Format(time.RFC3339),
+		})
+	} else {
+		response = types.NewAPIResponse(false, "Content validation failed")
+		response.SetData(map[string]interface{}{
+			"errors":      result.Errors,
+			"valid":       false,
+			"error_count": len(result.Errors),
+		})
+	}
+	return &apiExample{
+		Method:      http.MethodPost,
+		Path:        "/admin/content",
+		Description: "Replace the whole content. The body is the content object; fields left out are cleared.",
+		Request:     content,
+		Response:    response,
+		Valid:       result.Valid,
+		Errors:      result.Errors,
+	}, nil
+}
+
+// contentValidateExample is POST /admin/schema/validate-content
+func (s *Server) contentValidateExample(schema *types.SchemaData) (*apiExample, error) {
+	content := managers.ExampleContent(schema)
+	result, err := s.validateExample(content)
+	if err != nil {
+		return nil, err
+	}
+	return &apiExample{
+		Method:      http.MethodPost,
+		Path:        "/admin/schema/validate-content",
+		Description: "Check content against the schema without saving it.",
+		Request:     map[string]interface{}{"content": content},
+		Response:    result,
+		Valid:       result.Valid,
+		Errors:      result.Errors,
+	}, nil
+}
+
+// fieldValidateExample is POST /admin/schema/validate-field-detailed, which
+// checks one changed field before it is saved
+func (s *Server) fieldValidateExample(schema *types.SchemaData) (*apiExample, error) {
+	content := managers.ExampleContent(schema)
+	field := ""
+	for _, name := range schema.PropertyOrder[""] {
+		if _, isObject := content[name].(map[string]interface{}); !isObject {
+			field = name
+			break
+		}
+	}
+	if field == "" {
+		names := make([]string, 0, len(content))
+		for name, value := range content {
+			if _, isObject := value.(map[string]interface{}); !isObject {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("the schema has no top-level field to validate")
+		}
+		sort.Strings(names)
+		field = names[0]
+	}
+
+	result, err := s.SchemaManager.ValidateFieldValueDetailed(field, content[field])
+	if err != nil {
+		return nil, err
+	}
+	return &apiExample{
+		Method:      http.MethodPost,
+		Path:        "/admin/schema/validate-field-detailed",
+		Description: "Check one top-level field's new value before saving it.",
+		Request:     map[string]interface{}{"field_name": field, "value": content[field]},
+		Response:    result,
+		Valid:       result.Valid,
+		Errors:      result.Errors,
+	}, nil
+}
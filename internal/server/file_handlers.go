@@ -1,7 +1,6 @@
 package server
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
@@ -9,37 +8,44 @@ import (
 	"onepagems/internal/types"
 )
 
+// fileListSpec sorts and filters GET /admin/files
+var fileListSpec = listSpec[types.FileInfo]{
+	Sorts: map[string]func(a, b types.FileInfo) int{
+		"name":        compareBy(func(f types.FileInfo) string { return f.Name }),
+		"size":        compareBy(func(f types.FileInfo) int64 { return f.Size }),
+		"modified_at": func(a, b types.FileInfo) int { return a.ModifiedAt.Compare(b.ModifiedAt) },
+	},
+	DefaultSort: "name",
+	Text:        func(f types.FileInfo) []string { return []string{f.Name} },
+}
+
 // handleFilesList lists all files in the data directory
 func (s *Server) handleFilesList(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	files, err := s.Storage.ListFiles()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to list files: %v", err))
 		return
 	}
 
-	files, err := s.Storage.ListFiles()
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to list files: %v", err), http.StatusInternalServerError)
+	files, page, ok := paginateList(s, w, r, files, fileListSpec)
+	if !ok {
 		return
 	}
 
 	response := types.NewAPIResponse(true, "Files listed successfully")
 	response.SetData(files)
+	response.Meta["pagination"] = page
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
 
 // handleTestStorage demonstrates file storage operations
 func (s *Server) handleTestStorage(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	// Test data
 	testData := map[string]interface{}{
 		"message":    "Hello from file storage test",
-		"timestamp":  time.Now().Format(time.RFC3339),
+		"timestamp":  s.Clock().Format(time.RFC3339),
 		"test_array": []string{"item1", "item2", "item3"},
 		"test_object": map[string]string{
 			"key1": "value1",
@@ -52,14 +58,14 @@ func (s *Server) handleTestStorage(w http.ResponseWriter, r *http.Request) {
 
 	// Write test file
 	if err := s.Storage.WriteJSONFile(filename, testData); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to write test file: %v", err), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to write test file: %v", err))
 		return
 	}
 
 	// Read it back
 	var readData map[string]interface{}
 	if err := s.Storage.ReadJSONFile(filename, &readData); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to read test file: %v", err), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to read test file: %v", err))
 		return
 	}
 
@@ -68,16 +74,16 @@ func (s *Server) handleTestStorage(w http.ResponseWriter, r *http.Request) {
 
 	// Test text file operations
 	textFilename := "test.txt"
-	textContent := fmt.Sprintf("Test text file created at %s\nThis demonstrates text file operations.", time.Now().Format(time.RFC3339))
+	textContent := fmt.Sprintf("Test text file created at %s\nThis demonstrates text file operations.", s.Clock().Format(time.RFC3339))
 
 	if err := s.Storage.WriteTextFile(textFilename, textContent); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to write text file: %v", err), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to write text file: %v", err))
 		return
 	}
 
 	readText, err := s.Storage.ReadTextFile(textFilename)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to read text file: %v", err), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to read text file: %v", err))
 		return
 	}
 
@@ -105,5 +111,5 @@ func (s *Server) handleTestStorage(w http.ResponseWriter, r *http.Request) {
 	response.SetData(result)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
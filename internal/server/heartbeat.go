@@ -0,0 +1,31 @@
+package server
+
+import (
+	"context"
+)
+
+// heartbeatSuccess pings the heartbeat monitor, if one is configured, that
+// background work is running; force pings even within the interval
+func (s *Server) heartbeatSuccess(force bool) {
+	if s.Heartbeat == nil {
+		return
+	}
+	go func() {
+		if err := s.Heartbeat.Success(context.Background(), s.Clock(), force); err != nil {
+			s.Logger.Printf("Heartbeat: %v", err)
+		}
+	}()
+}
+
+// heartbeatFail tells the heartbeat monitor, if one is configured, that
+// background work failed
+func (s *Server) heartbeatFail(reason string) {
+	if s.Heartbeat == nil {
+		return
+	}
+	go func() {
+		if err := s.Heartbeat.Fail(context.Background(), reason); err != nil {
+			s.Logger.Printf("Heartbeat: %v", err)
+		}
+	}()
+}
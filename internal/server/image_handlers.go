@@ -0,0 +1,312 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// imageListSpec sorts and filters GET /admin/images
+var imageListSpec = listSpec[types.ImageInfo]{
+	Sorts: map[string]func(a, b types.ImageInfo) int{
+		"filename":      compareBy(func(i types.ImageInfo) string { return i.Filename }),
+		"original_name": compareBy(func(i types.ImageInfo) string { return i.OriginalName }),
+		"size":          compareBy(func(i types.ImageInfo) int64 { return i.Size }),
+		"uploaded_at":   func(a, b types.ImageInfo) int { return a.UploadedAt.Compare(b.UploadedAt) },
+	},
+	DefaultSort: "-uploaded_at",
+	Text: func(i types.ImageInfo) []string {
+		return []string{i.Filename, i.OriginalName, i.AltText}
+	},
+}
+
+// handleImages lists all uploaded images
+func (s *Server) handleImages(w http.ResponseWriter, r *http.Request) {
+	images, err := s.ImageManager.ListImages()
+	if err != nil {
+		response := types.NewAPIResponse(false, "Failed to list images: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
+		return
+	}
+
+	images, page, ok := paginateList(s, w, r, images, imageListSpec)
+	if !ok {
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Images listed successfully")
+	response.SetData(images)
+	response.Meta["pagination"] = page
+	response.Meta["alt_text_suggestions"] = s.ImageManager.HasSuggester()
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleImageUpload accepts a multipart image upload in the "image" field
+func (s *Server) handleImageUpload(w http.ResponseWriter, r *http.Request) {
+	// Allow a little headroom for the multipart envelope
+	r.Body = http.MaxBytesReader(w, r.Body, s.Config.UploadMaxSize+1024*1024)
+	if err := r.ParseMultipartForm(s.Config.UploadMaxSize); err != nil {
+		response := types.NewAPIResponse(false, "Invalid upload: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		response := types.NewAPIResponse(false, "Image file is required")
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		response := types.NewAPIResponse(false, "Failed to read upload: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	info, err := s.ImageManager.UploadImage(r.Context(), header.Filename, data)
+	if err != nil {
+		response := types.NewAPIResponse(false, "Failed to upload image: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	s.logActivity(r.Context(), "Image Uploaded", "Image "+info.OriginalName+" was uploaded as "+info.Filename)
+	s.afterUpload(r.Context(), info)
+
+	response := types.NewAPIResponse(true, "Image uploaded successfully")
+	response.SetData(info)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleImageAltText updates the alt text of an image
+func (s *Server) handleImageAltText(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		Filename string `json:"filename"`
+		AltText  string `json:"alt_text"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil || requestData.Filename == "" {
+		response := types.NewAPIResponse(false, "Filename and alt_text are required")
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	info, err := s.ImageManager.UpdateAltText(requestData.Filename, requestData.AltText)
+	if err != nil {
+		response := types.NewAPIResponse(false, "Failed to update alt text: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusNotFound, response)
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Alt text updated successfully")
+	response.SetData(info)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleImageSuggestAltText returns a fresh alt text proposal without saving it
+func (s *Server) handleImageSuggestAltText(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		Filename string `json:"filename"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil || requestData.Filename == "" {
+		response := types.NewAPIResponse(false, "Filename is required")
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	altText, err := s.ImageManager.SuggestAltText(r.Context(), requestData.Filename)
+	if err != nil {
+		response := types.NewAPIResponse(false, err.Error())
+		s.writeErrorResponse(w, r, http.StatusBadGateway, response)
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Alt text suggested")
+	response.SetData(map[string]interface{}{
+		"filename": requestData.Filename,
+		"alt_text": altText,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleImageDelete deletes an uploaded image
+func (s *Server) handleImageDelete(w http.ResponseWriter, r *http.Request) {
+	filename := r.URL.Query().Get("filename")
+	if filename == "" {
+		response := types.NewAPIResponse(false, "Filename is required")
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+	if r.URL.Query().Get("force") != "true" {
+		usage, err := s.imageUsage(filename)
+		if err != nil {
+			response := types.NewAPIResponse(false, "Failed to delete image: "+err.Error())
+			s.writeErrorResponse(w, r, http.StatusNotFound, response)
+			return
+		}
+		if usage.InUse() {
+			response := types.NewAPIResponse(false, "Image "+filename+" is still used; delete it with ?force=true to leave those references broken")
+			response.SetData(usage)
+			s.writeErrorResponse(w, r, http.StatusConflict, response)
+			return
+		}
+	}
+	if !s.requireConfirmation(w, r, "images.delete", filename, nil) {
+		return
+	}
+
+	if err := s.ImageManager.DeleteImage(filename); err != nil {
+		response := types.NewAPIResponse(false, "Failed to delete image: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusNotFound, response)
+		return
+	}
+
+	s.logActivity(r.Context(), "Image Deleted", "Image "+filename+" was deleted")
+
+	response := types.NewAPIResponse(true, "Image deleted successfully")
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleImageUsage lists the content fields and template lines that use an
+// image
+func (s *Server) handleImageUsage(w http.ResponseWriter, r *http.Request) {
+	filename := r.URL.Query().Get("filename")
+	if filename == "" {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Filename is required")
+		return
+	}
+
+	usage, err := s.imageUsage(filename)
+	if err != nil {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Image usage retrieved")
+	response.SetData(usage)
+	response.Meta["in_use"] = usage.InUse()
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// imageUsage finds where an image is used in the content, the draft and
+// the template
+func (s *Server) imageUsage(filename string) (*managers.ImageUsage, error) {
+	image, err := s.ImageManager.GetImage(filename)
+	if err != nil {
+		return nil, err
+	}
+	content, err := s.ContentManager.LoadContent()
+	if err != nil {
+		return nil, err
+	}
+	var draftContent *types.ContentData
+	if draft, err := s.ContentManager.LoadDraft(); err != nil {
+		return nil, err
+	} else if draft != nil {
+		draftContent = draft.Content
+	}
+	template, err := s.TemplateManager.LoadTemplate()
+	if err != nil {
+		return nil, err
+	}
+	return managers.FindImageUsage(image, content, draftContent, template), nil
+}
+
+// handleImageCrop crops an image to a pixel rectangle
+func (s *Server) handleImageCrop(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		Filename string `json:"filename"`
+		X        int    `json:"x"`
+		Y        int    `json:"y"`
+		Width    int    `json:"width"`
+		Height   int    `json:"height"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil || requestData.Filename == "" {
+		response := types.NewAPIResponse(false, "Filename and crop rectangle are required")
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	info, err := s.ImageManager.CropImage(r.Context(), requestData.Filename, requestData.X, requestData.Y, requestData.Width, requestData.Height)
+	if err != nil {
+		response := types.NewAPIResponse(false, "Failed to crop image: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	s.logActivity(r.Context(), "Image Edited", "Image "+info.Filename+" was cropped")
+
+	response := types.NewAPIResponse(true, "Image cropped successfully")
+	response.SetData(info)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleImageRotate rotates an image clockwise by a multiple of 90 degrees
+func (s *Server) handleImageRotate(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		Filename string `json:"filename"`
+		Degrees  int    `json:"degrees"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil || requestData.Filename == "" {
+		response := types.NewAPIResponse(false, "Filename and degrees are required")
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	info, err := s.ImageManager.RotateImage(r.Context(), requestData.Filename, requestData.Degrees)
+	if err != nil {
+		response := types.NewAPIResponse(false, "Failed to rotate image: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	s.logActivity(r.Context(), "Image Edited", "Image "+info.Filename+" was rotated")
+
+	response := types.NewAPIResponse(true, "Image rotated successfully")
+	response.SetData(info)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleImageFocalPoint sets the focal point used when cropping image variants
+func (s *Server) handleImageFocalPoint(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		Filename string  `json:"filename"`
+		X        float64 `json:"x"`
+		Y        float64 `json:"y"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil || requestData.Filename == "" {
+		response := types.NewAPIResponse(false, "Filename and focal point are required")
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	info, err := s.ImageManager.SetFocalPoint(r.Context(), requestData.Filename, requestData.X, requestData.Y)
+	if err != nil {
+		response := types.NewAPIResponse(false, "Failed to set focal point: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Focal point updated successfully")
+	response.SetData(info)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
@@ -0,0 +1,290 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// importMappingRequest is the body of the preview and commit steps
+type importMappingRequest struct {
+	managers.ImportMapping
+	AsDraft bool `json:"as_draft,omitempty"` // commit only
+}
+
+// handleImportMappingCreate stores uploaded JSON or CSV for mapping and
+// answers with the fields found in it, the content fields of the schema
+// and suggested rules pairing them. The data is the request body, or the
+// "file" field of a multipart form.
+func (s *Server) handleImportMappingCreate(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.Config.UploadMaxSize+1024*1024)
+
+	filename := r.URL.Query().Get("filename")
+	var data []byte
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(s.Config.UploadMaxSize); err != nil {
+			s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Failed to parse upload: "+err.Error())
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "No file uploaded")
+			return
+		}
+		defer file.Close()
+		filename = header.Filename
+		data, err = io.ReadAll(file)
+	} else {
+		data, err = io.ReadAll(r.Body)
+		if filename == "" && strings.Contains(r.Header.Get("Content-Type"), "csv") {
+			filename = "upload.csv"
+		}
+	}
+	if err != nil {
+		s.writeError(w, r, http.StatusRequestEntityTooLarge, types.ErrCodePayloadTooLarge, "Upload is too large")
+		return
+	}
+	if int64(len(data)) > s.Config.UploadMaxSize {
+		s.writeError(w, r, http.StatusRequestEntityTooLarge, types.ErrCodePayloadTooLarge, "Upload is too large")
+		return
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Upload is empty")
+		return
+	}
+
+	schema, err := s.SchemaManager.LoadSchema()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load schema: "+err.Error())
+		return
+	}
+	upload, err := s.ImportMappings.Create(filename, data, s.Clock())
+	if err != nil {
+		if errors.Is(err, managers.ErrImportInvalid) {
+			s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, err.Error())
+			return
+		}
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+
+	fields := managers.DetectImportFields(upload.Data)
+	targets := managers.ImportTargets(schema)
+	response := types.NewAPIResponse(true, "Upload ready for mapping")
+	response.SetData(map[string]interface{}{
+		"id":          upload.ID,
+		"filename":    upload.Filename,
+		"format":      upload.Format,
+		"fields":      fields,
+		"targets":     targets,
+		"suggestions": managers.SuggestImportRules(fields, targets),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleImportMappingGet returns an upload with its fields and the schema's
+// targets
+func (s *Server) handleImportMappingGet(w http.ResponseWriter, r *http.Request) {
+	upload, ok := s.loadImportUpload(w, r)
+	if !ok {
+		return
+	}
+	schema, err := s.SchemaManager.LoadSchema()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load schema: "+err.Error())
+		return
+	}
+
+	fields := managers.DetectImportFields(upload.Data)
+	targets := managers.ImportTargets(schema)
+	response := types.NewAPIResponse(true, "Upload loaded")
+	response.SetData(map[string]interface{}{
+		"upload":      upload,
+		"fields":      fields,
+		"targets":     targets,
+		"suggestions": managers.SuggestImportRules(fields, targets),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleImportMappingPreview applies a mapping, {"rules": [...], "base"},
+// without saving, and returns the content it builds with the schema
+// validation of it and the values the rules could not map
+func (s *Server) handleImportMappingPreview(w http.ResponseWriter, r *http.Request) {
+	upload, ok := s.loadImportUpload(w, r)
+	if !ok {
+		return
+	}
+	request, ok := s.decodeImportMapping(w, r)
+	if !ok {
+		return
+	}
+	result, content, ok := s.applyImportMapping(w, r, upload, request.ImportMapping)
+	if !ok {
+		return
+	}
+	validation, err := s.SchemaManager.ValidateContentDetailed(content)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Validation failed: "+err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Import preview")
+	response.SetData(map[string]interface{}{
+		"content":    content,
+		"applied":    result.Applied,
+		"errors":     result.Errors,
+		"unmapped":   result.Unmapped,
+		"validation": validation,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleImportMappingCommit applies a mapping and saves the result as the
+// content, checked like any other save and confirmed like other imports.
+// With "as_draft": true it becomes the content draft instead, which needs
+// neither. The upload is removed once saved.
+func (s *Server) handleImportMappingCommit(w http.ResponseWriter, r *http.Request) {
+	upload, ok := s.loadImportUpload(w, r)
+	if !ok {
+		return
+	}
+	request, ok := s.decodeImportMapping(w, r)
+	if !ok {
+		return
+	}
+	if !request.AsDraft && !s.requireConfirmation(w, r, "content.import", "", nil) {
+		return
+	}
+	result, content, ok := s.applyImportMapping(w, r, upload, request.ImportMapping)
+	if !ok {
+		return
+	}
+
+	if request.AsDraft {
+		contentData := &types.ContentData{}
+		if err := s.mapToContentData(content, contentData); err != nil {
+			s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Failed to process content: "+err.Error())
+			return
+		}
+		username := ""
+		if session, ok := types.SessionFromContext(r.Context()); ok {
+			username = session.Username
+		}
+		if _, err := s.ContentManager.SaveDraft(contentData, username); err != nil {
+			s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, err.Error())
+			return
+		}
+	} else {
+		validation, err := s.saveSubmittedContent(r.Context(), content)
+		if err != nil {
+			s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+			return
+		}
+		if !validation.Valid {
+			s.writeContentInvalid(w, r, validation)
+			return
+		}
+	}
+
+	if _, err := s.ImportMappings.Delete(upload.ID); err != nil {
+		s.Logger.Printf("Failed to remove import upload %s: %v", upload.ID, err)
+	}
+	message := "Content imported from " + upload.Format
+	if request.AsDraft {
+		message = "Content draft imported from " + upload.Format
+	}
+	s.logActivity(r.Context(), "Content Imported", message)
+
+	response := types.NewAPIResponse(true, message)
+	response.SetData(map[string]interface{}{
+		"applied":  result.Applied,
+		"errors":   result.Errors,
+		"unmapped": result.Unmapped,
+		"as_draft": request.AsDraft,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleImportMappingDelete drops an upload without importing it
+func (s *Server) handleImportMappingDelete(w http.ResponseWriter, r *http.Request) {
+	deleted, err := s.ImportMappings.Delete(r.PathValue("id"))
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+	if !deleted {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "Upload not found")
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Upload deleted")
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// loadImportUpload loads the upload named in the path, writing the error
+// response when it cannot
+func (s *Server) loadImportUpload(w http.ResponseWriter, r *http.Request) (*managers.ImportUpload, bool) {
+	upload, err := s.ImportMappings.Load(r.PathValue("id"))
+	if errors.Is(err, managers.ErrImportNotFound) {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "Upload not found; uploads are kept for a day")
+		return nil, false
+	}
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return nil, false
+	}
+	return upload, true
+}
+
+// decodeImportMapping reads the mapping from the request body
+func (s *Server) decodeImportMapping(w http.ResponseWriter, r *http.Request) (*importMappingRequest, bool) {
+	var request importMappingRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON in request body")
+		return nil, false
+	}
+	if len(request.Rules) == 0 {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "At least one rule is required")
+		return nil, false
+	}
+	return &request, true
+}
+
+// applyImportMapping builds the content for a mapping, converted to schema
+// types as a form save would be
+func (s *Server) applyImportMapping(w http.ResponseWriter, r *http.Request, upload *managers.ImportUpload, mapping managers.ImportMapping) (*managers.ImportResult, map[string]interface{}, bool) {
+	schema, err := s.SchemaManager.LoadSchema()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load schema: "+err.Error())
+		return nil, nil, false
+	}
+	current, err := s.ContentManager.LoadContent()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load content: "+err.Error())
+		return nil, nil, false
+	}
+	result, err := managers.ApplyImportMapping(upload.Data, mapping, managers.ImportTargets(schema), current)
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, err.Error())
+		return nil, nil, false
+	}
+
+	content := draftContentMap(result.Content)
+	if _, err := s.SchemaManager.CoerceContent(content); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to process content: "+err.Error())
+		return nil, nil, false
+	}
+	return result, content, true
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// sseKeepAlive is how often an idle event stream sends a comment so
+// proxies do not close it
+const sseKeepAlive = 15 * time.Second
+
+// jobListSpec sorts and filters GET /admin/jobs
+var jobListSpec = listSpec[types.Job]{
+	Sorts: map[string]func(a, b types.Job) int{
+		"type":       compareBy(func(j types.Job) string { return j.Type }),
+		"status":     compareBy(func(j types.Job) string { return j.Status }),
+		"created_at": func(a, b types.Job) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	},
+	DefaultSort: "-created_at",
+	Text:        func(j types.Job) []string { return []string{j.Type, j.Status, j.CreatedBy} },
+}
+
+// handleJobsList lists recent background jobs, newest first
+func (s *Server) handleJobsList(w http.ResponseWriter, r *http.Request) {
+	jobs, page, ok := paginateList(s, w, r, s.Jobs.List(), jobListSpec)
+	if !ok {
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Jobs retrieved")
+	response.SetData(map[string]interface{}{
+		"jobs": jobs,
+	})
+	response.Meta["pagination"] = page
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleJobGet returns one job with its progress and item errors
+func (s *Server) handleJobGet(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.Jobs.Get(r.PathValue("id"))
+	if !ok {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "Job not found")
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Job retrieved")
+	response.SetData(job)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleJobEvents streams a job's progress as server-sent events. Each
+// change is a "progress" event carrying the job; the stream ends with a
+// "done" event once the job has finished.
+func (s *Server) handleJobEvents(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	updates, cancel := s.Jobs.Subscribe(id)
+	defer cancel()
+
+	job, ok := s.Jobs.Get(id)
+	if !ok {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "Job not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	controller := http.NewResponseController(w)
+
+	send := func(event string, job types.Job) bool {
+		data, err := json.Marshal(job)
+		if err != nil {
+			return false
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+			return false
+		}
+		return controller.Flush() == nil
+	}
+
+	if updates == nil || job.Finished() {
+		send("done", job)
+		return
+	}
+	if !send("progress", job) {
+		return
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil || controller.Flush() != nil {
+				return
+			}
+		case update, open := <-updates:
+			if !open {
+				if final, ok := s.Jobs.Get(id); ok {
+					send("done", final)
+				}
+				return
+			}
+			if !send("progress", update) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// loginBackoffBase is how long a client waits after its second failed
+// sign-in; each further failure doubles the wait until the lockout
+const loginBackoffBase = time.Second
+
+// loginUsernameMaxWait caps the wait failures for a username impose. A
+// username is never locked out, so guessing from many addresses cannot
+// keep its owner from signing in.
+const loginUsernameMaxWait = 30 * time.Second
+
+// loginGuard slows down and then locks out guessing at /admin/login. It
+// counts failures per client address, which are locked out after
+// maxAttempts, and per username, which only back off up to
+// loginUsernameMaxWait. Rotating usernames does not get around the first,
+// and rotating addresses is still slowed down by the second.
+type loginGuard struct {
+	mu      sync.Mutex
+	entries map[string]*loginFailures
+}
+
+// loginFailures is the failed sign-ins of one key
+type loginFailures struct {
+	count   int
+	last    time.Time
+	retryAt time.Time
+	locked  bool
+}
+
+// newLoginGuard creates an empty login guard
+func newLoginGuard() *loginGuard {
+	return &loginGuard{entries: make(map[string]*loginFailures)}
+}
+
+// loginGuardKeys returns the keys a sign-in for username is counted under
+func loginGuardKeys(r *http.Request, username string) []string {
+	return []string{"ip:" + clientIP(r), "user:" + username}
+}
+
+// retryAt returns when the keys may try again, or the zero time when they
+// may now
+func (lg *loginGuard) retryAt(keys []string, now time.Time) time.Time {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	var retry time.Time
+	for _, key := range keys {
+		if entry, ok := lg.entries[key]; ok && entry.retryAt.After(now) && entry.retryAt.After(retry) {
+			retry = entry.retryAt
+		}
+	}
+	return retry
+}
+
+// fail counts a failed sign-in for each key. The second and later failures
+// impose a doubling wait; the maxAttempts-th locks an address key out for
+// lockout. Username keys wait at most loginUsernameMaxWait and are never
+// locked. Failures older than lockout are forgotten.
+func (lg *loginGuard) fail(keys []string, maxAttempts int, lockout time.Duration, now time.Time) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	lg.sweep(lockout, now)
+	for _, key := range keys {
+		entry, ok := lg.entries[key]
+		if !ok {
+			entry = &loginFailures{}
+			lg.entries[key] = entry
+		}
+		entry.count++
+		entry.last = now
+		lockable, maxWait := !strings.HasPrefix(key, "user:"), lockout
+		if !lockable {
+			maxWait = min(lockout, loginUsernameMaxWait)
+		}
+		switch {
+		case lockable && entry.count >= maxAttempts:
+			entry.locked = true
+			entry.retryAt = now.Add(lockout)
+		case entry.count > 1:
+			wait := maxWait
+			if shift := entry.count - 2; shift < 30 {
+				wait = min(loginBackoffBase<<shift, maxWait)
+			}
+			entry.retryAt = now.Add(wait)
+		}
+	}
+}
+
+// succeed forgets the failures of keys after a successful sign-in
+func (lg *loginGuard) succeed(keys []string) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	for _, key := range keys {
+		delete(lg.entries, key)
+	}
+}
+
+// sweep drops keys whose wait is over and whose last failure is older than
+// lockout; callers hold mu
+func (lg *loginGuard) sweep(lockout time.Duration, now time.Time) {
+	for key, entry := range lg.entries {
+		if !entry.retryAt.After(now) && now.Sub(entry.last) >= lockout {
+			delete(lg.entries, key)
+		}
+	}
+}
+
+// list returns the keys with recent failures, locked and latest first
+func (lg *loginGuard) list(lockout time.Duration, now time.Time) []types.LoginLockout {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	lg.sweep(lockout, now)
+	lockouts := make([]types.LoginLockout, 0, len(lg.entries))
+	for key, entry := range lg.entries {
+		lockout := types.LoginLockout{
+			Key:         key,
+			Failures:    entry.count,
+			LastFailure: entry.last,
+			Locked:      entry.locked && entry.retryAt.After(now),
+		}
+		if entry.retryAt.After(now) {
+			lockout.RetryAt = entry.retryAt
+		}
+		lockouts = append(lockouts, lockout)
+	}
+	sort.Slice(lockouts, func(i, j int) bool {
+		if lockouts[i].Locked != lockouts[j].Locked {
+			return lockouts[i].Locked
+		}
+		return lockouts[i].LastFailure.After(lockouts[j].LastFailure)
+	})
+	return lockouts
+}
+
+// clear forgets key, or every key when key is empty, and returns how many
+// were cleared
+func (lg *loginGuard) clear(key string) int {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	if key == "" {
+		cleared := len(lg.entries)
+		lg.entries = make(map[string]*loginFailures)
+		return cleared
+	}
+	if _, ok := lg.entries[key]; !ok {
+		return 0
+	}
+	delete(lg.entries, key)
+	return 1
+}
+
+// loginLockout is the configured lockout period
+func (s *Server) loginLockout() time.Duration {
+	return time.Duration(s.Config.LoginLockout) * time.Second
+}
+
+// allowLogin answers 429 with Retry-After when the client or username must
+// wait before trying again, and reports whether the sign-in may go ahead
+func (s *Server) allowLogin(w http.ResponseWriter, r *http.Request, username string) bool {
+	if s.Config.LoginMaxAttempts <= 0 {
+		return true
+	}
+	now := s.Clock()
+	retry := s.loginGuard.retryAt(loginGuardKeys(r, username), now)
+	if retry.IsZero() {
+		return true
+	}
+	seconds := max(1, int(retry.Sub(now).Seconds()+0.5))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	s.writeError(w, r, http.StatusTooManyRequests, types.ErrCodeRateLimited,
+		fmt.Sprintf("Too many failed sign-ins; try again in %d seconds", seconds))
+	return false
+}
+
+// recordLoginFailure counts a failed sign-in towards backoff and lockout
+func (s *Server) recordLoginFailure(r *http.Request, username string) {
+	if s.Config.LoginMaxAttempts <= 0 {
+		return
+	}
+	s.loginGuard.fail(loginGuardKeys(r, username), s.Config.LoginMaxAttempts, s.loginLockout(), s.Clock())
+}
+
+// recordLoginSuccess clears the failures of a client and username that
+// just signed in
+func (s *Server) recordLoginSuccess(r *http.Request, username string) {
+	s.loginGuard.succeed(loginGuardKeys(r, username))
+}
+
+// handleLoginLockouts lists client addresses and usernames with recent
+// failed sign-ins, and whether they are locked out
+func (s *Server) handleLoginLockouts(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	lockouts := s.loginGuard.list(s.loginLockout(), s.Clock())
+	response := types.NewAPIResponse(true, fmt.Sprintf("%d client(s) with failed sign-ins", len(lockouts)))
+	response.SetData(map[string]interface{}{
+		"lockouts":     lockouts,
+		"max_attempts": s.Config.LoginMaxAttempts,
+		"lockout":      s.Config.LoginLockout,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleLoginLockoutsClear lifts the lockout of one key ("ip:<address>" or
+// "user:<username>"), or of every key when none is given
+func (s *Server) handleLoginLockoutsClear(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid form data")
+		return
+	}
+	if !s.checkCSRF(w, r) {
+		return
+	}
+
+	key := strings.TrimSpace(r.FormValue("key"))
+	cleared := s.loginGuard.clear(key)
+	if key != "" && cleared == 0 {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "No failed sign-ins recorded for "+key)
+		return
+	}
+	if key == "" {
+		s.logActivity(r.Context(), "Login Lockouts", fmt.Sprintf("Cleared %d login lockout(s)", cleared))
+	} else {
+		s.logActivity(r.Context(), "Login Lockouts", "Cleared login lockout for "+key)
+	}
+
+	response := types.NewAPIResponse(true, fmt.Sprintf("Cleared %d login lockout(s)", cleared))
+	response.SetData(map[string]interface{}{
+		"cleared": cleared,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
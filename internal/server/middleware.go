@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"onepagems/internal/tracing"
+	"onepagems/internal/types"
+)
+
+// Middleware wraps a handler with behaviour shared by every route
+type Middleware func(http.Handler) http.Handler
+
+// Use adds middleware to the chain every request passes through before
+// reaching the router. Middleware runs in the order added, after the
+// built-in request ID, access log, panic recovery, tracing, rate limit,
+// compression and timeout middleware; add it before Start.
+func (s *Server) Use(middleware ...Middleware) {
+	s.middleware = append(s.middleware, middleware...)
+}
+
+// Handler returns the router wrapped in the middleware chain, the first
+// middleware added outermost
+func (s *Server) Handler() http.Handler {
+	var handler http.Handler = http.HandlerFunc(s.serveRoutes)
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		handler = s.middleware[i](handler)
+	}
+	return handler
+}
+
+// useDefaultMiddleware installs the built-in chain. The request ID comes
+// first so everything after it can log it; recovery sits inside the access
+// log so a panic is logged as the 500 it answers.
+func (s *Server) useDefaultMiddleware() {
+	s.Use(s.withRequestID, s.withAccessLog, s.withRecover, s.withTracing, s.withRateLimit, s.withCompression, s.withTimeouts)
+}
+
+// validRequestID limits which client-supplied request IDs are trusted
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// slowRoutes are admin endpoints that may legitimately run longer than a
+// normal admin request and use the generation timeout instead
+var slowRoutes = map[string]bool{
+	"/admin/api/generate":   true,
+	"/admin/import/archive": true,
+	"/admin/export/archive": true,
+}
+
+// streamedRoutes send downloads as they are produced. The timeout handler
+// would buffer the whole body, so their timeout is applied to the request
+// context and the connection's write deadline instead.
+var streamedRoutes = map[string]bool{
+	"/admin/export/archive": true,
+	"/admin/content/export": true,
+	"/admin/schema/export":  true,
+}
+
+// isEventStream reports whether a path is a server-sent event stream, which
+// stays open for as long as the client listens
+func isEventStream(path string) bool {
+	return strings.HasPrefix(path, "/admin/jobs/") && strings.HasSuffix(path, "/events")
+}
+
+// timeoutFor returns the request timeout for a path, or 0 for no timeout
+func (s *Server) timeoutFor(path string) time.Duration {
+	if isEventStream(path) {
+		return 0
+	}
+	seconds := s.Config.PublicTimeout
+	if slowRoutes[path] {
+		seconds = s.Config.GenerateTimeout
+	} else if path == "/admin" || strings.HasPrefix(path, "/admin/") {
+		seconds = s.Config.AdminTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// withTimeouts cancels each request's context once its route timeout elapses
+// and answers 503 so a hung backend cannot hold the connection indefinitely
+func (s *Server) withTimeouts(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := s.timeoutFor(r.URL.Path)
+		if timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if streamedRoutes[r.URL.Path] {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			// Not every writer supports deadlines; the context still applies
+			controller := http.NewResponseController(w)
+			if controller.SetWriteDeadline(time.Now().Add(timeout)) == nil {
+				defer controller.SetWriteDeadline(time.Time{})
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+		http.TimeoutHandler(next, timeout, "Request timed out").ServeHTTP(w, r)
+	})
+}
+
+// statusRecorder captures the status code and body size written by a
+// handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status  int
+	bytes   int64
+	written bool // the response has started
+}
+
+// WriteHeader records the status code before passing it on
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.written {
+		r.status = status
+		r.written = status >= 200
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write counts the body bytes; a body without a status is sent as 200
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if !r.written {
+		r.status = http.StatusOK
+		r.written = true
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush event streams
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// withTracing starts a server span for every request, continuing any trace
+// passed in a W3C traceparent header
+func (s *Server) withTracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !tracing.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := tracing.Extract(r.Context(), r.Header)
+		ctx, span := tracing.StartKind(ctx, r.Method+" "+r.URL.Path, tracing.KindServer)
+		defer span.End()
+
+		span.SetAttribute("http.request.method", r.Method)
+		span.SetAttribute("url.path", r.URL.Path)
+		span.SetAttribute("request.id", types.RequestIDFromContext(r.Context()))
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttribute("http.response.status_code", recorder.status)
+		if recorder.status >= 500 {
+			span.RecordError(fmt.Errorf("HTTP %d", recorder.status))
+		}
+	})
+}
+
+// withRequestID assigns every request an ID, reusing a well-formed incoming
+// X-Request-ID, and echoes it in the response header. Server errors are
+// logged with the ID so reports can be matched to log lines, unless the
+// access log already has them.
+func (s *Server) withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(types.RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+
+		w.Header().Set(types.RequestIDHeader, requestID)
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(types.RequestIDContext(r.Context(), requestID)))
+
+		// The access log already has a line for every request
+		if recorder.status >= 500 && !s.Config.AccessLog {
+			s.Logger.Printf("[%s] %s %s failed with status %d", requestID, r.Method, r.URL.Path, recorder.status)
+		}
+	})
+}
+
+// newRequestID returns a random 16 character hex ID
+func newRequestID() string {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// withAccessLog logs each request with its client, status, bytes sent and
+// latency
+func (s *Server) withAccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.Config.AccessLog {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		started := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		s.Logger.Printf("[%s] %s %s %s %d %dB %s", types.RequestIDFromContext(r.Context()), clientIP(r),
+			r.Method, r.URL.Path, recorder.status, recorder.bytes, time.Since(started).Round(time.Microsecond))
+	})
+}
+
+// withRecover turns a panicking handler into a 500 problem response and logs
+// the panic with its stack, instead of dropping the connection
+func (s *Server) withRecover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// The server's own signal to abort the response quietly
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+			s.Logger.Printf("[%s] panic serving %s %s: %v\n%s", types.RequestIDFromContext(r.Context()),
+				r.Method, r.URL.Path, recovered, debug.Stack())
+			if recorder.written {
+				// Too late for an error response; cut the reply short
+				panic(http.ErrAbortHandler)
+			}
+			types.NewProblem(http.StatusInternalServerError, types.ErrCodeInternal, "Internal server error").Write(recorder, r)
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// notifyInterval is how often queued publish notifications are checked
+const notifyInterval = time.Minute
+
+// publishSite generates the site and tells stakeholders and team chats
+// what the publish changed, or that it failed. reason describes why it was
+// published when a person did not ask.
+func (s *Server) publishSite(ctx context.Context, reason string) (*types.GenerationResult, error) {
+	by := "scheduler"
+	if session, ok := types.SessionFromContext(ctx); ok {
+		by = session.Username
+	}
+	notify := s.Notifier != nil || s.Webhooks.Enabled() || s.Webhooks.DeployHooks() > 0
+
+	// What was live before, to summarize the change
+	var oldContent map[string]interface{}
+	var oldPage string
+	if notify {
+		var err error
+		if oldContent, err = s.Generator.PublishedContent(); err != nil {
+			s.Logger.Printf("Publish notification: %v", err)
+		}
+		if oldPage, err = s.Generator.PublishedPage(); err != nil {
+			s.Logger.Printf("Publish notification: %v", err)
+		}
+	}
+
+	result, err := s.Generator.Generate(ctx)
+	if err != nil {
+		s.postWebhook(managers.WebhookEvent{
+			Type:  managers.EventGenerationFailed,
+			Title: "Site generation failed (" + by + ")",
+			Text:  err.Error(),
+		})
+		return result, err
+	}
+	backedUp := true
+	for _, problem := range result.Errors {
+		if strings.HasPrefix(problem, managers.BuildBackupFailed) {
+			backedUp = false
+			s.heartbeatFail(problem)
+			s.postWebhook(managers.WebhookEvent{
+				Type:  managers.EventBackupFailed,
+				Title: "Could not keep a backup of the published site for rollback",
+				Text:  problem,
+			})
+		}
+	}
+	if backedUp {
+		s.heartbeatSuccess(true)
+	}
+	s.afterGenerate(ctx, result)
+	if !notify {
+		return result, nil
+	}
+
+	newContent, _ := s.Generator.PublishedContent()
+	newPage, _ := s.Generator.PublishedPage()
+	event := managers.PublishEvent{
+		At:      result.GeneratedAt,
+		By:      by,
+		Reason:  reason,
+		Summary: managers.SummarizePublish(oldContent, newContent, oldPage, newPage),
+	}
+	event.Site, _ = newContent["title"].(string)
+
+	title := fmt.Sprintf("%s was published by %s", event.Site, by)
+	if reason != "" {
+		title += " (" + reason + ")"
+	}
+	published := managers.WebhookEvent{
+		Type:  managers.EventPublish,
+		Title: title,
+		Text:  strings.TrimSpace(managers.FormatPublishSummary(event.Summary)),
+		URL:   s.Config.SiteURL,
+		At:    event.At,
+	}
+	s.postWebhook(published)
+	s.triggerDeploys(published)
+
+	if s.Notifier != nil {
+		// Sending may be slow; the publish has already succeeded
+		go func() {
+			if err := s.Notifier.Published(event); err != nil {
+				s.Logger.Printf("Publish notification: %v", err)
+			}
+		}()
+	}
+	return result, nil
+}
+
+// postWebhook queues an event in the outbox for each chat and webhook
+// configured for its type; they are delivered in the background and
+// retried when they fail
+func (s *Server) postWebhook(event managers.WebhookEvent) {
+	for _, delivery := range s.Webhooks.Deliveries(event) {
+		s.enqueue(managers.OutboxWebhook, delivery.Adapter+" "+event.Type+": "+event.Title, delivery)
+	}
+}
+
+// loginAlertInterval is the least time between login alerts for one
+// client, so a password-guessing run does not flood the channel
+const loginAlertInterval = 10 * time.Minute
+
+// alertFailedLogin posts a login alert for a failed sign-in
+func (s *Server) alertFailedLogin(r *http.Request, username string) {
+	if !s.Webhooks.Enabled() {
+		return
+	}
+	client := clientIP(r)
+	now := s.Clock()
+	s.loginAlertsMu.Lock()
+	last, seen := s.loginAlerts[client]
+	if seen && now.Sub(last) < loginAlertInterval {
+		s.loginAlertsMu.Unlock()
+		return
+	}
+	s.loginAlerts[client] = now
+	s.loginAlertsMu.Unlock()
+
+	s.postWebhook(managers.WebhookEvent{
+		Type:  managers.EventLoginAlert,
+		Title: "Failed admin sign-in",
+		Text:  fmt.Sprintf("Username %q from %s. Further failures from this address are not reported for %s.", username, client, loginAlertInterval),
+		At:    now,
+	})
+}
+
+// runNotifications hands daily digests to the outbox while the server runs
+func (s *Server) runNotifications() {
+	ticker := time.NewTicker(notifyInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Notifier.SendDue(s.Clock()); err != nil {
+				s.Logger.Printf("Publish notification: %v", err)
+				s.heartbeatFail("Publish notification: " + err.Error())
+			}
+		case <-s.stopping:
+			return
+		}
+	}
+}
+
+// handleNotificationsGet shows who is told about publishes, how, and what
+// is waiting to be sent
+func (s *Server) handleNotificationsGet(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	data := map[string]interface{}{"enabled": s.Notifier != nil}
+	if s.Notifier != nil {
+		pending, err := s.Notifier.Pending()
+		if err != nil {
+			s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+			return
+		}
+		data["recipients"] = s.Notifier.Recipients()
+		data["mode"] = s.Notifier.Mode()
+		data["digest_time"] = s.Config.NotifyDigestTime
+		data["pending"] = pending
+	}
+
+	response := types.NewAPIResponse(true, "Publish notifications retrieved")
+	response.SetData(data)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleNotificationsSend hands queued notifications to the outbox now,
+// without waiting for the daily digest
+func (s *Server) handleNotificationsSend(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	if s.Notifier == nil {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "Publish notifications are not configured; set NOTIFY_EMAILS")
+		return
+	}
+
+	pending, err := s.Notifier.Pending()
+	if err == nil {
+		err = s.Notifier.Flush(s.Clock())
+	}
+	if err != nil {
+		s.writeError(w, r, http.StatusBadGateway, types.ErrCodeUpstream, fmt.Sprintf("Failed to send notifications: %v", err))
+		return
+	}
+
+	response := types.NewAPIResponse(true, fmt.Sprintf("Queued %d publish notification(s) for sending", len(pending)))
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleWebhooksGet lists the kinds of endpoint each event type is posted
+// to. URLs are left out since they often carry tokens.
+func (s *Server) handleWebhooksGet(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Webhooks retrieved")
+	response.SetData(map[string]interface{}{
+		"events":  managers.WebhookEvents,
+		"targets": s.Webhooks.Targets(),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleWebhooksTest posts a test message to the endpoints of one event
+// type (form: event) and reports whether they accepted it
+func (s *Server) handleWebhooksTest(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	event := r.FormValue("event")
+	if len(s.Webhooks.Targets()[event]) == 0 {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, fmt.Sprintf("No webhooks are configured for %q", event))
+		return
+	}
+
+	err := s.Webhooks.Dispatch(r.Context(), managers.WebhookEvent{
+		Type:  event,
+		Title: "Test message from OnePage CMS",
+		Text:  fmt.Sprintf("%s events will be posted here.", event),
+		URL:   s.Config.SiteURL,
+	})
+	if err != nil {
+		s.writeError(w, r, http.StatusBadGateway, types.ErrCodeUpstream, err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Test message sent")
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
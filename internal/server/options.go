@@ -0,0 +1,117 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"onepagems/internal/httpclient"
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// AuthProvider authenticates admin users and manages their sessions.
+// managers.AuthManager is the default implementation.
+type AuthProvider interface {
+	Login(username, password string) (*types.Session, error)
+	Logout(sessionID string) error
+	GetSessionFromRequest(r *http.Request) (*types.Session, error)
+	RequireAuth(next http.HandlerFunc) http.HandlerFunc
+	CreateSessionCookie(sessionID string) *http.Cookie
+	ClearSessionCookie() *http.Cookie
+	GetActiveSessions() int
+	ListSessions() []*types.Session
+	ChangePassword(currentPassword, newPassword string) error
+}
+
+// LoginAttemptTracker is implemented by auth providers that record rejected
+// sign-ins, as managers.AuthManager does
+type LoginAttemptTracker interface {
+	RecordFailedLogin(username, ip string)
+	FailedLogins() types.FailedLoginReport
+}
+
+// SessionStarter is implemented by auth providers that can open a session
+// for a user verified without a password, which recovery and emergency
+// logins need
+type SessionStarter interface {
+	StartSession(username string) (*types.Session, error)
+}
+
+// PasswordVerifier is implemented by auth providers that can check a
+// password without opening a session, which passkey second factor logins
+// need
+type PasswordVerifier interface {
+	VerifyPassword(username, password string) bool
+}
+
+// Reauthenticator is implemented by auth providers that can check the
+// password again for a signed-in session, unlocking it after inactivity
+// and allowing sensitive actions for a while, as managers.AuthManager does
+type Reauthenticator interface {
+	Reauthenticate(sessionID, password string) (*types.Session, error)
+}
+
+// SessionPersister is implemented by auth providers that keep sessions
+// between restarts, as managers.AuthManager does with a SessionStore. The
+// server loads them on start and flushes them periodically and on Stop.
+type SessionPersister interface {
+	LoadSessions() error
+	FlushSessions() error
+}
+
+// SessionChecker is implemented by auth providers that can tell whether a
+// session ID is valid without touching the session, as managers.AuthManager
+// does. The admin rate limit counts requests per session only for sessions
+// it confirms; with other providers it counts per client address.
+type SessionChecker interface {
+	HasSession(sessionID string) bool
+}
+
+// Option configures a Server created by NewServer
+type Option func(*Server)
+
+// WithStorage uses storage instead of a FileStorage rooted at Config.DataDir.
+// All managers are built on top of it.
+func WithStorage(storage *managers.FileStorage) Option {
+	return func(s *Server) {
+		s.Storage = storage
+	}
+}
+
+// WithAuthProvider replaces the built-in session authentication
+func WithAuthProvider(auth AuthProvider) Option {
+	return func(s *Server) {
+		s.AuthManager = auth
+	}
+}
+
+// WithLogger sends server logs to logger instead of the standard logger
+func WithLogger(logger *log.Logger) Option {
+	return func(s *Server) {
+		s.Logger = logger
+	}
+}
+
+// WithClock makes the server read the current time from clock
+func WithClock(clock func() time.Time) Option {
+	return func(s *Server) {
+		s.Clock = clock
+	}
+}
+
+// WithHTTPClients makes outbound calls through clients instead of ones
+// built from the OUTBOUND_PROXY and OUTBOUND_CA_BUNDLE settings
+func WithHTTPClients(clients *httpclient.Clients) Option {
+	return func(s *Server) {
+		s.HTTPClients = clients
+	}
+}
+
+// WithRouter registers routes on mux instead of a new ServeMux, so the
+// server can share a router with other handlers
+func WithRouter(mux *http.ServeMux) Option {
+	return func(s *Server) {
+		s.Mux = mux
+	}
+}
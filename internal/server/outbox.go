@@ -0,0 +1,223 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// outboxInterval is how often the outbox is checked for retries that are
+// due; new messages are sent right away
+const outboxInterval = 15 * time.Second
+
+// setupOutbox registers how each kind of outbound message is delivered.
+// mailer is nil when email is not configured.
+func (s *Server) setupOutbox(mailer managers.Mailer) {
+	s.Outbox.Handle(managers.OutboxWebhook, func(ctx context.Context, payload json.RawMessage) error {
+		var delivery managers.WebhookDelivery
+		if err := json.Unmarshal(payload, &delivery); err != nil {
+			return err
+		}
+		return s.Webhooks.Deliver(ctx, delivery)
+	})
+	s.Outbox.Handle(managers.OutboxDeploy, func(ctx context.Context, payload json.RawMessage) error {
+		var trigger deployTrigger
+		if err := json.Unmarshal(payload, &trigger); err != nil {
+			return err
+		}
+		return s.Webhooks.TriggerDeploy(ctx, trigger.Hook, trigger.Event)
+	})
+	if mailer != nil {
+		s.Outbox.Handle(managers.OutboxEmail, func(ctx context.Context, payload json.RawMessage) error {
+			var message managers.MailMessage
+			if err := json.Unmarshal(payload, &message); err != nil {
+				return err
+			}
+			return mailer.Send(message.To, message.Subject, message.Body)
+		})
+	}
+}
+
+// deployTrigger is a deploy hook call waiting in the outbox
+type deployTrigger struct {
+	Hook  int                   `json:"hook"`
+	Event managers.WebhookEvent `json:"event"`
+}
+
+// enqueue stores a message in the outbox and wakes the sender, logging
+// failures
+func (s *Server) enqueue(kind, summary string, payload interface{}) {
+	if _, err := s.Outbox.Enqueue(kind, summary, payload); err != nil {
+		s.Logger.Printf("Outbox: %v", err)
+		return
+	}
+	select {
+	case s.outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// triggerDeploys queues a call to every deploy hook after a publish
+func (s *Server) triggerDeploys(event managers.WebhookEvent) {
+	for i := 0; i < s.Webhooks.DeployHooks(); i++ {
+		s.enqueue(managers.OutboxDeploy, fmt.Sprintf("Deploy hook %d: %s", i+1, event.Title), deployTrigger{Hook: i, Event: event})
+	}
+}
+
+// runOutbox delivers outbound messages as they are queued and retries
+// failed ones as they come due, while the server runs
+func (s *Server) runOutbox() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.stopping
+		cancel()
+	}()
+
+	ticker := time.NewTicker(outboxInterval)
+	defer ticker.Stop()
+
+	for {
+		s.processOutbox(ctx)
+		select {
+		case <-ticker.C:
+		case <-s.outboxWake:
+		case <-s.stopping:
+			return
+		}
+	}
+}
+
+// processOutbox runs one delivery pass, logging failures
+func (s *Server) processOutbox(ctx context.Context) (delivered, failed int, err error) {
+	delivered, failed, err = s.Outbox.Process(ctx)
+	if err != nil {
+		s.Logger.Printf("Outbox: %v", err)
+	}
+	if failed > 0 {
+		s.Logger.Printf("Outbox: %d message(s) failed and will be retried; see /admin/outbox", failed)
+	}
+	return delivered, failed, err
+}
+
+// handleOutboxList lists outbound messages waiting for delivery or given up
+// on (dead letters), optionally only those with ?status=pending or dead
+func (s *Server) handleOutboxList(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	messages, err := s.Outbox.List()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status != "" && status != managers.OutboxPending && status != managers.OutboxDead {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "status must be pending or dead")
+		return
+	}
+
+	counts := map[string]int{managers.OutboxPending: 0, managers.OutboxDead: 0}
+	list := []managers.OutboxMessage{}
+	for _, message := range messages {
+		counts[message.Status]++
+		if status == "" || message.Status == status {
+			list = append(list, message)
+		}
+	}
+
+	response := types.NewAPIResponse(true, "Outbox retrieved")
+	response.SetData(list)
+	response.Meta["pending"] = counts[managers.OutboxPending]
+	response.Meta["dead"] = counts[managers.OutboxDead]
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleOutboxRetry makes a message due now, giving a dead letter a fresh
+// set of attempts, and wakes the sender
+func (s *Server) handleOutboxRetry(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	message, err := s.Outbox.Retry(r.PathValue("id"))
+	if errors.Is(err, managers.ErrOutboxNotFound) {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "Message not found; it may have been delivered")
+		return
+	}
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+	select {
+	case s.outboxWake <- struct{}{}:
+	default:
+	}
+	s.logActivity(r.Context(), "Outbox Retry", fmt.Sprintf("Retrying %s message %s", message.Kind, message.ID))
+
+	response := types.NewAPIResponse(true, "Message queued for delivery")
+	response.SetData(message)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleOutboxDiscard removes a message without delivering it
+func (s *Server) handleOutboxDiscard(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	id := r.PathValue("id")
+	err := s.Outbox.Discard(id)
+	if errors.Is(err, managers.ErrOutboxNotFound) {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "Message not found; it may have been delivered")
+		return
+	}
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+	s.logActivity(r.Context(), "Outbox Discard", "Discarded message "+id)
+
+	response := types.NewAPIResponse(true, "Message discarded")
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleOutboxProcess runs a delivery pass now as a job, without waiting
+// for retries to come due
+func (s *Server) handleOutboxProcess(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	session, _ := types.SessionFromContext(r.Context())
+
+	job := s.Jobs.Start("outbox", session.Username, 1, func(ctx context.Context, progress *managers.JobProgress) (interface{}, error) {
+		messages, err := s.Outbox.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, message := range messages {
+			if message.Status == managers.OutboxPending {
+				if _, err := s.Outbox.Retry(message.ID); err != nil && !errors.Is(err, managers.ErrOutboxNotFound) {
+					return nil, err
+				}
+			}
+		}
+		progress.Step("Delivering", 0)
+		delivered, failed, err := s.processOutbox(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]int{"delivered": delivered, "failed": failed}, nil
+	})
+	s.writeJobAccepted(w, r, job, "Outbox delivery started")
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"onepagems/internal/types"
+)
+
+// Page sizes of list endpoints
+const (
+	defaultPerPage = 50
+	maxPerPage     = 200
+)
+
+// listSpec describes how a list endpoint sorts and filters its items
+type listSpec[T any] struct {
+	// Sorts maps each sortable field to an ascending comparison
+	Sorts map[string]func(a, b T) int
+
+	// DefaultSort is used when the request has no sort, e.g. "-created_at"
+	DefaultSort string
+
+	// Text returns the strings ?filter= is matched against
+	Text func(item T) []string
+}
+
+// listPage is the pagination metadata of a list response
+type listPage struct {
+	Page       int    `json:"page"`
+	PerPage    int    `json:"per_page"`
+	Total      int    `json:"total"`
+	TotalPages int    `json:"total_pages"`
+	Sort       string `json:"sort"`
+	Filter     string `json:"filter,omitempty"`
+}
+
+// paginateList applies ?page=&per_page=&sort=&filter= to items. sort names
+// a field, descending with a "-" prefix; filter keeps items containing the
+// text, ignoring case. It sets the Link and X-Total-Count headers and
+// returns the page with its metadata. Invalid parameters get a 400 and
+// ok is false.
+func paginateList[T any](s *Server, w http.ResponseWriter, r *http.Request, items []T, spec listSpec[T]) ([]T, *listPage, bool) {
+	query := r.URL.Query()
+	page, perPage := 1, defaultPerPage
+	for name, target := range map[string]*int{"page": &page, "per_page": &perPage} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 {
+			s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, fmt.Sprintf("%s must be a positive integer", name))
+			return nil, nil, false
+		}
+		*target = value
+	}
+	perPage = min(perPage, maxPerPage)
+
+	sortParam := query.Get("sort")
+	if sortParam == "" {
+		sortParam = spec.DefaultSort
+	}
+	field, desc := strings.CutPrefix(sortParam, "-")
+	compare, known := spec.Sorts[field]
+	if !known {
+		fields := make([]string, 0, len(spec.Sorts))
+		for name := range spec.Sorts {
+			fields = append(fields, name)
+		}
+		slices.Sort(fields)
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest,
+			fmt.Sprintf("Cannot sort by %q; use one of %s, with a - prefix for descending", field, strings.Join(fields, ", ")))
+		return nil, nil, false
+	}
+
+	filter := strings.TrimSpace(query.Get("filter"))
+	matched := items
+	if filter != "" && spec.Text != nil {
+		needle := strings.ToLower(filter)
+		matched = make([]T, 0, len(items))
+		for _, item := range items {
+			for _, text := range spec.Text(item) {
+				if strings.Contains(strings.ToLower(text), needle) {
+					matched = append(matched, item)
+					break
+				}
+			}
+		}
+	} else {
+		matched = slices.Clone(items)
+	}
+
+	slices.SortStableFunc(matched, func(a, b T) int {
+		if desc {
+			return compare(b, a)
+		}
+		return compare(a, b)
+	})
+
+	meta := &listPage{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      len(matched),
+		TotalPages: max(1, (len(matched)+perPage-1)/perPage),
+		Sort:       sortParam,
+		Filter:     filter,
+	}
+	setListHeaders(w, r, meta)
+
+	start := min((page-1)*perPage, len(matched))
+	end := min(start+perPage, len(matched))
+	return matched[start:end], meta, true
+}
+
+// setListHeaders sets X-Total-Count and an RFC 8288 Link header pointing at
+// the first, previous, next and last pages
+func setListHeaders(w http.ResponseWriter, r *http.Request, meta *listPage) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(meta.Total))
+
+	link := func(page int, rel string) string {
+		query := r.URL.Query()
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(meta.PerPage))
+		return fmt.Sprintf("<%s?%s>; rel=%q", r.URL.Path, query.Encode(), rel)
+	}
+	links := []string{link(1, "first")}
+	if meta.Page > 1 {
+		links = append(links, link(min(meta.Page-1, meta.TotalPages), "prev"))
+	}
+	if meta.Page < meta.TotalPages {
+		links = append(links, link(meta.Page+1, "next"))
+	}
+	links = append(links, link(meta.TotalPages, "last"))
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+// compareBy returns an ascending comparison of a field of T
+func compareBy[T any, K cmp.Ordered](key func(T) K) func(a, b T) int {
+	return func(a, b T) int {
+		return cmp.Compare(key(a), key(b))
+	}
+}
@@ -0,0 +1,352 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// maxPasskeyBody bounds a passkey request body
+const maxPasskeyBody = 64 * 1024
+
+// passkeyRelyingPartyName is shown by authenticators when registering
+const passkeyRelyingPartyName = "OnePage CMS"
+
+// passkeyRelyingParty returns the relying party passkeys are checked
+// against: PASSKEY_ORIGIN, else the origin of SITE_URL, else the origin
+// the request was made to
+func (s *Server) passkeyRelyingParty(r *http.Request) (managers.PasskeyRelyingParty, error) {
+	origin := s.Config.PasskeyOrigin
+	if origin == "" {
+		origin = s.Config.SiteURL
+	}
+	if origin == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		origin = scheme + "://" + r.Host
+	}
+	return managers.NewPasskeyRelyingParty(origin, passkeyRelyingPartyName)
+}
+
+// passkeysEnabled answers 404 when passkey sign-in is turned off or the
+// auth provider cannot open sessions for it, and reports whether passkeys
+// may be used
+func (s *Server) passkeysEnabled(w http.ResponseWriter, r *http.Request) (managers.PasskeyRelyingParty, bool) {
+	if s.Config.PasskeyLogin == managers.PasskeyLoginOff {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "Passkeys are turned off")
+		return managers.PasskeyRelyingParty{}, false
+	}
+	if _, ok := s.AuthManager.(SessionStarter); !ok {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "The configured authentication does not support passkeys")
+		return managers.PasskeyRelyingParty{}, false
+	}
+	rp, err := s.passkeyRelyingParty(r)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return managers.PasskeyRelyingParty{}, false
+	}
+	return rp, true
+}
+
+// passkeySecondFactor reports whether a password sign-in for username must
+// be confirmed with a passkey
+func (s *Server) passkeySecondFactor(username string) bool {
+	if s.Config.PasskeyLogin != managers.PasskeyLoginSecondFactor {
+		return false
+	}
+	if _, ok := s.AuthManager.(PasswordVerifier); !ok {
+		return false
+	}
+	if _, ok := s.AuthManager.(SessionStarter); !ok {
+		return false
+	}
+	count, err := s.Passkeys.Count(username)
+	if err != nil {
+		// Failing open would skip the second factor, so the user has to
+		// use the passkey route, which reports the error
+		s.Logger.Printf("Failed to read passkeys: %v", err)
+		return true
+	}
+	return count > 0
+}
+
+// loginWithSecondFactor checks the password without signing in and
+// answers with the options for confirming the sign-in with a passkey
+func (s *Server) loginWithSecondFactor(w http.ResponseWriter, r *http.Request, username, password string) {
+	verifier := s.AuthManager.(PasswordVerifier)
+	if !verifier.VerifyPassword(username, password) {
+		s.rejectLogin(w, r, username)
+		return
+	}
+	s.requirePasskey(w, r, username, "Password accepted; confirm the sign-in with a passkey")
+}
+
+// requirePasskey answers a sign-in whose first factor passed with the
+// options for confirming it with a passkey
+func (s *Server) requirePasskey(w http.ResponseWriter, r *http.Request, username, message string) {
+	rp, err := s.passkeyRelyingParty(r)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+	options, err := s.Passkeys.BeginLogin(rp, username, true, s.Clock())
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, message)
+	response.SetData(map[string]interface{}{
+		"passkey_required": true,
+		"passkey_options":  options,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handlePasskeyLoginBegin starts a passwordless sign-in and returns the
+// options for navigator.credentials.get
+func (s *Server) handlePasskeyLoginBegin(w http.ResponseWriter, r *http.Request) {
+	rp, ok := s.passkeysEnabled(w, r)
+	if !ok {
+		return
+	}
+	if s.Config.PasskeyLogin != managers.PasskeyLoginPasswordless {
+		s.writeError(w, r, http.StatusForbidden, types.ErrCodeForbidden, "Sign in with the password first; the passkey confirms it")
+		return
+	}
+	if !s.allowLogin(w, r, s.Config.AdminUsername) {
+		return
+	}
+
+	options, err := s.Passkeys.BeginLogin(rp, "", false, s.Clock())
+	if errors.Is(err, managers.ErrPasskeyNotFound) {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "No passkeys are registered")
+		return
+	}
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Passkey sign-in started")
+	response.SetData(map[string]interface{}{
+		"passkey_options": options,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handlePasskeyLoginFinish signs in with the passkey assertion for a login
+// started by handlePasskeyLoginBegin or a second factor password sign-in
+func (s *Server) handlePasskeyLoginFinish(w http.ResponseWriter, r *http.Request) {
+	rp, ok := s.passkeysEnabled(w, r)
+	if !ok {
+		return
+	}
+	if !s.allowLogin(w, r, s.Config.AdminUsername) {
+		return
+	}
+
+	var credential types.PasskeyCredential
+	r.Body = http.MaxBytesReader(w, r.Body, maxPasskeyBody)
+	if err := json.NewDecoder(r.Body).Decode(&credential); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid passkey credential")
+		return
+	}
+
+	login, err := s.Passkeys.FinishLogin(rp, credential, s.Clock())
+	if err == nil && login.Passkey.Username != s.Config.AdminUsername {
+		err = fmt.Errorf("passkey belongs to former user '%s'", login.Passkey.Username)
+	}
+	if err == nil && s.Config.PasskeyLogin == managers.PasskeyLoginSecondFactor && !login.SecondFactor {
+		err = errors.New("passwordless sign-in is turned off")
+	}
+	if err != nil {
+		if errors.Is(err, managers.ErrPasskeyInvalid) || errors.Is(err, managers.ErrPasskeyNotFound) || errors.Is(err, managers.ErrPasskeyChallenge) || login != nil {
+			s.Logger.Printf("Passkey sign-in refused: %v", err)
+			s.rejectLogin(w, r, s.Config.AdminUsername)
+			return
+		}
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+
+	session, err := s.AuthManager.(SessionStarter).StartSession(login.Passkey.Username)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to create session: %v", err))
+		return
+	}
+	http.SetCookie(w, s.AuthManager.CreateSessionCookie(session.ID))
+	s.recordLoginSuccess(r, session.Username)
+	s.logActivityAs(r.Context(), session.Username, "Login", fmt.Sprintf("Signed in with passkey '%s' from %s", login.Passkey.Name, clientIP(r)))
+
+	response := types.NewAPIResponse(true, "Login successful")
+	response.SetData(map[string]interface{}{
+		"session_id": session.ID,
+		"expires_at": session.ExpiresAt,
+		"passkey":    login.Passkey,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handlePasskeysList lists the signed-in user's passkeys
+func (s *Server) handlePasskeysList(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	session, _ := types.SessionFromContext(r.Context())
+
+	passkeys, err := s.Passkeys.List(session.Username)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, fmt.Sprintf("%d passkey(s)", len(passkeys)))
+	response.SetData(map[string]interface{}{
+		"passkeys":      passkeys,
+		"passkey_login": s.Config.PasskeyLogin,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handlePasskeyRegisterBegin starts registering a passkey for the
+// signed-in user and returns the options for navigator.credentials.create
+func (s *Server) handlePasskeyRegisterBegin(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	if !s.requireRecentAuth(w, r) {
+		return
+	}
+	if !s.checkCSRF(w, r) {
+		return
+	}
+	rp, ok := s.passkeysEnabled(w, r)
+	if !ok {
+		return
+	}
+	session, _ := types.SessionFromContext(r.Context())
+
+	options, err := s.Passkeys.BeginRegistration(rp, session.Username, s.Clock())
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Passkey registration started")
+	response.SetData(map[string]interface{}{
+		"passkey_options": options,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// passkeyRegistration is the body of a registration's finish request
+type passkeyRegistration struct {
+	Name       string                  `json:"name"`
+	Credential types.PasskeyCredential `json:"credential"`
+}
+
+// handlePasskeyRegisterFinish stores the passkey created for a
+// registration started by the signed-in user
+func (s *Server) handlePasskeyRegisterFinish(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	if !s.checkCSRF(w, r) {
+		return
+	}
+	rp, ok := s.passkeysEnabled(w, r)
+	if !ok {
+		return
+	}
+	session, _ := types.SessionFromContext(r.Context())
+
+	var registration passkeyRegistration
+	r.Body = http.MaxBytesReader(w, r.Body, maxPasskeyBody)
+	if err := json.NewDecoder(r.Body).Decode(&registration); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid passkey registration")
+		return
+	}
+
+	passkey, err := s.Passkeys.FinishRegistration(rp, session.Username, registration.Name, registration.Credential, s.Clock())
+	if errors.Is(err, managers.ErrPasskeyInvalid) || errors.Is(err, managers.ErrPasskeyChallenge) {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeValidationFailed, err.Error())
+		return
+	}
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+	s.logActivity(r.Context(), "Passkey Added", fmt.Sprintf("Passkey '%s' registered", passkey.Name))
+	s.postWebhook(managers.WebhookEvent{
+		Type:  managers.EventLoginAlert,
+		Title: "Passkey added to the admin account",
+		Text:  fmt.Sprintf("Passkey '%s' was registered from %s. If this wasn't you, remove it and change the password.", passkey.Name, clientIP(r)),
+		At:    s.Clock(),
+	})
+
+	response := types.NewAPIResponse(true, "Passkey registered")
+	response.SetData(map[string]interface{}{
+		"passkey": passkey,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handlePasskeyDelete removes one of the signed-in user's passkeys
+func (s *Server) handlePasskeyDelete(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	if !s.requireRecentAuth(w, r) {
+		return
+	}
+	if !s.checkCSRF(w, r) {
+		return
+	}
+	session, _ := types.SessionFromContext(r.Context())
+
+	id := r.PathValue("id")
+	passkeys, err := s.Passkeys.List(session.Username)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+	owned := false
+	for _, p := range passkeys {
+		owned = owned || p.ID == id
+	}
+	if !owned {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "Passkey not found")
+		return
+	}
+
+	passkey, err := s.Passkeys.Delete(id)
+	if errors.Is(err, managers.ErrPasskeyNotFound) {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "Passkey not found")
+		return
+	}
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+	s.logActivity(r.Context(), "Passkey Removed", fmt.Sprintf("Passkey '%s' removed", passkey.Name))
+
+	response := types.NewAPIResponse(true, "Passkey removed")
+	response.SetData(map[string]interface{}{
+		"passkey": passkey,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
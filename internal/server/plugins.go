@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// pluginHookTimeout bounds the hooks run in the background after a
+// generation or an upload
+const pluginHookTimeout = time.Minute
+
+// PluginRoute is an admin route added by a plugin. Path is relative to
+// /admin/plugins/<name>, e.g. "/report" or "/items/{id}".
+type PluginRoute struct {
+	Method      string
+	Path        string
+	Description string
+	Handler     http.HandlerFunc
+}
+
+// RouteProvider is implemented by plugins that add admin routes. The routes
+// are mounted under /admin/plugins/<name> and require an admin session.
+type RouteProvider interface {
+	Routes() []PluginRoute
+}
+
+// WithPlugin registers a plugin; see managers.Plugin for the hooks it may
+// implement
+func WithPlugin(plugin managers.Plugin) Option {
+	return func(s *Server) {
+		s.Plugins.Register(plugin)
+	}
+}
+
+// setupPluginRoutes mounts the routes of plugins implementing RouteProvider
+func (s *Server) setupPluginRoutes() {
+	for _, plugin := range s.Plugins.Plugins() {
+		provider, ok := plugin.(RouteProvider)
+		if !ok {
+			continue
+		}
+		for _, route := range provider.Routes() {
+			path := "/admin/plugins/" + plugin.Name() + "/" + strings.TrimPrefix(route.Path, "/")
+			handler := route.Handler
+			s.Mux.HandleFunc(route.Method+" "+path, s.AuthManager.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+				if !s.requireAdmin(w, r) {
+					return
+				}
+				handler(w, r)
+			}))
+			s.Logger.Printf("  %s %s - %s (plugin %s)", route.Method, path, route.Description, plugin.Name())
+		}
+	}
+}
+
+// pluginInfo describes a registered plugin for GET /admin/plugins
+type pluginInfo struct {
+	Name   string   `json:"name"`
+	Hooks  []string `json:"hooks"`
+	Routes []string `json:"routes"`
+}
+
+// handlePluginsList lists the registered plugins with their hooks and routes
+func (s *Server) handlePluginsList(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	plugins := make([]pluginInfo, 0, len(s.Plugins.Plugins()))
+	for _, plugin := range s.Plugins.Plugins() {
+		info := pluginInfo{Name: plugin.Name(), Hooks: managers.PluginHooks(plugin), Routes: []string{}}
+		if provider, ok := plugin.(RouteProvider); ok {
+			for _, route := range provider.Routes() {
+				info.Routes = append(info.Routes, route.Method+" /admin/plugins/"+plugin.Name()+"/"+strings.TrimPrefix(route.Path, "/"))
+			}
+		}
+		plugins = append(plugins, info)
+	}
+
+	response := types.NewAPIResponse(true, "Plugins retrieved")
+	response.SetData(map[string]interface{}{
+		"plugins": plugins,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// afterGenerate runs the generate hooks in the background, logging failures
+func (s *Server) afterGenerate(ctx context.Context, result *types.GenerationResult) {
+	if !s.Plugins.HasHook(managers.PluginEventGenerate) {
+		return
+	}
+	s.runInBackground(func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), pluginHookTimeout)
+		defer cancel()
+		if err := s.Plugins.Generated(ctx, result); err != nil {
+			s.Logger.Printf("Generate hook: %v", err)
+		}
+	})
+}
+
+// afterUpload runs the upload hooks in the background, logging failures
+func (s *Server) afterUpload(ctx context.Context, image *types.ImageInfo) {
+	if !s.Plugins.HasHook(managers.PluginEventUpload) {
+		return
+	}
+	s.runInBackground(func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), pluginHookTimeout)
+		defer cancel()
+		if err := s.Plugins.Uploaded(ctx, image); err != nil {
+			s.Logger.Printf("Upload hook: %v", err)
+		}
+	})
+}
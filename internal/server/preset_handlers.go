@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// handleSchemaPresets lists the built-in section presets
+func (s *Server) handleSchemaPresets(w http.ResponseWriter, r *http.Request) {
+	presets := managers.SectionPresets()
+
+	response := types.NewAPIResponse(true, "Section presets retrieved")
+	response.SetData(map[string]interface{}{
+		"presets": presets,
+		"count":   len(presets),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleSchemaPresetAdd adds a section preset to the schema under
+// sections.<key>. The key defaults to the preset name.
+func (s *Server) handleSchemaPresetAdd(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		Preset string `json:"preset"`
+		Key    string `json:"key"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		response := types.NewAPIResponse(false, "Invalid JSON in request body: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	if _, ok := managers.GetSectionPreset(requestData.Preset); !ok {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "Unknown section preset: "+requestData.Preset)
+		return
+	}
+
+	if err := s.SchemaManager.AddSectionPreset(requestData.Preset, requestData.Key); err != nil {
+		response := types.NewAPIResponse(false, "Failed to add section preset: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	key := requestData.Key
+	if key == "" {
+		key = requestData.Preset
+	}
+	s.logActivity(r.Context(), "Schema Updated", "Added "+requestData.Preset+" section as sections."+key)
+
+	response := types.NewAPIResponse(true, "Section preset added")
+	response.SetData(map[string]interface{}{
+		"preset": requestData.Preset,
+		"key":    key,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
@@ -1,22 +1,47 @@
 package server
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"mime"
 	"net/http"
 	"os"
+	"path"
+	"strings"
+	"time"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
 )
 
 // handlePublicPage serves the main public page
 func (s *Server) handlePublicPage(w http.ResponseWriter, r *http.Request) {
-	// For now, serve a simple placeholder
-	if r.URL.Path != "/" {
-		http.NotFound(w, r)
+	if s.Live != nil {
+		s.serveLivePage(w, r)
 		return
 	}
 
-	// Check if index.html exists
-	indexPath := "index.html"
+	// Serve the generated site if it exists
+	indexPath := s.Generator.OutputFile("index.html")
 	if _, err := os.Stat(indexPath); err == nil {
+		policy := s.Generator.ContentSecurityPolicy()
+		if s.Generator.HasVariantPage() {
+			variant := s.pageVariant(w, r)
+			s.Variants.RecordExposure(variant)
+			if variant == managers.VariantB {
+				indexPath = s.Generator.OutputFile(managers.VariantPageFilename)
+				policy = s.Generator.VariantContentSecurityPolicy()
+			}
+			// Each visitor keeps seeing the page their cookie picked
+			w.Header().Set("Vary", "Cookie")
+			w.Header().Set("Cache-Control", "private, no-cache")
+		}
+		if policy != "" {
+			w.Header().Set("Content-Security-Policy", policy)
+		}
 		http.ServeFile(w, r, indexPath)
 		return
 	}
@@ -39,6 +64,136 @@ func (s *Server) handlePublicPage(w http.ResponseWriter, r *http.Request) {
 </html>`)
 }
 
+// serveLivePage renders the page from the current content, for live mode.
+// Visitors get the same page and policy a generation would have written.
+func (s *Server) serveLivePage(w http.ResponseWriter, r *http.Request) {
+	page, variant, err := s.Live.Pages(r.Context())
+	if err != nil {
+		s.Logger.Printf("Live render failed: %v", err)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to render page")
+		return
+	}
+	if variant != nil {
+		picked := s.pageVariant(w, r)
+		s.Variants.RecordExposure(picked)
+		if picked == managers.VariantB {
+			page = variant
+		}
+		w.Header().Set("Vary", "Cookie")
+		w.Header().Set("Cache-Control", "private, no-cache")
+	} else {
+		// Edits show up on the next request, so caches must revalidate
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	if page.Policy != "" {
+		w.Header().Set("Content-Security-Policy", page.Policy)
+	}
+	sum := sha256.Sum256([]byte(page.HTML))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	http.ServeContent(w, r, "index.html", time.Time{}, strings.NewReader(page.HTML))
+}
+
+// handleLiveAsset serves the external assets of the live render, falling
+// back to the assets written by the last generation
+func (s *Server) handleLiveAsset(files http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Live != nil {
+			if body, ok := s.Live.Asset(r.URL.Path); ok {
+				w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(r.URL.Path)))
+				// Asset names carry a content hash, so they never change
+				w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+				http.ServeContent(w, r, r.URL.Path, time.Time{}, strings.NewReader(body))
+				return
+			}
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// handlePublicContent serves the content published by the last generation,
+// without private fields. In live mode it serves the current content.
+func (s *Server) handlePublicContent(w http.ResponseWriter, r *http.Request) {
+	if s.Live == nil {
+		s.servePublishedJSON(w, r, managers.PublicContentFilename)
+		return
+	}
+	public, renderedAt, err := s.Live.PublicContent(r.Context())
+	if err != nil {
+		s.Logger.Printf("Live render failed: %v", err)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to prepare content")
+		return
+	}
+	data, err := json.MarshalIndent(public, "", "  ")
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to encode content")
+		return
+	}
+	s.servePublishedData(w, r, managers.PublicContentFilename, "application/json", data, renderedAt)
+}
+
+// handleSearchIndex serves the section search index written by the last
+// generation, for in-page search in themes
+func (s *Server) handleSearchIndex(w http.ResponseWriter, r *http.Request) {
+	s.servePublishedJSON(w, r, managers.SearchIndexFilename)
+}
+
+// handleVCard serves the contact card written by the last generation
+func (s *Server) handleVCard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Disposition", "attachment; filename="+managers.VCardFilename)
+	s.servePublishedFile(w, r, managers.VCardFilename, "text/vcard; charset=utf-8")
+}
+
+// handleCalendar serves the iCalendar feed written by the last generation
+func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	s.servePublishedFile(w, r, managers.CalendarFilename, "text/calendar; charset=utf-8")
+}
+
+// handleSecurityTxt serves the security.txt written by the last generation
+func (s *Server) handleSecurityTxt(w http.ResponseWriter, r *http.Request) {
+	s.servePublishedFile(w, r, managers.SecurityTxtFilename, "text/plain; charset=utf-8")
+}
+
+// handleHumansTxt serves the humans.txt written by the last generation
+func (s *Server) handleHumansTxt(w http.ResponseWriter, r *http.Request) {
+	s.servePublishedFile(w, r, managers.HumansTxtFilename, "text/plain; charset=utf-8")
+}
+
+// servePublishedJSON serves a JSON file from the output directory
+func (s *Server) servePublishedJSON(w http.ResponseWriter, r *http.Request, filename string) {
+	s.servePublishedFile(w, r, filename, "application/json")
+}
+
+// servePublishedFile serves a generated file from the output directory.
+// Responses carry an ETag and Last-Modified so clients can revalidate
+// cheaply, and any origin may read them.
+func (s *Server) servePublishedFile(w http.ResponseWriter, r *http.Request, filename, contentType string) {
+	filePath := s.Generator.OutputFile(filename)
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "Content has not been published yet")
+		return
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "Content has not been published yet")
+		return
+	}
+
+	s.servePublishedData(w, r, filename, contentType, data, info.ModTime())
+}
+
+// servePublishedData serves published data with the headers of
+// servePublishedFile
+func (s *Server) servePublishedData(w http.ResponseWriter, r *http.Request, filename, contentType string, data []byte, modTime time.Time) {
+	sum := sha256.Sum256(data)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	http.ServeContent(w, r, filename, modTime, bytes.NewReader(data))
+}
+
 // handleHealth returns health status
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
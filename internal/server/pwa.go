@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"onepagems/internal/types"
+)
+
+// adminServiceWorkerFile is the admin service worker in the templates
+// directory
+const adminServiceWorkerFile = "admin_sw.js"
+
+// adminIconSVG is the admin panel's app icon
+const adminIconSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 512 512">` +
+	`<rect width="512" height="512" rx="96" fill="#667eea"/>` +
+	`<rect x="128" y="112" width="256" height="288" rx="24" fill="#fff"/>` +
+	`<rect x="168" y="168" width="176" height="24" rx="12" fill="#667eea"/>` +
+	`<rect x="168" y="224" width="176" height="16" rx="8" fill="#c3cbf5"/>` +
+	`<rect x="168" y="264" width="176" height="16" rx="8" fill="#c3cbf5"/>` +
+	`<rect x="168" y="304" width="112" height="16" rx="8" fill="#c3cbf5"/></svg>`
+
+// handleAdminManifest serves the web app manifest that lets browsers
+// install the admin panel as an app
+func (s *Server) handleAdminManifest(w http.ResponseWriter, r *http.Request) {
+	manifest := map[string]interface{}{
+		"name":             "OnePage CMS Admin",
+		"short_name":       "OnePage CMS",
+		"description":      "Edit the site's content, images, schema and template",
+		"start_url":        "/admin",
+		"scope":            "/admin",
+		"display":          "standalone",
+		"background_color": "#f5f7fa",
+		"theme_color":      "#667eea",
+		"icons": []map[string]string{
+			{"src": "/admin/icon.svg", "sizes": "any", "type": "image/svg+xml", "purpose": "any maskable"},
+		},
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Type", "application/manifest+json")
+	json.NewEncoder(w).Encode(manifest)
+}
+
+// handleAdminIcon serves the icon named in the manifest
+func (s *Server) handleAdminIcon(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Write([]byte(adminIconSVG))
+}
+
+// handleAdminServiceWorker serves the service worker that keeps the admin
+// shell and the last content read available offline. It is served from
+// /admin/sw.js but controls /admin too, which Service-Worker-Allowed
+// permits. Like the manifest and icon it is public: it holds no site data.
+func (s *Server) handleAdminServiceWorker(w http.ResponseWriter, r *http.Request) {
+	script, err := os.ReadFile(filepath.Join("templates", adminServiceWorkerFile))
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load service worker")
+		return
+	}
+	// Browsers check for a new worker on every visit
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Service-Worker-Allowed", "/admin")
+	w.Write(script)
+}
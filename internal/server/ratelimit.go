@@ -0,0 +1,163 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// rateLimitWindow is the period admin API requests are counted over
+const rateLimitWindow = time.Minute
+
+// maxRateLimitClients caps how many clients are counted at once; past it
+// the client whose window started first is forgotten
+const maxRateLimitClients = 10000
+
+// rateLimiter counts requests per client in fixed windows
+type rateLimiter struct {
+	mu        sync.Mutex
+	windows   map[string]*rateWindow
+	lastSweep time.Time
+}
+
+// rateWindow is one client's count in the current window
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// newRateLimiter creates an empty rate limiter
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{windows: make(map[string]*rateWindow)}
+}
+
+// allow counts a request for key and reports whether it is within limit,
+// with the requests left and when the window resets
+func (rl *rateLimiter) allow(key string, limit int, now time.Time) (bool, int, time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	// Forget clients whose window has passed so unused keys do not pile up
+	window, ok := rl.windows[key]
+	if now.Sub(rl.lastSweep) >= rateLimitWindow || !ok && len(rl.windows) >= maxRateLimitClients {
+		rl.sweep(now)
+	}
+	if !ok && len(rl.windows) >= maxRateLimitClients {
+		rl.evictOldest()
+	}
+
+	if !ok || now.Sub(window.start) >= rateLimitWindow {
+		window = &rateWindow{start: now}
+		rl.windows[key] = window
+	}
+	reset := window.start.Add(rateLimitWindow)
+	if window.count >= limit {
+		return false, 0, reset
+	}
+	window.count++
+	return true, limit - window.count, reset
+}
+
+// sweep forgets the clients whose window has passed; callers hold mu
+func (rl *rateLimiter) sweep(now time.Time) {
+	for k, window := range rl.windows {
+		if now.Sub(window.start) >= rateLimitWindow {
+			delete(rl.windows, k)
+		}
+	}
+	rl.lastSweep = now
+}
+
+// evictOldest forgets the client whose window started first; callers hold
+// mu
+func (rl *rateLimiter) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for k, window := range rl.windows {
+		if oldestKey == "" || window.start.Before(oldest) {
+			oldestKey, oldest = k, window.start
+		}
+	}
+	delete(rl.windows, oldestKey)
+}
+
+// rateLimitKey returns the client key and per-minute limit of an admin
+// request. Browser sessions (cookie) and bearer tokens (automation) have
+// separate limits, counted per session once the auth provider confirms it.
+// A credential it does not know is counted against the client address at
+// the token limit, so made-up values neither escape the limit nor add
+// keys. Requests without credentials are not limited here since
+// authentication rejects them.
+func (s *Server) rateLimitKey(r *http.Request) (string, int) {
+	credential, limit := "", s.Config.RateLimitToken
+	if cookie, err := r.Cookie("session_id"); err == nil && cookie.Value != "" {
+		credential, limit = cookie.Value, s.Config.RateLimitSession
+	} else {
+		credential = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	if credential == "" {
+		return "", 0
+	}
+
+	checker, ok := s.AuthManager.(SessionChecker)
+	if !ok {
+		return "client:" + rateLimitClient(r), limit
+	}
+	if checker.HasSession(credential) {
+		return "session:" + managers.SessionKey(credential), limit
+	}
+	return "unknown:" + rateLimitClient(r), s.Config.RateLimitToken
+}
+
+// rateLimitClient is the address requests are counted against when the
+// session is not known. IPv6 clients count by their /64, since one host
+// usually has all of it.
+func rateLimitClient(r *http.Request) string {
+	ip := clientIP(r)
+	if addr, err := netip.ParseAddr(ip); err == nil && addr.Is6() && !addr.Is4In6() {
+		if prefix, err := addr.Prefix(64); err == nil {
+			return prefix.String()
+		}
+	}
+	return ip
+}
+
+// withRateLimit limits admin API requests per session and per token, or
+// per client address for credentials the auth provider does not confirm,
+// answering 429 once a client's limit for the minute is used up. Responses
+// carry RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers.
+func (s *Server) withRateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/admin/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key, limit := s.rateLimitKey(r)
+		if key == "" || limit <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, remaining, reset := s.rateLimits.allow(key, limit, s.Clock())
+		resetSeconds := strconv.Itoa(max(1, int(reset.Sub(s.Clock()).Seconds()+0.5)))
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit, int(rateLimitWindow.Seconds())))
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("RateLimit-Reset", resetSeconds)
+
+		if !allowed {
+			w.Header().Set("Retry-After", resetSeconds)
+			types.NewProblem(http.StatusTooManyRequests, types.ErrCodeRateLimited,
+				fmt.Sprintf("Rate limit of %d requests per minute exceeded", limit)).Write(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// reauthURL is where the password is entered again, to unlock an idle
+// session or to allow sensitive actions
+const reauthURL = "/admin/auth/reauth"
+
+// requireRecentAuth writes a 403 and returns false unless the password was
+// entered within the last REAUTH_WINDOW minutes. It guards credential
+// changes and restores, so a tab left signed in on a shared machine cannot
+// be used for them. It passes every request when REAUTH_WINDOW is 0 or the
+// auth provider cannot check the password again.
+func (s *Server) requireRecentAuth(w http.ResponseWriter, r *http.Request) bool {
+	if s.Config.ReauthWindow <= 0 {
+		return true
+	}
+	if _, ok := s.AuthManager.(Reauthenticator); !ok {
+		return true
+	}
+	session, ok := types.SessionFromContext(r.Context())
+	if !ok {
+		s.writeError(w, r, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Authentication required")
+		return false
+	}
+
+	window := time.Duration(s.Config.ReauthWindow) * time.Minute
+	if s.Clock().Sub(session.AuthenticatedAt) <= window {
+		return true
+	}
+	problem := types.NewProblem(http.StatusForbidden, types.ErrCodeReauthRequired, "Enter the password again at POST "+reauthURL+" to continue")
+	problem.Data = map[string]interface{}{
+		"reauth_url":       reauthURL,
+		"authenticated_at": session.AuthenticatedAt,
+	}
+	problem.Write(w, r)
+	return false
+}
+
+// handleReauth checks the password for the current session, unlocking it
+// if it was locked after inactivity and allowing sensitive actions for
+// REAUTH_WINDOW minutes. It is not behind RequireAuth, which turns locked
+// sessions away.
+func (s *Server) handleReauth(w http.ResponseWriter, r *http.Request) {
+	reauth, ok := s.AuthManager.(Reauthenticator)
+	if !ok {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "The auth provider cannot check the password again")
+		return
+	}
+	session, err := s.AuthManager.GetSessionFromRequest(r)
+	if session == nil || (err != nil && !errors.Is(err, managers.ErrSessionLocked)) {
+		s.writeError(w, r, http.StatusUnauthorized, types.ErrCodeUnauthorized, "Authentication required")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid form data")
+		return
+	}
+	password := r.FormValue("password")
+	if password == "" {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "password is required")
+		return
+	}
+	username := session.Username
+	if !s.allowLogin(w, r, username) {
+		return
+	}
+
+	unlocked := session.Locked
+	session, err = reauth.Reauthenticate(session.ID, password)
+	if err != nil {
+		s.rejectLogin(w, r, username)
+		return
+	}
+	s.recordLoginSuccess(r, username)
+	if unlocked {
+		s.logActivityAs(r.Context(), username, "Session Unlocked", "Unlocked an idle session from "+clientIP(r))
+	}
+
+	response := types.NewAPIResponse(true, "Password confirmed")
+	response.SetData(map[string]interface{}{
+		"authenticated_at": session.AuthenticatedAt,
+		"reauth_until":     session.AuthenticatedAt.Add(time.Duration(s.Config.ReauthWindow) * time.Minute),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
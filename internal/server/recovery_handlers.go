@@ -0,0 +1,199 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// issueRecoverySession signs the admin in after a recovery or emergency
+// check passed, and reports it the way a failed sign-in is reported
+func (s *Server) issueRecoverySession(w http.ResponseWriter, r *http.Request, method string) (*types.Session, bool) {
+	starter, ok := s.AuthManager.(SessionStarter)
+	if !ok {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "The configured authentication does not support recovery logins")
+		return nil, false
+	}
+	session, err := starter.StartSession(s.Config.AdminUsername)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to create session: %v", err))
+		return nil, false
+	}
+	http.SetCookie(w, s.AuthManager.CreateSessionCookie(session.ID))
+
+	client := clientIP(r)
+	s.logActivityAs(r.Context(), session.Username, "Recovery Login", fmt.Sprintf("Admin signed in with %s from %s", method, client))
+	s.postWebhook(managers.WebhookEvent{
+		Type:  managers.EventLoginAlert,
+		Title: "Admin signed in with " + method,
+		Text:  fmt.Sprintf("From %s. If this wasn't you, change the password and regenerate the recovery codes.", client),
+		At:    s.Clock(),
+	})
+	return session, true
+}
+
+// loginWithRecoveryCode signs the admin in with a one-time recovery code
+// in place of the password. The code replaces the password only: when
+// passkeys are the second factor, the sign-in still has to be confirmed
+// with one.
+func (s *Server) loginWithRecoveryCode(w http.ResponseWriter, r *http.Request, username, code string) {
+	if username != s.Config.AdminUsername {
+		s.rejectLogin(w, r, username)
+		return
+	}
+	remaining, err := s.Recovery.Use(code)
+	if errors.Is(err, managers.ErrRecoveryCodeInvalid) {
+		s.rejectLogin(w, r, username)
+		return
+	}
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+
+	if s.passkeySecondFactor(username) {
+		client := clientIP(r)
+		s.logActivityAs(r.Context(), username, "Recovery Code", fmt.Sprintf("Recovery code used from %s; awaiting passkey confirmation", client))
+		s.postWebhook(managers.WebhookEvent{
+			Type:  managers.EventLoginAlert,
+			Title: "Admin recovery code used",
+			Text:  fmt.Sprintf("From %s, %d code(s) left. The sign-in still needs a passkey. If this wasn't you, regenerate the recovery codes.", client, remaining),
+			At:    s.Clock(),
+		})
+		s.requirePasskey(w, r, username, fmt.Sprintf("Recovery code accepted, %d left; confirm the sign-in with a passkey", remaining))
+		return
+	}
+
+	session, ok := s.issueRecoverySession(w, r, "a recovery code")
+	if !ok {
+		return
+	}
+	s.recordLoginSuccess(r, username)
+	response := types.NewAPIResponse(true, fmt.Sprintf("Login successful; %d recovery code(s) left", remaining))
+	response.SetData(map[string]interface{}{
+		"session_id":               session.ID,
+		"expires_at":               session.ExpiresAt,
+		"recovery_codes_remaining": remaining,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleEmergencyLogin signs the admin in without the password, for when it
+// is lost, with the one-time token written to the emergency token file. In
+// local mode the request must also come from the server itself. The token
+// is required there too: behind a reverse proxy on the same host, every
+// request comes from a loopback address.
+func (s *Server) handleEmergencyLogin(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid form data")
+		return
+	}
+
+	switch s.Config.EmergencyLogin {
+	case managers.EmergencyLoginToken:
+	case managers.EmergencyLoginLocal:
+		if !isLocalRequest(r) {
+			s.writeError(w, r, http.StatusForbidden, types.ErrCodeForbidden, "Emergency login only works from the server itself")
+			return
+		}
+	default:
+		s.writeError(w, r, http.StatusForbidden, types.ErrCodeForbidden, "Emergency login is turned off")
+		return
+	}
+
+	err := managers.ConsumeEmergencyToken(s.Config.EmergencyTokenFile, r.FormValue("token"))
+	if errors.Is(err, managers.ErrEmergencyTokenDisabled) {
+		s.writeError(w, r, http.StatusForbidden, types.ErrCodeForbidden, "Emergency login is not enabled; write a token to the emergency token file first")
+		return
+	}
+	if errors.Is(err, managers.ErrEmergencyTokenInvalid) {
+		s.rejectLogin(w, r, s.Config.AdminUsername)
+		return
+	}
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+
+	session, ok := s.issueRecoverySession(w, r, "emergency login")
+	if !ok {
+		return
+	}
+	response := types.NewAPIResponse(true, "Emergency login successful; change the password now")
+	response.SetData(map[string]interface{}{
+		"session_id": session.ID,
+		"expires_at": session.ExpiresAt,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// isLocalRequest reports whether r comes from a loopback address and was not
+// forwarded by a proxy
+func isLocalRequest(r *http.Request) bool {
+	for _, header := range []string{"Forwarded", "X-Forwarded-For", "X-Real-IP"} {
+		if r.Header.Get(header) != "" {
+			return false
+		}
+	}
+	ip := net.ParseIP(clientIP(r))
+	return ip != nil && ip.IsLoopback()
+}
+
+// handleRecoveryCodesGet reports how many recovery codes are left
+func (s *Server) handleRecoveryCodesGet(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	status, err := s.Recovery.Status()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Recovery codes retrieved")
+	response.SetData(map[string]interface{}{
+		"recovery_codes":  status,
+		"emergency_login": s.Config.EmergencyLogin,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleRecoveryCodesRegenerate replaces the recovery codes and returns the
+// new ones. Earlier codes stop working.
+func (s *Server) handleRecoveryCodesRegenerate(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	if !s.requireRecentAuth(w, r) {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid form data")
+		return
+	}
+	if !s.checkCSRF(w, r) {
+		return
+	}
+
+	codes, err := s.Recovery.Generate()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+	s.logActivity(r.Context(), "Recovery Codes", "Recovery codes regenerated")
+
+	response := types.NewAPIResponse(true, "Recovery codes regenerated; store them somewhere safe, they are not shown again")
+	response.SetData(map[string]interface{}{
+		"codes": codes,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
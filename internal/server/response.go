@@ -0,0 +1,63 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"onepagems/internal/types"
+)
+
+// encodeResponse writes an API response as JSON. Failed responses carry the
+// request ID in meta.request_id so users can quote it when reporting problems.
+func (s *Server) encodeResponse(w http.ResponseWriter, r *http.Request, response *types.APIResponse) {
+	if !response.Success {
+		if requestID := types.RequestIDFromContext(r.Context()); requestID != "" {
+			if response.Meta == nil {
+				response.Meta = make(map[string]interface{})
+			}
+			response.Meta["request_id"] = requestID
+		}
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// encodeCachedResponse writes a successful API response like
+// encodeResponse, with an ETag of the body. A request whose If-None-Match
+// already has it gets 304 Not Modified, so an editor polling for changes
+// only downloads a document when it changed. Clients must revalidate on
+// every use, and only the signed-in browser may keep a copy.
+func (s *Server) encodeCachedResponse(w http.ResponseWriter, r *http.Request, response *types.APIResponse) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(response); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to encode response: "+err.Error())
+		return
+	}
+	sum := sha256.Sum256(body.Bytes())
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body.Bytes()))
+}
+
+// writeError sends an application/problem+json error. An empty code falls
+// back to the default code for the status.
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	types.NewProblem(status, code, detail).Write(w, r)
+}
+
+// writeErrorResponse sends a failed APIResponse as a problem, keeping its
+// validation errors and data
+func (s *Server) writeErrorResponse(w http.ResponseWriter, r *http.Request, status int, response *types.APIResponse) {
+	code := ""
+	if len(response.Errors) > 0 {
+		code = types.ErrCodeValidationFailed
+	}
+
+	problem := types.NewProblem(status, code, response.Message)
+	problem.Errors = response.Errors
+	problem.Data = response.Data
+	problem.Write(w, r)
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// applyRetention prunes old records when a retention run is due, logging
+// and recording what was purged
+func (s *Server) applyRetention() {
+	due, err := s.Retention.Due(s.Clock())
+	if err != nil {
+		s.Logger.Printf("Retention: %v", err)
+		s.heartbeatFail("Retention: " + err.Error())
+		return
+	}
+	if !due {
+		return
+	}
+	if _, err := s.runRetention(context.Background()); err != nil {
+		s.heartbeatFail("Retention: " + err.Error())
+	}
+}
+
+// runRetention runs the retention policy now and records the report in the
+// activity log. Errors pruning one kind of record are reported along with
+// what was purged.
+func (s *Server) runRetention(ctx context.Context) (*managers.RetentionReport, error) {
+	report, err := s.Retention.Run(s.Clock())
+	if err == nil && len(report.Errors) > 0 {
+		err = fmt.Errorf("%s", strings.Join(report.Errors, "; "))
+	}
+	if err != nil {
+		s.Logger.Printf("Retention: %v", err)
+	}
+	s.logActivity(ctx, "Retention Applied", fmt.Sprintf("Purged %d activity entries, %d jobs and %d dead outbox messages",
+		report.Activity, report.Jobs, report.Outbox))
+	return report, err
+}
+
+// handleRetentionGet shows the retention policy and the report of the last
+// run
+func (s *Server) handleRetentionGet(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	last, err := s.Retention.LastReport()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Retention policy retrieved")
+	response.SetData(map[string]interface{}{
+		"policy":      s.Retention.Policy(),
+		"enabled":     s.Retention.Policy().Enabled(),
+		"last_report": last,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleRetentionRun applies the retention policy now instead of waiting
+// for the scheduler
+func (s *Server) handleRetentionRun(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	policy := s.Retention.Policy()
+	if !policy.Enabled() {
+		s.writeError(w, r, http.StatusConflict, types.ErrCodeConflict, "No retention period is set; set ACTIVITY_RETENTION_DAYS, JOB_RETENTION_DAYS or OUTBOX_RETENTION_DAYS")
+		return
+	}
+	if !s.requireConfirmation(w, r, "retention.run", "", map[string]interface{}{"policy": policy}) {
+		return
+	}
+
+	report, err := s.runRetention(r.Context())
+	if err != nil {
+		response := types.NewAPIResponse(false, "Retention ran with errors: "+err.Error())
+		response.SetData(report)
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
+		return
+	}
+
+	response := types.NewAPIResponse(true, fmt.Sprintf("Purged %d record(s)", report.Total()))
+	response.SetData(report)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
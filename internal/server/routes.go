@@ -1,126 +1,411 @@
 package server
 
 import (
-	"log"
 	"net/http"
 	"path/filepath"
+
+	"onepagems/internal/types"
 )
 
 // setupRoutes configures all the HTTP routes
 func (s *Server) setupRoutes() {
 	// Static file serving
-	s.Mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir(s.Config.StaticDir))))
-	s.Mux.Handle("/images/", http.StripPrefix("/images/", http.FileServer(http.Dir(filepath.Join(s.Config.DataDir, "images")))))
+	s.Mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.Dir(s.Config.StaticDir))))
+	s.Mux.Handle("GET /images/", http.StripPrefix("/images/", http.FileServer(http.Dir(filepath.Join(s.Config.DataDir, "images")))))
+	s.Mux.Handle("GET /assets/", http.StripPrefix("/assets/", s.handleLiveAsset(http.FileServer(http.Dir(filepath.Join(s.Config.OutputDir, "assets"))))))
+	s.Mux.Handle("GET /qr/", http.StripPrefix("/qr/", http.FileServer(http.Dir(filepath.Join(s.Config.OutputDir, "qr")))))
 
 	// Public routes
-	s.Mux.HandleFunc("/", s.handlePublicPage)
-	s.Mux.HandleFunc("/health", s.handleHealth)
+	s.Mux.HandleFunc("GET /{$}", s.handlePublicPage)
+	s.Mux.HandleFunc("GET /content.json", s.handlePublicContent)
+	s.Mux.HandleFunc("GET /search.json", s.handleSearchIndex)
+	s.Mux.HandleFunc("GET /contact.vcf", s.handleVCard)
+	s.Mux.HandleFunc("GET /events.ics", s.handleCalendar)
+	s.Mux.HandleFunc("GET /.well-known/security.txt", s.handleSecurityTxt)
+	s.Mux.HandleFunc("GET /humans.txt", s.handleHumansTxt)
+	s.Mux.HandleFunc("GET /health", s.handleHealth)
+	s.Mux.HandleFunc("GET /status.json", s.handleStatusJSON)
+	s.Mux.HandleFunc("GET /status.svg", s.handleStatusBadge)
 
 	// Authentication routes (not protected)
-	s.Mux.HandleFunc("/admin/login", s.handleAdminLogin)
-	s.Mux.HandleFunc("/admin/logout", s.handleAdminLogout)
+	s.Mux.HandleFunc("GET /admin/login", s.serveLoginForm)
+	s.Mux.HandleFunc("POST /admin/login", s.handleAdminLogin)
+	s.Mux.HandleFunc("POST /admin/login/emergency", s.handleEmergencyLogin)
+	s.Mux.HandleFunc("POST /admin/login/passkey/begin", s.handlePasskeyLoginBegin)
+	s.Mux.HandleFunc("POST /admin/login/passkey/finish", s.handlePasskeyLoginFinish)
+	s.Mux.HandleFunc("POST /admin/logout", s.handleAdminLogout)
+	s.Mux.HandleFunc("POST /admin/auth/reauth", s.handleReauth)
+	s.Mux.HandleFunc("GET /admin/manifest.webmanifest", s.handleAdminManifest)
+	s.Mux.HandleFunc("GET /admin/icon.svg", s.handleAdminIcon)
+	s.Mux.HandleFunc("GET /admin/sw.js", s.handleAdminServiceWorker)
 
 	// Protected admin routes
-	s.Mux.HandleFunc("/admin", s.AuthManager.RequireAuth(s.handleAdminPanel))
-	s.Mux.HandleFunc("/admin/content", s.AuthManager.RequireAuth(s.handleAdminContent))
-	s.Mux.HandleFunc("/admin/api/stats", s.AuthManager.RequireAuth(s.handleAPIStats))
-	s.Mux.HandleFunc("/admin/api/generate", s.AuthManager.RequireAuth(s.handleAPIGenerate))
-	s.Mux.HandleFunc("/admin/api/status", s.AuthManager.RequireAuth(s.handleAPIStatus))
+	s.Mux.HandleFunc("GET /admin", s.AuthManager.RequireAuth(s.handleAdminPanel))
+	s.Mux.HandleFunc("GET /admin/content", s.AuthManager.RequireAuth(s.handleAdminContent))
+	s.Mux.HandleFunc("POST /admin/content", s.AuthManager.RequireAuth(s.handleContentUpdate))
+	s.Mux.HandleFunc("GET /admin/api/stats", s.AuthManager.RequireAuth(s.handleAPIStats))
+	s.Mux.HandleFunc("GET /admin/api/quality", s.AuthManager.RequireAuth(s.handleAPIQuality))
+	s.Mux.HandleFunc("GET /admin/api/examples", s.AuthManager.RequireAuth(s.handleAPIExamplesList))
+	s.Mux.HandleFunc("GET /admin/api/examples/{endpoint}", s.AuthManager.RequireAuth(s.handleAPIExample))
+	s.Mux.HandleFunc("POST /admin/api/generate", s.AuthManager.RequireAuth(s.handleAPIGenerate))
+	s.Mux.HandleFunc("GET /admin/notifications", s.AuthManager.RequireAuth(s.handleNotificationsGet))
+	s.Mux.HandleFunc("POST /admin/notifications/send", s.AuthManager.RequireAuth(s.handleNotificationsSend))
+	s.Mux.HandleFunc("GET /admin/webhooks", s.AuthManager.RequireAuth(s.handleWebhooksGet))
+	s.Mux.HandleFunc("POST /admin/webhooks/test", s.AuthManager.RequireAuth(s.handleWebhooksTest))
+	s.Mux.HandleFunc("GET /admin/outbox", s.AuthManager.RequireAuth(s.handleOutboxList))
+	s.Mux.HandleFunc("POST /admin/outbox/process", s.AuthManager.RequireAuth(s.handleOutboxProcess))
+	s.Mux.HandleFunc("POST /admin/outbox/{id}/retry", s.AuthManager.RequireAuth(s.handleOutboxRetry))
+	s.Mux.HandleFunc("DELETE /admin/outbox/{id}", s.AuthManager.RequireAuth(s.handleOutboxDiscard))
+	s.Mux.HandleFunc("GET /admin/retention", s.AuthManager.RequireAuth(s.handleRetentionGet))
+	s.Mux.HandleFunc("POST /admin/retention/run", s.AuthManager.RequireAuth(s.handleRetentionRun))
+	s.Mux.HandleFunc("GET /admin/api/generate/diff", s.AuthManager.RequireAuth(s.handleAPIGenerateDiff))
+	s.Mux.HandleFunc("GET /admin/site/builds", s.AuthManager.RequireAuth(s.handleSiteBuilds))
+	s.Mux.HandleFunc("POST /admin/site/rollback", s.AuthManager.RequireAuth(s.handleSiteRollback))
+	s.Mux.HandleFunc("GET /admin/site/presets", s.AuthManager.RequireAuth(s.handleSitePresets))
+	s.Mux.HandleFunc("POST /admin/site/reset", s.AuthManager.RequireAuth(s.handleSiteReset))
+	s.Mux.HandleFunc("GET /admin/sites", s.AuthManager.RequireAuth(s.handleSitesList))
+	s.Mux.HandleFunc("POST /admin/site/duplicate", s.AuthManager.RequireAuth(s.handleSiteDuplicate))
+	s.Mux.HandleFunc("GET /admin/variants", s.AuthManager.RequireAuth(s.handleVariantsList))
+	s.Mux.HandleFunc("PUT /admin/variants/{key}", s.AuthManager.RequireAuth(s.handleVariantSet))
+	s.Mux.HandleFunc("DELETE /admin/variants/{key}", s.AuthManager.RequireAuth(s.handleVariantDelete))
+	s.Mux.HandleFunc("GET /admin/schedules", s.AuthManager.RequireAuth(s.handleSchedulesList))
+	s.Mux.HandleFunc("POST /admin/schedules", s.AuthManager.RequireAuth(s.handleScheduleCreate))
+	s.Mux.HandleFunc("DELETE /admin/schedules/{id}", s.AuthManager.RequireAuth(s.handleScheduleDelete))
+	s.Mux.HandleFunc("GET /admin/api/status", s.AuthManager.RequireAuth(s.handleAPIStatus))
+	s.Mux.HandleFunc("GET /admin/search", s.AuthManager.RequireAuth(s.handleAdminSearch))
 
 	// File management test endpoints (protected)
-	s.Mux.HandleFunc("/admin/files", s.AuthManager.RequireAuth(s.handleFilesList))
-	s.Mux.HandleFunc("/admin/test-storage", s.AuthManager.RequireAuth(s.handleTestStorage))
+	s.Mux.HandleFunc("GET /admin/files", s.AuthManager.RequireAuth(s.handleFilesList))
+	s.Mux.HandleFunc("POST /admin/test-storage", s.AuthManager.RequireAuth(s.handleTestStorage))
+
+	// Image management endpoints (protected)
+	s.Mux.HandleFunc("POST /admin/upload", s.AuthManager.RequireAuth(s.handleImageUpload))
+	s.Mux.HandleFunc("GET /admin/images", s.AuthManager.RequireAuth(s.handleImages))
+	s.Mux.HandleFunc("POST /admin/images/delete", s.AuthManager.RequireAuth(s.handleImageDelete))
+	s.Mux.HandleFunc("DELETE /admin/images/delete", s.AuthManager.RequireAuth(s.handleImageDelete))
+	s.Mux.HandleFunc("GET /admin/images/usage", s.AuthManager.RequireAuth(s.handleImageUsage))
+	s.Mux.HandleFunc("POST /admin/images/alt-text", s.AuthManager.RequireAuth(s.handleImageAltText))
+	s.Mux.HandleFunc("POST /admin/images/suggest-alt-text", s.AuthManager.RequireAuth(s.handleImageSuggestAltText))
+	s.Mux.HandleFunc("POST /admin/images/crop", s.AuthManager.RequireAuth(s.handleImageCrop))
+	s.Mux.HandleFunc("POST /admin/images/rotate", s.AuthManager.RequireAuth(s.handleImageRotate))
+	s.Mux.HandleFunc("POST /admin/images/focal-point", s.AuthManager.RequireAuth(s.handleImageFocalPoint))
+	s.Mux.HandleFunc("POST /admin/images/bulk-delete", s.AuthManager.RequireAuth(s.handleImagesBulkDelete))
+	s.Mux.HandleFunc("POST /admin/images/regenerate-variants", s.AuthManager.RequireAuth(s.handleImagesRegenerateVariants))
 
 	// Template management endpoints (protected)
-	s.Mux.HandleFunc("/admin/template", s.AuthManager.RequireAuth(s.handleTemplate))
-	s.Mux.HandleFunc("/admin/template/info", s.AuthManager.RequireAuth(s.handleTemplateInfo))
-	s.Mux.HandleFunc("/admin/template/restore", s.AuthManager.RequireAuth(s.handleTemplateRestore))
-	s.Mux.HandleFunc("/admin/test-template", s.AuthManager.RequireAuth(s.handleTestTemplate))
+	s.Mux.HandleFunc("GET /admin/template", s.AuthManager.RequireAuth(s.handleTemplateGet))
+	s.Mux.HandleFunc("POST /admin/template", s.AuthManager.RequireAuth(s.handleTemplatePost))
+	s.Mux.HandleFunc("GET /admin/template/info", s.AuthManager.RequireAuth(s.handleTemplateInfo))
+	s.Mux.HandleFunc("POST /admin/template/compatibility", s.AuthManager.RequireAuth(s.handleTemplateCompatibility))
+	s.Mux.HandleFunc("GET /admin/template/check", s.AuthManager.RequireAuth(s.handleTemplateCheck))
+	s.Mux.HandleFunc("POST /admin/template/restore", s.AuthManager.RequireAuth(s.handleTemplateRestore))
+	s.Mux.HandleFunc("GET /admin/templates/gallery", s.AuthManager.RequireAuth(s.handleStarterGallery))
+	s.Mux.HandleFunc("POST /admin/templates/apply", s.AuthManager.RequireAuth(s.handleStarterApply))
+	s.Mux.HandleFunc("GET /admin/section-renderers", s.AuthManager.RequireAuth(s.handleSectionRenderersList))
+	s.Mux.HandleFunc("POST /admin/section-renderers", s.AuthManager.RequireAuth(s.handleSectionRendererRegister))
+	s.Mux.HandleFunc("DELETE /admin/section-renderers/{name}", s.AuthManager.RequireAuth(s.handleSectionRendererRemove))
+	s.Mux.HandleFunc("POST /admin/test-template", s.AuthManager.RequireAuth(s.handleTestTemplate))
 
 	// Content management endpoints (protected)
-	s.Mux.HandleFunc("/admin/content/info", s.AuthManager.RequireAuth(s.handleContentInfo))
-	s.Mux.HandleFunc("/admin/content/restore", s.AuthManager.RequireAuth(s.handleContentRestore))
-	s.Mux.HandleFunc("/admin/content/export", s.AuthManager.RequireAuth(s.handleContentExport))
-	s.Mux.HandleFunc("/admin/content/import", s.AuthManager.RequireAuth(s.handleContentImport))
-	s.Mux.HandleFunc("/admin/content/auto-save", s.AuthManager.RequireAuth(s.handleContentAutoSave))
-	s.Mux.HandleFunc("/admin/content/preview", s.AuthManager.RequireAuth(s.handlePreviewContent))
-	s.Mux.HandleFunc("/admin/test-content", s.AuthManager.RequireAuth(s.handleTestContent))
+	s.Mux.HandleFunc("GET /admin/content/info", s.AuthManager.RequireAuth(s.handleContentInfo))
+	s.Mux.HandleFunc("POST /admin/content/restore", s.AuthManager.RequireAuth(s.handleContentRestore))
+	s.Mux.HandleFunc("POST /admin/content/heal", s.AuthManager.RequireAuth(s.handleContentHeal))
+	s.Mux.HandleFunc("POST /admin/content/duplicate", s.AuthManager.RequireAuth(s.handleContentDuplicate))
+	s.Mux.HandleFunc("GET /admin/content/changes", s.AuthManager.RequireAuth(s.handleContentChanges))
+	s.Mux.HandleFunc("POST /admin/content/changes", s.AuthManager.RequireAuth(s.handleContentChangesApply))
+	s.Mux.HandleFunc("POST /admin/content/section/archive", s.AuthManager.RequireAuth(s.handleSectionArchive))
+	s.Mux.HandleFunc("POST /admin/content/section/restore", s.AuthManager.RequireAuth(s.handleSectionRestore))
+	s.Mux.HandleFunc("GET /admin/content/sections/archived", s.AuthManager.RequireAuth(s.handleSectionsArchived))
+	s.Mux.HandleFunc("DELETE /admin/content/sections/archived/{key}", s.AuthManager.RequireAuth(s.handleSectionArchivedDelete))
+	s.Mux.HandleFunc("POST /admin/content/form", s.AuthManager.RequireAuth(s.handleContentForm))
+	s.Mux.HandleFunc("GET /admin/basic/content", s.AuthManager.RequireAuth(s.handleBasicContent))
+	s.Mux.HandleFunc("GET /admin/basic/template", s.AuthManager.RequireAuth(s.handleBasicTemplate))
+	s.Mux.HandleFunc("POST /admin/basic/template", s.AuthManager.RequireAuth(s.handleBasicTemplatePost))
+	s.Mux.HandleFunc("GET /admin/basic/images", s.AuthManager.RequireAuth(s.handleBasicImages))
+	s.Mux.HandleFunc("POST /admin/basic/images", s.AuthManager.RequireAuth(s.handleBasicImagesPost))
+	s.Mux.HandleFunc("GET /admin/content/export", s.AuthManager.RequireAuth(s.handleContentExport))
+	s.Mux.HandleFunc("POST /admin/content/import", s.AuthManager.RequireAuth(s.handleContentImport))
+	s.Mux.HandleFunc("POST /admin/content/import/mappings", s.AuthManager.RequireAuth(s.handleImportMappingCreate))
+	s.Mux.HandleFunc("GET /admin/content/import/mappings/{id}", s.AuthManager.RequireAuth(s.handleImportMappingGet))
+	s.Mux.HandleFunc("POST /admin/content/import/mappings/{id}/preview", s.AuthManager.RequireAuth(s.handleImportMappingPreview))
+	s.Mux.HandleFunc("POST /admin/content/import/mappings/{id}/commit", s.AuthManager.RequireAuth(s.handleImportMappingCommit))
+	s.Mux.HandleFunc("DELETE /admin/content/import/mappings/{id}", s.AuthManager.RequireAuth(s.handleImportMappingDelete))
+	s.Mux.HandleFunc("POST /admin/content/auto-save", s.AuthManager.RequireAuth(s.handleContentAutoSave))
+	s.Mux.HandleFunc("GET /admin/content/autosave", s.AuthManager.RequireAuth(s.handleContentAutosaveGet))
+	s.Mux.HandleFunc("DELETE /admin/content/autosave", s.AuthManager.RequireAuth(s.handleContentAutosaveDiscard))
+	s.Mux.HandleFunc("GET /admin/content/draft", s.AuthManager.RequireAuth(s.handleContentDraftGet))
+	s.Mux.HandleFunc("PUT /admin/content/draft", s.AuthManager.RequireAuth(s.handleContentDraftSave))
+	s.Mux.HandleFunc("DELETE /admin/content/draft", s.AuthManager.RequireAuth(s.handleContentDraftDiscard))
+	s.Mux.HandleFunc("POST /admin/content/draft/publish", s.AuthManager.RequireAuth(s.handleContentDraftPublish))
+	s.Mux.HandleFunc("GET /admin/content/draft/preview", s.AuthManager.RequireAuth(s.handleContentDraftPreview))
+	s.Mux.HandleFunc("GET /admin/content/preview", s.AuthManager.RequireAuth(s.handlePreviewContent))
+	s.Mux.HandleFunc("POST /admin/test-content", s.AuthManager.RequireAuth(s.handleTestContent))
 
 	// Schema management endpoints (protected)
-	s.Mux.HandleFunc("/admin/schema", s.AuthManager.RequireAuth(s.handleSchema))
-	s.Mux.HandleFunc("/admin/schema/info", s.AuthManager.RequireAuth(s.handleSchemaInfo))
-	s.Mux.HandleFunc("/admin/schema/restore", s.AuthManager.RequireAuth(s.handleSchemaRestore))
-	s.Mux.HandleFunc("/admin/schema/export", s.AuthManager.RequireAuth(s.handleSchemaExport))
-	s.Mux.HandleFunc("/admin/schema/import", s.AuthManager.RequireAuth(s.handleSchemaImport))
-	s.Mux.HandleFunc("/admin/schema/validate", s.AuthManager.RequireAuth(s.handleSchemaValidate))
-	s.Mux.HandleFunc("/admin/schema/form", s.AuthManager.RequireAuth(s.handleSchemaForm))
-	s.Mux.HandleFunc("/admin/schema/form-fields", s.AuthManager.RequireAuth(s.handleSchemaFormFields))
-	s.Mux.HandleFunc("/admin/test-schema", s.AuthManager.RequireAuth(s.handleTestSchema))
+	s.Mux.HandleFunc("GET /admin/schema", s.AuthManager.RequireAuth(s.handleSchemaGet))
+	s.Mux.HandleFunc("POST /admin/schema", s.AuthManager.RequireAuth(s.handleSchemaPost))
+	s.Mux.HandleFunc("GET /admin/schema/info", s.AuthManager.RequireAuth(s.handleSchemaInfo))
+	s.Mux.HandleFunc("POST /admin/schema/restore", s.AuthManager.RequireAuth(s.handleSchemaRestore))
+	s.Mux.HandleFunc("GET /admin/schema/export", s.AuthManager.RequireAuth(s.handleSchemaExport))
+	s.Mux.HandleFunc("POST /admin/schema/import", s.AuthManager.RequireAuth(s.handleSchemaImport))
+	s.Mux.HandleFunc("POST /admin/schema/migrate", s.AuthManager.RequireAuth(s.handleSchemaMigrate))
+	s.Mux.HandleFunc("POST /admin/schema/validate", s.AuthManager.RequireAuth(s.handleSchemaValidate))
+	s.Mux.HandleFunc("GET /admin/schema/form", s.AuthManager.RequireAuth(s.handleSchemaForm))
+	s.Mux.HandleFunc("GET /admin/schema/form-fields", s.AuthManager.RequireAuth(s.handleSchemaFormFields))
+	s.Mux.HandleFunc("GET /admin/schema/presets", s.AuthManager.RequireAuth(s.handleSchemaPresets))
+	s.Mux.HandleFunc("POST /admin/schema/presets", s.AuthManager.RequireAuth(s.handleSchemaPresetAdd))
+	s.Mux.HandleFunc("POST /admin/test-schema", s.AuthManager.RequireAuth(s.handleTestSchema))
+
+	// Section anchor slugs (protected)
+	s.Mux.HandleFunc("GET /admin/slugs", s.AuthManager.RequireAuth(s.handleSlugsGet))
+	s.Mux.HandleFunc("POST /admin/slugs", s.AuthManager.RequireAuth(s.handleSlugSet))
+	s.Mux.HandleFunc("POST /admin/slugs/regenerate", s.AuthManager.RequireAuth(s.handleSlugsRegenerate))
+
+	// Custom code slots (protected, admin only)
+	s.Mux.HandleFunc("GET /admin/custom-code", s.AuthManager.RequireAuth(s.handleCustomCodeGet))
+	s.Mux.HandleFunc("POST /admin/custom-code", s.AuthManager.RequireAuth(s.handleCustomCodePost))
+
+	// Encrypted integration secrets (protected, admin only)
+	s.Mux.HandleFunc("GET /admin/secrets", s.AuthManager.RequireAuth(s.handleSecretsList))
+	s.Mux.HandleFunc("POST /admin/secrets", s.AuthManager.RequireAuth(s.handleSecretsSet))
+	s.Mux.HandleFunc("DELETE /admin/secrets/{name}", s.AuthManager.RequireAuth(s.handleSecretsDelete))
+
+	// Disk layout diagnostics (protected, admin only)
+	s.Mux.HandleFunc("GET /admin/doctor", s.AuthManager.RequireAuth(s.handleDoctor))
+	s.Mux.HandleFunc("POST /admin/migration-backups/prune", s.AuthManager.RequireAuth(s.handleMigrationBackupsPrune))
+
+	// Site archives and background jobs (protected; import is admin only)
+	s.Mux.HandleFunc("GET /admin/export/archive", s.AuthManager.RequireAuth(s.handleArchiveExport))
+	s.Mux.HandleFunc("POST /admin/import/archive", s.AuthManager.RequireAuth(s.handleArchiveImport))
+	s.Mux.HandleFunc("GET /admin/jobs", s.AuthManager.RequireAuth(s.handleJobsList))
+	s.Mux.HandleFunc("GET /admin/jobs/{id}", s.AuthManager.RequireAuth(s.handleJobGet))
+	s.Mux.HandleFunc("GET /admin/jobs/{id}/events", s.AuthManager.RequireAuth(s.handleJobEvents))
 
 	// Schema parser endpoints (protected)
-	s.Mux.HandleFunc("/admin/schema/analyze", s.AuthManager.RequireAuth(s.handleSchemaAnalyze))
-	s.Mux.HandleFunc("/admin/schema/field-metadata", s.AuthManager.RequireAuth(s.handleSchemaFieldMetadata))
-	s.Mux.HandleFunc("/admin/schema/validation-rules", s.AuthManager.RequireAuth(s.handleSchemaValidationRules))
-	s.Mux.HandleFunc("/admin/schema/field-types", s.AuthManager.RequireAuth(s.handleSchemaFieldTypes))
-	s.Mux.HandleFunc("/admin/schema/required-fields", s.AuthManager.RequireAuth(s.handleSchemaRequiredFields))
-	s.Mux.HandleFunc("/admin/schema/validate-field", s.AuthManager.RequireAuth(s.handleSchemaValidateField))
+	s.Mux.HandleFunc("GET /admin/schema/analyze", s.AuthManager.RequireAuth(s.handleSchemaAnalyze))
+	s.Mux.HandleFunc("GET /admin/schema/field-metadata", s.AuthManager.RequireAuth(s.handleSchemaFieldMetadata))
+	s.Mux.HandleFunc("GET /admin/schema/validation-rules", s.AuthManager.RequireAuth(s.handleSchemaValidationRules))
+	s.Mux.HandleFunc("GET /admin/schema/field-types", s.AuthManager.RequireAuth(s.handleSchemaFieldTypes))
+	s.Mux.HandleFunc("GET /admin/schema/required-fields", s.AuthManager.RequireAuth(s.handleSchemaRequiredFields))
+	s.Mux.HandleFunc("POST /admin/schema/validate-field", s.AuthManager.RequireAuth(s.handleSchemaValidateField))
 
 	// Schema validator endpoints (protected)
-	s.Mux.HandleFunc("/admin/schema/validate-content", s.AuthManager.RequireAuth(s.handleSchemaValidateContent))
-	s.Mux.HandleFunc("/admin/schema/validate-field-detailed", s.AuthManager.RequireAuth(s.handleSchemaValidateFieldDetailed))
-	s.Mux.HandleFunc("/admin/schema/validation-report", s.AuthManager.RequireAuth(s.handleSchemaValidationReport))
+	s.Mux.HandleFunc("POST /admin/schema/validate-content", s.AuthManager.RequireAuth(s.handleSchemaValidateContent))
+	s.Mux.HandleFunc("POST /admin/schema/validate-field-detailed", s.AuthManager.RequireAuth(s.handleSchemaValidateFieldDetailed))
+	s.Mux.HandleFunc("POST /admin/schema/validation-report", s.AuthManager.RequireAuth(s.handleSchemaValidationReport))
 
 	// Authentication status endpoints (protected)
-	s.Mux.HandleFunc("/admin/auth/status", s.AuthManager.RequireAuth(s.handleAuthStatus))
-	s.Mux.HandleFunc("/admin/auth/sessions", s.AuthManager.RequireAuth(s.handleAuthSessions))
-	s.Mux.HandleFunc("/admin/auth/change-password", s.AuthManager.RequireAuth(s.handleChangePassword))
-
-	log.Println("Routes configured:")
-	log.Println("  GET  /               - Public page")
-	log.Println("  GET  /health         - Health check")
-	log.Println("  GET  /static/        - Static files")
-	log.Println("  GET  /images/        - Image files")
-	log.Println("  GET  /admin          - Admin panel")
-	log.Println("  POST /admin/login    - Admin login")
-	log.Println("  POST /admin/logout   - Admin logout")
-	log.Println("  GET  /admin          - Admin dashboard")
-	log.Println("  GET/POST /admin/content - Content editor interface")
-	log.Println("  GET  /admin/api/stats - Dashboard statistics API")
-	log.Println("  POST /admin/api/generate - Site generation API")
-	log.Println("  GET  /admin/api/status - System status API")
-	log.Println("  GET  /admin/files    - List files (test)")
-	log.Println("  POST /admin/test-storage - Test storage operations")
-	log.Println("  GET/POST /admin/template - Template management")
-	log.Println("  GET  /admin/template/info - Template information")
-	log.Println("  POST /admin/template/restore - Restore template")
-	log.Println("  POST /admin/test-template - Test template operations")
-	log.Println("  GET/POST /admin/content - Content management")
-	log.Println("  GET  /admin/content/info - Content information")
-	log.Println("  POST /admin/content/restore - Restore content")
-	log.Println("  GET  /admin/content/export - Export content")
-	log.Println("  POST /admin/content/import - Import content")
-	log.Println("  POST /admin/content/auto-save - Auto-save content")
-	log.Println("  GET  /admin/content/preview - Preview content")
-	log.Println("  POST /admin/test-content - Test content operations")
-	log.Println("  GET/POST /admin/schema - Schema management")
-	log.Println("  GET  /admin/schema/info - Schema information")
-	log.Println("  POST /admin/schema/restore - Restore schema")
-	log.Println("  GET  /admin/schema/export - Export schema")
-	log.Println("  POST /admin/schema/import - Import schema")
-	log.Println("  POST /admin/schema/validate - Validate data against schema")
-	log.Println("  GET  /admin/schema/form - Generate complete form from schema")
-	log.Println("  GET  /admin/schema/form-fields - Generate form fields from schema")
-	log.Println("  POST /admin/test-schema - Test schema operations")
-	log.Println("  GET  /admin/schema/analyze - Comprehensive schema analysis")
-	log.Println("  GET  /admin/schema/field-metadata - Get field metadata (query: field)")
-	log.Println("  GET  /admin/schema/validation-rules - Get all validation rules")
-	log.Println("  GET  /admin/schema/field-types - Get field types mapping")
-	log.Println("  GET  /admin/schema/required-fields - Get required/optional fields")
-	log.Println("  POST /admin/schema/validate-field - Validate single field value")
-	log.Println("  POST /admin/schema/validate-content - Comprehensive content validation")
-	log.Println("  POST /admin/schema/validate-field-detailed - Detailed field validation")
-	log.Println("  POST /admin/schema/validation-report - Generate validation report")
-	log.Println("  GET  /admin/auth/status - Authentication status")
-	log.Println("  GET  /admin/auth/sessions - List active sessions")
-	log.Println("  POST /admin/auth/change-password - Change password")
+	s.Mux.HandleFunc("GET /admin/auth/status", s.AuthManager.RequireAuth(s.handleAuthStatus))
+	s.Mux.HandleFunc("GET /admin/auth/sessions", s.AuthManager.RequireAuth(s.handleAuthSessions))
+	s.Mux.HandleFunc("POST /admin/auth/change-password", s.AuthManager.RequireAuth(s.handleChangePassword))
+	s.Mux.HandleFunc("GET /admin/auth/recovery-codes", s.AuthManager.RequireAuth(s.handleRecoveryCodesGet))
+	s.Mux.HandleFunc("POST /admin/auth/recovery-codes", s.AuthManager.RequireAuth(s.handleRecoveryCodesRegenerate))
+	s.Mux.HandleFunc("GET /admin/auth/passkeys", s.AuthManager.RequireAuth(s.handlePasskeysList))
+	s.Mux.HandleFunc("POST /admin/auth/passkeys/register/begin", s.AuthManager.RequireAuth(s.handlePasskeyRegisterBegin))
+	s.Mux.HandleFunc("POST /admin/auth/passkeys/register/finish", s.AuthManager.RequireAuth(s.handlePasskeyRegisterFinish))
+	s.Mux.HandleFunc("DELETE /admin/auth/passkeys/{id}", s.AuthManager.RequireAuth(s.handlePasskeyDelete))
+	s.Mux.HandleFunc("GET /admin/plugins", s.AuthManager.RequireAuth(s.handlePluginsList))
+	s.Mux.HandleFunc("GET /admin/activity", s.AuthManager.RequireAuth(s.handleActivityList))
+	s.Mux.HandleFunc("GET /admin/confirm", s.AuthManager.RequireAuth(s.handleConfirm))
+	s.Mux.HandleFunc("GET /admin/auth/lockouts", s.AuthManager.RequireAuth(s.handleLoginLockouts))
+	s.Mux.HandleFunc("DELETE /admin/auth/lockouts", s.AuthManager.RequireAuth(s.handleLoginLockoutsClear))
+
+	s.Logger.Println("Routes configured:")
+	s.Logger.Println("  GET  /               - Public page")
+	s.Logger.Println("  GET  /content.json   - Published content without private fields")
+	s.Logger.Println("  GET  /search.json    - Section search index")
+	s.Logger.Println("  GET  /contact.vcf    - Contact card")
+	s.Logger.Println("  GET  /events.ics     - Calendar of dated content")
+	s.Logger.Println("  GET  /.well-known/security.txt - Security contact")
+	s.Logger.Println("  GET  /humans.txt     - Site credits")
+	s.Logger.Println("  GET  /health         - Health check")
+	s.Logger.Println("  GET  /status.json    - Uptime, last generation and last backup")
+	s.Logger.Println("  GET  /status.svg     - Status badge")
+	s.Logger.Println("  GET  /static/        - Static files")
+	s.Logger.Println("  GET  /images/        - Image files")
+	s.Logger.Println("  GET  /assets/        - Generated site assets")
+	s.Logger.Println("  GET  /qr/            - Generated QR codes")
+	s.Logger.Println("  GET  /admin          - Admin panel")
+	s.Logger.Println("  POST /admin/login    - Admin login")
+	s.Logger.Println("  POST /admin/login/emergency - Emergency admin login (EMERGENCY_LOGIN)")
+	s.Logger.Println("  POST /admin/login/passkey/begin - Start a passkey sign-in")
+	s.Logger.Println("  POST /admin/login/passkey/finish - Sign in with a passkey")
+	s.Logger.Println("  POST /admin/logout   - Admin logout")
+	s.Logger.Println("  POST /admin/auth/reauth - Enter the password again to unlock an idle session or allow sensitive actions")
+	s.Logger.Println("  GET  /admin/manifest.webmanifest - Web app manifest for installing the admin panel")
+	s.Logger.Println("  GET  /admin/sw.js - Service worker caching the admin panel for offline use")
+	s.Logger.Println("  GET  /admin          - Admin dashboard")
+	s.Logger.Println("  GET/POST /admin/content - Content editor interface")
+	s.Logger.Println("  GET  /admin/api/stats - Dashboard statistics API")
+	s.Logger.Println("  GET  /admin/api/quality - Content quality score")
+	s.Logger.Println("  GET  /admin/api/examples/{endpoint} - Example request and response from the schema")
+	s.Logger.Println("  POST /admin/api/generate - Site generation API")
+	s.Logger.Println("  GET  /admin/api/generate/diff - Diff of the published page and the next generation")
+	s.Logger.Println("  GET  /admin/notifications - Publish notification settings and queue (admin)")
+	s.Logger.Println("  POST /admin/notifications/send - Send queued publish notifications now (admin)")
+	s.Logger.Println("  GET  /admin/webhooks - Chat and webhook endpoints by event (admin)")
+	s.Logger.Println("  POST /admin/webhooks/test - Post a test message for an event (admin, form: event)")
+	s.Logger.Println("  GET  /admin/outbox - Webhooks, emails and deploy hooks waiting or dead-lettered (admin, query: status)")
+	s.Logger.Println("  POST /admin/outbox/process - Deliver everything pending now, as a job (admin)")
+	s.Logger.Println("  POST /admin/outbox/{id}/retry - Retry a message now (admin)")
+	s.Logger.Println("  DELETE /admin/outbox/{id} - Discard a message (admin)")
+	s.Logger.Println("  GET  /admin/retention - Retention policy and last purge report (admin)")
+	s.Logger.Println("  POST /admin/retention/run - Purge records past their retention period now (admin)")
+	s.Logger.Println("  GET  /admin/site/builds - Generated builds kept for rollback")
+	s.Logger.Println("  POST /admin/site/rollback - Put a previous build live")
+	s.Logger.Println("  GET  /admin/site/presets - Presets a site can be reset to")
+	s.Logger.Println("  POST /admin/site/reset - Reset the site to a preset, archiving it first (admin)")
+	s.Logger.Println("  GET  /admin/sites - Copies of the site in SITES_DIR (admin)")
+	s.Logger.Println("  POST /admin/site/duplicate - Copy the site into a new slot in SITES_DIR (admin)")
+	s.Logger.Println("  GET  /admin/variants - A/B variants and exposure counts")
+	s.Logger.Println("  PUT  /admin/variants/{key} - Set the B variant of a section")
+	s.Logger.Println("  DELETE /admin/variants/{key} - End the A/B test of a section")
+	s.Logger.Println("  GET  /admin/schedules - Scheduled content")
+	s.Logger.Println("  POST /admin/schedules - Schedule a section or field value")
+	s.Logger.Println("  DELETE /admin/schedules/{id} - Delete a schedule")
+	s.Logger.Println("  GET  /admin/api/status - System status API")
+	s.Logger.Println("  GET  /admin/search   - Search content, schema, images and snippets (query: q, type)")
+	s.Logger.Println("  GET  /admin/files    - List files (test)")
+	s.Logger.Println("  POST /admin/test-storage - Test storage operations")
+	s.Logger.Println("  POST /admin/upload   - Upload image")
+	s.Logger.Println("  GET  /admin/images   - List images")
+	s.Logger.Println("  POST /admin/images/delete - Delete image (query: filename, force)")
+	s.Logger.Println("  GET  /admin/images/usage - Content fields and template lines using an image (query: filename)")
+	s.Logger.Println("  POST /admin/images/alt-text - Update image alt text")
+	s.Logger.Println("  POST /admin/images/suggest-alt-text - Suggest image alt text")
+	s.Logger.Println("  POST /admin/images/crop - Crop image")
+	s.Logger.Println("  POST /admin/images/rotate - Rotate image")
+	s.Logger.Println("  POST /admin/images/focal-point - Set image focal point")
+	s.Logger.Println("  POST /admin/images/bulk-delete - Delete several images as a job")
+	s.Logger.Println("  POST /admin/images/regenerate-variants - Regenerate image variants as a job")
+	s.Logger.Println("  GET/POST /admin/template - Template management")
+	s.Logger.Println("  GET  /admin/template/info - Template information")
+	s.Logger.Println("  POST /admin/template/compatibility - Compare a template with the schema (form: content, add_missing)")
+	s.Logger.Println("  GET  /admin/template/check - Template variables with no field behind them and schema fields never shown")
+	s.Logger.Println("  POST /admin/template/restore - Restore template")
+	s.Logger.Println("  GET  /admin/templates/gallery - Starter templates")
+	s.Logger.Println("  POST /admin/templates/apply - Replace schema, template and content with a starter, archiving the site first (admin)")
+	s.Logger.Println("  GET/POST /admin/section-renderers - List or register named section renderers")
+	s.Logger.Println("  DELETE /admin/section-renderers/{name} - Remove a registered section renderer")
+	s.Logger.Println("  POST /admin/test-template - Test template operations")
+	s.Logger.Println("  GET/POST /admin/content - Content management")
+	s.Logger.Println("  GET  /admin/content/info - Content information")
+	s.Logger.Println("  POST /admin/content/restore - Restore content")
+	s.Logger.Println("  POST /admin/content/heal - Fix trivial schema violations (query: dry_run)")
+	s.Logger.Println("  POST /admin/content/duplicate - Duplicate a section or array item")
+	s.Logger.Println("  GET  /admin/content/changes - Content patches since a revision (query: since)")
+	s.Logger.Println("  POST /admin/content/changes - Apply offline patches against a base revision")
+	s.Logger.Println("  POST /admin/content/section/archive - Archive a section")
+	s.Logger.Println("  POST /admin/content/section/restore - Restore an archived section")
+	s.Logger.Println("  GET /admin/content/sections/archived - List archived sections")
+	s.Logger.Println("  DELETE /admin/content/sections/archived/{key} - Delete an archived section")
+	s.Logger.Println("  POST /admin/content/form - Save content from a form-encoded or multipart post")
+	s.Logger.Println("  GET  /admin/basic/content - Content form without JavaScript")
+	s.Logger.Println("  GET/POST /admin/basic/template - Template editor without JavaScript")
+	s.Logger.Println("  GET/POST /admin/basic/images - Image upload without JavaScript")
+	s.Logger.Println("  GET  /admin/content/export - Export content (query: include_private)")
+	s.Logger.Println("  POST /admin/content/import - Import content")
+	s.Logger.Println("  POST /admin/content/import/mappings - Upload JSON or CSV to map onto the schema")
+	s.Logger.Println("  GET  /admin/content/import/mappings/{id} - Upload fields, schema targets and suggested rules")
+	s.Logger.Println("  POST /admin/content/import/mappings/{id}/preview - Content a mapping would build")
+	s.Logger.Println("  POST /admin/content/import/mappings/{id}/commit - Save the mapped content (or as_draft)")
+	s.Logger.Println("  DELETE /admin/content/import/mappings/{id} - Drop an upload")
+	s.Logger.Println("  POST /admin/content/auto-save - Auto-save unsaved edits for the current user")
+	s.Logger.Println("  GET  /admin/content/autosave - Recover the current user's auto-saved edits")
+	s.Logger.Println("  DELETE /admin/content/autosave - Discard the current user's auto-saved edits")
+	s.Logger.Println("  GET  /admin/content/draft - Unpublished content draft")
+	s.Logger.Println("  PUT  /admin/content/draft - Save the content draft")
+	s.Logger.Println("  DELETE /admin/content/draft - Discard the content draft")
+	s.Logger.Println("  POST /admin/content/draft/publish - Make the draft the content (query: force)")
+	s.Logger.Println("  GET  /admin/content/draft/preview - Page rendered with the draft")
+	s.Logger.Println("  GET  /admin/content/preview - Preview content")
+	s.Logger.Println("  POST /admin/test-content - Test content operations")
+	s.Logger.Println("  GET/POST /admin/schema - Schema management")
+	s.Logger.Println("  GET  /admin/schema/info - Schema information")
+	s.Logger.Println("  POST /admin/schema/restore - Restore schema")
+	s.Logger.Println("  GET  /admin/schema/export - Export schema (?format=json|typescript|zod)")
+	s.Logger.Println("  POST /admin/schema/import - Import schema")
+	s.Logger.Println("  POST /admin/schema/migrate - Migrate content to a schema: renames, defaults, orphans (query: dry_run)")
+	s.Logger.Println("  POST /admin/schema/validate - Validate data against schema")
+	s.Logger.Println("  GET  /admin/schema/form - Generate complete form from schema")
+	s.Logger.Println("  GET  /admin/schema/form-fields - Generate form fields from schema")
+	s.Logger.Println("  GET/POST /admin/schema/presets - List or add section presets")
+	s.Logger.Println("  GET/POST /admin/slugs - List or set section anchor slugs")
+	s.Logger.Println("  POST /admin/slugs/regenerate - Regenerate section slugs from titles")
+	s.Logger.Println("  GET/POST /admin/custom-code - Custom head and body-end code (admin only)")
+	s.Logger.Println("  GET/POST /admin/secrets - Encrypted integration secrets (admin only)")
+	s.Logger.Println("  DELETE /admin/secrets/{name} - Delete a secret (admin only)")
+	s.Logger.Println("  GET  /admin/doctor   - Check the data directory for problems (admin only)")
+	s.Logger.Println("  POST /admin/migration-backups/prune - Delete old pre-migration backups as a job (admin only)")
+	s.Logger.Println("  GET  /admin/export/archive - Download data files and images as a zip")
+	s.Logger.Println("  POST /admin/import/archive - Restore a zip archive as a job (admin only)")
+	s.Logger.Println("  GET  /admin/jobs     - List background jobs")
+	s.Logger.Println("  GET  /admin/jobs/{id} - Get job progress and errors")
+	s.Logger.Println("  GET  /admin/jobs/{id}/events - Stream job progress (server-sent events)")
+	s.Logger.Println("  POST /admin/test-schema - Test schema operations")
+	s.Logger.Println("  GET  /admin/schema/analyze - Comprehensive schema analysis")
+	s.Logger.Println("  GET  /admin/schema/field-metadata - Get field metadata (query: field)")
+	s.Logger.Println("  GET  /admin/schema/validation-rules - Get all validation rules")
+	s.Logger.Println("  GET  /admin/schema/field-types - Get field types mapping")
+	s.Logger.Println("  GET  /admin/schema/required-fields - Get required/optional fields")
+	s.Logger.Println("  POST /admin/schema/validate-field - Validate single field value")
+	s.Logger.Println("  POST /admin/schema/validate-content - Comprehensive content validation")
+	s.Logger.Println("  POST /admin/schema/validate-field-detailed - Detailed field validation")
+	s.Logger.Println("  POST /admin/schema/validation-report - Generate validation report")
+	s.Logger.Println("  GET  /admin/auth/status - Authentication status")
+	s.Logger.Println("  GET  /admin/auth/sessions - List active sessions")
+	s.Logger.Println("  POST /admin/auth/change-password - Change password")
+	s.Logger.Println("  GET  /admin/auth/recovery-codes - Recovery codes left (admin)")
+	s.Logger.Println("  POST /admin/auth/recovery-codes - Regenerate recovery codes (admin)")
+	s.Logger.Println("  GET  /admin/auth/passkeys - Registered passkeys (admin)")
+	s.Logger.Println("  POST /admin/auth/passkeys/register/begin - Start registering a passkey (admin)")
+	s.Logger.Println("  POST /admin/auth/passkeys/register/finish - Store a new passkey (admin)")
+	s.Logger.Println("  DELETE /admin/auth/passkeys/{id} - Remove a passkey (admin)")
+	s.Logger.Println("  GET  /admin/activity - Activity log, paginated and filtered (admin)")
+	s.Logger.Println("  GET  /admin/confirm - Confirmation token for a destructive action")
+	s.Logger.Println("  GET  /admin/auth/lockouts - Failed sign-ins and lockouts (admin)")
+	s.Logger.Println("  DELETE /admin/auth/lockouts - Clear one or all lockouts (admin)")
+	s.Logger.Println("  GET  /admin/plugins - Registered plugins, hooks and routes (admin)")
+
+	s.setupPluginRoutes()
+}
+
+// routeErrorWriter replaces the router's plain-text 404 and 405 responses
+// with problem+json, keeping the Allow header the router sets on 405s
+type routeErrorWriter struct {
+	http.ResponseWriter
+	server      *Server
+	request     *http.Request
+	intercepted bool
+}
+
+// WriteHeader swaps router errors for problem responses
+func (w *routeErrorWriter) WriteHeader(status int) {
+	switch status {
+	case http.StatusNotFound:
+		w.intercepted = true
+		w.server.writeError(w.ResponseWriter, w.request, status, types.ErrCodeNotFound, "Not found")
+	case http.StatusMethodNotAllowed:
+		w.intercepted = true
+		w.server.writeError(w.ResponseWriter, w.request, status, types.ErrCodeMethodNotAllowed, "Method not allowed")
+	default:
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+// Write discards the router's plain-text body once a problem has been sent
+func (w *routeErrorWriter) Write(b []byte) (int, error) {
+	if w.intercepted {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// serveRoutes dispatches requests to the router. Requests that match no
+// route get problem+json errors instead of the router's plain-text ones.
+func (s *Server) serveRoutes(w http.ResponseWriter, r *http.Request) {
+	if _, pattern := s.Mux.Handler(r); pattern == "" {
+		w = &routeErrorWriter{ResponseWriter: w, server: s, request: r}
+	}
+	s.Mux.ServeHTTP(w, r)
 }
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// scheduleInterval is how often the scheduler checks whether scheduled
+// content started or ended
+const scheduleInterval = time.Minute
+
+// runScheduler regenerates the site whenever the set of active schedules
+// differs from the one the live site was generated with, and applies the
+// retention policy once a day, until Stop
+func (s *Server) runScheduler() {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	s.applySchedules()
+	s.applyRetention()
+	for {
+		select {
+		case <-ticker.C:
+			s.applySchedules()
+			s.applyRetention()
+		case <-s.stopping:
+			return
+		}
+	}
+}
+
+// applySchedules regenerates the site if scheduled content changed
+func (s *Server) applySchedules() {
+	s.scheduling.Lock()
+	defer s.scheduling.Unlock()
+
+	needed, err := s.Schedules.NeedsRegeneration(s.Clock())
+	if err != nil {
+		s.Logger.Printf("Scheduler: %v", err)
+		s.heartbeatFail("Scheduler: " + err.Error())
+		return
+	}
+	if !needed {
+		s.heartbeatSuccess(false)
+		return
+	}
+
+	ctx := context.Background()
+	if s.Config.GenerateTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(s.Config.GenerateTimeout)*time.Second)
+		defer cancel()
+	}
+	if _, err := s.publishSite(ctx, "scheduled content started or ended"); err != nil {
+		s.Logger.Printf("Scheduler: site generation failed: %v", err)
+		s.heartbeatFail("Scheduler: site generation failed: " + err.Error())
+		return
+	}
+	s.logActivity(ctx, "Site Generated", "Site was regenerated because scheduled content started or ended")
+}
+
+// parseScheduleTime reads a schedule bound given as RFC 3339 or as a date
+// in the site time zone. A date end bound includes the whole day.
+func (s *Server) parseScheduleTime(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", value, s.location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a date (2006-01-02) nor an RFC 3339 time", value)
+	}
+	if end {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
+
+// handleSchedulesList returns every schedule, marking the active ones
+func (s *Server) handleSchedulesList(w http.ResponseWriter, r *http.Request) {
+	schedules, err := s.Schedules.List()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+
+	now := s.Clock()
+	active := make([]string, 0)
+	for _, schedule := range schedules {
+		if schedule.ActiveAt(now) {
+			active = append(active, schedule.ID)
+		}
+	}
+
+	response := types.NewAPIResponse(true, "Schedules retrieved")
+	response.SetData(schedules)
+	response.Meta["active"] = active
+	response.Meta["timezone"] = s.location.String()
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleScheduleCreate schedules a value for a section or field. The body
+// is {"path", "value", "starts_at", "ends_at", "note"}; bounds are dates
+// in the site time zone or RFC 3339 times, and either may be left out.
+// The content with the value in place must pass validation.
+func (s *Server) handleScheduleCreate(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		Path     string      `json:"path"`
+		Value    interface{} `json:"value"`
+		StartsAt string      `json:"starts_at"`
+		EndsAt   string      `json:"ends_at"`
+		Note     string      `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON in request body")
+		return
+	}
+	startsAt, err := s.parseScheduleTime(requestData.StartsAt, false)
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "starts_at: "+err.Error())
+		return
+	}
+	endsAt, err := s.parseScheduleTime(requestData.EndsAt, true)
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "ends_at: "+err.Error())
+		return
+	}
+	if startsAt.IsZero() && endsAt.IsZero() {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "starts_at or ends_at is required")
+		return
+	}
+
+	content, err := s.ContentManager.LoadContent()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load content: "+err.Error())
+		return
+	}
+	candidate := map[string]interface{}{
+		"title":       content.Title,
+		"description": content.Description,
+		"sections":    content.Sections,
+	}
+	if err := managers.ApplyContentValue(candidate, requestData.Path, requestData.Value); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	validationResult, err := s.SchemaManager.ValidateContentDetailed(candidate)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Validation failed: "+err.Error())
+		return
+	}
+	if !validationResult.Valid {
+		s.writeContentInvalid(w, r, validationResult)
+		return
+	}
+
+	session, _ := types.SessionFromContext(r.Context())
+	schedule, err := s.Schedules.Add(managers.ContentSchedule{
+		Path:      requestData.Path,
+		Value:     requestData.Value,
+		StartsAt:  startsAt,
+		EndsAt:    endsAt,
+		Note:      requestData.Note,
+		CreatedBy: session.Username,
+	})
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	s.logActivity(r.Context(), "Content Scheduled", fmt.Sprintf("Scheduled %s", schedule.Path))
+
+	// A schedule that is already active goes live right away
+	go s.applySchedules()
+
+	response := types.NewAPIResponse(true, "Content scheduled")
+	response.SetData(schedule)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleScheduleDelete removes a schedule; if it was active, the site is
+// regenerated without it
+func (s *Server) handleScheduleDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	deleted, err := s.Schedules.Delete(id)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+	if !deleted {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, fmt.Sprintf("Schedule %q not found", id))
+		return
+	}
+
+	s.logActivity(r.Context(), "Schedule Deleted", fmt.Sprintf("Deleted schedule %s", id))
+	go s.applySchedules()
+
+	response := types.NewAPIResponse(true, "Schedule deleted")
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
@@ -2,128 +2,137 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 
+	"onepagems/internal/managers"
 	"onepagems/internal/types"
 )
 
-// handleSchema handles schema management requests
-func (s *Server) handleSchema(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case "GET":
-		// Load and return current schema
-		schema, err := s.SchemaManager.LoadSchema()
-		if err != nil {
-			response := types.NewAPIResponse(false, "Failed to load schema: "+err.Error())
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(response)
-			return
-		}
+// handleSchemaGet loads and returns the current schema
+func (s *Server) handleSchemaGet(w http.ResponseWriter, r *http.Request) {
+	schema, err := s.SchemaManager.LoadSchema()
+	if err != nil {
+		response := types.NewAPIResponse(false, "Failed to load schema: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
+		return
+	}
 
-		response := types.NewAPIResponse(true, "Schema loaded successfully")
-		response.SetData(schema)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
-
-	case "POST":
-		// Update schema
-		var updates map[string]interface{}
-		if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-			response := types.NewAPIResponse(false, "Invalid JSON in request body: "+err.Error())
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(response)
-			return
-		}
+	response := types.NewAPIResponse(true, "Schema loaded successfully")
+	response.SetData(schema)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeCachedResponse(w, r, response)
+}
+
+// handleSchemaPost updates the schema
+func (s *Server) handleSchemaPost(w http.ResponseWriter, r *http.Request) {
+	var updates map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		response := types.NewAPIResponse(false, "Invalid JSON in request body: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
 
-		if err := s.SchemaManager.UpdateSchema(updates); err != nil {
-			response := types.NewAPIResponse(false, "Failed to update schema: "+err.Error())
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(response)
+	if err := s.SchemaManager.UpdateSchema(updates); err != nil {
+		if errors.Is(err, managers.ErrSchemaTooLarge) || errors.Is(err, managers.ErrSchemaCycle) || errors.Is(err, managers.ErrSchemaRef) {
+			s.writeError(w, r, http.StatusUnprocessableEntity, types.ErrCodeValidationFailed, err.Error())
 			return
 		}
+		response := types.NewAPIResponse(false, "Failed to update schema: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
+		return
+	}
 
-		response := types.NewAPIResponse(true, "Schema updated successfully")
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+	s.logActivity(r.Context(), "Schema Updated", fmt.Sprintf("Schema updated (%d key(s) changed)", len(updates)))
 
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
+	response := types.NewAPIResponse(true, "Schema updated successfully")
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
 }
 
 // handleSchemaInfo returns information about the current schema
 func (s *Server) handleSchemaInfo(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	info, err := s.SchemaManager.GetSchemaInfo()
 	if err != nil {
 		response := types.NewAPIResponse(false, "Failed to get schema information: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
 		return
 	}
 
 	response := types.NewAPIResponse(true, "Schema information retrieved")
 	response.SetData(info)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
 
 // handleSchemaRestore restores schema from backup
 func (s *Server) handleSchemaRestore(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !s.requireRecentAuth(w, r) {
+		return
+	}
+	if !s.requireConfirmation(w, r, "schema.restore", "", nil) {
 		return
 	}
-
 	if err := s.SchemaManager.RestoreSchema(); err != nil {
 		response := types.NewAPIResponse(false, "Failed to restore schema: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
 		return
 	}
 
+	s.logActivity(r.Context(), "Schema Restored", "Schema restored from backup")
+
 	response := types.NewAPIResponse(true, "Schema restored from backup successfully")
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
 
 // handleSchemaExport exports schema as JSON
 func (s *Server) handleSchemaExport(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+	case managers.TypeExportTypeScript, managers.TypeExportZod:
+		s.handleSchemaTypesExport(w, r, format)
+		return
+	default:
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, fmt.Sprintf("Unknown format %q: use json, typescript or zod", format))
 		return
 	}
 
-	data, err := s.SchemaManager.ExportSchema()
+	schema, err := s.SchemaManager.LoadSchema()
 	if err != nil {
 		response := types.NewAPIResponse(false, "Failed to export schema: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Content-Disposition", "attachment; filename=schema-export.json")
-	w.Write(data)
+	if err := managers.EncodeExport(w, schema); err != nil {
+		s.Logger.Printf("Schema export failed: %v", err)
+	}
 }
 
-// handleSchemaImport imports schema from JSON
-func (s *Server) handleSchemaImport(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// handleSchemaTypesExport serves TypeScript or zod definitions of the public
+// content, for frontends consuming /content.json
+func (s *Server) handleSchemaTypesExport(w http.ResponseWriter, r *http.Request, format string) {
+	source, err := s.SchemaManager.ExportClientTypes(format)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to generate types: "+err.Error())
 		return
 	}
 
+	filename := "content.d.ts"
+	if format == managers.TypeExportZod {
+		filename = "content.zod.ts"
+	}
+	w.Header().Set("Content-Type", "application/typescript; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	w.Write([]byte(source))
+}
+
+// handleSchemaImport imports schema from JSON
+func (s *Server) handleSchemaImport(w http.ResponseWriter, r *http.Request) {
 	// Read the request body
 	var requestData struct {
 		Schema json.RawMessage `json:"schema"`
@@ -131,32 +140,43 @@ func (s *Server) handleSchemaImport(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
 		response := types.NewAPIResponse(false, "Invalid JSON in request body: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
 		return
 	}
 
+	// Only an import that leaves content fields out of the editor needs
+	// confirming
+	var imported types.SchemaData
+	if err := json.Unmarshal(requestData.Schema, &imported); err == nil {
+		if content, err := s.ContentManager.LoadContent(); err == nil {
+			if dropped := managers.SchemaDroppedFields(&imported, content); len(dropped) > 0 {
+				details := map[string]interface{}{"dropped_fields": dropped}
+				if !s.requireConfirmation(w, r, "schema.import", "", details) {
+					return
+				}
+			}
+		}
+	}
+
 	if err := s.SchemaManager.ImportSchema(requestData.Schema); err != nil {
+		if errors.Is(err, managers.ErrSchemaTooLarge) || errors.Is(err, managers.ErrSchemaCycle) || errors.Is(err, managers.ErrSchemaRef) {
+			s.writeError(w, r, http.StatusUnprocessableEntity, types.ErrCodeValidationFailed, err.Error())
+			return
+		}
 		response := types.NewAPIResponse(false, "Failed to import schema: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
 		return
 	}
 
+	s.logActivity(r.Context(), "Schema Imported", "Schema replaced by an import")
+
 	response := types.NewAPIResponse(true, "Schema imported successfully")
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
 
 // handleSchemaValidate validates content against the current schema
 func (s *Server) handleSchemaValidate(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	// Read the request body
 	var requestData struct {
 		Data interface{} `json:"data"`
@@ -164,62 +184,46 @@ func (s *Server) handleSchemaValidate(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
 		response := types.NewAPIResponse(false, "Invalid JSON in request body: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
 		return
 	}
 
 	if err := s.SchemaManager.ValidateAgainstSchema(requestData.Data); err != nil {
 		response := types.NewAPIResponse(false, "Validation failed: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
 		return
 	}
 
 	response := types.NewAPIResponse(true, "Data is valid against schema")
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
 
 // handleSchemaForm generates complete form structure from schema
 func (s *Server) handleSchemaForm(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	form, err := s.SchemaManager.GenerateCompleteForm()
 	if err != nil {
 		response := types.NewAPIResponse(false, "Failed to generate form from schema: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
 		return
 	}
+	form.Fields = s.withoutArchivedSections(form.Fields)
 
 	response := types.NewAPIResponse(true, "Form generated from schema")
 	response.SetData(form)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeCachedResponse(w, r, response)
 }
 
 // handleSchemaFormFields generates just the form fields array from schema
 func (s *Server) handleSchemaFormFields(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	fields, err := s.SchemaManager.GenerateFormFromSchema()
 	if err != nil {
 		response := types.NewAPIResponse(false, "Failed to generate form fields from schema: "+err.Error())
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		s.writeErrorResponse(w, r, http.StatusInternalServerError, response)
 		return
 	}
+	fields = s.withoutArchivedSections(fields)
 
 	response := types.NewAPIResponse(true, "Form fields generated from schema")
 	response.SetData(map[string]interface{}{
@@ -227,16 +231,11 @@ func (s *Server) handleSchemaFormFields(w http.ResponseWriter, r *http.Request)
 		"count":  len(fields),
 	})
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
 
 // handleTestSchema tests schema management operations
 func (s *Server) handleTestSchema(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	results := make(map[string]interface{})
 
 	// Test 1: Load current schema
@@ -299,5 +298,5 @@ func (s *Server) handleTestSchema(w http.ResponseWriter, r *http.Request) {
 	response.SetData(results)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// handleSchemaMigrate migrates the content to a schema. The body is
+// {"schema", "renames", "add_defaults", "drop_orphans"}: schema replaces
+// the current one, which is diffed with it; without it the content is
+// brought in line with the current schema. With ?dry_run=true only the
+// report is returned. Otherwise the migrated content must validate, and
+// removing values needs the schema.migrate confirmation.
+func (s *Server) handleSchemaMigrate(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	var requestData struct {
+		Schema json.RawMessage `json:"schema"`
+		managers.SchemaMigrationOptions
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON in request body")
+		return
+	}
+
+	current, err := s.SchemaManager.LoadSchema()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load schema: "+err.Error())
+		return
+	}
+	target := current
+	if len(requestData.Schema) > 0 && string(requestData.Schema) != "null" {
+		target = &types.SchemaData{}
+		if err := json.Unmarshal(requestData.Schema, target); err != nil {
+			s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid schema: "+err.Error())
+			return
+		}
+	}
+
+	content, err := s.ContentManager.LoadContent()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load content: "+err.Error())
+		return
+	}
+	contentMap := draftContentMap(content)
+
+	report, err := s.SchemaManager.MigrateContent(current, target, contentMap, requestData.SchemaMigrationOptions)
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	report.DryRun = dryRun
+
+	if dryRun {
+		response := types.NewAPIResponse(true, fmt.Sprintf("%d schema change(s), %d content change(s) planned", len(report.Changes), len(report.Actions)))
+		response.SetData(report)
+		w.Header().Set("Content-Type", "application/json")
+		s.encodeResponse(w, r, response)
+		return
+	}
+
+	if !report.Validation.Valid {
+		response := types.NewAPIResponse(false, "The migrated content does not validate against the schema")
+		response.SetData(report)
+		s.writeErrorResponse(w, r, http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	var dropped []string
+	for _, action := range report.Actions {
+		if action.Action == managers.MigrationDrop {
+			dropped = append(dropped, action.Path)
+		}
+	}
+	dropped = append(dropped, report.Impacted...)
+	if len(dropped) > 0 {
+		details := map[string]interface{}{"dropped_fields": dropped}
+		if !s.requireConfirmation(w, r, "schema.migrate", "", details) {
+			return
+		}
+	}
+
+	contentData := &types.ContentData{}
+	if err := s.mapToContentData(contentMap, contentData); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to process content: "+err.Error())
+		return
+	}
+	if err := s.Plugins.ContentSave(r.Context(), contentData); err != nil {
+		s.writeError(w, r, http.StatusUnprocessableEntity, types.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	schemaChanged := target != current
+	if schemaChanged {
+		if err := s.SchemaManager.SaveSchema(target); err != nil {
+			s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to save schema: "+err.Error())
+			return
+		}
+	}
+	if err := s.ContentManager.SaveContent(contentData); err != nil {
+		// Keep the schema and content in step
+		if schemaChanged {
+			if restoreErr := s.SchemaManager.RestoreSchema(); restoreErr != nil {
+				s.Logger.Printf("Schema migration: failed to restore the schema: %v", restoreErr)
+			}
+		}
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to save content: "+err.Error())
+		return
+	}
+	report.Saved = true
+
+	s.logActivity(r.Context(), "Schema Migrated", fmt.Sprintf("Content migrated with %d change(s)", len(report.Actions)))
+
+	response := types.NewAPIResponse(true, fmt.Sprintf("Content migrated with %d change(s)", len(report.Actions)))
+	response.SetData(report)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
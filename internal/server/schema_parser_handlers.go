@@ -4,20 +4,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+
+	"onepagems/internal/types"
 )
 
 // Schema Parser Handlers
 
 // handleSchemaAnalyze returns comprehensive schema analysis
 func (s *Server) handleSchemaAnalyze(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	analysis, err := s.SchemaManager.ParseSchemaDetailed()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to analyze schema: %v", err), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to analyze schema: %v", err))
 		return
 	}
 
@@ -27,20 +24,15 @@ func (s *Server) handleSchemaAnalyze(w http.ResponseWriter, r *http.Request) {
 
 // handleSchemaFieldMetadata returns metadata for a specific field
 func (s *Server) handleSchemaFieldMetadata(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	fieldName := r.URL.Query().Get("field")
 	if fieldName == "" {
-		http.Error(w, "Field name is required", http.StatusBadRequest)
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Field name is required")
 		return
 	}
 
 	metadata, err := s.SchemaManager.GetFieldMetadata(fieldName)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get field metadata: %v", err), http.StatusNotFound)
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, fmt.Sprintf("Failed to get field metadata: %v", err))
 		return
 	}
 
@@ -50,14 +42,9 @@ func (s *Server) handleSchemaFieldMetadata(w http.ResponseWriter, r *http.Reques
 
 // handleSchemaValidationRules returns all validation rules for the schema
 func (s *Server) handleSchemaValidationRules(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	rules, err := s.SchemaManager.GetValidationRules()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get validation rules: %v", err), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to get validation rules: %v", err))
 		return
 	}
 
@@ -70,14 +57,9 @@ func (s *Server) handleSchemaValidationRules(w http.ResponseWriter, r *http.Requ
 
 // handleSchemaFieldTypes returns field types mapping
 func (s *Server) handleSchemaFieldTypes(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	fieldTypes, err := s.SchemaManager.GetSchemaFieldTypes()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get field types: %v", err), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to get field types: %v", err))
 		return
 	}
 
@@ -90,20 +72,15 @@ func (s *Server) handleSchemaFieldTypes(w http.ResponseWriter, r *http.Request)
 
 // handleSchemaRequiredFields returns required and optional fields
 func (s *Server) handleSchemaRequiredFields(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	requiredFields, err := s.SchemaManager.GetRequiredFields()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get required fields: %v", err), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to get required fields: %v", err))
 		return
 	}
 
 	optionalFields, err := s.SchemaManager.GetOptionalFields()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get optional fields: %v", err), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to get optional fields: %v", err))
 		return
 	}
 
@@ -117,29 +94,24 @@ func (s *Server) handleSchemaRequiredFields(w http.ResponseWriter, r *http.Reque
 
 // handleSchemaValidateField validates a field value against schema
 func (s *Server) handleSchemaValidateField(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var requestData struct {
 		FieldName string      `json:"field_name"`
 		Value     interface{} `json:"value"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
-		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON data")
 		return
 	}
 
 	if requestData.FieldName == "" {
-		http.Error(w, "Field name is required", http.StatusBadRequest)
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Field name is required")
 		return
 	}
 
 	validationFailures, err := s.SchemaManager.ValidateFieldValue(requestData.FieldName, requestData.Value)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to validate field: %v", err), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to validate field: %v", err))
 		return
 	}
 
@@ -158,23 +130,18 @@ func (s *Server) handleSchemaValidateField(w http.ResponseWriter, r *http.Reques
 
 // handleSchemaValidateContent validates entire content using comprehensive validator
 func (s *Server) handleSchemaValidateContent(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var requestData struct {
 		Content interface{} `json:"content"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
-		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON data")
 		return
 	}
 
 	validationResult, err := s.SchemaManager.ValidateContentDetailed(requestData.Content)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to validate content: %v", err), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to validate content: %v", err))
 		return
 	}
 
@@ -184,29 +151,24 @@ func (s *Server) handleSchemaValidateContent(w http.ResponseWriter, r *http.Requ
 
 // handleSchemaValidateFieldDetailed validates a field value using comprehensive validator
 func (s *Server) handleSchemaValidateFieldDetailed(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var requestData struct {
 		FieldName string      `json:"field_name"`
 		Value     interface{} `json:"value"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
-		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON data")
 		return
 	}
 
 	if requestData.FieldName == "" {
-		http.Error(w, "Field name is required", http.StatusBadRequest)
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Field name is required")
 		return
 	}
 
 	validationResult, err := s.SchemaManager.ValidateFieldValueDetailed(requestData.FieldName, requestData.Value)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to validate field: %v", err), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to validate field: %v", err))
 		return
 	}
 
@@ -216,23 +178,18 @@ func (s *Server) handleSchemaValidateFieldDetailed(w http.ResponseWriter, r *htt
 
 // handleSchemaValidationReport generates a comprehensive validation report
 func (s *Server) handleSchemaValidationReport(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var requestData struct {
 		Content interface{} `json:"content"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
-		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON data")
 		return
 	}
 
 	report, err := s.SchemaManager.GenerateValidationReport(requestData.Content)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to generate validation report: %v", err), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to generate validation report: %v", err))
 		return
 	}
 
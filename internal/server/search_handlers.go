@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// searchListSpec sorts and filters GET /admin/search. Hits come grouped by
+// type in the order the search finds them, which the default sort keeps.
+var searchListSpec = listSpec[managers.AdminSearchHit]{
+	Sorts: map[string]func(a, b managers.AdminSearchHit) int{
+		"type": compareBy(func(h managers.AdminSearchHit) string { return h.Type }),
+		"path": compareBy(func(h managers.AdminSearchHit) string { return h.Path }),
+	},
+	DefaultSort: "type",
+	Text: func(h managers.AdminSearchHit) []string {
+		return []string{h.Path, h.Excerpt}
+	},
+}
+
+// handleAdminSearch searches content, schema titles and descriptions, images
+// and, for the admin user, the custom code snippets for ?q=. ?type= keeps
+// hits of one type (content, schema, image or snippet).
+func (s *Server) handleAdminSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "q is required")
+		return
+	}
+	hitType := r.URL.Query().Get("type")
+	switch hitType {
+	case "", managers.SearchHitContent, managers.SearchHitSchema, managers.SearchHitImage, managers.SearchHitSnippet:
+	default:
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "type must be content, schema, image or snippet")
+		return
+	}
+
+	schema, err := s.SchemaManager.LoadSchema()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load schema: "+err.Error())
+		return
+	}
+	content, err := s.ContentManager.LoadContent()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load content: "+err.Error())
+		return
+	}
+	images, err := s.ImageManager.ListImages()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to list images: "+err.Error())
+		return
+	}
+
+	// Custom code is only visible to the admin user
+	var code *types.CustomCode
+	if session, ok := types.SessionFromContext(r.Context()); ok && session.Username == s.Config.AdminUsername {
+		if code, err = s.CustomCode.Load(); err != nil {
+			s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load custom code: "+err.Error())
+			return
+		}
+	}
+
+	hits := managers.SearchAdminData(query, schema, content, images, code)
+	if hitType != "" {
+		matched := hits[:0]
+		for _, hit := range hits {
+			if hit.Type == hitType {
+				matched = append(matched, hit)
+			}
+		}
+		hits = matched
+	}
+
+	hits, page, ok := paginateList(s, w, r, hits, searchListSpec)
+	if !ok {
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Search completed")
+	response.SetData(map[string]interface{}{
+		"query": query,
+		"hits":  hits,
+	})
+	response.Meta["pagination"] = page
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// secretListSpec sorts and filters GET /admin/secrets
+var secretListSpec = listSpec[managers.SecretInfo]{
+	Sorts: map[string]func(a, b managers.SecretInfo) int{
+		"name":       compareBy(func(i managers.SecretInfo) string { return i.Name }),
+		"updated_at": func(a, b managers.SecretInfo) int { return a.UpdatedAt.Compare(b.UpdatedAt) },
+	},
+	DefaultSort: "name",
+	Text:        func(i managers.SecretInfo) []string { return []string{i.Name} },
+}
+
+// handleSecretsList lists the stored secrets by name. Values are never
+// returned; integrations read them inside the server.
+func (s *Server) handleSecretsList(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	secrets, err := s.Secrets.List()
+	if err != nil {
+		s.writeSecretsError(w, r, err)
+		return
+	}
+
+	secrets, page, ok := paginateList(s, w, r, secrets, secretListSpec)
+	if !ok {
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Secrets retrieved")
+	response.SetData(map[string]interface{}{
+		"secrets": secrets,
+	})
+	response.Meta["pagination"] = page
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleSecretsSet stores a secret, replacing any previous value
+func (s *Server) handleSecretsSet(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	if !s.requireRecentAuth(w, r) {
+		return
+	}
+
+	var requestData struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 64*1024)
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		response := types.NewAPIResponse(false, "Invalid JSON in request body: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	session, _ := types.SessionFromContext(r.Context())
+	info, err := s.Secrets.Set(requestData.Name, requestData.Value, session.Username)
+	if err != nil {
+		s.writeSecretsError(w, r, err)
+		return
+	}
+
+	s.logActivity(r.Context(), "Secret Updated", fmt.Sprintf("%s set secret %s", info.UpdatedBy, info.Name))
+
+	response := types.NewAPIResponse(true, "Secret saved")
+	response.SetData(map[string]interface{}{
+		"secret": info,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleSecretsDelete removes a secret
+func (s *Server) handleSecretsDelete(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	if !s.requireRecentAuth(w, r) {
+		return
+	}
+
+	name := r.PathValue("name")
+	if err := s.Secrets.Delete(name); err != nil {
+		s.writeSecretsError(w, r, err)
+		return
+	}
+
+	session, _ := types.SessionFromContext(r.Context())
+	s.logActivity(r.Context(), "Secret Deleted", fmt.Sprintf("%s deleted secret %s", session.Username, name))
+
+	response := types.NewAPIResponse(true, "Secret deleted")
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// writeSecretsError maps secret store errors to responses. Storage and
+// decryption failures are logged, not returned.
+func (s *Server) writeSecretsError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, managers.ErrSecretsDisabled):
+		s.writeError(w, r, http.StatusServiceUnavailable, types.ErrCodeInvalidRequest, err.Error())
+	case errors.Is(err, managers.ErrSecretNotFound):
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, err.Error())
+	case errors.Is(err, managers.ErrSecretInvalid):
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeValidationFailed, err.Error())
+	default:
+		s.Logger.Printf("Secrets store error: %v", err)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Secrets store error")
+	}
+}
+
+// secretLookup returns a function reading a secret for an integration,
+// empty when it is not set or secrets are not configured
+func (s *Server) secretLookup(name string) func() string {
+	return func() string {
+		value, err := s.Secrets.Get(name)
+		if err != nil {
+			if !errors.Is(err, managers.ErrSecretNotFound) && !errors.Is(err, managers.ErrSecretsDisabled) {
+				s.Logger.Printf("Failed to read secret %s: %v", name, err)
+			}
+			return ""
+		}
+		return value
+	}
+}
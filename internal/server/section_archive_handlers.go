@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// decodeSectionKey reads the {"key"} body shared by the archive endpoints
+func (s *Server) decodeSectionKey(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var requestData struct {
+		Key string `json:"key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON in request body")
+		return "", false
+	}
+	key := strings.TrimSpace(requestData.Key)
+	if key == "" {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "key is required")
+		return "", false
+	}
+	return key, true
+}
+
+// writeSectionArchiveError maps section archive errors to statuses
+func (s *Server) writeSectionArchiveError(w http.ResponseWriter, r *http.Request, key string, err error) {
+	switch {
+	case errors.Is(err, managers.ErrSectionNotFound):
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, fmt.Sprintf("Section %q not found", key))
+	case errors.Is(err, managers.ErrSectionExists), errors.Is(err, managers.ErrSectionRequired):
+		s.writeError(w, r, http.StatusConflict, types.ErrCodeConflict, fmt.Sprintf("Section %q: %v", key, err))
+	default:
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+	}
+}
+
+// handleSectionArchive takes a section off the page without deleting it.
+// The section leaves the content, and so the generated site and the
+// editing forms, and is kept in the archive until restored. Sections the
+// schema requires cannot be archived.
+func (s *Server) handleSectionArchive(w http.ResponseWriter, r *http.Request) {
+	key, ok := s.decodeSectionKey(w, r)
+	if !ok {
+		return
+	}
+	if s.SchemaManager.SectionRequired(key) {
+		s.writeSectionArchiveError(w, r, key, managers.ErrSectionRequired)
+		return
+	}
+
+	session, _ := types.SessionFromContext(r.Context())
+	archived, err := s.ContentManager.ArchiveSection(key, session.Username)
+	if err != nil {
+		s.writeSectionArchiveError(w, r, key, err)
+		return
+	}
+
+	s.logActivity(r.Context(), "Section Archived", fmt.Sprintf("Archived section %s", key))
+
+	response := types.NewAPIResponse(true, "Section archived")
+	response.SetData(archived)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleSectionRestore puts an archived section back on the page. It fails
+// with 409 if a section with the same key was added in the meantime.
+func (s *Server) handleSectionRestore(w http.ResponseWriter, r *http.Request) {
+	key, ok := s.decodeSectionKey(w, r)
+	if !ok {
+		return
+	}
+
+	restored, err := s.ContentManager.RestoreSection(key)
+	if err != nil {
+		s.writeSectionArchiveError(w, r, key, err)
+		return
+	}
+
+	s.logActivity(r.Context(), "Section Restored", fmt.Sprintf("Restored section %s", key))
+
+	response := types.NewAPIResponse(true, "Section restored")
+	response.SetData(restored)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleSectionsArchived lists archived sections, newest first
+func (s *Server) handleSectionsArchived(w http.ResponseWriter, r *http.Request) {
+	sections, err := s.ContentManager.ArchivedSections()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Archived sections retrieved")
+	response.SetData(sections)
+	response.Meta["count"] = len(sections)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleSectionArchivedDelete permanently deletes an archived section
+func (s *Server) handleSectionArchivedDelete(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	if err := s.ContentManager.DeleteArchivedSection(key); err != nil {
+		s.writeSectionArchiveError(w, r, key, err)
+		return
+	}
+
+	s.logActivity(r.Context(), "Archived Section Deleted", fmt.Sprintf("Deleted archived section %s", key))
+
+	response := types.NewAPIResponse(true, "Archived section deleted")
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// withoutArchivedSections drops form fields of archived sections, which a
+// schema still describes but the content no longer holds
+func (s *Server) withoutArchivedSections(fields []types.FormField) []types.FormField {
+	sections, err := s.ContentManager.ArchivedSections()
+	if err != nil || len(sections) == 0 {
+		return fields
+	}
+
+	kept := make([]types.FormField, 0, len(fields))
+	for _, field := range fields {
+		archived := false
+		for _, section := range sections {
+			prefix := "sections." + section.Key
+			if field.Name == prefix || strings.HasPrefix(field.Name, prefix+".") || strings.HasPrefix(field.Name, prefix+"[") {
+				archived = true
+				break
+			}
+		}
+		if !archived {
+			kept = append(kept, field)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"onepagems/internal/types"
+)
+
+// handleSectionRenderersList lists the built-in and registered section
+// renderers
+func (s *Server) handleSectionRenderersList(w http.ResponseWriter, r *http.Request) {
+	renderers, err := s.Renderers.List()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Section renderers retrieved")
+	response.SetData(map[string]interface{}{
+		"renderers": renderers,
+		"count":     len(renderers),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleSectionRendererRegister registers a named section renderer, such as
+// one that comes with a theme. A renderer named like a built-in one
+// replaces it.
+func (s *Server) handleSectionRendererRegister(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Source      string `json:"source"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		response := types.NewAPIResponse(false, "Invalid JSON in request body: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	renderer, err := s.Renderers.Register(requestData.Name, requestData.Description, requestData.Source)
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, fmt.Sprintf("Failed to register section renderer: %v", err))
+		return
+	}
+	s.logActivity(r.Context(), "Template Updated", "Registered section renderer "+renderer.Name)
+
+	response := types.NewAPIResponse(true, "Section renderer registered")
+	response.SetData(renderer)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleSectionRendererRemove removes a registered section renderer
+func (s *Server) handleSectionRendererRemove(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := s.Renderers.Remove(name); err != nil {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, err.Error())
+		return
+	}
+	s.logActivity(r.Context(), "Template Updated", "Removed section renderer "+name)
+
+	response := types.NewAPIResponse(true, "Section renderer removed")
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
@@ -1,12 +1,20 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"onepagems/internal/httpclient"
 	"onepagems/internal/managers"
+	"onepagems/internal/tracing"
 	"onepagems/internal/types"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Server represents the HTTP server
@@ -16,41 +24,443 @@ type Server struct {
 	TemplateManager *managers.TemplateManager
 	ContentManager  *managers.ContentManager
 	SchemaManager   *managers.SchemaManager
-	AuthManager     *managers.AuthManager
+	AuthManager     AuthProvider
+	ImageManager    *managers.ImageManager
+	CustomCode      *managers.CustomCodeManager
+	Slugs           *managers.SlugManager
+	Variants        *managers.VariantManager
+	Schedules       *managers.ScheduleManager
+	Renderers       *managers.SectionRendererManager
+	Autosaves       *managers.AutosaveManager
+	Recovery        *managers.RecoveryManager
+	Passkeys        *managers.PasskeyManager
+	ImportMappings  *managers.ImportMappingManager
+	Notifier        *managers.PublishNotifier
+	Webhooks        *managers.WebhookDispatcher
+	Outbox          *managers.Outbox
+	Heartbeat       *managers.Heartbeat
+	ExportSigner    *managers.ExportSigner
+	Secrets         *managers.SecretManager
+	Jobs            *managers.JobManager
+	Activity        *managers.ActivityLogger
+	Retention       *managers.RetentionManager
+	Plugins         *managers.PluginManager
+	Generator       *managers.SiteGenerator
+	Live            *managers.LiveRenderer    // set when RENDER_MODE is live
+	Autocert        *managers.AutocertManager // set when AUTOCERT_HOSTS is set
+	HTTPClients     *httpclient.Clients       // outbound calls, with the proxy settings
 	Mux             *http.ServeMux
+	Logger          *log.Logger
+	Clock           func() time.Time
+
+	middleware    []Middleware          // see Use
+	credentials   *managers.AuthManager // the default auth provider, loaded by Load
+	settingsErr   error                 // settings NewServer could not apply; Load returns it
+	loadOnce      sync.Once
+	loadErr       error
+	csrfKey       []byte
+	rateLimits    *rateLimiter
+	loginGuard    *loginGuard
+	confirmations *usedConfirmations // spent confirmation tokens
+	location      *time.Location     // site time zone for displayed dates
+	startedAt     time.Time
+	scheduling    sync.Mutex // one scheduled regeneration at a time
+
+	loginAlerts   map[string]time.Time // last login alert by client address
+	loginAlertsMu sync.Mutex
+
+	httpServer     *http.Server
+	redirectServer *http.Server  // plain HTTP to HTTPS, when TLS is on
+	stopping       chan struct{} // closed by Stop to end the background loops
+	outboxWake     chan struct{} // signals the outbox loop that a message was queued
+	stopOnce       sync.Once
+	background     sync.WaitGroup // background loops started by Start
 }
 
-// NewServer creates a new server instance
-func NewServer(config *types.Config) *Server {
-	storage := managers.NewFileStorage(config.DataDir)
-	server := &Server{
-		Config:          config,
-		Storage:         storage,
-		TemplateManager: managers.NewTemplateManager(storage),
-		ContentManager:  managers.NewContentManager(storage, config.DataDir),
-		SchemaManager:   managers.NewSchemaManager(storage, config.DataDir),
-		AuthManager:     managers.NewAuthManager(config),
-		Mux:             http.NewServeMux(),
+// NewServer creates a new server instance. Options replace the default
+// storage, authentication, logger, clock or router, or add plugins.
+func NewServer(config *types.Config, opts ...Option) *Server {
+	server := &Server{Config: config, csrfKey: newCSRFKey(), rateLimits: newRateLimiter(), loginGuard: newLoginGuard(), confirmations: newUsedConfirmations(), Plugins: managers.NewPluginManager(), loginAlerts: make(map[string]time.Time), stopping: make(chan struct{}), outboxWake: make(chan struct{}, 1)}
+	for _, opt := range opts {
+		opt(server)
+	}
+
+	if server.Storage == nil {
+		server.Storage = managers.NewFileStorage(config.DataDir)
+	}
+	if server.Logger == nil {
+		server.Logger = log.Default()
+	}
+	if server.AuthManager == nil {
+		auth := managers.NewAuthManager(config)
+		if config.PersistSessions {
+			auth.SetSessionStore(managers.NewFileSessionStore(server.Storage))
+		}
+		// A password changed from the admin outlives ADMIN_PASSWORD; Load
+		// reads it
+		auth.SetCredentialStore(managers.NewFileCredentialStore(server.Storage))
+		server.credentials = auth
+		server.AuthManager = auth
+	}
+	if server.Clock == nil {
+		server.Clock = time.Now
+	}
+	if server.Mux == nil {
+		server.Mux = http.NewServeMux()
+	}
+
+	server.startedAt = server.Clock()
+
+	location, err := time.LoadLocation(config.Timezone)
+	if err != nil {
+		server.settingError("invalid timezone", err)
+		location = time.UTC
+	}
+	server.location = location
+
+	// Outbound clients, including the trace exporter, use the proxy settings
+	if server.HTTPClients == nil {
+		clients, err := httpclient.NewClients(config.OutboundProxy, config.OutboundCABundle)
+		if err != nil {
+			server.settingError("invalid outbound HTTP settings", err)
+		}
+		server.HTTPClients = clients
+	}
+	tracing.Init(config.TracingEndpoint, config.TracingServiceName, config.TracingHeaders, server.HTTPClients.New(10*time.Second))
+
+	storage := server.Storage
+	// Disabled until Load reads the key, which may be in a file
+	server.Secrets, _ = managers.NewSecretManager(storage, "")
+
+	var altTextSuggester managers.AltTextSuggester
+	if config.AltTextProviderURL != "" {
+		suggester := managers.NewHTTPAltTextSuggester(config.AltTextProviderURL, config.AltTextProviderKey, server.HTTPClients)
+		suggester.SetAPIKeyFunc(server.secretLookup(managers.SecretAltTextProviderKey))
+		altTextSuggester = suggester
+	}
+
+	server.TemplateManager = managers.NewTemplateManager(storage)
+	server.ContentManager = managers.NewContentManager(storage, config.DataDir)
+	server.SchemaManager = managers.NewSchemaManager(storage, config.DataDir)
+	server.TemplateManager.SetSchemaManager(server.SchemaManager)
+	server.SchemaManager.SetStrict(config.SchemaStrict)
+	server.SchemaManager.SetLimits(types.FormLimits{
+		MaxUploadSize:     config.UploadMaxSize,
+		MaxRichTextLength: config.RichTextMaxLength,
+		MaxArrayItems:     config.ArrayMaxItems,
+	})
+	server.SchemaManager.SetSchemaLimits(types.SchemaLimits{
+		MaxDepth:      config.SchemaMaxDepth,
+		MaxProperties: config.SchemaMaxProperties,
+		MaxEnumValues: config.SchemaMaxEnumValues,
+	})
+	server.ImageManager = managers.NewImageManager(storage, config.UploadMaxSize, altTextSuggester)
+	server.Generator = managers.NewSiteGenerator(storage, server.TemplateManager, server.ContentManager, config.OutputDir, config.ImageCDNURL, config.AssetMode)
+	server.CustomCode = managers.NewCustomCodeManager(storage, config.CustomCodeMaxSize)
+	server.Generator.SetCustomCode(server.CustomCode)
+	server.Generator.SetSchemaManager(server.SchemaManager)
+	server.Slugs = managers.NewSlugManager(storage)
+	server.Generator.SetSlugManager(server.Slugs)
+	server.Variants = managers.NewVariantManager(storage)
+	server.Generator.SetVariantManager(server.Variants)
+	server.Schedules = managers.NewScheduleManager(storage)
+	server.Generator.SetScheduleManager(server.Schedules)
+	server.Renderers = managers.NewSectionRendererManager(storage)
+	server.Generator.SetSectionRenderers(server.Renderers)
+	server.Autosaves = managers.NewAutosaveManager(storage)
+	server.Recovery = managers.NewRecoveryManager(storage)
+	server.Passkeys = managers.NewPasskeyManager(storage)
+	server.ImportMappings = managers.NewImportMappingManager(storage)
+	server.Generator.SetSiteURL(config.SiteURL)
+	server.Generator.SetLocation(server.location)
+	server.Generator.SetBuildHistory(config.SiteBuildHistory)
+	server.Generator.SetTextFiles(config.SecurityContact, config.SecurityPolicy, config.HumansTxt)
+	if config.RenderMode == managers.RenderModeLive {
+		server.Live = managers.NewLiveRenderer(server.Generator)
+	}
+	server.Jobs = managers.NewJobManager(storage)
+	server.Activity = managers.NewActivityLogger(storage)
+	webhooks, err := managers.NewWebhookDispatcher(config.Webhooks, server.HTTPClients)
+	if err != nil {
+		server.settingError("invalid webhook settings", err)
+		webhooks, _ = managers.NewWebhookDispatcher(nil, server.HTTPClients)
+	}
+	webhooks.SetMatrixToken(server.secretLookup(managers.SecretMatrixAccessToken))
+	if err := webhooks.SetDeployHooks(config.DeployHooks); err != nil {
+		server.settingError("invalid deploy hook settings", err)
+	}
+	server.Webhooks = webhooks
+	server.Outbox = managers.NewOutbox(storage)
+	var mailer managers.Mailer
+	if config.HeartbeatURL != "" {
+		server.Heartbeat = managers.NewHeartbeat(config.HeartbeatURL, time.Duration(config.HeartbeatInterval)*time.Second, server.HTTPClients)
+	}
+	if len(config.NotifyEmails) > 0 {
+		password := config.SMTPPassword
+		lookup := server.secretLookup(managers.SecretSMTPPassword)
+		mailer = managers.NewSMTPMailer(config.SMTPHost, config.SMTPPort, config.SMTPUsername, func() string {
+			if password != "" {
+				return password
+			}
+			return lookup()
+		}, config.SMTPFrom)
+		notifier, err := managers.NewPublishNotifier(storage, mailer, config.NotifyEmails, config.NotifyDigest, config.NotifyDigestTime, location)
+		if err != nil {
+			server.settingError("invalid publish notification settings", err)
+		} else {
+			notifier.SetSiteURL(config.SiteURL)
+			notifier.SetOutbox(server.Outbox)
+			server.Notifier = notifier
+		}
+	}
+	server.setupOutbox(mailer)
+	server.Retention = managers.NewRetentionManager(storage, managers.RetentionPolicy{
+		ActivityDays: config.ActivityRetentionDays,
+		JobDays:      config.JobRetentionDays,
+		OutboxDays:   config.OutboxRetentionDays,
+	}, server.Activity, server.Jobs, server.Outbox)
+	if config.ExportSigningKey != "" {
+		signer, err := managers.NewExportSigner(config.ExportSigningKey)
+		if err != nil {
+			// Refuse to run with exports silently unsigned
+			server.settingError("invalid export signing key", err)
+		}
+		server.ExportSigner = signer
+	}
+
+	if len(config.PluginHooks) > 0 {
+		hooks, err := managers.NewExternalHooks(config.PluginHooks, server.HTTPClients)
+		if err != nil {
+			server.settingError("invalid plugin hook settings", err)
+		} else {
+			server.Plugins.Register(hooks)
+		}
 	}
 
-	// Set up routes
+	// Set up middleware and routes
+	server.useDefaultMiddleware()
 	server.setupRoutes()
 
 	return server
 }
 
-// Start starts the HTTP server
-func (s *Server) Start() error {
+// settingError records a setting NewServer could not apply. NewServer does
+// not exit the process that embeds it; Load, and so Start, fail instead.
+func (s *Server) settingError(message string, err error) {
+	s.settingsErr = errors.Join(s.settingsErr, fmt.Errorf("%s: %w", message, err))
+}
+
+// Load prepares the data directory and reads what the server needs from
+// disk before serving: it migrates and creates the directories, sets up
+// autocert, then loads the saved admin password, the secrets and CSRF keys
+// and the persisted sessions in parallel. It first returns any setting
+// NewServer could not apply, as NewServer neither touches the disk nor
+// exits. Start calls Load; call it before serving Handler from another
+// http.Server, and do not serve if it fails. Only the first call does the
+// work.
+func (s *Server) Load() error {
+	s.loadOnce.Do(func() { s.loadErr = s.load() })
+	return s.loadErr
+}
+
+func (s *Server) load() error {
+	if s.settingsErr != nil {
+		return s.settingsErr
+	}
+
+	// Upgrade the data directory before anything reads it
+	if err := s.migrateData(); err != nil {
+		if errors.Is(err, managers.ErrMigrationBackup) {
+			s.Webhooks.Dispatch(context.Background(), managers.WebhookEvent{
+				Type:  managers.EventBackupFailed,
+				Title: "Could not back up the data directory before migrating it",
+				Text:  err.Error(),
+			})
+		}
+		return fmt.Errorf("failed to migrate data directory: %w", err)
+	}
+
 	// Ensure data directories exist
 	if err := s.ensureDirectories(); err != nil {
 		return fmt.Errorf("failed to create directories: %w", err)
 	}
 
+	if len(s.Config.AutocertHosts) > 0 {
+		dir := s.Config.AutocertDir
+		if dir == "" {
+			dir = filepath.Join(s.Config.DataDir, "autocert")
+		}
+		// Reads or creates the ACME account key
+		autocert, err := managers.NewAutocertManager(s.Config.AutocertHosts, s.Config.AutocertEmail, dir, s.Config.AutocertDirectory, s.HTTPClients)
+		if err != nil {
+			return fmt.Errorf("invalid autocert settings: %w", err)
+		}
+		s.Autocert = autocert
+	}
+
+	var wg sync.WaitGroup
+	var credentialsErr, secretsErr error
+	if s.credentials != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			loaded, err := s.credentials.LoadCredentials()
+			if err != nil {
+				credentialsErr = fmt.Errorf("failed to load admin credentials: %w", err)
+			} else if loaded {
+				s.Logger.Println("Using the admin password saved in auth.json; ADMIN_PASSWORD is ignored")
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		key, err := managers.ReadSecretsKey(s.Config.SecretsKey, s.Config.SecretsKeyFile)
+		if err == nil {
+			err = s.Secrets.SetKey(key)
+		}
+		if err != nil {
+			secretsErr = err
+			return
+		}
+		// Derived from the secrets key when there is one
+		csrfKey, err := s.loadCSRFKey()
+		if err != nil {
+			secretsErr = err
+			return
+		}
+		s.csrfKey = csrfKey
+	}()
+	if persister, ok := s.AuthManager.(SessionPersister); ok {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := persister.LoadSessions(); err != nil {
+				// Not fatal: the admin just signs in again
+				s.Logger.Printf("Failed to restore sessions: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(credentialsErr, secretsErr)
+}
+
+// Start starts the HTTP server
+func (s *Server) Start() error {
+	if err := s.Load(); err != nil {
+		return err
+	}
+
+	if persister, ok := s.AuthManager.(SessionPersister); ok {
+		s.runInBackground(func() { s.runSessionFlush(persister) })
+	}
+	s.runInBackground(s.runScheduler)
+	s.runInBackground(s.runOutbox)
+	if s.Notifier != nil {
+		s.runInBackground(s.runNotifications)
+	}
+
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return err
+	}
 	addr := ":" + s.Config.Port
-	log.Printf("Starting server on http://localhost%s", addr)
-	log.Printf("Admin panel: http://localhost%s/admin", addr)
+	s.httpServer = &http.Server{
+		Addr:      addr,
+		Handler:   s.Handler(),
+		TLSConfig: tlsConfig,
+	}
+
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+		if err := s.startRedirectServer(); err != nil {
+			return err
+		}
+		if s.Autocert != nil {
+			s.Logger.Printf("Certificates from %s for %s", s.Config.AutocertDirectory, strings.Join(s.Autocert.Hosts(), ", "))
+			s.runInBackground(s.runAutocertRenewal)
+		}
+	}
+
+	// Warm the schema analysis without holding up startup
+	s.runInBackground(s.warmSchemaAnalysis)
 
-	return http.ListenAndServe(addr, s.Mux)
+	s.Logger.Printf("Ready in %s", s.Clock().Sub(s.startedAt).Round(time.Millisecond))
+	s.Logger.Printf("Starting server on %s://localhost%s", scheme, addr)
+	s.Logger.Printf("Admin panel: %s://localhost%s/admin", scheme, addr)
+
+	// Stop makes ListenAndServe return at once; it then waits for requests
+	if tlsConfig != nil {
+		err = s.httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = s.httpServer.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// warmSchemaAnalysis parses the schema once so the first request that
+// needs the analysis finds it cached
+func (s *Server) warmSchemaAnalysis() {
+	if _, err := s.SchemaManager.ParseSchemaDetailed(); err != nil {
+		s.Logger.Printf("Failed to analyze schema: %v", err)
+	}
+}
+
+// runInBackground runs loop until Stop is called
+func (s *Server) runInBackground(loop func()) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		loop()
+	}()
+}
+
+// Stop stops accepting connections and waits until requests in progress,
+// the background loops and any scheduled generation have finished, or ctx
+// is done. Buffered traces are flushed last.
+func (s *Server) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stopping) })
+	defer tracing.Shutdown()
+
+	var err error
+	if s.redirectServer != nil {
+		s.redirectServer.Shutdown(ctx)
+	}
+	if s.httpServer != nil {
+		err = s.httpServer.Shutdown(ctx)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		s.scheduling.Lock() // a generation started by a request
+		s.scheduling.Unlock()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if err == nil {
+			err = fmt.Errorf("background work still running: %w", ctx.Err())
+		}
+	}
+	return err
+}
+
+// migrateData applies pending data directory migrations, logging each one
+func (s *Server) migrateData() error {
+	applied, backup, err := managers.NewMigrationRunner(s.Storage).Run()
+	if backup != "" {
+		s.Logger.Printf("Backed up data directory to %s before migrating", backup)
+	}
+	for _, migration := range applied {
+		s.Logger.Printf("Applied data migration %d: %s", migration.Version, migration.Description)
+	}
+	return err
 }
 
 // ensureDirectories creates necessary directories if they don't exist
@@ -64,13 +474,14 @@ func (s *Server) ensureDirectories() error {
 	dirs := []string{
 		s.Config.StaticDir,
 		s.Config.TemplatesDir,
+		s.Config.OutputDir,
 	}
 
 	for _, dir := range dirs {
 		if err := os.MkdirAll(dir, 0755); err != nil {
 			return fmt.Errorf("failed to create directory %s: %w", dir, err)
 		}
-		log.Printf("Ensured directory exists: %s", dir)
+		s.Logger.Printf("Ensured directory exists: %s", dir)
 	}
 
 	return nil
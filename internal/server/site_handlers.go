@@ -0,0 +1,181 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// handleSiteBuilds lists the generated builds kept for rollback, newest
+// first, marking the one that is live
+func (s *Server) handleSiteBuilds(w http.ResponseWriter, r *http.Request) {
+	builds, err := s.Generator.Builds()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Site builds retrieved")
+	response.SetData(builds)
+	response.Meta["count"] = len(builds)
+	response.Meta["history"] = s.Config.SiteBuildHistory
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleSiteRollback makes a kept build the live site again, leaving the
+// content as it is. The body is {"build": id}; without one, the build
+// before the live one is restored. Generating the site publishes the
+// current content again.
+func (s *Server) handleSiteRollback(w http.ResponseWriter, r *http.Request) {
+	if !s.requireRecentAuth(w, r) {
+		return
+	}
+	var requestData struct {
+		Build string `json:"build"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON in request body")
+		return
+	}
+	if !s.requireConfirmation(w, r, "site.rollback", requestData.Build, nil) {
+		return
+	}
+
+	build, err := s.Generator.Rollback(requestData.Build)
+	switch {
+	case errors.Is(err, managers.ErrBuildNotFound):
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, fmt.Sprintf("Build %q not found", requestData.Build))
+		return
+	case errors.Is(err, managers.ErrNoPreviousBuild):
+		s.writeError(w, r, http.StatusConflict, types.ErrCodeConflict, err.Error())
+		return
+	case err != nil:
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Rollback failed: "+err.Error())
+		return
+	}
+
+	s.logActivity(r.Context(), "Site Rolled Back", fmt.Sprintf("Live site rolled back to build %s", build.ID))
+
+	response := types.NewAPIResponse(true, "Site rolled back")
+	response.SetData(build)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleSitePresets lists the presets POST /admin/site/reset accepts
+func (s *Server) handleSitePresets(w http.ResponseWriter, r *http.Request) {
+	presets := managers.SitePresets()
+	response := types.NewAPIResponse(true, "Site presets retrieved")
+	response.SetData(presets)
+	response.Meta["count"] = len(presets)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleSiteReset returns the site to a preset: {"preset": "blank",
+// "keep_images": false}. The site is archived into DATA_DIR/site-backups
+// first; import that archive to undo the reset.
+func (s *Server) handleSiteReset(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	if !s.requireRecentAuth(w, r) {
+		return
+	}
+	var requestData struct {
+		Preset     string `json:"preset"`
+		KeepImages bool   `json:"keep_images"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON in request body")
+		return
+	}
+	if requestData.Preset == "" {
+		requestData.Preset = "blank"
+	}
+	preset, ok := managers.GetSitePreset(requestData.Preset)
+	if !ok {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, fmt.Sprintf("Unknown site preset %q", requestData.Preset))
+		return
+	}
+	if !s.requireConfirmation(w, r, "site.reset", preset.Name, nil) {
+		return
+	}
+
+	result, err := managers.ResetSite(s.Storage, preset, requestData.KeepImages, s.ContentManager, s.SchemaManager, s.TemplateManager, s.Clock())
+	if err != nil {
+		detail := "Reset failed: " + err.Error()
+		if result != nil {
+			detail += "; the site before the reset is in " + result.Backup
+		}
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, detail)
+		return
+	}
+
+	s.logActivity(r.Context(), "Site Reset", fmt.Sprintf("Site reset to the %s preset, backup in %s", preset.Name, result.Backup))
+
+	response := types.NewAPIResponse(true, "Site reset to the "+preset.Title+" preset")
+	response.SetData(result)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleSitesList lists the copies of the site in SITES_DIR
+func (s *Server) handleSitesList(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	slots, err := managers.ListSiteSlots(s.Config.SitesDir)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Site slots retrieved")
+	response.SetData(slots)
+	response.Meta["count"] = len(slots)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleSiteDuplicate copies the site into a new slot in SITES_DIR:
+// {"slug": "spring-sale"}. Serve the copy by starting another instance
+// with DATA_DIR set to the slot.
+func (s *Server) handleSiteDuplicate(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	var requestData struct {
+		Slug string `json:"slug"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON in request body")
+		return
+	}
+
+	slot, err := managers.DuplicateSite(s.Storage, s.Config.SitesDir, requestData.Slug, s.Clock())
+	switch {
+	case errors.Is(err, managers.ErrSiteSlotInvalid):
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, err.Error())
+		return
+	case errors.Is(err, managers.ErrSiteSlotExists):
+		s.writeError(w, r, http.StatusConflict, types.ErrCodeConflict, fmt.Sprintf("Site slot %q already exists", requestData.Slug))
+		return
+	case err != nil:
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Duplicate failed: "+err.Error())
+		return
+	}
+
+	s.logActivity(r.Context(), "Site Duplicated", fmt.Sprintf("Site copied to slot %s", slot.Name))
+
+	response := types.NewAPIResponse(true, "Site duplicated to "+slot.Name)
+	response.SetData(slot)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"onepagems/internal/types"
+)
+
+// handleSlugsGet returns the anchor slug of every section, assigning slugs
+// to sections that have none yet
+func (s *Server) handleSlugsGet(w http.ResponseWriter, r *http.Request) {
+	s.writeSlugs(w, r, "Section slugs retrieved")
+}
+
+// handleSlugSet stores an explicit slug for a section
+func (s *Server) handleSlugSet(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		Key  string `json:"key"`
+		Slug string `json:"slug"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		response := types.NewAPIResponse(false, "Invalid JSON in request body: "+err.Error())
+		s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+		return
+	}
+
+	content, err := s.ContentManager.LoadContent()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to load content: %v", err))
+		return
+	}
+	if _, exists := content.Sections[requestData.Key]; !exists {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, "Unknown section: "+requestData.Key)
+		return
+	}
+
+	slug, err := s.Slugs.Set(requestData.Key, requestData.Slug)
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	s.logActivity(r.Context(), "Slug Updated", "Section "+requestData.Key+" is now #"+slug)
+	s.writeSlugs(w, r, "Section slug saved")
+}
+
+// handleSlugsRegenerate derives slugs again from the current section
+// titles, for one section (key) or all of them
+func (s *Server) handleSlugsRegenerate(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		Key string `json:"key"`
+	}
+
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+			response := types.NewAPIResponse(false, "Invalid JSON in request body: "+err.Error())
+			s.writeErrorResponse(w, r, http.StatusBadRequest, response)
+			return
+		}
+	}
+
+	if err := s.Slugs.Regenerate(requestData.Key); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to regenerate slugs: %v", err))
+		return
+	}
+
+	target := "all sections"
+	if requestData.Key != "" {
+		target = "section " + requestData.Key
+	}
+	s.logActivity(r.Context(), "Slugs Regenerated", "Regenerated slugs for "+target)
+	s.writeSlugs(w, r, "Section slugs regenerated")
+}
+
+// writeSlugs responds with the resolved slug of every section. Slugs are
+// resolved from public content, as during generation, so a private title
+// never ends up in an anchor.
+func (s *Server) writeSlugs(w http.ResponseWriter, r *http.Request, message string) {
+	content, err := s.ContentManager.LoadContent()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to load content: %v", err))
+		return
+	}
+	public, err := s.SchemaManager.PublicContent(content)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to prepare content: %v", err))
+		return
+	}
+
+	sections, _ := public["sections"].(map[string]interface{})
+	slugs, err := s.Slugs.Resolve(sections)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to resolve slugs: %v", err))
+		return
+	}
+
+	response := types.NewAPIResponse(true, message)
+	response.SetData(map[string]interface{}{
+		"slugs": slugs,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// handleStarterGallery lists the starter templates POST
+// /admin/templates/apply accepts
+func (s *Server) handleStarterGallery(w http.ResponseWriter, r *http.Request) {
+	starters := managers.StarterTemplates()
+	response := types.NewAPIResponse(true, "Starter templates retrieved")
+	response.SetData(starters)
+	response.Meta["count"] = len(starters)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleStarterApply replaces the schema, template and content with a
+// starter's: {"name": "portfolio"}. The site is archived into
+// DATA_DIR/site-backups first; import that archive to undo it.
+func (s *Server) handleStarterApply(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	if !s.requireRecentAuth(w, r) {
+		return
+	}
+	var requestData struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON in request body")
+		return
+	}
+	starter, ok := managers.GetStarterTemplate(requestData.Name)
+	if !ok {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, fmt.Sprintf("Unknown starter template %q; see GET /admin/templates/gallery", requestData.Name))
+		return
+	}
+	if !s.requireConfirmation(w, r, "templates.apply", starter.Name, nil) {
+		return
+	}
+
+	result, err := managers.ApplyStarterTemplate(s.Storage, starter, s.ContentManager, s.SchemaManager, s.TemplateManager, s.Clock())
+	if err != nil {
+		detail := "Applying the starter failed: " + err.Error()
+		if result != nil {
+			detail += "; the site was left as it was, and is also archived in " + result.Backup
+		}
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, detail)
+		return
+	}
+
+	s.logActivity(r.Context(), "Starter Template Applied", fmt.Sprintf("Applied the %s starter template, backup in %s", starter.Name, result.Backup))
+
+	response := types.NewAPIResponse(true, "Applied the "+starter.Title+" starter template")
+	response.SetData(result)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"time"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// staleBackupAge is when the badge starts warning about an old backup
+const staleBackupAge = 7 * 24 * time.Hour
+
+// badge colors, as used by common README badges
+const (
+	badgeGreen  = "#4c1"
+	badgeYellow = "#dfb317"
+	badgeGrey   = "#9f9f9f"
+)
+
+// SiteStatus is the public health summary served at /status.json
+type SiteStatus struct {
+	Status        string     `json:"status"` // "ok", or "pending" before the first generation
+	StartedAt     time.Time  `json:"started_at"`
+	UptimeSeconds int64      `json:"uptime_seconds"`
+	LastGenerated *time.Time `json:"last_generated,omitempty"`
+	LastBackup    *time.Time `json:"last_backup,omitempty"`
+}
+
+// getSiteStatus collects the public health summary
+func (s *Server) getSiteStatus() *SiteStatus {
+	now := s.Clock()
+	status := &SiteStatus{
+		Status:        "pending",
+		StartedAt:     s.startedAt,
+		UptimeSeconds: int64(now.Sub(s.startedAt).Seconds()),
+	}
+
+	if info, err := os.Stat(s.Generator.OutputFile("index.html")); err == nil {
+		generated := info.ModTime()
+		status.LastGenerated = &generated
+		status.Status = "ok"
+	}
+
+	// The newest of the content backup and the data directory snapshots
+	// taken before migrations
+	lastBackup := s.ContentManager.LastBackup()
+	if backups, err := managers.NewMigrationRunner(s.Storage).Backups(); err == nil && len(backups) > 0 {
+		if newest := backups[len(backups)-1].CreatedAt; newest.After(lastBackup) {
+			lastBackup = newest
+		}
+	}
+	if !lastBackup.IsZero() {
+		status.LastBackup = &lastBackup
+	}
+	return status
+}
+
+// handleStatusJSON serves the public health summary for uptime monitors,
+// unwrapped like /health so monitors can match fields directly
+func (s *Server) handleStatusJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.getSiteStatus())
+}
+
+// handleStatusBadge serves an SVG badge for a README. ?metric= picks what
+// it shows: generated (default), backup or uptime; ?label= replaces the
+// label.
+func (s *Server) handleStatusBadge(w http.ResponseWriter, r *http.Request) {
+	status := s.getSiteStatus()
+	now := s.Clock()
+
+	metric := r.URL.Query().Get("metric")
+	var label, message, color string
+	switch metric {
+	case "", "generated":
+		label, message, color = "generated", "never", badgeGrey
+		if status.LastGenerated != nil {
+			message, color = humanAge(now.Sub(*status.LastGenerated))+" ago", badgeGreen
+		}
+	case "backup":
+		label, message, color = "backup", "never", badgeGrey
+		if status.LastBackup != nil {
+			age := now.Sub(*status.LastBackup)
+			message, color = humanAge(age)+" ago", badgeGreen
+			if age > staleBackupAge {
+				color = badgeYellow
+			}
+		}
+	case "uptime":
+		label, message, color = "uptime", humanAge(now.Sub(status.StartedAt)), badgeGreen
+	default:
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, fmt.Sprintf("Unknown metric %q; use generated, backup or uptime", metric))
+		return
+	}
+	if custom := r.URL.Query().Get("label"); custom != "" {
+		label = custom
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Write([]byte(renderBadge(label, message, color)))
+}
+
+// renderBadge draws a flat two-part badge. Widths are estimated from the
+// text length, which is close enough for the short texts used here.
+func renderBadge(label, message, color string) string {
+	textWidth := func(text string) int { return len([]rune(text))*7 + 10 }
+	labelWidth, messageWidth := textWidth(label), textWidth(message)
+	width := labelWidth + messageWidth
+	label, message = html.EscapeString(label), html.EscapeString(message)
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`+
+		`<title>%s: %s</title>`+
+		`<rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%d" y="14">%s</text><text x="%d" y="14">%s</text></g></svg>`,
+		width, label, message, label, message,
+		labelWidth, labelWidth, messageWidth, color,
+		labelWidth/2, label, labelWidth+messageWidth/2, message)
+}
+
+// humanAge shortens a duration to its largest unit, e.g. "3d" or "5m"
+func humanAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
@@ -1,97 +1,194 @@
 package server
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
+	"onepagems/internal/managers"
 	"onepagems/internal/types"
 )
 
-// handleTemplate handles template operations (GET to load, POST to save)
-func (s *Server) handleTemplate(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case "GET":
-		s.handleTemplateGet(w, r)
-	case "POST":
-		s.handleTemplatePost(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-}
-
 // handleTemplateGet loads and returns the current template
 func (s *Server) handleTemplateGet(w http.ResponseWriter, r *http.Request) {
 	content, err := s.TemplateManager.LoadTemplate()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to load template: %v", err), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to load template: %v", err))
 		return
 	}
 
 	response := types.NewAPIResponse(true, "Template loaded successfully")
 	response.SetData(map[string]interface{}{
 		"content": content,
+		"engine":  s.TemplateManager.EngineName(),
+		"engines": managers.TemplateEngineNames(),
 	})
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
 
 // handleTemplatePost saves a new template
 func (s *Server) handleTemplatePost(w http.ResponseWriter, r *http.Request) {
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid form data")
 		return
 	}
 
 	content := r.FormValue("content")
 	if content == "" {
-		http.Error(w, "Template content is required", http.StatusBadRequest)
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Template content is required")
 		return
 	}
 
-	// Save template
-	if err := s.TemplateManager.SaveTemplate(content); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to save template: %v", err), http.StatusBadRequest)
+	// Save template, rendered by the engine given or the current one
+	engine := r.FormValue("engine")
+	if engine == "" {
+		engine = s.TemplateManager.EngineName()
+	}
+	if err := s.TemplateManager.SaveTemplateWithEngine(content, engine); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, fmt.Sprintf("Failed to save template: %v", err))
 		return
 	}
+	s.logActivity(r.Context(), "Template Updated", fmt.Sprintf("Template saved (%s engine, %d bytes)", engine, len(content)))
 
 	response := types.NewAPIResponse(true, "Template saved successfully")
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
 
-// handleTemplateInfo returns information about the current template
-func (s *Server) handleTemplateInfo(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// handleTemplateCompatibility checks a template before it replaces the
+// current one: which fields it reads that the schema does not declare or
+// the content leaves empty, and which schema fields it would not show. With
+// add_missing=true the missing fields are added to the schema as optional
+// properties first. engine names the template's engine, by default the
+// current one.
+func (s *Server) handleTemplateCompatibility(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid form data")
 		return
 	}
 
-	info, err := s.TemplateManager.GetTemplateInfo()
+	content := r.FormValue("content")
+	if content == "" {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Template content is required")
+		return
+	}
+	addMissing, _ := strconv.ParseBool(r.FormValue("add_missing"))
+	engine := s.TemplateManager.Engine()
+	if name := r.FormValue("engine"); name != "" {
+		var err error
+		if engine, err = managers.TemplateEngineByName(name); err != nil {
+			s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, err.Error())
+			return
+		}
+	}
+
+	report, err := s.templateCompatibility(engine, content)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get template info: %v", err), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, err.Error())
 		return
 	}
 
-	// Get template variables
+	if addMissing && len(report.Missing) > 0 {
+		schema, err := s.SchemaManager.LoadSchema()
+		if err != nil {
+			s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to load schema: %v", err))
+			return
+		}
+		added := managers.AddTemplateFields(schema, report.Missing)
+		if err := s.SchemaManager.SaveSchema(schema); err != nil {
+			s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to save schema: %v", err))
+			return
+		}
+		s.logActivity(r.Context(), "Schema Updated", fmt.Sprintf("Added %d field(s) used by a new template", len(added)))
+
+		if report, err = s.templateCompatibility(engine, content); err != nil {
+			s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+			return
+		}
+		report.Added = added
+	}
+
+	message := "Template is compatible with the schema"
+	if !report.Compatible {
+		message = fmt.Sprintf("Template uses %d field(s) the schema does not declare", len(report.Missing))
+	}
+	response := types.NewAPIResponse(true, message)
+	response.SetData(report)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleTemplateCheck cross-references the saved template with the schema
+// and content: variables with no field behind them, with the declared field
+// each was likely meant to be, and schema fields the template never shows
+func (s *Server) handleTemplateCheck(w http.ResponseWriter, r *http.Request) {
 	content, err := s.TemplateManager.LoadTemplate()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to load template for analysis: %v", err), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to load template: %v", err))
+		return
+	}
+	schema, err := s.SchemaManager.LoadSchema()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to load schema: %v", err))
+		return
+	}
+	current, err := s.ContentManager.LoadContent()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to load content: %v", err))
+		return
+	}
+
+	check, err := managers.CheckTemplate(s.TemplateManager.Engine(), content, schema, current)
+	if err != nil {
+		s.writeError(w, r, http.StatusUnprocessableEntity, types.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	message := "Every template variable has a field behind it"
+	if !check.OK {
+		message = fmt.Sprintf("Template reads %d variable(s) with no field behind them", len(check.Unbacked))
+	}
+	response := types.NewAPIResponse(true, message)
+	response.SetData(check)
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// templateCompatibility compares a template with the current schema and
+// content
+func (s *Server) templateCompatibility(engine managers.TemplateEngine, content string) (*managers.TemplateCompatibility, error) {
+	schema, err := s.SchemaManager.LoadSchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema: %w", err)
+	}
+	current, err := s.ContentManager.LoadContent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load content: %w", err)
+	}
+	return managers.CheckTemplateCompatibility(engine, content, schema, current)
+}
+
+// handleTemplateInfo returns information about the current template
+func (s *Server) handleTemplateInfo(w http.ResponseWriter, r *http.Request) {
+	info, err := s.TemplateManager.GetTemplateInfo()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to get template info: %v", err))
 		return
 	}
 
-	variables, err := s.TemplateManager.GetTemplateVariables(content)
+	// Load the template for analysis
+	content, err := s.TemplateManager.LoadTemplate()
 	if err != nil {
-		// Don't fail completely if variable analysis fails
-		variables = []string{"Error analyzing variables: " + err.Error()}
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to load template for analysis: %v", err))
+		return
 	}
 
 	result := map[string]interface{}{
 		"file_info": info,
-		"variables": variables,
 		"content_preview": func() string {
 			if len(content) > 200 {
 				return content[:200] + "..."
@@ -100,37 +197,41 @@ func (s *Server) handleTemplateInfo(w http.ResponseWriter, r *http.Request) {
 		}(),
 	}
 
+	// Don't fail completely if variable analysis fails
+	if variables, err := s.TemplateManager.GetTemplateVariables(content); err != nil {
+		result["variables"] = []string{"Error analyzing variables: " + err.Error()}
+	} else {
+		result["variables"] = variables.Paths()
+		result["undefined_variables"] = variables.Undefined
+	}
+
 	response := types.NewAPIResponse(true, "Template info retrieved successfully")
 	response.SetData(result)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
 
 // handleTemplateRestore restores template from backup
 func (s *Server) handleTemplateRestore(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !s.requireRecentAuth(w, r) {
+		return
+	}
+	if !s.requireConfirmation(w, r, "template.restore", "", nil) {
 		return
 	}
-
 	if err := s.TemplateManager.RestoreTemplate(); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to restore template: %v", err), http.StatusInternalServerError)
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, fmt.Sprintf("Failed to restore template: %v", err))
 		return
 	}
 
 	response := types.NewAPIResponse(true, "Template restored from backup successfully")
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
 
 // handleTestTemplate tests template functionality
 func (s *Server) handleTestTemplate(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	// Test template operations
 	results := make(map[string]interface{})
 
@@ -166,12 +267,13 @@ func (s *Server) handleTestTemplate(w http.ResponseWriter, r *http.Request) {
 		results["template_variables"] = "Failed: " + err.Error()
 	} else {
 		results["template_variables"] = "Success"
-		results["variable_count"] = len(variables)
-		results["variables"] = variables
+		results["variable_count"] = len(variables.Variables)
+		results["variables"] = variables.Paths()
+		results["undefined_variables"] = variables.Undefined
 	}
 
 	// Test 5: Save a test template (minor modification)
-	testContent := content + "\n<!-- Test modification at " + time.Now().Format(time.RFC3339) + " -->"
+	testContent := content + "\n<!-- Test modification at " + s.Clock().Format(time.RFC3339) + " -->"
 	if err := s.TemplateManager.SaveTemplate(testContent); err != nil {
 		results["save_template"] = "Failed: " + err.Error()
 	} else {
@@ -183,5 +285,5 @@ func (s *Server) handleTestTemplate(w http.ResponseWriter, r *http.Request) {
 	response.SetData(results)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	s.encodeResponse(w, r, response)
 }
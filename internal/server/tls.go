@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// autocertInterval is how often certificates are checked for renewal
+const autocertInterval = 12 * time.Hour
+
+// tlsConfig returns the HTTPS settings, or nil when TLS is off
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	switch {
+	case s.Autocert != nil:
+		return &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: s.Autocert.GetCertificate,
+		}, nil
+	case s.Config.TLSCertFile != "":
+		cert, err := tls.LoadX509KeyPair(s.Config.TLSCertFile, s.Config.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		return &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+		}, nil
+	}
+	return nil, nil
+}
+
+// startRedirectServer listens for plain HTTP on HTTP_REDIRECT_PORT and
+// redirects to HTTPS, answering ACME challenges there first when
+// certificates come from autocert
+func (s *Server) startRedirectServer() error {
+	if s.Config.HTTPRedirectPort == "" {
+		if s.Autocert != nil {
+			s.Logger.Printf("HTTP_REDIRECT_PORT is off: certificates can only be issued if another server answers ACME challenges on port 80")
+		}
+		return nil
+	}
+
+	var handler http.Handler = http.HandlerFunc(s.httpsRedirect)
+	if s.Autocert != nil {
+		handler = s.Autocert.HTTPHandler(handler)
+	}
+	addr := ":" + s.Config.HTTPRedirectPort
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for HTTP redirects on %s (set HTTP_REDIRECT_PORT=off to disable them): %w", addr, err)
+	}
+	s.redirectServer = &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := s.redirectServer.Serve(listener); err != http.ErrServerClosed {
+			s.Logger.Printf("HTTP redirect server stopped: %v", err)
+		}
+	}()
+	s.Logger.Printf("Redirecting http://localhost%s to HTTPS", addr)
+	return nil
+}
+
+// httpsRedirect sends a plain HTTP request to the same URL over HTTPS.
+// GET and HEAD get a 301; other methods a 308 so they are not turned into
+// GETs.
+func (s *Server) httpsRedirect(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if host == "" {
+		http.Error(w, "Host header required", http.StatusBadRequest)
+		return
+	}
+	if s.Config.Port != "443" {
+		host = net.JoinHostPort(host, s.Config.Port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	status := http.StatusMovedPermanently
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		status = http.StatusPermanentRedirect
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+}
+
+// runAutocertRenewal orders missing certificates at startup, so the first
+// visitor does not wait for one, and renews them as they come due
+func (s *Server) runAutocertRenewal() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.stopping
+		cancel()
+	}()
+
+	ticker := time.NewTicker(autocertInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Autocert.RenewDue(ctx); err != nil && ctx.Err() == nil {
+			s.Logger.Printf("Certificate renewal: %v", err)
+			s.heartbeatFail("Certificate renewal: " + err.Error())
+		}
+		select {
+		case <-ticker.C:
+		case <-s.stopping:
+			return
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+
+	"onepagems/internal/managers"
+	"onepagems/internal/types"
+)
+
+// variantCookie keeps a visitor on the same page of an A/B test
+const variantCookie = "onepagems_variant"
+
+// variantCookieMaxAge is how long a visitor keeps their variant, 30 days
+const variantCookieMaxAge = 30 * 24 * 60 * 60
+
+// pageVariant returns the A/B variant a visitor sees, assigning one at
+// random and remembering it in a cookie on their first visit
+func (s *Server) pageVariant(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(variantCookie); err == nil {
+		if cookie.Value == managers.VariantA || cookie.Value == managers.VariantB {
+			return cookie.Value
+		}
+	}
+
+	variant := managers.VariantA
+	if rand.IntN(2) == 1 {
+		variant = managers.VariantB
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     variantCookie,
+		Value:    variant,
+		Path:     "/",
+		MaxAge:   variantCookieMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return variant
+}
+
+// handleVariantsList returns the B variant of each section under test and
+// how often each page has been shown
+func (s *Server) handleVariantsList(w http.ResponseWriter, r *http.Request) {
+	variants, err := s.Variants.Load()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+		return
+	}
+
+	response := types.NewAPIResponse(true, "Variants retrieved")
+	response.SetData(map[string]interface{}{
+		"variants":  variants,
+		"exposures": s.Variants.Exposures(),
+		"live":      s.Generator.HasVariantPage(),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleVariantSet stores the B variant of a section. The body is the
+// section as it should appear on the B page; it is validated in place of
+// the current section. The test goes live with the next generation.
+func (s *Server) handleVariantSet(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	var section map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&section); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Invalid JSON in request body")
+		return
+	}
+
+	content, err := s.ContentManager.LoadContent()
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to load content: "+err.Error())
+		return
+	}
+	if _, exists := content.Sections[key]; !exists {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, fmt.Sprintf("Section %q not found", key))
+		return
+	}
+
+	sections := make(map[string]interface{}, len(content.Sections))
+	for name, value := range content.Sections {
+		sections[name] = value
+	}
+	sections[key] = section
+	candidate := map[string]interface{}{
+		"title":       content.Title,
+		"description": content.Description,
+		"sections":    sections,
+	}
+	if _, err := s.SchemaManager.CoerceContent(candidate); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, types.ErrCodeInvalidRequest, "Failed to process variant: "+err.Error())
+		return
+	}
+	validationResult, err := s.SchemaManager.ValidateContentDetailed(candidate)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Validation failed: "+err.Error())
+		return
+	}
+	if !validationResult.Valid {
+		s.writeContentInvalid(w, r, validationResult)
+		return
+	}
+
+	if err := s.Variants.Set(key, sections[key]); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to save variant: "+err.Error())
+		return
+	}
+
+	s.logActivity(r.Context(), "Variant Saved", fmt.Sprintf("Saved the B variant of section %s", key))
+
+	response := types.NewAPIResponse(true, "Variant saved; generate the site to start the test")
+	response.SetData(map[string]interface{}{"key": key, "section": sections[key]})
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
+
+// handleVariantDelete ends the test on a section. Visitors keep seeing
+// the B page until the site is generated again.
+func (s *Server) handleVariantDelete(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	deleted, err := s.Variants.Delete(key)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, types.ErrCodeInternal, "Failed to delete variant: "+err.Error())
+		return
+	}
+	if !deleted {
+		s.writeError(w, r, http.StatusNotFound, types.ErrCodeNotFound, fmt.Sprintf("Section %q has no variant", key))
+		return
+	}
+
+	s.logActivity(r.Context(), "Variant Deleted", fmt.Sprintf("Deleted the B variant of section %s", key))
+
+	response := types.NewAPIResponse(true, "Variant deleted")
+	w.Header().Set("Content-Type", "application/json")
+	s.encodeResponse(w, r, response)
+}
@@ -0,0 +1,219 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	exportBatchSize = 100
+	exportInterval  = 5 * time.Second
+	exportQueueSize = 2048
+)
+
+// Exporter batches finished spans and posts them to an OTLP/HTTP endpoint
+type Exporter struct {
+	endpoint    string
+	serviceName string
+	headers     map[string]string
+	client      *http.Client
+	queue       chan *Span
+	done        chan struct{}
+	wg          sync.WaitGroup
+	once        sync.Once
+}
+
+// NewExporter creates and starts an exporter posting with client. endpoint
+// is the collector base URL (e.g. http://localhost:4318); /v1/traces is
+// appended when missing.
+func NewExporter(endpoint, serviceName string, headers map[string]string, client *http.Client) *Exporter {
+	endpoint = strings.TrimRight(endpoint, "/")
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint += "/v1/traces"
+	}
+	if serviceName == "" {
+		serviceName = "onepagems"
+	}
+
+	e := &Exporter{
+		endpoint:    endpoint,
+		serviceName: serviceName,
+		headers:     headers,
+		client:      client,
+		queue:       make(chan *Span, exportQueueSize),
+		done:        make(chan struct{}),
+	}
+
+	e.wg.Add(1)
+	go e.run()
+	return e
+}
+
+// Enqueue queues a finished span, dropping it if the queue is full
+func (e *Exporter) Enqueue(span *Span) {
+	select {
+	case e.queue <- span:
+	default:
+	}
+}
+
+// Shutdown exports any queued spans and stops the background worker
+func (e *Exporter) Shutdown() {
+	e.once.Do(func() {
+		close(e.done)
+		e.wg.Wait()
+	})
+}
+
+// run collects spans into batches and exports them periodically
+func (e *Exporter) run() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, exportBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.export(batch); err != nil {
+			log.Printf("Trace export failed: %v", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) >= exportBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.done:
+			for {
+				select {
+				case span := <-e.queue:
+					batch = append(batch, span)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// export posts a batch of spans using the OTLP JSON encoding
+func (e *Exporter) export(spans []*Span) error {
+	payload, err := json.Marshal(e.encode(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", e.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("collector request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// encode converts spans to an OTLP ExportTraceServiceRequest
+func (e *Exporter) encode(spans []*Span) map[string]interface{} {
+	encoded := make([]map[string]interface{}, 0, len(spans))
+	for _, span := range spans {
+		span.mu.Lock()
+		item := map[string]interface{}{
+			"traceId":           hex.EncodeToString(span.traceID[:]),
+			"spanId":            hex.EncodeToString(span.spanID[:]),
+			"name":              span.name,
+			"kind":              span.kind,
+			"startTimeUnixNano": strconv.FormatInt(span.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(span.end.UnixNano(), 10),
+			"attributes":        encodeAttributes(span.attributes),
+		}
+		if span.parentID != [8]byte{} {
+			item["parentSpanId"] = hex.EncodeToString(span.parentID[:])
+		}
+		if span.errMessage != "" {
+			item["status"] = map[string]interface{}{"code": 2, "message": span.errMessage}
+		}
+		span.mu.Unlock()
+		encoded = append(encoded, item)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": encodeAttributes(map[string]interface{}{"service.name": e.serviceName}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "onepagems"},
+						"spans": encoded,
+					},
+				},
+			},
+		},
+	}
+}
+
+// encodeAttributes converts attributes to OTLP KeyValue objects
+func encodeAttributes(attributes map[string]interface{}) []map[string]interface{} {
+	encoded := make([]map[string]interface{}, 0, len(attributes))
+	for key, value := range attributes {
+		var anyValue map[string]interface{}
+		switch v := value.(type) {
+		case bool:
+			anyValue = map[string]interface{}{"boolValue": v}
+		case int:
+			anyValue = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case int64:
+			anyValue = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			anyValue = map[string]interface{}{"doubleValue": v}
+		default:
+			anyValue = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		encoded = append(encoded, map[string]interface{}{"key": key, "value": anyValue})
+	}
+	return encoded
+}
+
+// ParseHeaders parses OTEL_EXPORTER_OTLP_HEADERS style "k1=v1,k2=v2" values
+func ParseHeaders(value string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			continue
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return headers
+}
@@ -0,0 +1,203 @@
+// Package tracing records OpenTelemetry-compatible spans and exports them
+// over OTLP/HTTP (JSON encoding). When no endpoint is configured every
+// operation is a no-op, so callers can instrument code unconditionally.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span is a single timed operation. A nil *Span is valid and ignores all calls.
+type Span struct {
+	tracer     *Tracer
+	name       string
+	traceID    [16]byte
+	spanID     [8]byte
+	parentID   [8]byte
+	kind       int
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+	errMessage string
+	mu         sync.Mutex
+	ended      bool
+}
+
+// Span kinds as defined by OTLP
+const (
+	KindInternal = 1
+	KindServer   = 2
+	KindClient   = 3
+)
+
+type spanContextKey struct{}
+
+// Tracer creates spans and hands finished ones to the exporter
+type Tracer struct {
+	exporter *Exporter
+}
+
+var (
+	globalMu     sync.RWMutex
+	globalTracer *Tracer
+)
+
+// Init configures the global tracer, exporting spans with client. An empty
+// endpoint disables tracing.
+func Init(endpoint, serviceName string, headers map[string]string, client *http.Client) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+
+	if globalTracer != nil && globalTracer.exporter != nil {
+		globalTracer.exporter.Shutdown()
+	}
+
+	if endpoint == "" {
+		globalTracer = nil
+		return
+	}
+	globalTracer = &Tracer{exporter: NewExporter(endpoint, serviceName, headers, client)}
+}
+
+// Shutdown flushes pending spans and stops the exporter
+func Shutdown() {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+
+	if globalTracer != nil && globalTracer.exporter != nil {
+		globalTracer.exporter.Shutdown()
+	}
+	globalTracer = nil
+}
+
+// Enabled reports whether spans are being exported
+func Enabled() bool {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return globalTracer != nil
+}
+
+// Start begins an internal span as a child of any span in ctx
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return StartKind(ctx, name, KindInternal)
+}
+
+// StartKind begins a span of the given kind as a child of any span in ctx
+func StartKind(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	globalMu.RLock()
+	tracer := globalTracer
+	globalMu.RUnlock()
+
+	if tracer == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		tracer:     tracer,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: make(map[string]interface{}),
+	}
+	rand.Read(span.spanID[:])
+
+	if parent := FromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		rand.Read(span.traceID[:])
+	}
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// FromContext returns the current span, or nil if there is none
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// SetAttribute records a key/value attribute on the span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attributes[key] = value
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed. A nil error is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.errMessage = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	s.tracer.exporter.Enqueue(s)
+}
+
+// TraceID returns the hex trace ID, or an empty string for a nil span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// Traceparent returns the W3C traceparent header value for the span
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:]))
+}
+
+// Extract returns a context whose remote parent is taken from an incoming
+// W3C traceparent header, so spans join the caller's trace
+func Extract(ctx context.Context, header http.Header) context.Context {
+	parts := strings.Split(header.Get("traceparent"), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ctx
+	}
+
+	remote := &Span{}
+	if _, err := hex.Decode(remote.traceID[:], []byte(parts[1])); err != nil {
+		return ctx
+	}
+	if _, err := hex.Decode(remote.spanID[:], []byte(parts[2])); err != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanContextKey{}, remote)
+}
+
+// Inject adds the traceparent header for the span in ctx to an outgoing request
+func Inject(ctx context.Context, header http.Header) {
+	if span := FromContext(ctx); span != nil {
+		header.Set("traceparent", span.Traceparent())
+	}
+}
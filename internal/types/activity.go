@@ -0,0 +1,12 @@
+package types
+
+import "time"
+
+// ActivityEntry is one admin action in the activity log
+type ActivityEntry struct {
+	Time        time.Time `json:"time"`
+	Action      string    `json:"action"`
+	Description string    `json:"description"`
+	Username    string    `json:"username,omitempty"`
+	RequestID   string    `json:"request_id,omitempty"`
+}
@@ -20,6 +20,67 @@ type FormField struct {
 	Value       interface{} `json:"value,omitempty"`
 	Format      string      `json:"format,omitempty"`
 	Description string      `json:"description,omitempty"`
+	Nullable    bool        `json:"nullable,omitempty"`  // value may be cleared to null
+	TabIndex    int         `json:"tab_index,omitempty"` // position in keyboard order, from 1
+	Autofocus   bool        `json:"autofocus,omitempty"`
+	HelpID      string      `json:"help_id,omitempty"` // anchor id for the description
+	Private     bool        `json:"private,omitempty"` // editable here but never published
+
+	// Accessibility hints, so every client renders the same accessible
+	// form. ID is the input's element id and ErrorID the id of the element
+	// holding its validation message, which aria-errormessage points at.
+	// DescribedBy is the input's aria-describedby: the help text, if any,
+	// and the validation message. Item fields hold "[]" like their name.
+	ID          string `json:"id"`
+	ErrorID     string `json:"error_id"`
+	DescribedBy string `json:"aria_describedby"`
+
+	// Fieldset names the field whose fieldset this one sits in: an object
+	// field, or the item of an array group, named with "[]". Object fields
+	// are fieldsets themselves, captioned by Legend.
+	Fieldset string `json:"fieldset,omitempty"`
+	Legend   string `json:"legend,omitempty"`
+
+	// Limits the server enforces on submit; zero means none
+	MinLength int   `json:"min_length,omitempty"`
+	MaxLength int   `json:"max_length,omitempty"`
+	MinItems  int   `json:"min_items,omitempty"`
+	MaxItems  int   `json:"max_items,omitempty"`
+	MaxSize   int64 `json:"max_size,omitempty"` // upload size in bytes, for image fields
+
+	// Group describes the items of an array of objects
+	Group *FormFieldGroup `json:"group,omitempty"`
+}
+
+// FormFieldGroup describes an array of objects edited as a list of field
+// groups, one per item. Field names hold "[]" where the editor puts the
+// item's index, e.g. "sections.services.items[].title" for the title of
+// "sections.services.items[2]". The editor disables adding at the field's
+// max_items and removing at its min_items.
+type FormFieldGroup struct {
+	ItemLabel    string                 `json:"item_label"`              // heading of each item, e.g. "Service"
+	SummaryField string                 `json:"summary_field,omitempty"` // item field to show when an item is collapsed
+	Fields       []FormField            `json:"fields"`
+	NewItem      map[string]interface{} `json:"new_item"` // value of an added item, from the item defaults
+	CanAdd       bool                   `json:"can_add"`
+	CanRemove    bool                   `json:"can_remove"`
+	CanReorder   bool                   `json:"can_reorder"`
+}
+
+// FormLimits are the server-wide limits content and uploads must keep to;
+// zero means none
+type FormLimits struct {
+	MaxUploadSize     int64 `json:"max_upload_size,omitempty"` // bytes per image
+	MaxRichTextLength int   `json:"max_rich_text_length,omitempty"`
+	MaxArrayItems     int   `json:"max_array_items,omitempty"`
+}
+
+// SchemaLimits bound the size of a schema so one built to exhaust the
+// recursive parser and validator is refused; 0 uses the default
+type SchemaLimits struct {
+	MaxDepth      int `json:"max_depth,omitempty"`       // levels of nested schemas
+	MaxProperties int `json:"max_properties,omitempty"`  // properties and subschemas at all levels
+	MaxEnumValues int `json:"max_enum_values,omitempty"` // values of one enum
 }
 
 // GeneratedForm represents a complete form generated from schema
@@ -27,6 +88,12 @@ type GeneratedForm struct {
 	Fields []FormField `json:"fields"`
 	Action string      `json:"action"`
 	Method string      `json:"method"`
+
+	// Autofocus names the field that should take focus when the form opens
+	Autofocus string `json:"autofocus,omitempty"`
+
+	// Limits are the server-wide limits each field's own limits include
+	Limits *FormLimits `json:"limits,omitempty"`
 }
 
 // NewAPIResponse creates a new API response
@@ -2,26 +2,241 @@ package types
 
 // Config represents the application configuration
 type Config struct {
-	Port           string `json:"port"`
-	AdminUsername  string `json:"admin_username"`
-	AdminPassword  string `json:"admin_password"`
-	UploadMaxSize  int64  `json:"upload_max_size"`
-	SessionTimeout int    `json:"session_timeout"` // in minutes
-	DataDir        string `json:"data_dir"`
-	StaticDir      string `json:"static_dir"`
-	TemplatesDir   string `json:"templates_dir"`
+	Port            string `json:"port"`
+	AdminUsername   string `json:"admin_username"`
+	AdminPassword   string `json:"admin_password"`
+	UploadMaxSize   int64  `json:"upload_max_size"`
+	ArchiveMaxSize  int64  `json:"archive_max_size"` // site archive imports
+	SessionTimeout  int    `json:"session_timeout"`  // in minutes
+	IdleTimeout     int    `json:"idle_timeout"`     // in minutes, locks an unused session; 0 never locks
+	ReauthWindow    int    `json:"reauth_window"`    // in minutes, password age sensitive actions accept; 0 never asks
+	PublicTimeout   int    `json:"public_timeout"`   // in seconds
+	AdminTimeout    int    `json:"admin_timeout"`    // in seconds
+	GenerateTimeout int    `json:"generate_timeout"` // in seconds, site generation
+	ShutdownTimeout int    `json:"shutdown_timeout"` // in seconds, wait for requests on exit
+
+	// Log one line per request with its status, size and latency, and gzip
+	// text responses for clients that accept it
+	AccessLog   bool `json:"access_log"`
+	Compression bool `json:"compression"`
+
+	// HTTPS: either a certificate and key, or certificates from an ACME CA
+	// (Let's Encrypt) for the hosts in AutocertHosts. With either, Port
+	// serves HTTPS and HTTPRedirectPort, when set, redirects plain HTTP to
+	// it. It defaults to 80 with autocert, which needs it for challenges.
+	TLSCertFile       string   `json:"tls_cert_file,omitempty"`
+	TLSKeyFile        string   `json:"tls_key_file,omitempty"`
+	AutocertHosts     []string `json:"autocert_hosts,omitempty"`
+	AutocertEmail     string   `json:"autocert_email,omitempty"`
+	AutocertDir       string   `json:"autocert_dir,omitempty"`       // defaults to DATA_DIR/autocert
+	AutocertDirectory string   `json:"autocert_directory,omitempty"` // ACME directory URL
+	HTTPRedirectPort  string   `json:"http_redirect_port,omitempty"` // "off" to not listen
+
+	// Keep admin sessions in the data directory so restarts don't sign
+	// the admin out
+	PersistSessions bool   `json:"persist_sessions"`
+	DataDir         string `json:"data_dir"`
+	StaticDir       string `json:"static_dir"`
+	TemplatesDir    string `json:"templates_dir"`
+	OutputDir       string `json:"output_dir"`
+
+	// SitesDir holds copies of the site made by POST /admin/site/duplicate,
+	// one data directory per slot
+	SitesDir string `json:"sites_dir"`
+
+	// ConfirmDestructive makes destructive endpoints (restores, imports,
+	// image deletes, rollbacks) require a confirmation token from
+	// GET /admin/confirm. Off by default, as API clients written before
+	// it would fail.
+	ConfirmDestructive bool `json:"confirm_destructive"`
+
+	// Optional vision provider used to suggest alt text for uploaded images
+	AltTextProviderURL string `json:"alt_text_provider_url,omitempty"`
+	AltTextProviderKey string `json:"-"`
+
+	// Optional CDN base URL used for image references in the generated site
+	ImageCDNURL string `json:"image_cdn_url,omitempty"`
+
+	// IANA time zone dates are shown in on the site and the dashboard,
+	// e.g. Europe/London
+	Timezone string `json:"timezone"`
+
+	// Public URL of the generated site, e.g. https://example.com/
+	SiteURL string `json:"site_url,omitempty"`
+
+	// security.txt contact URI and disclosure policy URL; the contact
+	// defaults to the email of the contact section
+	SecurityContact string `json:"security_contact,omitempty"`
+	SecurityPolicy  string `json:"security_policy,omitempty"`
+
+	// Publish a humans.txt credit file built from the content
+	HumansTxt bool `json:"humans_txt"`
+
+	// How generated pages emit inline CSS/JS: "inline" (CSP hashes) or "external"
+	AssetMode string `json:"asset_mode"`
+
+	// How the public page is served: "static" (the last generated
+	// index.html) or "live" (rendered from the current content per request)
+	RenderMode string `json:"render_mode"`
+
+	// Reject content fields the schema does not declare, even where the
+	// schema leaves additionalProperties unset
+	SchemaStrict bool `json:"schema_strict"`
+
+	// Longest rich text value and most items in an array field that content
+	// may have, on top of the schema's own limits; 0 leaves them unlimited
+	RichTextMaxLength int `json:"rich_text_max_length"`
+	ArrayMaxItems     int `json:"array_max_items"`
+
+	// Largest schema accepted, so an imported schema cannot exhaust the
+	// parser: nesting depth, properties at all levels and values per enum
+	SchemaMaxDepth      int `json:"schema_max_depth"`
+	SchemaMaxProperties int `json:"schema_max_properties"`
+	SchemaMaxEnumValues int `json:"schema_max_enum_values"`
+
+	// Number of generated builds kept for rolling the live site back;
+	// 0 keeps none
+	SiteBuildHistory int `json:"site_build_history"`
+
+	// Maximum size in bytes of each custom code slot (head and end of body)
+	CustomCodeMaxSize int `json:"custom_code_max_size"`
+
+	// Admin API requests allowed per minute for each browser session and for
+	// each bearer token (automation); 0 turns the limit off
+	RateLimitSession int `json:"rate_limit_session"`
+	RateLimitToken   int `json:"rate_limit_token"`
+
+	// Failed sign-ins allowed per client address before it is locked out
+	// for LoginLockout seconds; failures per username only slow sign-ins
+	// down. 0 turns both off.
+	LoginMaxAttempts int `json:"login_max_attempts"`
+	LoginLockout     int `json:"login_lockout"`
+
+	// Optional key that signs content exports and verifies them on import:
+	// an HMAC secret, or "ed25519:" and a base64 seed
+	ExportSigningKey string `json:"-"`
+
+	// Key of the encrypted secrets store (base64, 32 bytes), given directly
+	// or as a path to a file holding it
+	SecretsKey     string `json:"-"`
+	SecretsKeyFile string `json:"-"`
+
+	// Proxy for outbound calls to integrations, overriding HTTP_PROXY and
+	// HTTPS_PROXY, and a PEM file of extra CAs to trust for them
+	OutboundProxy    string `json:"outbound_proxy,omitempty"`
+	OutboundCABundle string `json:"outbound_ca_bundle,omitempty"`
+
+	// Stakeholders emailed when the site is published, "immediate" or
+	// "daily" digests, and the time of day (HH:MM, site time zone) a daily
+	// digest is sent
+	NotifyEmails     []string `json:"notify_emails,omitempty"`
+	NotifyDigest     string   `json:"notify_digest"`
+	NotifyDigestTime string   `json:"notify_digest_time"`
+
+	// SMTP server publish notifications are sent through
+	SMTPHost     string `json:"smtp_host,omitempty"`
+	SMTPPort     int    `json:"smtp_port"`
+	SMTPUsername string `json:"smtp_username,omitempty"`
+	SMTPPassword string `json:"-"`
+	SMTPFrom     string `json:"smtp_from,omitempty"`
+
+	// Chat and webhook endpoints by event type (publish, generation_failed,
+	// backup_failed, login_alert), each a comma-separated list such as
+	// "slack:https://hooks.slack.com/..."
+	Webhooks map[string]string `json:"-"`
+
+	// Build hook URLs of the hosting provider (Netlify, Vercel, Cloudflare
+	// Pages...) posted after each publish; kept out of JSON as they act as
+	// credentials
+	DeployHooks []string `json:"-"`
+
+	// External plugin hooks by event (content_save, generate, upload), each
+	// a comma-separated list of "exec:<command>" entries and URLs
+	PluginHooks map[string]string `json:"-"`
+
+	// Optional heartbeat URL (healthchecks.io style) pinged while scheduled
+	// work and generations succeed, and with /fail appended when they fail;
+	// routine pings are at most HeartbeatInterval seconds apart
+	HeartbeatURL      string `json:"heartbeat_url,omitempty"`
+	HeartbeatInterval int    `json:"heartbeat_interval"`
+
+	// Days the activity log, finished jobs and dead-lettered outbox
+	// messages are kept before the scheduler prunes them; 0 keeps them
+	// forever
+	ActivityRetentionDays int `json:"activity_retention_days"`
+	JobRetentionDays      int `json:"job_retention_days"`
+	OutboxRetentionDays   int `json:"outbox_retention_days"`
+
+	// EmergencyLogin is "token" (the token in EmergencyTokenFile signs the
+	// admin in once), "local" (the token too, and only from a loopback
+	// address) or "off", the default
+	EmergencyLogin     string `json:"emergency_login"`
+	EmergencyTokenFile string `json:"emergency_token_file,omitempty"`
+
+	// PasskeyLogin is "passwordless" (a passkey signs in on its own),
+	// "second_factor" (users with a passkey confirm the password with it)
+	// or "off", the default. PasskeyOrigin is the origin the admin is served from,
+	// defaulting to that of SiteURL, then to the request's
+	PasskeyLogin  string `json:"passkey_login"`
+	PasskeyOrigin string `json:"passkey_origin,omitempty"`
+
+	// Optional OTLP/HTTP collector for trace export, e.g. http://localhost:4318
+	TracingEndpoint    string            `json:"tracing_endpoint,omitempty"`
+	TracingServiceName string            `json:"tracing_service_name,omitempty"`
+	TracingHeaders     map[string]string `json:"-"`
+}
+
+// TLSEnabled reports whether the server listens with HTTPS
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" || len(c.AutocertHosts) > 0
 }
 
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
-		Port:           "8080",
-		AdminUsername:  "admin",
-		AdminPassword:  "",              // Will be set to hashed "admin123" in ValidateConfig
-		UploadMaxSize:  5 * 1024 * 1024, // 5MB
-		SessionTimeout: 60,              // 60 minutes
-		DataDir:        "./data",
-		StaticDir:      "./static",
-		TemplatesDir:   "./templates",
+		Port:                "8080",
+		AdminUsername:       "admin",
+		AdminPassword:       "",                // Will be set to hashed "admin123" in ValidateConfig
+		UploadMaxSize:       5 * 1024 * 1024,   // 5MB
+		ArchiveMaxSize:      512 * 1024 * 1024, // 512MB
+		SessionTimeout:      60,                // 60 minutes
+		IdleTimeout:         0,                 // off unless set
+		ReauthWindow:        0,                 // off unless set
+		PublicTimeout:       10,
+		AdminTimeout:        60,
+		GenerateTimeout:     300,
+		ShutdownTimeout:     30,
+		AccessLog:           true,
+		Compression:         true,
+		AutocertDirectory:   "https://acme-v02.api.letsencrypt.org/directory",
+		PersistSessions:     true,
+		ConfirmDestructive:  false,
+		DataDir:             "./data",
+		SitesDir:            "./sites",
+		StaticDir:           "./static",
+		TemplatesDir:        "./templates",
+		OutputDir:           "./public",
+		AssetMode:           "inline",
+		RenderMode:          "static",
+		CustomCodeMaxSize:   16 * 1024, // 16KB
+		HumansTxt:           true,
+		SiteBuildHistory:    5,
+		RichTextMaxLength:   64 * 1024,
+		ArrayMaxItems:       100,
+		SchemaMaxDepth:      32,
+		SchemaMaxProperties: 2000,
+		SchemaMaxEnumValues: 1000,
+		Timezone:            "UTC",
+		RateLimitSession:    600,
+		RateLimitToken:      120,
+		LoginMaxAttempts:    5,
+		LoginLockout:        900,
+		TracingServiceName:  "onepagems",
+		NotifyDigest:        "immediate",
+		NotifyDigestTime:    "09:00",
+		SMTPPort:            587,
+		HeartbeatInterval:   300,
+		EmergencyLogin:      "off",
+		PasskeyLogin:        "off",
 	}
 }
@@ -18,6 +18,20 @@ type SchemaData struct {
 	Schema     string                 `json:"$schema"`
 	Type       string                 `json:"type"`
 	Properties map[string]interface{} `json:"properties"`
+
+	// AdditionalProperties controls whether content may carry top-level fields
+	// the schema does not declare. Unset means they are allowed.
+	AdditionalProperties *bool `json:"additionalProperties,omitempty"`
+
+	// Defs and Definitions hold reusable schemas that properties refer to
+	// with a local $ref such as "#/$defs/address"
+	Defs        map[string]interface{} `json:"$defs,omitempty"`
+	Definitions map[string]interface{} `json:"definitions,omitempty"`
+
+	// PropertyOrder lists property names in the order they appear in the
+	// schema file, keyed by the dotted path of their parent object ("" for
+	// the root, "team[]" for the items of the team array). It is filled when the schema is decoded from JSON.
+	PropertyOrder map[string][]string `json:"-"`
 }
 
 // ToJSON converts any struct to JSON string
@@ -4,12 +4,25 @@ import "time"
 
 // ImageInfo represents information about an uploaded image
 type ImageInfo struct {
-	Filename     string    `json:"filename"`
-	OriginalName string    `json:"original_name"`
-	Size         int64     `json:"size"`
-	ContentType  string    `json:"content_type"`
-	UploadedAt   time.Time `json:"uploaded_at"`
-	URL          string    `json:"url"`
+	Filename      string            `json:"filename"`
+	OriginalName  string            `json:"original_name"`
+	Size          int64             `json:"size"`
+	ContentType   string            `json:"content_type"`
+	UploadedAt    time.Time         `json:"uploaded_at"`
+	URL           string            `json:"url"`
+	AltText       string            `json:"alt_text,omitempty"`
+	AltTextSource string            `json:"alt_text_source,omitempty"` // "suggested" or "manual"
+	Width         int               `json:"width,omitempty"`
+	Height        int               `json:"height,omitempty"`
+	FocalPoint    *FocalPoint       `json:"focal_point,omitempty"`
+	Variants      map[string]string `json:"variants,omitempty"` // variant name -> URL
+}
+
+// FocalPoint marks the most important point of an image as fractions of
+// its width and height (0.0-1.0), kept in view when cropping variants
+type FocalPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
 }
 
 // FileBackup represents backup file information
@@ -0,0 +1,46 @@
+package types
+
+import "time"
+
+// Job states
+const (
+	JobQueued      = "queued"
+	JobRunning     = "running"
+	JobSucceeded   = "succeeded"
+	JobFailed      = "failed"
+	JobInterrupted = "interrupted" // the server stopped while it ran
+)
+
+// Job is a long-running admin task, such as restoring an archive. Progress
+// is a percentage over all steps; Done and Total count within the current
+// step, in the step's own unit (files, bytes).
+type Job struct {
+	ID         string         `json:"id"`
+	Type       string         `json:"type"`
+	Status     string         `json:"status"`
+	Progress   float64        `json:"progress"`
+	Step       string         `json:"step,omitempty"`
+	StepIndex  int            `json:"step_index"`
+	Steps      int            `json:"steps"`
+	Done       int64          `json:"done"`
+	Total      int64          `json:"total"`
+	Errors     []JobItemError `json:"errors,omitempty"`
+	ErrorCount int            `json:"error_count"`
+	Error      string         `json:"error,omitempty"`
+	Result     interface{}    `json:"result,omitempty"`
+	CreatedBy  string         `json:"created_by,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	StartedAt  *time.Time     `json:"started_at,omitempty"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+}
+
+// Finished reports whether the job has stopped running
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobInterrupted
+}
+
+// JobItemError is a failure of one item that did not stop the job
+type JobItemError struct {
+	Item  string `json:"item"`
+	Error string `json:"error"`
+}
@@ -0,0 +1,96 @@
+package types
+
+import "time"
+
+// Passkey is a WebAuthn credential registered for the admin. IDs and other
+// binary values are base64url without padding, as in the WebAuthn JSON
+// encoding.
+type Passkey struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Username   string    `json:"username"`
+	Algorithm  int       `json:"algorithm"` // COSE algorithm, e.g. -7 for ES256
+	SignCount  uint32    `json:"sign_count"`
+	Transports []string  `json:"transports,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at,omitzero"`
+}
+
+// PasskeyDescriptor names a credential in registration and login options
+type PasskeyDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// PasskeyRelyingParty identifies the site a passkey is registered with
+type PasskeyRelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// PasskeyUser identifies the account a passkey signs in to
+type PasskeyUser struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// PasskeyCredentialParam is a signature algorithm the server accepts
+type PasskeyCredentialParam struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// PasskeyAuthenticatorSelection states what kind of authenticator to use
+type PasskeyAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// PasskeyCreationOptions is the JSON form of
+// PublicKeyCredentialCreationOptions, for
+// PublicKeyCredential.parseCreationOptionsFromJSON
+type PasskeyCreationOptions struct {
+	Challenge              string                        `json:"challenge"`
+	RP                     PasskeyRelyingParty           `json:"rp"`
+	User                   PasskeyUser                   `json:"user"`
+	PubKeyCredParams       []PasskeyCredentialParam      `json:"pubKeyCredParams"`
+	Timeout                int                           `json:"timeout"`
+	ExcludeCredentials     []PasskeyDescriptor           `json:"excludeCredentials"`
+	AuthenticatorSelection PasskeyAuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                        `json:"attestation"`
+}
+
+// PasskeyRequestOptions is the JSON form of
+// PublicKeyCredentialRequestOptions, for
+// PublicKeyCredential.parseRequestOptionsFromJSON
+type PasskeyRequestOptions struct {
+	Challenge        string              `json:"challenge"`
+	RPID             string              `json:"rpId"`
+	Timeout          int                 `json:"timeout"`
+	AllowCredentials []PasskeyDescriptor `json:"allowCredentials"`
+	UserVerification string              `json:"userVerification"`
+}
+
+// PasskeyCredential is a credential as returned by
+// PublicKeyCredential.toJSON(), from either navigator.credentials.create
+// or navigator.credentials.get
+type PasskeyCredential struct {
+	ID       string                    `json:"id"`
+	RawID    string                    `json:"rawId"`
+	Type     string                    `json:"type"`
+	Response PasskeyCredentialResponse `json:"response"`
+}
+
+// PasskeyCredentialResponse holds the authenticator's response: an
+// attestation object on registration, authenticator data and a signature
+// on login
+type PasskeyCredentialResponse struct {
+	ClientDataJSON    string   `json:"clientDataJSON"`
+	AttestationObject string   `json:"attestationObject,omitempty"`
+	Transports        []string `json:"transports,omitempty"`
+	AuthenticatorData string   `json:"authenticatorData,omitempty"`
+	Signature         string   `json:"signature,omitempty"`
+	UserHandle        string   `json:"userHandle,omitempty"`
+}
@@ -0,0 +1,112 @@
+package types
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Stable machine-readable error codes returned in Problem.Code
+const (
+	ErrCodeInvalidRequest     = "invalid_request"
+	ErrCodeValidationFailed   = "validation_failed"
+	ErrCodeUnauthorized       = "unauthorized"
+	ErrCodeInvalidCredentials = "invalid_credentials"
+	ErrCodeForbidden          = "forbidden"
+	ErrCodeNotFound           = "not_found"
+	ErrCodeMethodNotAllowed   = "method_not_allowed"
+	ErrCodeConflict           = "conflict"
+	ErrCodePayloadTooLarge    = "payload_too_large"
+	ErrCodeInvalidSignature   = "invalid_signature"
+	ErrCodeRateLimited        = "rate_limited"
+	ErrCodeUpstream           = "upstream_error"
+	ErrCodeTimeout            = "timeout"
+	ErrCodeInternal           = "internal_error"
+
+	// ErrCodeConfirmationRequired is returned by destructive endpoints
+	// called without a valid confirmation token
+	ErrCodeConfirmationRequired = "confirmation_required"
+
+	// ErrCodeSessionLocked is returned for a session locked after
+	// inactivity, and ErrCodeReauthRequired by sensitive endpoints when the
+	// password was last entered too long ago; both clear once the password
+	// is entered again
+	ErrCodeSessionLocked  = "session_locked"
+	ErrCodeReauthRequired = "reauth_required"
+)
+
+// ProblemContentType is the media type of RFC 7807 error responses
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details response. Success and Message mirror
+// APIResponse so existing clients that read the envelope keep working.
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	Code      string            `json:"code"`
+	RequestID string            `json:"request_id,omitempty"`
+	Errors    []ValidationError `json:"errors,omitempty"`
+	Data      interface{}       `json:"data,omitempty"`
+	Success   bool              `json:"success"`
+	Message   string            `json:"message,omitempty"`
+}
+
+// NewProblem creates a problem for the given status, code and detail message
+func NewProblem(status int, code, detail string) *Problem {
+	if code == "" {
+		code = ErrCodeForStatus(status)
+	}
+	return &Problem{
+		Type:    "urn:onepagems:error:" + code,
+		Title:   http.StatusText(status),
+		Status:  status,
+		Detail:  detail,
+		Code:    code,
+		Message: detail,
+	}
+}
+
+// ErrCodeForStatus returns the default error code for an HTTP status
+func ErrCodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeInvalidRequest
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrCodeForbidden
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return ErrCodeMethodNotAllowed
+	case http.StatusConflict:
+		return ErrCodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return ErrCodePayloadTooLarge
+	case http.StatusUnprocessableEntity:
+		return ErrCodeValidationFailed
+	case http.StatusPreconditionRequired:
+		return ErrCodeConfirmationRequired
+	case http.StatusTooManyRequests:
+		return ErrCodeRateLimited
+	case http.StatusBadGateway:
+		return ErrCodeUpstream
+	case http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrCodeTimeout
+	default:
+		return ErrCodeInternal
+	}
+}
+
+// Write sends the problem as application/problem+json, tagging it with the
+// request path and the request ID from r's context
+func (p *Problem) Write(w http.ResponseWriter, r *http.Request) {
+	p.Instance = r.URL.Path
+	p.RequestID = RequestIDFromContext(r.Context())
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,22 @@
+package types
+
+import "context"
+
+// RequestIDContextKey is the key used to store the request ID in context
+type RequestIDContextKey string
+
+const RequestIDKey RequestIDContextKey = "request_id"
+
+// RequestIDHeader is the HTTP header carrying the request ID
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDContext creates a new context with the request ID
+func RequestIDContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, RequestIDKey, requestID)
+}
+
+// RequestIDFromContext retrieves the request ID from context, or "" if unset
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(RequestIDKey).(string)
+	return requestID
+}
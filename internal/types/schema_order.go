@@ -0,0 +1,80 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// UnmarshalJSON decodes a schema and records the declared order of its
+// properties, which a plain map loses
+func (s *SchemaData) UnmarshalJSON(data []byte) error {
+	type schemaAlias SchemaData
+	var alias schemaAlias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+	*s = SchemaData(alias)
+
+	s.PropertyOrder = make(map[string][]string)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	return readPropertyOrder(dec, "", false, s.PropertyOrder)
+}
+
+// readPropertyOrder walks the next JSON value. When properties is true the
+// value is a "properties" object and its keys are recorded under path.
+func readPropertyOrder(dec *json.Decoder, path string, properties bool, order map[string][]string) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return nil
+	}
+	if delim == '[' {
+		for dec.More() {
+			if err := skipJSONValue(dec); err != nil {
+				return err
+			}
+		}
+		_, err := dec.Token()
+		return err
+	}
+
+	for dec.More() {
+		keyToken, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := keyToken.(string)
+
+		switch {
+		case properties:
+			order[path] = append(order[path], key)
+			fieldPath := key
+			if path != "" {
+				fieldPath = path + "." + key
+			}
+			err = readPropertyOrder(dec, fieldPath, false, order)
+		case key == "properties":
+			err = readPropertyOrder(dec, path, true, order)
+		case key == "items":
+			// Item properties are recorded under the array's path with "[]"
+			err = readPropertyOrder(dec, path+"[]", false, order)
+		default:
+			err = skipJSONValue(dec)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
+
+// skipJSONValue consumes the next JSON value
+func skipJSONValue(dec *json.Decoder) error {
+	var discard json.RawMessage
+	return dec.Decode(&discard)
+}
@@ -17,6 +17,46 @@ type Session struct {
 	CreatedAt time.Time `json:"created_at"`
 	ExpiresAt time.Time `json:"expires_at"`
 	IsActive  bool      `json:"is_active"`
+
+	// LastSeenAt is the last request made with the session and
+	// AuthenticatedAt the last time its user entered the password. A
+	// session idle for longer than the idle timeout is Locked until the
+	// password is entered again.
+	LastSeenAt      time.Time `json:"last_seen_at,omitzero"`
+	AuthenticatedAt time.Time `json:"authenticated_at,omitzero"`
+	Locked          bool      `json:"locked,omitempty"`
+
+	// FailedLoginsSinceLast are the rejected sign-ins between the previous
+	// successful login and the one that created this session
+	FailedLoginsSinceLast *FailedLoginReport `json:"failed_logins_since_last,omitempty"`
+}
+
+// FailedLogin is one rejected sign-in attempt
+type FailedLogin struct {
+	Username string    `json:"username"`
+	IP       string    `json:"ip"`
+	At       time.Time `json:"at"`
+}
+
+// FailedLoginReport summarizes rejected sign-ins since the last successful
+// login. Recent keeps the latest attempts, newest last.
+type FailedLoginReport struct {
+	Count        int           `json:"count"`
+	LastIP       string        `json:"last_ip,omitempty"`
+	FirstAttempt time.Time     `json:"first_attempt,omitzero"`
+	LastAttempt  time.Time     `json:"last_attempt,omitzero"`
+	Recent       []FailedLogin `json:"recent"`
+}
+
+// LoginLockout is a client address or username that recently failed to
+// sign in. Until RetryAt, sign-ins for it are refused; Locked is set once
+// an address has used up its attempts. Usernames are never locked.
+type LoginLockout struct {
+	Key         string    `json:"key"` // "ip:<address>" or "user:<username>"
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	RetryAt     time.Time `json:"retry_at,omitzero"`
+	Locked      bool      `json:"locked"`
 }
 
 // SessionContext creates a new context with the session
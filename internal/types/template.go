@@ -24,4 +24,28 @@ type GenerationResult struct {
 	GeneratedAt time.Time `json:"generated_at"`
 	Errors      []string  `json:"errors,omitempty"`
 	Size        int64     `json:"size,omitempty"`
+
+	AssetMode             string `json:"asset_mode,omitempty"`
+	ContentSecurityPolicy string `json:"content_security_policy,omitempty"`
+}
+
+// CustomCode holds admin-supplied HTML injected into every generated page,
+// such as analytics or verification snippets
+type CustomCode struct {
+	HeadHTML    string            `json:"head_html"`     // inserted before </head>
+	BodyEndHTML string            `json:"body_end_html"` // inserted before </body>
+	UpdatedAt   time.Time         `json:"updated_at,omitempty"`
+	UpdatedBy   string            `json:"updated_by,omitempty"`
+	History     []CustomCodeAudit `json:"history,omitempty"`
+}
+
+// CustomCodeAudit records one change to the custom code slots. Only sizes
+// and hashes are kept so the log never holds copies of old snippets.
+type CustomCodeAudit struct {
+	At          time.Time `json:"at"`
+	By          string    `json:"by"`
+	HeadSize    int       `json:"head_size"`
+	HeadHash    string    `json:"head_hash,omitempty"`
+	BodyEndSize int       `json:"body_end_size"`
+	BodyEndHash string    `json:"body_end_hash,omitempty"`
 }
@@ -0,0 +1,62 @@
+// Service worker for the OnePage CMS admin panel. It keeps the pages and
+// API reads last loaded while online, so the panel opens and the content
+// editor works offline. Edits saved offline are queued by the page and sent
+// to POST /admin/content/changes when the connection returns.
+
+const CACHE = 'onepagems-admin-v1';
+const SHELL = ['/admin/manifest.webmanifest', '/admin/icon.svg'];
+
+// Reads kept for offline use; pages are kept as they are visited
+const CACHED_READS = ['/admin/content', '/admin/schema/form', '/admin/auth/status'];
+
+// Visiting these means the session is over; cached pages must not outlive it
+const SIGN_OUT = ['/admin/login', '/admin/logout'];
+
+self.addEventListener('install', event => {
+    event.waitUntil(caches.open(CACHE).then(cache => cache.addAll(SHELL)).then(() => self.skipWaiting()));
+});
+
+self.addEventListener('activate', event => {
+    event.waitUntil(caches.keys()
+        .then(keys => Promise.all(keys.filter(key => key !== CACHE).map(key => caches.delete(key))))
+        .then(() => self.clients.claim()));
+});
+
+self.addEventListener('fetch', event => {
+    const request = event.request;
+    const url = new URL(request.url);
+    if (url.origin !== self.location.origin) return;
+
+    if (SIGN_OUT.some(path => url.pathname.startsWith(path))) {
+        event.waitUntil(caches.delete(CACHE));
+        return;
+    }
+    if (request.method !== 'GET') return;
+
+    if (request.mode === 'navigate' || CACHED_READS.includes(url.pathname) || SHELL.includes(url.pathname)) {
+        event.respondWith(networkFirst(request));
+    }
+});
+
+// networkFirst answers from the network and keeps a copy, or from the copy
+// when offline. Redirects to the login page are not kept.
+async function networkFirst(request) {
+    const cache = await caches.open(CACHE);
+    try {
+        const response = await fetch(request);
+        if (response.ok && !response.redirected) {
+            cache.put(request, response.clone());
+        }
+        return response;
+    } catch (error) {
+        const cached = await cache.match(request);
+        if (cached) return cached;
+        if (request.mode === 'navigate') {
+            return new Response('<h1>Offline</h1><p>This page has not been opened while online yet.</p>', {
+                status: 503,
+                headers: { 'Content-Type': 'text/html; charset=utf-8' }
+            });
+        }
+        throw error;
+    }
+}